@@ -33,6 +33,9 @@ type IPConfig struct {
 	// ip pool
 	IPPool string `json:"ipPool,omitempty"`
 
+	// the MAC address to be set on the interface, empty if it is not fixed
+	Mac string `json:"mac,omitempty"`
+
 	// nic
 	// Required: true
 	Nic *string `json:"nic"`
@@ -23,6 +23,9 @@ import (
 // swagger:model IpamAddResponse
 type IpamAddResponse struct {
 
+	// IPs are allocated from the default IPPools because the IPPools specified by Pod annotations do not exist
+	DefaultPoolFallback bool `json:"defaultPoolFallback,omitempty"`
+
 	// dns
 	DNS *DNS `json:"dns,omitempty"`
 
@@ -33,6 +33,9 @@ type Route struct {
 	// if name
 	// Required: true
 	IfName *string `json:"ifName"`
+
+	// the metric of the route, zero means the default one of the system
+	Metric int64 `json:"metric,omitempty"`
 }
 
 // Validate validates this route
@@ -274,6 +274,10 @@ func init() {
         "ipPool": {
           "type": "string"
         },
+        "mac": {
+          "description": "the MAC address to be set on the interface, empty if it is not fixed",
+          "type": "string"
+        },
         "nic": {
           "type": "string"
         },
@@ -339,6 +343,10 @@ func init() {
         "ips"
       ],
       "properties": {
+        "defaultPoolFallback": {
+          "description": "IPs are allocated from the default IPPools because the IPPools specified by Pod annotations do not exist",
+          "type": "boolean"
+        },
         "dns": {
           "type": "object",
           "$ref": "#/definitions/DNS"
@@ -401,6 +409,10 @@ func init() {
         },
         "ifName": {
           "type": "string"
+        },
+        "metric": {
+          "description": "the metric of the route, zero means the default one of the system",
+          "type": "integer"
         }
       }
     }
@@ -663,6 +675,10 @@ func init() {
         "ipPool": {
           "type": "string"
         },
+        "mac": {
+          "description": "the MAC address to be set on the interface, empty if it is not fixed",
+          "type": "string"
+        },
         "nic": {
           "type": "string"
         },
@@ -728,6 +744,10 @@ func init() {
         "ips"
       ],
       "properties": {
+        "defaultPoolFallback": {
+          "description": "IPs are allocated from the default IPPools because the IPPools specified by Pod annotations do not exist",
+          "type": "boolean"
+        },
         "dns": {
           "type": "object",
           "$ref": "#/definitions/DNS"
@@ -790,6 +810,10 @@ func init() {
         },
         "ifName": {
           "type": "string"
+        },
+        "metric": {
+          "description": "the metric of the route, zero means the default one of the system",
+          "type": "integer"
         }
       }
     }
@@ -33,15 +33,195 @@ type ClientOption func(*runtime.ClientOperation)
 
 // ClientService is the interface for Client methods
 type ClientService interface {
+	DeleteIpamToken(params *DeleteIpamTokenParams, opts ...ClientOption) (*DeleteIpamTokenOK, error)
+
+	GetIpamAudit(params *GetIpamAuditParams, opts ...ClientOption) (*GetIpamAuditOK, error)
+
+	GetIpamEndpoint(params *GetIpamEndpointParams, opts ...ClientOption) (*GetIpamEndpointOK, error)
+
+	GetIpamStats(params *GetIpamStatsParams, opts ...ClientOption) (*GetIpamStatsOK, error)
+
 	GetIpamStatus(params *GetIpamStatusParams, opts ...ClientOption) (*GetIpamStatusOK, error)
 
 	PostIpamGcIps(params *PostIpamGcIpsParams, opts ...ClientOption) (*PostIpamGcIpsOK, error)
 
+	PostIpamPreview(params *PostIpamPreviewParams, opts ...ClientOption) (*PostIpamPreviewOK, error)
+
+	PostIpamReservedips(params *PostIpamReservedipsParams, opts ...ClientOption) (*PostIpamReservedipsOK, error)
+
+	PostIpamToken(params *PostIpamTokenParams, opts ...ClientOption) (*PostIpamTokenOK, error)
+
 	PutIpamIP(params *PutIpamIPParams, opts ...ClientOption) (*PutIpamIPOK, error)
 
 	SetTransport(transport runtime.ClientTransport)
 }
 
+/*
+DeleteIpamToken returns allocation token
+
+Return the token of the cluster-wide budget of concurrent IP allocations
+*/
+func (a *Client) DeleteIpamToken(params *DeleteIpamTokenParams, opts ...ClientOption) (*DeleteIpamTokenOK, error) {
+	// TODO: Validate the params before sending
+	if params == nil {
+		params = NewDeleteIpamTokenParams()
+	}
+	op := &runtime.ClientOperation{
+		ID:                 "DeleteIpamToken",
+		Method:             "DELETE",
+		PathPattern:        "/ipam/token",
+		ProducesMediaTypes: []string{"application/json"},
+		ConsumesMediaTypes: []string{"application/json"},
+		Schemes:            []string{"http"},
+		Params:             params,
+		Reader:             &DeleteIpamTokenReader{formats: a.formats},
+		Context:            params.Context,
+		Client:             params.HTTPClient,
+	}
+	for _, opt := range opts {
+		opt(op)
+	}
+
+	result, err := a.transport.Submit(op)
+	if err != nil {
+		return nil, err
+	}
+	success, ok := result.(*DeleteIpamTokenOK)
+	if ok {
+		return success, nil
+	}
+	// unexpected success response
+	// safeguard: normally, absent a default response, unknown success responses return an error above: so this is a codegen issue
+	msg := fmt.Sprintf("unexpected success response for DeleteIpamToken: API contract not enforced by server. Client expected to get an error, but got: %T", result)
+	panic(msg)
+}
+
+/*
+	GetIpamAudit audits IP allocations
+
+	Cross-check the IP allocations of the IPPools, SpiderEndpoints and Pods,
+
+and report all the mismatches
+*/
+func (a *Client) GetIpamAudit(params *GetIpamAuditParams, opts ...ClientOption) (*GetIpamAuditOK, error) {
+	// TODO: Validate the params before sending
+	if params == nil {
+		params = NewGetIpamAuditParams()
+	}
+	op := &runtime.ClientOperation{
+		ID:                 "GetIpamAudit",
+		Method:             "GET",
+		PathPattern:        "/ipam/audit",
+		ProducesMediaTypes: []string{"application/json"},
+		ConsumesMediaTypes: []string{"application/json"},
+		Schemes:            []string{"http"},
+		Params:             params,
+		Reader:             &GetIpamAuditReader{formats: a.formats},
+		Context:            params.Context,
+		Client:             params.HTTPClient,
+	}
+	for _, opt := range opts {
+		opt(op)
+	}
+
+	result, err := a.transport.Submit(op)
+	if err != nil {
+		return nil, err
+	}
+	success, ok := result.(*GetIpamAuditOK)
+	if ok {
+		return success, nil
+	}
+	// unexpected success response
+	// safeguard: normally, absent a default response, unknown success responses return an error above: so this is a codegen issue
+	msg := fmt.Sprintf("unexpected success response for GetIpamAudit: API contract not enforced by server. Client expected to get an error, but got: %T", result)
+	panic(msg)
+}
+
+/*
+	GetIpamEndpoint gets endpoint by IP
+
+	Get the Pod which the IP address is currently allocated to, according to
+
+the SpiderEndpoints
+*/
+func (a *Client) GetIpamEndpoint(params *GetIpamEndpointParams, opts ...ClientOption) (*GetIpamEndpointOK, error) {
+	// TODO: Validate the params before sending
+	if params == nil {
+		params = NewGetIpamEndpointParams()
+	}
+	op := &runtime.ClientOperation{
+		ID:                 "GetIpamEndpoint",
+		Method:             "GET",
+		PathPattern:        "/ipam/endpoint",
+		ProducesMediaTypes: []string{"application/json"},
+		ConsumesMediaTypes: []string{"application/json"},
+		Schemes:            []string{"http"},
+		Params:             params,
+		Reader:             &GetIpamEndpointReader{formats: a.formats},
+		Context:            params.Context,
+		Client:             params.HTTPClient,
+	}
+	for _, opt := range opts {
+		opt(op)
+	}
+
+	result, err := a.transport.Submit(op)
+	if err != nil {
+		return nil, err
+	}
+	success, ok := result.(*GetIpamEndpointOK)
+	if ok {
+		return success, nil
+	}
+	// unexpected success response
+	// safeguard: normally, absent a default response, unknown success responses return an error above: so this is a codegen issue
+	msg := fmt.Sprintf("unexpected success response for GetIpamEndpoint: API contract not enforced by server. Client expected to get an error, but got: %T", result)
+	panic(msg)
+}
+
+/*
+	GetIpamStats gets allocation statistics
+
+	Get the counts and rates of IP allocations and releases over a time window,
+
+grouped by IPPool and Namespace
+*/
+func (a *Client) GetIpamStats(params *GetIpamStatsParams, opts ...ClientOption) (*GetIpamStatsOK, error) {
+	// TODO: Validate the params before sending
+	if params == nil {
+		params = NewGetIpamStatsParams()
+	}
+	op := &runtime.ClientOperation{
+		ID:                 "GetIpamStats",
+		Method:             "GET",
+		PathPattern:        "/ipam/stats",
+		ProducesMediaTypes: []string{"application/json"},
+		ConsumesMediaTypes: []string{"application/json"},
+		Schemes:            []string{"http"},
+		Params:             params,
+		Reader:             &GetIpamStatsReader{formats: a.formats},
+		Context:            params.Context,
+		Client:             params.HTTPClient,
+	}
+	for _, opt := range opts {
+		opt(op)
+	}
+
+	result, err := a.transport.Submit(op)
+	if err != nil {
+		return nil, err
+	}
+	success, ok := result.(*GetIpamStatsOK)
+	if ok {
+		return success, nil
+	}
+	// unexpected success response
+	// safeguard: normally, absent a default response, unknown success responses return an error above: so this is a codegen issue
+	msg := fmt.Sprintf("unexpected success response for GetIpamStats: API contract not enforced by server. Client expected to get an error, but got: %T", result)
+	panic(msg)
+}
+
 /*
 GetIpamStatus gets status
 
@@ -122,6 +302,133 @@ func (a *Client) PostIpamGcIps(params *PostIpamGcIpsParams, opts ...ClientOption
 	panic(msg)
 }
 
+/*
+	PostIpamPreview previews IP allocation
+
+	Check whether the IP allocation of the Pod would succeed with the current
+
+IPPools, Namespaces and Nodes, according to its IPAM annotations
+*/
+func (a *Client) PostIpamPreview(params *PostIpamPreviewParams, opts ...ClientOption) (*PostIpamPreviewOK, error) {
+	// TODO: Validate the params before sending
+	if params == nil {
+		params = NewPostIpamPreviewParams()
+	}
+	op := &runtime.ClientOperation{
+		ID:                 "PostIpamPreview",
+		Method:             "POST",
+		PathPattern:        "/ipam/preview",
+		ProducesMediaTypes: []string{"application/json"},
+		ConsumesMediaTypes: []string{"application/json"},
+		Schemes:            []string{"http"},
+		Params:             params,
+		Reader:             &PostIpamPreviewReader{formats: a.formats},
+		Context:            params.Context,
+		Client:             params.HTTPClient,
+	}
+	for _, opt := range opts {
+		opt(op)
+	}
+
+	result, err := a.transport.Submit(op)
+	if err != nil {
+		return nil, err
+	}
+	success, ok := result.(*PostIpamPreviewOK)
+	if ok {
+		return success, nil
+	}
+	// unexpected success response
+	// safeguard: normally, absent a default response, unknown success responses return an error above: so this is a codegen issue
+	msg := fmt.Sprintf("unexpected success response for PostIpamPreview: API contract not enforced by server. Client expected to get an error, but got: %T", result)
+	panic(msg)
+}
+
+/*
+	PostIpamReservedips imports reserved IP addresses
+
+	Create or update one SpiderReservedIP for each of the reserved IP addresses
+
+maintained outside the cluster, such as in a CMDB, the unchanged ones are
+skipped so that the import is idempotent
+*/
+func (a *Client) PostIpamReservedips(params *PostIpamReservedipsParams, opts ...ClientOption) (*PostIpamReservedipsOK, error) {
+	// TODO: Validate the params before sending
+	if params == nil {
+		params = NewPostIpamReservedipsParams()
+	}
+	op := &runtime.ClientOperation{
+		ID:                 "PostIpamReservedips",
+		Method:             "POST",
+		PathPattern:        "/ipam/reservedips",
+		ProducesMediaTypes: []string{"application/json"},
+		ConsumesMediaTypes: []string{"application/json"},
+		Schemes:            []string{"http"},
+		Params:             params,
+		Reader:             &PostIpamReservedipsReader{formats: a.formats},
+		Context:            params.Context,
+		Client:             params.HTTPClient,
+	}
+	for _, opt := range opts {
+		opt(op)
+	}
+
+	result, err := a.transport.Submit(op)
+	if err != nil {
+		return nil, err
+	}
+	success, ok := result.(*PostIpamReservedipsOK)
+	if ok {
+		return success, nil
+	}
+	// unexpected success response
+	// safeguard: normally, absent a default response, unknown success responses return an error above: so this is a codegen issue
+	msg := fmt.Sprintf("unexpected success response for PostIpamReservedips: API contract not enforced by server. Client expected to get an error, but got: %T", result)
+	panic(msg)
+}
+
+/*
+	PostIpamToken acquires allocation token
+
+	Acquire a token of the cluster-wide budget of concurrent IP allocations
+
+for the node, it blocks until the token is issued
+*/
+func (a *Client) PostIpamToken(params *PostIpamTokenParams, opts ...ClientOption) (*PostIpamTokenOK, error) {
+	// TODO: Validate the params before sending
+	if params == nil {
+		params = NewPostIpamTokenParams()
+	}
+	op := &runtime.ClientOperation{
+		ID:                 "PostIpamToken",
+		Method:             "POST",
+		PathPattern:        "/ipam/token",
+		ProducesMediaTypes: []string{"application/json"},
+		ConsumesMediaTypes: []string{"application/json"},
+		Schemes:            []string{"http"},
+		Params:             params,
+		Reader:             &PostIpamTokenReader{formats: a.formats},
+		Context:            params.Context,
+		Client:             params.HTTPClient,
+	}
+	for _, opt := range opts {
+		opt(op)
+	}
+
+	result, err := a.transport.Submit(op)
+	if err != nil {
+		return nil, err
+	}
+	success, ok := result.(*PostIpamTokenOK)
+	if ok {
+		return success, nil
+	}
+	// unexpected success response
+	// safeguard: normally, absent a default response, unknown success responses return an error above: so this is a codegen issue
+	msg := fmt.Sprintf("unexpected success response for PostIpamToken: API contract not enforced by server. Client expected to get an error, but got: %T", result)
+	panic(msg)
+}
+
 /*
 PutIpamIP forces set ip
 
@@ -42,6 +42,26 @@ func configureAPI(api *restapi.SpiderpoolControllerAPIAPI) http.Handler {
 
 	api.JSONProducer = runtime.JSONProducer()
 
+	if api.ControllerDeleteIpamTokenHandler == nil {
+		api.ControllerDeleteIpamTokenHandler = controller.DeleteIpamTokenHandlerFunc(func(params controller.DeleteIpamTokenParams) middleware.Responder {
+			return middleware.NotImplemented("operation controller.DeleteIpamToken has not yet been implemented")
+		})
+	}
+	if api.ControllerGetIpamAuditHandler == nil {
+		api.ControllerGetIpamAuditHandler = controller.GetIpamAuditHandlerFunc(func(params controller.GetIpamAuditParams) middleware.Responder {
+			return middleware.NotImplemented("operation controller.GetIpamAudit has not yet been implemented")
+		})
+	}
+	if api.ControllerGetIpamEndpointHandler == nil {
+		api.ControllerGetIpamEndpointHandler = controller.GetIpamEndpointHandlerFunc(func(params controller.GetIpamEndpointParams) middleware.Responder {
+			return middleware.NotImplemented("operation controller.GetIpamEndpoint has not yet been implemented")
+		})
+	}
+	if api.ControllerGetIpamStatsHandler == nil {
+		api.ControllerGetIpamStatsHandler = controller.GetIpamStatsHandlerFunc(func(params controller.GetIpamStatsParams) middleware.Responder {
+			return middleware.NotImplemented("operation controller.GetIpamStats has not yet been implemented")
+		})
+	}
 	if api.ControllerGetIpamStatusHandler == nil {
 		api.ControllerGetIpamStatusHandler = controller.GetIpamStatusHandlerFunc(func(params controller.GetIpamStatusParams) middleware.Responder {
 			return middleware.NotImplemented("operation controller.GetIpamStatus has not yet been implemented")
@@ -67,6 +87,21 @@ func configureAPI(api *restapi.SpiderpoolControllerAPIAPI) http.Handler {
 			return middleware.NotImplemented("operation controller.PostIpamGcIps has not yet been implemented")
 		})
 	}
+	if api.ControllerPostIpamPreviewHandler == nil {
+		api.ControllerPostIpamPreviewHandler = controller.PostIpamPreviewHandlerFunc(func(params controller.PostIpamPreviewParams) middleware.Responder {
+			return middleware.NotImplemented("operation controller.PostIpamPreview has not yet been implemented")
+		})
+	}
+	if api.ControllerPostIpamReservedipsHandler == nil {
+		api.ControllerPostIpamReservedipsHandler = controller.PostIpamReservedipsHandlerFunc(func(params controller.PostIpamReservedipsParams) middleware.Responder {
+			return middleware.NotImplemented("operation controller.PostIpamReservedips has not yet been implemented")
+		})
+	}
+	if api.ControllerPostIpamTokenHandler == nil {
+		api.ControllerPostIpamTokenHandler = controller.PostIpamTokenHandlerFunc(func(params controller.PostIpamTokenParams) middleware.Responder {
+			return middleware.NotImplemented("operation controller.PostIpamToken has not yet been implemented")
+		})
+	}
 	if api.ControllerPutIpamIPHandler == nil {
 		api.ControllerPutIpamIPHandler = controller.PutIpamIPHandlerFunc(func(params controller.PutIpamIPParams) middleware.Responder {
 			return middleware.NotImplemented("operation controller.PutIpamIP has not yet been implemented")
@@ -35,6 +35,88 @@ func init() {
   },
   "basePath": "/v1",
   "paths": {
+    "/ipam/audit": {
+      "get": {
+        "description": "Cross-check the IP allocations of the IPPools, SpiderEndpoints and Pods,\nand report all the mismatches\n",
+        "tags": [
+          "controller"
+        ],
+        "summary": "Audit IP allocations",
+        "responses": {
+          "200": {
+            "description": "Success",
+            "schema": {
+              "$ref": "#/definitions/IpamAudit"
+            }
+          },
+          "500": {
+            "description": "Audit failure",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            }
+          }
+        }
+      }
+    },
+    "/ipam/endpoint": {
+      "get": {
+        "description": "Get the Pod which the IP address is currently allocated to, according to\nthe SpiderEndpoints\n",
+        "tags": [
+          "controller"
+        ],
+        "summary": "Get endpoint by IP",
+        "parameters": [
+          {
+            "type": "string",
+            "description": "the IP address, without the prefix length",
+            "name": "ip",
+            "in": "query",
+            "required": true
+          },
+          {
+            "type": "integer",
+            "description": "the IP version of the IP address, it is detected from the IP address if not set",
+            "name": "ipVersion",
+            "in": "query"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success",
+            "schema": {
+              "$ref": "#/definitions/IpamEndpoint"
+            }
+          },
+          "400": {
+            "description": "Invalid IP address",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            },
+            "x-go-name": "BadRequest"
+          },
+          "404": {
+            "description": "IP address not allocated",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            },
+            "x-go-name": "NotFound"
+          },
+          "409": {
+            "description": "IP address allocated to multiple Pods",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            },
+            "x-go-name": "Conflict"
+          },
+          "500": {
+            "description": "Get endpoint failure",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            }
+          }
+        }
+      }
+    },
     "/ipam/gc_ips": {
       "post": {
         "description": "Trigger global gc or specific ip gc with the param\n",
@@ -69,6 +151,133 @@ func init() {
         }
       }
     },
+    "/ipam/preview": {
+      "post": {
+        "description": "Check whether the IP allocation of the Pod would succeed with the current\nIPPools, Namespaces and Nodes, according to its IPAM annotations\n",
+        "tags": [
+          "controller"
+        ],
+        "summary": "Preview IP allocation",
+        "parameters": [
+          {
+            "description": "the manifest of the Pod",
+            "name": "pod",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "type": "object"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success",
+            "schema": {
+              "$ref": "#/definitions/IpamPreview"
+            }
+          },
+          "400": {
+            "description": "Invalid Pod manifest",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            },
+            "x-go-name": "BadRequest"
+          },
+          "500": {
+            "description": "Preview failure",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            }
+          }
+        }
+      }
+    },
+    "/ipam/reservedips": {
+      "post": {
+        "description": "Create or update one SpiderReservedIP for each of the reserved IP addresses\nmaintained outside the cluster, such as in a CMDB, the unchanged ones are\nskipped so that the import is idempotent\n",
+        "tags": [
+          "controller"
+        ],
+        "summary": "Import reserved IP addresses",
+        "parameters": [
+          {
+            "description": "the reserved IP addresses to import",
+            "name": "reservedIPs",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/ReservedIPImport"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success",
+            "schema": {
+              "$ref": "#/definitions/ReservedIPImportResult"
+            }
+          },
+          "400": {
+            "description": "Invalid reserved IP addresses",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            },
+            "x-go-name": "BadRequest"
+          },
+          "500": {
+            "description": "Import failure",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            }
+          }
+        }
+      }
+    },
+    "/ipam/stats": {
+      "get": {
+        "description": "Get the counts and rates of IP allocations and releases over a time window,\ngrouped by IPPool and Namespace\n",
+        "tags": [
+          "controller"
+        ],
+        "summary": "Get allocation statistics",
+        "parameters": [
+          {
+            "type": "string",
+            "default": "1h",
+            "description": "the time window ending now, such as 5m, 1h and 24h, it is at most 24h",
+            "name": "window",
+            "in": "query"
+          },
+          {
+            "type": "string",
+            "description": "only the statistics of the IPPool",
+            "name": "pool",
+            "in": "query"
+          },
+          {
+            "type": "string",
+            "description": "only the statistics of the Namespace",
+            "name": "namespace",
+            "in": "query"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success",
+            "schema": {
+              "$ref": "#/definitions/IpamStats"
+            }
+          },
+          "400": {
+            "description": "Invalid time window",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            },
+            "x-go-name": "BadRequest"
+          }
+        }
+      }
+    },
     "/ipam/status": {
       "get": {
         "description": "Get ipam status for spiderpool controller cli debug usage\n",
@@ -86,6 +295,60 @@ func init() {
         }
       }
     },
+    "/ipam/token": {
+      "post": {
+        "description": "Acquire a token of the cluster-wide budget of concurrent IP allocations\nfor the node, it blocks until the token is issued\n",
+        "tags": [
+          "controller"
+        ],
+        "summary": "Acquire allocation token",
+        "parameters": [
+          {
+            "type": "string",
+            "description": "the node which the token is issued to",
+            "name": "node",
+            "in": "query",
+            "required": true
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success",
+            "schema": {
+              "$ref": "#/definitions/IpamToken"
+            }
+          },
+          "429": {
+            "description": "Too many requests waiting for tokens",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            },
+            "x-go-name": "TooManyRequests"
+          }
+        }
+      },
+      "delete": {
+        "description": "Return the token of the cluster-wide budget of concurrent IP allocations\n",
+        "tags": [
+          "controller"
+        ],
+        "summary": "Return allocation token",
+        "parameters": [
+          {
+            "type": "string",
+            "description": "the token to return",
+            "name": "token",
+            "in": "query",
+            "required": true
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success"
+          }
+        }
+      }
+    },
     "/runtime/liveness": {
       "get": {
         "description": "Check pod liveness probe",
@@ -138,6 +401,288 @@ func init() {
       }
     }
   },
+  "definitions": {
+    "Error": {
+      "description": "API error",
+      "type": "string"
+    },
+    "IpamAudit": {
+      "description": "Report of the consistency audit of the IP allocations",
+      "type": "object",
+      "properties": {
+        "endpoints": {
+          "description": "the count of the audited SpiderEndpoints",
+          "type": "integer"
+        },
+        "ipPools": {
+          "description": "the count of the audited IPPools",
+          "type": "integer"
+        },
+        "items": {
+          "description": "the mismatches found, none if the IP allocations are consistent",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/IpamAuditItem"
+          }
+        },
+        "pods": {
+          "description": "the count of the audited Pods",
+          "type": "integer"
+        }
+      }
+    },
+    "IpamAuditItem": {
+      "description": "A mismatch among the IPPools, SpiderEndpoints and Pods",
+      "type": "object",
+      "properties": {
+        "category": {
+          "description": "the category of the mismatch, AllocatedWithoutEndpoint, AllocatedWithoutPod, EndpointWithoutPoolRecord, EndpointWithoutPod or DoubleAllocation",
+          "type": "string"
+        },
+        "containerID": {
+          "type": "string"
+        },
+        "ip": {
+          "type": "string"
+        },
+        "message": {
+          "description": "the detail of the mismatch",
+          "type": "string"
+        },
+        "namespace": {
+          "type": "string"
+        },
+        "pod": {
+          "type": "string"
+        },
+        "pool": {
+          "type": "string"
+        }
+      }
+    },
+    "IpamEndpoint": {
+      "description": "The Pod which an IP address is allocated to",
+      "type": "object",
+      "properties": {
+        "containerID": {
+          "type": "string"
+        },
+        "ip": {
+          "description": "the IP address with the prefix length",
+          "type": "string"
+        },
+        "namespace": {
+          "type": "string"
+        },
+        "nic": {
+          "type": "string"
+        },
+        "node": {
+          "type": "string"
+        },
+        "ownerControllerName": {
+          "type": "string"
+        },
+        "ownerControllerType": {
+          "type": "string"
+        },
+        "pod": {
+          "type": "string"
+        },
+        "pool": {
+          "type": "string"
+        },
+        "uid": {
+          "description": "the UID of the Pod, it is empty for the Pods of StatefulSets",
+          "type": "string"
+        }
+      }
+    },
+    "IpamPreview": {
+      "description": "Whether the IP allocation of a Pod would succeed",
+      "type": "object",
+      "properties": {
+        "allowed": {
+          "description": "whether the IP allocation would succeed",
+          "type": "boolean"
+        },
+        "messages": {
+          "description": "why the IP allocation would fail, or what is not previewed",
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
+        "pools": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/IpamPreviewPool"
+          }
+        }
+      }
+    },
+    "IpamPreviewPool": {
+      "description": "Whether an IPPool candidate could allocate IP addresses to the Pod",
+      "type": "object",
+      "properties": {
+        "filter": {
+          "description": "the filter eliminating the IPPool",
+          "type": "string"
+        },
+        "ipVersion": {
+          "type": "integer"
+        },
+        "nic": {
+          "type": "string"
+        },
+        "pool": {
+          "type": "string"
+        },
+        "reason": {
+          "description": "why the IPPool is not usable",
+          "type": "string"
+        },
+        "usable": {
+          "description": "whether the IPPool is usable regardless of the node affinity",
+          "type": "boolean"
+        }
+      }
+    },
+    "IpamStats": {
+      "description": "Statistics of IP allocations and releases over a time window",
+      "type": "object",
+      "properties": {
+        "items": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/IpamStatsItem"
+          }
+        },
+        "window": {
+          "description": "the time window",
+          "type": "string"
+        }
+      }
+    },
+    "IpamStatsItem": {
+      "description": "Statistics of IP allocations and releases of an IPPool in a Namespace",
+      "type": "object",
+      "properties": {
+        "allocationRate": {
+          "description": "the average count of IP allocations per minute",
+          "type": "number"
+        },
+        "allocations": {
+          "description": "the count of IP allocations",
+          "type": "integer"
+        },
+        "namespace": {
+          "type": "string"
+        },
+        "pool": {
+          "type": "string"
+        },
+        "releaseRate": {
+          "description": "the average count of IP releases per minute",
+          "type": "number"
+        },
+        "releases": {
+          "description": "the count of IP releases",
+          "type": "integer"
+        }
+      }
+    },
+    "IpamToken": {
+      "description": "Token of the cluster-wide budget of concurrent IP allocations",
+      "type": "object",
+      "properties": {
+        "token": {
+          "type": "string"
+        }
+      }
+    },
+    "ReservedIPImport": {
+      "description": "Reserved IP addresses to import",
+      "type": "object",
+      "required": [
+        "records"
+      ],
+      "properties": {
+        "records": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/ReservedIPRecord"
+          }
+        },
+        "source": {
+          "description": "the source of the reserved IP addresses, which the SpiderReservedIPs are labeled with and named after",
+          "type": "string"
+        }
+      }
+    },
+    "ReservedIPImportFailure": {
+      "description": "A reserved IP address failed to import",
+      "type": "object",
+      "properties": {
+        "error": {
+          "type": "string"
+        },
+        "ip": {
+          "type": "string"
+        },
+        "name": {
+          "type": "string"
+        }
+      }
+    },
+    "ReservedIPImportResult": {
+      "description": "The result of importing reserved IP addresses",
+      "type": "object",
+      "properties": {
+        "created": {
+          "description": "the count of the created SpiderReservedIPs",
+          "type": "integer"
+        },
+        "failures": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/ReservedIPImportFailure"
+          }
+        },
+        "unchanged": {
+          "description": "the count of the SpiderReservedIPs already up to date",
+          "type": "integer"
+        },
+        "updated": {
+          "description": "the count of the updated SpiderReservedIPs",
+          "type": "integer"
+        }
+      }
+    },
+    "ReservedIPRecord": {
+      "description": "A reserved IP address with its metadata",
+      "type": "object",
+      "required": [
+        "ip"
+      ],
+      "properties": {
+        "description": {
+          "type": "string"
+        },
+        "ip": {
+          "description": "the IP address or IP range",
+          "type": "string"
+        },
+        "name": {
+          "description": "the name of the SpiderReservedIP, it is generated from the source and the IP address if not set",
+          "type": "string"
+        },
+        "owner": {
+          "type": "string"
+        }
+      }
+    }
+  },
   "x-schemes": [
     "http"
   ]
@@ -157,6 +702,88 @@ func init() {
   },
   "basePath": "/v1",
   "paths": {
+    "/ipam/audit": {
+      "get": {
+        "description": "Cross-check the IP allocations of the IPPools, SpiderEndpoints and Pods,\nand report all the mismatches\n",
+        "tags": [
+          "controller"
+        ],
+        "summary": "Audit IP allocations",
+        "responses": {
+          "200": {
+            "description": "Success",
+            "schema": {
+              "$ref": "#/definitions/IpamAudit"
+            }
+          },
+          "500": {
+            "description": "Audit failure",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            }
+          }
+        }
+      }
+    },
+    "/ipam/endpoint": {
+      "get": {
+        "description": "Get the Pod which the IP address is currently allocated to, according to\nthe SpiderEndpoints\n",
+        "tags": [
+          "controller"
+        ],
+        "summary": "Get endpoint by IP",
+        "parameters": [
+          {
+            "type": "string",
+            "description": "the IP address, without the prefix length",
+            "name": "ip",
+            "in": "query",
+            "required": true
+          },
+          {
+            "type": "integer",
+            "description": "the IP version of the IP address, it is detected from the IP address if not set",
+            "name": "ipVersion",
+            "in": "query"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success",
+            "schema": {
+              "$ref": "#/definitions/IpamEndpoint"
+            }
+          },
+          "400": {
+            "description": "Invalid IP address",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            },
+            "x-go-name": "BadRequest"
+          },
+          "404": {
+            "description": "IP address not allocated",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            },
+            "x-go-name": "NotFound"
+          },
+          "409": {
+            "description": "IP address allocated to multiple Pods",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            },
+            "x-go-name": "Conflict"
+          },
+          "500": {
+            "description": "Get endpoint failure",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            }
+          }
+        }
+      }
+    },
     "/ipam/gc_ips": {
       "post": {
         "description": "Trigger global gc or specific ip gc with the param\n",
@@ -191,6 +818,133 @@ func init() {
         }
       }
     },
+    "/ipam/preview": {
+      "post": {
+        "description": "Check whether the IP allocation of the Pod would succeed with the current\nIPPools, Namespaces and Nodes, according to its IPAM annotations\n",
+        "tags": [
+          "controller"
+        ],
+        "summary": "Preview IP allocation",
+        "parameters": [
+          {
+            "description": "the manifest of the Pod",
+            "name": "pod",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "type": "object"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success",
+            "schema": {
+              "$ref": "#/definitions/IpamPreview"
+            }
+          },
+          "400": {
+            "description": "Invalid Pod manifest",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            },
+            "x-go-name": "BadRequest"
+          },
+          "500": {
+            "description": "Preview failure",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            }
+          }
+        }
+      }
+    },
+    "/ipam/reservedips": {
+      "post": {
+        "description": "Create or update one SpiderReservedIP for each of the reserved IP addresses\nmaintained outside the cluster, such as in a CMDB, the unchanged ones are\nskipped so that the import is idempotent\n",
+        "tags": [
+          "controller"
+        ],
+        "summary": "Import reserved IP addresses",
+        "parameters": [
+          {
+            "description": "the reserved IP addresses to import",
+            "name": "reservedIPs",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/ReservedIPImport"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success",
+            "schema": {
+              "$ref": "#/definitions/ReservedIPImportResult"
+            }
+          },
+          "400": {
+            "description": "Invalid reserved IP addresses",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            },
+            "x-go-name": "BadRequest"
+          },
+          "500": {
+            "description": "Import failure",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            }
+          }
+        }
+      }
+    },
+    "/ipam/stats": {
+      "get": {
+        "description": "Get the counts and rates of IP allocations and releases over a time window,\ngrouped by IPPool and Namespace\n",
+        "tags": [
+          "controller"
+        ],
+        "summary": "Get allocation statistics",
+        "parameters": [
+          {
+            "type": "string",
+            "default": "1h",
+            "description": "the time window ending now, such as 5m, 1h and 24h, it is at most 24h",
+            "name": "window",
+            "in": "query"
+          },
+          {
+            "type": "string",
+            "description": "only the statistics of the IPPool",
+            "name": "pool",
+            "in": "query"
+          },
+          {
+            "type": "string",
+            "description": "only the statistics of the Namespace",
+            "name": "namespace",
+            "in": "query"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success",
+            "schema": {
+              "$ref": "#/definitions/IpamStats"
+            }
+          },
+          "400": {
+            "description": "Invalid time window",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            },
+            "x-go-name": "BadRequest"
+          }
+        }
+      }
+    },
     "/ipam/status": {
       "get": {
         "description": "Get ipam status for spiderpool controller cli debug usage\n",
@@ -208,6 +962,60 @@ func init() {
         }
       }
     },
+    "/ipam/token": {
+      "post": {
+        "description": "Acquire a token of the cluster-wide budget of concurrent IP allocations\nfor the node, it blocks until the token is issued\n",
+        "tags": [
+          "controller"
+        ],
+        "summary": "Acquire allocation token",
+        "parameters": [
+          {
+            "type": "string",
+            "description": "the node which the token is issued to",
+            "name": "node",
+            "in": "query",
+            "required": true
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success",
+            "schema": {
+              "$ref": "#/definitions/IpamToken"
+            }
+          },
+          "429": {
+            "description": "Too many requests waiting for tokens",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            },
+            "x-go-name": "TooManyRequests"
+          }
+        }
+      },
+      "delete": {
+        "description": "Return the token of the cluster-wide budget of concurrent IP allocations\n",
+        "tags": [
+          "controller"
+        ],
+        "summary": "Return allocation token",
+        "parameters": [
+          {
+            "type": "string",
+            "description": "the token to return",
+            "name": "token",
+            "in": "query",
+            "required": true
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success"
+          }
+        }
+      }
+    },
     "/runtime/liveness": {
       "get": {
         "description": "Check pod liveness probe",
@@ -260,6 +1068,288 @@ func init() {
       }
     }
   },
+  "definitions": {
+    "Error": {
+      "description": "API error",
+      "type": "string"
+    },
+    "IpamAudit": {
+      "description": "Report of the consistency audit of the IP allocations",
+      "type": "object",
+      "properties": {
+        "endpoints": {
+          "description": "the count of the audited SpiderEndpoints",
+          "type": "integer"
+        },
+        "ipPools": {
+          "description": "the count of the audited IPPools",
+          "type": "integer"
+        },
+        "items": {
+          "description": "the mismatches found, none if the IP allocations are consistent",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/IpamAuditItem"
+          }
+        },
+        "pods": {
+          "description": "the count of the audited Pods",
+          "type": "integer"
+        }
+      }
+    },
+    "IpamAuditItem": {
+      "description": "A mismatch among the IPPools, SpiderEndpoints and Pods",
+      "type": "object",
+      "properties": {
+        "category": {
+          "description": "the category of the mismatch, AllocatedWithoutEndpoint, AllocatedWithoutPod, EndpointWithoutPoolRecord, EndpointWithoutPod or DoubleAllocation",
+          "type": "string"
+        },
+        "containerID": {
+          "type": "string"
+        },
+        "ip": {
+          "type": "string"
+        },
+        "message": {
+          "description": "the detail of the mismatch",
+          "type": "string"
+        },
+        "namespace": {
+          "type": "string"
+        },
+        "pod": {
+          "type": "string"
+        },
+        "pool": {
+          "type": "string"
+        }
+      }
+    },
+    "IpamEndpoint": {
+      "description": "The Pod which an IP address is allocated to",
+      "type": "object",
+      "properties": {
+        "containerID": {
+          "type": "string"
+        },
+        "ip": {
+          "description": "the IP address with the prefix length",
+          "type": "string"
+        },
+        "namespace": {
+          "type": "string"
+        },
+        "nic": {
+          "type": "string"
+        },
+        "node": {
+          "type": "string"
+        },
+        "ownerControllerName": {
+          "type": "string"
+        },
+        "ownerControllerType": {
+          "type": "string"
+        },
+        "pod": {
+          "type": "string"
+        },
+        "pool": {
+          "type": "string"
+        },
+        "uid": {
+          "description": "the UID of the Pod, it is empty for the Pods of StatefulSets",
+          "type": "string"
+        }
+      }
+    },
+    "IpamPreview": {
+      "description": "Whether the IP allocation of a Pod would succeed",
+      "type": "object",
+      "properties": {
+        "allowed": {
+          "description": "whether the IP allocation would succeed",
+          "type": "boolean"
+        },
+        "messages": {
+          "description": "why the IP allocation would fail, or what is not previewed",
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
+        "pools": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/IpamPreviewPool"
+          }
+        }
+      }
+    },
+    "IpamPreviewPool": {
+      "description": "Whether an IPPool candidate could allocate IP addresses to the Pod",
+      "type": "object",
+      "properties": {
+        "filter": {
+          "description": "the filter eliminating the IPPool",
+          "type": "string"
+        },
+        "ipVersion": {
+          "type": "integer"
+        },
+        "nic": {
+          "type": "string"
+        },
+        "pool": {
+          "type": "string"
+        },
+        "reason": {
+          "description": "why the IPPool is not usable",
+          "type": "string"
+        },
+        "usable": {
+          "description": "whether the IPPool is usable regardless of the node affinity",
+          "type": "boolean"
+        }
+      }
+    },
+    "IpamStats": {
+      "description": "Statistics of IP allocations and releases over a time window",
+      "type": "object",
+      "properties": {
+        "items": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/IpamStatsItem"
+          }
+        },
+        "window": {
+          "description": "the time window",
+          "type": "string"
+        }
+      }
+    },
+    "IpamStatsItem": {
+      "description": "Statistics of IP allocations and releases of an IPPool in a Namespace",
+      "type": "object",
+      "properties": {
+        "allocationRate": {
+          "description": "the average count of IP allocations per minute",
+          "type": "number"
+        },
+        "allocations": {
+          "description": "the count of IP allocations",
+          "type": "integer"
+        },
+        "namespace": {
+          "type": "string"
+        },
+        "pool": {
+          "type": "string"
+        },
+        "releaseRate": {
+          "description": "the average count of IP releases per minute",
+          "type": "number"
+        },
+        "releases": {
+          "description": "the count of IP releases",
+          "type": "integer"
+        }
+      }
+    },
+    "IpamToken": {
+      "description": "Token of the cluster-wide budget of concurrent IP allocations",
+      "type": "object",
+      "properties": {
+        "token": {
+          "type": "string"
+        }
+      }
+    },
+    "ReservedIPImport": {
+      "description": "Reserved IP addresses to import",
+      "type": "object",
+      "required": [
+        "records"
+      ],
+      "properties": {
+        "records": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/ReservedIPRecord"
+          }
+        },
+        "source": {
+          "description": "the source of the reserved IP addresses, which the SpiderReservedIPs are labeled with and named after",
+          "type": "string"
+        }
+      }
+    },
+    "ReservedIPImportFailure": {
+      "description": "A reserved IP address failed to import",
+      "type": "object",
+      "properties": {
+        "error": {
+          "type": "string"
+        },
+        "ip": {
+          "type": "string"
+        },
+        "name": {
+          "type": "string"
+        }
+      }
+    },
+    "ReservedIPImportResult": {
+      "description": "The result of importing reserved IP addresses",
+      "type": "object",
+      "properties": {
+        "created": {
+          "description": "the count of the created SpiderReservedIPs",
+          "type": "integer"
+        },
+        "failures": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/ReservedIPImportFailure"
+          }
+        },
+        "unchanged": {
+          "description": "the count of the SpiderReservedIPs already up to date",
+          "type": "integer"
+        },
+        "updated": {
+          "description": "the count of the updated SpiderReservedIPs",
+          "type": "integer"
+        }
+      }
+    },
+    "ReservedIPRecord": {
+      "description": "A reserved IP address with its metadata",
+      "type": "object",
+      "required": [
+        "ip"
+      ],
+      "properties": {
+        "description": {
+          "type": "string"
+        },
+        "ip": {
+          "description": "the IP address or IP range",
+          "type": "string"
+        },
+        "name": {
+          "description": "the name of the SpiderReservedIP, it is generated from the source and the IP address if not set",
+          "type": "string"
+        },
+        "owner": {
+          "type": "string"
+        }
+      }
+    }
+  },
   "x-schemes": [
     "http"
   ]
@@ -48,6 +48,18 @@ func NewSpiderpoolControllerAPIAPI(spec *loads.Document) *SpiderpoolControllerAP
 
 		JSONProducer: runtime.JSONProducer(),
 
+		ControllerDeleteIpamTokenHandler: controller.DeleteIpamTokenHandlerFunc(func(params controller.DeleteIpamTokenParams) middleware.Responder {
+			return middleware.NotImplemented("operation controller.DeleteIpamToken has not yet been implemented")
+		}),
+		ControllerGetIpamAuditHandler: controller.GetIpamAuditHandlerFunc(func(params controller.GetIpamAuditParams) middleware.Responder {
+			return middleware.NotImplemented("operation controller.GetIpamAudit has not yet been implemented")
+		}),
+		ControllerGetIpamEndpointHandler: controller.GetIpamEndpointHandlerFunc(func(params controller.GetIpamEndpointParams) middleware.Responder {
+			return middleware.NotImplemented("operation controller.GetIpamEndpoint has not yet been implemented")
+		}),
+		ControllerGetIpamStatsHandler: controller.GetIpamStatsHandlerFunc(func(params controller.GetIpamStatsParams) middleware.Responder {
+			return middleware.NotImplemented("operation controller.GetIpamStats has not yet been implemented")
+		}),
 		ControllerGetIpamStatusHandler: controller.GetIpamStatusHandlerFunc(func(params controller.GetIpamStatusParams) middleware.Responder {
 			return middleware.NotImplemented("operation controller.GetIpamStatus has not yet been implemented")
 		}),
@@ -63,6 +75,15 @@ func NewSpiderpoolControllerAPIAPI(spec *loads.Document) *SpiderpoolControllerAP
 		ControllerPostIpamGcIpsHandler: controller.PostIpamGcIpsHandlerFunc(func(params controller.PostIpamGcIpsParams) middleware.Responder {
 			return middleware.NotImplemented("operation controller.PostIpamGcIps has not yet been implemented")
 		}),
+		ControllerPostIpamPreviewHandler: controller.PostIpamPreviewHandlerFunc(func(params controller.PostIpamPreviewParams) middleware.Responder {
+			return middleware.NotImplemented("operation controller.PostIpamPreview has not yet been implemented")
+		}),
+		ControllerPostIpamReservedipsHandler: controller.PostIpamReservedipsHandlerFunc(func(params controller.PostIpamReservedipsParams) middleware.Responder {
+			return middleware.NotImplemented("operation controller.PostIpamReservedips has not yet been implemented")
+		}),
+		ControllerPostIpamTokenHandler: controller.PostIpamTokenHandlerFunc(func(params controller.PostIpamTokenParams) middleware.Responder {
+			return middleware.NotImplemented("operation controller.PostIpamToken has not yet been implemented")
+		}),
 		ControllerPutIpamIPHandler: controller.PutIpamIPHandlerFunc(func(params controller.PutIpamIPParams) middleware.Responder {
 			return middleware.NotImplemented("operation controller.PutIpamIP has not yet been implemented")
 		}),
@@ -102,6 +123,14 @@ type SpiderpoolControllerAPIAPI struct {
 	//   - application/json
 	JSONProducer runtime.Producer
 
+	// ControllerDeleteIpamTokenHandler sets the operation handler for the delete ipam token operation
+	ControllerDeleteIpamTokenHandler controller.DeleteIpamTokenHandler
+	// ControllerGetIpamAuditHandler sets the operation handler for the get ipam audit operation
+	ControllerGetIpamAuditHandler controller.GetIpamAuditHandler
+	// ControllerGetIpamEndpointHandler sets the operation handler for the get ipam endpoint operation
+	ControllerGetIpamEndpointHandler controller.GetIpamEndpointHandler
+	// ControllerGetIpamStatsHandler sets the operation handler for the get ipam stats operation
+	ControllerGetIpamStatsHandler controller.GetIpamStatsHandler
 	// ControllerGetIpamStatusHandler sets the operation handler for the get ipam status operation
 	ControllerGetIpamStatusHandler controller.GetIpamStatusHandler
 	// RuntimeGetRuntimeLivenessHandler sets the operation handler for the get runtime liveness operation
@@ -112,6 +141,12 @@ type SpiderpoolControllerAPIAPI struct {
 	RuntimeGetRuntimeStartupHandler runtimeops.GetRuntimeStartupHandler
 	// ControllerPostIpamGcIpsHandler sets the operation handler for the post ipam gc ips operation
 	ControllerPostIpamGcIpsHandler controller.PostIpamGcIpsHandler
+	// ControllerPostIpamPreviewHandler sets the operation handler for the post ipam preview operation
+	ControllerPostIpamPreviewHandler controller.PostIpamPreviewHandler
+	// ControllerPostIpamReservedipsHandler sets the operation handler for the post ipam reservedips operation
+	ControllerPostIpamReservedipsHandler controller.PostIpamReservedipsHandler
+	// ControllerPostIpamTokenHandler sets the operation handler for the post ipam token operation
+	ControllerPostIpamTokenHandler controller.PostIpamTokenHandler
 	// ControllerPutIpamIPHandler sets the operation handler for the put ipam IP operation
 	ControllerPutIpamIPHandler controller.PutIpamIPHandler
 
@@ -191,6 +226,18 @@ func (o *SpiderpoolControllerAPIAPI) Validate() error {
 		unregistered = append(unregistered, "JSONProducer")
 	}
 
+	if o.ControllerDeleteIpamTokenHandler == nil {
+		unregistered = append(unregistered, "controller.DeleteIpamTokenHandler")
+	}
+	if o.ControllerGetIpamAuditHandler == nil {
+		unregistered = append(unregistered, "controller.GetIpamAuditHandler")
+	}
+	if o.ControllerGetIpamEndpointHandler == nil {
+		unregistered = append(unregistered, "controller.GetIpamEndpointHandler")
+	}
+	if o.ControllerGetIpamStatsHandler == nil {
+		unregistered = append(unregistered, "controller.GetIpamStatsHandler")
+	}
 	if o.ControllerGetIpamStatusHandler == nil {
 		unregistered = append(unregistered, "controller.GetIpamStatusHandler")
 	}
@@ -206,6 +253,15 @@ func (o *SpiderpoolControllerAPIAPI) Validate() error {
 	if o.ControllerPostIpamGcIpsHandler == nil {
 		unregistered = append(unregistered, "controller.PostIpamGcIpsHandler")
 	}
+	if o.ControllerPostIpamPreviewHandler == nil {
+		unregistered = append(unregistered, "controller.PostIpamPreviewHandler")
+	}
+	if o.ControllerPostIpamReservedipsHandler == nil {
+		unregistered = append(unregistered, "controller.PostIpamReservedipsHandler")
+	}
+	if o.ControllerPostIpamTokenHandler == nil {
+		unregistered = append(unregistered, "controller.PostIpamTokenHandler")
+	}
 	if o.ControllerPutIpamIPHandler == nil {
 		unregistered = append(unregistered, "controller.PutIpamIPHandler")
 	}
@@ -297,6 +353,22 @@ func (o *SpiderpoolControllerAPIAPI) initHandlerCache() {
 		o.handlers = make(map[string]map[string]http.Handler)
 	}
 
+	if o.handlers["DELETE"] == nil {
+		o.handlers["DELETE"] = make(map[string]http.Handler)
+	}
+	o.handlers["DELETE"]["/ipam/token"] = controller.NewDeleteIpamToken(o.context, o.ControllerDeleteIpamTokenHandler)
+	if o.handlers["GET"] == nil {
+		o.handlers["GET"] = make(map[string]http.Handler)
+	}
+	o.handlers["GET"]["/ipam/audit"] = controller.NewGetIpamAudit(o.context, o.ControllerGetIpamAuditHandler)
+	if o.handlers["GET"] == nil {
+		o.handlers["GET"] = make(map[string]http.Handler)
+	}
+	o.handlers["GET"]["/ipam/endpoint"] = controller.NewGetIpamEndpoint(o.context, o.ControllerGetIpamEndpointHandler)
+	if o.handlers["GET"] == nil {
+		o.handlers["GET"] = make(map[string]http.Handler)
+	}
+	o.handlers["GET"]["/ipam/stats"] = controller.NewGetIpamStats(o.context, o.ControllerGetIpamStatsHandler)
 	if o.handlers["GET"] == nil {
 		o.handlers["GET"] = make(map[string]http.Handler)
 	}
@@ -317,6 +389,18 @@ func (o *SpiderpoolControllerAPIAPI) initHandlerCache() {
 		o.handlers["POST"] = make(map[string]http.Handler)
 	}
 	o.handlers["POST"]["/ipam/gc_ips"] = controller.NewPostIpamGcIps(o.context, o.ControllerPostIpamGcIpsHandler)
+	if o.handlers["POST"] == nil {
+		o.handlers["POST"] = make(map[string]http.Handler)
+	}
+	o.handlers["POST"]["/ipam/preview"] = controller.NewPostIpamPreview(o.context, o.ControllerPostIpamPreviewHandler)
+	if o.handlers["POST"] == nil {
+		o.handlers["POST"] = make(map[string]http.Handler)
+	}
+	o.handlers["POST"]["/ipam/reservedips"] = controller.NewPostIpamReservedips(o.context, o.ControllerPostIpamReservedipsHandler)
+	if o.handlers["POST"] == nil {
+		o.handlers["POST"] = make(map[string]http.Handler)
+	}
+	o.handlers["POST"]["/ipam/token"] = controller.NewPostIpamToken(o.context, o.ControllerPostIpamTokenHandler)
 	if o.handlers["PUT"] == nil {
 		o.handlers["PUT"] = make(map[string]http.Handler)
 	}
@@ -0,0 +1,85 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/runtime"
+	runtime_client "github.com/go-openapi/runtime/client"
+	"github.com/go-openapi/strfmt"
+
+	controllerOpenAPIClient "github.com/spidernet-io/spiderpool/api/v1/controller/client"
+	"github.com/spidernet-io/spiderpool/api/v1/controller/client/controller"
+	"github.com/spidernet-io/spiderpool/pkg/limiter"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+// controllerTokenIssuer acquires the tokens of the cluster-wide budget of
+// concurrent IP allocations from spiderpool-controller. If the controller
+// is unreachable, the allocations go on without tokens rather than fail,
+// so that the budget never blocks the cluster on its own. The requests are
+// authenticated with the ServiceAccount token of spiderpool-agent, from
+// which spiderpool-controller learns the node of the caller.
+type controllerTokenIssuer struct {
+	client  *controllerOpenAPIClient.SpiderpoolControllerAPI
+	timeout time.Duration
+}
+
+func newControllerTokenIssuer(server string, timeout time.Duration) limiter.TokenIssuer {
+	transport := runtime_client.New(server, controllerOpenAPIClient.DefaultBasePath, controllerOpenAPIClient.DefaultSchemes)
+	transport.DefaultAuthentication = runtime.ClientAuthInfoWriterFunc(authenticateServiceAccount)
+
+	return &controllerTokenIssuer{
+		client:  controllerOpenAPIClient.New(transport, strfmt.Default),
+		timeout: timeout,
+	}
+}
+
+func (c *controllerTokenIssuer) AcquireToken(ctx context.Context, node string) (string, error) {
+	params := controller.NewPostIpamTokenParamsWithTimeout(c.timeout).WithContext(ctx).WithNode(node)
+	resp, err := c.client.Controller.PostIpamToken(params)
+	if err != nil {
+		var tooManyRequests *controller.PostIpamTokenTooManyRequests
+		if errors.As(err, &tooManyRequests) {
+			return "", fmt.Errorf("%s", tooManyRequests.Payload)
+		}
+
+		logutils.FromContext(ctx).Sugar().Warnf("Failed to acquire the token of cluster-wide allocations, go on without it: %v", err)
+		return "", nil
+	}
+
+	return resp.Payload.Token, nil
+}
+
+func (c *controllerTokenIssuer) ReturnToken(ctx context.Context, token string) {
+	if token == "" {
+		return
+	}
+
+	// The token is returned even if the allocation is canceled.
+	params := controller.NewDeleteIpamTokenParamsWithTimeout(c.timeout).WithContext(context.Background()).WithToken(token)
+	if _, err := c.client.Controller.DeleteIpamToken(params); err != nil {
+		logutils.FromContext(ctx).Sugar().Warnf("Failed to return the token of cluster-wide allocations, it will be reclaimed on expiration: %v", err)
+	}
+}
+
+const serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// authenticateServiceAccount sets the ServiceAccount token as the bearer
+// token of the request. The token is read on each request, for it's rotated
+// by kubelet.
+func authenticateServiceAccount(req runtime.ClientRequest, _ strfmt.Registry) error {
+	token, err := os.ReadFile(serviceAccountTokenPath)
+	if err != nil {
+		return fmt.Errorf("failed to read the ServiceAccount token: %v", err)
+	}
+
+	return req.SetHeaderParam(runtime.HeaderAuthorization, "Bearer "+strings.TrimSpace(string(token)))
+}
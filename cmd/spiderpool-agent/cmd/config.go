@@ -27,6 +27,7 @@ import (
 	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
 	"github.com/spidernet-io/spiderpool/pkg/statefulsetmanager"
 	"github.com/spidernet-io/spiderpool/pkg/subnetmanager"
+	"github.com/spidernet-io/spiderpool/pkg/types"
 	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
 )
 
@@ -49,17 +50,46 @@ type envConf struct {
 var envInfo = []envConf{
 	{"SPIDERPOOL_LOG_LEVEL", constant.LogInfoLevelStr, true, &agentContext.Cfg.LogLevel, nil, nil},
 	{"SPIDERPOOL_ENABLED_METRIC", "false", false, nil, &agentContext.Cfg.EnabledMetric, nil},
+	{"SPIDERPOOL_LOG_SUMMARIZE_THRESHOLD", "20", false, nil, nil, &agentContext.Cfg.LogSummarizeThreshold},
+	{"SPIDERPOOL_LOG_REDACT_ANNOTATIONS", "false", false, nil, &agentContext.Cfg.LogRedactAnnotations, nil},
 	{"SPIDERPOOL_HEALTH_PORT", "5710", true, &agentContext.Cfg.HttpPort, nil, nil},
 	{"SPIDERPOOL_METRIC_HTTP_PORT", "5711", true, &agentContext.Cfg.MetricHttpPort, nil, nil},
 	{"SPIDERPOOL_UPDATE_CR_MAX_RETRIES", "4", false, nil, nil, &agentContext.Cfg.UpdateCRMaxRetries},
 	{"SPIDERPOOL_UPDATE_CR_RETRY_UNIT_TIME", "50", false, nil, nil, &agentContext.Cfg.UpdateCRRetryUnitTime},
 	{"SPIDERPOOL_WORKLOADENDPOINT_MAX_HISTORY_RECORDS", "100", true, nil, nil, &agentContext.Cfg.WorkloadEndpointMaxHistoryRecords},
+	{"SPIDERPOOL_WORKLOADENDPOINT_MAX_HISTORY_AGE_IN_HOUR", "0", false, nil, nil, &agentContext.Cfg.WorkloadEndpointMaxHistoryAge},
+	{"SPIDERPOOL_WORKLOADENDPOINT_CACHE_ENABLED", "false", false, nil, &agentContext.Cfg.EnableWorkloadEndpointCache, nil},
 	{"SPIDERPOOL_IPPOOL_MAX_ALLOCATED_IPS", "5000", true, nil, nil, &agentContext.Cfg.IPPoolMaxAllocatedIPs},
+	{"SPIDERPOOL_IPPOOL_MAX_RELEASE_PARALLELISM", "4", false, nil, nil, &agentContext.Cfg.IPPoolMaxReleaseParallelism},
 	{"SPIDERPOOL_GOPS_LISTEN_PORT", "5712", false, &agentContext.Cfg.GopsListenPort, nil, nil},
 	{"SPIDERPOOL_PYROSCOPE_PUSH_SERVER_ADDRESS", "", false, &agentContext.Cfg.PyroscopeAddress, nil, nil},
 	{"SPIDERPOOL_LIMITER_MAX_QUEUE_SIZE", "1000", true, nil, nil, &agentContext.Cfg.LimiterMaxQueueSize},
 	{"SPIDERPOOL_ENABLED_STATEFULSET", "true", true, nil, &agentContext.Cfg.EnableStatefulSet, nil},
 	{"SPIDERPOOL_WAIT_SUBNET_POOL_TIME_IN_SECOND", "2", false, nil, nil, &agentContext.Cfg.WaitSubnetPoolTime},
+	{"SPIDERPOOL_WAIT_SUBNET_POOL_TIMEOUT_IN_SECOND", "0", false, nil, nil, &agentContext.Cfg.WaitSubnetPoolTimeout},
+	{"SPIDERPOOL_RELEASE_JOURNAL_PATH", "/var/run/spidernet/release-journal.json", false, &agentContext.Cfg.ReleaseJournalPath, nil, nil},
+	{"SPIDERPOOL_RELEASE_JOURNAL_REPLAY_TIME_IN_SECOND", "10", false, nil, nil, &agentContext.Cfg.ReleaseJournalReplayTime},
+	{"SPIDERPOOL_RELEASE_JOURNAL_MAX_BACKOFF_IN_SECOND", "300", false, nil, nil, &agentContext.Cfg.ReleaseJournalMaxBackoff},
+	{"SPIDERPOOL_RELEASE_JOURNAL_MAX_SIZE", "1000", false, nil, nil, &agentContext.Cfg.ReleaseJournalMaxSize},
+	{"SPIDERPOOL_IPPOOL_QUARANTINE_FAILURE_THRESHOLD", "0", false, nil, nil, &agentContext.Cfg.IPPoolQuarantineFailureThreshold},
+	{"SPIDERPOOL_IPPOOL_QUARANTINE_FAILURE_WINDOW_IN_SECOND", "60", false, nil, nil, &agentContext.Cfg.IPPoolQuarantineFailureWindow},
+	{"SPIDERPOOL_NODE_NAME", "", false, &agentContext.Cfg.NodeName, nil, nil},
+	{"SPIDERPOOL_SANDBOX_STATE_DIR", "", false, &agentContext.Cfg.SandboxStateDir, nil, nil},
+	{"SPIDERPOOL_CRI_SOCKET_PATH", "", false, &agentContext.Cfg.CRISocketPath, nil, nil},
+	{"SPIDERPOOL_CRI_TIMEOUT_IN_SECOND", "2", false, nil, nil, &agentContext.Cfg.CRITimeout},
+	{"SPIDERPOOL_IP_LEASE_RENEW_INTERVAL_IN_SECOND", "0", false, nil, nil, &agentContext.Cfg.IPLeaseRenewInterval},
+	{"SPIDERPOOL_GATEWAY_PROBE_TIMEOUT_IN_MILLISECOND", "0", false, nil, nil, &agentContext.Cfg.GatewayProbeTimeout},
+	{"SPIDERPOOL_IP_CONFLICT_PROBE_TIMEOUT_IN_MILLISECOND", "0", false, nil, nil, &agentContext.Cfg.IPConflictProbeTimeout},
+	{"SPIDERPOOL_RELEASE_DEFERRAL_IN_SECOND", "0", false, nil, nil, &agentContext.Cfg.ReleaseDeferralTime},
+	{"SPIDERPOOL_GATEWAY_UNREACHABLE_REPORT_ENABLED", "false", false, nil, &agentContext.Cfg.ReportGatewayUnreachable, nil},
+	{"SPIDERPOOL_GATEWAY_UNREACHABLE_IPPOOL_SKIPPED", "false", false, nil, &agentContext.Cfg.SkipGatewayUnreachableIPPools, nil},
+	{"SPIDERPOOL_NETWORK_SCAN_INTERVAL_IN_SECOND", "0", false, nil, nil, &agentContext.Cfg.NetworkScanInterval},
+	{"SPIDERPOOL_NETWORK_SCAN_PROBE_RATE", "20", false, nil, nil, &agentContext.Cfg.NetworkScanProbeRate},
+	{"SPIDERPOOL_NETWORK_SCAN_PROBE_TIMEOUT_IN_MILLISECOND", "1000", false, nil, nil, &agentContext.Cfg.NetworkScanProbeTimeout},
+	{"SPIDERPOOL_NODE_READINESS_TAINT_ENABLED", "false", false, nil, &agentContext.Cfg.NodeReadinessTaintEnabled, nil},
+	{"SPIDERPOOL_NODE_READINESS_CHECK_INTERVAL_IN_SECOND", "5", false, nil, nil, &agentContext.Cfg.NodeReadinessCheckInterval},
+	{"SPIDERPOOL_ALLOCATION_TOKEN_SERVER", "", false, &agentContext.Cfg.AllocationTokenServer, nil, nil},
+	{"SPIDERPOOL_ALLOCATION_TOKEN_TIMEOUT_IN_SECOND", "35", false, nil, nil, &agentContext.Cfg.AllocationTokenTimeout},
 	{"GOLANG_ENV_MAXPROCS", "8", false, nil, nil, &agentContext.Cfg.GoMaxProcs},
 	{"GIT_COMMIT_VERSION", "", false, &agentContext.Cfg.CommitVersion, nil, nil},
 	{"GIT_COMMIT_TIME", "", false, &agentContext.Cfg.CommitTime, nil, nil},
@@ -75,8 +105,10 @@ type Config struct {
 	ConfigPath string
 
 	// env
-	LogLevel      string
-	EnabledMetric bool
+	LogLevel              string
+	LogSummarizeThreshold int
+	LogRedactAnnotations  bool
+	EnabledMetric         bool
 
 	HttpPort         string
 	MetricHttpPort   string
@@ -86,8 +118,35 @@ type Config struct {
 	UpdateCRMaxRetries                int
 	UpdateCRRetryUnitTime             int
 	WorkloadEndpointMaxHistoryRecords int
+	WorkloadEndpointMaxHistoryAge     int
+	EnableWorkloadEndpointCache       bool
 	IPPoolMaxAllocatedIPs             int
+	IPPoolMaxReleaseParallelism       int
 	WaitSubnetPoolTime                int
+	WaitSubnetPoolTimeout             int
+	ReleaseJournalPath                string
+	ReleaseJournalReplayTime          int
+	ReleaseJournalMaxBackoff          int
+	ReleaseJournalMaxSize             int
+	IPPoolQuarantineFailureThreshold  int
+	IPPoolQuarantineFailureWindow     int
+	NodeName                          string
+	SandboxStateDir                   string
+	CRISocketPath                     string
+	CRITimeout                        int
+	IPLeaseRenewInterval              int
+	GatewayProbeTimeout               int
+	IPConflictProbeTimeout            int
+	ReleaseDeferralTime               int
+	ReportGatewayUnreachable          bool
+	SkipGatewayUnreachableIPPools     bool
+	NetworkScanInterval               int
+	NetworkScanProbeRate              int
+	NetworkScanProbeTimeout           int
+	NodeReadinessTaintEnabled         bool
+	NodeReadinessCheckInterval        int
+	AllocationTokenServer             string
+	AllocationTokenTimeout            int
 
 	LimiterMaxQueueSize int
 
@@ -103,10 +162,26 @@ type Config struct {
 	EnableStatefulSet                 bool     `yaml:"enableStatefulSet"`
 	EnableSpiderSubnet                bool     `yaml:"enableSpiderSubnet"`
 	ClusterSubnetDefaultFlexibleIPNum int      `yaml:"clusterSubnetDefaultFlexibleIPNumber"`
+	EnableAnnotatedPoolFallback       bool     `yaml:"enableAnnotatedPoolFallback"`
+	RejectHostNetworkPod              bool     `yaml:"rejectHostNetworkPod"`
+	IPPoolCandidateOrder              string   `yaml:"ippoolCandidateOrder"`
+	MaxIPsPerWorkload                 int      `yaml:"maxIPsPerWorkload"`
+	ReserveSpecialIPs                 bool     `yaml:"reserveSpecialIPs"`
+
+	IPPoolLimiter map[string]IPPoolLimiterConfig `yaml:"ippoolLimiter"`
+
+	SubnetThirdPartyControllers []types.ThirdPartyController `yaml:"subnetThirdPartyControllers"`
 
 	GoMaxProcs int
 }
 
+// IPPoolLimiterConfig overrides the limit of the concurrent IP allocations
+// and releases of an IPPool.
+type IPPoolLimiterConfig struct {
+	MaxConcurrency            int `yaml:"maxConcurrency"`
+	QueueTimeoutInMillisecond int `yaml:"queueTimeoutInMillisecond"`
+}
+
 type AgentContext struct {
 	Cfg Config
 
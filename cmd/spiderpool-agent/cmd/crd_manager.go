@@ -17,6 +17,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
 )
 
 var scheme = runtime.NewScheme()
@@ -48,5 +49,23 @@ func newCRDManager() (ctrl.Manager, error) {
 		return nil, err
 	}
 
+	// The Endpoints are read from the cache on the hot path of IP allocation,
+	// and listed by the node from it in the background if enabled, while all
+	// the other reads of them still go to the API server.
+	if agentContext.Cfg.EnableWorkloadEndpointCache {
+		if err := workloadendpointmanager.RegisterEndpointIndexers(agentContext.InnerCtx, mgr.GetFieldIndexer()); err != nil {
+			return nil, err
+		}
+		if _, err := mgr.GetCache().GetInformer(agentContext.InnerCtx, &spiderpoolv1.SpiderEndpoint{}); err != nil {
+			return nil, err
+		}
+	}
+
 	return mgr, nil
 }
+
+// newIPPoolWatcher creates the client to watch the auto-created IPPools to be
+// ready, which talks to the API server directly like the uncached IPPools.
+func newIPPoolWatcher() (client.WithWatch, error) {
+	return client.NewWithWatch(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+}
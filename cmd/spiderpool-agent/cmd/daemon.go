@@ -16,18 +16,24 @@ import (
 
 	"github.com/google/gops/agent"
 	"github.com/pyroscope-io/client/pyroscope"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/event"
 	"github.com/spidernet-io/spiderpool/pkg/ipam"
 	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
 	"github.com/spidernet-io/spiderpool/pkg/limiter"
 	"github.com/spidernet-io/spiderpool/pkg/logutils"
 	"github.com/spidernet-io/spiderpool/pkg/namespacemanager"
+	"github.com/spidernet-io/spiderpool/pkg/networkscanner"
 	"github.com/spidernet-io/spiderpool/pkg/nodemanager"
 	"github.com/spidernet-io/spiderpool/pkg/podmanager"
 	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
 	"github.com/spidernet-io/spiderpool/pkg/singletons"
 	"github.com/spidernet-io/spiderpool/pkg/statefulsetmanager"
 	"github.com/spidernet-io/spiderpool/pkg/subnetmanager"
+	subnetmanagercontrollers "github.com/spidernet-io/spiderpool/pkg/subnetmanager/controllers"
 	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
 )
 
@@ -43,6 +49,10 @@ func DaemonMain() {
 		panic(fmt.Sprintf("failed to initialize logger with level %s, reason=%v \n", agentContext.Cfg.LogLevel, err))
 	}
 	logger = logutils.Logger.Named(BinNameAgent)
+	spiderpoolv1.SetStringOptions(spiderpoolv1.StringOptions{
+		SummarizeThreshold: agentContext.Cfg.LogSummarizeThreshold,
+		RedactAnnotations:  agentContext.Cfg.LogRedactAnnotations,
+	})
 
 	currentP := runtime.GOMAXPROCS(-1)
 	logger.Sugar().Infof("default max golang procs %v \n", currentP)
@@ -117,6 +127,11 @@ func DaemonMain() {
 		agentContext.Cfg.ClusterSubnetDefaultFlexibleIPNum,
 	)
 
+	if err := subnetmanagercontrollers.ValidateThirdPartyControllers(agentContext.Cfg.SubnetThirdPartyControllers); nil != err {
+		logger.Fatal(err.Error())
+	}
+	singletons.InitThirdPartyControllers(agentContext.Cfg.SubnetThirdPartyControllers)
+
 	agentContext.InnerCtx, agentContext.InnerCancel = context.WithCancel(context.Background())
 	logger.Info("Begin to initialize spiderpool-agent runtime manager")
 	mgr, err := newCRDManager()
@@ -125,21 +140,60 @@ func DaemonMain() {
 	}
 	agentContext.CRDManager = mgr
 
+	logger.Info("Begin to initialize spiderpool-agent event recorder")
+	event.EventRecorder = mgr.GetEventRecorderFor(constant.SpiderpoolAgent)
+
 	// init managers...
 	initAgentServiceManagers(agentContext.InnerCtx)
 
+	var tokenIssuer limiter.TokenIssuer
+	if agentContext.Cfg.AllocationTokenServer != "" {
+		logger.Sugar().Infof("Acquire the tokens of cluster-wide allocations from %s", agentContext.Cfg.AllocationTokenServer)
+		tokenIssuer = newControllerTokenIssuer(agentContext.Cfg.AllocationTokenServer, time.Duration(agentContext.Cfg.AllocationTokenTimeout)*time.Second)
+	}
+
+	var ipPoolWatcher client.WithWatch
+	if agentContext.Cfg.EnableSpiderSubnet {
+		logger.Info("Begin to initialize the watcher of auto-created IPPools")
+		ipPoolWatcher, err = newIPPoolWatcher()
+		if nil != err {
+			logger.Fatal(err.Error())
+		}
+	}
+
 	logger.Info("Begin to initialize IPAM")
 	ipam, err := ipam.NewIPAM(
 		ipam.IPAMConfig{
-			EnableIPv4:               agentContext.Cfg.EnableIPv4,
-			EnableIPv6:               agentContext.Cfg.EnableIPv6,
-			ClusterDefaultIPv4IPPool: agentContext.Cfg.ClusterDefaultIPv4IPPool,
-			ClusterDefaultIPv6IPPool: agentContext.Cfg.ClusterDefaultIPv6IPPool,
-			EnableSpiderSubnet:       agentContext.Cfg.EnableSpiderSubnet,
-			EnableStatefulSet:        agentContext.Cfg.EnableStatefulSet,
-			OperationRetries:         agentContext.Cfg.UpdateCRMaxRetries,
-			OperationGapDuration:     time.Duration(agentContext.Cfg.WaitSubnetPoolTime) * time.Second,
-			LimiterConfig:            limiter.LimiterConfig{MaxQueueSize: &agentContext.Cfg.LimiterMaxQueueSize},
+			EnableIPv4:                    agentContext.Cfg.EnableIPv4,
+			EnableIPv6:                    agentContext.Cfg.EnableIPv6,
+			ClusterDefaultIPv4IPPool:      agentContext.Cfg.ClusterDefaultIPv4IPPool,
+			ClusterDefaultIPv6IPPool:      agentContext.Cfg.ClusterDefaultIPv6IPPool,
+			EnableSpiderSubnet:            agentContext.Cfg.EnableSpiderSubnet,
+			EnableStatefulSet:             agentContext.Cfg.EnableStatefulSet,
+			EnableAnnotatedPoolFallback:   agentContext.Cfg.EnableAnnotatedPoolFallback,
+			RejectHostNetworkPod:          agentContext.Cfg.RejectHostNetworkPod,
+			IPPoolCandidateOrder:          agentContext.Cfg.IPPoolCandidateOrder,
+			OperationRetries:              agentContext.Cfg.UpdateCRMaxRetries,
+			OperationGapDuration:          time.Duration(agentContext.Cfg.WaitSubnetPoolTime) * time.Second,
+			WaitSubnetPoolTimeout:         time.Duration(agentContext.Cfg.WaitSubnetPoolTimeout) * time.Second,
+			LimiterConfig:                 limiter.LimiterConfig{MaxQueueSize: &agentContext.Cfg.LimiterMaxQueueSize, TicketLimits: genIPPoolTicketLimits(agentContext.Cfg.IPPoolLimiter)},
+			ReleaseJournalPath:            agentContext.Cfg.ReleaseJournalPath,
+			ReleaseJournalReplayDuration:  time.Duration(agentContext.Cfg.ReleaseJournalReplayTime) * time.Second,
+			ReleaseJournalMaxBackoff:      time.Duration(agentContext.Cfg.ReleaseJournalMaxBackoff) * time.Second,
+			ReleaseJournalMaxSize:         agentContext.Cfg.ReleaseJournalMaxSize,
+			QuarantineFailureThreshold:    agentContext.Cfg.IPPoolQuarantineFailureThreshold,
+			QuarantineFailureWindow:       time.Duration(agentContext.Cfg.IPPoolQuarantineFailureWindow) * time.Second,
+			MaxIPsPerWorkload:             agentContext.Cfg.MaxIPsPerWorkload,
+			NodeName:                      agentContext.Cfg.NodeName,
+			SandboxStateDir:               agentContext.Cfg.SandboxStateDir,
+			CRISocketPath:                 agentContext.Cfg.CRISocketPath,
+			CRITimeout:                    time.Duration(agentContext.Cfg.CRITimeout) * time.Second,
+			IPLeaseRenewDuration:          time.Duration(agentContext.Cfg.IPLeaseRenewInterval) * time.Second,
+			GatewayProbeTimeout:           time.Duration(agentContext.Cfg.GatewayProbeTimeout) * time.Millisecond,
+			IPConflictProbeTimeout:        time.Duration(agentContext.Cfg.IPConflictProbeTimeout) * time.Millisecond,
+			ReleaseDeferralDuration:       time.Duration(agentContext.Cfg.ReleaseDeferralTime) * time.Second,
+			ReportGatewayUnreachable:      agentContext.Cfg.ReportGatewayUnreachable,
+			SkipGatewayUnreachableIPPools: agentContext.Cfg.SkipGatewayUnreachableIPPools,
 		},
 		agentContext.IPPoolManager,
 		agentContext.EndpointManager,
@@ -148,6 +202,8 @@ func DaemonMain() {
 		agentContext.PodManager,
 		agentContext.StsManager,
 		agentContext.SubnetManager,
+		ipPoolWatcher,
+		tokenIssuer,
 	)
 	if nil != err {
 		logger.Fatal(err.Error())
@@ -212,6 +268,16 @@ func DaemonMain() {
 	}
 	agentContext.unixClient = spiderpoolAgentAPI
 
+	if agentContext.Cfg.NodeReadinessTaintEnabled {
+		logger.Info("Begin to run the readiness gate of the Node")
+		go runNodeReadinessGate(agentContext.InnerCtx, time.Duration(agentContext.Cfg.NodeReadinessCheckInterval)*time.Second)
+	}
+
+	if agentContext.Cfg.NetworkScanInterval > 0 {
+		logger.Info("Begin to scan the free IP addresses of IPPools on the network")
+		initNetworkScanner(agentContext.InnerCtx)
+	}
+
 	// TODO (Icarus9913): improve k8s StartupProbe
 	logger.Info("Set spiderpool-agent startup probe ready")
 	agentContext.IsStartupProbe.Store(true)
@@ -294,6 +360,8 @@ func initAgentServiceManagers(ctx context.Context) {
 			MaxConflictRetries:    agentContext.Cfg.UpdateCRMaxRetries,
 			ConflictRetryUnitTime: time.Duration(agentContext.Cfg.UpdateCRRetryUnitTime) * time.Millisecond,
 			MaxHistoryRecords:     &agentContext.Cfg.WorkloadEndpointMaxHistoryRecords,
+			MaxHistoryAge:         time.Duration(agentContext.Cfg.WorkloadEndpointMaxHistoryAge) * time.Hour,
+			CachedReader:          endpointCachedReader(),
 		},
 		agentContext.CRDManager.GetClient(),
 	)
@@ -315,6 +383,8 @@ func initAgentServiceManagers(ctx context.Context) {
 			MaxConflictRetries:    agentContext.Cfg.UpdateCRMaxRetries,
 			ConflictRetryUnitTime: time.Duration(agentContext.Cfg.UpdateCRRetryUnitTime) * time.Millisecond,
 			MaxAllocatedIPs:       &agentContext.Cfg.IPPoolMaxAllocatedIPs,
+			MaxReleaseParallelism: agentContext.Cfg.IPPoolMaxReleaseParallelism,
+			ReserveSpecialIPs:     agentContext.Cfg.ReserveSpecialIPs,
 		},
 		agentContext.CRDManager.GetClient(),
 		agentContext.RIPManager,
@@ -322,6 +392,13 @@ func initAgentServiceManagers(ctx context.Context) {
 	if err != nil {
 		logger.Fatal(err.Error())
 	}
+	// The IPPools are read from the API server when allocating, so that the
+	// status is updated against the latest resourceVersion, and no IPPool
+	// informer is kept on every node. The free IP cache only follows the
+	// SpiderReservedIPs, which are cached by the CRD manager anyway.
+	if err := ipPoolManager.SetupFreeIPCacheInformers(ctx, agentContext.CRDManager.GetCache()); err != nil {
+		logger.Fatal(err.Error())
+	}
 	agentContext.IPPoolManager = ipPoolManager
 
 	if agentContext.Cfg.EnableSpiderSubnet {
@@ -343,3 +420,54 @@ func initAgentServiceManagers(ctx context.Context) {
 		logger.Info("Feature SpiderSubnet is disabled")
 	}
 }
+
+// genIPPoolTicketLimits converts the limiter overrides of IPPools in the
+// configmap to the limits of the tickets, which are the names of IPPools.
+func genIPPoolTicketLimits(ipPoolLimiter map[string]IPPoolLimiterConfig) map[string]limiter.TicketLimit {
+	limits := make(map[string]limiter.TicketLimit, len(ipPoolLimiter))
+	for pool, c := range ipPoolLimiter {
+		limits[pool] = limiter.TicketLimit{
+			MaxConcurrency: c.MaxConcurrency,
+			QueueTimeout:   time.Duration(c.QueueTimeoutInMillisecond) * time.Millisecond,
+		}
+	}
+
+	return limits
+}
+
+// endpointCachedReader returns the cache of the CRD manager if the Endpoints
+// are allowed to be read from it, otherwise nil.
+func endpointCachedReader() client.Reader {
+	if !agentContext.Cfg.EnableWorkloadEndpointCache {
+		return nil
+	}
+
+	return agentContext.CRDManager.GetCache()
+}
+
+// initNetworkScanner starts to probe the free IP addresses of the IPPools
+// attached to the node, and reports the ones in use outside Kubernetes.
+func initNetworkScanner(ctx context.Context) {
+	scanner, err := networkscanner.NewNetworkScanner(
+		networkscanner.NetworkScannerConfig{
+			NodeName:         agentContext.Cfg.NodeName,
+			EnableIPv4:       agentContext.Cfg.EnableIPv4,
+			EnableIPv6:       agentContext.Cfg.EnableIPv6,
+			IntervalDuration: time.Duration(agentContext.Cfg.NetworkScanInterval) * time.Second,
+			ProbeRate:        agentContext.Cfg.NetworkScanProbeRate,
+			ProbeTimeout:     time.Duration(agentContext.Cfg.NetworkScanProbeTimeout) * time.Millisecond,
+		},
+		agentContext.IPPoolManager,
+		agentContext.RIPManager,
+	)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	go func() {
+		scanLogger := logutils.Logger.Named("Network-Scanner")
+		if err := scanner.Start(logutils.IntoContext(ctx, scanLogger)); err != nil {
+			logger.Error(err.Error())
+		}
+	}()
+}
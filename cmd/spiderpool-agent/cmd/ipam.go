@@ -132,6 +132,10 @@ func gatherIPAMAllocationErrMetric(ctx context.Context, err error) {
 		metric.IpamAllocationErrIPUsedOutCounts.Add(ctx, 1)
 		internal = false
 	}
+	if errors.Is(err, constant.ErrWorkloadIPLimitExceeded) {
+		metric.IpamAllocationErrWorkloadIPLimitCounts.Add(ctx, 1)
+		internal = false
+	}
 
 	if internal {
 		metric.IpamAllocationErrInternalCounts.Add(ctx, 1)
@@ -0,0 +1,121 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+const defaultNodeReadinessCheckInterval = 5 * time.Second
+
+// runNodeReadinessGate removes the taint TaintAgentNotReady from the local
+// Node once the IPAM of spiderpool-agent is functional, so that no Pod is
+// scheduled to the Node before the agent can actually allocate IP addresses
+// for it. It retries at each interval until succeeded.
+func runNodeReadinessGate(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultNodeReadinessCheckInterval
+	}
+
+	logger := logutils.Logger.Named("Node-Readiness-Gate")
+	ctx = logutils.IntoContext(ctx, logger)
+
+	for {
+		err := checkIPAMFunctional(ctx)
+		if err == nil {
+			err = agentContext.NodeManager.RemoveNodeTaint(ctx, agentContext.Cfg.NodeName, constant.TaintAgentNotReady)
+			if err == nil {
+				logger.Sugar().Infof("IPAM is functional, remove taint %s from Node %s", constant.TaintAgentNotReady, agentContext.Cfg.NodeName)
+				return
+			}
+		}
+		logger.Sugar().Warnf("Node is not ready for IPAM, retry in %s: %v", interval, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// checkIPAMFunctional checks that the informers are synced, the cluster
+// default IPPools of each enabled IP version are resolvable, and a canary IP
+// address could be allocated from and released to one of them.
+func checkIPAMFunctional(ctx context.Context) error {
+	if !agentContext.CRDManager.GetCache().WaitForCacheSync(ctx) {
+		return fmt.Errorf("informer caches are not synced")
+	}
+
+	var versions []types.IPVersion
+	var defaultPools [][]string
+	if agentContext.Cfg.EnableIPv4 && len(agentContext.Cfg.ClusterDefaultIPv4IPPool) != 0 {
+		versions = append(versions, constant.IPv4)
+		defaultPools = append(defaultPools, agentContext.Cfg.ClusterDefaultIPv4IPPool)
+	}
+	if agentContext.Cfg.EnableIPv6 && len(agentContext.Cfg.ClusterDefaultIPv6IPPool) != 0 {
+		versions = append(versions, constant.IPv6)
+		defaultPools = append(defaultPools, agentContext.Cfg.ClusterDefaultIPv6IPPool)
+	}
+
+	for i, pools := range defaultPools {
+		var errs []string
+		allocated := false
+		for _, pool := range pools {
+			if err := canaryAllocate(ctx, pool); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", pool, err))
+				continue
+			}
+			allocated = true
+			break
+		}
+		if !allocated {
+			return fmt.Errorf("failed to allocate canary IPv%d address from the cluster default IPPools: %s", versions[i], strings.Join(errs, "; "))
+		}
+	}
+
+	return nil
+}
+
+// canaryAllocate allocates an IP address from the IPPool to a canary Pod
+// which does not exist, then releases it immediately. The allocation left
+// by a failed release is reclaimed by the IP GC as the Pod is gone.
+func canaryAllocate(ctx context.Context, poolName string) error {
+	nodeName := agentContext.Cfg.NodeName
+	containerID := "spiderpool-canary-" + string(uuid.NewUUID())
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: metav1.NamespaceSystem,
+			Name:      "spiderpool-agent-canary-" + nodeName,
+			UID:       apitypes.UID(containerID),
+		},
+		Spec: corev1.PodSpec{NodeName: nodeName},
+	}
+	podController := types.PodTopController{
+		Kind:      constant.KindPod,
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		UID:       pod.UID,
+	}
+
+	ipConfig, err := agentContext.IPPoolManager.AllocateIP(ctx, poolName, containerID, constant.ClusterDefaultInterfaceName, pod, podController)
+	if err != nil {
+		return err
+	}
+
+	ip := strings.Split(*ipConfig.Address, "/")[0]
+	return agentContext.IPPoolManager.ReleaseIP(ctx, poolName, []types.IPAndCID{{IP: ip, ContainerID: containerID}})
+}
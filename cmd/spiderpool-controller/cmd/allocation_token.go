@@ -0,0 +1,266 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/runtime"
+	runtime_client "github.com/go-openapi/runtime/client"
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	controllerOpenAPIClient "github.com/spidernet-io/spiderpool/api/v1/controller/client"
+	controllerOpenAPIClientController "github.com/spidernet-io/spiderpool/api/v1/controller/client/controller"
+	"github.com/spidernet-io/spiderpool/api/v1/controller/models"
+	"github.com/spidernet-io/spiderpool/api/v1/controller/server/restapi/controller"
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/limiter"
+	"github.com/spidernet-io/spiderpool/pkg/lock"
+)
+
+// Singleton
+var (
+	httpPostControllerIpamToken   = &_httpPostControllerIpamToken{controllerContext}
+	httpDeleteControllerIpamToken = &_httpDeleteControllerIpamToken{controllerContext}
+)
+
+type _httpPostControllerIpamToken struct {
+	*ControllerContext
+}
+
+// Handle handles POST requests for /ipam/token. An empty token is issued
+// if the cluster-wide budget is disabled. The token is issued to the node
+// of the authenticated spiderpool-agent, rather than the node reported by
+// the request.
+func (h *_httpPostControllerIpamToken) Handle(params controller.PostIpamTokenParams) middleware.Responder {
+	if h.AllocationTokenIssuer == nil {
+		return controller.NewPostIpamTokenOK().WithPayload(&models.IpamToken{})
+	}
+
+	ctx, node, err := h.authenticateAgent(params.HTTPRequest)
+	if err != nil {
+		return authenticationError(err)
+	}
+	if params.Node != node {
+		return middleware.Error(http.StatusForbidden, models.Error(fmt.Sprintf("spiderpool-agent on node %s can't acquire token for node %s", node, params.Node)))
+	}
+
+	if h.Cfg.AllocationTokenQueueTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(h.Cfg.AllocationTokenQueueTimeout)*time.Second)
+		defer cancel()
+	}
+
+	token, err := h.AllocationTokenIssuer.AcquireToken(ctx, node)
+	if err != nil {
+		// The agents go on without tokens if the budget is unavailable.
+		if errors.Is(err, limiter.ErrNoLeader) {
+			return middleware.Error(http.StatusServiceUnavailable, models.Error(err.Error()))
+		}
+		return controller.NewPostIpamTokenTooManyRequests().WithPayload(models.Error(err.Error()))
+	}
+
+	return controller.NewPostIpamTokenOK().WithPayload(&models.IpamToken{Token: token})
+}
+
+type _httpDeleteControllerIpamToken struct {
+	*ControllerContext
+}
+
+// Handle handles DELETE requests for /ipam/token.
+func (h *_httpDeleteControllerIpamToken) Handle(params controller.DeleteIpamTokenParams) middleware.Responder {
+	if h.AllocationTokenIssuer == nil {
+		return controller.NewDeleteIpamTokenOK()
+	}
+
+	ctx, _, err := h.authenticateAgent(params.HTTPRequest)
+	if err != nil {
+		return authenticationError(err)
+	}
+	h.AllocationTokenIssuer.ReturnToken(ctx, params.Token)
+
+	return controller.NewDeleteIpamTokenOK()
+}
+
+var (
+	errUnauthenticated = errors.New("unauthenticated")
+	errForbidden       = errors.New("forbidden")
+)
+
+const (
+	// The extra information of the ServiceAccount tokens bound to Pods,
+	// which is reviewed by the API server.
+	extraPodNameKey = "authentication.kubernetes.io/pod-name"
+	extraPodUIDKey  = "authentication.kubernetes.io/pod-uid"
+)
+
+func authenticationError(err error) middleware.Responder {
+	if errors.Is(err, errForbidden) {
+		return middleware.Error(http.StatusForbidden, models.Error(err.Error()))
+	}
+	if errors.Is(err, errUnauthenticated) {
+		return middleware.Error(http.StatusUnauthorized, models.Error(err.Error()))
+	}
+
+	return middleware.Error(http.StatusServiceUnavailable, models.Error(err.Error()))
+}
+
+type bearerTokenKey struct{}
+
+// authenticateAgent authenticates the bearer token of the request with a
+// TokenReview, which must be of the ServiceAccount of spiderpool-agent and
+// bound to its Pod. It returns the node where the Pod runs, and the context
+// carrying the bearer token, with which the request is forwarded to the
+// leader.
+func (h *ControllerContext) authenticateAgent(req *http.Request) (context.Context, string, error) {
+	ctx := req.Context()
+
+	bearer, ok := strings.CutPrefix(req.Header.Get(runtime.HeaderAuthorization), "Bearer ")
+	if !ok || bearer == "" {
+		return nil, "", fmt.Errorf("%w: missing bearer token", errUnauthenticated)
+	}
+
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: bearer},
+	}
+	review, err := h.ClientSet.AuthenticationV1().TokenReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to review the bearer token: %v", err)
+	}
+	if !review.Status.Authenticated {
+		return nil, "", fmt.Errorf("%w: %s", errUnauthenticated, review.Status.Error)
+	}
+
+	user := review.Status.User
+	agent := fmt.Sprintf("system:serviceaccount:%s:%s", h.Cfg.ControllerPodNamespace, h.Cfg.AgentServiceAccountName)
+	if user.Username != agent {
+		return nil, "", fmt.Errorf("%w: %s is not %s", errForbidden, user.Username, agent)
+	}
+	podNames := user.Extra[extraPodNameKey]
+	if len(podNames) != 1 {
+		return nil, "", fmt.Errorf("%w: the bearer token is not bound to a Pod", errForbidden)
+	}
+
+	pod, err := h.ClientSet.CoreV1().Pods(h.Cfg.ControllerPodNamespace).Get(ctx, podNames[0], metav1.GetOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: failed to get the Pod %s bound to the bearer token: %v", errForbidden, podNames[0], err)
+	}
+	if podUIDs := user.Extra[extraPodUIDKey]; len(podUIDs) != 1 || podUIDs[0] != string(pod.UID) {
+		return nil, "", fmt.Errorf("%w: the Pod %s bound to the bearer token is gone", errForbidden, pod.Name)
+	}
+	if pod.Spec.NodeName == "" {
+		return nil, "", fmt.Errorf("%w: the Pod %s bound to the bearer token is not scheduled", errForbidden, pod.Name)
+	}
+
+	return context.WithValue(ctx, bearerTokenKey{}, bearer), pod.Spec.NodeName, nil
+}
+
+// withCallerAuth forwards the bearer token of the caller to the leader,
+// which authenticates the caller by itself.
+func withCallerAuth(ctx context.Context) controllerOpenAPIClientController.ClientOption {
+	return func(op *runtime.ClientOperation) {
+		if bearer, ok := ctx.Value(bearerTokenKey{}).(string); ok {
+			op.AuthInfo = runtime_client.BearerToken(bearer)
+		}
+	}
+}
+
+// leaderTokenClient forwards the token requests to the replica elected as
+// the leader, which is located by the holder of the election Lease. The
+// address of the leader is cached for a while to spare the API server.
+type leaderTokenClient struct {
+	lock    lock.Mutex
+	timeout time.Duration
+
+	leaderAddr string
+	client     *controllerOpenAPIClient.SpiderpoolControllerAPI
+	expiration time.Time
+}
+
+const leaderAddrCacheTTL = 5 * time.Second
+
+func newLeaderTokenClient(timeout time.Duration) *leaderTokenClient {
+	return &leaderTokenClient{timeout: timeout}
+}
+
+// Issuer implements limiter.LeaderIssuerFunc.
+func (l *leaderTokenClient) Issuer(ctx context.Context) (limiter.TokenIssuer, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.client != nil && time.Now().Before(l.expiration) {
+		return &forwardedTokenIssuer{client: l.client, timeout: l.timeout}, nil
+	}
+
+	addr, err := getLeaderAddr(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if addr != l.leaderAddr || l.client == nil {
+		transport := runtime_client.New(addr, controllerOpenAPIClient.DefaultBasePath, controllerOpenAPIClient.DefaultSchemes)
+		l.client = controllerOpenAPIClient.New(transport, strfmt.Default)
+		l.leaderAddr = addr
+	}
+	l.expiration = time.Now().Add(leaderAddrCacheTTL)
+
+	return &forwardedTokenIssuer{client: l.client, timeout: l.timeout}, nil
+}
+
+func getLeaderAddr(ctx context.Context) (string, error) {
+	namespace := controllerContext.Cfg.ControllerPodNamespace
+	lease, err := controllerContext.ClientSet.CoordinationV1().Leases(namespace).Get(ctx, constant.SpiderControllerElectorLockName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the election Lease: %v", err)
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == "" {
+		return "", errors.New("the election Lease has no holder")
+	}
+
+	pod, err := controllerContext.ClientSet.CoreV1().Pods(namespace).Get(ctx, *lease.Spec.HolderIdentity, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the leader Pod %s: %v", *lease.Spec.HolderIdentity, err)
+	}
+	if pod.Status.PodIP == "" {
+		return "", fmt.Errorf("the leader Pod %s has no IP address", pod.Name)
+	}
+
+	return net.JoinHostPort(pod.Status.PodIP, controllerContext.Cfg.HttpPort), nil
+}
+
+// forwardedTokenIssuer acquires and returns the tokens through the API of
+// the leader.
+type forwardedTokenIssuer struct {
+	client  *controllerOpenAPIClient.SpiderpoolControllerAPI
+	timeout time.Duration
+}
+
+func (f *forwardedTokenIssuer) AcquireToken(ctx context.Context, node string) (string, error) {
+	params := controllerOpenAPIClientController.NewPostIpamTokenParamsWithTimeout(f.timeout).WithContext(ctx).WithNode(node)
+	resp, err := f.client.Controller.PostIpamToken(params, withCallerAuth(ctx))
+	if err != nil {
+		var tooManyRequests *controllerOpenAPIClientController.PostIpamTokenTooManyRequests
+		if errors.As(err, &tooManyRequests) {
+			return "", fmt.Errorf("%s", tooManyRequests.Payload)
+		}
+		return "", fmt.Errorf("%w: failed to forward to the leader: %v", limiter.ErrNoLeader, err)
+	}
+
+	return resp.Payload.Token, nil
+}
+
+func (f *forwardedTokenIssuer) ReturnToken(ctx context.Context, token string) {
+	params := controllerOpenAPIClientController.NewDeleteIpamTokenParamsWithTimeout(f.timeout).WithContext(ctx).WithToken(token)
+	if _, err := f.client.Controller.DeleteIpamToken(params, withCallerAuth(ctx)); err != nil {
+		logger.Sugar().Warnf("Failed to return token to the leader, it will be reclaimed on expiration: %v", err)
+	}
+}
@@ -22,12 +22,15 @@ import (
 	"github.com/spidernet-io/spiderpool/pkg/election"
 	"github.com/spidernet-io/spiderpool/pkg/gcmanager"
 	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	"github.com/spidernet-io/spiderpool/pkg/limiter"
 	"github.com/spidernet-io/spiderpool/pkg/namespacemanager"
 	"github.com/spidernet-io/spiderpool/pkg/nodemanager"
 	"github.com/spidernet-io/spiderpool/pkg/podmanager"
 	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
 	"github.com/spidernet-io/spiderpool/pkg/statefulsetmanager"
 	"github.com/spidernet-io/spiderpool/pkg/subnetmanager"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+	"github.com/spidernet-io/spiderpool/pkg/verifymanager"
 	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
 )
 
@@ -48,21 +51,40 @@ type envConf struct {
 var envInfo = []envConf{
 	{"SPIDERPOOL_LOG_LEVEL", constant.LogInfoLevelStr, true, &controllerContext.Cfg.LogLevel, nil, nil},
 	{"SPIDERPOOL_ENABLED_METRIC", "false", false, nil, &controllerContext.Cfg.EnabledMetric, nil},
+	{"SPIDERPOOL_LOG_SUMMARIZE_THRESHOLD", "20", false, nil, nil, &controllerContext.Cfg.LogSummarizeThreshold},
+	{"SPIDERPOOL_LOG_REDACT_ANNOTATIONS", "false", false, nil, &controllerContext.Cfg.LogRedactAnnotations, nil},
 	{"SPIDERPOOL_HEALTH_PORT", "5720", true, &controllerContext.Cfg.HttpPort, nil, nil},
 	{"SPIDERPOOL_METRIC_HTTP_PORT", "5721", true, &controllerContext.Cfg.MetricHttpPort, nil, nil},
 	{"SPIDERPOOL_WEBHOOK_PORT", "5722", true, &controllerContext.Cfg.WebhookPort, nil, nil},
 	{"SPIDERPOOL_GOPS_LISTEN_PORT", "5724", false, &controllerContext.Cfg.GopsListenPort, nil, nil},
 	{"SPIDERPOOL_PYROSCOPE_PUSH_SERVER_ADDRESS", "", false, &controllerContext.Cfg.PyroscopeAddress, nil, nil},
 	{"SPIDERPOOL_WORKLOADENDPOINT_MAX_HISTORY_RECORDS", "100", false, nil, nil, &controllerContext.Cfg.WorkloadEndpointMaxHistoryRecords},
+	{"SPIDERPOOL_WORKLOADENDPOINT_COMPACTION_ENABLED", "true", false, nil, &controllerContext.Cfg.EnableWorkloadEndpointCompaction, nil},
+	{"SPIDERPOOL_WORKLOADENDPOINT_COMPACTION_INTERVAL_IN_SECOND", "0", false, nil, nil, &controllerContext.Cfg.WorkloadEndpointCompactionInterval},
+	{"SPIDERPOOL_WORKLOADENDPOINT_MAX_HISTORY_AGE_IN_HOUR", "0", false, nil, nil, &controllerContext.Cfg.WorkloadEndpointMaxHistoryAge},
+	{"SPIDERPOOL_WORKLOADENDPOINT_MIGRATION_ENABLED", "true", false, nil, &controllerContext.Cfg.EnableWorkloadEndpointMigration, nil},
 	{"SPIDERPOOL_IPPOOL_MAX_ALLOCATED_IPS", "5000", false, nil, nil, &controllerContext.Cfg.IPPoolMaxAllocatedIPs},
 	{"SPIDERPOOL_SUBNET_RESYNC_PERIOD", "300", false, nil, nil, &controllerContext.Cfg.SubnetResyncPeriod},
 	{"SPIDERPOOL_SUBNET_APPLICATION_CONTROLLER_WORKERS", "5", true, nil, nil, &controllerContext.Cfg.SubnetAppControllerWorkers},
 	{"SPIDERPOOL_SUBNET_INFORMER_WORKERS", "3", true, nil, nil, &controllerContext.Cfg.SubnetInformerWorkers},
 	{"SPIDERPOOL_SUBNET_INFORMER_MAX_WORKQUEUE_LENGTH", "10000", false, nil, nil, &controllerContext.Cfg.SubnetInformerMaxWorkqueueLength},
+	{"SPIDERPOOL_SUBNET_AUTO_POOL_HEADROOM_PERCENT", "0", false, nil, nil, &controllerContext.Cfg.SubnetAutoPoolHeadroomPercent},
+	{"SPIDERPOOL_SUBNET_CAPACITY_CHECK_ENABLED", "false", false, nil, &controllerContext.Cfg.EnableSubnetCapacityCheck, nil},
+	{"SPIDERPOOL_SUBNET_DEFRAG_CHECK_INTERVAL_IN_SECOND", "0", false, nil, nil, &controllerContext.Cfg.SubnetDefragCheckInterval},
+	{"SPIDERPOOL_SUBNET_DEFRAG_COMPACTION_ENABLED", "false", false, nil, &controllerContext.Cfg.EnableSubnetDefragCompaction, nil},
+	{"SPIDERPOOL_SUBNET_DAEMONSET_NODE_SIZING_ENABLED", "false", false, nil, &controllerContext.Cfg.EnableSubnetDaemonSetNodeSizing, nil},
+	{"SPIDERPOOL_SERVICE_BACKEND_IPS_ENABLED", "false", false, nil, &controllerContext.Cfg.EnableServiceBackendIPs, nil},
+	{"SPIDERPOOL_SERVICE_RESYNC_PERIOD", "300", false, nil, nil, &controllerContext.Cfg.ServiceResyncPeriod},
+	{"SPIDERPOOL_SERVICE_INFORMER_WORKERS", "3", false, nil, nil, &controllerContext.Cfg.ServiceInformerWorkers},
+	{"SPIDERPOOL_SERVICE_INFORMER_MAX_WORKQUEUE_LENGTH", "10000", false, nil, nil, &controllerContext.Cfg.ServiceInformerMaxWorkqueueLength},
 	{"SPIDERPOOL_UPDATE_CR_MAX_RETRIES", "4", false, nil, nil, &controllerContext.Cfg.UpdateCRMaxRetries},
 	{"SPIDERPOOL_UPDATE_CR_RETRY_UNIT_TIME", "50", false, nil, nil, &controllerContext.Cfg.UpdateCRRetryUnitTime},
 	{"SPIDERPOOL_GC_IP_ENABLED", "true", true, nil, &gcIPConfig.EnableGCIP, nil},
 	{"SPIDERPOOL_GC_TERMINATING_POD_IP_ENABLED", "true", true, nil, &gcIPConfig.EnableGCForTerminatingPod, nil},
+	{"SPIDERPOOL_GC_DELETED_NODE_IP_ENABLED", "false", false, nil, &gcIPConfig.EnableGCForDeletedNode, nil},
+	{"SPIDERPOOL_GC_DELETED_NODE_IP_DELAY", "60", false, nil, nil, &gcIPConfig.DeletedNodeGraceDelay},
+	{"SPIDERPOOL_GC_STUCK_POD_IP_ENABLED", "false", false, nil, &gcIPConfig.EnableGCForStuckPod, nil},
+	{"SPIDERPOOL_GC_STUCK_POD_IP_DELAY", "60", false, nil, nil, &gcIPConfig.StuckPodGraceDelay},
 	{"SPIDERPOOL_GC_IP_WORKER_NUM", "3", true, nil, nil, &gcIPConfig.ReleaseIPWorkerNum},
 	{"SPIDERPOOL_GC_CHANNEL_BUFFER", "5000", true, nil, nil, &gcIPConfig.GCIPChannelBuffer},
 	{"SPIDERPOOL_GC_MAX_PODENTRY_DB_CAP", "100000", true, nil, nil, &gcIPConfig.MaxPodEntryDatabaseCap},
@@ -71,6 +93,28 @@ var envInfo = []envConf{
 	{"SPIDERPOOL_GC_SIGNAL_TIMEOUT_DURATION", "3", true, nil, nil, &gcIPConfig.GCSignalTimeoutDuration},
 	{"SPIDERPOOL_GC_HTTP_REQUEST_TIME_GAP", "1", true, nil, nil, &gcIPConfig.GCSignalGapDuration},
 	{"SPIDERPOOL_GC_ADDITIONAL_GRACE_DELAY", "5", true, nil, nil, &gcIPConfig.AdditionalGraceDelay},
+	{"SPIDERPOOL_GC_TERMINATING_POD_IP_DELAY", "-1", false, nil, nil, &gcIPConfig.TerminatingPodGraceDelay},
+	{"SPIDERPOOL_GC_SUCCEEDED_POD_IP_DELAY", "-1", false, nil, nil, &gcIPConfig.SucceededPodGraceDelay},
+	{"SPIDERPOOL_GC_FAILED_POD_IP_DELAY", "-1", false, nil, nil, &gcIPConfig.FailedPodGraceDelay},
+	{"SPIDERPOOL_GC_EVICTED_POD_IP_DELAY", "-1", false, nil, nil, &gcIPConfig.EvictedPodGraceDelay},
+	{"SPIDERPOOL_GC_DELETED_POD_IP_DELAY", "-1", false, nil, nil, &gcIPConfig.DeletedPodGraceDelay},
+	{"SPIDERPOOL_GC_DRY_RUN", "false", false, nil, &gcIPConfig.DryRun, nil},
+	{"SPIDERPOOL_GC_ADAPTIVE_PACING_ENABLED", "true", false, nil, &gcIPConfig.EnableAdaptivePacing, nil},
+	{"SPIDERPOOL_GC_BUSY_CHURN_RATE", "10", false, nil, nil, &gcIPConfig.BusyChurnRate},
+	{"SPIDERPOOL_GC_SCANALL_WORKER_NUM", "1", false, nil, nil, &gcIPConfig.ScanAllWorkerNum},
+	{"SPIDERPOOL_GC_RELEASE_BATCH_SIZE", "100", false, nil, nil, &gcIPConfig.ReleaseBatchSize},
+	{"SPIDERPOOL_GC_API_QPS", "0", false, nil, nil, &gcIPConfig.APIQPS},
+	{"SPIDERPOOL_GC_API_BURST", "0", false, nil, nil, &gcIPConfig.APIBurst},
+	{"SPIDERPOOL_GC_ZOMBIE_CONTAINERID_RECONCILE_ENABLED", "true", false, nil, &gcIPConfig.EnableZombieContainerIDReconcile, nil},
+	{"SPIDERPOOL_GC_ORPHANED_ENDPOINT_MAX_AGE", "86400", false, nil, nil, &gcIPConfig.OrphanedEndpointMaxAge},
+	{"SPIDERPOOL_GC_ORPHANED_ENDPOINT_CHECK_INTERVAL", "600", false, nil, nil, &gcIPConfig.OrphanedEndpointCheckInterval},
+	{"SPIDERPOOL_GC_DOUBLE_ALLOCATION_CHECK_INTERVAL", "300", false, nil, nil, &gcIPConfig.DoubleAllocationCheckInterval},
+	{"SPIDERPOOL_GC_DOUBLE_ALLOCATION_POLICY", "report", false, &gcIPConfig.DoubleAllocationPolicy, nil, nil},
+	{"SPIDERPOOL_GC_DOUBLE_ALLOCATION_QUARANTINE_DURATION", "3600", false, nil, nil, &gcIPConfig.DoubleAllocationQuarantineDuration},
+	{"SPIDERPOOL_GC_RECLAIM_WEBHOOK_URL", "", false, &gcIPConfig.ReclaimWebhookURL, nil, nil},
+	{"SPIDERPOOL_GC_RECLAIM_WEBHOOK_TIMEOUT", "5", false, nil, nil, &gcIPConfig.ReclaimWebhookTimeout},
+	{"SPIDERPOOL_GC_RECLAIM_WEBHOOK_MAX_RETRIES", "5", false, nil, nil, &gcIPConfig.ReclaimWebhookMaxRetries},
+	{"SPIDERPOOL_GC_RECLAIM_WEBHOOK_QUEUE_SIZE", "10000", false, nil, nil, &gcIPConfig.ReclaimWebhookQueueSize},
 	{"SPIDERPOOL_POD_NAMESPACE", "", true, &controllerContext.Cfg.ControllerPodNamespace, nil, nil},
 	{"SPIDERPOOL_POD_NAME", "", true, &controllerContext.Cfg.ControllerPodName, nil, nil},
 	{"SPIDERPOOL_GC_LEADER_DURATION", "15", true, nil, nil, &controllerContext.Cfg.LeaseDuration},
@@ -85,6 +129,31 @@ var envInfo = []envConf{
 	{"SPIDERPOOL_WORKQUEUE_RETRY_DELAY_DURATION", "5", true, nil, nil, &controllerContext.Cfg.WorkQueueRequeueDelayDuration},
 	{"SPIDERPOOL_IPPOOL_INFORMER_WORKERS", "3", true, nil, nil, &controllerContext.Cfg.IPPoolInformerWorkers},
 	{"SPIDERPOOL_WORKQUEUE_MAX_RETRIES", "500", true, nil, nil, &controllerContext.Cfg.WorkQueueMaxRetries},
+	{"SPIDERPOOL_IPPOOL_QUARANTINE_COOL_DOWN_TIME_IN_SECOND", "300", false, nil, nil, &controllerContext.Cfg.IPPoolQuarantineCoolDownTime},
+	{"SPIDERPOOL_IPPOOL_MAX_SPEC_CHANGELOGS", "10", false, nil, nil, &controllerContext.Cfg.IPPoolMaxSpecChangelogs},
+	{"SPIDERPOOL_IPPOOL_CONFLICT_CHECK_INTERVAL_IN_SECOND", "300", false, nil, nil, &controllerContext.Cfg.IPPoolConflictCheckInterval},
+	{"SPIDERPOOL_IPPOOL_LEASE_CHECK_INTERVAL_IN_SECOND", "60", false, nil, nil, &controllerContext.Cfg.IPPoolLeaseCheckInterval},
+	{"SPIDERPOOL_IPPOOL_USAGE_FORECAST_INTERVAL_IN_SECOND", "300", false, nil, nil, &controllerContext.Cfg.IPPoolUsageForecastInterval},
+	{"SPIDERPOOL_IPPOOL_USAGE_FORECAST_WINDOW_IN_SECOND", "3600", false, nil, nil, &controllerContext.Cfg.IPPoolUsageForecastWindow},
+	{"SPIDERPOOL_IPPOOL_GATEWAY_UNREACHABLE_NODE_THRESHOLD", "0", false, nil, nil, &controllerContext.Cfg.IPPoolGatewayUnreachableNodeThreshold},
+	{"SPIDERPOOL_IPPOOL_DATAPATH_FAILURE_WINDOW_IN_SECOND", "300", false, nil, nil, &controllerContext.Cfg.IPPoolDatapathFailureWindow},
+	{"SPIDERPOOL_IPPOOL_AUTO_EXCLUDE_GATEWAYS", "true", false, nil, &controllerContext.Cfg.IPPoolAutoExcludeGateways, nil},
+	{"SPIDERPOOL_RESERVEDIP_EXPIRATION_CHECK_INTERVAL_IN_SECOND", "60", false, nil, nil, &controllerContext.Cfg.ReservedIPExpirationCheckInterval},
+	{"SPIDERPOOL_NETWORK_SCAN_RESERVATION_INTERVAL_IN_SECOND", "0", false, nil, nil, &controllerContext.Cfg.NetworkScanReservationInterval},
+	{"SPIDERPOOL_RESERVEDIP_CONFLICT_CHECK_INTERVAL_IN_SECOND", "60", false, nil, nil, &controllerContext.Cfg.ReservedIPConflictCheckInterval},
+	{"SPIDERPOOL_ALLOCATION_TOKEN_MAX_CONCURRENCY", "0", false, nil, nil, &controllerContext.Cfg.AllocationTokenMaxConcurrency},
+	{"SPIDERPOOL_ALLOCATION_TOKEN_MAX_QUEUE_SIZE", "10000", false, nil, nil, &controllerContext.Cfg.AllocationTokenMaxQueueSize},
+	{"SPIDERPOOL_ALLOCATION_TOKEN_TTL_IN_SECOND", "60", false, nil, nil, &controllerContext.Cfg.AllocationTokenTTL},
+	{"SPIDERPOOL_ALLOCATION_TOKEN_QUEUE_TIMEOUT_IN_SECOND", "30", false, nil, nil, &controllerContext.Cfg.AllocationTokenQueueTimeout},
+	{"SPIDERPOOL_AGENT_SERVICE_ACCOUNT_NAME", "spiderpool-agent", false, &controllerContext.Cfg.AgentServiceAccountName, nil, nil},
+	{"SPIDERPOOL_GATEWAY_UNREACHABLE_IPPOOL_SKIPPED", "false", false, nil, &controllerContext.Cfg.SkipGatewayUnreachableIPPools, nil},
+	{"SPIDERPOOL_REPORT_ONLY", "false", false, nil, &controllerContext.Cfg.ReportOnly, nil},
+	{"SPIDERPOOL_SELF_VERIFICATION_ENABLED", "false", false, nil, &controllerContext.Cfg.EnableSelfVerification, nil},
+	{"SPIDERPOOL_SELF_VERIFICATION_IPPOOL", "", false, &controllerContext.Cfg.SelfVerificationIPPool, nil, nil},
+	{"SPIDERPOOL_SELF_VERIFICATION_NAMESPACE", "", false, &controllerContext.Cfg.SelfVerificationNamespace, nil, nil},
+	{"SPIDERPOOL_SELF_VERIFICATION_IMAGE", "busybox", false, &controllerContext.Cfg.SelfVerificationImage, nil, nil},
+	{"SPIDERPOOL_SELF_VERIFICATION_INTERVAL_IN_SECOND", "600", false, nil, nil, &controllerContext.Cfg.SelfVerificationInterval},
+	{"SPIDERPOOL_SELF_VERIFICATION_TIMEOUT_IN_SECOND", "120", false, nil, nil, &controllerContext.Cfg.SelfVerificationTimeout},
 }
 
 type Config struct {
@@ -101,8 +170,10 @@ type Config struct {
 	TlsServerKeyPath  string
 
 	// env
-	LogLevel      string
-	EnabledMetric bool
+	LogLevel              string
+	LogSummarizeThreshold int
+	LogRedactAnnotations  bool
+	EnabledMetric         bool
 
 	HttpPort       string
 	MetricHttpPort string
@@ -116,16 +187,65 @@ type Config struct {
 	WorkloadEndpointMaxHistoryRecords int
 	IPPoolMaxAllocatedIPs             int
 
+	EnableWorkloadEndpointCompaction   bool
+	WorkloadEndpointCompactionInterval int
+	WorkloadEndpointMaxHistoryAge      int
+	EnableWorkloadEndpointMigration    bool
+
 	SubnetResyncPeriod               int
 	SubnetAppControllerWorkers       int
 	SubnetInformerWorkers            int
 	SubnetInformerMaxWorkqueueLength int
+	SubnetAutoPoolHeadroomPercent    int
+	EnableSubnetCapacityCheck        bool
+	SubnetDefragCheckInterval        int
+	EnableSubnetDefragCompaction     bool
+	EnableSubnetDaemonSetNodeSizing  bool
 	WorkQueueMaxRetries              int
+
+	EnableServiceBackendIPs           bool
+	ServiceResyncPeriod               int
+	ServiceInformerWorkers            int
+	ServiceInformerMaxWorkqueueLength int
 	// if IPPoolWorkQueueRequeueDelayDuration is negative number, we would not requeue it
 	WorkQueueRequeueDelayDuration int
 
-	IPPoolInformerWorkers            int
-	IPPoolInformerMaxWorkQueueLength int
+	IPPoolInformerWorkers                 int
+	IPPoolInformerMaxWorkQueueLength      int
+	IPPoolQuarantineCoolDownTime          int
+	IPPoolMaxSpecChangelogs               int
+	IPPoolConflictCheckInterval           int
+	IPPoolLeaseCheckInterval              int
+	IPPoolUsageForecastInterval           int
+	IPPoolUsageForecastWindow             int
+	IPPoolGatewayUnreachableNodeThreshold int
+	IPPoolDatapathFailureWindow           int
+	IPPoolAutoExcludeGateways             bool
+
+	ReservedIPExpirationCheckInterval int
+	NetworkScanReservationInterval    int
+	ReservedIPConflictCheckInterval   int
+
+	AllocationTokenMaxConcurrency int
+	AllocationTokenMaxQueueSize   int
+	AllocationTokenTTL            int
+	AllocationTokenQueueTimeout   int
+	AgentServiceAccountName       string
+
+	// SkipGatewayUnreachableIPPools previews the IP allocation as the
+	// agents skip the IPPools whose gateways are unreachable.
+	SkipGatewayUnreachableIPPools bool
+
+	// ReportOnly makes all writes of the controller dry runs, and admits
+	// the requests which would be denied by the webhooks.
+	ReportOnly bool
+
+	EnableSelfVerification    bool
+	SelfVerificationIPPool    string
+	SelfVerificationNamespace string
+	SelfVerificationImage     string
+	SelfVerificationInterval  int
+	SelfVerificationTimeout   int
 
 	LeaseDuration      int
 	LeaseRenewDeadline int
@@ -142,6 +262,11 @@ type Config struct {
 	ClusterDefaultIPv4Subnet          []string `yaml:"clusterDefaultIPv4Subnet"`
 	ClusterDefaultIPv6Subnet          []string `yaml:"clusterDefaultIPv6Subnet"`
 	ClusterSubnetDefaultFlexibleIPNum int      `yaml:"clusterSubnetDefaultFlexibleIPNumber"`
+	ReserveSpecialIPs                 bool     `yaml:"reserveSpecialIPs"`
+	EnableAnnotatedPoolFallback       bool     `yaml:"enableAnnotatedPoolFallback"`
+	MaxIPsPerWorkload                 int      `yaml:"maxIPsPerWorkload"`
+
+	SubnetThirdPartyControllers []types.ThirdPartyController `yaml:"subnetThirdPartyControllers"`
 
 	GoMaxProcs int
 }
@@ -167,9 +292,17 @@ type ControllerContext struct {
 	NSManager       namespacemanager.NamespaceManager
 	PodManager      podmanager.PodManager
 	GCManager       gcmanager.GCManager
+	VerifyManager   verifymanager.VerifyManager
 	StsManager      statefulsetmanager.StatefulSetManager
 	Leader          election.SpiderLeaseElector
 
+	// AllocationStats is fed by the IPPool informer, which only runs on the
+	// leader.
+	AllocationStats *ippoolmanager.AllocationStats
+	// AllocationTokenIssuer issues the tokens of the cluster-wide budget of
+	// concurrent IP allocations to the agents, nil means no budget.
+	AllocationTokenIssuer limiter.TokenIssuer
+
 	// handler
 	HttpServer        *server.Server
 	MetricsHttpServer *http.Server
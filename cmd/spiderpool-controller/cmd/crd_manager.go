@@ -19,8 +19,11 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
 
 	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/reportonly"
+	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
 )
 
 var scheme = runtime.NewScheme()
@@ -36,8 +39,14 @@ func newCRDManager() (ctrl.Manager, error) {
 		return nil, err
 	}
 
+	var newClient cluster.NewClientFunc
+	if controllerContext.Cfg.ReportOnly {
+		newClient = reportonly.NewClient
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
+		NewClient:              newClient,
 		Port:                   port,
 		CertDir:                path.Dir(controllerContext.Cfg.TlsServerCertPath),
 		MetricsBindAddress:     "0",
@@ -59,6 +68,12 @@ func newCRDManager() (ctrl.Manager, error) {
 		return nil, err
 	}
 
+	// The Endpoints are read from the API server directly, except the ones
+	// listed by the indexed fields from the cache.
+	if err := workloadendpointmanager.RegisterEndpointIndexers(controllerContext.InnerCtx, mgr.GetFieldIndexer()); err != nil {
+		return nil, err
+	}
+
 	// register a http handler for webhook health check
 	mgr.GetWebhookServer().Register(webhookMutateRoute, &_webhookHealthCheck{})
 
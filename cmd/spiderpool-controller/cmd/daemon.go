@@ -15,6 +15,8 @@ import (
 
 	"github.com/google/gops/agent"
 	"github.com/pyroscope-io/client/pyroscope"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	ctrl "sigs.k8s.io/controller-runtime"
 
@@ -23,15 +25,20 @@ import (
 	"github.com/spidernet-io/spiderpool/pkg/event"
 	"github.com/spidernet-io/spiderpool/pkg/gcmanager"
 	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
 	crdclientset "github.com/spidernet-io/spiderpool/pkg/k8s/client/clientset/versioned"
+	"github.com/spidernet-io/spiderpool/pkg/limiter"
 	"github.com/spidernet-io/spiderpool/pkg/logutils"
 	"github.com/spidernet-io/spiderpool/pkg/namespacemanager"
 	"github.com/spidernet-io/spiderpool/pkg/nodemanager"
 	"github.com/spidernet-io/spiderpool/pkg/podmanager"
 	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+	"github.com/spidernet-io/spiderpool/pkg/servicemanager"
 	"github.com/spidernet-io/spiderpool/pkg/singletons"
 	"github.com/spidernet-io/spiderpool/pkg/statefulsetmanager"
 	"github.com/spidernet-io/spiderpool/pkg/subnetmanager"
+	subnetmanagercontrollers "github.com/spidernet-io/spiderpool/pkg/subnetmanager/controllers"
+	"github.com/spidernet-io/spiderpool/pkg/verifymanager"
 	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
 )
 
@@ -47,6 +54,10 @@ func DaemonMain() {
 		panic(fmt.Sprintf("failed to initialize logger with level %s, reason=%v \n", controllerContext.Cfg.LogLevel, err))
 	}
 	logger = logutils.Logger.Named(BinNameController)
+	spiderpoolv1.SetStringOptions(spiderpoolv1.StringOptions{
+		SummarizeThreshold: controllerContext.Cfg.LogSummarizeThreshold,
+		RedactAnnotations:  controllerContext.Cfg.LogRedactAnnotations,
+	})
 
 	currentP := runtime.GOMAXPROCS(-1)
 	logger.Sugar().Infof("default max golang procs %v \n", currentP)
@@ -121,6 +132,15 @@ func DaemonMain() {
 		controllerContext.Cfg.ClusterSubnetDefaultFlexibleIPNum,
 	)
 
+	if err := subnetmanagercontrollers.ValidateThirdPartyControllers(controllerContext.Cfg.SubnetThirdPartyControllers); nil != err {
+		logger.Fatal(err.Error())
+	}
+	singletons.InitThirdPartyControllers(controllerContext.Cfg.SubnetThirdPartyControllers)
+
+	if controllerContext.Cfg.ReportOnly {
+		logger.Warn("Run in report-only mode, the changes to the cluster are reported but never applied")
+	}
+
 	controllerContext.InnerCtx, controllerContext.InnerCancel = context.WithCancel(context.Background())
 	logger.Info("Begin to initialize spiderpool-controller runtime manager")
 	mgr, err := newCRDManager()
@@ -168,6 +188,31 @@ func DaemonMain() {
 	logger.Info("Begin to initialize IP GC Manager")
 	initGCManager(controllerContext.InnerCtx)
 
+	if controllerContext.Cfg.EnableWorkloadEndpointCompaction {
+		go runEndpointCompaction(controllerContext.InnerCtx)
+	}
+
+	if controllerContext.Cfg.EnableWorkloadEndpointMigration {
+		go runEndpointMigration(controllerContext.InnerCtx)
+	}
+
+	if controllerContext.Cfg.ReservedIPExpirationCheckInterval > 0 {
+		go runReservedIPExpiration(controllerContext.InnerCtx)
+	}
+
+	if controllerContext.Cfg.NetworkScanReservationInterval > 0 {
+		go runNetworkScanReservation(controllerContext.InnerCtx)
+	}
+
+	if controllerContext.Cfg.ReservedIPConflictCheckInterval > 0 {
+		go runReservedIPStatusSync(controllerContext.InnerCtx)
+	}
+
+	// The canary Pods are never created in report-only mode.
+	if controllerContext.Cfg.EnableSelfVerification && !controllerContext.Cfg.ReportOnly {
+		initVerifyManager(controllerContext.InnerCtx)
+	}
+
 	// TODO (Icarus9913): improve k8s StartupProbe
 	logger.Info("Set spiderpool-controller Startup probe ready")
 	controllerContext.IsStartupProbe.Store(true)
@@ -253,6 +298,8 @@ func initControllerServiceManagers(ctx context.Context) {
 			MaxConflictRetries:    controllerContext.Cfg.UpdateCRMaxRetries,
 			ConflictRetryUnitTime: time.Duration(controllerContext.Cfg.UpdateCRRetryUnitTime) * time.Millisecond,
 			MaxHistoryRecords:     &controllerContext.Cfg.WorkloadEndpointMaxHistoryRecords,
+			MaxHistoryAge:         time.Duration(controllerContext.Cfg.WorkloadEndpointMaxHistoryAge) * time.Hour,
+			IndexedReader:         controllerContext.CRDManager.GetCache(),
 		},
 		controllerContext.CRDManager.GetClient(),
 	)
@@ -273,6 +320,7 @@ func initControllerServiceManagers(ctx context.Context) {
 		Client:     controllerContext.CRDManager.GetClient(),
 		EnableIPv4: controllerContext.Cfg.EnableIPv4,
 		EnableIPv6: controllerContext.Cfg.EnableIPv6,
+		ReportOnly: controllerContext.Cfg.ReportOnly,
 	}).SetupWebhookWithManager(controllerContext.CRDManager); err != nil {
 		logger.Fatal(err.Error())
 	}
@@ -283,6 +331,7 @@ func initControllerServiceManagers(ctx context.Context) {
 			MaxConflictRetries:    controllerContext.Cfg.UpdateCRMaxRetries,
 			ConflictRetryUnitTime: time.Duration(controllerContext.Cfg.UpdateCRRetryUnitTime) * time.Millisecond,
 			MaxAllocatedIPs:       &controllerContext.Cfg.IPPoolMaxAllocatedIPs,
+			ReserveSpecialIPs:     controllerContext.Cfg.ReserveSpecialIPs,
 		},
 		controllerContext.CRDManager.GetClient(),
 		controllerContext.RIPManager,
@@ -291,6 +340,26 @@ func initControllerServiceManagers(ctx context.Context) {
 		logger.Fatal(err.Error())
 	}
 	controllerContext.IPPoolManager = ipPoolManager
+	controllerContext.AllocationStats = ippoolmanager.NewAllocationStats()
+	if controllerContext.Cfg.AllocationTokenMaxConcurrency > 0 {
+		// Only the leader issues tokens, so that the budget is shared by all
+		// replicas. The forwarded requests may wait in the queue of the
+		// leader for the queue timeout.
+		tokenIssuer := limiter.NewTokenIssuer(limiter.TokenIssuerConfig{
+			MaxConcurrency: controllerContext.Cfg.AllocationTokenMaxConcurrency,
+			MaxQueueSize:   &controllerContext.Cfg.AllocationTokenMaxQueueSize,
+			TokenTTL:       time.Duration(controllerContext.Cfg.AllocationTokenTTL) * time.Second,
+		})
+		var forwardTimeout time.Duration
+		if controllerContext.Cfg.AllocationTokenQueueTimeout > 0 {
+			forwardTimeout = time.Duration(controllerContext.Cfg.AllocationTokenQueueTimeout+5) * time.Second
+		}
+		controllerContext.AllocationTokenIssuer = limiter.NewLeaderTokenIssuer(
+			tokenIssuer,
+			controllerContext.Leader,
+			newLeaderTokenClient(forwardTimeout).Issuer,
+		)
+	}
 
 	logger.Debug("Begin to set up IPPool webhook")
 	if err := (&ippoolmanager.IPPoolWebhook{
@@ -299,6 +368,9 @@ func initControllerServiceManagers(ctx context.Context) {
 		EnableIPv4:         controllerContext.Cfg.EnableIPv4,
 		EnableIPv6:         controllerContext.Cfg.EnableIPv6,
 		EnableSpiderSubnet: controllerContext.Cfg.EnableSpiderSubnet,
+
+		AutoExcludeGateways: controllerContext.Cfg.IPPoolAutoExcludeGateways,
+		ReportOnly:          controllerContext.Cfg.ReportOnly,
 	}).SetupWebhookWithManager(controllerContext.CRDManager); err != nil {
 		logger.Fatal(err.Error())
 	}
@@ -324,6 +396,7 @@ func initControllerServiceManagers(ctx context.Context) {
 			Client:     controllerContext.CRDManager.GetClient(),
 			EnableIPv4: controllerContext.Cfg.EnableIPv4,
 			EnableIPv6: controllerContext.Cfg.EnableIPv6,
+			ReportOnly: controllerContext.Cfg.ReportOnly,
 		}).SetupWebhookWithManager(controllerContext.CRDManager); err != nil {
 			logger.Fatal(err.Error())
 		}
@@ -343,6 +416,7 @@ func initGCManager(ctx context.Context) {
 		controllerContext.IPPoolManager,
 		controllerContext.PodManager,
 		controllerContext.StsManager,
+		controllerContext.RIPManager,
 		controllerContext.Leader,
 	)
 	if nil != err {
@@ -355,6 +429,199 @@ func initGCManager(ctx context.Context) {
 	}
 }
 
+// runEndpointCompaction rewrites the Endpoints created by the older versions
+// into the compact form once the controller is elected as the leader, and
+// repeats it periodically if the interval is positive. With the max age of
+// the historical records, it repeats hourly at least to prune the expired
+// ones.
+func runEndpointCompaction(ctx context.Context) {
+	compactionLogger := logutils.Logger.Named("Endpoint-Compaction")
+	ctx, cancel := context.WithCancel(logutils.IntoContext(ctx, compactionLogger))
+	defer cancel()
+
+	interval := time.Duration(controllerContext.Cfg.WorkloadEndpointCompactionInterval) * time.Second
+	if controllerContext.Cfg.WorkloadEndpointMaxHistoryAge > 0 && (interval <= 0 || interval > time.Hour) {
+		interval = time.Hour
+	}
+	period := interval
+	if period <= 0 {
+		// Poll the leadership for the one-shot compaction.
+		period = time.Duration(controllerContext.Cfg.LeaseRetryGap) * time.Second
+	}
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if !controllerContext.Leader.IsElected() {
+			return
+		}
+
+		compacted, err := controllerContext.EndpointManager.CompactEndpoints(ctx, controllerContext.GCManager)
+		if err != nil {
+			compactionLogger.Sugar().Warnf("Failed to compact some Endpoints, %d compacted: %v", compacted, err)
+			return
+		}
+		compactionLogger.Sugar().Infof("Compact %d Endpoints", compacted)
+
+		if interval <= 0 {
+			cancel()
+		}
+	}, period)
+}
+
+// runReservedIPExpiration deletes the expired SpiderReservedIPs periodically
+// while the controller is the leader, so that their IP addresses are returned
+// to the IPPools.
+func runReservedIPExpiration(ctx context.Context) {
+	expirationLogger := logutils.Logger.Named("ReservedIP-Expiration")
+	ctx = logutils.IntoContext(ctx, expirationLogger)
+
+	interval := time.Duration(controllerContext.Cfg.ReservedIPExpirationCheckInterval) * time.Second
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if !controllerContext.Leader.IsElected() {
+			return
+		}
+
+		deleted, err := controllerContext.RIPManager.DeleteExpiredReservedIPs(ctx)
+		if err != nil {
+			expirationLogger.Sugar().Warnf("Failed to delete some expired SpiderReservedIPs, %d deleted: %v", deleted, err)
+			return
+		}
+		if deleted > 0 {
+			expirationLogger.Sugar().Infof("Delete %d expired SpiderReservedIPs", deleted)
+		}
+	}, interval)
+}
+
+// runReservedIPStatusSync refreshes the status of the SpiderReservedIPs
+// periodically while the controller is the leader, which flags the
+// reservations of the IP addresses still allocated to Pods, and counts the
+// IP addresses of the IPPools blocked by the reservations. The claimed IP
+// addresses left bound to their Pods are consumed or unbound beforehand.
+func runReservedIPStatusSync(ctx context.Context) {
+	statusLogger := logutils.Logger.Named("ReservedIP-Status-Sync")
+	ctx = logutils.IntoContext(ctx, statusLogger)
+
+	interval := time.Duration(controllerContext.Cfg.ReservedIPConflictCheckInterval) * time.Second
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if !controllerContext.Leader.IsElected() {
+			return
+		}
+
+		consumed, err := controllerContext.RIPManager.ConsumeBoundClaims(ctx)
+		if err != nil {
+			statusLogger.Sugar().Warnf("Failed to consume some claimed IP addresses bound to Pods, %d consumed: %v", consumed, err)
+		} else if consumed > 0 {
+			statusLogger.Sugar().Infof("Consume %d claimed IP addresses allocated to their bound Pods", consumed)
+		}
+
+		conflicting, err := controllerContext.RIPManager.SyncReservedIPStatus(ctx)
+		if err != nil {
+			statusLogger.Sugar().Warnf("Failed to refresh the status of some SpiderReservedIPs: %v", err)
+			return
+		}
+		if conflicting > 0 {
+			statusLogger.Sugar().Warnf("%d SpiderReservedIPs reserve IP addresses still allocated to Pods", conflicting)
+		}
+	}, interval)
+}
+
+// runNetworkScanReservation reserves the IP addresses of the IPPools which
+// are reported in use on the network by the scans of spiderpool-agent
+// periodically while the controller is the leader.
+func runNetworkScanReservation(ctx context.Context) {
+	reservationLogger := logutils.Logger.Named("Network-Scan-Reservation")
+	ctx = logutils.IntoContext(ctx, reservationLogger)
+
+	interval := time.Duration(controllerContext.Cfg.NetworkScanReservationInterval) * time.Second
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if !controllerContext.Leader.IsElected() {
+			return
+		}
+
+		poolList, err := controllerContext.IPPoolManager.ListIPPools(ctx)
+		if err != nil {
+			reservationLogger.Sugar().Warnf("Failed to list IPPools: %v", err)
+			return
+		}
+
+		scanned := 0
+		for i := range poolList.Items {
+			pool := &poolList.Items[i]
+			if pool.DeletionTimestamp != nil {
+				continue
+			}
+
+			if err := limiter.Pause(ctx, controllerContext.GCManager, scanned); err != nil {
+				return
+			}
+			scanned++
+
+			reserved, err := controllerContext.RIPManager.ReserveInUseIPs(ctx, pool)
+			if err != nil {
+				reservationLogger.Sugar().Warnf("Failed to reserve the IP addresses of IPPool %s in use on the network: %v", pool.Name, err)
+				continue
+			}
+			if reserved > 0 {
+				reservationLogger.Sugar().Infof("Reserve %d IP addresses of IPPool %s in use on the network with SpiderReservedIP %s", reserved, pool.Name, reservedipmanager.InUseReservedIPName(pool.Name))
+			}
+		}
+	}, interval)
+}
+
+// runEndpointMigration upgrades the Endpoints stored with the older status
+// schema once the controller is elected as the leader. The progress is logged
+// every endpointMigrationReportBatch Endpoints, and the migration is retried
+// until all of them are upgraded.
+func runEndpointMigration(ctx context.Context) {
+	migrationLogger := logutils.Logger.Named("Endpoint-Migration")
+	ctx, cancel := context.WithCancel(logutils.IntoContext(ctx, migrationLogger))
+	defer cancel()
+
+	const endpointMigrationReportBatch = 500
+	report := func(progress workloadendpointmanager.EndpointMigrationProgress) {
+		if processed := progress.Migrated + progress.UpToDate + progress.Failed; processed%endpointMigrationReportBatch == 0 {
+			migrationLogger.Sugar().Infof("Migrating Endpoints to schema version %d: %s", workloadendpointmanager.LatestEndpointSchemaVersion(), progress)
+		}
+	}
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if !controllerContext.Leader.IsElected() {
+			return
+		}
+
+		progress, err := controllerContext.EndpointManager.MigrateEndpoints(ctx, report, controllerContext.GCManager)
+		if err != nil {
+			migrationLogger.Sugar().Warnf("Failed to migrate some Endpoints, %s: %v", progress, err)
+			return
+		}
+		migrationLogger.Sugar().Infof("Migrate Endpoints to schema version %d: %s", workloadendpointmanager.LatestEndpointSchemaVersion(), progress)
+		cancel()
+	}, time.Duration(controllerContext.Cfg.LeaseRetryGap)*time.Second)
+}
+
+func initVerifyManager(ctx context.Context) {
+	logger.Info("Begin to initialize self verification")
+	verifyManager, err := verifymanager.NewVerifyManager(
+		verifymanager.VerifyManagerConfig{
+			IPPoolName:       controllerContext.Cfg.SelfVerificationIPPool,
+			Namespace:        controllerContext.Cfg.SelfVerificationNamespace,
+			Image:            controllerContext.Cfg.SelfVerificationImage,
+			IntervalDuration: time.Duration(controllerContext.Cfg.SelfVerificationInterval) * time.Second,
+			TimeoutDuration:  time.Duration(controllerContext.Cfg.SelfVerificationTimeout) * time.Second,
+		},
+		controllerContext.CRDManager.GetClient(),
+		controllerContext.IPPoolManager,
+		controllerContext.Leader,
+	)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+	controllerContext.VerifyManager = verifyManager
+
+	if err := controllerContext.VerifyManager.Start(ctx); err != nil {
+		logger.Fatal(err.Error())
+	}
+}
+
 func initSpiderControllerLeaderElect(ctx context.Context) {
 	leaseDuration := time.Duration(controllerContext.Cfg.LeaseDuration) * time.Second
 	renewDeadline := time.Duration(controllerContext.Cfg.LeaseRenewDeadline) * time.Second
@@ -403,17 +670,28 @@ func setupInformers() {
 	logger.Info("Begin to set up IPPool informer")
 	ipPoolController := ippoolmanager.NewIPPoolController(
 		ippoolmanager.IPPoolControllerConfig{
-			EnableIPv4:                    controllerContext.Cfg.EnableIPv4,
-			EnableIPv6:                    controllerContext.Cfg.EnableIPv6,
-			IPPoolControllerWorkers:       controllerContext.Cfg.IPPoolInformerWorkers,
-			EnableSpiderSubnet:            controllerContext.Cfg.EnableSpiderSubnet,
-			LeaderRetryElectGap:           time.Duration(controllerContext.Cfg.LeaseRetryGap) * time.Second,
-			MaxWorkqueueLength:            controllerContext.Cfg.IPPoolInformerMaxWorkQueueLength,
-			WorkQueueRequeueDelayDuration: time.Duration(controllerContext.Cfg.WorkQueueRequeueDelayDuration) * time.Second,
-			WorkQueueMaxRetries:           controllerContext.Cfg.WorkQueueMaxRetries,
+			EnableIPv4:                      controllerContext.Cfg.EnableIPv4,
+			EnableIPv6:                      controllerContext.Cfg.EnableIPv6,
+			IPPoolControllerWorkers:         controllerContext.Cfg.IPPoolInformerWorkers,
+			EnableSpiderSubnet:              controllerContext.Cfg.EnableSpiderSubnet,
+			LeaderRetryElectGap:             time.Duration(controllerContext.Cfg.LeaseRetryGap) * time.Second,
+			MaxWorkqueueLength:              controllerContext.Cfg.IPPoolInformerMaxWorkQueueLength,
+			WorkQueueRequeueDelayDuration:   time.Duration(controllerContext.Cfg.WorkQueueRequeueDelayDuration) * time.Second,
+			WorkQueueMaxRetries:             controllerContext.Cfg.WorkQueueMaxRetries,
+			QuarantineCoolDownDuration:      time.Duration(controllerContext.Cfg.IPPoolQuarantineCoolDownTime) * time.Second,
+			MaxSpecChangelogs:               controllerContext.Cfg.IPPoolMaxSpecChangelogs,
+			ConflictCheckInterval:           time.Duration(controllerContext.Cfg.IPPoolConflictCheckInterval) * time.Second,
+			LeaseCheckInterval:              time.Duration(controllerContext.Cfg.IPPoolLeaseCheckInterval) * time.Second,
+			UsageForecastInterval:           time.Duration(controllerContext.Cfg.IPPoolUsageForecastInterval) * time.Second,
+			UsageForecastWindow:             time.Duration(controllerContext.Cfg.IPPoolUsageForecastWindow) * time.Second,
+			GatewayUnreachableNodeThreshold: controllerContext.Cfg.IPPoolGatewayUnreachableNodeThreshold,
+			DatapathFailureWindow:           time.Duration(controllerContext.Cfg.IPPoolDatapathFailureWindow) * time.Second,
+			ReserveSpecialIPs:               controllerContext.Cfg.ReserveSpecialIPs,
 		},
 		controllerContext.CRDManager.GetClient(),
 		controllerContext.RIPManager,
+		controllerContext.IPPoolManager,
+		controllerContext.AllocationStats,
 	)
 	err = ipPoolController.SetupInformer(controllerContext.InnerCtx, crdClient, controllerContext.Leader)
 	if nil != err {
@@ -429,6 +707,8 @@ func setupInformers() {
 			ResyncPeriod:            time.Duration(controllerContext.Cfg.SubnetResyncPeriod) * time.Second,
 			SubnetControllerWorkers: controllerContext.Cfg.SubnetInformerWorkers,
 			MaxWorkqueueLength:      controllerContext.Cfg.SubnetInformerMaxWorkqueueLength,
+			DefragCheckInterval:     time.Duration(controllerContext.Cfg.SubnetDefragCheckInterval) * time.Second,
+			EnableDefragCompaction:  controllerContext.Cfg.EnableSubnetDefragCompaction,
 		}).SetupInformer(controllerContext.InnerCtx, crdClient, controllerContext.Leader); err != nil {
 			logger.Fatal(err.Error())
 		}
@@ -444,15 +724,58 @@ func setupInformers() {
 				MaxWorkqueueLength:            controllerContext.Cfg.SubnetInformerMaxWorkqueueLength,
 				WorkQueueRequeueDelayDuration: time.Duration(controllerContext.Cfg.WorkQueueRequeueDelayDuration) * time.Second,
 				LeaderRetryElectGap:           time.Duration(controllerContext.Cfg.LeaseRetryGap) * time.Second,
+				ThirdPartyControllers:         controllerContext.Cfg.SubnetThirdPartyControllers,
+				EnableCapacityCheck:           controllerContext.Cfg.EnableSubnetCapacityCheck,
+				EnableDaemonSetNodeSizing:     controllerContext.Cfg.EnableSubnetDaemonSetNodeSizing,
 			})
 		if nil != err {
 			logger.Fatal(err.Error())
 		}
 
-		err = subnetAppController.SetupInformer(controllerContext.InnerCtx, controllerContext.ClientSet, controllerContext.Leader)
+		dynamicClient, err := dynamic.NewForConfig(ctrl.GetConfigOrDie())
 		if nil != err {
 			logger.Fatal(err.Error())
 		}
+
+		err = subnetAppController.SetupInformer(controllerContext.InnerCtx, controllerContext.ClientSet, dynamicClient, controllerContext.Leader)
+		if nil != err {
+			logger.Fatal(err.Error())
+		}
+
+		if controllerContext.Cfg.SubnetAutoPoolHeadroomPercent > 0 {
+			logger.Info("Begin to set up auto-created IPPool scaler")
+			if err := (&subnetmanager.AutoPoolScaler{
+				Client:              controllerContext.CRDManager.GetClient(),
+				HeadroomPercent:     controllerContext.Cfg.SubnetAutoPoolHeadroomPercent,
+				LeaderRetryElectGap: time.Duration(controllerContext.Cfg.LeaseRetryGap) * time.Second,
+				ResyncPeriod:        time.Duration(controllerContext.Cfg.SubnetResyncPeriod) * time.Second,
+				Workers:             controllerContext.Cfg.SubnetInformerWorkers,
+				MaxWorkqueueLength:  controllerContext.Cfg.SubnetInformerMaxWorkqueueLength,
+			}).SetupInformer(controllerContext.InnerCtx, crdClient, controllerContext.Leader); err != nil {
+				logger.Fatal(err.Error())
+			}
+		}
+	}
+
+	if controllerContext.Cfg.EnableServiceBackendIPs {
+		logger.Info("Begin to set up Service informer")
+		serviceController, err := servicemanager.NewServiceController(
+			servicemanager.ServiceControllerConfig{
+				ControllerWorkers:   controllerContext.Cfg.ServiceInformerWorkers,
+				MaxWorkqueueLength:  controllerContext.Cfg.ServiceInformerMaxWorkqueueLength,
+				ResyncPeriod:        time.Duration(controllerContext.Cfg.ServiceResyncPeriod) * time.Second,
+				LeaderRetryElectGap: time.Duration(controllerContext.Cfg.LeaseRetryGap) * time.Second,
+			},
+			controllerContext.CRDManager.GetClient(),
+		)
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+
+		err = serviceController.SetupInformer(controllerContext.InnerCtx, controllerContext.ClientSet, controllerContext.Leader)
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
 	}
 }
 
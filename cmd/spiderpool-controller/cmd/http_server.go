@@ -28,6 +28,15 @@ func newControllerOpenAPIServer() (*controllerOpenAPIServer.Server, error) {
 		logger.Sugar().Infof(s, i)
 	}
 
+	// controller API
+	api.ControllerGetIpamAuditHandler = httpGetControllerIpamAudit
+	api.ControllerGetIpamEndpointHandler = httpGetControllerIpamEndpoint
+	api.ControllerGetIpamStatsHandler = httpGetControllerIpamStats
+	api.ControllerPostIpamPreviewHandler = httpPostControllerIpamPreview
+	api.ControllerPostIpamReservedipsHandler = httpPostControllerIpamReservedIPs
+	api.ControllerPostIpamTokenHandler = httpPostControllerIpamToken
+	api.ControllerDeleteIpamTokenHandler = httpDeleteControllerIpamToken
+
 	// runtime API
 	api.RuntimeGetRuntimeStartupHandler = httpGetControllerStartup
 	api.RuntimeGetRuntimeReadinessHandler = httpGetControllerReadiness
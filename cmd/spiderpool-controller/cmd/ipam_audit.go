@@ -0,0 +1,58 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/go-openapi/runtime/middleware"
+
+	"github.com/spidernet-io/spiderpool/api/v1/controller/models"
+	"github.com/spidernet-io/spiderpool/api/v1/controller/server/restapi/controller"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+)
+
+// Singleton
+var httpGetControllerIpamAudit = &_httpGetControllerIpamAudit{controllerContext}
+
+type _httpGetControllerIpamAudit struct {
+	*ControllerContext
+}
+
+// Handle handles GET requests for /ipam/audit.
+func (g *_httpGetControllerIpamAudit) Handle(params controller.GetIpamAuditParams) middleware.Responder {
+	ctx := params.HTTPRequest.Context()
+
+	podList, err := g.PodManager.ListPods(ctx)
+	if err != nil {
+		return controller.NewGetIpamAuditInternalServerError().WithPayload(models.Error(fmt.Sprintf("failed to list Pods: %v", err)))
+	}
+	endpointList, err := g.EndpointManager.ListEndpoints(ctx)
+	if err != nil {
+		return controller.NewGetIpamAuditInternalServerError().WithPayload(models.Error(fmt.Sprintf("failed to list SpiderEndpoints: %v", err)))
+	}
+	poolList, err := g.IPPoolManager.ListIPPools(ctx)
+	if err != nil {
+		return controller.NewGetIpamAuditInternalServerError().WithPayload(models.Error(fmt.Sprintf("failed to list IPPools: %v", err)))
+	}
+
+	report := &models.IpamAudit{
+		IPPools:   int64(len(poolList.Items)),
+		Endpoints: int64(len(endpointList.Items)),
+		Pods:      int64(len(podList.Items)),
+	}
+	for _, m := range ippoolmanager.AuditIPAllocations(poolList.Items, endpointList.Items, podList.Items) {
+		report.Items = append(report.Items, &models.IpamAuditItem{
+			Category:    m.Category,
+			IP:          m.IP,
+			Pool:        m.Pool,
+			Namespace:   m.Namespace,
+			Pod:         m.Pod,
+			ContainerID: m.ContainerID,
+			Message:     m.Message,
+		})
+	}
+
+	return controller.NewGetIpamAuditOK().WithPayload(report)
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/go-openapi/runtime/middleware"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/spidernet-io/spiderpool/api/v1/controller/models"
+	"github.com/spidernet-io/spiderpool/api/v1/controller/server/restapi/controller"
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// Singleton
+var httpGetControllerIpamEndpoint = &_httpGetControllerIpamEndpoint{controllerContext}
+
+type _httpGetControllerIpamEndpoint struct {
+	*ControllerContext
+}
+
+// Handle handles GET requests for /ipam/endpoint.
+func (g *_httpGetControllerIpamEndpoint) Handle(params controller.GetIpamEndpointParams) middleware.Responder {
+	ipVersion := constant.IPv6
+	if params.IPVersion != nil {
+		ipVersion = types.IPVersion(*params.IPVersion)
+	} else if ip := net.ParseIP(params.IP); ip != nil && ip.To4() != nil {
+		ipVersion = constant.IPv4
+	}
+
+	endpoint, err := g.EndpointManager.GetEndpointByIP(params.HTTPRequest.Context(), ipVersion, params.IP)
+	if err != nil {
+		switch {
+		case errors.Is(err, constant.ErrWrongInput):
+			return controller.NewGetIpamEndpointBadRequest().WithPayload(models.Error(err.Error()))
+		case apierrors.IsNotFound(err):
+			return controller.NewGetIpamEndpointNotFound().WithPayload(models.Error(err.Error()))
+		case errors.Is(err, constant.ErrIPConflict):
+			return controller.NewGetIpamEndpointConflict().WithPayload(models.Error(err.Error()))
+		default:
+			return controller.NewGetIpamEndpointInternalServerError().WithPayload(models.Error(err.Error()))
+		}
+	}
+
+	resp := &models.IpamEndpoint{
+		Namespace:           endpoint.Namespace,
+		Pod:                 endpoint.Name,
+		OwnerControllerType: endpoint.Status.OwnerControllerType,
+		OwnerControllerName: endpoint.Status.OwnerControllerName,
+	}
+	for _, ref := range endpoint.OwnerReferences {
+		if ref.Kind == constant.KindPod {
+			resp.UID = string(ref.UID)
+		}
+	}
+	if current := endpoint.Status.Current; current != nil {
+		resp.ContainerID = current.ContainerID
+		if current.Node != nil {
+			resp.Node = *current.Node
+		}
+		fillIpamEndpointAllocation(resp, current, net.ParseIP(params.IP).String())
+	}
+
+	return controller.NewGetIpamEndpointOK().WithPayload(resp)
+}
+
+// fillIpamEndpointAllocation fills in the NIC, IP address and IPPool of the
+// allocation holding the IP address.
+func fillIpamEndpointAllocation(resp *models.IpamEndpoint, current *spiderpoolv1.PodIPAllocation, ip string) {
+	for _, d := range current.IPs {
+		for _, a := range []struct{ ip, pool *string }{{d.IPv4, d.IPv4Pool}, {d.IPv6, d.IPv6Pool}} {
+			if a.ip == nil {
+				continue
+			}
+			if addr, _, _ := strings.Cut(*a.ip, "/"); addr != ip {
+				continue
+			}
+
+			resp.Nic = d.NIC
+			resp.IP = *a.ip
+			if a.pool != nil {
+				resp.Pool = *a.pool
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-openapi/runtime/middleware"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/spidernet-io/spiderpool/api/v1/controller/models"
+	"github.com/spidernet-io/spiderpool/api/v1/controller/server/restapi/controller"
+	"github.com/spidernet-io/spiderpool/pkg/ipam"
+)
+
+// Singleton
+var httpPostControllerIpamPreview = &_httpPostControllerIpamPreview{controllerContext}
+
+type _httpPostControllerIpamPreview struct {
+	*ControllerContext
+}
+
+// Handle handles POST requests for /ipam/preview.
+func (g *_httpPostControllerIpamPreview) Handle(params controller.PostIpamPreviewParams) middleware.Responder {
+	data, err := json.Marshal(params.Pod)
+	if err != nil {
+		return controller.NewPostIpamPreviewBadRequest().WithPayload(models.Error(fmt.Sprintf("invalid Pod manifest: %v", err)))
+	}
+	var pod corev1.Pod
+	if err := json.Unmarshal(data, &pod); err != nil {
+		return controller.NewPostIpamPreviewBadRequest().WithPayload(models.Error(fmt.Sprintf("invalid Pod manifest: %v", err)))
+	}
+
+	// The IPAM config of the agents which the selection of IPPools depends on.
+	config := ipam.IPAMConfig{
+		EnableIPv4:                    g.Cfg.EnableIPv4,
+		EnableIPv6:                    g.Cfg.EnableIPv6,
+		ClusterDefaultIPv4IPPool:      g.Cfg.ClusterDefaultIPv4IPPool,
+		ClusterDefaultIPv6IPPool:      g.Cfg.ClusterDefaultIPv6IPPool,
+		EnableSpiderSubnet:            g.Cfg.EnableSpiderSubnet,
+		EnableStatefulSet:             g.Cfg.EnableStatefulSet,
+		EnableAnnotatedPoolFallback:   g.Cfg.EnableAnnotatedPoolFallback,
+		MaxIPsPerWorkload:             g.Cfg.MaxIPsPerWorkload,
+		SkipGatewayUnreachableIPPools: g.Cfg.SkipGatewayUnreachableIPPools,
+	}
+	preview, err := ipam.PreviewPodIPAM(params.HTTPRequest.Context(), &pod, config, g.IPPoolManager, g.EndpointManager, g.NodeManager, g.NSManager, g.PodManager)
+	if err != nil {
+		return controller.NewPostIpamPreviewInternalServerError().WithPayload(models.Error(err.Error()))
+	}
+
+	resp := &models.IpamPreview{
+		Allowed:  preview.Allowed,
+		Messages: preview.Messages,
+	}
+	for _, p := range preview.Pools {
+		resp.Pools = append(resp.Pools, &models.IpamPreviewPool{
+			Nic:       p.NIC,
+			IPVersion: p.IPVersion,
+			Pool:      p.Pool,
+			Usable:    p.Usable,
+			Reason:    p.Reason,
+			Filter:    p.Filter,
+		})
+	}
+
+	return controller.NewPostIpamPreviewOK().WithPayload(resp)
+}
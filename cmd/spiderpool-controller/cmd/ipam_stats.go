@@ -0,0 +1,57 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-openapi/runtime/middleware"
+
+	"github.com/spidernet-io/spiderpool/api/v1/controller/models"
+	"github.com/spidernet-io/spiderpool/api/v1/controller/server/restapi/controller"
+)
+
+// Singleton
+var httpGetControllerIpamStats = &_httpGetControllerIpamStats{controllerContext}
+
+type _httpGetControllerIpamStats struct {
+	*ControllerContext
+}
+
+// Handle handles GET requests for /ipam/stats.
+func (g *_httpGetControllerIpamStats) Handle(params controller.GetIpamStatsParams) middleware.Responder {
+	window, err := time.ParseDuration(*params.Window)
+	if err != nil {
+		return controller.NewGetIpamStatsBadRequest().WithPayload(models.Error(fmt.Sprintf("invalid time window: %v", err)))
+	}
+
+	var pool, namespace string
+	if params.Pool != nil {
+		pool = *params.Pool
+	}
+	if params.Namespace != nil {
+		namespace = *params.Namespace
+	}
+
+	items, err := g.AllocationStats.Query(window, pool, namespace, time.Now())
+	if err != nil {
+		return controller.NewGetIpamStatsBadRequest().WithPayload(models.Error(err.Error()))
+	}
+
+	minutes := window.Minutes()
+	stats := &models.IpamStats{Window: window.String()}
+	for _, item := range items {
+		stats.Items = append(stats.Items, &models.IpamStatsItem{
+			Pool:           item.Pool,
+			Namespace:      item.Namespace,
+			Allocations:    item.Allocations,
+			Releases:       item.Releases,
+			AllocationRate: float64(item.Allocations) / minutes,
+			ReleaseRate:    float64(item.Releases) / minutes,
+		})
+	}
+
+	return controller.NewGetIpamStatsOK().WithPayload(stats)
+}
@@ -0,0 +1,62 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/swag"
+
+	"github.com/spidernet-io/spiderpool/api/v1/controller/models"
+	"github.com/spidernet-io/spiderpool/api/v1/controller/server/restapi/controller"
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+)
+
+// Singleton
+var httpPostControllerIpamReservedIPs = &_httpPostControllerIpamReservedIPs{controllerContext}
+
+type _httpPostControllerIpamReservedIPs struct {
+	*ControllerContext
+}
+
+// Handle handles POST requests for /ipam/reservedips.
+func (g *_httpPostControllerIpamReservedIPs) Handle(params controller.PostIpamReservedipsParams) middleware.Responder {
+	records := make([]reservedipmanager.ReservedIPRecord, 0, len(params.ReservedIPs.Records))
+	for _, r := range params.ReservedIPs.Records {
+		if r == nil {
+			continue
+		}
+		records = append(records, reservedipmanager.ReservedIPRecord{
+			Name:        r.Name,
+			IP:          swag.StringValue(r.IP),
+			Description: r.Description,
+			Owner:       r.Owner,
+		})
+	}
+
+	result, err := g.RIPManager.ImportReservedIPs(params.HTTPRequest.Context(), params.ReservedIPs.Source, records)
+	if err != nil {
+		if errors.Is(err, constant.ErrWrongInput) {
+			return controller.NewPostIpamReservedipsBadRequest().WithPayload(models.Error(err.Error()))
+		}
+		return controller.NewPostIpamReservedipsInternalServerError().WithPayload(models.Error(err.Error()))
+	}
+
+	resp := &models.ReservedIPImportResult{
+		Created:   int64(result.Created),
+		Updated:   int64(result.Updated),
+		Unchanged: int64(result.Unchanged),
+	}
+	for _, f := range result.Failures {
+		resp.Failures = append(resp.Failures, &models.ReservedIPImportFailure{
+			Name:  f.Name,
+			IP:    f.IP,
+			Error: f.Error,
+		})
+	}
+
+	return controller.NewPostIpamReservedipsOK().WithPayload(resp)
+}
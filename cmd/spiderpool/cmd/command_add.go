@@ -132,6 +132,9 @@ func CmdAdd(args *skel.CmdArgs) (err error) {
 		logger.Error(err.Error())
 		return err
 	}
+	if ipamResponse.Payload.DefaultPoolFallback {
+		logger.Warn("IPPools specified by Pod annotations do not exist, IP addresses are allocated from the default IPPools")
+	}
 
 	// assemble result with ipam response.
 	result, err := assembleResult(conf.CNIVersion, args.IfName, ipamResponse)
@@ -162,7 +165,8 @@ func assembleResult(cniVersion, IfName string, ipamResponse *daemonset.PostIpamI
 		}
 	}
 
-	// Result Routes
+	// Result Routes, the metric of routes is dropped since the route of
+	// this CNI version doesn't support priority.
 	var routes []*types.Route
 	for _, singleRoute := range ipamResponse.Payload.Routes {
 		if *singleRoute.IfName == IfName {
@@ -191,7 +195,10 @@ func assembleResult(cniVersion, IfName string, ipamResponse *daemonset.PostIpamI
 				return nil, err
 			}
 			gateway := net.ParseIP(ipconfig.Gateway)
-			nic := &current.Interface{Name: *ipconfig.Nic}
+			// The MAC address is fixed by the Pod annotation or derived by
+			// the IPPool, it's left to the main CNI plugin to set it on the
+			// interface.
+			nic := &current.Interface{Name: *ipconfig.Nic, Mac: ipconfig.Mac}
 			netInterfaces = append(netInterfaces, nic)
 
 			// record ips
@@ -0,0 +1,121 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
+
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
+)
+
+const cleanupTimeout = 5 * time.Minute
+
+// endpointCmd represents the endpoint command.
+var endpointCmd = &cobra.Command{
+	Use:   "endpoint",
+	Short: "spiderpoolclt endpoint cli",
+	Long:  `spiderpoolclt endpoint cli to interact with spiderendpoint`,
+}
+
+// endpointCleanupCmd represents the cleanup command.
+var endpointCleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "clean up spiderendpoints in bulk",
+	Long:  `delete the spiderendpoints matching the namespace and label selector, and remove their finalizers`,
+	Run: func(cmd *cobra.Command, args []string) {
+		namespace, err := cmd.Flags().GetString("namespace")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		selector, err := cmd.Flags().GetString("selector")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		terminatingOnly, err := cmd.Flags().GetBool("terminating-only")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		parallelism, err := cmd.Flags().GetInt("parallelism")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+
+		opts := workloadendpointmanager.EndpointCleanupOptions{
+			Namespace:   namespace,
+			Parallelism: parallelism,
+			DryRun:      dryRun,
+		}
+		if terminatingOnly {
+			opts.Filter = func(endpoint *spiderpoolv1.SpiderEndpoint) bool {
+				return endpoint.DeletionTimestamp != nil
+			}
+		}
+		if err := cleanupEndpoints(selector, opts); err != nil {
+			logger.Fatal(err.Error())
+		}
+	},
+}
+
+// cleanupEndpoints prints the spiderendpoints cleaned up, or the ones which
+// would be cleaned up in dry-run mode.
+func cleanupEndpoints(selector string, opts workloadendpointmanager.EndpointCleanupOptions) error {
+	labelSelector, err := labels.Parse(selector)
+	if err != nil {
+		return fmt.Errorf("invalid label selector '%s': %v", selector, err)
+	}
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	manager, err := workloadendpointmanager.NewWorkloadEndpointManager(
+		workloadendpointmanager.EndpointManagerConfig{
+			MaxConflictRetries:    4,
+			ConflictRetryUnitTime: 50 * time.Millisecond,
+		},
+		c,
+	)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	result, cleanupErr := manager.CleanupEndpoints(ctx, labelSelector, opts)
+	if result != nil {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	}
+	if cleanupErr != nil {
+		return fmt.Errorf("failed to clean up spiderendpoints: %v", cleanupErr)
+	}
+
+	return nil
+}
+
+func init() {
+	endpointCleanupCmd.PersistentFlags().String("namespace", "", "[optional] namespace of spiderendpoints, default to all namespaces")
+	endpointCleanupCmd.PersistentFlags().String("selector", "", "[optional] label selector of spiderendpoints")
+	endpointCleanupCmd.PersistentFlags().Bool("terminating-only", true, "[optional] only clean up the terminating spiderendpoints")
+	endpointCleanupCmd.PersistentFlags().Int("parallelism", 10, "[optional] maximum number of spiderendpoints cleaned up concurrently")
+	endpointCleanupCmd.PersistentFlags().Bool("dry-run", false, "[optional] only print the spiderendpoints which would be cleaned up")
+
+	rootCmd.AddCommand(endpointCmd)
+	endpointCmd.AddCommand(endpointCleanupCmd)
+}
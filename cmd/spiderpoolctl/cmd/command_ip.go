@@ -4,9 +4,28 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	runtime_client "github.com/go-openapi/runtime/client"
+	"github.com/go-openapi/strfmt"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controllerOpenAPIClient "github.com/spidernet-io/spiderpool/api/v1/controller/client"
+	"github.com/spidernet-io/spiderpool/api/v1/controller/client/controller"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
 )
 
+const apiTimeout = 30 * time.Second
+
 // ipCmd represents the base command.
 var ipCmd = &cobra.Command{
 	Use:   "ip",
@@ -20,10 +39,108 @@ var ipShowCmd = &cobra.Command{
 	Short: "show ip related data",
 	Long:  `show pod who is taking this ip`,
 	Run: func(cmd *cobra.Command, args []string) {
-		logger.Info("This is spiderpool ctl ip show...")
+		ip, err := cmd.Flags().GetString("ip")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		if ip == "" {
+			logger.Fatal("flag 'ip' is required to show the pod who is taking it")
+		}
+
+		if err := showIPAllocation(ip); err != nil {
+			logger.Fatal(err.Error())
+		}
+	},
+}
+
+// newClient generates a k8s client which knows the spiderpool resources.
+func newClient() (client.Client, error) {
+	scheme := runtime.NewScheme()
+	if err := spiderpoolv1.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add spiderpoolv1 runtime scheme: %v", err)
+	}
+	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate k8s client: %v", err)
+	}
+
+	return c, nil
+}
+
+// showIPAllocation prints the pod and its top owner who is taking the ip.
+func showIPAllocation(ip string) error {
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+
+	var poolList spiderpoolv1.SpiderIPPoolList
+	if err := c.List(ctx, &poolList); err != nil {
+		return fmt.Errorf("failed to list ippools: %v", err)
+	}
+
+	pool, allocation := ippoolmanager.FindIPAllocation(poolList.Items, ip)
+	if pool == nil {
+		fmt.Printf("ip %s is not allocated\n", ip)
+		return nil
+	}
+
+	fmt.Printf("IP:           %s\n", ip)
+	fmt.Printf("IPPool:       %s\n", pool.Name)
+	fmt.Printf("Pod:          %s/%s\n", allocation.Namespace, allocation.Pod)
+	fmt.Printf("PodUID:       %s\n", allocation.PodUID)
+	fmt.Printf("Owner:        %s %s\n", allocation.OwnerControllerType, allocation.OwnerControllerName)
+	fmt.Printf("Node:         %s\n", allocation.Node)
+	fmt.Printf("Interface:    %s\n", allocation.NIC)
+	fmt.Printf("ContainerID:  %s\n", allocation.ContainerID)
+
+	return nil
+}
+
+// ipAuditCmd represents the audit command.
+var ipAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "audit the ip allocations of the cluster",
+	Long:  `cross-check the ip allocations of the ippools, spiderendpoints and pods, and print the report of all mismatches in JSON`,
+	Run: func(cmd *cobra.Command, args []string) {
+		server, err := cmd.Flags().GetString("server")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+
+		if err := auditIPAllocations(server); err != nil {
+			logger.Fatal(err.Error())
+		}
 	},
 }
 
+// auditIPAllocations requests spiderpool-controller to audit the ip
+// allocations, and prints the report.
+func auditIPAllocations(server string) error {
+	transport := runtime_client.New(server, controllerOpenAPIClient.DefaultBasePath, controllerOpenAPIClient.DefaultSchemes)
+	c := controllerOpenAPIClient.New(transport, strfmt.Default)
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+
+	resp, err := c.Controller.GetIpamAudit(controller.NewGetIpamAuditParamsWithContext(ctx))
+	if err != nil {
+		var failure *controller.GetIpamAuditInternalServerError
+		if errors.As(err, &failure) {
+			err = fmt.Errorf("%s", failure.Payload)
+		}
+		return fmt.Errorf("failed to audit ip allocations: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(resp.Payload)
+}
+
 // ipReleaseCmd represents the release command.
 var ipReleaseCmd = &cobra.Command{
 	Use:   "release",
@@ -46,7 +163,10 @@ var ipSetCmd = &cobra.Command{
 
 func init() {
 	// show flags
-	ipShowCmd.PersistentFlags().String("ip", "", "[optional] ip")
+	ipShowCmd.PersistentFlags().String("ip", "", "[required] ip")
+
+	// audit flags
+	ipAuditCmd.PersistentFlags().String("server", "spiderpool-controller.kube-system.svc:5720", "[optional] address of the HTTP server of spiderpool-controller")
 
 	// release flags
 	ipReleaseCmd.PersistentFlags().String("ip", "", "[required] ip")
@@ -91,6 +211,7 @@ func init() {
 
 	rootCmd.AddCommand(ipCmd)
 	ipCmd.AddCommand(ipShowCmd)
+	ipCmd.AddCommand(ipAuditCmd)
 	ipCmd.AddCommand(ipReleaseCmd)
 	ipCmd.AddCommand(ipSetCmd)
 }
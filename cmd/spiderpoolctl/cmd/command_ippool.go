@@ -0,0 +1,167 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+)
+
+// ippoolCmd represents the ippool command.
+var ippoolCmd = &cobra.Command{
+	Use:   "ippool",
+	Short: "spiderpoolclt ippool cli",
+	Long:  `spiderpoolclt ippool cli to interact with ippool`,
+}
+
+// ippoolExportCmd represents the export command.
+var ippoolExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "export the ip allocations of ippool",
+	Long:  `export the full ip allocation map of ippool to json, which could be imported into another cluster`,
+	Run: func(cmd *cobra.Command, args []string) {
+		pool, err := cmd.Flags().GetString("ippool")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		file, err := cmd.Flags().GetString("file")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+
+		if err := exportIPAllocations(pool, file); err != nil {
+			logger.Fatal(err.Error())
+		}
+	},
+}
+
+// ippoolImportCmd represents the import command.
+var ippoolImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "import the ip allocations into ippool",
+	Long:  `import the exported ip allocation map into ippool after validation, the ippool must have the same subnet`,
+	Run: func(cmd *cobra.Command, args []string) {
+		pool, err := cmd.Flags().GetString("ippool")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		file, err := cmd.Flags().GetString("file")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+
+		if err := importIPAllocations(pool, file); err != nil {
+			logger.Fatal(err.Error())
+		}
+	},
+}
+
+func newIPPoolManager() (ippoolmanager.IPPoolManager, error) {
+	c, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+	rIPManager, err := reservedipmanager.NewReservedIPManager(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return ippoolmanager.NewIPPoolManager(
+		ippoolmanager.IPPoolManagerConfig{
+			MaxConflictRetries:    4,
+			ConflictRetryUnitTime: 50 * time.Millisecond,
+		},
+		c,
+		rIPManager,
+	)
+}
+
+// exportIPAllocations writes the allocation state of the ippool to the file,
+// or the standard output if the file is not specified.
+func exportIPAllocations(pool, file string) error {
+	manager, err := newIPPoolManager()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+
+	state, err := manager.ExportIPAllocations(ctx, pool)
+	if err != nil {
+		return fmt.Errorf("failed to export ip allocations of ippool %s: %v", pool, err)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if file == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+
+	return os.WriteFile(file, append(data, '\n'), 0o600)
+}
+
+// importIPAllocations records the allocation state in the file to the ippool.
+func importIPAllocations(pool, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var state ippoolmanager.IPPoolAllocationState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse ip allocations in %s: %v", file, err)
+	}
+	if pool == "" {
+		pool = state.IPPool
+	}
+
+	manager, err := newIPPoolManager()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+
+	if err := manager.ImportIPAllocations(ctx, pool, &state); err != nil {
+		return fmt.Errorf("failed to import ip allocations into ippool %s: %v", pool, err)
+	}
+	fmt.Printf("imported %d ip allocations into ippool %s\n", len(state.AllocatedIPs), pool)
+
+	return nil
+}
+
+func init() {
+	// export flags
+	ippoolExportCmd.PersistentFlags().String("ippool", "", "[required] ippool name")
+	ippoolExportCmd.PersistentFlags().String("file", "", "[optional] file to write, default to stdout")
+	err := ippoolExportCmd.MarkPersistentFlagRequired("ippool")
+	if nil != err {
+		logger.Error(err.Error())
+	}
+
+	// import flags
+	ippoolImportCmd.PersistentFlags().String("ippool", "", "[optional] ippool name, default to the exported one")
+	ippoolImportCmd.PersistentFlags().String("file", "", "[required] file exported by 'ippool export'")
+	err = ippoolImportCmd.MarkPersistentFlagRequired("file")
+	if nil != err {
+		logger.Error(err.Error())
+	}
+
+	rootCmd.AddCommand(ippoolCmd)
+	ippoolCmd.AddCommand(ippoolExportCmd)
+	ippoolCmd.AddCommand(ippoolImportCmd)
+}
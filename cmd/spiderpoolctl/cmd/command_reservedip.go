@@ -0,0 +1,162 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	runtime_client "github.com/go-openapi/runtime/client"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/spf13/cobra"
+
+	controllerOpenAPIClient "github.com/spidernet-io/spiderpool/api/v1/controller/client"
+	"github.com/spidernet-io/spiderpool/api/v1/controller/client/controller"
+	"github.com/spidernet-io/spiderpool/api/v1/controller/models"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+)
+
+// reservedIPCmd represents the reservedip command.
+var reservedIPCmd = &cobra.Command{
+	Use:   "reservedip",
+	Short: "spiderpoolctl reservedip cli",
+	Long:  `spiderpoolctl reservedip cli to interact with spiderreservedip`,
+}
+
+// reservedIPImportCmd represents the import command.
+var reservedIPImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "import reserved IP addresses in bulk",
+	Long:  `create or update one spiderreservedip for each reserved IP address listed in a CSV or JSON file, the ones already up to date are skipped`,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, err := cmd.Flags().GetString("file")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		source, err := cmd.Flags().GetString("source")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		server, err := cmd.Flags().GetString("server")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		batchSize, err := cmd.Flags().GetInt("batch-size")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+
+		if err := importReservedIPs(file, format, source, server, batchSize); err != nil {
+			logger.Fatal(err.Error())
+		}
+	},
+}
+
+// readReservedIPRecords reads the records from the CSV or JSON file, the
+// format is guessed from the file extension if not specified.
+func readReservedIPRecords(file, format string) ([]reservedipmanager.ReservedIPRecord, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), ".")
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch format {
+	case "csv":
+		return reservedipmanager.ParseReservedIPRecordsCSV(f)
+	case "json":
+		return reservedipmanager.ParseReservedIPRecordsJSON(f)
+	default:
+		return nil, fmt.Errorf("unknown format '%s' of file %s, specify it with --format", format, file)
+	}
+}
+
+// importReservedIPs posts the records to spiderpool-controller in batches,
+// and prints the failures and the counts of the changed spiderreservedips.
+func importReservedIPs(file, format, source, server string, batchSize int) error {
+	if batchSize <= 0 || batchSize > reservedipmanager.MaxReservedIPImportRecords {
+		return fmt.Errorf("batch size must be in range [1, %d]", reservedipmanager.MaxReservedIPImportRecords)
+	}
+
+	records, err := readReservedIPRecords(file, format)
+	if err != nil {
+		return fmt.Errorf("failed to read reserved IP addresses: %v", err)
+	}
+
+	transport := runtime_client.New(server, controllerOpenAPIClient.DefaultBasePath, controllerOpenAPIClient.DefaultSchemes)
+	c := controllerOpenAPIClient.New(transport, strfmt.Default)
+
+	var created, updated, unchanged, failed int64
+	for start := 0; start < len(records); start += batchSize {
+		end := start + batchSize
+		if end > len(records) {
+			end = len(records)
+		}
+
+		body := &models.ReservedIPImport{Source: source}
+		for _, r := range records[start:end] {
+			body.Records = append(body.Records, &models.ReservedIPRecord{
+				Name:        r.Name,
+				IP:          swag.String(r.IP),
+				Description: r.Description,
+				Owner:       r.Owner,
+			})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+		params := controller.NewPostIpamReservedipsParamsWithContext(ctx).WithReservedIPs(body)
+		resp, err := c.Controller.PostIpamReservedips(params)
+		cancel()
+		if err != nil {
+			var badRequest *controller.PostIpamReservedipsBadRequest
+			if errors.As(err, &badRequest) {
+				err = fmt.Errorf("%s", badRequest.Payload)
+			}
+			return fmt.Errorf("failed to import records %d-%d: %v", start+1, end, err)
+		}
+
+		created += resp.Payload.Created
+		updated += resp.Payload.Updated
+		unchanged += resp.Payload.Unchanged
+		for _, f := range resp.Payload.Failures {
+			failed++
+			fmt.Printf("failed to import %s as %s: %s\n", f.IP, f.Name, f.Error)
+		}
+	}
+
+	fmt.Printf("created: %d, updated: %d, unchanged: %d, failed: %d\n", created, updated, unchanged, failed)
+	if failed != 0 {
+		return fmt.Errorf("failed to import %d of %d reserved IP addresses", failed, len(records))
+	}
+
+	return nil
+}
+
+func init() {
+	reservedIPImportCmd.PersistentFlags().String("file", "", "[required] CSV or JSON file of the reserved IP addresses, the CSV file starts with a header row of the columns 'ip', 'name', 'description' and 'owner'")
+	reservedIPImportCmd.PersistentFlags().String("format", "", "[optional] format of the file, 'csv' or 'json', default to the file extension")
+	reservedIPImportCmd.PersistentFlags().String("source", reservedipmanager.DefaultReservedIPImportSource, "[optional] source of the reserved IP addresses, such as the name of the CMDB, which prefixes the generated names and labels the spiderreservedips")
+	reservedIPImportCmd.PersistentFlags().String("server", "spiderpool-controller.kube-system.svc:5720", "[optional] address of the HTTP server of spiderpool-controller")
+	reservedIPImportCmd.PersistentFlags().Int("batch-size", 200, "[optional] number of the reserved IP addresses imported per request")
+	err := reservedIPImportCmd.MarkPersistentFlagRequired("file")
+	if nil != err {
+		logger.Error(err.Error())
+	}
+
+	rootCmd.AddCommand(reservedIPCmd)
+	reservedIPCmd.AddCommand(reservedIPImportCmd)
+}
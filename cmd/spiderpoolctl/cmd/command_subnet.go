@@ -0,0 +1,107 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/subnetmanager"
+)
+
+// subnetCmd represents the subnet command.
+var subnetCmd = &cobra.Command{
+	Use:   "subnet",
+	Short: "spiderpoolclt subnet cli",
+	Long:  `spiderpoolclt subnet cli to interact with subnet`,
+}
+
+// subnetDefragCmd represents the defrag command.
+var subnetDefragCmd = &cobra.Command{
+	Use:   "defrag",
+	Short: "defragment the free ips of subnet",
+	Long:  `compute the plan to compact the free ips of subnet by moving the auto-created ippools without allocated ips, and apply it optionally`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subnet, err := cmd.Flags().GetString("subnet")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		apply, err := cmd.Flags().GetBool("apply")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+
+		if err := defragSubnet(subnet, apply); err != nil {
+			logger.Fatal(err.Error())
+		}
+	},
+}
+
+// defragSubnet prints the defragmentation plan of the subnet, and applies it
+// if required.
+func defragSubnet(subnetName string, apply bool) error {
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+
+	var subnet spiderpoolv1.SpiderSubnet
+	if err := c.Get(ctx, apitypes.NamespacedName{Name: subnetName}, &subnet); err != nil {
+		return fmt.Errorf("failed to get subnet %s: %v", subnetName, err)
+	}
+
+	var poolList spiderpoolv1.SpiderIPPoolList
+	if err := c.List(ctx, &poolList, client.MatchingLabels{constant.LabelIPPoolOwnerSpiderSubnet: subnetName}); err != nil {
+		return fmt.Errorf("failed to list ippools of subnet %s: %v", subnetName, err)
+	}
+	ipPools := make([]*spiderpoolv1.SpiderIPPool, 0, len(poolList.Items))
+	for i := range poolList.Items {
+		ipPools = append(ipPools, &poolList.Items[i])
+	}
+
+	plan, err := subnetmanager.GenSubnetDefragPlan(&subnet, ipPools)
+	if err != nil {
+		return fmt.Errorf("failed to compute the defragmentation plan of subnet %s: %v", subnetName, err)
+	}
+
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+
+	if !apply || len(plan.Moves) == 0 {
+		return nil
+	}
+
+	moved, err := subnetmanager.ApplySubnetDefragPlan(ctx, c, plan)
+	if err != nil {
+		return fmt.Errorf("failed to defragment subnet %s after moving %d ippools: %v", subnetName, moved, err)
+	}
+	fmt.Printf("moved %d ippools of subnet %s\n", moved, subnetName)
+
+	return nil
+}
+
+func init() {
+	subnetDefragCmd.PersistentFlags().String("subnet", "", "[required] subnet name")
+	subnetDefragCmd.PersistentFlags().Bool("apply", false, "[optional] apply the plan rather than only print it")
+	err := subnetDefragCmd.MarkPersistentFlagRequired("subnet")
+	if nil != err {
+		logger.Error(err.Error())
+	}
+
+	rootCmd.AddCommand(subnetCmd)
+	subnetCmd.AddCommand(subnetDefragCmd)
+}
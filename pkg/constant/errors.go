@@ -12,6 +12,12 @@ var (
 	ErrNoAvailablePool  = errors.New("no IPPool available")
 	ErrRetriesExhausted = errors.New("exhaust all retries")
 	ErrIPUsedOut        = errors.New("all IP addresses used out")
+	ErrIPConflict       = errors.New("IP address allocated to multiple Pods")
+	ErrIPVacating       = errors.New("IP address being vacated")
+	ErrClaimedIPTaken   = errors.New("claimed IP address taken by another Pod")
+	ErrIPInUse          = errors.New("IP address in use on the network")
+
+	ErrWorkloadIPLimitExceeded = errors.New("IP holding limit of workload exceeded")
 )
 
 var ErrMissingRequiredParam = errors.New("must be specified")
@@ -10,6 +10,9 @@ const (
 	IPv6 types.IPVersion = 6
 )
 
+// MACAuto asks to derive the fixed MAC address from the IP address.
+const MACAuto = "auto"
+
 const (
 	InvalidIPVersion = types.IPVersion(976)
 	InvalidCIDR      = "invalid CIDR"
@@ -20,3 +23,14 @@ const (
 )
 
 var InvalidIPRanges = []string{InvalidIPRange}
+
+// The CNI operations and their results recorded in the historical IP
+// allocations of SpiderEndpoint.
+const (
+	CNIOperationAdd = "ADD"
+	CNIOperationDel = "DEL"
+
+	CNIResultSuccess  = "success"
+	CNIResultFailure  = "failure"
+	CNIResultRollback = "rollback"
+)
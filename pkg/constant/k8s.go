@@ -58,15 +58,91 @@ const (
 	AnnoPodIPPools      = AnnotationPre + "/ippools"
 	AnnoPodRoutes       = AnnotationPre + "/routes"
 	AnnoPodDNS          = AnnotationPre + "/dns"
-	AnnoPodStatus       = AnnotationPre + "/status"
+	AnnoPodMAC          = AnnotationPre + "/mac"
 	AnnoNSDefautlV4Pool = AnnotationPre + "/default-ipv4-ippool"
 	AnnoNSDefautlV6Pool = AnnotationPre + "/default-ipv6-ippool"
 
+	// AnnoTenant specifies the tenant (VRF) of the Pods in the Namespace,
+	// the Pods are only allowed to annotate the same tenant.
+	AnnoTenant = AnnotationPre + "/tenant"
+
+	// TaintAgentNotReady is the taint key registered on the Nodes, such as
+	// with '--register-with-taints' of kubelet, and removed by the
+	// spiderpool-agent once its IPAM is functional on the Node.
+	TaintAgentNotReady = AnnotationPre + "/agent-not-ready"
+
+	// AnnoIPPoolSpecChanges is set by the webhook to record the latest spec
+	// changes of IPPool as a JSON array, so that none of them is lost if the
+	// IPPool is updated again before they are moved into its status.
+	AnnoIPPoolSpecChanges = AnnotationPre + "/spec-changes"
+
+	// AnnoIPPoolSplit asks the controller to split the IPPool, the value is
+	// a JSON object mapping the names of new IPPools to their IP ranges.
+	AnnoIPPoolSplit = AnnotationPre + "/ippool-split"
+	// AnnoIPPoolMerge asks the controller to merge the comma-separated
+	// sibling IPPools into the IPPool.
+	AnnoIPPoolMerge = AnnotationPre + "/ippool-merge"
+	// AnnoIPPoolReshaping is set by the controller while the IPPool is being
+	// split or merged, no IP address is allocated from the IPPool meanwhile.
+	AnnoIPPoolReshaping = AnnotationPre + "/ippool-reshaping"
+	// AnnoIPPoolTransfer records the IP addresses being transferred into the
+	// IPPool by a split or merge, so that a half-done transfer is resumed.
+	AnnoIPPoolTransfer = AnnotationPre + "/ippool-transfer"
+	// AnnoIPPoolAutoExcludedGateways records the gateways of neighboring
+	// subnets separated by commas which the webhook has appended to
+	// 'spec.excludeIPs', so that they're removed once no longer listed.
+	AnnoIPPoolAutoExcludedGateways = AnnotationPre + "/auto-excluded-gateways"
+
 	// subnet manager annotation and labels
 	AnnoSpiderSubnet              = AnnotationPre + "/subnet"
 	AnnoSpiderSubnets             = AnnotationPre + "/subnets"
 	AnnoSpiderSubnetPoolIPNumber  = AnnotationPre + "/ippool-ip-number"
 	AnnoSpiderSubnetReclaimIPPool = AnnotationPre + "/ippool-reclaim"
+	// AnnoSpiderSubnetDualStack is the shorthand of annotations 'subnet' and
+	// 'ippool-ip-number' for a pair of IPv4 and IPv6 SpiderSubnets, whose
+	// auto-created IPPools are created and scaled together with the same
+	// IP number.
+	AnnoSpiderSubnetDualStack = AnnotationPre + "/subnet-dual-stack"
+	// AnnoSpiderSubnetPoolWaitTimeout is how long the IP allocation of the
+	// Pod waits for its auto-created IPPools to be ready, such as '30s'.
+	AnnoSpiderSubnetPoolWaitTimeout = AnnotationPre + "/subnet-pool-wait-timeout"
+	// AnnoSpiderSubnetPoolIPs pins the IP ranges of the auto-created IPPools
+	// of the application, separated by commas. The auto-created IPPools take
+	// exactly these IP addresses from their SpiderSubnets, and the annotation
+	// is copied onto them.
+	AnnoSpiderSubnetPoolIPs = AnnotationPre + "/subnet-pool-ips"
+
+	// AnnoSpiderSubnetIPPoolTemplate is the template on SpiderSubnet to
+	// generate an IPPool for each node or zone, with the CIDR of the Subnet
+	// sliced.
+	AnnoSpiderSubnetIPPoolTemplate = AnnotationPre + "/ippool-template"
+	IPPoolTemplateScopeNode        = "node"
+	IPPoolTemplateScopeZone        = "zone"
+
+	// AnnoSpiderSubnetReclaimPolicy is the policy to reclaim the auto-created
+	// IPPools once their application is deleted, it's set on the SpiderSubnet
+	// or the Pod template of the application, and the latter takes
+	// precedence. The value is 'Immediate', 'Retain' or 'Delayed:<minutes>'.
+	AnnoSpiderSubnetReclaimPolicy = AnnotationPre + "/ippool-reclaim-policy"
+	ReclaimPolicyImmediate        = "Immediate"
+	ReclaimPolicyRetain           = "Retain"
+	ReclaimPolicyDelayed          = "Delayed"
+	// AnnoIPPoolReclaimAfter is the RFC 3339 time after which the auto-created
+	// IPPool retained by the Delayed reclaim policy is reclaimed, unless an
+	// application of the same kind, namespace and name adopts it before.
+	AnnoIPPoolReclaimAfter = AnnotationPre + "/reclaim-after"
+	// AnnoIPPoolVacatingIPs is set by the controller on the IPPool whose IP
+	// ranges are excluded by its controller Subnet but still allocated, the
+	// IP ranges separated by commas are no longer allocated, and they're
+	// removed from the IPPool once all of them are released.
+	AnnoIPPoolVacatingIPs = AnnotationPre + "/vacating-ips"
+	// AnnoEndpointSchemaVersion is the version of the status schema of the
+	// SpiderEndpoint, the older ones are migrated by the controller.
+	AnnoEndpointSchemaVersion = AnnotationPre + "/endpoint-schema-version"
+	// AnnoGCSkip set to "true" on the SpiderIPPool or SpiderEndpoint freezes
+	// it from the IP garbage collection, such as while investigating an
+	// incident.
+	AnnoGCSkip = AnnotationPre + "/gc-skip"
 
 	LabelIPPoolOwnerSpiderSubnet   = AnnotationPre + "/owner-spider-subnet"
 	LabelIPPoolOwnerApplication    = AnnotationPre + "/owner-application"
@@ -76,6 +152,49 @@ const (
 	LabelIPPoolVersionV6           = "IPv6"
 	LabelIPPoolReclaimIPPool       = AnnoSpiderSubnetReclaimIPPool
 	LabelIPPoolInterface           = AnnotationPre + "/interface"
+	// LabelIPPoolBorrowedBy is the SpiderSubnet specified by the application,
+	// on behalf of which the auto-created IPPool borrows IP addresses from
+	// one of its fallback subnets.
+	LabelIPPoolBorrowedBy = AnnotationPre + "/borrowed-by-subnet"
+	// LabelIPPoolTemplateKey is the node or zone of the IPPool generated
+	// from the template of its SpiderSubnet.
+	LabelIPPoolTemplateKey = AnnotationPre + "/ippool-template-key"
+	// LabelIPPoolParent is the parent IPPool which the IPPool is carved
+	// from.
+	LabelIPPoolParent = AnnotationPre + "/parent-ippool"
+	// LabelIPPoolParentUID is the UID of the parent IPPool recorded when
+	// the IPPool is carved, which tells whether the parent has been
+	// re-created with the same name since.
+	LabelIPPoolParentUID = AnnotationPre + "/parent-ippool-uid"
+	// LabelIPPoolTenant is the tenant which the IPPool belongs to, it's
+	// absent for the IPPools of the default tenant.
+	LabelIPPoolTenant = AnnotationPre + "/tenant"
+
+	// LabelReservedIPInUseIPPool is the IPPool whose IP addresses observed
+	// in use on the network are held by the SpiderReservedIP.
+	LabelReservedIPInUseIPPool = AnnotationPre + "/in-use-ippool"
+
+	// LabelReservedIPImportSource is the source which the SpiderReservedIP is
+	// imported from, such as a CMDB. Only the SpiderReservedIPs imported from
+	// the same source are updated by the later imports.
+	LabelReservedIPImportSource = AnnotationPre + "/import-source"
+
+	// LabelReservedIPQuarantineReason is why the IP address is quarantined
+	// by the SpiderReservedIP, such as its double allocation.
+	LabelReservedIPQuarantineReason = AnnotationPre + "/quarantine-reason"
+
+	// AnnoReservedIPDescription and AnnoReservedIPOwner record the metadata
+	// of the imported SpiderReservedIP.
+	AnnoReservedIPDescription = AnnotationPre + "/reservation-description"
+	AnnoReservedIPOwner       = AnnotationPre + "/reservation-owner"
+
+	// AnnoServiceBackendIPs is set by the controller to list the IP
+	// addresses allocated by spiderpool to the Pods selected by the Service.
+	AnnoServiceBackendIPs = AnnotationPre + "/backend-ips"
+
+	// LabelSelfVerification marks the canary Pods created by the self
+	// verification of spiderpool-controller.
+	LabelSelfVerification = AnnotationPre + "/self-verification"
 
 	LabelSubnetCIDR = AnnotationPre + "/subnet-cidr"
 	LabelIPPoolCIDR = AnnotationPre + "/ippool-cidr"
@@ -112,6 +231,92 @@ const (
 	EventReasonScaleIPPool  = "ScaleIPPool"
 	EventReasonDeleteIPPool = "DeleteIPPool"
 	EventReasonResyncSubnet = "ResyncSubnet"
+
+	EventReasonQuarantineIPPool = "QuarantineIPPool"
+
+	EventReasonAnnotatedPoolFallback = "AnnotatedPoolFallback"
+
+	EventReasonReservedIPDenied = "ReservedIPDenied"
+
+	EventReasonUpdateIPPoolSpec = "UpdateIPPoolSpec"
+
+	EventReasonSelfVerificationFailed = "SelfVerificationFailed"
+
+	EventReasonReshapeIPPool = "ReshapeIPPool"
+
+	EventReasonConflictIPPool = "ConflictIPPool"
+
+	EventReasonGatewayUnreachable = "GatewayUnreachable"
+
+	EventReasonInsufficientSubnetIPs = "InsufficientSubnetIPs"
+
+	EventReasonAdoptIPPool = "AdoptIPPool"
+
+	EventReasonSyncSubnetDefaults = "SyncSubnetDefaults"
+
+	EventReasonDefragSubnet = "DefragSubnet"
+
+	EventReasonVacateIPs = "VacateIPs"
+
+	EventReasonGCSkipped = "GCSkipped"
+
+	EventReasonDoubleAllocation = "DoubleAllocation"
+)
+
+// SpiderIPPool condition types and reasons
+const (
+	IPPoolConditionQuarantined = "Quarantined"
+	IPPoolConditionVerified    = "Verified"
+	IPPoolConditionDrained     = "Drained"
+	IPPoolConditionReady       = "Ready"
+	IPPoolConditionExhausted   = "Exhausted"
+	IPPoolConditionConflicting = "Conflicting"
+	IPPoolConditionOrphaned    = "Orphaned"
+
+	IPPoolConditionGatewayUnreachable = "GatewayUnreachable"
+
+	IPPoolReasonRepeatedAllocationFailures = "RepeatedAllocationFailures"
+	IPPoolReasonCoolDownExpired            = "CoolDownExpired"
+	IPPoolReasonVerificationSucceeded      = "VerificationSucceeded"
+	IPPoolReasonVerificationFailed         = "VerificationFailed"
+	IPPoolReasonAllocationsRemaining       = "AllocationsRemaining"
+	IPPoolReasonAllocationsReleased        = "AllocationsReleased"
+	IPPoolReasonAvailable                  = "Available"
+	IPPoolReasonDisabled                   = "Disabled"
+	IPPoolReasonDraining                   = "Draining"
+	IPPoolReasonQuarantined                = "Quarantined"
+	IPPoolReasonReshaping                  = "Reshaping"
+	IPPoolReasonExhausted                  = "Exhausted"
+	IPPoolReasonConflicting                = "Conflicting"
+	IPPoolReasonOrphaned                   = "Orphaned"
+	IPPoolReasonFreeIPsAvailable           = "FreeIPsAvailable"
+	IPPoolReasonNoFreeIPs                  = "NoFreeIPs"
+	IPPoolReasonNoOverlap                  = "NoOverlap"
+	IPPoolReasonOverlappingIPPools         = "OverlappingIPPools"
+	IPPoolReasonReservedIPsInUse           = "ReservedIPsInUse"
+	IPPoolReasonSubnetControlled           = "SubnetControlled"
+	IPPoolReasonNoControllerSubnet         = "NoControllerSubnet"
+	IPPoolReasonUnreachableFromNodes       = "UnreachableFromNodes"
+	IPPoolReasonGatewayReachable           = "GatewayReachable"
+)
+
+// SpiderReservedIP condition types and reasons
+const (
+	ReservedIPConditionConflicting = "Conflicting"
+
+	ReservedIPReasonAllocatedIPs   = "AllocatedIPs"
+	ReservedIPReasonNoAllocatedIPs = "NoAllocatedIPs"
+)
+
+// The evidence of the IP addresses of SpiderIPPool suspected leaked, and the
+// actions planned by the IP garbage collection
+const (
+	IPLeakEvidencePodNotFound      = "PodNotFound"
+	IPLeakEvidencePodTerminated    = "PodTerminated"
+	IPLeakEvidenceStaleContainerID = "StaleContainerID"
+
+	IPLeakActionReleaseIP                   = "ReleaseIP"
+	IPLeakActionReleaseIPAndRemoveFinalizer = "ReleaseIPAndRemoveFinalizer"
 )
 
 const ClusterDefaultInterfaceName = "eth0"
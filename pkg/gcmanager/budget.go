@@ -0,0 +1,111 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package gcmanager
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	metrics "github.com/spidernet-io/spiderpool/pkg/metric"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// waitAPIBudget blocks until the API budget of IP garbage collection allows
+// one more write to the API server.
+func (s *SpiderGC) waitAPIBudget(ctx context.Context) error {
+	if s.apiLimiter == nil {
+		return nil
+	}
+
+	return s.apiLimiter.Wait(ctx)
+}
+
+// recordReclaim records the number of reclaimed IPs, and the lag since they
+// became eligible for release if it's known.
+func recordReclaim(ctx context.Context, num int, eligibleTime time.Time) {
+	metrics.IPGCReclaimedIPCounts.Add(ctx, int64(num))
+	if !eligibleTime.IsZero() {
+		metrics.IPGCReclaimLagSecondsHistogram.Record(ctx, time.Since(eligibleTime).Seconds())
+	}
+}
+
+// pendingRelease is the IP of an IPPool waiting to be released by scan-all.
+type pendingRelease struct {
+	ip         string
+	allocation spiderpoolv1.PoolIPAllocation
+	leak       spiderpoolv1.IPLeak
+	logger     *zap.Logger
+
+	// removeFinalizer removes the finalizer of the SpiderEndpoint once the
+	// IP is released.
+	removeFinalizer bool
+	// eligibleTime is when the IP became eligible for release, zero if
+	// unknown.
+	eligibleTime time.Time
+}
+
+// releaseBatch releases the pending IPs of the IPPool in one update, and
+// removes the finalizers of their SpiderEndpoints if required. The IPs not
+// released are recorded to leaks.
+func (s *SpiderGC) releaseBatch(ctx context.Context, poolName string, batch []pendingRelease, leaks map[string]spiderpoolv1.IPLeak) {
+	if len(batch) == 0 {
+		return
+	}
+
+	if s.gcConfig.DryRun {
+		for _, p := range batch {
+			if p.removeFinalizer {
+				p.logger.Sugar().Infof("dry run, would release ip '%s' and remove SpiderEndpoint '%s/%s' finalizer",
+					p.ip, p.allocation.Namespace, p.allocation.Pod)
+			} else {
+				p.logger.Sugar().Infof("dry run, would release ip '%s'", p.ip)
+			}
+			leaks[p.ip] = p.leak
+		}
+		return
+	}
+
+	ipAndCIDs := make([]types.IPAndCID, 0, len(batch))
+	for _, p := range batch {
+		ipAndCIDs = append(ipAndCIDs, types.IPAndCID{IP: p.ip, ContainerID: p.allocation.ContainerID})
+	}
+
+	err := s.waitAPIBudget(ctx)
+	if nil == err {
+		err = s.ippoolMgr.ReleaseIP(ctx, poolName, ipAndCIDs)
+	}
+	if nil != err {
+		metrics.IPGCFailureCounts.Add(ctx, 1)
+		for _, p := range batch {
+			p.logger.Sugar().Errorf("failed to release ip '%s', error: '%v'", p.ip, err)
+			leaks[p.ip] = p.leak
+		}
+		return
+	}
+
+	metrics.IPGCTotalCounts.Add(ctx, 1)
+	for _, p := range batch {
+		recordReclaim(ctx, 1, p.eligibleTime)
+		s.publishReclaim(ctx, ReclaimEvent{
+			IP:          p.ip,
+			Pool:        poolName,
+			Namespace:   p.allocation.Namespace,
+			Pod:         p.allocation.Pod,
+			ContainerID: p.allocation.ContainerID,
+			Node:        p.allocation.Node,
+			Reason:      p.leak.Evidence,
+		})
+		p.logger.Sugar().Infof("release ip '%s' successfully", p.ip)
+
+		if !p.removeFinalizer {
+			continue
+		}
+		if err := s.removeWEPFinalizer(ctx, p.logger, p.allocation.Namespace, p.allocation.Pod); nil != err {
+			p.logger.Error(err.Error())
+		}
+	}
+}
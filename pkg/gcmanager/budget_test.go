@@ -0,0 +1,234 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package gcmanager
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/lock"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// recordingIPPoolManager records the IPs released in each update, and fails
+// the releases of the IPPools in failedPools.
+type recordingIPPoolManager struct {
+	ippoolmanager.IPPoolManager
+
+	lock        lock.Mutex
+	releases    map[string][][]types.IPAndCID
+	failedPools map[string]struct{}
+}
+
+func (r *recordingIPPoolManager) ReleaseIP(ctx context.Context, poolName string, ipAndCIDs []types.IPAndCID) error {
+	r.lock.Lock()
+	r.releases[poolName] = append(r.releases[poolName], ipAndCIDs)
+	_, failed := r.failedPools[poolName]
+	r.lock.Unlock()
+	if failed {
+		return fmt.Errorf("failed to release IPs of IPPool %s", poolName)
+	}
+
+	return r.IPPoolManager.ReleaseIP(ctx, poolName, ipAndCIDs)
+}
+
+func (r *recordingIPPoolManager) batchSizes(poolName string) []int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var sizes []int
+	for _, ipAndCIDs := range r.releases[poolName] {
+		sizes = append(sizes, len(ipAndCIDs))
+	}
+	return sizes
+}
+
+var _ = Describe("API budget and batched release", Label("budget_test"), func() {
+	// newPool returns the IPPool whose IPs are all allocated to the pods not
+	// found in k8s.
+	newPool := func(name, subnet string, num int) *spiderpoolv1.SpiderIPPool {
+		pool := &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		pool.Spec.Subnet = subnet + ".0/24"
+		pool.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{}
+		for i := 0; i < num; i++ {
+			pool.Status.AllocatedIPs[fmt.Sprintf("%s.%d", subnet, i+10)] = spiderpoolv1.PoolIPAllocation{
+				ContainerID: fmt.Sprintf("%s-c%d", name, i),
+				NIC:         "eth0",
+				Namespace:   "default",
+				Pod:         fmt.Sprintf("%s-pod%d", name, i),
+			}
+		}
+		pool.Status.AllocatedIPCount = new(int64)
+		*pool.Status.AllocatedIPCount = int64(num)
+		return pool
+	}
+
+	var gc *SpiderGC
+	var fakeClient client.Client
+	var recorder *recordingIPPoolManager
+	BeforeEach(func() {
+		fakeClient = newFakeClient(
+			newPool("pool1", "172.18.40", 5),
+			newPool("pool2", "172.18.41", 3),
+		)
+		gc = newTestSpiderGC(fakeClient, &GarbageCollectionConfig{ReleaseBatchSize: 2, ScanAllWorkerNum: 2})
+		recorder = &recordingIPPoolManager{
+			IPPoolManager: gc.ippoolMgr,
+			releases:      map[string][][]types.IPAndCID{},
+			failedPools:   map[string]struct{}{},
+		}
+		gc.ippoolMgr = recorder
+	})
+
+	getPool := func(name string) *spiderpoolv1.SpiderIPPool {
+		var pool spiderpoolv1.SpiderIPPool
+		err := fakeClient.Get(context.TODO(), client.ObjectKey{Name: name}, &pool)
+		Expect(err).NotTo(HaveOccurred())
+		return &pool
+	}
+
+	// exhaustBudget leaves no API budget for the next hour.
+	exhaustBudget := func() {
+		gc.apiLimiter = rate.NewLimiter(rate.Every(time.Hour), 1)
+		Expect(gc.apiLimiter.Allow()).To(BeTrue())
+	}
+
+	Describe("waitAPIBudget", func() {
+		It("is unlimited without the limiter", func() {
+			Expect(gc.waitAPIBudget(context.TODO())).To(Succeed())
+		})
+
+		It("allows the writes within the burst", func() {
+			gc.apiLimiter = rate.NewLimiter(rate.Every(time.Hour), 2)
+			ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+			defer cancel()
+			Expect(gc.waitAPIBudget(ctx)).To(Succeed())
+			Expect(gc.waitAPIBudget(ctx)).To(Succeed())
+		})
+
+		It("fails once the budget is exhausted before ctx is done", func() {
+			exhaustBudget()
+			ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+			defer cancel()
+			Expect(gc.waitAPIBudget(ctx)).NotTo(Succeed())
+		})
+	})
+
+	Describe("releaseBatch", func() {
+		newBatch := func() []pendingRelease {
+			return []pendingRelease{
+				{
+					ip:         "172.18.40.10",
+					allocation: spiderpoolv1.PoolIPAllocation{ContainerID: "pool1-c0", Namespace: "default", Pod: "pool1-pod0"},
+					leak:       spiderpoolv1.IPLeak{Evidence: "PodNotFound", ContainerID: "pool1-c0"},
+					logger:     logger,
+				},
+				{
+					ip:         "172.18.40.11",
+					allocation: spiderpoolv1.PoolIPAllocation{ContainerID: "pool1-c1", Namespace: "default", Pod: "pool1-pod1"},
+					leak:       spiderpoolv1.IPLeak{Evidence: "PodNotFound", ContainerID: "pool1-c1"},
+					logger:     logger,
+				},
+			}
+		}
+
+		It("releases the batch in one update", func() {
+			leaks := map[string]spiderpoolv1.IPLeak{}
+			gc.releaseBatch(context.TODO(), "pool1", newBatch(), leaks)
+			Expect(leaks).To(BeEmpty())
+			Expect(recorder.batchSizes("pool1")).To(Equal([]int{2}))
+			Expect(getPool("pool1").Status.AllocatedIPs).To(HaveLen(3))
+		})
+
+		It("only records the leaks in dry-run mode", func() {
+			gc.gcConfig.DryRun = true
+			leaks := map[string]spiderpoolv1.IPLeak{}
+			gc.releaseBatch(context.TODO(), "pool1", newBatch(), leaks)
+			Expect(leaks).To(HaveLen(2))
+			Expect(recorder.batchSizes("pool1")).To(BeEmpty())
+		})
+
+		It("records the whole batch as leaks once the release fails", func() {
+			recorder.failedPools["pool1"] = struct{}{}
+			leaks := map[string]spiderpoolv1.IPLeak{}
+			gc.releaseBatch(context.TODO(), "pool1", newBatch(), leaks)
+			Expect(leaks).To(HaveKey("172.18.40.10"))
+			Expect(leaks).To(HaveKey("172.18.40.11"))
+			Expect(getPool("pool1").Status.AllocatedIPs).To(HaveLen(5))
+		})
+
+		It("records the whole batch as leaks once the API budget is exhausted", func() {
+			exhaustBudget()
+			ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+			defer cancel()
+			leaks := map[string]spiderpoolv1.IPLeak{}
+			gc.releaseBatch(ctx, "pool1", newBatch(), leaks)
+			Expect(leaks).To(HaveLen(2))
+			Expect(recorder.batchSizes("pool1")).To(BeEmpty())
+		})
+	})
+
+	Describe("scanIPPool", func() {
+		It("releases the leaked IPs in batches of ReleaseBatchSize", func() {
+			var scanned atomic.Int64
+			gc.scanIPPool(context.TODO(), getPool("pool1"), gcPace{}, &scanned)
+			Expect(scanned.Load()).To(BeEquivalentTo(5))
+			Expect(recorder.batchSizes("pool1")).To(Equal([]int{2, 2, 1}))
+			Expect(getPool("pool1").Status.AllocatedIPs).To(BeEmpty())
+		})
+
+		It("releases the IPs one by one without a valid ReleaseBatchSize", func() {
+			gc.gcConfig.ReleaseBatchSize = 0
+			var scanned atomic.Int64
+			gc.scanIPPool(context.TODO(), getPool("pool2"), gcPace{}, &scanned)
+			Expect(recorder.batchSizes("pool2")).To(Equal([]int{1, 1, 1}))
+		})
+
+		It("reports the IPs failed to be released as leaks", func() {
+			recorder.failedPools["pool1"] = struct{}{}
+			var scanned atomic.Int64
+			gc.scanIPPool(context.TODO(), getPool("pool1"), gcPace{}, &scanned)
+
+			pool := getPool("pool1")
+			Expect(pool.Status.AllocatedIPs).To(HaveLen(5))
+			Expect(pool.Status.SuspectedLeakedIPs).To(HaveLen(5))
+		})
+
+		It("stops pausing between the scan batches once ctx is done", func() {
+			ctx, cancel := context.WithCancel(context.TODO())
+			cancel()
+			var scanned atomic.Int64
+			gc.scanIPPool(ctx, getPool("pool1"), gcPace{batchSize: 1, batchGap: time.Hour}, &scanned)
+			Expect(scanned.Load()).To(BeEquivalentTo(1))
+			Expect(recorder.batchSizes("pool1")).To(BeEmpty())
+		})
+	})
+
+	Describe("executeScanAll", func() {
+		It("scans all the IPPools with the workers", func() {
+			gc.executeScanAll(context.TODO())
+			Expect(recorder.batchSizes("pool1")).To(Equal([]int{2, 2, 1}))
+			Expect(recorder.batchSizes("pool2")).To(Equal([]int{2, 1}))
+			Expect(getPool("pool1").Status.AllocatedIPs).To(BeEmpty())
+			Expect(getPool("pool2").Status.AllocatedIPs).To(BeEmpty())
+		})
+
+		It("goes on with the other IPPools once a worker fails to release", func() {
+			recorder.failedPools["pool1"] = struct{}{}
+			gc.executeScanAll(context.TODO())
+			Expect(getPool("pool1").Status.SuspectedLeakedIPs).To(HaveLen(5))
+			Expect(getPool("pool2").Status.AllocatedIPs).To(BeEmpty())
+		})
+	})
+})
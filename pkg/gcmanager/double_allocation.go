@@ -0,0 +1,268 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package gcmanager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/event"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	metrics "github.com/spidernet-io/spiderpool/pkg/metric"
+)
+
+// The policies to remediate the IPs allocated more than once.
+const (
+	// DoubleAllocationPolicyReport only reports the double allocations with
+	// metric and events.
+	DoubleAllocationPolicyReport = "report"
+	// DoubleAllocationPolicyQuarantine also quarantines the IPs by
+	// SpiderReservedIPs, so that they are not allocated to any other pod and
+	// are vacated once their pods restart.
+	DoubleAllocationPolicyQuarantine = "quarantine"
+	// DoubleAllocationPolicyEvict also evicts the newer pods claiming the
+	// quarantined IPs, the oldest one keeps the IP.
+	DoubleAllocationPolicyEvict = "evict"
+)
+
+// doubleAllocationQuarantineReason labels the SpiderReservedIPs quarantining the double allocated IPs.
+const doubleAllocationQuarantineReason = "double-allocation"
+
+func validateDoubleAllocationPolicy(policy string) error {
+	switch policy {
+	case DoubleAllocationPolicyReport, DoubleAllocationPolicyQuarantine, DoubleAllocationPolicyEvict:
+		return nil
+	default:
+		return fmt.Errorf("unknown double allocation policy '%s', it should be one of '%s', '%s' and '%s'", policy,
+			DoubleAllocationPolicyReport, DoubleAllocationPolicyQuarantine, DoubleAllocationPolicyEvict)
+	}
+}
+
+// doubleAllocation is an IP allocated more than once in the IPPools of a tenant, the IPPools of different tenants are
+// allowed to overlap.
+type doubleAllocation struct {
+	tenant string
+	ip     string
+}
+
+func (d doubleAllocation) String() string {
+	if d.tenant == "" {
+		return d.ip
+	}
+	return d.ip + " of tenant " + d.tenant
+}
+
+// detectDoubleAllocations finds the IPs allocated in more than one SpiderIPPool or currently taken by more than one
+// SpiderEndpoint of the same tenant, which are left by past bugs or manual edits. They are exported with metric, the
+// newly found ones are reported with events, and they are remediated following DoubleAllocationPolicy unless in dry-run
+// mode or any of their SpiderIPPools and SpiderEndpoints is frozen from the IP garbage collection.
+func (s *SpiderGC) detectDoubleAllocations(ctx context.Context) {
+	// only the elected controller detects the double allocations
+	if !s.leader.IsElected() {
+		return
+	}
+
+	poolList, err := s.ippoolMgr.ListIPPools(ctx)
+	if nil != err {
+		logger.Sugar().Errorf("failed to list SpiderIPPools to detect double allocations: %v", err)
+		return
+	}
+	endpointList, err := s.wepMgr.ListEndpoints(ctx)
+	if nil != err {
+		logger.Sugar().Errorf("failed to list SpiderEndpoints to detect double allocations: %v", err)
+		return
+	}
+
+	claimsByIP := map[doubleAllocation][]ippoolmanager.AuditMismatch{}
+	for _, claim := range ippoolmanager.FindDoubleAllocations(poolList.Items, endpointList.Items) {
+		key := doubleAllocation{tenant: claim.Tenant, ip: claim.IP}
+		claimsByIP[key] = append(claimsByIP[key], claim)
+	}
+	metrics.IPGCDoubleAllocatedIPCounts.Record(int64(len(claimsByIP)))
+
+	pools := make(map[string]*spiderpoolv1.SpiderIPPool, len(poolList.Items))
+	for i := range poolList.Items {
+		pools[poolList.Items[i].Name] = &poolList.Items[i]
+	}
+	endpoints := make(map[string]*spiderpoolv1.SpiderEndpoint, len(endpointList.Items))
+	for i := range endpointList.Items {
+		endpoints[endpointList.Items[i].Namespace+"/"+endpointList.Items[i].Name] = &endpointList.Items[i]
+	}
+
+	detected := make(map[doubleAllocation]struct{}, len(claimsByIP))
+	remediated := 0
+	for key, claims := range claimsByIP {
+		detected[key] = struct{}{}
+		if _, ok := s.doubleAllocatedIPs[key]; !ok {
+			reportDoubleAllocation(key, claims, pools)
+		}
+
+		if s.gcConfig.DryRun || s.gcConfig.DoubleAllocationPolicy == DoubleAllocationPolicyReport {
+			continue
+		}
+		if isDoubleAllocationGCSkipped(ctx, claims, pools, endpoints) {
+			logger.Sugar().Warnf("skip remediating double allocated IP %s, its SpiderIPPools or SpiderEndpoints are frozen", key)
+			continue
+		}
+		if key.tenant == "" && hasUnlabeledTenantIPPools(poolList.Items) {
+			logger.Sugar().Warnf("skip remediating double allocated IP %s, the quarantine can't exclude the IPPools of the other tenants without label '%s'",
+				key, constant.LabelIPPoolTenant)
+			continue
+		}
+		if err := s.Pause(ctx, remediated); err != nil {
+			return
+		}
+		remediated++
+		s.remediateDoubleAllocation(ctx, key, claims)
+	}
+	s.doubleAllocatedIPs = detected
+}
+
+// isDoubleAllocationGCSkipped reports whether any SpiderIPPool or SpiderEndpoint claiming the double allocated IP is
+// frozen from the IP garbage collection.
+func isDoubleAllocationGCSkipped(ctx context.Context, claims []ippoolmanager.AuditMismatch,
+	pools map[string]*spiderpoolv1.SpiderIPPool, endpoints map[string]*spiderpoolv1.SpiderEndpoint) bool {
+	skipped := false
+	for _, claim := range claims {
+		if pool, ok := pools[claim.Pool]; ok && isGCSkipped(ctx, pool, constant.SpiderIPPoolKind) {
+			skipped = true
+		}
+		if endpoint, ok := endpoints[claim.Namespace+"/"+claim.Pod]; ok && isGCSkipped(ctx, endpoint, constant.SpiderEndpointKind) {
+			skipped = true
+		}
+	}
+
+	return skipped
+}
+
+// hasUnlabeledTenantIPPools reports whether any IPPool of a non-default tenant lacks the tenant label, such as the
+// ones created before the label was introduced and not updated since, which would be selected as the IPPools of the
+// default tenant.
+func hasUnlabeledTenantIPPools(pools []spiderpoolv1.SpiderIPPool) bool {
+	for i := range pools {
+		if tenant := ippoolmanager.GetIPPoolTenant(&pools[i]); tenant != "" && pools[i].Labels[constant.LabelIPPoolTenant] != tenant {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reportDoubleAllocation logs the double allocated IP and records events on its SpiderIPPools.
+func reportDoubleAllocation(key doubleAllocation, claims []ippoolmanager.AuditMismatch, pools map[string]*spiderpoolv1.SpiderIPPool) {
+	podNames := claimantPods(claims)
+	logger.Sugar().Warnf("IP %s is double allocated to pods %v", key, podNames)
+
+	reported := map[string]struct{}{}
+	for _, claim := range claims {
+		pool, ok := pools[claim.Pool]
+		if !ok {
+			continue
+		}
+		if _, ok := reported[pool.Name]; ok {
+			continue
+		}
+		reported[pool.Name] = struct{}{}
+		event.EventRecorder.Eventf(pool, corev1.EventTypeWarning, constant.EventReasonDoubleAllocation,
+			"IP address %s is double allocated to Pods %s", key, strings.Join(podNames, ", "))
+	}
+}
+
+// claimantPods returns the distinct pods claiming the IP in order.
+func claimantPods(claims []ippoolmanager.AuditMismatch) []string {
+	seen := map[string]struct{}{}
+	var podNames []string
+	for _, claim := range claims {
+		name := claim.Namespace + "/" + claim.Pod
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		podNames = append(podNames, name)
+	}
+	sort.Strings(podNames)
+
+	return podNames
+}
+
+// remediateDoubleAllocation quarantines the double allocated IP in its tenant, and evicts the newer pods claiming it if
+// DoubleAllocationPolicy is evict.
+func (s *SpiderGC) remediateDoubleAllocation(ctx context.Context, key doubleAllocation, claims []ippoolmanager.AuditMismatch) {
+	ip := key.String()
+	quarantined, err := s.rIPMgr.QuarantineIP(ctx, key.ip, key.tenant, doubleAllocationQuarantineReason,
+		time.Duration(s.gcConfig.DoubleAllocationQuarantineDuration)*time.Second)
+	if nil != err {
+		logger.Sugar().Errorf("failed to quarantine double allocated IP %s: %v", ip, err)
+		return
+	}
+	if quarantined {
+		metrics.IPGCQuarantinedIPCounts.Add(ctx, 1)
+		logger.Sugar().Warnf("quarantine double allocated IP %s", ip)
+	}
+
+	if s.gcConfig.DoubleAllocationPolicy != DoubleAllocationPolicyEvict {
+		return
+	}
+
+	var pods []*corev1.Pod
+	for _, name := range claimantPods(claims) {
+		namespace, podName, _ := strings.Cut(name, "/")
+		pod, err := s.podMgr.GetPodByName(ctx, namespace, podName)
+		if nil != err {
+			if !apierrors.IsNotFound(err) {
+				logger.Sugar().Errorf("failed to get pod '%s' claiming double allocated IP %s: %v", name, ip, err)
+				return
+			}
+			continue
+		}
+		pods = append(pods, pod)
+	}
+	if len(pods) < 2 {
+		return
+	}
+
+	// the oldest pod keeps the IP
+	sort.SliceStable(pods, func(i, j int) bool {
+		return pods[i].CreationTimestamp.Before(&pods[j].CreationTimestamp)
+	})
+	for _, pod := range pods[1:] {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if err := s.evictPod(ctx, pod); nil != err {
+			logger.Sugar().Errorf("failed to evict pod '%s/%s' claiming double allocated IP %s: %v", pod.Namespace, pod.Name, ip, err)
+			continue
+		}
+
+		metrics.IPGCDoubleAllocationEvictedPodCounts.Add(ctx, 1)
+		event.EventRecorder.Eventf(pod, corev1.EventTypeWarning, constant.EventReasonDoubleAllocation,
+			"Evicted since IP address %s is also allocated to the older Pod %s/%s", ip, pods[0].Namespace, pods[0].Name)
+		logger.Sugar().Warnf("evict pod '%s/%s' claiming double allocated IP %s, pod '%s/%s' keeps it",
+			pod.Namespace, pod.Name, ip, pods[0].Namespace, pods[0].Name)
+	}
+}
+
+// evictPod evicts the pod through the eviction API, which respects its PodDisruptionBudgets.
+func (s *SpiderGC) evictPod(ctx context.Context, pod *corev1.Pod) error {
+	if err := s.waitAPIBudget(ctx); nil != err {
+		return err
+	}
+
+	return s.k8ClientSet.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: pod.Namespace,
+			Name:      pod.Name,
+		},
+	})
+}
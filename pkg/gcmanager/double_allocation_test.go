@@ -0,0 +1,118 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package gcmanager
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+)
+
+var _ = Describe("detectDoubleAllocations", Label("double_allocation_test"), func() {
+	const ip = "172.18.40.10"
+
+	newPool := func(name, tenant, podName string) *spiderpoolv1.SpiderIPPool {
+		pool := &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		pool.Spec.Subnet = "172.18.40.0/24"
+		if tenant != "" {
+			pool.Spec.Tenant = pointer.String(tenant)
+			pool.Labels = map[string]string{constant.LabelIPPoolTenant: tenant}
+		}
+		pool.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{
+			ip: {ContainerID: podName + "-c", NIC: "eth0", Namespace: "default", Pod: podName},
+		}
+		return pool
+	}
+
+	var gc *SpiderGC
+	var fakeClient client.Client
+
+	setup := func(objs ...client.Object) {
+		fakeClient = newFakeClient(objs...)
+		gc = newTestSpiderGC(fakeClient, &GarbageCollectionConfig{DoubleAllocationPolicy: DoubleAllocationPolicyQuarantine})
+	}
+
+	quarantine := func(tenant string) error {
+		var rIP spiderpoolv1.SpiderReservedIP
+		return fakeClient.Get(context.TODO(), client.ObjectKey{Name: reservedipmanager.QuarantineReservedIPName(ip, tenant)}, &rIP)
+	}
+
+	It("ignores the same IP allocated in the IPPools of different tenants", func() {
+		setup(newPool("pool1", "tenant1", "pod1"), newPool("pool2", "tenant2", "pod2"))
+
+		gc.detectDoubleAllocations(context.TODO())
+		Expect(gc.doubleAllocatedIPs).To(BeEmpty())
+		Expect(apierrors.IsNotFound(quarantine("tenant1"))).To(BeTrue())
+		Expect(apierrors.IsNotFound(quarantine("tenant2"))).To(BeTrue())
+		Expect(apierrors.IsNotFound(quarantine(""))).To(BeTrue())
+	})
+
+	It("quarantines the IP double allocated in the same tenant", func() {
+		setup(newPool("pool1", "tenant1", "pod1"), newPool("pool2", "tenant1", "pod2"), newPool("pool3", "tenant2", "pod3"))
+
+		gc.detectDoubleAllocations(context.TODO())
+		Expect(gc.doubleAllocatedIPs).To(HaveKey(doubleAllocation{tenant: "tenant1", ip: ip}))
+		Expect(gc.doubleAllocatedIPs).To(HaveLen(1))
+		Expect(quarantine("tenant1")).To(Succeed())
+		Expect(apierrors.IsNotFound(quarantine(""))).To(BeTrue())
+	})
+
+	It("does not remediate the IP if any of its IPPools is frozen from gc", func() {
+		frozen := newPool("pool2", "", "pod2")
+		frozen.Annotations = map[string]string{constant.AnnoGCSkip: constant.True}
+		setup(newPool("pool1", "", "pod1"), frozen)
+
+		gc.detectDoubleAllocations(context.TODO())
+		Expect(gc.doubleAllocatedIPs).To(HaveLen(1))
+		Expect(apierrors.IsNotFound(quarantine(""))).To(BeTrue())
+	})
+
+	It("does not remediate the IP if any of its SpiderEndpoints is frozen from gc", func() {
+		endpoint := &spiderpoolv1.SpiderEndpoint{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "pod2",
+			Annotations: map[string]string{constant.AnnoGCSkip: constant.True},
+		}}
+		setup(newPool("pool1", "", "pod1"), newPool("pool2", "", "pod2"), endpoint)
+
+		gc.detectDoubleAllocations(context.TODO())
+		Expect(apierrors.IsNotFound(quarantine(""))).To(BeTrue())
+	})
+
+	It("does not remediate the IP of the default tenant if the IPPools of other tenants are not labeled", func() {
+		unlabeled := newPool("pool3", "tenant1", "pod3")
+		unlabeled.Labels = nil
+		unlabeled.Status.AllocatedIPs = nil
+		setup(newPool("pool1", "", "pod1"), newPool("pool2", "", "pod2"), unlabeled)
+
+		gc.detectDoubleAllocations(context.TODO())
+		Expect(gc.doubleAllocatedIPs).To(HaveLen(1))
+		Expect(apierrors.IsNotFound(quarantine(""))).To(BeTrue())
+	})
+
+	It("quarantines the IP of the default tenant", func() {
+		setup(newPool("pool1", "", "pod1"), newPool("pool2", "", "pod2"))
+
+		gc.detectDoubleAllocations(context.TODO())
+		Expect(quarantine("")).To(Succeed())
+	})
+
+	It("only reports the IP in dry-run mode", func() {
+		setup(newPool("pool1", "", "pod1"), newPool("pool2", "", "pod2"))
+		gc.gcConfig.DryRun = true
+
+		gc.detectDoubleAllocations(context.TODO())
+		Expect(gc.doubleAllocatedIPs).To(HaveLen(1))
+		Expect(apierrors.IsNotFound(quarantine(""))).To(BeTrue())
+	})
+})
@@ -6,15 +6,21 @@ package gcmanager
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"time"
 
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 
 	"github.com/spidernet-io/spiderpool/pkg/election"
 	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	"github.com/spidernet-io/spiderpool/pkg/limiter"
 	"github.com/spidernet-io/spiderpool/pkg/logutils"
 	"github.com/spidernet-io/spiderpool/pkg/podmanager"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
 	"github.com/spidernet-io/spiderpool/pkg/statefulsetmanager"
 	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
 )
@@ -22,8 +28,20 @@ import (
 type GarbageCollectionConfig struct {
 	EnableGCIP                bool
 	EnableGCForTerminatingPod bool
+	EnableGCForDeletedNode    bool
 	EnableStatefulSet         bool
 
+	// DeletedNodeGraceDelay is how long to wait before checking the alive
+	// pods bound to a deleted node again, whose IPs are kept until the pods
+	// are gone or terminating past their grace period.
+	DeletedNodeGraceDelay int
+
+	// EnableGCForStuckPod releases the IPs of the pods stuck in 'Terminating'
+	// on the NotReady nodes, including the StatefulSet ones, StuckPodGraceDelay
+	// seconds after their grace period.
+	EnableGCForStuckPod bool
+	StuckPodGraceDelay  int
+
 	ReleaseIPWorkerNum     int
 	GCIPChannelBuffer      int
 	MaxPodEntryDatabaseCap int
@@ -33,8 +51,72 @@ type GarbageCollectionConfig struct {
 	GCSignalTimeoutDuration   int
 	GCSignalGapDuration       int
 	AdditionalGraceDelay      int
+
+	// The delays after the grace period of the Pods in each phase before
+	// their IP addresses are released, AdditionalGraceDelay is used if
+	// negative.
+	TerminatingPodGraceDelay int
+	SucceededPodGraceDelay   int
+	FailedPodGraceDelay      int
+	EvictedPodGraceDelay     int
+	DeletedPodGraceDelay     int
+
+	// DryRun only logs the IP addresses and SpiderEndpoints which would be
+	// cleaned up, without releasing them.
+	DryRun bool
+
+	// EnableAdaptivePacing adapts the pace of IP garbage collection to the
+	// cluster churn rate, BusyChurnRate is the number of Pod creations and
+	// deletions per second regarded as mass rescheduling.
+	EnableAdaptivePacing bool
+	BusyChurnRate        int
+
+	// ScanAllWorkerNum is the number of IPPools checked by scan-all
+	// concurrently.
+	ScanAllWorkerNum int
+	// ReleaseBatchSize is the max number of IPs of an IPPool released by
+	// scan-all in one update.
+	ReleaseBatchSize int
+	// APIQPS and APIBurst budget the writes of IP garbage collection to the
+	// API server, a non-positive APIQPS means unlimited.
+	APIQPS   int
+	APIBurst int
+
+	// EnableZombieContainerIDReconcile rewrites the IPPool allocations whose
+	// containerIDs no longer match the current containers of the alive Pods
+	// using the IPs, instead of releasing them.
+	EnableZombieContainerIDReconcile bool
+
+	// OrphanedEndpointMaxAge is how long the SpiderEndpoints without pods and
+	// current IP allocations stay inactive before they are cleaned up every
+	// OrphanedEndpointCheckInterval seconds, a non-positive value disables
+	// the cleanup.
+	OrphanedEndpointMaxAge        int
+	OrphanedEndpointCheckInterval int
+
+	// DoubleAllocationCheckInterval is how often the IPs allocated more than
+	// once are detected in seconds, a non-positive value disables the
+	// detection. They are remediated following DoubleAllocationPolicy, and
+	// quarantined for DoubleAllocationQuarantineDuration seconds, forever
+	// if not positive.
+	DoubleAllocationCheckInterval      int
+	DoubleAllocationPolicy             string
+	DoubleAllocationQuarantineDuration int
+
+	// ReclaimWebhookURL is the external webhook which every IP released by
+	// IP garbage collection is posted to, empty to disable it. Each post
+	// times out in ReclaimWebhookTimeout seconds and is retried up to
+	// ReclaimWebhookMaxRetries times, at most ReclaimWebhookQueueSize events
+	// wait for delivery.
+	ReclaimWebhookURL        string
+	ReclaimWebhookTimeout    int
+	ReclaimWebhookMaxRetries int
+	ReclaimWebhookQueueSize  int
 }
 
+// gcNodeChannelBuffer is the number of the deleted nodes waiting for their IPs to be reclaimed.
+const gcNodeChannelBuffer = 100
+
 var logger *zap.Logger
 
 type GCManager interface {
@@ -45,6 +127,10 @@ type GCManager interface {
 	TriggerGCAll()
 
 	Health()
+
+	// Pacer paces the other bulk API writers with the pace of IP garbage
+	// collection.
+	limiter.Pacer
 }
 
 var _ GCManager = &SpiderGC{}
@@ -59,11 +145,26 @@ type SpiderGC struct {
 	// signal
 	gcSignal         chan struct{}
 	gcIPPoolIPSignal chan *PodEntry
+	gcNodeSignal     chan string
+
+	churn *churnMeter
+	// apiLimiter budgets the writes to the API server, nil means unlimited.
+	apiLimiter *rate.Limiter
 
 	wepMgr    workloadendpointmanager.WorkloadEndpointManager
 	ippoolMgr ippoolmanager.IPPoolManager
 	podMgr    podmanager.PodManager
 	stsMgr    statefulsetmanager.StatefulSetManager
+	rIPMgr    reservedipmanager.ReservedIPManager
+
+	// nodeLister lists the nodes from the cache of the node informer, nil if it's not started.
+	nodeLister corelisters.NodeLister
+
+	// doubleAllocatedIPs are the double allocated IPs found last time.
+	doubleAllocatedIPs map[doubleAllocation]struct{}
+
+	// reclaimWebhook publishes the released IPs, nil if disabled.
+	reclaimWebhook *reclaimWebhook
 
 	leader election.SpiderLeaseElector
 }
@@ -73,6 +174,7 @@ func NewGCManager(ctx context.Context, clientSet *kubernetes.Clientset, config *
 	ippoolManager ippoolmanager.IPPoolManager,
 	podManager podmanager.PodManager,
 	stsManager statefulsetmanager.StatefulSetManager,
+	rIPManager reservedipmanager.ReservedIPManager,
 	spiderControllerLeader election.SpiderLeaseElector) (GCManager, error) {
 	if clientSet == nil {
 		return nil, fmt.Errorf("k8s ClientSet must be specified")
@@ -94,12 +196,44 @@ func NewGCManager(ctx context.Context, clientSet *kubernetes.Clientset, config *
 		return nil, fmt.Errorf("pod manager must be specified")
 	}
 
+	if rIPManager == nil {
+		return nil, fmt.Errorf("reservedIP manager must be specified")
+	}
+
 	if spiderControllerLeader == nil {
 		return nil, fmt.Errorf("spiderpool controller leader must be specified")
 	}
 
+	if config.DoubleAllocationCheckInterval > 0 {
+		if err := validateDoubleAllocationPolicy(config.DoubleAllocationPolicy); nil != err {
+			return nil, err
+		}
+	}
+
+	if config.EnableGCForDeletedNode && config.DeletedNodeGraceDelay <= 0 {
+		return nil, fmt.Errorf("deleted node grace delay must be positive, got %d", config.DeletedNodeGraceDelay)
+	}
+
+	if config.ReclaimWebhookURL != "" {
+		if _, err := url.ParseRequestURI(config.ReclaimWebhookURL); nil != err {
+			return nil, fmt.Errorf("invalid reclaim webhook URL '%s': %v", config.ReclaimWebhookURL, err)
+		}
+		if config.ReclaimWebhookQueueSize <= 0 {
+			return nil, fmt.Errorf("reclaim webhook queue size must be positive, got %d", config.ReclaimWebhookQueueSize)
+		}
+	}
+
 	logger = logutils.Logger.Named("IP-GarbageCollection")
 
+	var apiLimiter *rate.Limiter
+	if config.APIQPS > 0 {
+		burst := config.APIBurst
+		if burst < config.APIQPS {
+			burst = config.APIQPS
+		}
+		apiLimiter = rate.NewLimiter(rate.Limit(config.APIQPS), burst)
+	}
+
 	spiderGC := &SpiderGC{
 		k8ClientSet: clientSet,
 		PodDB:       NewPodDBer(config.MaxPodEntryDatabaseCap),
@@ -107,11 +241,18 @@ func NewGCManager(ctx context.Context, clientSet *kubernetes.Clientset, config *
 
 		gcSignal:         make(chan struct{}, 1),
 		gcIPPoolIPSignal: make(chan *PodEntry, config.GCIPChannelBuffer),
+		gcNodeSignal:     make(chan string, gcNodeChannelBuffer),
+
+		churn:      &churnMeter{},
+		apiLimiter: apiLimiter,
 
 		wepMgr:    wepManager,
 		ippoolMgr: ippoolManager,
 		podMgr:    podManager,
 		stsMgr:    stsManager,
+		rIPMgr:    rIPManager,
+
+		reclaimWebhook: newReclaimWebhook(config),
 
 		leader: spiderControllerLeader,
 	}
@@ -125,6 +266,13 @@ func (s *SpiderGC) Start(ctx context.Context) error {
 		return nil
 	}
 
+	// the pods stuck on the NotReady nodes are told by the node lister, which is synced before the pod informer starts
+	if s.gcConfig.EnableGCForDeletedNode || s.gcConfig.EnableGCForStuckPod {
+		if err := s.startNodeInformer(ctx); nil != err {
+			return err
+		}
+	}
+
 	// start pod informer
 	go s.startPodInformer()
 
@@ -138,6 +286,30 @@ func (s *SpiderGC) Start(ctx context.Context) error {
 		go s.releaseIPPoolIPExecutor(ctx, i)
 	}
 
+	// reclaim the IPs of the deleted nodes right away
+	if s.gcConfig.EnableGCForDeletedNode {
+		go s.reclaimNodeIPExecutor(ctx)
+	}
+
+	// clean up the orphaned SpiderEndpoints independent of the IP release
+	if s.gcConfig.OrphanedEndpointMaxAge > 0 && s.gcConfig.OrphanedEndpointCheckInterval > 0 {
+		go wait.UntilWithContext(ctx, s.cleanupOrphanedEndpoints, time.Duration(s.gcConfig.OrphanedEndpointCheckInterval)*time.Second)
+	}
+
+	// detect and remediate the IPs allocated more than once
+	if s.gcConfig.DoubleAllocationCheckInterval > 0 {
+		go wait.UntilWithContext(ctx, s.detectDoubleAllocations, time.Duration(s.gcConfig.DoubleAllocationCheckInterval)*time.Second)
+	}
+
+	// publish the released IPs to the external webhook
+	if s.reclaimWebhook != nil {
+		go s.reclaimWebhook.run(ctx)
+	}
+
+	if s.gcConfig.DryRun {
+		logger.Warn("IP garbage collection runs in dry-run mode, nothing is released")
+	}
+
 	logger.Info("running IP garbage collection")
 	return nil
 }
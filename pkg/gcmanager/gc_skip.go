@@ -0,0 +1,59 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package gcmanager
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/event"
+	metrics "github.com/spidernet-io/spiderpool/pkg/metric"
+)
+
+// isGCSkipped reports whether the SpiderIPPool or SpiderEndpoint is frozen from the IP garbage collection by annotation,
+// the skipped one is reflected in metric and event.
+func isGCSkipped(ctx context.Context, obj client.Object, kind string) bool {
+	if obj.GetAnnotations()[constant.AnnoGCSkip] != constant.True {
+		return false
+	}
+
+	metrics.IPGCSkippedCounts.Add(ctx, 1, attribute.String(metrics.AttrKeyKind, kind))
+	event.EventRecorder.Eventf(obj, corev1.EventTypeNormal, constant.EventReasonGCSkipped,
+		"IP garbage collection is skipped due to annotation %s", constant.AnnoGCSkip)
+	logger.Sugar().Infof("%s '%s' is skipped by IP garbage collection due to annotation '%s'", kind, client.ObjectKeyFromObject(obj), constant.AnnoGCSkip)
+
+	return true
+}
+
+// isEndpointGCSkipped reports whether the SpiderEndpoint of the pod is frozen from the IP garbage collection, the pod
+// without SpiderEndpoint is never skipped.
+func (s *SpiderGC) isEndpointGCSkipped(ctx context.Context, namespace, podName string) (bool, error) {
+	endpoint, err := s.wepMgr.GetEndpointByName(ctx, namespace, podName)
+	if nil != err {
+		if client.IgnoreNotFound(err) == nil {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get SpiderEndpoint '%s/%s': %v", namespace, podName, err)
+	}
+
+	return isGCSkipped(ctx, endpoint, constant.SpiderEndpointKind), nil
+}
+
+// isIPPoolGCSkipped reports whether the SpiderIPPool is frozen from the IP garbage collection.
+func (s *SpiderGC) isIPPoolGCSkipped(ctx context.Context, poolName string) (bool, error) {
+	pool, err := s.ippoolMgr.GetIPPoolByName(ctx, poolName)
+	if nil != err {
+		if client.IgnoreNotFound(err) == nil {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get SpiderIPPool '%s': %v", poolName, err)
+	}
+
+	return isGCSkipped(ctx, pool, constant.SpiderIPPoolKind), nil
+}
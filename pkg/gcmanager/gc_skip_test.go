@@ -0,0 +1,152 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package gcmanager
+
+import (
+	"context"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/event"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+)
+
+var _ = Describe("GC skip annotation", Label("gc_skip_test"), func() {
+	var gc *SpiderGC
+	var fakeClient client.Client
+	var recorder *record.FakeRecorder
+	var pool *spiderpoolv1.SpiderIPPool
+	var endpoint *spiderpoolv1.SpiderEndpoint
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(10)
+		defaultRecorder := event.EventRecorder
+		event.EventRecorder = recorder
+		DeferCleanup(func() {
+			event.EventRecorder = defaultRecorder
+		})
+
+		// the IPs are allocated to the pods not found in k8s
+		pool = &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: "pool"}}
+		pool.Spec.Subnet = "172.18.40.0/24"
+		pool.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{
+			"172.18.40.10": {ContainerID: "c0", NIC: "eth0", Namespace: metav1.NamespaceDefault, Pod: "pod0"},
+			"172.18.40.11": {ContainerID: "c1", NIC: "eth0", Namespace: metav1.NamespaceDefault, Pod: "pod1"},
+		}
+		endpoint = &spiderpoolv1.SpiderEndpoint{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "pod0"}}
+	})
+
+	JustBeforeEach(func() {
+		fakeClient = newFakeClient(pool, endpoint)
+		gc = newTestSpiderGC(fakeClient, &GarbageCollectionConfig{})
+	})
+
+	freeze := func(obj client.Object) {
+		obj.SetAnnotations(map[string]string{constant.AnnoGCSkip: constant.True})
+	}
+
+	getPool := func() *spiderpoolv1.SpiderIPPool {
+		var p spiderpoolv1.SpiderIPPool
+		Expect(fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(pool), &p)).To(Succeed())
+		return &p
+	}
+
+	Describe("isGCSkipped", func() {
+		It("skips the object annotated and records an event", func() {
+			freeze(pool)
+			Expect(isGCSkipped(context.TODO(), pool, constant.SpiderIPPoolKind)).To(BeTrue())
+			Expect(recorder.Events).To(Receive(HavePrefix(corev1.EventTypeNormal + " " + constant.EventReasonGCSkipped)))
+		})
+
+		It("does not skip the object without the annotation set to true", func() {
+			Expect(isGCSkipped(context.TODO(), pool, constant.SpiderIPPoolKind)).To(BeFalse())
+
+			pool.Annotations = map[string]string{constant.AnnoGCSkip: "false"}
+			Expect(isGCSkipped(context.TODO(), pool, constant.SpiderIPPoolKind)).To(BeFalse())
+			Expect(recorder.Events).NotTo(Receive())
+		})
+	})
+
+	Context("with the SpiderIPPool and SpiderEndpoint annotated", func() {
+		BeforeEach(func() {
+			freeze(pool)
+			freeze(endpoint)
+		})
+
+		It("looks up the annotations of the SpiderIPPool and SpiderEndpoint", func() {
+			Expect(gc.isIPPoolGCSkipped(context.TODO(), pool.Name)).To(BeTrue())
+			Expect(gc.isEndpointGCSkipped(context.TODO(), endpoint.Namespace, endpoint.Name)).To(BeTrue())
+		})
+
+		It("does not skip the ones not found", func() {
+			Expect(gc.isIPPoolGCSkipped(context.TODO(), "gone-pool")).To(BeFalse())
+			Expect(gc.isEndpointGCSkipped(context.TODO(), metav1.NamespaceDefault, "gone-pod")).To(BeFalse())
+		})
+
+		It("leaves the SpiderIPPool untouched in scan-all", func() {
+			var scanned atomic.Int64
+			gc.scanIPPool(context.TODO(), getPool(), gcPace{}, &scanned)
+			Expect(scanned.Load()).To(BeZero())
+			Expect(getPool().Status.AllocatedIPs).To(HaveLen(2))
+		})
+	})
+
+	Context("with the SpiderEndpoint annotated", func() {
+		BeforeEach(func() {
+			freeze(endpoint)
+		})
+
+		It("keeps the IPs of the SpiderEndpoint in scan-all", func() {
+			var scanned atomic.Int64
+			gc.scanIPPool(context.TODO(), getPool(), gcPace{}, &scanned)
+			Expect(getPool().Status.AllocatedIPs).To(HaveKey("172.18.40.10"))
+			Expect(getPool().Status.AllocatedIPs).NotTo(HaveKey("172.18.40.11"))
+		})
+	})
+
+	It("releases the IPs without the annotation in scan-all", func() {
+		var scanned atomic.Int64
+		gc.scanIPPool(context.TODO(), getPool(), gcPace{}, &scanned)
+		Expect(getPool().Status.AllocatedIPs).To(BeEmpty())
+		Expect(recorder.Events).NotTo(Receive(ContainSubstring(constant.EventReasonGCSkipped)))
+	})
+
+	Context("with the SpiderEndpoint released", func() {
+		BeforeEach(func() {
+			now := metav1.Now()
+			endpoint.DeletionTimestamp = &now
+			endpoint.Finalizers = []string{constant.SpiderFinalizer}
+		})
+
+		hasFinalizer := func() bool {
+			var e spiderpoolv1.SpiderEndpoint
+			err := fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(endpoint), &e)
+			if apierrors.IsNotFound(err) {
+				return false
+			}
+			Expect(err).NotTo(HaveOccurred())
+			return len(e.Finalizers) != 0
+		}
+
+		It("removes the finalizer of the SpiderEndpoint", func() {
+			gc.cleanupReleasedEndpoints(context.TODO())
+			Expect(hasFinalizer()).To(BeFalse())
+		})
+
+		It("keeps the finalizer of the SpiderEndpoint annotated", func() {
+			freeze(endpoint)
+			Expect(fakeClient.Update(context.TODO(), endpoint)).To(Succeed())
+
+			gc.cleanupReleasedEndpoints(context.TODO())
+			Expect(hasFinalizer()).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,107 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package gcmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/metric"
+	"github.com/spidernet-io/spiderpool/pkg/podmanager"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+	"github.com/spidernet-io/spiderpool/pkg/statefulsetmanager"
+	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
+)
+
+var scheme *runtime.Scheme
+
+func TestGCManager(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "GCManager Suite", Label("gcmanager", "unitest"))
+}
+
+var _ = BeforeSuite(func() {
+	scheme = runtime.NewScheme()
+	err := spiderpoolv1.AddToScheme(scheme)
+	Expect(err).NotTo(HaveOccurred())
+	err = corev1.AddToScheme(scheme)
+	Expect(err).NotTo(HaveOccurred())
+
+	ctx := context.TODO()
+	_, err = metric.InitMetricController(ctx, "gcmanager_test", false)
+	Expect(err).NotTo(HaveOccurred())
+	err = metric.InitSpiderpoolControllerMetrics(ctx)
+	Expect(err).NotTo(HaveOccurred())
+
+	logger = logutils.Logger.Named("IP-GarbageCollection")
+})
+
+// fakeLeader is the elector whose leadership is fixed.
+type fakeLeader struct {
+	elected bool
+}
+
+func (f *fakeLeader) Run(ctx context.Context, clientSet kubernetes.Interface) error { return nil }
+
+func (f *fakeLeader) IsElected() bool { return f.elected }
+
+// newTestSpiderGC returns the SpiderGC elected as the leader, whose managers
+// are backed by the fake client.
+func newTestSpiderGC(fakeClient client.Client, config *GarbageCollectionConfig) *SpiderGC {
+	rIPManager, err := reservedipmanager.NewReservedIPManager(fakeClient)
+	Expect(err).NotTo(HaveOccurred())
+	ipPoolManager, err := ippoolmanager.NewIPPoolManager(
+		ippoolmanager.IPPoolManagerConfig{
+			MaxConflictRetries:    3,
+			ConflictRetryUnitTime: time.Millisecond,
+		},
+		fakeClient,
+		rIPManager,
+	)
+	Expect(err).NotTo(HaveOccurred())
+	endpointManager, err := workloadendpointmanager.NewWorkloadEndpointManager(
+		workloadendpointmanager.EndpointManagerConfig{
+			MaxConflictRetries: 3,
+			MaxHistoryRecords:  pointer.Int(10),
+		},
+		fakeClient,
+	)
+	Expect(err).NotTo(HaveOccurred())
+	podManager, err := podmanager.NewPodManager(podmanager.PodManagerConfig{MaxConflictRetries: 3}, fakeClient)
+	Expect(err).NotTo(HaveOccurred())
+	stsManager, err := statefulsetmanager.NewStatefulSetManager(fakeClient)
+	Expect(err).NotTo(HaveOccurred())
+
+	return &SpiderGC{
+		PodDB:            NewPodDBer(100),
+		gcConfig:         config,
+		gcSignal:         make(chan struct{}, 1),
+		gcIPPoolIPSignal: make(chan *PodEntry, 100),
+		gcNodeSignal:     make(chan string, gcNodeChannelBuffer),
+		churn:            &churnMeter{},
+		wepMgr:           endpointManager,
+		ippoolMgr:        ipPoolManager,
+		podMgr:           podManager,
+		stsMgr:           stsManager,
+		rIPMgr:           rIPManager,
+		leader:           &fakeLeader{elected: true},
+	}
+}
+
+func newFakeClient(objs ...client.Object) client.Client {
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
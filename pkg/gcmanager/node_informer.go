@@ -0,0 +1,206 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package gcmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+// startNodeInformer sets up k8s node informer and waits for its cache to be synced, which feeds the node lister. It reclaims
+// the IPs allocated on the deleted nodes if 'EnableGCForDeletedNode' is on.
+func (s *SpiderGC) startNodeInformer(ctx context.Context) error {
+	logger.Sugar().Infof("register node informer")
+
+	informerFactory := informers.NewSharedInformerFactory(s.k8ClientSet, 0)
+	nodeInformer := informerFactory.Core().V1().Nodes()
+	if s.gcConfig.EnableGCForDeletedNode {
+		nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			DeleteFunc: s.onNodeDel,
+		})
+	}
+	s.nodeLister = nodeInformer.Lister()
+
+	informerFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), nodeInformer.Informer().HasSynced) {
+		return fmt.Errorf("failed to wait for the node informer to sync")
+	}
+
+	return nil
+}
+
+// onNodeDel represents Node informer Delete Event
+func (s *SpiderGC) onNodeDel(obj interface{}) {
+	// backup controller could be elected as master
+	if !s.leader.IsElected() {
+		return
+	}
+
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			logger.Sugar().Errorf("onNodeDel: failed to assert object '%+v' to k8s Node", obj)
+			return
+		}
+		node, ok = tombstone.Obj.(*corev1.Node)
+		if !ok {
+			logger.Sugar().Errorf("onNodeDel: failed to assert tombstone object '%+v' to k8s Node", tombstone.Obj)
+			return
+		}
+	}
+
+	logger.Sugar().Infof("onNodeDel: receive node '%s' deleted event", node.Name)
+
+	select {
+	case s.gcNodeSignal <- node.Name:
+	case <-time.After(time.Duration(s.gcConfig.GCSignalTimeoutDuration) * time.Second):
+		logger.Sugar().Errorf("failed to reclaim the IPs of deleted node '%s', gcNodeSignal:len=%d, leave them to scan all", node.Name, len(s.gcNodeSignal))
+	}
+}
+
+// reclaimNodeIPExecutor receives the deleted nodes to reclaim their IPs, and checks the nodes whose pods are still alive
+// again after DeletedNodeGraceDelay seconds.
+func (s *SpiderGC) reclaimNodeIPExecutor(ctx context.Context) {
+	logger.Info("Starting running 'reclaimNodeIPExecutor'")
+
+	for {
+		select {
+		case nodeName := <-s.gcNodeSignal:
+			pending := s.reclaimDeletedNodeIPs(ctx, nodeName)
+			if pending == 0 {
+				continue
+			}
+
+			delay := time.Duration(s.gcConfig.DeletedNodeGraceDelay) * time.Second
+			logger.Sugar().Infof("%d IPs of deleted node '%s' are still used by alive pods, check them again after %v", pending, nodeName, delay)
+			time.AfterFunc(delay, func() {
+				select {
+				case s.gcNodeSignal <- nodeName:
+				case <-ctx.Done():
+				}
+			})
+
+		case <-ctx.Done():
+			logger.Info("receive ctx done, stop running reclaimNodeIPExecutor")
+			return
+		}
+	}
+}
+
+// reclaimDeletedNodeIPs releases the IPs allocated on the deleted node right away, once their pods are gone or terminating
+// past their grace period. The node may be deleted by 'kubectl delete node' while it's alive, whose kubelet re-registers it
+// soon and keeps the pods running, so the IPs of the other pods still bound to the node are kept and returned as pending
+// to be checked again. The pods rescheduled to other nodes are left to scan all.
+func (s *SpiderGC) reclaimDeletedNodeIPs(ctx context.Context, nodeName string) (pending int) {
+	nodeLogger := logger.With(zap.String("node", nodeName))
+
+	// the node may be re-registered with the same name
+	_, err := s.nodeLister.Get(nodeName)
+	if err == nil {
+		nodeLogger.Sugar().Infof("node '%s' exists again, no need to reclaim its IPs", nodeName)
+		return 0
+	}
+	if !apierrors.IsNotFound(err) {
+		nodeLogger.Sugar().Errorf("failed to check node '%s', leave its IPs to scan all: %v", nodeName, err)
+		return 0
+	}
+
+	poolList, err := s.ippoolMgr.ListIPPools(ctx)
+	if nil != err {
+		nodeLogger.Sugar().Errorf("failed to list IPPools, leave the IPs of node '%s' to scan all: %v", nodeName, err)
+		return 0
+	}
+
+	reclaimed := 0
+	for i := range poolList.Items {
+		pool := &poolList.Items[i]
+		if isGCSkipped(ctx, pool, constant.SpiderIPPoolKind) {
+			continue
+		}
+
+		for poolIP, poolIPAllocation := range pool.Status.AllocatedIPs {
+			if poolIPAllocation.Node != nodeName {
+				continue
+			}
+
+			wrappedLog := nodeLogger.With(zap.String("podNS", poolIPAllocation.Namespace), zap.String("podName", poolIPAllocation.Pod),
+				zap.String("containerID", poolIPAllocation.ContainerID), zap.String("NIC", poolIPAllocation.NIC),
+				zap.String("gc-reason", "node of the pod was deleted"))
+
+			pod, err := s.podMgr.GetPodByName(ctx, poolIPAllocation.Namespace, poolIPAllocation.Pod)
+			if nil != err && !apierrors.IsNotFound(err) {
+				wrappedLog.Sugar().Errorf("check pod from kubernetes failed with error '%v'", err)
+				continue
+			}
+
+			if nil == err {
+				// the pod with the same name was recreated on another node
+				if pod.Spec.NodeName != nodeName || (poolIPAllocation.PodUID != "" && string(pod.UID) != poolIPAllocation.PodUID) {
+					continue
+				}
+
+				// the pod may still be running on the node which will be re-registered
+				if !isPodTerminatedGracefully(pod, time.Now()) {
+					wrappedLog.Sugar().Debugf("pod is still alive, check IP '%s' again later", poolIP)
+					pending++
+					continue
+				}
+			}
+
+			// StatefulSet pod keeps its IP once it's recreated
+			if s.gcConfig.EnableStatefulSet && poolIPAllocation.OwnerControllerType == constant.KindStatefulSet {
+				isValidStsPod, err := s.stsMgr.IsValidStatefulSetPod(ctx, poolIPAllocation.Namespace, poolIPAllocation.Pod, poolIPAllocation.OwnerControllerType)
+				if nil != err {
+					wrappedLog.Sugar().Errorf("failed to check StatefulSet pod '%s/%s' IP '%s' should be cleaned or not, error: %v",
+						poolIPAllocation.Namespace, poolIPAllocation.Pod, poolIP, err)
+					continue
+				}
+
+				if isValidStsPod {
+					continue
+				}
+			}
+
+			if s.endpointGCSkipped(ctx, wrappedLog, poolIPAllocation) {
+				continue
+			}
+
+			err = s.releaseSingleIPAndRemoveWEPFinalizer(logutils.IntoContext(ctx, wrappedLog), pool.Name, poolIP, poolIPAllocation, reclaimReasonNodeDeleted)
+			if nil != err {
+				wrappedLog.Error(err.Error())
+				continue
+			}
+			reclaimed++
+		}
+	}
+
+	nodeLogger.Sugar().Infof("reclaim %d IPs of deleted node '%s', %d IPs are pending", reclaimed, nodeName, pending)
+
+	return pending
+}
+
+// isPodTerminatedGracefully reports whether the pod is terminating and its grace period has passed.
+func isPodTerminatedGracefully(pod *corev1.Pod, now time.Time) bool {
+	if pod.DeletionTimestamp == nil {
+		return false
+	}
+
+	gracePeriod := time.Duration(0)
+	if pod.DeletionGracePeriodSeconds != nil {
+		gracePeriod = time.Duration(*pod.DeletionGracePeriodSeconds) * time.Second
+	}
+
+	return now.After(pod.DeletionTimestamp.Add(gracePeriod))
+}
@@ -0,0 +1,130 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package gcmanager
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+)
+
+var _ = Describe("reclaimDeletedNodeIPs", Label("node_informer_test"), func() {
+	const ip = "172.18.40.10"
+	const nodeName = "node1"
+
+	var s *SpiderGC
+	var fakeClient client.Client
+	var nodeIndexer cache.Indexer
+	var poolT *spiderpoolv1.SpiderIPPool
+	var podT *corev1.Pod
+
+	BeforeEach(func() {
+		poolT = &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: "pool"}}
+		poolT.Spec.Subnet = "172.18.40.0/24"
+		poolT.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{
+			ip: {ContainerID: "container", NIC: "eth0", Node: nodeName, Namespace: "default", Pod: "pod", PodUID: "pod-uid"},
+		}
+
+		podT = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod", UID: "pod-uid"},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	})
+
+	setup := func(objs ...client.Object) {
+		fakeClient = newFakeClient(objs...)
+		s = newTestSpiderGC(fakeClient, &GarbageCollectionConfig{EnableGCForDeletedNode: true, DeletedNodeGraceDelay: 60})
+		nodeIndexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		s.nodeLister = corelisters.NewNodeLister(nodeIndexer)
+	}
+
+	allocated := func() bool {
+		var pool spiderpoolv1.SpiderIPPool
+		err := fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(poolT), &pool)
+		Expect(err).NotTo(HaveOccurred())
+		_, ok := pool.Status.AllocatedIPs[ip]
+		return ok
+	}
+
+	It("keeps the IPs of the node registered again", func() {
+		setup(poolT)
+		err := nodeIndexer.Add(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(s.reclaimDeletedNodeIPs(context.TODO(), nodeName)).To(BeZero())
+		Expect(allocated()).To(BeTrue())
+	})
+
+	It("releases the IP of the pod which is gone", func() {
+		setup(poolT)
+
+		Expect(s.reclaimDeletedNodeIPs(context.TODO(), nodeName)).To(BeZero())
+		Expect(allocated()).To(BeFalse())
+	})
+
+	It("keeps the IP of the pod still running on the deleted node and checks it again", func() {
+		setup(poolT, podT)
+
+		Expect(s.reclaimDeletedNodeIPs(context.TODO(), nodeName)).To(Equal(1))
+		Expect(allocated()).To(BeTrue())
+	})
+
+	It("keeps the IP of the pod terminating within its grace period", func() {
+		deletionTimestamp := metav1.NewTime(time.Now())
+		podT.DeletionTimestamp = &deletionTimestamp
+		podT.DeletionGracePeriodSeconds = pointer.Int64(30)
+		podT.Finalizers = []string{"test"}
+		setup(poolT, podT)
+
+		Expect(s.reclaimDeletedNodeIPs(context.TODO(), nodeName)).To(Equal(1))
+		Expect(allocated()).To(BeTrue())
+	})
+
+	It("releases the IP of the pod terminating past its grace period", func() {
+		deletionTimestamp := metav1.NewTime(time.Now().Add(-time.Minute))
+		podT.DeletionTimestamp = &deletionTimestamp
+		podT.DeletionGracePeriodSeconds = pointer.Int64(30)
+		podT.Finalizers = []string{"test"}
+		setup(poolT, podT)
+
+		Expect(s.reclaimDeletedNodeIPs(context.TODO(), nodeName)).To(BeZero())
+		Expect(allocated()).To(BeFalse())
+	})
+
+	It("leaves the IP of the pod recreated on another node to scan all", func() {
+		podT.UID = "new-pod-uid"
+		podT.Spec.NodeName = "node2"
+		setup(poolT, podT)
+
+		Expect(s.reclaimDeletedNodeIPs(context.TODO(), nodeName)).To(BeZero())
+		Expect(allocated()).To(BeTrue())
+	})
+
+	It("checks the node again after the grace delay while its pods are alive", func() {
+		setup(poolT, podT)
+		s.gcConfig.DeletedNodeGraceDelay = 0
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		go s.reclaimNodeIPExecutor(ctx)
+		s.gcNodeSignal <- nodeName
+
+		Consistently(allocated).Should(BeTrue())
+
+		err := fakeClient.Delete(context.TODO(), podT)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(allocated).Should(BeFalse())
+	})
+})
@@ -0,0 +1,59 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package gcmanager
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
+)
+
+// cleanupOrphanedEndpoints deletes the SpiderEndpoints whose pods are gone, whose IPs are all released and which have been
+// inactive for OrphanedEndpointMaxAge seconds, and removes their finalizers. They are left behind when the finalizer removal
+// fails after the IPs are released, independent of the IP release.
+func (s *SpiderGC) cleanupOrphanedEndpoints(ctx context.Context) {
+	// only the elected controller cleans up the orphaned SpiderEndpoints
+	if !s.leader.IsElected() {
+		return
+	}
+
+	maxAge := time.Duration(s.gcConfig.OrphanedEndpointMaxAge) * time.Second
+	now := time.Now()
+	result, err := s.wepMgr.CleanupEndpoints(ctx, nil, workloadendpointmanager.EndpointCleanupOptions{
+		Filter: func(endpoint *spiderpoolv1.SpiderEndpoint) bool {
+			if !workloadendpointmanager.IsStaleEndpoint(endpoint, maxAge, now) {
+				return false
+			}
+			if isGCSkipped(ctx, endpoint, constant.SpiderEndpointKind) {
+				return false
+			}
+
+			_, err := s.podMgr.GetPodByName(ctx, endpoint.Namespace, endpoint.Name)
+			if nil != err && !apierrors.IsNotFound(err) {
+				logger.Sugar().Errorf("failed to check pod '%s/%s' of orphaned SpiderEndpoint: %v", endpoint.Namespace, endpoint.Name, err)
+			}
+			return apierrors.IsNotFound(err)
+		},
+		Parallelism: s.gcConfig.ReleaseIPWorkerNum,
+		Pacer:       s,
+		DryRun:      s.gcConfig.DryRun,
+	})
+	if nil != err {
+		logger.Sugar().Errorf("failed to clean up orphaned SpiderEndpoints: %v", err)
+	}
+	if result == nil || len(result.Cleaned) == 0 {
+		return
+	}
+
+	if s.gcConfig.DryRun {
+		logger.Sugar().Infof("dry run, would clean up orphaned SpiderEndpoints %v", result.Cleaned)
+		return
+	}
+	logger.Sugar().Infof("clean up orphaned SpiderEndpoints %v successfully", result.Cleaned)
+}
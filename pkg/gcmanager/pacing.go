@@ -0,0 +1,143 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package gcmanager
+
+import (
+	"context"
+	"time"
+
+	"github.com/spidernet-io/spiderpool/pkg/lock"
+	metrics "github.com/spidernet-io/spiderpool/pkg/metric"
+)
+
+const (
+	// churnWindowSeconds is the length of the sliding window used to
+	// measure the cluster churn rate.
+	churnWindowSeconds = 60
+
+	minTracePodGap = time.Second
+
+	// the number of IP allocations scanned by the scan-all loop, or the
+	// writes of the other bulk API writers, before taking a break, and the
+	// break itself, when the cluster is quiet.
+	quietBatchSize = 100
+	quietBatchGap  = 500 * time.Millisecond
+)
+
+// churnMeter measures the cluster churn rate (Pod events per second) with
+// per-second buckets in a sliding window.
+type churnMeter struct {
+	lock       lock.Mutex
+	buckets    [churnWindowSeconds]int64
+	lastSecond int64
+}
+
+// Mark records a Pod event happened at now.
+func (c *churnMeter) Mark(now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.advance(now.Unix())
+	c.buckets[now.Unix()%churnWindowSeconds]++
+}
+
+// Rate returns the average number of Pod events per second in the window.
+func (c *churnMeter) Rate(now time.Time) float64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.advance(now.Unix())
+	var total int64
+	for _, n := range c.buckets {
+		total += n
+	}
+
+	return float64(total) / churnWindowSeconds
+}
+
+// advance clears the buckets which have slid out of the window.
+func (c *churnMeter) advance(second int64) {
+	if second <= c.lastSecond {
+		return
+	}
+
+	if second-c.lastSecond >= churnWindowSeconds {
+		c.buckets = [churnWindowSeconds]int64{}
+	} else {
+		for s := c.lastSecond + 1; s <= second; s++ {
+			c.buckets[s%churnWindowSeconds] = 0
+		}
+	}
+	c.lastSecond = second
+}
+
+// gcPace describes how aggressively the IP garbage collection works.
+type gcPace struct {
+	// tracePodGap is the interval of traversing the PodEntry database.
+	tracePodGap time.Duration
+	// batchSize is the number of IP allocations checked by scan-all, or
+	// the writes of the other bulk API writers, before pausing for
+	// batchGap, zero means no pause.
+	batchSize int
+	batchGap  time.Duration
+}
+
+// currentPace chooses the pace of IP garbage collection according to the
+// cluster churn rate. It speeds up during mass rescheduling to avoid falling
+// behind, and slows down during quiet periods to reduce the pressure on the
+// API server.
+func (s *SpiderGC) currentPace() gcPace {
+	basePace := gcPace{
+		tracePodGap: time.Duration(s.gcConfig.TracePodGapDuration) * time.Second,
+	}
+	if !s.gcConfig.EnableAdaptivePacing || s.gcConfig.BusyChurnRate <= 0 {
+		return basePace
+	}
+
+	rate := s.churn.Rate(time.Now())
+	busy := float64(s.gcConfig.BusyChurnRate)
+
+	pace := basePace
+	switch {
+	case rate >= busy:
+		// mass rescheduling, trace Pods as fast as possible
+		pace.tracePodGap = basePace.tracePodGap / 4
+	case rate < busy/10:
+		// quiet period
+		pace.tracePodGap = basePace.tracePodGap * 2
+		pace.batchSize = quietBatchSize
+		pace.batchGap = quietBatchGap
+	}
+
+	if pace.tracePodGap < minTracePodGap {
+		pace.tracePodGap = minTracePodGap
+	}
+
+	metrics.IPGCChurnRate.Record(rate)
+	metrics.IPGCPaceSeconds.Record(pace.tracePodGap.Seconds())
+
+	return pace
+}
+
+// Pause implements limiter.Pacer, it pauses the bulk API writers, such as
+// the cleanup of the SpiderEndpoints and the periodic reconcilers of the
+// controller, following the batches of the current pace.
+func (s *SpiderGC) Pause(ctx context.Context, written int) error {
+	pace := s.currentPace()
+	return pace.pause(ctx, int64(written))
+}
+
+// pause blocks for batchGap if the done ones fill up a batch.
+func (p gcPace) pause(ctx context.Context, done int64) error {
+	if p.batchSize <= 0 || done == 0 || done%int64(p.batchSize) != 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(p.batchGap):
+		return nil
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package gcmanager
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("pacing", Label("pacing_test"), func() {
+	var now time.Time
+	BeforeEach(func() {
+		now = time.Unix(1700000000, 0)
+	})
+
+	Describe("churnMeter", func() {
+		var meter *churnMeter
+		BeforeEach(func() {
+			meter = &churnMeter{}
+		})
+
+		It("averages the events over the window", func() {
+			for n := 0; n < churnWindowSeconds; n++ {
+				meter.Mark(now.Add(time.Duration(n) * time.Second))
+				meter.Mark(now.Add(time.Duration(n) * time.Second))
+			}
+			Expect(meter.Rate(now.Add(churnWindowSeconds*time.Second - time.Second))).To(Equal(2.0))
+		})
+
+		It("counts the events of the same second together", func() {
+			for n := 0; n < 30; n++ {
+				meter.Mark(now.Add(time.Duration(n) * time.Millisecond))
+			}
+			Expect(meter.Rate(now)).To(Equal(30.0 / churnWindowSeconds))
+		})
+
+		It("drops the events sliding out of the window", func() {
+			meter.Mark(now)
+			meter.Mark(now.Add(30 * time.Second))
+			Expect(meter.Rate(now.Add(59 * time.Second))).To(Equal(2.0 / churnWindowSeconds))
+			Expect(meter.Rate(now.Add(60 * time.Second))).To(Equal(1.0 / churnWindowSeconds))
+			Expect(meter.Rate(now.Add(90 * time.Second))).To(BeZero())
+		})
+
+		It("forgets everything after a quiet window", func() {
+			for n := 0; n < 10; n++ {
+				meter.Mark(now.Add(time.Duration(n) * time.Second))
+			}
+			Expect(meter.Rate(now.Add(10 * time.Minute))).To(BeZero())
+
+			meter.Mark(now.Add(10 * time.Minute))
+			Expect(meter.Rate(now.Add(10 * time.Minute))).To(Equal(1.0 / churnWindowSeconds))
+		})
+
+		It("keeps the rate if the clock goes back within the window", func() {
+			meter.Mark(now)
+			meter.Mark(now.Add(10 * time.Second))
+			Expect(meter.Rate(now.Add(5 * time.Second))).To(Equal(2.0 / churnWindowSeconds))
+		})
+	})
+
+	Describe("currentPace", func() {
+		var gc *SpiderGC
+		BeforeEach(func() {
+			gc = newTestSpiderGC(newFakeClient(), &GarbageCollectionConfig{
+				TracePodGapDuration:  8,
+				EnableAdaptivePacing: true,
+				BusyChurnRate:        10,
+			})
+		})
+
+		markRate := func(rate int) {
+			// the events of the whole window end at the current second
+			current := time.Now()
+			for n := 0; n < rate*churnWindowSeconds; n++ {
+				gc.churn.Mark(current)
+			}
+		}
+
+		It("keeps the configured pace without adaptive pacing", func() {
+			gc.gcConfig.EnableAdaptivePacing = false
+			Expect(gc.currentPace()).To(Equal(gcPace{tracePodGap: 8 * time.Second}))
+		})
+
+		It("slows down and pauses the bulk writers during quiet periods", func() {
+			Expect(gc.currentPace()).To(Equal(gcPace{
+				tracePodGap: 16 * time.Second,
+				batchSize:   quietBatchSize,
+				batchGap:    quietBatchGap,
+			}))
+		})
+
+		It("keeps the configured pace with moderate churn", func() {
+			markRate(5)
+			Expect(gc.currentPace()).To(Equal(gcPace{tracePodGap: 8 * time.Second}))
+		})
+
+		It("speeds up during mass rescheduling", func() {
+			markRate(10)
+			Expect(gc.currentPace()).To(Equal(gcPace{tracePodGap: 2 * time.Second}))
+		})
+
+		It("never traces the Pods faster than the minimum gap", func() {
+			gc.gcConfig.TracePodGapDuration = 2
+			markRate(20)
+			Expect(gc.currentPace().tracePodGap).To(Equal(minTracePodGap))
+		})
+	})
+
+	Describe("pause", func() {
+		pace := gcPace{batchSize: 2, batchGap: 10 * time.Millisecond}
+
+		It("pauses once a batch is done", func() {
+			start := time.Now()
+			Expect(pace.pause(context.TODO(), 2)).To(Succeed())
+			Expect(time.Since(start)).To(BeNumerically(">=", pace.batchGap))
+		})
+
+		It("does not pause within a batch or without batches", func() {
+			start := time.Now()
+			Expect(pace.pause(context.TODO(), 0)).To(Succeed())
+			Expect(pace.pause(context.TODO(), 1)).To(Succeed())
+			Expect(gcPace{}.pause(context.TODO(), 2)).To(Succeed())
+			Expect(time.Since(start)).To(BeNumerically("<", pace.batchGap))
+		})
+
+		It("stops pausing once the context is done", func() {
+			ctx, cancel := context.WithCancel(context.TODO())
+			cancel()
+			Expect(gcPace{batchSize: 1, batchGap: time.Hour}.pause(ctx, 1)).To(MatchError(context.Canceled))
+		})
+
+		It("paces the bulk writers with the current pace", func() {
+			gc := newTestSpiderGC(newFakeClient(), &GarbageCollectionConfig{
+				TracePodGapDuration:  8,
+				EnableAdaptivePacing: true,
+				BusyChurnRate:        10,
+			})
+			ctx, cancel := context.WithCancel(context.TODO())
+			cancel()
+			Expect(gc.Pause(ctx, quietBatchSize-1)).To(Succeed())
+			Expect(gc.Pause(ctx, quietBatchSize)).To(MatchError(context.Canceled))
+		})
+	})
+})
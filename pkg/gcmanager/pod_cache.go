@@ -9,6 +9,7 @@ import (
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ktypes "k8s.io/apimachinery/pkg/types"
 
@@ -151,7 +152,7 @@ func (s *SpiderGC) buildPodEntry(oldPod, currentPod *corev1.Pod, deleted bool) (
 			NodeName:            currentPod.Spec.NodeName,
 			EntryUpdateTime:     metav1.Now().UTC(),
 			TracingStartTime:    metav1.Now().UTC(),
-			TracingGracefulTime: time.Duration(s.gcConfig.AdditionalGraceDelay) * time.Second,
+			TracingGracefulTime: s.graceDelay(constant.PodDeleted),
 			PodTracingReason:    constant.PodDeleted,
 		}
 
@@ -159,8 +160,18 @@ func (s *SpiderGC) buildPodEntry(oldPod, currentPod *corev1.Pod, deleted bool) (
 		podEntry.TracingStopTime = podEntry.TracingStartTime.Add(podEntry.TracingGracefulTime)
 		return podEntry, nil
 	} else {
+		// the pod stuck in 'Terminating' on the NotReady node is reclaimed by force, including the StatefulSet one.
+		var isStuckPod bool
+		if s.gcConfig.EnableGCForStuckPod && currentPod.DeletionTimestamp != nil {
+			notReady, err := s.isNodeNotReady(currentPod.Spec.NodeName)
+			if nil != err {
+				return nil, err
+			}
+			isStuckPod = notReady
+		}
+
 		// no need to trace Terminating StatefulSet pod.
-		if ownerRef != nil && ownerRef.Kind == constant.KindStatefulSet {
+		if !isStuckPod && ownerRef != nil && ownerRef.Kind == constant.KindStatefulSet {
 			return nil, nil
 		}
 
@@ -200,7 +211,7 @@ func (s *SpiderGC) buildPodEntry(oldPod, currentPod *corev1.Pod, deleted bool) (
 
 		if isBuildTerminatingPodEntry {
 			// disable for gc terminating pod
-			if !s.gcConfig.EnableGCForTerminatingPod {
+			if !s.gcConfig.EnableGCForTerminatingPod && !isStuckPod {
 				logger.Sugar().Debugf("IP gc already turn off 'EnableGCForTerminatingPod' configuration, disacrd pod '%s/%s'", currentPod.Namespace, currentPod.Name)
 				return nil, nil
 			}
@@ -218,7 +229,11 @@ func (s *SpiderGC) buildPodEntry(oldPod, currentPod *corev1.Pod, deleted bool) (
 			if currentPod.DeletionGracePeriodSeconds == nil {
 				return nil, fmt.Errorf("pod '%s/%s' status is '%v' but doesn't have 'DeletionGracePeriodSeconds' property", currentPod.Namespace, currentPod.Name, podStatus)
 			}
-			podEntry.TracingGracefulTime = (time.Duration(*currentPod.DeletionGracePeriodSeconds) + time.Duration(s.gcConfig.AdditionalGraceDelay)) * time.Second
+			if isStuckPod {
+				podEntry.TracingGracefulTime = time.Duration(*currentPod.DeletionGracePeriodSeconds+int64(s.gcConfig.StuckPodGraceDelay)) * time.Second
+			} else {
+				podEntry.TracingGracefulTime = time.Duration(*currentPod.DeletionGracePeriodSeconds)*time.Second + s.graceDelay(podStatus)
+			}
 
 			// stop time
 			podEntry.TracingStopTime = podEntry.TracingStartTime.Add(podEntry.TracingGracefulTime)
@@ -233,7 +248,7 @@ func (s *SpiderGC) buildPodEntry(oldPod, currentPod *corev1.Pod, deleted bool) (
 				PodTracingReason: podStatus,
 			}
 
-			startTime, _, gracefulTime, err := s.computeSucceededOrFailedPodTerminatingTime(currentPod)
+			startTime, _, gracefulTime, err := s.computeSucceededOrFailedPodTerminatingTime(currentPod, podStatus)
 			if nil != err {
 				return nil, err
 			}
@@ -253,10 +268,10 @@ func (s *SpiderGC) buildPodEntry(oldPod, currentPod *corev1.Pod, deleted bool) (
 }
 
 // computeSucceededOrFailedPodTerminatingTime will compute terminating start time, stop time and graceful period for 'Succeeded | Failed' phase pod
-func (s *SpiderGC) computeSucceededOrFailedPodTerminatingTime(podYaml *corev1.Pod) (terminatingStartTime, terminatingStopTime time.Time, gracefulTime time.Duration, err error) {
+func (s *SpiderGC) computeSucceededOrFailedPodTerminatingTime(podYaml *corev1.Pod, podStatus types.PodStatus) (terminatingStartTime, terminatingStopTime time.Time, gracefulTime time.Duration, err error) {
 	// check container numbers
 	containerNum := len(podYaml.Status.ContainerStatuses)
-	if containerNum == 0 {
+	if containerNum == 0 && podStatus != constant.PodEvicted {
 		err = fmt.Errorf("pod '%s/%s' doesn't have any containers", podYaml.Namespace, podYaml.Name)
 		return
 	}
@@ -273,6 +288,15 @@ func (s *SpiderGC) computeSucceededOrFailedPodTerminatingTime(podYaml *corev1.Po
 		}
 	}
 
+	// the containers of the evicted pod may never run, use the time when its conditions changed last
+	if tmpStartTime.IsZero() && podStatus == constant.PodEvicted {
+		for _, condition := range podYaml.Status.Conditions {
+			if tmpStartTime.Before(condition.LastTransitionTime.UTC()) {
+				tmpStartTime = condition.LastTransitionTime.UTC()
+			}
+		}
+	}
+
 	if tmpStartTime.IsZero() {
 		err = fmt.Errorf("pod '%s/%s' status is '%v' but doesn't have terminated finishedTime",
 			podYaml.Namespace, podYaml.Name, podYaml.Status.Phase)
@@ -286,9 +310,56 @@ func (s *SpiderGC) computeSucceededOrFailedPodTerminatingTime(podYaml *corev1.Po
 		err = fmt.Errorf("pod '%s/%s' doesn't have 'TerminationGracePeriodSeconds' property", podYaml.Namespace, podYaml.Name)
 		return
 	}
-	gracefulTime = (time.Duration(*podYaml.Spec.TerminationGracePeriodSeconds) + time.Duration(s.gcConfig.AdditionalGraceDelay)) * time.Second
+	gracefulTime = time.Duration(*podYaml.Spec.TerminationGracePeriodSeconds)*time.Second + s.graceDelay(podStatus)
 
 	// stop time
 	terminatingStopTime = terminatingStartTime.Add(gracefulTime)
 	return
 }
+
+// graceDelay returns the delay after the grace period of the pod in the given status before releasing its IPs,
+// the delay of each phase falls back to 'AdditionalGraceDelay' if negative.
+func (s *SpiderGC) graceDelay(podStatus types.PodStatus) time.Duration {
+	delay := -1
+	switch podStatus {
+	case constant.PodTerminating, constant.PodGraceTimeout:
+		delay = s.gcConfig.TerminatingPodGraceDelay
+	case constant.PodSucceeded:
+		delay = s.gcConfig.SucceededPodGraceDelay
+	case constant.PodFailed:
+		delay = s.gcConfig.FailedPodGraceDelay
+	case constant.PodEvicted:
+		delay = s.gcConfig.EvictedPodGraceDelay
+	case constant.PodDeleted:
+		delay = s.gcConfig.DeletedPodGraceDelay
+	}
+
+	if delay < 0 {
+		delay = s.gcConfig.AdditionalGraceDelay
+	}
+
+	return time.Duration(delay) * time.Second
+}
+
+// isNodeNotReady reports whether the node is NotReady or gone, the pods on which are unreachable.
+func (s *SpiderGC) isNodeNotReady(nodeName string) (bool, error) {
+	if nodeName == "" {
+		return false, nil
+	}
+
+	node, err := s.nodeLister.Get(nodeName)
+	if nil != err {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to get node '%s', error: %v", nodeName, err)
+	}
+
+	for _, c := range node.Status.Conditions {
+		if c.Type == corev1.NodeReady {
+			return c.Status != corev1.ConditionTrue, nil
+		}
+	}
+
+	return true, nil
+}
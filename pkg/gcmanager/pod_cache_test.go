@@ -0,0 +1,217 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package gcmanager
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/pointer"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+var _ = Describe("pod cache", Label("pod_cache_test"), func() {
+	var s *SpiderGC
+	var nodeIndexer cache.Indexer
+	var podT *corev1.Pod
+
+	// addNode adds the node with the Ready condition to the node lister.
+	addNode := func(name string, ready corev1.ConditionStatus) {
+		err := nodeIndexer.Add(&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		s = newTestSpiderGC(newFakeClient(), &GarbageCollectionConfig{
+			EnableGCForStuckPod:       true,
+			StuckPodGraceDelay:        60,
+			EnableGCForTerminatingPod: false,
+			TerminatingPodGraceDelay:  5,
+			EnableStatefulSet:         true,
+		})
+		nodeIndexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		s.nodeLister = corelisters.NewNodeLister(nodeIndexer)
+
+		deletionTimestamp := metav1.NewTime(time.Now())
+		podT = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:                  "default",
+				Name:                       "sts-0",
+				DeletionTimestamp:          &deletionTimestamp,
+				DeletionGracePeriodSeconds: pointer.Int64(30),
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "apps/v1",
+					Kind:       constant.KindStatefulSet,
+					Name:       "sts",
+					Controller: pointer.Bool(true),
+				}},
+			},
+			Spec: corev1.PodSpec{
+				NodeName:                      "node1",
+				TerminationGracePeriodSeconds: pointer.Int64(30),
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	})
+
+	It("traces the StatefulSet pod stuck on the NotReady node", func() {
+		addNode("node1", corev1.ConditionUnknown)
+
+		podEntry, err := s.buildPodEntry(nil, podT, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(podEntry).NotTo(BeNil())
+		Expect(podEntry.PodName).To(Equal(podT.Name))
+		Expect(podEntry.TracingStartTime).To(Equal(podT.DeletionTimestamp.Time))
+	})
+
+	It("traces the pod stuck on the deleted node", func() {
+		podEntry, err := s.buildPodEntry(nil, podT, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(podEntry).NotTo(BeNil())
+	})
+
+	It("does not trace the terminating StatefulSet pod on the Ready node", func() {
+		addNode("node1", corev1.ConditionTrue)
+
+		podEntry, err := s.buildPodEntry(nil, podT, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(podEntry).To(BeNil())
+	})
+
+	It("delays the release of the stuck pod by the stuck delay instead of the terminating one", func() {
+		addNode("node1", corev1.ConditionFalse)
+		podT.OwnerReferences = nil
+
+		podEntry, err := s.buildPodEntry(nil, podT, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(podEntry).NotTo(BeNil())
+		Expect(podEntry.TracingGracefulTime).To(Equal(90 * time.Second))
+		Expect(podEntry.TracingStopTime).To(Equal(podT.DeletionTimestamp.Add(90 * time.Second)))
+
+		s.gcConfig.EnableGCForTerminatingPod = true
+		addNode("node1", corev1.ConditionTrue)
+		podEntry, err = s.buildPodEntry(nil, podT, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(podEntry).NotTo(BeNil())
+		Expect(podEntry.TracingGracefulTime).To(Equal(35 * time.Second))
+	})
+
+	Describe("per-phase grace delays", func() {
+		BeforeEach(func() {
+			s.gcConfig.AdditionalGraceDelay = 1
+			s.gcConfig.TerminatingPodGraceDelay = 5
+			s.gcConfig.SucceededPodGraceDelay = 0
+			s.gcConfig.FailedPodGraceDelay = 20
+			s.gcConfig.EvictedPodGraceDelay = -1
+			s.gcConfig.DeletedPodGraceDelay = 40
+		})
+
+		DescribeTable("delays the release of the pod by the delay of its phase",
+			func(podStatus types.PodStatus, delay time.Duration) {
+				Expect(s.graceDelay(podStatus)).To(Equal(delay))
+			},
+			Entry("terminating", constant.PodTerminating, 5*time.Second),
+			Entry("grace period timeout", constant.PodGraceTimeout, 5*time.Second),
+			Entry("succeeded without the delay", constant.PodSucceeded, time.Duration(0)),
+			Entry("failed", constant.PodFailed, 20*time.Second),
+			Entry("evicted falling back to the additional delay", constant.PodEvicted, time.Second),
+			Entry("deleted", constant.PodDeleted, 40*time.Second),
+			Entry("other phases with the additional delay", constant.PodRunning, time.Second),
+		)
+
+		It("traces the deleted pod by the delay of deleted pods", func() {
+			podT.OwnerReferences = nil
+
+			podEntry, err := s.buildPodEntry(nil, podT, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(podEntry).NotTo(BeNil())
+			Expect(podEntry.PodTracingReason).To(Equal(constant.PodDeleted))
+			Expect(podEntry.TracingGracefulTime).To(Equal(40 * time.Second))
+		})
+
+		It("traces the terminating pod by the delay of terminating pods", func() {
+			s.gcConfig.EnableGCForTerminatingPod = true
+			addNode("node1", corev1.ConditionTrue)
+			podT.OwnerReferences = nil
+
+			podEntry, err := s.buildPodEntry(nil, podT, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(podEntry).NotTo(BeNil())
+			Expect(podEntry.TracingGracefulTime).To(Equal(35 * time.Second))
+		})
+
+		Context("with the finished pods", func() {
+			var finishedAt time.Time
+			BeforeEach(func() {
+				finishedAt = time.Now().Add(-time.Minute).Truncate(time.Second).UTC()
+				podT.DeletionTimestamp = nil
+				podT.OwnerReferences = nil
+				podT.Status.ContainerStatuses = []corev1.ContainerStatus{{
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{FinishedAt: metav1.NewTime(finishedAt)}},
+				}}
+			})
+
+			It("traces the succeeded pod by the delay of succeeded pods", func() {
+				podT.Status.Phase = corev1.PodSucceeded
+
+				podEntry, err := s.buildPodEntry(nil, podT, false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(podEntry).NotTo(BeNil())
+				Expect(podEntry.PodTracingReason).To(Equal(constant.PodSucceeded))
+				Expect(podEntry.TracingStartTime).To(Equal(finishedAt))
+				Expect(podEntry.TracingGracefulTime).To(Equal(30 * time.Second))
+			})
+
+			It("traces the failed pod by the delay of failed pods", func() {
+				podT.Spec.RestartPolicy = corev1.RestartPolicyNever
+				podT.Status.Phase = corev1.PodFailed
+
+				podEntry, err := s.buildPodEntry(nil, podT, false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(podEntry).NotTo(BeNil())
+				Expect(podEntry.PodTracingReason).To(Equal(constant.PodFailed))
+				Expect(podEntry.TracingGracefulTime).To(Equal(50 * time.Second))
+			})
+
+			It("traces the evicted pod whose containers never ran since its conditions changed", func() {
+				transitedAt := finishedAt.Add(time.Second)
+				podT.Status.Phase = corev1.PodFailed
+				podT.Status.Reason = "Evicted"
+				podT.Status.ContainerStatuses = nil
+				podT.Status.Conditions = []corev1.PodCondition{
+					{Type: corev1.PodScheduled, LastTransitionTime: metav1.NewTime(finishedAt)},
+					{Type: corev1.PodReady, LastTransitionTime: metav1.NewTime(transitedAt)},
+				}
+
+				podEntry, err := s.buildPodEntry(nil, podT, false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(podEntry).NotTo(BeNil())
+				Expect(podEntry.PodTracingReason).To(Equal(constant.PodEvicted))
+				Expect(podEntry.TracingStartTime).To(Equal(transitedAt))
+				Expect(podEntry.TracingGracefulTime).To(Equal(31 * time.Second))
+			})
+
+			It("refuses the failed pod without containers", func() {
+				podT.Spec.RestartPolicy = corev1.RestartPolicyNever
+				podT.Status.Phase = corev1.PodFailed
+				podT.Status.ContainerStatuses = nil
+
+				_, err := s.buildPodEntry(nil, podT, false)
+				Expect(err).To(MatchError(ContainSubstring("doesn't have any containers")))
+			})
+		})
+	})
+})
@@ -4,6 +4,8 @@
 package gcmanager
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/tools/cache"
@@ -33,17 +35,23 @@ func (s *SpiderGC) startPodInformer() {
 
 // onPodAdd represents Pod informer Add Event
 func (s *SpiderGC) onPodAdd(obj interface{}) {
-	// backup controller could be elected as master
-	if !s.leader.IsElected() {
-		return
-	}
-
 	pod, ok := obj.(*corev1.Pod)
 	if !ok {
 		logger.Sugar().Errorf("onPodAdd: failed to assert object '%+v' to k8s Pod", obj)
 		return
 	}
 
+	// the informer lists all existing Pods at the beginning, only the newly
+	// created ones are counted as churn.
+	if time.Since(pod.CreationTimestamp.Time) < churnWindowSeconds*time.Second {
+		s.churn.Mark(time.Now())
+	}
+
+	// backup controller could be elected as master
+	if !s.leader.IsElected() {
+		return
+	}
+
 	podEntry, err := s.buildPodEntry(nil, pod, false)
 	if nil != err {
 		logger.Sugar().Errorf("onPodAdd: failed to build Pod Entry '%s/%s', error: %v", pod.Namespace, pod.Name, err)
@@ -97,6 +105,8 @@ func (s *SpiderGC) onPodUpdate(oldObj interface{}, newObj interface{}) {
 
 // onPodDel represents Pod informer Delete Event
 func (s *SpiderGC) onPodDel(obj interface{}) {
+	s.churn.Mark(time.Now())
+
 	// backup controller could be elected as master
 	if !s.leader.IsElected() {
 		return
@@ -0,0 +1,155 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package gcmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	metrics "github.com/spidernet-io/spiderpool/pkg/metric"
+)
+
+// reclaimReasonNodeDeleted is the reason of the IPs reclaimed since their node was deleted.
+const reclaimReasonNodeDeleted = "NodeDeleted"
+
+const (
+	reclaimWebhookInitialBackoff = time.Second
+	reclaimWebhookMaxBackoff     = 30 * time.Second
+)
+
+// ReclaimEvent is posted to the reclaim webhook as JSON for every IP released
+// by IP garbage collection.
+type ReclaimEvent struct {
+	IP          string    `json:"ip"`
+	Pool        string    `json:"pool"`
+	Namespace   string    `json:"namespace"`
+	Pod         string    `json:"pod"`
+	ContainerID string    `json:"containerID"`
+	Node        string    `json:"node,omitempty"`
+	Reason      string    `json:"reason"`
+	Time        time.Time `json:"time"`
+}
+
+// reclaimWebhook delivers the reclaim events to the external webhook in
+// order, retrying each of them with backoff. The events which can't be
+// delivered are dead-lettered, that is logged and counted by metric.
+type reclaimWebhook struct {
+	url            string
+	client         *http.Client
+	maxRetries     int
+	initialBackoff time.Duration
+	events         chan ReclaimEvent
+
+	// deadLetter gives up the event which can't be delivered.
+	deadLetter func(ctx context.Context, event ReclaimEvent, err error)
+}
+
+func newReclaimWebhook(config *GarbageCollectionConfig) *reclaimWebhook {
+	if config.ReclaimWebhookURL == "" {
+		return nil
+	}
+
+	return &reclaimWebhook{
+		url:            config.ReclaimWebhookURL,
+		client:         &http.Client{Timeout: time.Duration(config.ReclaimWebhookTimeout) * time.Second},
+		maxRetries:     config.ReclaimWebhookMaxRetries,
+		initialBackoff: reclaimWebhookInitialBackoff,
+		events:         make(chan ReclaimEvent, config.ReclaimWebhookQueueSize),
+		deadLetter:     deadLetterReclaim,
+	}
+}
+
+// publishReclaim queues the reclaim event for the webhook without blocking
+// the IP garbage collection, it's dead-lettered if the queue is full.
+func (s *SpiderGC) publishReclaim(ctx context.Context, event ReclaimEvent) {
+	if s.reclaimWebhook == nil {
+		return
+	}
+
+	event.Time = time.Now()
+	select {
+	case s.reclaimWebhook.events <- event:
+	default:
+		s.reclaimWebhook.deadLetter(ctx, event, fmt.Errorf("queue is full"))
+	}
+}
+
+// run delivers the queued reclaim events until ctx is done.
+func (w *reclaimWebhook) run(ctx context.Context) {
+	logger.Sugar().Infof("publishing reclaimed IPs to webhook '%s'", w.url)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-w.events:
+			if err := w.deliver(ctx, event); nil != err {
+				w.deadLetter(ctx, event, err)
+				continue
+			}
+			metrics.IPGCReclaimWebhookDeliveredCounts.Add(ctx, 1)
+		}
+	}
+}
+
+// deliver posts the reclaim event, retrying up to maxRetries times with
+// exponential backoff.
+func (w *reclaimWebhook) deliver(ctx context.Context, event ReclaimEvent) error {
+	body, err := json.Marshal(event)
+	if nil != err {
+		return err
+	}
+
+	backoff := w.initialBackoff
+	for attempt := 0; ; attempt++ {
+		err = w.post(ctx, body)
+		if nil == err || attempt >= w.maxRetries {
+			return err
+		}
+
+		logger.Sugar().Debugf("failed to publish reclaimed IP '%s' to webhook, retry in %v: %v", event.IP, backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > reclaimWebhookMaxBackoff {
+			backoff = reclaimWebhookMaxBackoff
+		}
+	}
+}
+
+func (w *reclaimWebhook) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if nil != err {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if nil != err {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// deadLetterReclaim gives up the reclaim event, it's logged in full so that
+// the IPAM of record can be reconciled by hand.
+func deadLetterReclaim(ctx context.Context, event ReclaimEvent, err error) {
+	metrics.IPGCReclaimWebhookDeadLetterCounts.Add(ctx, 1)
+	data, _ := json.Marshal(event)
+	logger.Sugar().Errorf("failed to publish reclaimed IP to webhook, drop event %s: %v", data, err)
+}
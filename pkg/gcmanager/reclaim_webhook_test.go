@@ -0,0 +1,134 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package gcmanager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes"
+)
+
+// deadLetterRecorder records the dead-lettered reclaim events.
+type deadLetterRecorder struct {
+	lock   sync.Mutex
+	events []ReclaimEvent
+	errs   []error
+}
+
+func (r *deadLetterRecorder) record(_ context.Context, event ReclaimEvent, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, event)
+	r.errs = append(r.errs, err)
+}
+
+func (r *deadLetterRecorder) Events() []ReclaimEvent {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]ReclaimEvent(nil), r.events...)
+}
+
+func (r *deadLetterRecorder) Errs() []error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]error(nil), r.errs...)
+}
+
+var _ = Describe("reclaim webhook", Label("reclaim_webhook_test"), func() {
+	var config *GarbageCollectionConfig
+	var recorder *deadLetterRecorder
+
+	// newTestReclaimWebhook returns the webhook posting to the server, whose
+	// backoff is shortened and whose dead letters are recorded.
+	newTestReclaimWebhook := func(url string) *reclaimWebhook {
+		config.ReclaimWebhookURL = url
+		w := newReclaimWebhook(config)
+		w.initialBackoff = time.Millisecond
+		w.deadLetter = recorder.record
+		return w
+	}
+
+	BeforeEach(func() {
+		config = &GarbageCollectionConfig{
+			ReclaimWebhookTimeout:    5,
+			ReclaimWebhookMaxRetries: 2,
+			ReclaimWebhookQueueSize:  10,
+		}
+		recorder = &deadLetterRecorder{}
+	})
+
+	It("retries the event on 5xx until it's delivered", func() {
+		var posts int32
+		var delivered ReclaimEvent
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if atomic.AddInt32(&posts, 1) < 3 {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			Expect(req.Header.Get("Content-Type")).To(Equal("application/json"))
+			Expect(json.NewDecoder(req.Body).Decode(&delivered)).To(Succeed())
+			rw.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		w := newTestReclaimWebhook(server.URL)
+		err := w.deliver(context.TODO(), ReclaimEvent{IP: "172.18.40.10", Pool: "pool", Reason: reclaimReasonNodeDeleted})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&posts)).To(BeEquivalentTo(3))
+		Expect(delivered.IP).To(Equal("172.18.40.10"))
+		Expect(delivered.Reason).To(Equal(reclaimReasonNodeDeleted))
+	})
+
+	It("dead-letters the event once the retries are exhausted", func() {
+		var posts int32
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&posts, 1)
+			rw.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		w := newTestReclaimWebhook(server.URL)
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		go w.run(ctx)
+
+		w.events <- ReclaimEvent{IP: "172.18.40.10", Pool: "pool"}
+		Eventually(recorder.Events).Should(HaveLen(1))
+		Expect(recorder.Events()[0].IP).To(Equal("172.18.40.10"))
+		Expect(recorder.Errs()[0]).To(MatchError(ContainSubstring("unexpected status code 500")))
+		Expect(atomic.LoadInt32(&posts)).To(BeEquivalentTo(config.ReclaimWebhookMaxRetries + 1))
+	})
+
+	It("dead-letters the event overflowing the queue", func() {
+		config.ReclaimWebhookQueueSize = 1
+		s := &SpiderGC{reclaimWebhook: newTestReclaimWebhook("http://127.0.0.1")}
+
+		ctx := context.TODO()
+		s.publishReclaim(ctx, ReclaimEvent{IP: "172.18.40.10"})
+		s.publishReclaim(ctx, ReclaimEvent{IP: "172.18.40.11"})
+
+		Expect(s.reclaimWebhook.events).To(HaveLen(1))
+		Expect(recorder.Events()).To(HaveLen(1))
+		Expect(recorder.Events()[0].IP).To(Equal("172.18.40.11"))
+		Expect(recorder.Errs()[0]).To(MatchError("queue is full"))
+	})
+
+	It("rejects the non-positive queue size", func() {
+		fakeClient := newFakeClient()
+		s := newTestSpiderGC(fakeClient, nil)
+		config.ReclaimWebhookURL = "http://127.0.0.1/reclaim"
+		config.ReclaimWebhookQueueSize = 0
+
+		_, err := NewGCManager(context.TODO(), &kubernetes.Clientset{}, config, s.wepMgr, s.ippoolMgr, s.podMgr, s.stsMgr, s.rIPMgr, s.leader)
+		Expect(err).To(MatchError(ContainSubstring("queue size must be positive")))
+	})
+})
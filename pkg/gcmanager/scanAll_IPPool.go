@@ -6,16 +6,20 @@ package gcmanager
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/spidernet-io/spiderpool/pkg/constant"
 	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
 	"github.com/spidernet-io/spiderpool/pkg/logutils"
 	metrics "github.com/spidernet-io/spiderpool/pkg/metric"
 	"github.com/spidernet-io/spiderpool/pkg/types"
+	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
 )
 
 // monitorGCSignal will monitor signal from CLI, DefaultGCInterval
@@ -78,129 +82,296 @@ func (s *SpiderGC) executeScanAll(ctx context.Context) {
 		return
 	}
 
-	for _, pool := range poolList.Items {
-		logger.Sugar().Debugf("checking IPPool '%s'", pool.Name)
+	pace := s.currentPace()
+	start := time.Now()
+	var scanned atomic.Int64
 
-		for poolIP, poolIPAllocation := range pool.Status.AllocatedIPs {
-			scanAllLogger := logger.With(zap.String("podNS", poolIPAllocation.Namespace), zap.String("podName", poolIPAllocation.Pod),
-				zap.String("containerID", poolIPAllocation.ContainerID), zap.String("NIC", poolIPAllocation.NIC))
+	workerNum := s.gcConfig.ScanAllWorkerNum
+	if workerNum < 1 {
+		workerNum = 1
+	}
+	pools := make(chan *spiderpoolv1.SpiderIPPool)
+	var wg sync.WaitGroup
+	for i := 0; i < workerNum; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pool := range pools {
+				s.scanIPPool(ctx, pool, pace, &scanned)
+			}
+		}()
+	}
 
-			podYaml, err := s.podMgr.GetPodByName(ctx, poolIPAllocation.Namespace, poolIPAllocation.Pod)
-			if err != nil {
-				wrappedLog := scanAllLogger.With(zap.String("gc-reason", "pod not found in k8s but still exists in IPPool allocation"))
-
-				// case: The pod in IPPool's ip-allocationDetail is not exist in k8s
-				if apierrors.IsNotFound(err) {
-					// check StatefulSet pod whether need to clean up its IP and Endpoint or not
-					if s.gcConfig.EnableStatefulSet && poolIPAllocation.OwnerControllerType == constant.KindStatefulSet {
-						isValidStsPod, err := s.stsMgr.IsValidStatefulSetPod(ctx, poolIPAllocation.Namespace, poolIPAllocation.Pod, poolIPAllocation.OwnerControllerType)
-						if nil != err {
-							scanAllLogger.Sugar().Errorf("failed to check StatefulSet pod '%s/%s' IP '%s' should be cleaned or not, error: %v",
-								poolIPAllocation.Namespace, poolIPAllocation.Pod, poolIP, err)
-							continue
-						}
-
-						if isValidStsPod {
-							scanAllLogger.Sugar().Warnf("no deed to release IP '%s' for StatefulSet pod '%s/%s'",
-								poolIP, poolIPAllocation.Namespace, poolIPAllocation.Pod)
-							continue
-						}
-					}
+feed:
+	for i := range poolList.Items {
+		select {
+		case pools <- &poolList.Items[i]:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(pools)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		logger.Info("receive ctx done, stop scanning all")
+		return
+	}
+	metrics.IPGCScanAllDurationSecondsHistogram.Record(ctx, time.Since(start).Seconds())
+
+	if s.leader.IsElected() && !s.gcConfig.DryRun {
+		s.cleanupReleasedEndpoints(ctx)
+	}
+}
 
-					err = s.releaseSingleIPAndRemoveWEPFinalizer(logutils.IntoContext(ctx, wrappedLog), pool.Name, poolIP, poolIPAllocation)
+// scanIPPool checks the IP allocations of the IPPool, the leaked IPs are
+// released in batches of ReleaseBatchSize.
+func (s *SpiderGC) scanIPPool(ctx context.Context, pool *spiderpoolv1.SpiderIPPool, pace gcPace, scanned *atomic.Int64) {
+	logger.Sugar().Debugf("checking IPPool '%s'", pool.Name)
+	if isGCSkipped(ctx, pool, constant.SpiderIPPoolKind) {
+		return
+	}
+
+	// the leaked IPs not released, due to dry-run mode or failures
+	leaks := map[string]spiderpoolv1.IPLeak{}
+
+	batchSize := s.gcConfig.ReleaseBatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	var batch []pendingRelease
+	queue := func(p pendingRelease) {
+		batch = append(batch, p)
+		if len(batch) >= batchSize {
+			s.releaseBatch(ctx, pool.Name, batch, leaks)
+			batch = nil
+		}
+	}
+
+	for poolIP, poolIPAllocation := range pool.Status.AllocatedIPs {
+		if err := pace.pause(ctx, scanned.Add(1)); err != nil {
+			return
+		}
+
+		scanAllLogger := logger.With(zap.String("podNS", poolIPAllocation.Namespace), zap.String("podName", poolIPAllocation.Pod),
+			zap.String("containerID", poolIPAllocation.ContainerID), zap.String("NIC", poolIPAllocation.NIC))
+
+		podYaml, err := s.podMgr.GetPodByName(ctx, poolIPAllocation.Namespace, poolIPAllocation.Pod)
+		if err != nil {
+			wrappedLog := scanAllLogger.With(zap.String("gc-reason", "pod not found in k8s but still exists in IPPool allocation"))
+
+			// case: The pod in IPPool's ip-allocationDetail is not exist in k8s
+			if apierrors.IsNotFound(err) {
+				// check StatefulSet pod whether need to clean up its IP and Endpoint or not
+				if s.gcConfig.EnableStatefulSet && poolIPAllocation.OwnerControllerType == constant.KindStatefulSet {
+					isValidStsPod, err := s.stsMgr.IsValidStatefulSetPod(ctx, poolIPAllocation.Namespace, poolIPAllocation.Pod, poolIPAllocation.OwnerControllerType)
 					if nil != err {
-						wrappedLog.Error(err.Error())
+						scanAllLogger.Sugar().Errorf("failed to check StatefulSet pod '%s/%s' IP '%s' should be cleaned or not, error: %v",
+							poolIPAllocation.Namespace, poolIPAllocation.Pod, poolIP, err)
 						continue
 					}
 
-					// clean up single IP and remove its corresponding SpiderEndpoint successfully, just continue to the next poolIP
+					if isValidStsPod {
+						scanAllLogger.Sugar().Warnf("no deed to release IP '%s' for StatefulSet pod '%s/%s'",
+							poolIP, poolIPAllocation.Namespace, poolIPAllocation.Pod)
+						continue
+					}
+				}
+
+				if s.endpointGCSkipped(ctx, wrappedLog, poolIPAllocation) {
 					continue
 				}
 
-				wrappedLog.Sugar().Errorf("check pod from kubernetes failed with error '%v'", err)
+				queue(pendingRelease{
+					ip:         poolIP,
+					allocation: poolIPAllocation,
+					leak: newIPLeak(poolIPAllocation, constant.IPLeakEvidencePodNotFound, constant.IPLeakActionReleaseIPAndRemoveFinalizer,
+						"pod not found in k8s but still exists in IPPool allocation"),
+					logger:          wrappedLog,
+					removeFinalizer: true,
+				})
 				continue
 			}
 
-			// check pod status phase with its yaml
-			podEntry, err := s.buildPodEntry(nil, podYaml, false)
-			if nil != err {
-				scanAllLogger.Sugar().Errorf("failed to build podEntry '%s/%s' in scanAll, error: %v", poolIPAllocation.Namespace, poolIPAllocation.Pod, err)
-				continue
-			}
+			wrappedLog.Sugar().Errorf("check pod from kubernetes failed with error '%v'", err)
+			continue
+		}
+
+		// check pod status phase with its yaml
+		podEntry, err := s.buildPodEntry(nil, podYaml, false)
+		if nil != err {
+			scanAllLogger.Sugar().Errorf("failed to build podEntry '%s/%s' in scanAll, error: %v", poolIPAllocation.Namespace, poolIPAllocation.Pod, err)
+			continue
+		}
+
+		// case: The pod in IPPool's ip-allocationDetail is also exist in k8s, but the pod is in 'Terminating|Succeeded|Failed' status phase
+		if podEntry != nil {
+			if time.Now().UTC().After(podEntry.TracingStopTime) {
+				if s.endpointGCSkipped(ctx, scanAllLogger, poolIPAllocation) {
+					continue
+				}
 
-			// case: The pod in IPPool's ip-allocationDetail is also exist in k8s, but the pod is in 'Terminating|Succeeded|Failed' status phase
-			if podEntry != nil {
-				if time.Now().UTC().After(podEntry.TracingStopTime) {
-					wrappedLog := scanAllLogger.With(zap.String("gc-reason", "pod is out of time"))
-					err = s.releaseSingleIPAndRemoveWEPFinalizer(logutils.IntoContext(ctx, wrappedLog), pool.Name, poolIP, poolIPAllocation)
+				queue(pendingRelease{
+					ip:         poolIP,
+					allocation: poolIPAllocation,
+					leak: newIPLeak(poolIPAllocation, constant.IPLeakEvidencePodTerminated, constant.IPLeakActionReleaseIPAndRemoveFinalizer,
+						fmt.Sprintf("pod is '%s' and out of time since %s", podEntry.PodTracingReason, podEntry.TracingStopTime.Format(time.RFC3339))),
+					logger:          scanAllLogger.With(zap.String("gc-reason", "pod is out of time")),
+					removeFinalizer: true,
+					eligibleTime:    podEntry.TracingStopTime,
+				})
+			} else {
+				// otherwise, flush the PodEntry database and let tracePodWorker to solve it if the current controller is elected master.
+				if s.leader.IsElected() {
+					err = s.PodDB.ApplyPodEntry(podEntry)
 					if nil != err {
-						wrappedLog.Error(err.Error())
+						scanAllLogger.Error(err.Error())
 						continue
 					}
-				} else {
-					// otherwise, flush the PodEntry database and let tracePodWorker to solve it if the current controller is elected master.
-					if s.leader.IsElected() {
-						err = s.PodDB.ApplyPodEntry(podEntry)
-						if nil != err {
-							scanAllLogger.Error(err.Error())
-							continue
-						}
-
-						scanAllLogger.With(zap.String("tracing-reason", string(podEntry.PodTracingReason))).
-							Sugar().Infof("update podEntry '%s/%s' successfully", poolIPAllocation.Namespace, poolIPAllocation.Pod)
-					}
+
+					scanAllLogger.With(zap.String("tracing-reason", string(podEntry.PodTracingReason))).
+						Sugar().Infof("update podEntry '%s/%s' successfully", poolIPAllocation.Namespace, poolIPAllocation.Pod)
 				}
-			} else {
-				endpoint, err := s.wepMgr.GetEndpointByName(ctx, podYaml.Namespace, podYaml.Name)
-				if err != nil {
-					scanAllLogger.Sugar().Errorf("failed to get Endpoint '%s/%s': %v", podYaml.Namespace, podYaml.Name, err)
+			}
+		} else {
+			endpoint, err := s.wepMgr.GetEndpointByName(ctx, podYaml.Namespace, podYaml.Name)
+			if err != nil {
+				scanAllLogger.Sugar().Errorf("failed to get Endpoint '%s/%s': %v", podYaml.Namespace, podYaml.Name, err)
+				continue
+			}
+
+			// case: The pod in IPPool's ip-allocationDetail is also exist in k8s, but the IP corresponding allocation containerID is different with wep current containerID
+			if endpoint.Status.Current != nil && endpoint.Status.Current.ContainerID != poolIPAllocation.ContainerID {
+				if isGCSkipped(ctx, endpoint, constant.SpiderEndpointKind) {
 					continue
 				}
 
-				// case: The pod in IPPool's ip-allocationDetail is also exist in k8s, but the IP corresponding allocation containerID is different with wep current containerID
-				if endpoint.Status.Current != nil && endpoint.Status.Current.ContainerID != poolIPAllocation.ContainerID {
-					wrappedLog := scanAllLogger.With(zap.String("gc-reason", "IPPoolAllocation containerID is different with wep current containerID"))
-					// release IP but no need to remove wep finalizer
-					err = s.ippoolMgr.ReleaseIP(ctx, pool.Name, []types.IPAndCID{{
-						IP:          poolIP,
-						ContainerID: poolIPAllocation.ContainerID},
-					})
-					if nil != err {
-						wrappedLog.Sugar().Errorf("failed to release ip '%s', error: '%v'", poolIP, err)
-						continue
+				if s.gcConfig.EnableZombieContainerIDReconcile && isZombieContainerID(pool.Name, poolIP, podYaml, endpoint) {
+					wrappedLog := scanAllLogger.With(zap.String("gc-reason", "IPPoolAllocation containerID is a zombie of the pod current container"))
+					if err := s.reconcileZombieContainerID(ctx, wrappedLog, pool.Name, poolIP, podYaml, endpoint); nil != err {
+						wrappedLog.Sugar().Errorf("failed to rewrite the zombie containerID of ip '%s', error: '%v'", poolIP, err)
 					}
-
-					wrappedLog.Sugar().Infof("release ip '%s' successfully!", poolIP)
+					continue
 				}
+
+				// release IP but no need to remove wep finalizer
+				queue(pendingRelease{
+					ip:         poolIP,
+					allocation: poolIPAllocation,
+					leak: newIPLeak(poolIPAllocation, constant.IPLeakEvidenceStaleContainerID, constant.IPLeakActionReleaseIP,
+						fmt.Sprintf("the current container of the pod is '%s'", endpoint.Status.Current.ContainerID)),
+					logger: scanAllLogger.With(zap.String("gc-reason", "IPPoolAllocation containerID is different with wep current containerID")),
+				})
 			}
 		}
-		logger.Sugar().Debugf("task checking IPPool '%s' is completed", pool.Name)
+	}
+	s.releaseBatch(ctx, pool.Name, batch, leaks)
+
+	// only the elected controller reports the leaked IPs, the reports of the previous scan are cleared if no more leak
+	if s.leader.IsElected() && (len(leaks) != 0 || len(pool.Status.SuspectedLeakedIPs) != 0) {
+		err := s.waitAPIBudget(ctx)
+		if nil == err {
+			err = s.ippoolMgr.ReportIPLeaks(ctx, pool.Name, leaks)
+		}
+		if nil != err {
+			logger.Sugar().Errorf("failed to report the leaked IPs of IPPool '%s': %v", pool.Name, err)
+		}
+	}
+	logger.Sugar().Debugf("task checking IPPool '%s' is completed", pool.Name)
+}
+
+// cleanupReleasedEndpoints cleans up the terminating SpiderEndpoints whose IP
+// addresses are all released, but whose finalizers failed to be removed.
+func (s *SpiderGC) cleanupReleasedEndpoints(ctx context.Context) {
+	result, err := s.wepMgr.CleanupEndpoints(ctx, nil, workloadendpointmanager.EndpointCleanupOptions{
+		Filter: func(endpoint *spiderpoolv1.SpiderEndpoint) bool {
+			return endpoint.DeletionTimestamp != nil && endpoint.Status.Current == nil && !isGCSkipped(ctx, endpoint, constant.SpiderEndpointKind)
+		},
+		Parallelism: s.gcConfig.ReleaseIPWorkerNum,
+		Pacer:       s,
+	})
+	if nil != err {
+		logger.Sugar().Errorf("failed to clean up released SpiderEndpoints: %v", err)
+	}
+	if result != nil && len(result.Cleaned) != 0 {
+		logger.Sugar().Infof("clean up released SpiderEndpoints %v successfully", result.Cleaned)
 	}
 }
 
 // releaseSingleIPAndRemoveWEPFinalizer serves for handleTerminatingPod to gc singleIP and remove wep finalizer
-func (s *SpiderGC) releaseSingleIPAndRemoveWEPFinalizer(ctx context.Context, poolName, poolIP string, poolIPAllocation spiderpoolv1.PoolIPAllocation) error {
+func (s *SpiderGC) releaseSingleIPAndRemoveWEPFinalizer(ctx context.Context, poolName, poolIP string, poolIPAllocation spiderpoolv1.PoolIPAllocation, reason string) error {
 	log := logutils.FromContext(ctx)
 
-	singleIP := []types.IPAndCID{{IP: poolIP, ContainerID: poolIPAllocation.ContainerID}}
-	err := s.ippoolMgr.ReleaseIP(ctx, poolName, singleIP)
+	if s.gcConfig.DryRun {
+		log.Sugar().Infof("dry run, would release ip '%s' and remove SpiderEndpoint '%s/%s' finalizer",
+			poolIP, poolIPAllocation.Namespace, poolIPAllocation.Pod)
+		return nil
+	}
+
+	err := s.waitAPIBudget(ctx)
+	if nil == err {
+		err = s.ippoolMgr.ReleaseIP(ctx, poolName, []types.IPAndCID{{IP: poolIP, ContainerID: poolIPAllocation.ContainerID}})
+	}
 	if nil != err {
 		metrics.IPGCFailureCounts.Add(ctx, 1)
 		return fmt.Errorf("failed to release IP '%s', error: '%v'", poolIP, err)
 	}
 
 	metrics.IPGCTotalCounts.Add(ctx, 1)
+	recordReclaim(ctx, 1, time.Time{})
+	s.publishReclaim(ctx, ReclaimEvent{
+		IP:          poolIP,
+		Pool:        poolName,
+		Namespace:   poolIPAllocation.Namespace,
+		Pod:         poolIPAllocation.Pod,
+		ContainerID: poolIPAllocation.ContainerID,
+		Node:        poolIPAllocation.Node,
+		Reason:      reason,
+	})
 	log.Sugar().Infof("release ip '%s' successfully", poolIP)
 
-	err = s.wepMgr.RemoveFinalizer(ctx, poolIPAllocation.Namespace, poolIPAllocation.Pod)
+	return s.removeWEPFinalizer(ctx, log, poolIPAllocation.Namespace, poolIPAllocation.Pod)
+}
+
+// removeWEPFinalizer removes the finalizer of the SpiderEndpoint, it's done if the SpiderEndpoint is already cleaned up
+func (s *SpiderGC) removeWEPFinalizer(ctx context.Context, log *zap.Logger, namespace, podName string) error {
+	err := s.waitAPIBudget(ctx)
+	if nil == err {
+		err = s.wepMgr.RemoveFinalizer(ctx, namespace, podName)
+	}
 	if nil != err {
 		if apierrors.IsNotFound(err) {
-			log.Sugar().Debugf("SpiderEndpoint '%s/%s' is already cleaned up", poolIPAllocation.Namespace, poolIPAllocation.Pod)
+			log.Sugar().Debugf("SpiderEndpoint '%s/%s' is already cleaned up", namespace, podName)
 			return nil
 		}
-		return fmt.Errorf("failed to remove SpiderEndpoint '%s/%s' finalizer, error: '%v'", poolIPAllocation.Namespace, poolIPAllocation.Pod, err)
+		return fmt.Errorf("failed to remove SpiderEndpoint '%s/%s' finalizer, error: '%v'", namespace, podName, err)
 	}
 
-	log.Sugar().Infof("remove SpiderEndpoint '%s/%s' finalizer successfully", poolIPAllocation.Namespace, poolIPAllocation.Pod)
+	log.Sugar().Infof("remove SpiderEndpoint '%s/%s' finalizer successfully", namespace, podName)
 	return nil
 }
+
+// endpointGCSkipped reports whether the SpiderEndpoint of the IP allocation is frozen from the IP garbage collection, it's
+// regarded as frozen if it fails to be checked.
+func (s *SpiderGC) endpointGCSkipped(ctx context.Context, log *zap.Logger, poolIPAllocation spiderpoolv1.PoolIPAllocation) bool {
+	skipped, err := s.isEndpointGCSkipped(ctx, poolIPAllocation.Namespace, poolIPAllocation.Pod)
+	if nil != err {
+		log.Error(err.Error())
+		return true
+	}
+
+	return skipped
+}
+
+// newIPLeak builds the evidence of the leaked IP which scanAll found
+func newIPLeak(poolIPAllocation spiderpoolv1.PoolIPAllocation, evidence, action, message string) spiderpoolv1.IPLeak {
+	return spiderpoolv1.IPLeak{
+		Namespace:   poolIPAllocation.Namespace,
+		Pod:         poolIPAllocation.Pod,
+		ContainerID: poolIPAllocation.ContainerID,
+		Evidence:    evidence,
+		Message:     message,
+		Action:      action,
+		DetectedAt:  metav1.Now(),
+	}
+}
@@ -31,7 +31,7 @@ func (s *SpiderGC) tracePodWorker(ctx context.Context) {
 				s.handlePodEntryForTracingTimeOut(&podCache)
 			}
 
-			time.Sleep(time.Duration(s.gcConfig.TracePodGapDuration) * time.Second)
+			time.Sleep(s.currentPace().tracePodGap)
 		}
 	}
 }
@@ -80,15 +80,38 @@ func (s *SpiderGC) releaseIPPoolIPExecutor(ctx context.Context, workerIndex int)
 				continue
 			}
 
+			if isGCSkipped(ctx, endpoint, constant.SpiderEndpointKind) {
+				continue
+			}
+
 			// we need to gather the pod corresponding SpiderEndpoint to get the used history IPs.
 			podUsedIPs := workloadendpointmanager.ListAllHistoricalIPs(endpoint)
 
-			// release pod used history IPs
+			if s.gcConfig.DryRun {
+				loggerReleaseIP.Sugar().Infof("dry run, would release pod '%s/%s' used IPs '%+v' and remove wep finalizer",
+					podCache.Namespace, podCache.PodName, podUsedIPs)
+				continue
+			}
+
+			// release pod used history IPs, the SpiderEndpoint is kept if any of its IPPools is frozen from gc
+			frozen := false
 			for poolName, ips := range podUsedIPs {
+				skipped, err := s.isIPPoolGCSkipped(ctx, poolName)
+				if nil != err || skipped {
+					if nil != err {
+						loggerReleaseIP.Error(err.Error())
+					}
+					frozen = true
+					continue
+				}
+
 				loggerReleaseIP.Sugar().Infof("pod '%s/%s used IPs '%+v' from pool '%s', begin to release",
 					podCache.Namespace, podCache.PodName, ips, poolName)
 
-				err = s.ippoolMgr.ReleaseIP(ctx, poolName, ips)
+				err = s.waitAPIBudget(ctx)
+				if nil == err {
+					err = s.ippoolMgr.ReleaseIP(ctx, poolName, ips)
+				}
 				if nil != err {
 					metrics.IPGCFailureCounts.Add(ctx, 1)
 					loggerReleaseIP.Sugar().Errorf("failed to release pool '%s' IPs '%+v' in wep '%s/%s', error: %v",
@@ -98,13 +121,31 @@ func (s *SpiderGC) releaseIPPoolIPExecutor(ctx context.Context, workerIndex int)
 
 				// metric
 				metrics.IPGCTotalCounts.Add(ctx, 1)
+				recordReclaim(ctx, len(ips), podCache.TracingStopTime)
+				for _, ip := range ips {
+					s.publishReclaim(ctx, ReclaimEvent{
+						IP:          ip.IP,
+						Pool:        poolName,
+						Namespace:   podCache.Namespace,
+						Pod:         podCache.PodName,
+						ContainerID: ip.ContainerID,
+						Node:        podCache.NodeName,
+						Reason:      "Pod" + string(podCache.PodTracingReason),
+					})
+				}
 			}
 
 			loggerReleaseIP.Sugar().Infof("release IPPoolIP task '%+v' successfully", *podCache)
+			if frozen {
+				continue
+			}
 
 			// delete StatefulSet wep (other controller wep has OwnerReference, its lifecycle is same with pod)
 			if endpoint.Status.OwnerControllerType == constant.KindStatefulSet {
-				err = s.wepMgr.DeleteEndpoint(ctx, endpoint)
+				err = s.waitAPIBudget(ctx)
+				if nil == err {
+					err = s.wepMgr.DeleteEndpoint(ctx, endpoint)
+				}
 				if nil != err {
 					loggerReleaseIP.Sugar().Errorf("failed to delete StatefulSet wep '%s/%s', error: '%v'",
 						podCache.Namespace, podCache.PodName, err)
@@ -112,7 +153,10 @@ func (s *SpiderGC) releaseIPPoolIPExecutor(ctx context.Context, workerIndex int)
 				}
 			}
 
-			err = s.wepMgr.RemoveFinalizer(ctx, podCache.Namespace, podCache.PodName)
+			err = s.waitAPIBudget(ctx)
+			if nil == err {
+				err = s.wepMgr.RemoveFinalizer(ctx, podCache.Namespace, podCache.PodName)
+			}
 			if nil != err {
 				loggerReleaseIP.Sugar().Errorf("failed to remove wep '%s/%s' finalizer, error: '%v'",
 					podCache.Namespace, podCache.PodName, err)
@@ -0,0 +1,57 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package gcmanager
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
+)
+
+// isZombieContainerID reports whether the IPPool allocation references a zombie containerID, while the IP is still in use by
+// the current container of the alive pod, e.g. the records of a StatefulSet pod are not all rewritten after its node reboots.
+func isZombieContainerID(poolName, poolIP string, pod *corev1.Pod, endpoint *spiderpoolv1.SpiderEndpoint) bool {
+	current := endpoint.Status.Current
+	if current == nil || current.Node == nil || *current.Node != pod.Spec.NodeName {
+		return false
+	}
+
+	// the current IP allocation must belong to the pod, rather than the previous one with the same name
+	if current.CreationTime != nil && current.CreationTime.Before(&pod.CreationTimestamp) {
+		return false
+	}
+
+	return workloadendpointmanager.HasCurrentIP(endpoint, poolName, poolIP)
+}
+
+// reconcileZombieContainerID rewrites the IPPool allocation with the zombie containerID to the current container of the pod,
+// rather than releasing the IP in use.
+func (s *SpiderGC) reconcileZombieContainerID(ctx context.Context, log *zap.Logger, poolName, poolIP string, pod *corev1.Pod, endpoint *spiderpoolv1.SpiderEndpoint) error {
+	containerID := endpoint.Status.Current.ContainerID
+	if s.gcConfig.DryRun {
+		log.Sugar().Infof("dry run, would rewrite the containerID of ip '%s' to '%s'", poolIP, containerID)
+		return nil
+	}
+
+	err := s.waitAPIBudget(ctx)
+	if nil == err {
+		err = s.ippoolMgr.UpdateAllocatedIPs(ctx, poolName, []types.IPAndCID{{
+			IP:          poolIP,
+			ContainerID: containerID,
+			Node:        pod.Spec.NodeName,
+			PodUID:      string(pod.UID),
+		}})
+	}
+	if nil != err {
+		return err
+	}
+
+	log.Sugar().Infof("rewrite the zombie containerID of ip '%s' to '%s' successfully", poolIP, containerID)
+	return nil
+}
@@ -54,6 +54,35 @@ func IsCIDROverlap(version types.IPVersion, subnet1, subnet2 string) (bool, erro
 	return containsCIDR(subnet1, subnet2) || containsCIDR(subnet2, subnet1), nil
 }
 
+// ReservedIPsOfCIDR returns the IP addresses of the subnet which can not be
+// assigned to hosts, that is, the network address and the broadcast address
+// of an IPv4 subnet, or the Subnet-Router anycast address of an IPv6 subnet.
+// Nothing is reserved for the point-to-point subnets (/31, /32, /127, /128).
+func ReservedIPsOfCIDR(version types.IPVersion, subnet string) ([]net.IP, error) {
+	ipNet, err := ParseCIDR(version, subnet)
+	if err != nil {
+		return nil, err
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	if bits-ones <= 1 {
+		return nil, nil
+	}
+
+	network := ipNet.IP.Mask(ipNet.Mask)
+	if version == constant.IPv6 {
+		return []net.IP{network}, nil
+	}
+
+	network = network.To4()
+	broadcast := make(net.IP, len(network))
+	for i := range network {
+		broadcast[i] = network[i] | ^ipNet.Mask[i]
+	}
+
+	return []net.IP{network, broadcast}, nil
+}
+
 func containsCIDR(subnet1 string, subnet2 string) bool {
 	// Ignore the error returned here. The format of the subnet should be
 	// verified in external IsCIDR.
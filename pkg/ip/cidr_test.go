@@ -100,6 +100,36 @@ var _ = Describe("CIDR", Label("cidr_test"), func() {
 		})
 	})
 
+	Describe("Test ReservedIPsOfCIDR", func() {
+		It("inputs invalid CIDR address", func() {
+			ips, err := spiderpoolip.ReservedIPsOfCIDR(constant.IPv4, constant.InvalidCIDR)
+			Expect(err).To(MatchError(spiderpoolip.ErrInvalidCIDRFormat))
+			Expect(ips).To(BeNil())
+		})
+
+		It("returns the network and broadcast addresses of IPv4 subnet", func() {
+			ips, err := spiderpoolip.ReservedIPsOfCIDR(constant.IPv4, "172.18.40.0/24")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal([]net.IP{net.ParseIP("172.18.40.0").To4(), net.ParseIP("172.18.40.255").To4()}))
+		})
+
+		It("returns the Subnet-Router anycast address of IPv6 subnet", func() {
+			ips, err := spiderpoolip.ReservedIPsOfCIDR(constant.IPv6, "abcd:1234::/120")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal([]net.IP{net.ParseIP("abcd:1234::")}))
+		})
+
+		It("returns nothing for point-to-point subnets", func() {
+			ips, err := spiderpoolip.ReservedIPsOfCIDR(constant.IPv4, "172.18.40.0/31")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(BeEmpty())
+
+			ips, err = spiderpoolip.ReservedIPsOfCIDR(constant.IPv6, "abcd:1234::/128")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(BeEmpty())
+		})
+	})
+
 	Describe("Test IsCIDROverlap", func() {
 		When("Verifying", func() {
 			It("inputs invalid IP version", func() {
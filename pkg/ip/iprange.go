@@ -6,8 +6,10 @@ package ip
 import (
 	"bytes"
 	"fmt"
+	"math/big"
 	"net"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/asaskevich/govalidator"
@@ -45,29 +47,45 @@ func ParseIPRanges(version types.IPVersion, ipRanges []string) ([]net.IP, error)
 }
 
 // ParseIPRange parses IP range as an IP address slices of the specified
-// IP version.
+// IP version. See IsIPRange for the notations of IP range.
 func ParseIPRange(version types.IPVersion, ipRange string) ([]net.IP, error) {
 	if err := IsIPRange(version, ipRange); err != nil {
 		return nil, err
 	}
 
-	arr := strings.Split(ipRange, "-")
-	n := len(arr)
+	// Ignore the result here. The format of the IP range has been verified
+	// in IsIPRange above.
+	r, _ := parseIPRangeNotation(version, ipRange)
+
 	var ips []net.IP
-	if n == 1 {
-		ips = append(ips, net.ParseIP(arr[0]))
+	cur := ipToInt(r.first)
+	end := ipToInt(r.last)
+	for cur.Cmp(end) <= 0 {
+		ips = append(ips, intToIP(cur))
+		cur = big.NewInt(0).Add(cur, r.step)
 	}
 
-	if n == 2 {
-		cur := net.ParseIP(arr[0])
-		end := net.ParseIP(arr[1])
-		for Cmp(cur, end) <= 0 {
-			ips = append(ips, cur)
-			cur = NextIP(cur)
+	return ips, nil
+}
+
+// IsCompactIPRange reports whether the IP range of the specified IP
+// version is in the CIDR, offset or step notation, which is supposed to be
+// converted into plain IP ranges with MergeIPRanges.
+func IsCompactIPRange(version types.IPVersion, ipRange string) bool {
+	r, ok := parseIPRangeNotation(version, ipRange)
+	return ok && r.compact
+}
+
+// HasCompactIPRanges reports whether any of the IP ranges is in the CIDR,
+// offset or step notation.
+func HasCompactIPRanges(version types.IPVersion, ipRanges []string) bool {
+	for _, r := range ipRanges {
+		if IsCompactIPRange(version, r) {
+			return true
 		}
 	}
 
-	return ips, nil
+	return false
 }
 
 // ConvertIPsToIPRanges converts the IP address slices of the specified
@@ -167,7 +185,17 @@ func IsIPRangeOverlap(version types.IPVersion, ipRange1, ipRange2 string) (bool,
 
 // IsIPRange reports whether ipRange string is a valid IP range. An IP
 // range can be a single IP address in the style of '172.18.40.0', or
-// an address range in the form of '172.18.40.0-172.18.40.10'.
+// an address range in the form of '172.18.40.0-172.18.40.10'. To avoid
+// long lists of IP ranges, the following compact notations are also
+// supported:
+// "172.18.40.0/28": all IP addresses of the CIDR.
+// "172.18.40.0/24@10-250": the IP addresses of the CIDR from offset 10
+// to 250, that is '172.18.40.10-172.18.40.250'. The last offset can be
+// omitted, which means the end of the CIDR.
+// "172.18.40.10-172.18.40.250:2": every 2nd IP address of the IP range,
+// the step also applies to the notations above. The last IPv6 address
+// of an IP range must be bracketed to be followed by the step, such as
+// "abcd:1234::1-[abcd:1234::ff]:2".
 // The following formats are invalid:
 // "172.18.40.0 - 172.18.40.10": there can be no space between two IP
 // addresses.
@@ -190,49 +218,125 @@ func IsIPRange(version types.IPVersion, ipRange string) error {
 // IsIPv4IPRange reports whether ipRange string is a valid IPv4 range.
 // See IsIPRange for more description of IP range.
 func IsIPv4IPRange(ipRange string) bool {
-	ips := strings.Split(ipRange, "-")
-	n := len(ips)
-	if n > 2 {
-		return false
-	}
+	_, ok := parseIPRangeNotation(constant.IPv4, ipRange)
+	return ok
+}
 
-	if n == 1 {
-		return govalidator.IsIPv4(ips[0])
+// IsIPv6IPRange reports whether ipRange string is a valid IPv6 range.
+// See IsIPRange for more description of IP range.
+func IsIPv6IPRange(ipRange string) bool {
+	_, ok := parseIPRangeNotation(constant.IPv6, ipRange)
+	return ok
+}
+
+// ipRangeNotation is the parsed form of an IP range, which consists of
+// every step-th IP address from the first one to the last one.
+type ipRangeNotation struct {
+	first   net.IP
+	last    net.IP
+	step    *big.Int
+	compact bool
+}
+
+// parseIPRangeNotation parses the IP range of the specified IP version,
+// false is returned if it is invalid. See IsIPRange for the notations.
+func parseIPRangeNotation(version types.IPVersion, ipRange string) (*ipRangeNotation, bool) {
+	isIP := govalidator.IsIPv4
+	if version == constant.IPv6 {
+		isIP = govalidator.IsIPv6
 	}
 
-	if n == 2 {
-		if !govalidator.IsIPv4(ips[0]) || !govalidator.IsIPv4(ips[1]) {
-			return false
+	r := &ipRangeNotation{step: big.NewInt(1)}
+	body := ipRange
+	if i := strings.LastIndex(ipRange, ":"); i > 0 && hasIPRangeStep(version, ipRange, i) {
+		step, err := strconv.ParseUint(ipRange[i+1:], 10, 64)
+		if err != nil || step == 0 {
+			return nil, false
 		}
-		if Cmp(net.ParseIP(ips[0]), net.ParseIP(ips[1])) == 1 {
-			return false
+		r.step = big.NewInt(0).SetUint64(step)
+		r.compact = true
+		body = ipRange[:i]
+		if strings.HasSuffix(body, "]") {
+			if !strings.Contains(body, "-[") {
+				return nil, false
+			}
+			body = strings.Replace(strings.TrimSuffix(body, "]"), "-[", "-", 1)
 		}
 	}
 
-	return true
-}
+	if strings.Contains(body, "/") {
+		cidr, offsets, hasOffsets := strings.Cut(body, "@")
+		if !govalidator.IsCIDR(cidr) {
+			return nil, false
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil || !isIP(ipNet.IP.String()) {
+			return nil, false
+		}
 
-// IsIPv6IPRange reports whether ipRange string is a valid IPv6 range.
-// See IsIPRange for more description of IP range.
-func IsIPv6IPRange(ipRange string) bool {
-	ips := strings.Split(ipRange, "-")
+		ones, bits := ipNet.Mask.Size()
+		size := big.NewInt(0).Lsh(big.NewInt(1), uint(bits-ones))
+		base := ipToInt(ipNet.IP)
+		firstOffset := big.NewInt(0)
+		lastOffset := big.NewInt(0).Sub(size, big.NewInt(1))
+		if hasOffsets {
+			from, to, hasTo := strings.Cut(offsets, "-")
+			if _, ok := firstOffset.SetString(from, 10); !ok || firstOffset.Sign() < 0 || strings.HasPrefix(from, "+") {
+				return nil, false
+			}
+			if hasTo {
+				if _, ok := lastOffset.SetString(to, 10); !ok || lastOffset.Sign() < 0 || strings.HasPrefix(to, "+") {
+					return nil, false
+				}
+			}
+			if lastOffset.Cmp(size) >= 0 || firstOffset.Cmp(lastOffset) > 0 {
+				return nil, false
+			}
+		}
+
+		r.first = intToIP(big.NewInt(0).Add(base, firstOffset))
+		r.last = intToIP(big.NewInt(0).Add(base, lastOffset))
+		r.compact = true
+		return r, true
+	}
+
+	ips := strings.Split(body, "-")
 	n := len(ips)
 	if n > 2 {
-		return false
+		return nil, false
 	}
 
 	if n == 1 {
-		return govalidator.IsIPv6(ips[0])
+		// A single IP address has nothing to step over.
+		if r.compact || !isIP(ips[0]) {
+			return nil, false
+		}
+		r.first = net.ParseIP(ips[0])
+		r.last = r.first
+		return r, true
 	}
 
-	if n == 2 {
-		if !govalidator.IsIPv6(ips[0]) || !govalidator.IsIPv6(ips[1]) {
-			return false
-		}
-		if Cmp(net.ParseIP(ips[0]), net.ParseIP(ips[1])) == 1 {
-			return false
-		}
+	if !isIP(ips[0]) || !isIP(ips[1]) {
+		return nil, false
+	}
+	r.first = net.ParseIP(ips[0])
+	r.last = net.ParseIP(ips[1])
+	if Cmp(r.first, r.last) == 1 {
+		return nil, false
+	}
+
+	return r, true
+}
+
+// hasIPRangeStep reports whether the colon at index i of the IP range
+// leads the step, rather than being a part of the IPv6 address.
+func hasIPRangeStep(version types.IPVersion, ipRange string, i int) bool {
+	if version != constant.IPv6 {
+		return true
+	}
+	if ipRange[i-1] == ']' {
+		return true
 	}
 
-	return true
+	return strings.Contains(ipRange, "/") && i > strings.LastIndex(ipRange, "/")
 }
@@ -152,6 +152,79 @@ var _ = Describe("IP range", Label("ip_range_test"), func() {
 			))
 		})
 
+		It("parses IPv4 IP range in compact notations", func() {
+			ips, err := spiderpoolip.ParseIPRange(constant.IPv4, "172.18.40.0/30")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal(
+				[]net.IP{
+					net.IPv4(172, 18, 40, 0),
+					net.IPv4(172, 18, 40, 1),
+					net.IPv4(172, 18, 40, 2),
+					net.IPv4(172, 18, 40, 3),
+				},
+			))
+
+			ips, err = spiderpoolip.ParseIPRange(constant.IPv4, "172.18.40.0/24@10-12")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal(
+				[]net.IP{
+					net.IPv4(172, 18, 40, 10),
+					net.IPv4(172, 18, 40, 11),
+					net.IPv4(172, 18, 40, 12),
+				},
+			))
+
+			ips, err = spiderpoolip.ParseIPRange(constant.IPv4, "172.18.40.0/24@254")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal([]net.IP{net.IPv4(172, 18, 40, 254), net.IPv4(172, 18, 40, 255)}))
+
+			ips, err = spiderpoolip.ParseIPRange(constant.IPv4, "172.18.40.10-172.18.40.15:2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal(
+				[]net.IP{
+					net.IPv4(172, 18, 40, 10),
+					net.IPv4(172, 18, 40, 12),
+					net.IPv4(172, 18, 40, 14),
+				},
+			))
+
+			ips, err = spiderpoolip.ParseIPRange(constant.IPv4, "172.18.40.0/24@1-7:3")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal(
+				[]net.IP{
+					net.IPv4(172, 18, 40, 1),
+					net.IPv4(172, 18, 40, 4),
+					net.IPv4(172, 18, 40, 7),
+				},
+			))
+		})
+
+		It("parses IPv6 IP range in compact notations", func() {
+			ips, err := spiderpoolip.ParseIPRange(constant.IPv6, "abcd:1234::/127")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal([]net.IP{net.ParseIP("abcd:1234::"), net.ParseIP("abcd:1234::1")}))
+
+			ips, err = spiderpoolip.ParseIPRange(constant.IPv6, "abcd:1234::/120@16-48:16")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal(
+				[]net.IP{
+					net.ParseIP("abcd:1234::10"),
+					net.ParseIP("abcd:1234::20"),
+					net.ParseIP("abcd:1234::30"),
+				},
+			))
+
+			ips, err = spiderpoolip.ParseIPRange(constant.IPv6, "abcd:1234::1-[abcd:1234::5]:2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal(
+				[]net.IP{
+					net.ParseIP("abcd:1234::1"),
+					net.ParseIP("abcd:1234::3"),
+					net.ParseIP("abcd:1234::5"),
+				},
+			))
+		})
+
 		It("parses IPv6 IP range", func() {
 			ips, err := spiderpoolip.ParseIPRange(constant.IPv6, "abcd:1234::a")
 			Expect(err).NotTo(HaveOccurred())
@@ -356,6 +429,15 @@ var _ = Describe("IP range", Label("ip_range_test"), func() {
 		})
 	})
 
+	Describe("Test HasCompactIPRanges", func() {
+		It("tests whether there are IP ranges in compact notations", func() {
+			Expect(spiderpoolip.HasCompactIPRanges(constant.IPv4, []string{"172.18.40.1-172.18.40.2", "172.18.40.10"})).To(BeFalse())
+			Expect(spiderpoolip.HasCompactIPRanges(constant.IPv4, []string{"172.18.40.10", "172.18.40.0/28"})).To(BeTrue())
+			Expect(spiderpoolip.HasCompactIPRanges(constant.IPv6, []string{"abcd:1234::1-abcd:1234::2"})).To(BeFalse())
+			Expect(spiderpoolip.HasCompactIPRanges(constant.IPv6, []string{"abcd:1234::1-[abcd:1234::2]:2"})).To(BeTrue())
+		})
+	})
+
 	Describe("Test IsIPv4IPRange", func() {
 		It("tests whether it is an IPv4 IP range", func() {
 			Expect(spiderpoolip.IsIPv4IPRange(constant.InvalidIPRange)).To(BeFalse())
@@ -364,8 +446,19 @@ var _ = Describe("IP range", Label("ip_range_test"), func() {
 			Expect(spiderpoolip.IsIPv4IPRange("172.18.40.2-172.18.40.1")).To(BeFalse())
 			Expect(spiderpoolip.IsIPv4IPRange("172.18.40.1-172.18.40.2-172.18.40.3")).To(BeFalse())
 
+			Expect(spiderpoolip.IsIPv4IPRange("172.18.40.10:2")).To(BeFalse())
+			Expect(spiderpoolip.IsIPv4IPRange("172.18.40.1-172.18.40.2:0")).To(BeFalse())
+			Expect(spiderpoolip.IsIPv4IPRange("172.18.40.0/33")).To(BeFalse())
+			Expect(spiderpoolip.IsIPv4IPRange("172.18.40.0/24@256")).To(BeFalse())
+			Expect(spiderpoolip.IsIPv4IPRange("172.18.40.0/24@10-5")).To(BeFalse())
+			Expect(spiderpoolip.IsIPv4IPRange("172.18.40.0/24@-1")).To(BeFalse())
+			Expect(spiderpoolip.IsIPv4IPRange("abcd:1234::/120")).To(BeFalse())
+
 			Expect(spiderpoolip.IsIPv4IPRange("172.18.40.10")).To(BeTrue())
 			Expect(spiderpoolip.IsIPv4IPRange("172.18.40.1-172.18.40.2")).To(BeTrue())
+			Expect(spiderpoolip.IsIPv4IPRange("172.18.40.0/28")).To(BeTrue())
+			Expect(spiderpoolip.IsIPv4IPRange("172.18.40.0/24@10-250")).To(BeTrue())
+			Expect(spiderpoolip.IsIPv4IPRange("172.18.40.10-172.18.40.250:2")).To(BeTrue())
 		})
 	})
 
@@ -377,8 +470,13 @@ var _ = Describe("IP range", Label("ip_range_test"), func() {
 			Expect(spiderpoolip.IsIPv6IPRange("abcd:1234::2-abcd:1234::1")).To(BeFalse())
 			Expect(spiderpoolip.IsIPv6IPRange("abcd:1234::1-abcd:1234::2-abcd:1234::3")).To(BeFalse())
 
+			Expect(spiderpoolip.IsIPv6IPRange("abcd:1234::1-abcd:1234::ff]:2")).To(BeFalse())
+			Expect(spiderpoolip.IsIPv6IPRange("172.18.40.0/24")).To(BeFalse())
+
 			Expect(spiderpoolip.IsIPv6IPRange("abcd:1234::a")).To(BeTrue())
 			Expect(spiderpoolip.IsIPv6IPRange("abcd:1234::1-abcd:1234::2")).To(BeTrue())
+			Expect(spiderpoolip.IsIPv6IPRange("abcd:1234::/120@16")).To(BeTrue())
+			Expect(spiderpoolip.IsIPv6IPRange("abcd:1234::1-[abcd:1234::ff]:2")).To(BeTrue())
 		})
 	})
 })
@@ -24,12 +24,142 @@ type IPAMConfig struct {
 	EnableSpiderSubnet bool
 	EnableStatefulSet  bool
 
+	// EnableAnnotatedPoolFallback allows to allocate IP addresses from the
+	// default IPPools when the IPPools specified by Pod annotations do not
+	// exist, instead of failing the allocation.
+	EnableAnnotatedPoolFallback bool
+
+	// IPPoolCandidateOrder is the order to try the IPPool candidates which
+	// remain after filtering, it is one of CandidateOrderDeclared and
+	// CandidateOrderLeastUtilized.
+	IPPoolCandidateOrder string
+
+	// RejectHostNetworkPod fails the allocations for the Pods using host
+	// network, instead of returning empty results.
+	RejectHostNetworkPod bool
+
 	OperationRetries     int
 	OperationGapDuration time.Duration
 	LimiterConfig        limiter.LimiterConfig
+
+	// WaitSubnetPoolTimeout is how long the IP allocation waits for the
+	// auto-created IPPools to be ready by default, a non-positive value
+	// means OperationRetries times OperationGapDuration.
+	WaitSubnetPoolTimeout time.Duration
+
+	// ReleaseJournalPath is the file path of the node-local release journal,
+	// an empty value disables the journal.
+	ReleaseJournalPath           string
+	ReleaseJournalReplayDuration time.Duration
+	// ReleaseJournalMaxBackoff caps the backoff of replaying the release
+	// intents which failed again.
+	ReleaseJournalMaxBackoff time.Duration
+	// ReleaseJournalMaxSize caps the number of the release intents in the
+	// journal, a non-positive value leaves it unbounded.
+	ReleaseJournalMaxSize int
+
+	// QuarantineFailureThreshold is the number of datapath-level failures of
+	// an IPPool on the node within QuarantineFailureWindow, which reports
+	// them to the IPPool to quarantine it, a non-positive value disables the
+	// quarantine.
+	QuarantineFailureThreshold int
+	QuarantineFailureWindow    time.Duration
+
+	// MaxIPsPerWorkload is the maximum number of IP addresses which a
+	// single workload (the top controller of Pods) may hold simultaneously
+	// across all IPPools, a non-positive value means unlimited.
+	MaxIPsPerWorkload int
+
+	// NodeName is the name of the node where the agent runs.
+	NodeName string
+	// SandboxStateDir is the directory where the container runtime keeps
+	// the state of each Pod sandbox in a sub-directory named after its ID,
+	// it's used to release the IP allocations of the sandboxes vanished
+	// while the agent was down. An empty value disables the reconciliation.
+	SandboxStateDir string
+	// CRISocketPath is the unix socket of the CRI RuntimeService of the
+	// container runtime. If it's set, the IP allocations attributed to the
+	// node are released by the agent only once their Pod sandboxes are gone
+	// or stopped. An empty value disables the check.
+	CRISocketPath string
+	CRITimeout    time.Duration
+
+	// IPLeaseRenewDuration is the interval to renew the leases of the IP
+	// allocations of the local Pods, a non-positive value disables the
+	// renewal.
+	IPLeaseRenewDuration time.Duration
+
+	// ReleaseDeferralDuration is how long the release of the IP allocation
+	// of a Pod protected by PodDisruptionBudget is deferred, during which
+	// it's preferentially handed over to the replacement Pod of the same
+	// controller on the node. A non-positive value disables the deferral.
+	ReleaseDeferralDuration time.Duration
+
+	// GatewayProbeTimeout is the timeout to probe the reachability of the
+	// gateways of the IPPools with standby gateways with ARP or NDP, all
+	// IPPools at once. A non-positive value disables the probe and
+	// 'spec.gateway' is always used.
+	GatewayProbeTimeout time.Duration
+	// ReportGatewayUnreachable probes 'spec.gateway' of all IPPools which
+	// allocate IP addresses on the node, not only the ones with standby
+	// gateways, and reports whether it's reachable from the node to the
+	// IPPool. It requires GatewayProbeTimeout.
+	ReportGatewayUnreachable bool
+	// SkipGatewayUnreachableIPPools stops selecting the IPPools with the
+	// condition GatewayUnreachable.
+	SkipGatewayUnreachableIPPools bool
+
+	// IPConflictProbeTimeout is the timeout to probe the newly allocated IP
+	// addresses on the network, the ones which reply fail the allocation
+	// as conflicts. A non-positive value disables the conflict detection.
+	IPConflictProbeTimeout time.Duration
 }
 
+const (
+	// CandidateOrderDeclared tries the IPPool candidates in the order they
+	// are declared, such as in Pod annotations.
+	CandidateOrderDeclared = "declared"
+	// CandidateOrderLeastUtilized tries the IPPool candidates with the
+	// highest ratio of free IP addresses first, to smooth the utilization
+	// across equivalent IPPools.
+	CandidateOrderLeastUtilized = "leastUtilized"
+)
+
+const (
+	defaultReleaseJournalReplayDuration = 10 * time.Second
+	defaultReleaseJournalMaxBackoff     = 5 * time.Minute
+	defaultQuarantineFailureWindow      = 60 * time.Second
+	defaultCRITimeout                   = 2 * time.Second
+)
+
 func setDefaultsForIPAMConfig(config IPAMConfig) IPAMConfig {
+	if config.IPPoolCandidateOrder == "" {
+		config.IPPoolCandidateOrder = CandidateOrderDeclared
+	}
+
+	if config.ReleaseJournalReplayDuration <= 0 {
+		config.ReleaseJournalReplayDuration = defaultReleaseJournalReplayDuration
+	}
+
+	if config.ReleaseJournalMaxBackoff < config.ReleaseJournalReplayDuration {
+		config.ReleaseJournalMaxBackoff = defaultReleaseJournalMaxBackoff
+		if config.ReleaseJournalMaxBackoff < config.ReleaseJournalReplayDuration {
+			config.ReleaseJournalMaxBackoff = config.ReleaseJournalReplayDuration
+		}
+	}
+
+	if config.QuarantineFailureWindow <= 0 {
+		config.QuarantineFailureWindow = defaultQuarantineFailureWindow
+	}
+
+	if config.CRITimeout <= 0 {
+		config.CRITimeout = defaultCRITimeout
+	}
+
+	if config.WaitSubnetPoolTimeout <= 0 {
+		config.WaitSubnetPoolTimeout = time.Duration(config.OperationRetries) * config.OperationGapDuration
+	}
+
 	return config
 }
 
@@ -0,0 +1,202 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+const (
+	// podSandboxStatusPath is the gRPC path of the PodSandboxStatus method
+	// of the CRI RuntimeService.
+	podSandboxStatusPath = "/runtime.v1.RuntimeService/PodSandboxStatus"
+
+	grpcStatusOK       = "0"
+	grpcStatusNotFound = "5"
+
+	// sandboxStateReady is the value of SANDBOX_READY of PodSandboxState.
+	sandboxStateReady = 0
+)
+
+// sandboxChecker checks the Pod sandboxes on the node.
+type sandboxChecker interface {
+	IsSandboxReady(ctx context.Context, sandboxID string) (bool, error)
+}
+
+// criSandboxChecker checks the Pod sandboxes via the CRI RuntimeService of
+// the container runtime, with a minimal gRPC client over its unix socket.
+type criSandboxChecker struct {
+	timeout time.Duration
+	client  *http.Client
+}
+
+func newCRISandboxChecker(socketPath string, timeout time.Duration) (*criSandboxChecker, error) {
+	socketPath = strings.TrimPrefix(socketPath, "unix://")
+	if !strings.HasPrefix(socketPath, "/") {
+		return nil, fmt.Errorf("CRI socket path '%s' must be absolute", socketPath)
+	}
+
+	transport := &http2.Transport{
+		// gRPC over the unix socket is in plaintext.
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, _, _ string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}
+
+	return &criSandboxChecker{
+		timeout: timeout,
+		client:  &http.Client{Transport: transport},
+	}, nil
+}
+
+// IsSandboxReady reports whether the Pod sandbox still exists and is ready.
+// The sandboxes which are not ready have been stopped with their network
+// torn down.
+func (c *criSandboxChecker) IsSandboxReady(ctx context.Context, sandboxID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	// PodSandboxStatusRequest{pod_sandbox_id: sandboxID}
+	msg := protowire.AppendTag(nil, 1, protowire.BytesType)
+	msg = protowire.AppendString(msg, sandboxID)
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	frame = append(frame, msg...)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost"+podSandboxStatusPath, bytes.NewReader(frame))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to request CRI: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected HTTP status %d from CRI", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed to read CRI response: %w", err)
+	}
+
+	// The status is sent in the headers if there is no response message.
+	status, message := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	switch status {
+	case grpcStatusOK:
+	case grpcStatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("CRI responded gRPC status '%s': %s", status, message)
+	}
+
+	if len(data) < 5 || data[0] != 0 {
+		return false, fmt.Errorf("invalid CRI response message")
+	}
+	n := binary.BigEndian.Uint32(data[1:5])
+	if uint32(len(data)-5) < n {
+		return false, fmt.Errorf("truncated CRI response message")
+	}
+
+	state, err := parsePodSandboxState(data[5 : 5+n])
+	if err != nil {
+		return false, err
+	}
+
+	return state == sandboxStateReady, nil
+}
+
+// parsePodSandboxState parses 'status.state' of PodSandboxStatusResponse.
+func parsePodSandboxState(b []byte) (uint64, error) {
+	status, err := consumeField(b, 1, protowire.BytesType)
+	if err != nil || status == nil {
+		return 0, err
+	}
+	state, err := consumeField(status, 3, protowire.VarintType)
+	if err != nil || state == nil {
+		// SANDBOX_READY is omitted as the default value.
+		return sandboxStateReady, err
+	}
+
+	v, n := protowire.ConsumeVarint(state)
+	if n < 0 {
+		return 0, fmt.Errorf("invalid sandbox state: %w", protowire.ParseError(n))
+	}
+
+	return v, nil
+}
+
+// consumeField returns the last value of the field, nil if the field is
+// absent.
+func consumeField(b []byte, num protowire.Number, typ protowire.Type) ([]byte, error) {
+	var value []byte
+	for len(b) > 0 {
+		fieldNum, fieldType, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, fmt.Errorf("invalid CRI response message: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		m := protowire.ConsumeFieldValue(fieldNum, fieldType, b)
+		if m < 0 {
+			return nil, fmt.Errorf("invalid CRI response message: %w", protowire.ParseError(m))
+		}
+		if fieldNum == num && fieldType == typ {
+			value = b[:m]
+			if typ == protowire.BytesType {
+				v, _ := protowire.ConsumeBytes(value)
+				value = v
+			}
+		}
+		b = b[m:]
+	}
+
+	return value, nil
+}
+
+// sandboxGone reports whether the Pod sandbox no longer runs on the node, so
+// that the IP allocation attributed to it is safe to be released. The Pod
+// may be alive but unknown to the API server during network partitions. It
+// is always true if the CRI check is disabled.
+func (i *ipam) sandboxGone(ctx context.Context, containerID string) bool {
+	if i.sandboxChecker == nil {
+		return true
+	}
+
+	logger := logutils.FromContext(ctx)
+	ready, err := i.sandboxChecker.IsSandboxReady(ctx, containerID)
+	if err != nil {
+		logger.Sugar().Warnf("Failed to check whether the Pod sandbox is gone via CRI, skip the release: %v", err)
+		return false
+	}
+	if ready {
+		logger.Warn("Pod sandbox is still running on the node, skip the release")
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,183 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// podSandboxStatus encodes PodSandboxStatusResponse with the state, the
+// state is omitted if it's negative.
+func podSandboxStatus(id string, state int) []byte {
+	var status []byte
+	status = protowire.AppendTag(status, 1, protowire.BytesType)
+	status = protowire.AppendString(status, id)
+	if state >= 0 {
+		status = protowire.AppendTag(status, 3, protowire.VarintType)
+		status = protowire.AppendVarint(status, uint64(state))
+	}
+
+	var resp []byte
+	resp = protowire.AppendTag(resp, 1, protowire.BytesType)
+	return protowire.AppendBytes(resp, status)
+}
+
+// serveFakeCRI serves the PodSandboxStatus of CRI over h2c on the unix
+// socket, the sandboxes not in states are not found.
+func serveFakeCRI(socketPath string, states map[string]int) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer GinkgoRecover()
+		Expect(r.URL.Path).To(Equal(podSandboxStatusPath))
+
+		body, err := io.ReadAll(r.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(len(body)).To(BeNumerically(">", 5))
+		id, err := consumeField(body[5:], 1, protowire.BytesType)
+		Expect(err).NotTo(HaveOccurred())
+
+		w.Header().Set("Content-Type", "application/grpc")
+		state, ok := states[string(id)]
+		if !ok {
+			// Trailers-only response.
+			w.Header().Set("Grpc-Status", grpcStatusNotFound)
+			w.Header().Set("Grpc-Message", "not found")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		msg := podSandboxStatus(string(id), state)
+		frame := make([]byte, 5, 5+len(msg))
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(append(frame, msg...))
+		w.Header().Set("Grpc-Status", grpcStatusOK)
+	})
+
+	l, err := net.Listen("unix", socketPath)
+	Expect(err).NotTo(HaveOccurred())
+	DeferCleanup(l.Close)
+
+	server := &http2.Server{}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go server.ServeConn(conn, &http2.ServeConnOpts{Handler: handler})
+		}
+	}()
+}
+
+var _ = Describe("CRI sandbox checker", Label("cri_test"), func() {
+	DescribeTable("parsePodSandboxState",
+		func(msg []byte, expectedState uint64, expectErr bool) {
+			state, err := parsePodSandboxState(msg)
+			if expectErr {
+				Expect(err).To(HaveOccurred())
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state).To(Equal(expectedState))
+		},
+		Entry("ready sandbox", podSandboxStatus("s1", 0), uint64(sandboxStateReady), false),
+		Entry("not ready sandbox", podSandboxStatus("s1", 1), uint64(1), false),
+		Entry("omitted default state", podSandboxStatus("s1", -1), uint64(sandboxStateReady), false),
+		Entry("empty response", []byte{}, uint64(0), false),
+		Entry("unknown fields",
+			append(
+				protowire.AppendVarint(protowire.AppendTag(nil, 9, protowire.VarintType), 42),
+				append(podSandboxStatus("s1", 1), protowire.AppendString(protowire.AppendTag(nil, 2, protowire.BytesType), "info")...)...,
+			),
+			uint64(1), false),
+		Entry("truncated message", podSandboxStatus("s1", 1)[:4], uint64(0), true),
+		Entry("truncated tag", []byte{0x80}, uint64(0), true),
+	)
+
+	DescribeTable("consumeField",
+		func(msg []byte, num protowire.Number, typ protowire.Type, expected []byte, expectErr bool) {
+			value, err := consumeField(msg, num, typ)
+			if expectErr {
+				Expect(err).To(HaveOccurred())
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(value).To(Equal(expected))
+		},
+		Entry("bytes field", protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), "id"),
+			protowire.Number(1), protowire.BytesType, []byte("id"), false),
+		Entry("last value wins",
+			protowire.AppendString(protowire.AppendTag(protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), "a"), 1, protowire.BytesType), "b"),
+			protowire.Number(1), protowire.BytesType, []byte("b"), false),
+		Entry("absent field", protowire.AppendString(protowire.AppendTag(nil, 2, protowire.BytesType), "id"),
+			protowire.Number(1), protowire.BytesType, []byte(nil), false),
+		Entry("field of another type", protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 1),
+			protowire.Number(1), protowire.BytesType, []byte(nil), false),
+		Entry("truncated value", protowire.AppendTag(nil, 1, protowire.BytesType),
+			protowire.Number(1), protowire.BytesType, []byte(nil), true),
+	)
+
+	It("requires the absolute socket path", func() {
+		_, err := newCRISandboxChecker("run/containerd/containerd.sock", time.Second)
+		Expect(err).To(HaveOccurred())
+		_, err = newCRISandboxChecker("unix:///run/containerd/containerd.sock", time.Second)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("with the container runtime", func() {
+		var checker *criSandboxChecker
+		BeforeEach(func() {
+			dir, err := os.MkdirTemp("", "cri")
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(os.RemoveAll, dir)
+
+			socketPath := filepath.Join(dir, "cri.sock")
+			serveFakeCRI(socketPath, map[string]int{"ready": 0, "notready": 1})
+			checker, err = newCRISandboxChecker("unix://"+socketPath, time.Second)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("reports the ready sandbox", func() {
+			ready, err := checker.IsSandboxReady(context.TODO(), "ready")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ready).To(BeTrue())
+		})
+
+		It("reports the stopped sandbox", func() {
+			ready, err := checker.IsSandboxReady(context.TODO(), "notready")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ready).To(BeFalse())
+		})
+
+		It("reports the sandbox not found", func() {
+			ready, err := checker.IsSandboxReady(context.TODO(), "gone")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ready).To(BeFalse())
+		})
+	})
+
+	It("fails if the container runtime is unreachable", func() {
+		checker, err := newCRISandboxChecker(filepath.Join(os.TempDir(), "nonexistent-cri.sock"), time.Second)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = checker.IsSandboxReady(context.TODO(), "s1")
+		Expect(err).To(HaveOccurred())
+
+		i := &ipam{sandboxChecker: checker}
+		Expect(i.sandboxGone(context.TODO(), "s1")).To(BeFalse())
+	})
+})
@@ -0,0 +1,107 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	corev1 "k8s.io/api/core/v1"
+
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+// selectIPPoolGateways probes 'spec.gateway' and 'spec.standbyGateways' of
+// the IPPools with ARP or NDP out of the interface of the node attached to
+// their subnets, the IPPools in parallel within GatewayProbeTimeout, and
+// returns the first reachable gateway of each IPPool in order. If none of
+// them replies, 'spec.gateway' is returned as usual, and a datapath failure
+// of the IPPool is recorded. The gateways of the IPPools whose subnets are
+// not attached to the node can't be probed, 'spec.gateway' is returned
+// without any report.
+func (i *ipam) selectIPPoolGateways(ctx context.Context, pools []*spiderpoolv1.SpiderIPPool, pod *corev1.Pod) map[string]string {
+	logger := logutils.FromContext(ctx)
+
+	probes := make([]*neighborProbe, 0, len(pools))
+	for _, pool := range pools {
+		p := &neighborProbe{subnet: pool.Spec.Subnet}
+		if pool.Spec.IPVersion != nil {
+			p.version = *pool.Spec.IPVersion
+		}
+		for _, gw := range append([]string{*pool.Spec.Gateway}, pool.Spec.StandbyGateways...) {
+			p.ips = append(p.ips, net.ParseIP(gw))
+		}
+		probes = append(probes, p)
+	}
+
+	i.probeNeighbors(ctx, probes, i.config.GatewayProbeTimeout)
+
+	gateways := make(map[string]string, len(pools))
+	for k, pool := range pools {
+		gateway := *pool.Spec.Gateway
+		gateways[pool.Name] = gateway
+
+		p := probes[k]
+		if p.err != nil {
+			logger.Sugar().Warnf("Failed to probe the gateways of IPPool %s: %v", pool.Name, p.err)
+			continue
+		}
+		if !p.attached {
+			logger.Sugar().Debugf("Subnet %s of IPPool %s is not attached to the node, use gateway %s", pool.Spec.Subnet, pool.Name, gateway)
+			continue
+		}
+
+		gw := firstReplied(p.ips, p.replied)
+		i.reportGatewayReachability(ctx, pool, gw != nil && gw.Equal(p.ips[0]))
+		if gw == nil {
+			logger.Sugar().Warnf("None of the gateways of IPPool %s is reachable, use gateway %s", pool.Name, gateway)
+			i.recordDatapathFailure(ctx, pool.Name, pod, fmt.Sprintf("gateway %s is unreachable", gateway))
+			continue
+		}
+		if !gw.Equal(p.ips[0]) {
+			logger.Sugar().Warnf("Gateway %s of IPPool %s is unreachable, use the standby gateway %s", gateway, pool.Name, gw)
+			gateways[pool.Name] = gw.String()
+		}
+	}
+
+	return gateways
+}
+
+// firstReplied returns the first IP address of ips in order which replied.
+func firstReplied(ips, replied []net.IP) net.IP {
+	for _, ip := range ips {
+		for _, r := range replied {
+			if ip.Equal(r) {
+				return ip
+			}
+		}
+	}
+
+	return nil
+}
+
+// reportGatewayReachability reports whether 'spec.gateway' of the IPPool is
+// reachable from the node, which is aggregated by spiderpool-controller
+// into the condition GatewayUnreachable of the IPPool.
+func (i *ipam) reportGatewayReachability(ctx context.Context, pool *spiderpoolv1.SpiderIPPool, reachable bool) {
+	if !i.config.ReportGatewayUnreachable || i.config.NodeName == "" {
+		return
+	}
+
+	if err := i.ipPoolManager.ReportGatewayReachability(ctx, pool.Name, i.config.NodeName, reachable); err != nil {
+		logutils.FromContext(ctx).Sugar().Warnf("Failed to report the reachability of gateway %s of IPPool %s: %v", *pool.Spec.Gateway, pool.Name, err)
+	}
+}
+
+// needGatewayProbe reports whether the gateway of the IP configuration
+// allocated from the IPPool is to be probed.
+func (i *ipam) needGatewayProbe(pool *spiderpoolv1.SpiderIPPool, gateway string) bool {
+	if i.config.GatewayProbeTimeout <= 0 || pool.Spec.Gateway == nil || gateway != *pool.Spec.Gateway {
+		return false
+	}
+
+	return len(pool.Spec.StandbyGateways) != 0 || i.config.ReportGatewayUnreachable || i.failureTracker != nil
+}
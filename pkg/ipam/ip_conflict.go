@@ -0,0 +1,72 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// detectIPConflicts probes the newly allocated IP addresses with ARP or NDP
+// from the node before they are set up in the Pod, the ones which reply are
+// in use by the hosts outside Kubernetes. Such a conflict fails the
+// allocation, whose IP addresses are rolled back, and is recorded as a
+// datapath failure of the IPPool. The IP addresses of the subnets not
+// attached to the node are not probed.
+func (i *ipam) detectIPConflicts(ctx context.Context, results []*types.AllocationResult, pod *corev1.Pod) error {
+	if i.config.IPConflictProbeTimeout <= 0 {
+		return nil
+	}
+
+	logger := logutils.FromContext(ctx)
+
+	var probes []*neighborProbe
+	subnets := map[string]*neighborProbe{}
+	pools := map[string]string{}
+	for _, r := range results {
+		ip, ipNet, err := net.ParseCIDR(*r.IP.Address)
+		if err != nil {
+			return fmt.Errorf("failed to parse the allocated IP address %s: %w", *r.IP.Address, err)
+		}
+
+		p, ok := subnets[ipNet.String()]
+		if !ok {
+			p = &neighborProbe{subnet: ipNet.String(), version: *r.IP.Version}
+			subnets[ipNet.String()] = p
+			probes = append(probes, p)
+		}
+		p.ips = append(p.ips, ip)
+		pools[ip.String()] = r.IP.IPPool
+	}
+
+	i.probeNeighbors(ctx, probes, i.config.IPConflictProbeTimeout)
+
+	var errs []error
+	for _, p := range probes {
+		if p.err != nil {
+			logger.Sugar().Warnf("Failed to detect the conflicts of IP addresses %v: %v", p.ips, p.err)
+			continue
+		}
+		if !p.attached {
+			logger.Sugar().Debugf("Subnet %s is not attached to the node, skip the conflict detection of IP addresses %v", p.subnet, p.ips)
+			continue
+		}
+
+		for _, ip := range p.replied {
+			pool := pools[ip.String()]
+			i.recordDatapathFailure(ctx, pool, pod, fmt.Sprintf("IP %s conflicts with a host on the network", ip))
+			errs = append(errs, fmt.Errorf("%w, IP %s of IPPool %s", constant.ErrIPInUse, ip, pool))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
@@ -5,34 +5,42 @@ package ipam
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/spidernet-io/spiderpool/api/v1/agent/models"
 	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/event"
 	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
 	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
 	"github.com/spidernet-io/spiderpool/pkg/limiter"
 	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/metric"
 	"github.com/spidernet-io/spiderpool/pkg/namespacemanager"
+	"github.com/spidernet-io/spiderpool/pkg/networkscanner"
 	"github.com/spidernet-io/spiderpool/pkg/nodemanager"
 	"github.com/spidernet-io/spiderpool/pkg/podmanager"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
 	"github.com/spidernet-io/spiderpool/pkg/singletons"
 	"github.com/spidernet-io/spiderpool/pkg/statefulsetmanager"
 	"github.com/spidernet-io/spiderpool/pkg/subnetmanager"
 	subnetmanagercontrollers "github.com/spidernet-io/spiderpool/pkg/subnetmanager/controllers"
 	"github.com/spidernet-io/spiderpool/pkg/types"
+	"github.com/spidernet-io/spiderpool/pkg/utils/convert"
 	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
 )
 
@@ -45,6 +53,9 @@ type IPAM interface {
 type ipam struct {
 	config      IPAMConfig
 	ipamLimiter limiter.Limiter
+	// tokenIssuer issues the tokens of the cluster-wide budget of concurrent
+	// IP allocations, nil means no budget.
+	tokenIssuer limiter.TokenIssuer
 
 	ipPoolManager   ippoolmanager.IPPoolManager
 	endpointManager workloadendpointmanager.WorkloadEndpointManager
@@ -53,8 +64,22 @@ type ipam struct {
 	podManager      podmanager.PodManager
 	stsManager      statefulsetmanager.StatefulSetManager
 	subnetManager   subnetmanager.SubnetManager
-
-	rollbacks sync.Map
+	// ipPoolWatcher watches the auto-created IPPools to be ready, nil means
+	// polling them.
+	ipPoolWatcher client.WithWatch
+
+	rollbacks      sync.Map
+	journal        *releaseJournal
+	failureTracker *failureTracker
+	deferrer       *releaseDeferrer
+	sandboxChecker sandboxChecker
+	neighborProber neighborProber
+	// poolFilters eliminate the IPPool candidates which can't allocate IP
+	// addresses to the Pod.
+	poolFilters poolFilterChain
+	// preview selects the IPPool candidates without recording any Event or
+	// metric, see PreviewPodIPAM.
+	preview bool
 }
 
 func NewIPAM(
@@ -66,6 +91,9 @@ func NewIPAM(
 	podManager podmanager.PodManager,
 	stsManager statefulsetmanager.StatefulSetManager,
 	subnetManager subnetmanager.SubnetManager,
+	ipPoolWatcher client.WithWatch,
+	tokenIssuer limiter.TokenIssuer,
+	poolFilters ...PoolFilter,
 ) (IPAM, error) {
 	if ipPoolManager == nil {
 		return nil, fmt.Errorf("ippool manager %w", constant.ErrMissingRequiredParam)
@@ -89,9 +117,43 @@ func NewIPAM(
 		return nil, fmt.Errorf("subnet manager %w", constant.ErrMissingRequiredParam)
 	}
 
-	return &ipam{
-		config:          setDefaultsForIPAMConfig(config),
+	var journal *releaseJournal
+	if config.ReleaseJournalPath != "" {
+		j, err := newReleaseJournal(config.ReleaseJournalPath, config.ReleaseJournalMaxSize)
+		if err != nil {
+			return nil, err
+		}
+		journal = j
+	}
+
+	config = setDefaultsForIPAMConfig(config)
+	if config.IPPoolCandidateOrder != CandidateOrderDeclared && config.IPPoolCandidateOrder != CandidateOrderLeastUtilized {
+		return nil, fmt.Errorf("%w, unknown IPPool candidate order '%s'", constant.ErrWrongInput, config.IPPoolCandidateOrder)
+	}
+
+	var failureTracker *failureTracker
+	if config.QuarantineFailureThreshold > 0 {
+		failureTracker = newFailureTracker(config.QuarantineFailureThreshold, config.QuarantineFailureWindow)
+	}
+
+	var deferrer *releaseDeferrer
+	if config.ReleaseDeferralDuration > 0 {
+		deferrer = newReleaseDeferrer(config.ReleaseDeferralDuration)
+	}
+
+	var sandboxChecker sandboxChecker
+	if config.CRISocketPath != "" {
+		c, err := newCRISandboxChecker(config.CRISocketPath, config.CRITimeout)
+		if err != nil {
+			return nil, err
+		}
+		sandboxChecker = c
+	}
+
+	i := &ipam{
+		config:          config,
 		ipamLimiter:     limiter.NewLimiter(config.LimiterConfig),
+		tokenIssuer:     tokenIssuer,
 		ipPoolManager:   ipPoolManager,
 		endpointManager: endpointManager,
 		nodeManager:     nodeManager,
@@ -99,8 +161,18 @@ func NewIPAM(
 		podManager:      podManager,
 		stsManager:      stsManager,
 		subnetManager:   subnetManager,
+		ipPoolWatcher:   ipPoolWatcher,
 		rollbacks:       sync.Map{},
-	}, nil
+		journal:         journal,
+		failureTracker:  failureTracker,
+		deferrer:        deferrer,
+		sandboxChecker:  sandboxChecker,
+		neighborProber:  networkscanner.ProbeNeighbors,
+	}
+	// The additional filters are run after the built-in ones.
+	i.poolFilters = append(i.builtinPoolFilters(), poolFilters...)
+
+	return i, nil
 }
 
 func (i *ipam) Allocate(ctx context.Context, addArgs *models.IpamAddArgs) (*models.IpamAddResponse, error) {
@@ -111,6 +183,9 @@ func (i *ipam) Allocate(ctx context.Context, addArgs *models.IpamAddArgs) (*mode
 	if err != nil {
 		return nil, fmt.Errorf("failed to get Pod %s/%s: %v", *addArgs.PodNamespace, *addArgs.PodName, err)
 	}
+	if pod.Spec.HostNetwork {
+		return i.allocateForHostNetworkPod(ctx, pod)
+	}
 	podStatus, allocatable := podmanager.CheckPodStatus(pod)
 	if !allocatable {
 		return nil, fmt.Errorf("%s Pod %s/%s cannot allocate IP addresees", strings.ToLower(string(podStatus)), pod.Namespace, pod.Name)
@@ -123,7 +198,7 @@ func (i *ipam) Allocate(ctx context.Context, addArgs *models.IpamAddArgs) (*mode
 	}
 	logger.Sugar().Debugf("%s %s/%s is the top controller of the Pod", podTopController.Kind, podTopController.Namespace, podTopController.Name)
 
-	endpoint, err := i.endpointManager.GetEndpointByName(ctx, pod.Namespace, pod.Name)
+	endpoint, err := i.endpointManager.GetCachedEndpointByName(ctx, pod.Namespace, pod.Name)
 	if client.IgnoreNotFound(err) != nil {
 		return nil, fmt.Errorf("failed to get Endpoint %s/%s: %v", pod.Namespace, pod.Name, err)
 	}
@@ -141,6 +216,7 @@ func (i *ipam) Allocate(ctx context.Context, addArgs *models.IpamAddArgs) (*mode
 			return nil, fmt.Errorf("failed to retrieve the IP allocation of StatefulSet %s/%s: %w", podTopController.Namespace, podTopController.Name, err)
 		}
 		if addResp != nil {
+			i.applyIPPoolNetworkConfig(ctx, *addArgs.IfName, pod, addResp)
 			return addResp, nil
 		}
 	} else {
@@ -150,6 +226,7 @@ func (i *ipam) Allocate(ctx context.Context, addArgs *models.IpamAddArgs) (*mode
 			return nil, fmt.Errorf("failed to retrieve the IP allocation in multi-NIC mode: %w", err)
 		}
 		if addResp != nil {
+			i.applyIPPoolNetworkConfig(ctx, *addArgs.IfName, pod, addResp)
 			return addResp, nil
 		}
 	}
@@ -159,10 +236,28 @@ func (i *ipam) Allocate(ctx context.Context, addArgs *models.IpamAddArgs) (*mode
 	if err != nil {
 		return nil, fmt.Errorf("failed to allocate IP addresses in standard mode: %w", err)
 	}
+	i.applyIPPoolNetworkConfig(ctx, *addArgs.IfName, pod, addResp)
 
 	return addResp, nil
 }
 
+// allocateForHostNetworkPod short-circuits the allocation for the Pod using
+// host network, which shares the IP addresses of the node. An empty response
+// is returned unless RejectHostNetworkPod is set.
+func (i *ipam) allocateForHostNetworkPod(ctx context.Context, pod *corev1.Pod) (*models.IpamAddResponse, error) {
+	logger := logutils.FromContext(ctx)
+
+	if i.config.RejectHostNetworkPod {
+		return nil, fmt.Errorf("%w, Pod %s/%s uses host network, no IP address should be allocated to it", constant.ErrWrongInput, pod.Namespace, pod.Name)
+	}
+
+	logger.Info("Pod uses host network, skip the allocation")
+	return &models.IpamAddResponse{
+		Ips:    []*models.IPConfig{},
+		Routes: []*models.Route{},
+	}, nil
+}
+
 func (i *ipam) retrieveStsIPAllocation(ctx context.Context, containerID string, pod *corev1.Pod, endpoint *spiderpoolv1.SpiderEndpoint) (*models.IpamAddResponse, error) {
 	logger := logutils.FromContext(ctx)
 
@@ -186,23 +281,48 @@ func (i *ipam) retrieveStsIPAllocation(ctx context.Context, containerID string,
 	// Check again in case cmdDel() cannot be complete under some special
 	// circumstances. Or the IP version config of spiderpool is modified.
 	for _, d := range endpoint.Status.Current.IPs {
+		// The IP addresses of multi-pool striping may be single stack.
+		if d.Stripe != nil && *d.Stripe != 0 {
+			continue
+		}
 		if i.config.EnableIPv4 && d.IPv4 == nil ||
 			i.config.EnableIPv6 && d.IPv6 == nil {
 			return nil, fmt.Errorf("the Pod of StatefulSet has legacy failure allocation %+v", d)
 		}
 	}
 
-	// Concurrently refresh the IP records of the IPPools.
-	if err := i.reallocateIPPoolIPRecords(ctx, containerID, pod.Spec.NodeName, endpoint); err != nil {
+	// The IPPools may be re-created with the same names, whose IP addresses
+	// are not the recorded ones any more.
+	recreated, err := i.getRecreatedPools(ctx, endpoint.Status.Current.IPs)
+	if err != nil {
 		return nil, err
 	}
+	if len(recreated) != 0 {
+		logger.Sugar().Warnf("IPPools %v of the IP allocation have been re-created, try to re-allocate", recreated)
+		return nil, nil
+	}
+
+	// Concurrently refresh the IP records of the IPPools.
+	if err := i.reallocateIPPoolIPRecords(ctx, containerID, pod, endpoint); err != nil {
+		if !errors.Is(err, constant.ErrIPVacating) {
+			return nil, err
+		}
+
+		// The IP addresses reserved with 'spec.vacateAllocatedIPs' are not
+		// reused, the whole IP allocation is released to re-allocate.
+		logger.Sugar().Warnf("The IP allocation of StatefulSet is being vacated, try to re-allocate: %v", err)
+		if err := i.releaseVacatedStsIPAllocation(ctx, containerID, endpoint); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
 
 	// Refresh the current IP allocation of the Endpoint.
 	if err := i.endpointManager.ReallocateCurrentIPAllocation(ctx, containerID, pod.Spec.NodeName, endpoint); err != nil {
 		return nil, fmt.Errorf("failed to update the current IP allocation of StatefulSet: %w", err)
 	}
 
-	ips, routes := convertIPDetailsToIPConfigsAndAllRoutes(endpoint.Status.Current.IPs)
+	ips, routes := convert.IPDetailsToIPConfigsAndAllRoutes(endpoint.Status.Current.IPs)
 	addResp := &models.IpamAddResponse{
 		Ips:    ips,
 		Routes: routes,
@@ -212,10 +332,64 @@ func (i *ipam) retrieveStsIPAllocation(ctx context.Context, containerID string,
 	return addResp, nil
 }
 
-func (i *ipam) reallocateIPPoolIPRecords(ctx context.Context, containerID, nodeName string, endpoint *spiderpoolv1.SpiderEndpoint) error {
+// releaseVacatedStsIPAllocation releases the current IP allocation of the
+// StatefulSet Pod, part of which may have been re-allocated to the new
+// container before the vacating IP addresses are found.
+func (i *ipam) releaseVacatedStsIPAllocation(ctx context.Context, containerID string, endpoint *spiderpoolv1.SpiderEndpoint) error {
+	if err := i.release(ctx, endpoint.Status.Current.ContainerID, endpoint.Status.Current.IPs); err != nil {
+		return err
+	}
+	if containerID == endpoint.Status.Current.ContainerID {
+		return nil
+	}
+
+	return i.release(ctx, containerID, endpoint.Status.Current.IPs)
+}
+
+// getRecreatedPools returns the IPPools of the IP allocation details which
+// are deleted or not the ones told by the recorded UIDs. The details recorded
+// before the UIDs are bound to the IPPools by name.
+func (i *ipam) getRecreatedPools(ctx context.Context, details []spiderpoolv1.IPAllocationDetail) ([]string, error) {
+	var recreated []string
+	check := func(poolName, uid *string) error {
+		if poolName == nil || uid == nil {
+			return nil
+		}
+		pool, err := i.ipPoolManager.GetIPPoolByName(ctx, *poolName)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				recreated = append(recreated, *poolName)
+				return nil
+			}
+			return fmt.Errorf("failed to get IPPool %s: %v", *poolName, err)
+		}
+		if string(pool.UID) != *uid {
+			recreated = append(recreated, *poolName)
+		}
+		return nil
+	}
+
+	for _, d := range details {
+		if err := check(d.IPv4Pool, d.IPv4PoolUID); err != nil {
+			return nil, err
+		}
+		if err := check(d.IPv6Pool, d.IPv6PoolUID); err != nil {
+			return nil, err
+		}
+	}
+
+	return recreated, nil
+}
+
+func (i *ipam) reallocateIPPoolIPRecords(ctx context.Context, containerID string, pod *corev1.Pod, endpoint *spiderpoolv1.SpiderEndpoint) error {
 	logger := logutils.FromContext(ctx)
 
-	pics := GroupIPDetails(containerID, nodeName, endpoint.Status.Current.IPs)
+	pics := GroupIPDetails(containerID, pod.Spec.NodeName, endpoint.Status.Current.IPs)
+	for _, ics := range pics {
+		for j := range ics {
+			ics[j].PodUID = string(pod.UID)
+		}
+	}
 	tickets := pics.Pools()
 	if err := i.ipamLimiter.AcquireTicket(ctx, tickets...); err != nil {
 		return fmt.Errorf("failed to queue correctly: %v", err)
@@ -232,6 +406,15 @@ func (i *ipam) reallocateIPPoolIPRecords(ctx context.Context, containerID, nodeN
 
 			if err := i.ipPoolManager.UpdateAllocatedIPs(ctx, poolName, ipAndCIDs); err != nil {
 				logger.Warn(err.Error())
+				var deniedErr *reservedipmanager.ReservedIPDeniedError
+				if errors.As(err, &deniedErr) {
+					event.EventRecorder.Eventf(
+						pod,
+						corev1.EventTypeWarning,
+						constant.EventReasonReservedIPDenied,
+						"%s, allocate new IP addresses instead", deniedErr.Message(),
+					)
+				}
 				errCh <- err
 				return
 			}
@@ -262,7 +445,7 @@ func (i *ipam) retrieveMultiNICIPAllocation(ctx context.Context, containerID, ni
 		return nil, nil
 	}
 
-	ips, routes := convertIPDetailsToIPConfigsAndAllRoutes(allocation.IPs)
+	ips, routes := convert.IPDetailsToIPConfigsAndAllRoutes(allocation.IPs)
 	addResp := &models.IpamAddResponse{
 		Ips:    ips,
 		Routes: routes,
@@ -281,12 +464,22 @@ func (i *ipam) allocateInStandardMode(ctx context.Context, addArgs *models.IpamA
 		return nil, err
 	}
 
+	logger.Debug("Parse custom MAC addresses")
+	customMACs, err := getCustomMACs(pod)
+	if err != nil {
+		return nil, err
+	}
+
 	logger.Debug("Generate IPPool candidates")
-	toBeAllocatedSet, err := i.genToBeAllocatedSet(ctx, addArgs, pod, podController)
+	toBeAllocatedSet, fallback, err := i.genToBeAllocatedSet(ctx, addArgs, pod, podController)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := i.checkWorkloadIPLimit(ctx, pod, podController, toBeAllocatedSet); err != nil {
+		return nil, err
+	}
+
 	// TODO(iiiceoo): Comment why containerID should be written first.
 	if endpoint == nil {
 		logger.Sugar().Infof("First sandbox of Pod is being created, mark the IP allocation")
@@ -301,57 +494,132 @@ func (i *ipam) allocateInStandardMode(ctx context.Context, addArgs *models.IpamA
 		}
 	}
 
-	results, err := i.allocateForAllNICs(ctx, toBeAllocatedSet, *addArgs.ContainerID, customRoutes, endpoint, pod, podController)
+	if details := i.takeOverDeferredRelease(ctx, toBeAllocatedSet, *addArgs.ContainerID, pod, podController); details != nil {
+		addResp, err := i.allocateFromDeferredRelease(ctx, *addArgs.ContainerID, details, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		logger.Sugar().Infof("Succeed to take over the deferred IP allocation: %+v", *addResp)
+		recordAllocationSources(ctx, toBeAllocatedSet)
+
+		return addResp, nil
+	}
+
+	results, err := i.allocateForAllNICs(ctx, toBeAllocatedSet, *addArgs.ContainerID, customRoutes, customMACs, endpoint, pod, podController)
 	if err != nil {
 		if len(results) != 0 {
 			logger.Sugar().Warnf("Failed to allocate IP addresses for all NICs, record incomplete IP allocation results for rollback: %+v", results)
 			i.addRollback(*addArgs.ContainerID, results)
 		}
+		i.recordCNICall(ctx, *addArgs.ContainerID, constant.CNIOperationAdd, constant.CNIResultFailure, endpoint)
 		return nil, err
 	}
 
-	resIPs, resRoutes := convertResultsToIPConfigsAndAllRoutes(results)
+	resIPs, resRoutes := convert.ResultsToIPConfigsAndAllRoutes(results)
 	addResp := &models.IpamAddResponse{
-		Ips:    resIPs,
-		Routes: resRoutes,
+		Ips:                 resIPs,
+		Routes:              resRoutes,
+		DefaultPoolFallback: fallback,
 	}
 	logger.Sugar().Infof("Succeed to allocate: %+v", *addResp)
+	recordAllocationSources(ctx, toBeAllocatedSet)
 
 	return addResp, nil
 }
 
-func (i *ipam) genToBeAllocatedSet(ctx context.Context, addArgs *models.IpamAddArgs, pod *corev1.Pod, podController types.PodTopController) (ToBeAllocateds, error) {
+// recordAllocationSources counts the successful IP allocation by the sources
+// of its IPPool candidates, which tells how the workloads are configured.
+func recordAllocationSources(ctx context.Context, tt ToBeAllocateds) {
+	for _, source := range tt.Sources() {
+		metric.IpamAllocationSourceCounts.Add(ctx, 1, attribute.String(metric.AttrKeySource, source))
+	}
+}
+
+// checkWorkloadIPLimit rejects the IP allocation if the IP addresses held by
+// the other Pods of the same workload plus the ones about to be allocated
+// exceed the limit, which protects shared IPPools from runaway workloads
+// (such as Jobs) spawning thousands of Pods.
+//
+// The limit is best-effort: the IP addresses are counted from the Endpoints
+// of the workload, read from the Endpoint cache if enabled, so the
+// concurrent allocations of the same workload, on other nodes or before the
+// cache observes them, may exceed it by the number of them.
+func (i *ipam) checkWorkloadIPLimit(ctx context.Context, pod *corev1.Pod, podController types.PodTopController, tt ToBeAllocateds) error {
+	if i.config.MaxIPsPerWorkload <= 0 || podController.Kind == constant.KindPod {
+		return nil
+	}
+
+	var toBeAllocated int
+	for _, t := range tt {
+		toBeAllocated += len(t.PoolCandidates)
+	}
+
+	endpoints, err := i.endpointManager.ListCachedEndpointsByOwnerController(ctx, pod.Namespace, podController.Kind, podController.Name)
+	if err != nil {
+		return fmt.Errorf("failed to list Endpoints to count the IP addresses held by %s %s/%s: %v", podController.Kind, podController.Namespace, podController.Name, err)
+	}
+
+	var held int
+	for _, endpoint := range endpoints {
+		// The IP allocation of the Pod itself is going to be replaced.
+		if endpoint.Name == pod.Name || endpoint.Status.Current == nil {
+			continue
+		}
+		for _, d := range endpoint.Status.Current.IPs {
+			if d.IPv4 != nil {
+				held++
+			}
+			if d.IPv6 != nil {
+				held++
+			}
+		}
+	}
+
+	if held+toBeAllocated > i.config.MaxIPsPerWorkload {
+		return fmt.Errorf("%w, %s %s/%s holds %d IP addresses, allocating %d more exceeds the limit %d",
+			constant.ErrWorkloadIPLimitExceeded, podController.Kind, podController.Namespace, podController.Name, held, toBeAllocated, i.config.MaxIPsPerWorkload)
+	}
+
+	return nil
+}
+
+func (i *ipam) genToBeAllocatedSet(ctx context.Context, addArgs *models.IpamAddArgs, pod *corev1.Pod, podController types.PodTopController) (ToBeAllocateds, bool, error) {
 	logger := logutils.FromContext(ctx)
 
 	logger.Debug("Select original IPPools through pool selection rules")
-	preliminary, err := i.getPoolCandidates(ctx, addArgs, pod, podController)
+	preliminary, fallback, err := i.getPoolCandidates(ctx, addArgs, pod, podController)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	logger.Sugar().Infof("Preliminary IPPool candidates: %s", preliminary)
 
 	logger.Debug("Precheck IPPool candidates")
 	if err := i.precheckPoolCandidates(ctx, preliminary); err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	logger.Sugar().Infof("Prechecked IPPool candidates: %s", preliminary)
 
 	logger.Debug("Filter out IPPool candidates")
 	if err := i.filterPoolCandidates(ctx, preliminary, pod); err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	logger.Sugar().Infof("Filtered IPPool candidates: %s", preliminary)
 
+	if i.config.IPPoolCandidateOrder == CandidateOrderLeastUtilized {
+		orderPoolCandidatesByUtilization(preliminary)
+		logger.Sugar().Infof("Ordered IPPool candidates by utilization: %s", preliminary)
+	}
+
 	logger.Debug("Verify IPPool candidates")
 	if err := i.verifyPoolCandidates(preliminary); err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	logger.Info("All IPPool candidates are valid")
 
-	return preliminary, nil
+	return preliminary, fallback, nil
 }
 
-func (i *ipam) allocateForAllNICs(ctx context.Context, tt ToBeAllocateds, containerID string, customRoutes []*models.Route, endpoint *spiderpoolv1.SpiderEndpoint, pod *corev1.Pod, podController types.PodTopController) ([]*AllocationResult, error) {
+func (i *ipam) allocateForAllNICs(ctx context.Context, tt ToBeAllocateds, containerID string, customRoutes []*models.Route, customMACs types.AnnoPodMACValue, endpoint *spiderpoolv1.SpiderEndpoint, pod *corev1.Pod, podController types.PodTopController) ([]*types.AllocationResult, error) {
 	logger := logutils.FromContext(ctx)
 
 	logger.Sugar().Debugf("Concurrently allocate IP addresses from all IPPool candidates")
@@ -360,15 +628,27 @@ func (i *ipam) allocateForAllNICs(ctx context.Context, tt ToBeAllocateds, contai
 		return results, err
 	}
 
+	logger.Sugar().Debugf("Detect the conflicts of the allocated IP addresses on the network")
+	if err := i.detectIPConflicts(ctx, results, pod); err != nil {
+		return results, err
+	}
+
 	logger.Sugar().Debugf("Group custom routes by IP allocation results")
 	if err := groupCustomRoutes(ctx, customRoutes, results); err != nil {
 		return results, fmt.Errorf("failed to group custom routes %+v: %v", customRoutes, err)
 	}
 
+	logger.Sugar().Debugf("Set custom MAC addresses on IP allocation results")
+	unifyIPPoolMACs(results)
+	if err := groupCustomMACs(customMACs, results); err != nil {
+		return results, fmt.Errorf("failed to set custom MAC addresses %+v: %v", customMACs, err)
+	}
+
 	logger.Sugar().Debugf("Patch IP allocation detail to Endpoint %s/%s", endpoint.Namespace, endpoint.Name)
 	if err = i.endpointManager.PatchIPAllocation(ctx, &spiderpoolv1.PodIPAllocation{
 		ContainerID: containerID,
-		IPs:         convertResultsToIPDetails(results),
+		IPs:         convert.ResultsToIPDetails(results),
+		CNICalls:    []spiderpoolv1.CNICall{workloadendpointmanager.NewCNICall(constant.CNIOperationAdd, i.config.NodeName, constant.CNIResultSuccess)},
 	}, endpoint); err != nil {
 		return results, fmt.Errorf("failed to patch IP allocation detail to Endpoint %s/%s: %v", endpoint.Namespace, endpoint.Name, err)
 	}
@@ -376,7 +656,7 @@ func (i *ipam) allocateForAllNICs(ctx context.Context, tt ToBeAllocateds, contai
 	return results, nil
 }
 
-func (i *ipam) allocateIPsFromAllCandidates(ctx context.Context, tt ToBeAllocateds, containerID string, pod *corev1.Pod, podController types.PodTopController) ([]*AllocationResult, error) {
+func (i *ipam) allocateIPsFromAllCandidates(ctx context.Context, tt ToBeAllocateds, containerID string, pod *corev1.Pod, podController types.PodTopController) ([]*types.AllocationResult, error) {
 	logger := logutils.FromContext(ctx)
 
 	tickets := tt.Pools()
@@ -385,8 +665,16 @@ func (i *ipam) allocateIPsFromAllCandidates(ctx context.Context, tt ToBeAllocate
 	}
 	defer i.ipamLimiter.ReleaseTicket(ctx, tickets...)
 
+	if i.tokenIssuer != nil {
+		token, err := i.tokenIssuer.AcquireToken(ctx, i.config.NodeName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire the token of cluster-wide allocations: %v", err)
+		}
+		defer i.tokenIssuer.ReturnToken(ctx, token)
+	}
+
 	n := len(tt.Candidates())
-	resultCh := make(chan *AllocationResult, n)
+	resultCh := make(chan *types.AllocationResult, n)
 	errCh := make(chan error, n)
 	wg := sync.WaitGroup{}
 	wg.Add(n)
@@ -417,7 +705,7 @@ func (i *ipam) allocateIPsFromAllCandidates(ctx context.Context, tt ToBeAllocate
 	close(resultCh)
 	close(errCh)
 
-	var results []*AllocationResult
+	var results []*types.AllocationResult
 	for res := range resultCh {
 		results = append(results, res)
 	}
@@ -434,11 +722,11 @@ func (i *ipam) allocateIPsFromAllCandidates(ctx context.Context, tt ToBeAllocate
 	return results, nil
 }
 
-func (i *ipam) allocateIPFromCandidate(ctx context.Context, c *PoolCandidate, nic, containerID string, cleanGateway bool, pod *corev1.Pod, podController types.PodTopController) (*AllocationResult, error) {
+func (i *ipam) allocateIPFromCandidate(ctx context.Context, c *PoolCandidate, nic, containerID string, cleanGateway bool, pod *corev1.Pod, podController types.PodTopController) (*types.AllocationResult, error) {
 	logger := logutils.FromContext(ctx)
 
 	var errs []error
-	var result *AllocationResult
+	var result *types.AllocationResult
 	for _, pool := range c.Pools {
 		ip, err := i.ipPoolManager.AllocateIP(ctx, pool, containerID, nic, pod, podController)
 		if err != nil {
@@ -447,10 +735,12 @@ func (i *ipam) allocateIPFromCandidate(ctx context.Context, c *PoolCandidate, ni
 			continue
 		}
 
-		result = &AllocationResult{
+		result = &types.AllocationResult{
 			IP:           ip,
+			PoolUID:      string(c.PToIPPool[pool].UID),
 			CleanGateway: cleanGateway,
-			Routes:       convertSpecRoutesToOAIRoutes(nic, c.PToIPPool[pool].Spec.Routes),
+			Routes:       convert.SpecRoutesToOAIRoutes(nic, ippoolmanager.GetIPPoolRoutes(c.PToIPPool[pool])),
+			Stripe:       c.Stripe,
 		}
 		logger.Sugar().Infof("Allocate IPv%d IP %s to NIC %s from IPPool %s", c.IPVersion, *result.IP.Address, nic, pool)
 		break
@@ -463,69 +753,183 @@ func (i *ipam) allocateIPFromCandidate(ctx context.Context, c *PoolCandidate, ni
 	return result, nil
 }
 
-func (i *ipam) getPoolCandidates(ctx context.Context, addArgs *models.IpamAddArgs, pod *corev1.Pod, podController types.PodTopController) (ToBeAllocateds, error) {
+// getPoolCandidates selects the original IPPool candidates through the pool
+// selection rules, and reports whether the default IPPools are used because
+// the IPPools specified by Pod annotations do not exist.
+func (i *ipam) getPoolCandidates(ctx context.Context, addArgs *models.IpamAddArgs, pod *corev1.Pod, podController types.PodTopController) (ToBeAllocateds, bool, error) {
 	// If faature SpiderSubnet is enabled, select IPPool candidates through the
 	// Pod annotations "ipam.spidernet.io/subnet" or "ipam.spidernet.io/subnets".
 	if i.config.EnableSpiderSubnet {
 		fromSubnet, err := i.getPoolFromSubnetAnno(ctx, pod, *addArgs.IfName, addArgs.CleanGateway, podController)
 		if nil != err {
-			return nil, fmt.Errorf("failed to get IPPool candidates from Subnet: %v", err)
+			return nil, false, fmt.Errorf("failed to get IPPool candidates from Subnet: %v", err)
 		}
 		if fromSubnet != nil {
-			return ToBeAllocateds{fromSubnet}, nil
+			fromSubnet.Source = SourceSubnetAnnotation
+			return ToBeAllocateds{fromSubnet}, false, nil
 		}
 	}
 
-	// Select IPPool candidates through the Pod annotation "ipam.spidernet.io/ippools".
+	var fromPodAnno ToBeAllocateds
 	if anno, ok := pod.Annotations[constant.AnnoPodIPPools]; ok {
-		return getPoolFromPodAnnoPools(ctx, anno, *addArgs.IfName)
+		// Select IPPool candidates through the Pod annotation "ipam.spidernet.io/ippools".
+		tt, err := getPoolFromPodAnnoPools(ctx, anno, *addArgs.IfName)
+		if err != nil {
+			return nil, false, err
+		}
+		fromPodAnno = tt
+	} else if anno, ok := pod.Annotations[constant.AnnoPodIPPool]; ok {
+		// Select IPPool candidates through the Pod annotation "ipam.spidernet.io/ippool".
+		t, err := getPoolFromPodAnnoPool(ctx, anno, *addArgs.IfName, addArgs.CleanGateway)
+		if err != nil {
+			return nil, false, err
+		}
+		fromPodAnno = ToBeAllocateds{t}
 	}
 
-	// Select IPPool candidates through the Pod annotation "ipam.spidernet.io/ippool".
-	if anno, ok := pod.Annotations[constant.AnnoPodIPPool]; ok {
-		t, err := getPoolFromPodAnnoPool(ctx, anno, *addArgs.IfName, addArgs.CleanGateway)
+	if fromPodAnno != nil {
+		fromPodAnno.setSource(SourceIPPoolAnnotation)
+		if !i.config.EnableAnnotatedPoolFallback {
+			return fromPodAnno, false, nil
+		}
+
+		fallback, err := i.fallBackToDefaultPools(ctx, fromPodAnno, addArgs, pod, podController)
 		if err != nil {
-			return nil, err
+			return nil, false, err
+		}
+		return fromPodAnno, fallback, nil
+	}
+
+	tt, err := i.getDefaultPoolCandidates(ctx, addArgs, *addArgs.IfName, addArgs.CleanGateway, pod, podController)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return tt, false, nil
+}
+
+// fallBackToDefaultPools drops the IPPool candidates specified by Pod
+// annotations which do not exist. Only the candidate left without any
+// IPPool falls back to the default IPPools of the same NIC and IP version,
+// the other candidates and NICs are kept as they are.
+func (i *ipam) fallBackToDefaultPools(ctx context.Context, tt ToBeAllocateds, addArgs *models.IpamAddArgs, pod *corev1.Pod, podController types.PodTopController) (bool, error) {
+	logger := logutils.FromContext(ctx)
+
+	fallback := false
+	for _, t := range tt {
+		var defaults ToBeAllocateds
+		for _, c := range t.PoolCandidates {
+			existing, missing, err := i.splitNonexistentPools(ctx, c.Pools)
+			if err != nil {
+				return false, err
+			}
+			if len(missing) == 0 {
+				continue
+			}
+
+			if len(existing) != 0 {
+				logger.Sugar().Warnf("IPv%d IPPools %v of NIC %s specified by Pod annotations do not exist, skip them", c.IPVersion, missing, t.NIC)
+				c.Pools = existing
+				continue
+			}
+
+			if defaults == nil {
+				defaults, err = i.getDefaultPoolCandidates(ctx, addArgs, t.NIC, t.CleanGateway, pod, podController)
+				if err != nil {
+					return false, err
+				}
+			}
+
+			var pools []string
+			for _, dc := range defaults.Candidates() {
+				if dc.IPVersion == c.IPVersion {
+					pools = append(pools, dc.Pools...)
+					break
+				}
+			}
+			if len(pools) == 0 {
+				return false, fmt.Errorf("%w, IPv%d IPPools %v of NIC %s specified by Pod annotations do not exist, and there are no default IPv%d IPPools to fall back to",
+					constant.ErrNoAvailablePool, c.IPVersion, missing, t.NIC, c.IPVersion)
+			}
+
+			logger.Sugar().Warnf("IPv%d IPPools %v of NIC %s specified by Pod annotations do not exist, fall back to the default IPPools %v", c.IPVersion, missing, t.NIC, pools)
+			if !i.preview {
+				event.EventRecorder.Eventf(
+					pod,
+					corev1.EventTypeWarning,
+					constant.EventReasonAnnotatedPoolFallback,
+					"IPv%d IPPools %v of NIC %s specified by Pod annotations do not exist, fall back to the default IPPools %v", c.IPVersion, missing, t.NIC, pools,
+				)
+			}
+			c.Pools = pools
+			fallback = true
 		}
-		return ToBeAllocateds{t}, nil
 	}
 
+	return fallback, nil
+}
+
+// getDefaultPoolCandidates selects the IPPool candidates when no IPPools or
+// Subnets are specified by Pod annotations.
+func (i *ipam) getDefaultPoolCandidates(ctx context.Context, addArgs *models.IpamAddArgs, nic string, cleanGateway bool, pod *corev1.Pod, podController types.PodTopController) (ToBeAllocateds, error) {
 	// If feature SpiderSubnet is enabled, select IPPool candidates through the cluster
 	// default Subnet defined in Configmap spiderpool-conf.
 	if i.config.EnableSpiderSubnet {
-		fromClusterDefaultSubnet, err := i.getPoolFromClusterDefaultSubnet(ctx, pod, *addArgs.IfName, addArgs.CleanGateway, podController)
+		fromClusterDefaultSubnet, err := i.getPoolFromClusterDefaultSubnet(ctx, pod, nic, cleanGateway, podController)
 		if nil != err {
 			return nil, err
 		}
 		if fromClusterDefaultSubnet != nil {
+			fromClusterDefaultSubnet.Source = SourceClusterDefault
 			return ToBeAllocateds{fromClusterDefaultSubnet}, nil
 		}
 	}
 
 	// Select IPPool candidates through the Namespace annotations
 	// "ipam.spidernet.io/defaultv4ippool" and "ipam.spidernet.io/defaultv6ippool".
-	t, err := i.getPoolFromNS(ctx, pod.Namespace, *addArgs.IfName, addArgs.CleanGateway)
+	t, err := i.getPoolFromNS(ctx, pod.Namespace, nic, cleanGateway)
 	if err != nil {
 		return nil, err
 	}
 	if t != nil {
+		t.Source = SourceNamespaceDefault
 		return ToBeAllocateds{t}, nil
 	}
 
 	// Select IPPool candidates through CNI network configuration.
-	if t := getPoolFromNetConf(ctx, *addArgs.IfName, addArgs.DefaultIPV4IPPool, addArgs.DefaultIPV6IPPool, addArgs.CleanGateway); t != nil {
+	if t := getPoolFromNetConf(ctx, nic, addArgs.DefaultIPV4IPPool, addArgs.DefaultIPV6IPPool, cleanGateway); t != nil {
+		t.Source = SourceNetConf
 		return ToBeAllocateds{t}, nil
 	}
 
 	// Select IPPool candidates through Configmap spiderpool-conf.
-	t, err = i.config.getClusterDefaultPool(ctx, *addArgs.IfName, addArgs.CleanGateway)
+	t, err = i.config.getClusterDefaultPool(ctx, nic, cleanGateway)
 	if err != nil {
 		return nil, err
 	}
+	t.Source = SourceClusterDefault
 
 	return ToBeAllocateds{t}, nil
 }
 
+// splitNonexistentPools splits the IPPool candidates into those which exist
+// and those which do not.
+func (i *ipam) splitNonexistentPools(ctx context.Context, pools []string) (existing, missing []string, err error) {
+	for _, pool := range pools {
+		_, err := i.ipPoolManager.GetIPPoolByName(ctx, pool)
+		if apierrors.IsNotFound(err) {
+			missing = append(missing, pool)
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get original candidate IPPool %s: %v", pool, err)
+		}
+		existing = append(existing, pool)
+	}
+
+	return existing, missing, nil
+}
+
 func (i *ipam) getPoolFromSubnetAnno(ctx context.Context, pod *corev1.Pod, nic string, cleanGateway bool, podController types.PodTopController) (*ToBeAllocated, error) {
 	logger := logutils.FromContext(ctx)
 
@@ -542,12 +946,21 @@ func (i *ipam) getPoolFromSubnetAnno(ctx context.Context, pod *corev1.Pod, nic s
 
 	var subnetItem types.AnnoSubnetItem
 	if len(subnetAnnoConfig.MultipleSubnets) != 0 {
+		found := false
 		for index := range subnetAnnoConfig.MultipleSubnets {
 			if subnetAnnoConfig.MultipleSubnets[index].Interface == nic {
 				subnetItem = subnetAnnoConfig.MultipleSubnets[index]
+				found = true
 				break
 			}
 		}
+
+		// the NIC without SpiderSubnets specified selects IPPool candidates
+		// in other ways, such as the Pod annotation "ipam.spidernet.io/ippools"
+		if !found {
+			logger.Sugar().Debugf("No SpiderSubnet specified for NIC %s in Pod annotation '%s'", nic, constant.AnnoSpiderSubnets)
+			return nil, nil
+		}
 	} else if subnetAnnoConfig.SingleSubnet != nil {
 		subnetItem = *subnetAnnoConfig.SingleSubnet
 	} else {
@@ -573,61 +986,76 @@ func (i *ipam) getPoolFromSubnetAnno(ctx context.Context, pod *corev1.Pod, nic s
 		return nil, err
 	}
 
+	// the IP number of the auto-created IPPools using the pinned IPs is fixed to the number of the pinned IPs
+	v4PoolIPNum, v6PoolIPNum := poolIPNum, poolIPNum
+	if subnetmanagercontrollers.IsPinnedIPs(subnetAnnoConfig) {
+		if i.config.EnableIPv4 {
+			if v4PoolIPNum, err = subnetmanagercontrollers.GetPinnedIPNumber(constant.IPv4, subnetAnnoConfig.PinnedIPv4IPs); nil != err {
+				return nil, err
+			}
+		}
+		if i.config.EnableIPv6 {
+			if v6PoolIPNum, err = subnetmanagercontrollers.GetPinnedIPNumber(constant.IPv6, subnetAnnoConfig.PinnedIPv6IPs); nil != err {
+				return nil, err
+			}
+		}
+	}
+
+	waitTimeout, err := i.getSubnetPoolWaitTimeout(pod)
+	if nil != err {
+		return nil, err
+	}
+
 	// This function will find the IPPool with the given match labels.
 	// The first return parameter represents the IPPool name, and the second parameter represents whether you need to create IPPool for orphan pod.
 	// If the application is an orphan pod and do not find any IPPool, it will return immediately to inform you to create IPPool.
-	findSubnetIPPool := func(matchLabels client.MatchingLabels) (*spiderpoolv1.SpiderIPPool, bool, error) {
-		var pool *spiderpoolv1.SpiderIPPool
+	// Otherwise, it waits for the IPPool to be created or scaled by the spiderpool-controller until the deadline.
+	findSubnetIPPool := func(matchLabels client.MatchingLabels, poolIPNum int) (*spiderpoolv1.SpiderIPPool, bool, error) {
 		subnetName := matchLabels[constant.LabelIPPoolOwnerSpiderSubnet]
-		for j := 1; j <= i.config.OperationRetries; j++ {
-			poolList, err := i.ipPoolManager.ListIPPools(ctx, matchLabels)
-			if nil != err {
-				return nil, false, fmt.Errorf("failed to get IPPoolList with labels '%v', error: %v", matchLabels, err)
-			}
+		poolList, err := i.subnetManager.ListAutoIPPools(ctx, matchLabels)
+		if nil != err {
+			return nil, false, fmt.Errorf("failed to get IPPoolList with labels '%v', error: %v", matchLabels, err)
+		}
 
-			// validation
-			if poolList == nil || len(poolList.Items) == 0 {
-				// the orphan pod should create its auto IPPool immediately if no IPPool found
-				if podController.Kind == constant.KindPod || podController.Kind == constant.KindUnknown {
-					return nil, true, nil
-				}
+		ready := autoPoolHasIPs(1)
+		switch len(poolList.Items) {
+		case 0:
+			// the orphan pod should create its auto IPPool immediately if no IPPool found
+			if podController.Kind == constant.KindPod || podController.Kind == constant.KindUnknown {
+				return nil, true, nil
+			}
+			logger.Sugar().Infof("no '%s' IPPool retrieved from SpiderSubnet '%s' with matchLabel '%v', wait for it to be created",
+				matchLabels[constant.LabelIPPoolVersion], subnetName, matchLabels)
+		case 1:
+			pool := poolList.Items[0].DeepCopy()
 
-				logger.Sugar().Errorf("fetch SubnetIPPool %d times: no '%s' IPPool retrieved from SpiderSubnet '%s' with matchLabel '%v', wait for a second and get a retry",
-					j, matchLabels[constant.LabelIPPoolVersion], subnetName, matchLabels)
-				time.Sleep(i.config.OperationGapDuration)
-				continue
-			} else if len(poolList.Items) == 1 {
-				pool = poolList.Items[0].DeepCopy()
-
-				// check whether the auto IPPool need to scale it desiredIPNumber or not for orphan pod and third party controller application
-				if podController.Kind == constant.KindPod || podController.Kind == constant.KindUnknown {
-					logger.Sugar().Debugf("found SpiderSubnet '%s' IPPool '%s' and check it whether need to be scaled", subnetName, pool.Name)
-					enableScaled, err := i.subnetManager.CheckScaleIPPool(ctx, pool, subnetName, poolIPNum)
-					if nil != err {
-						return nil, false, fmt.Errorf("failed to check IPPool %s whether need to be scaled: %v", pool.Name, err)
-					}
-					if enableScaled {
-						// wait for a while and let ippool informer to scale the IPPool's IPs
-						time.Sleep(i.config.OperationGapDuration)
-					}
+			// check whether the auto IPPool need to scale it desiredIPNumber or not for orphan pod and third party controller application
+			if podController.Kind == constant.KindPod || podController.Kind == constant.KindUnknown {
+				logger.Sugar().Debugf("found SpiderSubnet '%s' IPPool '%s' and check it whether need to be scaled", subnetName, pool.Name)
+				enableScaled, err := i.subnetManager.CheckScaleIPPool(ctx, pool, subnetName, poolIPNum)
+				if nil != err {
+					return nil, false, fmt.Errorf("failed to check IPPool %s whether need to be scaled: %v", pool.Name, err)
 				}
-
-				// we fetched Auto-created IPPool but it doesn't have any IPs, just wait for a while and let the IPPool informer to allocate IPs for it
-				if len(pool.Spec.IPs) == 0 {
-					logger.Sugar().Errorf("fetch SubnetIPPool %d times: retrieved IPPool '%s' but no IPs, wait for a second and get a retry", j, pool.Name)
-					time.Sleep(i.config.OperationGapDuration)
-					continue
+				if enableScaled {
+					// wait for the ippool informer to scale the IPPool's IPs
+					ready = autoPoolHasIPs(poolIPNum)
 				}
-			} else {
-				return nil, false, fmt.Errorf("it's invalid for '%s/%s/%s' corresponding SpiderSubnet '%s' owns multiple matchLabel '%v' corresponding IPPools '%v' for one specify application",
-					podController.Kind, podController.Namespace, podController.Name, subnetName, matchLabels, poolList.Items)
 			}
 
-			break
+			if ready(pool) {
+				return pool, false, nil
+			}
+			logger.Sugar().Infof("retrieved IPPool '%s' but it's not ready, wait for the IPPool informer to allocate IPs for it", pool.Name)
+		default:
+			return nil, false, fmt.Errorf("it's invalid for '%s/%s/%s' corresponding SpiderSubnet '%s' owns multiple matchLabel '%v' corresponding IPPools '%v' for one specify application",
+				podController.Kind, podController.Namespace, podController.Name, subnetName, matchLabels, poolList.Items)
 		}
-		if pool == nil {
-			return nil, false, fmt.Errorf("no matching IPPool candidate with labels '%v'", matchLabels)
+
+		pool, err := i.waitAutoIPPool(ctx, matchLabels, ready, waitTimeout)
+		if nil != err {
+			return nil, false, err
 		}
+
 		return pool, false, nil
 	}
 
@@ -653,26 +1081,26 @@ func (i *ipam) getPoolFromSubnetAnno(ctx context.Context, pod *corev1.Pod, nic s
 		go func() {
 			defer wg.Done()
 
-			v4PoolCandidate, shouldCreateV4Pool, errV4 = findSubnetIPPool(client.MatchingLabels{
+			v4Labels := client.MatchingLabels{
 				constant.LabelIPPoolOwnerApplicationUID: string(podController.UID),
 				constant.LabelIPPoolVersion:             constant.LabelIPPoolVersionV4,
 				constant.LabelIPPoolOwnerSpiderSubnet:   subnetItem.IPv4[0],
 				constant.LabelIPPoolOwnerApplication:    subnetmanagercontrollers.AppLabelValue(podController.Kind, podController.Namespace, podController.Name),
 				constant.LabelIPPoolInterface:           subnetItem.Interface,
-			})
+			}
+			v4PoolCandidate, shouldCreateV4Pool, errV4 = findSubnetIPPool(v4Labels, v4PoolIPNum)
 			if nil != errV4 {
 				return
 			}
 
 			if shouldCreateV4Pool {
-				v4Pool, err := i.subnetManager.AllocateEmptyIPPool(ctx, subnetItem.IPv4[0], podController, podSelector, poolIPNum, constant.IPv4, reclaimIPPool, nic)
+				_, err := i.subnetManager.AllocateEmptyIPPool(ctx, subnetItem.IPv4[0], podController, podSelector, v4PoolIPNum, subnetAnnoConfig.PinnedIPv4IPs, constant.IPv4, reclaimIPPool, nic)
 				if nil != err {
 					errV4 = err
 					return
 				}
-				v4PoolCandidate = v4Pool
-				// wait for a second to make the spiderpool-controller allocates IP for the IPPool from SpiderSubnet
-				time.Sleep(i.config.OperationGapDuration)
+				// wait for the spiderpool-controller to allocate IPs for the IPPool from SpiderSubnet
+				v4PoolCandidate, errV4 = i.waitAutoIPPool(ctx, v4Labels, autoPoolHasIPs(1), waitTimeout)
 			}
 		}()
 	}
@@ -683,26 +1111,26 @@ func (i *ipam) getPoolFromSubnetAnno(ctx context.Context, pod *corev1.Pod, nic s
 		go func() {
 			defer wg.Done()
 
-			v6PoolCandidate, shouldCreateV6Pool, errV6 = findSubnetIPPool(client.MatchingLabels{
+			v6Labels := client.MatchingLabels{
 				constant.LabelIPPoolOwnerApplicationUID: string(podController.UID),
 				constant.LabelIPPoolVersion:             constant.LabelIPPoolVersionV6,
 				constant.LabelIPPoolOwnerSpiderSubnet:   subnetItem.IPv6[0],
 				constant.LabelIPPoolOwnerApplication:    subnetmanagercontrollers.AppLabelValue(podController.Kind, podController.Namespace, podController.Name),
 				constant.LabelIPPoolInterface:           subnetItem.Interface,
-			})
+			}
+			v6PoolCandidate, shouldCreateV6Pool, errV6 = findSubnetIPPool(v6Labels, v6PoolIPNum)
 			if nil != errV6 {
 				return
 			}
 
 			if shouldCreateV6Pool {
-				v6Pool, err := i.subnetManager.AllocateEmptyIPPool(ctx, subnetItem.IPv6[0], podController, podSelector, poolIPNum, constant.IPv6, reclaimIPPool, nic)
+				_, err := i.subnetManager.AllocateEmptyIPPool(ctx, subnetItem.IPv6[0], podController, podSelector, v6PoolIPNum, subnetAnnoConfig.PinnedIPv6IPs, constant.IPv6, reclaimIPPool, nic)
 				if nil != err {
 					errV6 = err
 					return
 				}
-				v6PoolCandidate = v6Pool
-				// wait for a second to make the spiderpool-controller allocates IP for the IPPool from SpiderSubnet
-				time.Sleep(i.config.OperationGapDuration)
+				// wait for the spiderpool-controller to allocate IPs for the IPPool from SpiderSubnet
+				v6PoolCandidate, errV6 = i.waitAutoIPPool(ctx, v6Labels, autoPoolHasIPs(1), waitTimeout)
 			}
 		}()
 	}
@@ -813,7 +1241,7 @@ func (i *ipam) findOrApplyClusterSubnetDefaultIPPool(ctx context.Context, podCon
 		if poolList == nil || len(poolList.Items) == 0 {
 			log.Sugar().Debugf("there's no 'IPv%d' IPPoolList retrieved from cluster default SpiderSubent '%s' with matchLabel '%v'",
 				ipVersion, subnetName, matchLabel)
-			pool, err := i.subnetManager.AllocateEmptyIPPool(ctx, subnetName, podController, podSelector, poolIPNum, ipVersion, reclaimIPPool, ifName)
+			pool, err := i.subnetManager.AllocateEmptyIPPool(ctx, subnetName, podController, podSelector, poolIPNum, nil, ipVersion, reclaimIPPool, ifName)
 			if nil != err {
 				return nil, err
 			}
@@ -852,7 +1280,7 @@ func (i *ipam) findOrApplyClusterSubnetDefaultIPPool(ctx context.Context, podCon
 				constant.LabelIPPoolVersion:             constant.LabelIPPoolVersionV4,
 				constant.LabelIPPoolInterface:           ifName,
 			}
-			v4PoolList, err := i.ipPoolManager.ListIPPools(ctx, matchLabels)
+			v4PoolList, err := i.subnetManager.ListAutoIPPools(ctx, matchLabels)
 			if nil != err {
 				errV4 = fmt.Errorf("failed to get IPv4 IPPoolList with labels '%v', error: %v", matchLabels, err)
 				return
@@ -874,7 +1302,7 @@ func (i *ipam) findOrApplyClusterSubnetDefaultIPPool(ctx context.Context, podCon
 				constant.LabelIPPoolVersion:             constant.LabelIPPoolVersionV6,
 				constant.LabelIPPoolInterface:           ifName,
 			}
-			v6PoolList, err := i.ipPoolManager.ListIPPools(ctx, matchLabels)
+			v6PoolList, err := i.subnetManager.ListAutoIPPools(ctx, matchLabels)
 			if nil != err {
 				errV6 = fmt.Errorf("failed to get IPv6 IPPoolList with labels '%v', error: %v", matchLabels, err)
 				return
@@ -960,92 +1388,128 @@ func (i *ipam) precheckPoolCandidates(ctx context.Context, tt ToBeAllocateds) er
 	return nil
 }
 
+// filterPoolCandidates eliminates the IPPool candidates which can't
+// allocate IP addresses to the Pod, the reasons are kept in the Filtered of
+// the candidates. All candidates are filtered even if one of them has no
+// IPPool left, and the error of the first one is returned. It stops at the
+// first filter failing to look up the resources it filters by.
 func (i *ipam) filterPoolCandidates(ctx context.Context, tt ToBeAllocateds, pod *corev1.Pod) error {
 	logger := logutils.FromContext(ctx)
 
+	tenant, err := i.getPodTenant(ctx, pod)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
 	for _, t := range tt {
 		for _, c := range t.PoolCandidates {
 			var errs []error
+			var quarantined []string
+			candidates := append([]string(nil), c.Pools...)
 
 			for j := 0; j < len(c.Pools); j++ {
 				pool := c.Pools[j]
-				if err := i.selectByPod(ctx, c.IPVersion, c.PToIPPool[pool], pod); err != nil {
+				if err := i.selectByPod(ctx, c.IPVersion, c.PToIPPool[pool], pod, tenant); err != nil {
+					// The IPPool is not to blame if the filter fails to
+					// look up the resources.
+					var lookupErr *PoolFilterLookupError
+					if errors.As(err, &lookupErr) {
+						return fmt.Errorf("failed to filter IPPool %s: %w", pool, err)
+					}
 					logger.Sugar().Warnf("IPPool %s is filtered by Pod: %v", pool, err)
 					errs = append(errs, err)
+					var filterErr *PoolFilterError
+					if errors.As(err, &filterErr) {
+						if !i.preview {
+							metric.IPPoolFilterCounts.Add(ctx, 1, attribute.String(metric.AttrKeyFilter, filterErr.Filter))
+						}
+						if filterErr.Filter == FilterQuarantined {
+							quarantined = append(quarantined, pool)
+						}
+					}
 
+					if c.Filtered == nil {
+						c.Filtered = map[string]error{}
+					}
+					c.Filtered[pool] = err
 					delete(c.PToIPPool, pool)
 					c.Pools = append((c.Pools)[:j], (c.Pools)[j+1:]...)
 					j--
 				}
 			}
+			if len(c.Pools) != 0 || firstErr != nil {
+				continue
+			}
 
-			if len(c.Pools) == 0 {
-				return fmt.Errorf("%w, all IPv%d IPPools %v of %s filtered out: %v", constant.ErrNoAvailablePool, c.IPVersion, c.Pools, t.NIC, utilerrors.NewAggregate(errs))
+			// The quarantined IPPools are never selected, even if they are
+			// the only candidates, the Pods would fail on their datapath
+			// anyway.
+			if len(quarantined) != 0 {
+				firstErr = fmt.Errorf("%w, all IPv%d IPPools %v of %s filtered out, IPPools %v are quarantined for repeated datapath failures: %v",
+					constant.ErrNoAvailablePool, c.IPVersion, candidates, t.NIC, quarantined, utilerrors.NewAggregate(errs))
+			} else {
+				firstErr = fmt.Errorf("%w, all IPv%d IPPools %v of %s filtered out: %v", constant.ErrNoAvailablePool, c.IPVersion, candidates, t.NIC, utilerrors.NewAggregate(errs))
 			}
 		}
 	}
 
-	return nil
+	return firstErr
 }
 
-func (i *ipam) selectByPod(ctx context.Context, version types.IPVersion, ipPool *spiderpoolv1.SpiderIPPool, pod *corev1.Pod) error {
-	if ipPool.DeletionTimestamp != nil {
-		return fmt.Errorf("terminating IPPool %s", ipPool.Name)
-	}
-
-	if *ipPool.Spec.Disable {
-		return fmt.Errorf("disabled IPPool %s", ipPool.Name)
+// getPodTenant returns the tenant of the Pod, which is the one of its
+// Namespace annotated with "ipam.spidernet.io/tenant". The tenant is not
+// up to the Pod, whose annotation is only allowed to repeat the tenant of
+// the Namespace, otherwise the Pods could allocate IP addresses from the
+// IPPools of the other tenants.
+func (i *ipam) getPodTenant(ctx context.Context, pod *corev1.Pod) (string, error) {
+	namespace, err := i.nsManager.GetNamespaceByName(ctx, pod.Namespace)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the tenant of Namespace %s: %v", pod.Namespace, err)
 	}
 
-	if *ipPool.Spec.IPVersion != version {
-		return fmt.Errorf("expect an IPv%d IPPool, but the version of the IPPool %s is IPv%d", version, ipPool.Name, *ipPool.Spec.IPVersion)
+	tenant := namespace.Annotations[constant.AnnoTenant]
+	if v, ok := pod.Annotations[constant.AnnoTenant]; ok && v != tenant {
+		return "", fmt.Errorf("%w, tenant %q of the Pod annotation %s mismatches the tenant %q of Namespace %s",
+			constant.ErrWrongInput, v, constant.AnnoTenant, tenant, pod.Namespace)
 	}
 
-	if ipPool.Status.TotalIPCount != nil && ipPool.Status.AllocatedIPCount != nil {
-		if *ipPool.Status.TotalIPCount-*ipPool.Status.AllocatedIPCount == 0 {
-			return constant.ErrIPUsedOut
-		}
-	}
+	return tenant, nil
+}
 
-	if ipPool.Spec.NodeAffinity != nil {
-		node, err := i.nodeManager.GetNodeByName(ctx, pod.Spec.NodeName)
-		if err != nil {
-			return err
-		}
-		selector, err := metav1.LabelSelectorAsSelector(ipPool.Spec.NodeAffinity)
-		if err != nil {
-			return err
-		}
-		if !selector.Matches(labels.Set(node.Labels)) {
-			return fmt.Errorf("unmatched Node affinity of IPPool %s", ipPool.Name)
-		}
-	}
+// orderPoolCandidatesByUtilization reorders the IPPools of each candidate by
+// the ratio of free IP addresses in descending order, the IPPools with the
+// same ratio keep the declared order.
+func orderPoolCandidatesByUtilization(tt ToBeAllocateds) {
+	for _, t := range tt {
+		for _, c := range t.PoolCandidates {
+			if len(c.Pools) < 2 {
+				continue
+			}
 
-	if ipPool.Spec.NamespaceAffinity != nil {
-		namespace, err := i.nsManager.GetNamespaceByName(ctx, pod.Namespace)
-		if err != nil {
-			return err
-		}
-		selector, err := metav1.LabelSelectorAsSelector(ipPool.Spec.NamespaceAffinity)
-		if err != nil {
-			return err
-		}
-		if !selector.Matches(labels.Set(namespace.Labels)) {
-			return fmt.Errorf("unmatched Namespace affinity of IPPool %s", ipPool.Name)
-		}
-	}
+			ratios := make(map[string]float64, len(c.Pools))
+			for _, pool := range c.Pools {
+				ratios[pool] = ippoolmanager.GetIPPoolFreeRatio(c.PToIPPool[pool])
+			}
 
-	if ipPool.Spec.PodAffinity != nil {
-		selector, err := metav1.LabelSelectorAsSelector(ipPool.Spec.PodAffinity)
-		if err != nil {
-			return err
-		}
-		if !selector.Matches(labels.Set(pod.Labels)) {
-			return fmt.Errorf("unmatched Pod affinity of IPPool %s", ipPool.Name)
+			// Copy the Pools before sorting, they may be shared with the
+			// configuration, such as the cluster default IPPools.
+			pools := make([]string, len(c.Pools))
+			copy(pools, c.Pools)
+			sort.SliceStable(pools, func(a, b int) bool {
+				return ratios[pools[a]] > ratios[pools[b]]
+			})
+			c.Pools = pools
 		}
 	}
+}
 
-	return nil
+func (i *ipam) selectByPod(ctx context.Context, version types.IPVersion, ipPool *spiderpoolv1.SpiderIPPool, pod *corev1.Pod, tenant string) error {
+	return i.poolFilters.filter(ctx, ipPool, &PoolFilterArgs{
+		IPVersion: version,
+		Pod:       pod,
+		Tenant:    tenant,
+	})
 }
 
 func (i *ipam) verifyPoolCandidates(tt ToBeAllocateds) error {
@@ -1075,22 +1539,48 @@ func (i *ipam) Release(ctx context.Context, delArgs *models.IpamDelArgs) error {
 	logger := logutils.FromContext(ctx)
 	logger.Info("Start to release")
 
-	endpoint, err := i.endpointManager.GetEndpointByName(ctx, *delArgs.PodNamespace, *delArgs.PodName)
+	intent := ReleaseIntent{
+		PodNamespace: *delArgs.PodNamespace,
+		PodName:      *delArgs.PodName,
+		ContainerID:  *delArgs.ContainerID,
+		NIC:          *delArgs.IfName,
+	}
+
+	err := i.releaseIntent(ctx, intent)
+	if err != nil && i.journal != nil && isReleaseRetriable(err) {
+		logger.Sugar().Warnf("API server is unreachable or busy, record the release intent to journal for replaying later: %v", err)
+		intent.CreationTime = time.Now()
+		intent.NextReplayTime = intent.CreationTime
+		if jErr := i.journal.Append(intent); jErr != nil {
+			return fmt.Errorf("%v, and failed to record the release intent to journal: %v", err, jErr)
+		}
+		return nil
+	}
+
+	return err
+}
+
+func (i *ipam) releaseIntent(ctx context.Context, intent ReleaseIntent) error {
+	logger := logutils.FromContext(ctx)
+
+	endpoint, err := i.endpointManager.GetCachedEndpointByName(ctx, intent.PodNamespace, intent.PodName)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			logger.Info("Endpoin does not exist, ignoring release")
+			// No Endpoint is created for the Pods using host network as
+			// well, since nothing is allocated to them.
+			logger.Info("Endpoint does not exist, ignoring release")
 			return nil
 		}
-		return fmt.Errorf("failed to get Endpoint %s/%s: %v", *delArgs.PodNamespace, *delArgs.PodName, err)
+		return fmt.Errorf("failed to get Endpoint %s/%s: %w", intent.PodNamespace, intent.PodName, err)
 	}
 
-	if err := i.releaseForAllNICs(ctx, *delArgs.ContainerID, *delArgs.IfName, endpoint); err != nil {
+	if err := i.releaseForAllNICs(ctx, intent.ContainerID, intent.NIC, endpoint); err != nil {
 		return err
 	}
 
 	if i.config.EnableSpiderSubnet && endpoint.Status.OwnerControllerType == constant.KindPod {
 		logger.Info("try to check whether need to delete dead orphan pod's auto-created IPPool")
-		err := i.deleteDeadOrphanPodAutoIPPool(ctx, *delArgs.PodNamespace, *delArgs.PodName, *delArgs.IfName)
+		err := i.deleteDeadOrphanPodAutoIPPool(ctx, intent.PodNamespace, intent.PodName, intent.NIC)
 		if nil != err {
 			logger.Sugar().Errorf("failed to delete dead orphan pod auto-created IPPool: %v", err)
 		}
@@ -1099,18 +1589,89 @@ func (i *ipam) Release(ctx context.Context, delArgs *models.IpamDelArgs) error {
 	return nil
 }
 
+// replayReleaseJournal retries the release intents recorded in the journal
+// which are due, the intents which are completed successfully or failed
+// permanently will be removed from it, and the other failed ones are
+// postponed with backoff.
+func (i *ipam) replayReleaseJournal(ctx context.Context) {
+	now := time.Now()
+	for _, intent := range i.journal.List() {
+		if now.Before(intent.NextReplayTime) {
+			continue
+		}
+
+		logger := logutils.Logger.Named("IPAM").With(
+			zap.String("Action", "ReplayReleaseJournal"),
+			zap.String("ContainerID", intent.ContainerID),
+			zap.String("IfName", intent.NIC),
+			zap.String("PodNamespace", intent.PodNamespace),
+			zap.String("PodName", intent.PodName),
+		)
+		rCtx := logutils.IntoContext(ctx, logger)
+
+		// The intent is kept to be replayed again.
+		if !i.sandboxGone(rCtx, intent.ContainerID) {
+			continue
+		}
+
+		if err := i.releaseIntent(rCtx, intent); err != nil {
+			if isReleasePermanentFailure(err) {
+				logger.Sugar().Errorf("failed to replay the release intent recorded at %s permanently, drop it: %v", intent.CreationTime, err)
+				if err := i.journal.Remove(intent); err != nil {
+					logger.Sugar().Errorf("failed to remove the release intent from journal: %v", err)
+				}
+				continue
+			}
+
+			i.postponeReleaseIntent(rCtx, intent, now)
+			if isAPIServerUnreachable(err) {
+				logger.Sugar().Debugf("API server is still unreachable, retry later: %v", err)
+				return
+			}
+			logger.Sugar().Errorf("failed to replay the release intent recorded at %s: %v", intent.CreationTime, err)
+			continue
+		}
+
+		if err := i.journal.Remove(intent); err != nil {
+			logger.Sugar().Errorf("failed to remove the release intent from journal: %v", err)
+			continue
+		}
+		logger.Info("Succeed to replay the release intent")
+	}
+}
+
+// postponeReleaseIntent postpones the next replay of the failed release
+// intent, the backoff doubles from ReleaseJournalReplayDuration up to
+// ReleaseJournalMaxBackoff.
+func (i *ipam) postponeReleaseIntent(ctx context.Context, intent ReleaseIntent, now time.Time) {
+	backoff := i.config.ReleaseJournalReplayDuration
+	for n := 0; n < intent.Attempts && backoff < i.config.ReleaseJournalMaxBackoff; n++ {
+		backoff *= 2
+	}
+	if backoff > i.config.ReleaseJournalMaxBackoff {
+		backoff = i.config.ReleaseJournalMaxBackoff
+	}
+
+	intent.Attempts++
+	intent.NextReplayTime = now.Add(backoff)
+	if err := i.journal.Update(intent); err != nil {
+		logutils.FromContext(ctx).Sugar().Errorf("failed to postpone the release intent in journal: %v", err)
+	}
+}
+
 func (i *ipam) releaseForAllNICs(ctx context.Context, containerID, nic string, endpoint *spiderpoolv1.SpiderEndpoint) error {
 	logger := logutils.FromContext(ctx)
 
 	rollback := i.getRollback(containerID)
 	if len(rollback) != 0 {
-		details := convertResultsToIPDetails(rollback)
+		details := convert.ResultsToIPDetails(rollback)
 		logger.Sugar().Infof("Roll back IP allocation details: %+v", details)
 
 		if err := i.release(ctx, containerID, details); err != nil {
-			return fmt.Errorf("failed to roll back the allocated IP addresses: %v", err)
+			return fmt.Errorf("failed to roll back the allocated IP addresses: %w", err)
 		}
 		i.removeRollback(containerID)
+		i.recordCNICall(ctx, containerID, constant.CNIOperationDel, constant.CNIResultRollback, endpoint)
 		logger.Info("Succeed to roll back")
 
 		return nil
@@ -1135,6 +1696,10 @@ func (i *ipam) releaseForAllNICs(ctx context.Context, containerID, nic string, e
 		}
 	}
 
+	if i.deferRelease(ctx, endpoint, allocation) {
+		return nil
+	}
+
 	logger.Sugar().Infof("Release IP allocation details: %+v", allocation.IPs)
 	if err := i.release(ctx, allocation.ContainerID, allocation.IPs); err != nil {
 		return err
@@ -1142,8 +1707,9 @@ func (i *ipam) releaseForAllNICs(ctx context.Context, containerID, nic string, e
 
 	logger.Info("Clear the current IP allocation")
 	if err := i.endpointManager.ClearCurrentIPAllocation(ctx, containerID, endpoint); err != nil {
-		return fmt.Errorf("failed to clear current IP allocation: %v", err)
+		return fmt.Errorf("failed to clear current IP allocation: %w", err)
 	}
+	i.recordCNICall(ctx, containerID, constant.CNIOperationDel, constant.CNIResultSuccess, endpoint)
 
 	logger.Info("Succeed to release")
 
@@ -1190,39 +1756,34 @@ func (i *ipam) release(ctx context.Context, containerID string, details []spider
 	pics := GroupIPDetails(containerID, "", details)
 	tickets := pics.Pools()
 	if err := i.ipamLimiter.AcquireTicket(ctx, tickets...); err != nil {
-		return fmt.Errorf("failed to queue correctly: %v", err)
+		return fmt.Errorf("failed to queue correctly: %w", err)
 	}
 	defer i.ipamLimiter.ReleaseTicket(ctx, tickets...)
 
-	errCh := make(chan error, len(pics))
-	wg := sync.WaitGroup{}
-	wg.Add(len(pics))
-
-	for p, ics := range pics {
-		go func(poolName string, ipAndCIDs []types.IPAndCID) {
-			defer wg.Done()
-
-			if err := i.ipPoolManager.ReleaseIP(ctx, poolName, ipAndCIDs); err != nil {
-				logger.Warn(err.Error())
-				errCh <- err
-				return
+	// Only the IP addresses failing to be released are retried, the
+	// retries are left to the release journal if the API server is
+	// unreachable.
+	for j := 0; ; j++ {
+		results, err := i.ipPoolManager.ReleaseIPs(ctx, pics)
+		failed := map[string][]types.IPAndCID{}
+		for _, r := range results {
+			if r.Err != nil {
+				logger.Sugar().Warnf("Failed to release IP address %s from IPPool %s: %v", r.IP, r.IPPool, r.Err)
+				failed[r.IPPool] = append(failed[r.IPPool], r.IPAndCID)
+				continue
 			}
-			logger.Sugar().Infof("Succeed to release IP addresses %+v from IPPool %s", ipAndCIDs, poolName)
-		}(p, ics)
-	}
-	wg.Wait()
-	close(errCh)
-
-	var errs []error
-	for err := range errCh {
-		errs = append(errs, err)
-	}
+			logger.Sugar().Infof("Succeed to release IP address %s from IPPool %s", r.IP, r.IPPool)
+		}
+		if err == nil {
+			return nil
+		}
+		if j == i.config.OperationRetries || !isReleaseRetriable(err) || isAPIServerUnreachable(err) {
+			return fmt.Errorf("failed to release allocated IP addresses %+v: %w", failed, err)
+		}
 
-	if len(errs) != 0 {
-		return fmt.Errorf("failed to release all allocated IP addresses %+v: %w", pics, utilerrors.NewAggregate(errs))
+		pics = failed
+		time.Sleep(i.config.OperationGapDuration)
 	}
-
-	return nil
 }
 
 // deleteDeadOrphanPodAutoIPPool will delete orphan pod corresponding IPPools
@@ -1262,7 +1823,16 @@ func (i *ipam) deleteDeadOrphanPodAutoIPPool(ctx context.Context, podNS, podName
 	return nil
 }
 
-func (i *ipam) addRollback(containerID string, results []*AllocationResult) {
+// recordCNICall records the CNI call in the historical IP allocation of the
+// container, which is only for post-mortems, so the failure is just logged.
+func (i *ipam) recordCNICall(ctx context.Context, containerID, operation, result string, endpoint *spiderpoolv1.SpiderEndpoint) {
+	call := workloadendpointmanager.NewCNICall(operation, i.config.NodeName, result)
+	if err := i.endpointManager.RecordCNICall(ctx, containerID, call, endpoint); err != nil {
+		logutils.FromContext(ctx).Sugar().Warnf("Failed to record CNI call %+v: %v", call, err)
+	}
+}
+
+func (i *ipam) addRollback(containerID string, results []*types.AllocationResult) {
 	i.rollbacks.Store(containerID, results)
 }
 
@@ -1270,13 +1840,13 @@ func (i *ipam) removeRollback(containerID string) {
 	i.rollbacks.Delete(containerID)
 }
 
-func (i *ipam) getRollback(containerID string) []*AllocationResult {
+func (i *ipam) getRollback(containerID string) []*types.AllocationResult {
 	v, ok := i.rollbacks.Load(containerID)
 	if !ok {
 		return nil
 	}
 
-	results, ok := v.([]*AllocationResult)
+	results, ok := v.([]*types.AllocationResult)
 	if !ok {
 		return nil
 	}
@@ -1285,5 +1855,33 @@ func (i *ipam) getRollback(containerID string) []*AllocationResult {
 }
 
 func (i *ipam) Start(ctx context.Context) error {
+	if (i.config.SandboxStateDir != "" || i.sandboxChecker != nil) && i.config.NodeName != "" {
+		go i.startReconcileLocalEndpoints(ctx)
+	}
+
+	if i.config.IPLeaseRenewDuration > 0 && i.config.NodeName != "" {
+		go wait.UntilWithContext(ctx, i.renewLocalIPLeases, i.config.IPLeaseRenewDuration)
+	}
+
+	if i.deferrer != nil {
+		go wait.UntilWithContext(ctx, i.releaseExpiredDeferrals, time.Second)
+	}
+
+	if i.journal != nil {
+		go func() {
+			ticker := time.NewTicker(i.config.ReleaseJournalReplayDuration)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					i.replayReleaseJournal(ctx)
+				}
+			}
+		}()
+	}
+
 	return i.ipamLimiter.Start(ctx)
 }
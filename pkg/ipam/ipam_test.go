@@ -0,0 +1,238 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/spidernet-io/spiderpool/api/v1/agent/models"
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/podmanager"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+	"github.com/spidernet-io/spiderpool/pkg/statefulsetmanager"
+	"github.com/spidernet-io/spiderpool/pkg/subnetmanager"
+	subnetmanagercontrollers "github.com/spidernet-io/spiderpool/pkg/subnetmanager/controllers"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
+)
+
+var _ = Describe("NewIPAM", Label("ipam_test"), func() {
+	DescribeTable("checks the order of the IPPool candidates",
+		func(order string, valid bool) {
+			fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+			rIPManager, err := reservedipmanager.NewReservedIPManager(fakeClient)
+			Expect(err).NotTo(HaveOccurred())
+			ipPoolManager, err := ippoolmanager.NewIPPoolManager(ippoolmanager.IPPoolManagerConfig{}, fakeClient, rIPManager)
+			Expect(err).NotTo(HaveOccurred())
+			endpointManager, err := workloadendpointmanager.NewWorkloadEndpointManager(workloadendpointmanager.EndpointManagerConfig{}, fakeClient)
+			Expect(err).NotTo(HaveOccurred())
+			podManager, err := podmanager.NewPodManager(podmanager.PodManagerConfig{}, fakeClient)
+			Expect(err).NotTo(HaveOccurred())
+			stsManager, err := statefulsetmanager.NewStatefulSetManager(fakeClient)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = NewIPAM(IPAMConfig{IPPoolCandidateOrder: order}, ipPoolManager, endpointManager,
+				&fakeNodeManager{}, &fakeNamespaceManager{}, podManager, stsManager, nil, nil, nil)
+			if valid {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(constant.ErrWrongInput))
+			}
+		},
+		Entry("the default", "", true),
+		Entry("the declared order", CandidateOrderDeclared, true),
+		Entry("the least utilized first", CandidateOrderLeastUtilized, true),
+		Entry("an unknown order", "mostUtilized", false),
+	)
+})
+
+var _ = Describe("Allocate", Label("ipam_test"), func() {
+	Describe("the Pod using host network", func() {
+		var i *ipam
+		var fakeClient client.Client
+		var config IPAMConfig
+		var addArgs *models.IpamAddArgs
+		BeforeEach(func() {
+			config = IPAMConfig{
+				EnableIPv4:               true,
+				ClusterDefaultIPv4IPPool: []string{"default-v4-pool"},
+			}
+			addArgs = &models.IpamAddArgs{
+				ContainerID:  pointer.String("container"),
+				IfName:       pointer.String(constant.ClusterDefaultInterfaceName),
+				NetNamespace: pointer.String("/var/run/netns/pod"),
+				PodNamespace: pointer.String(metav1.NamespaceDefault),
+				PodName:      pointer.String("pod"),
+			}
+		})
+
+		JustBeforeEach(func() {
+			pool := &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: "default-v4-pool"}}
+			pool.Spec.IPVersion = pointer.Int64(constant.IPv4)
+			pool.Spec.Subnet = "172.18.40.0/24"
+			pool.Spec.IPs = []string{"172.18.40.10"}
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "pod"},
+				Spec:       corev1.PodSpec{NodeName: "node", HostNetwork: true},
+				Status:     corev1.PodStatus{Phase: corev1.PodPending},
+			}
+			fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(pool, pod).Build()
+
+			rIPManager, err := reservedipmanager.NewReservedIPManager(fakeClient)
+			Expect(err).NotTo(HaveOccurred())
+			ipPoolManager, err := ippoolmanager.NewIPPoolManager(ippoolmanager.IPPoolManagerConfig{}, fakeClient, rIPManager)
+			Expect(err).NotTo(HaveOccurred())
+			endpointManager, err := workloadendpointmanager.NewWorkloadEndpointManager(workloadendpointmanager.EndpointManagerConfig{}, fakeClient)
+			Expect(err).NotTo(HaveOccurred())
+			podManager, err := podmanager.NewPodManager(podmanager.PodManagerConfig{}, fakeClient)
+			Expect(err).NotTo(HaveOccurred())
+
+			i = &ipam{
+				config:          setDefaultsForIPAMConfig(config),
+				ipPoolManager:   ipPoolManager,
+				endpointManager: endpointManager,
+				podManager:      podManager,
+			}
+		})
+
+		// expectNothingAllocated checks that neither the IPPool nor the
+		// Endpoint records the IP allocation of the Pod.
+		expectNothingAllocated := func() {
+			var pool spiderpoolv1.SpiderIPPool
+			err := fakeClient.Get(context.TODO(), client.ObjectKey{Name: "default-v4-pool"}, &pool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pool.Status.AllocatedIPs).To(BeEmpty())
+
+			err = fakeClient.Get(context.TODO(), client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: "pod"}, &spiderpoolv1.SpiderEndpoint{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		}
+
+		It("skips the allocation with an empty response", func() {
+			addResp, err := i.Allocate(context.TODO(), addArgs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(addResp.Ips).To(BeEmpty())
+			Expect(addResp.Routes).To(BeEmpty())
+			expectNothingAllocated()
+		})
+
+		When("the Pods using host network are rejected", func() {
+			BeforeEach(func() {
+				config.RejectHostNetworkPod = true
+			})
+
+			It("fails the allocation", func() {
+				addResp, err := i.Allocate(context.TODO(), addArgs)
+				Expect(err).To(MatchError(constant.ErrWrongInput))
+				Expect(err).To(MatchError(ContainSubstring("Pod default/pod uses host network")))
+				Expect(addResp).To(BeNil())
+				expectNothingAllocated()
+			})
+		})
+	})
+})
+
+var _ = Describe("getPoolFromSubnetAnno", Label("ipam_test"), func() {
+	podController := types.PodTopController{
+		Kind:      constant.KindDeployment,
+		Namespace: metav1.NamespaceDefault,
+		Name:      "deploy",
+		UID:       "deploy-uid",
+		APP: &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "deploy", UID: "deploy-uid"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: pointer.Int32(1),
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "deploy"}},
+			},
+		},
+	}
+
+	// newAutoPool returns the auto-created IPPool of the Deployment from the
+	// SpiderSubnet for the NIC.
+	newAutoPool := func(nic string) *spiderpoolv1.SpiderIPPool {
+		pool := &spiderpoolv1.SpiderIPPool{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "auto-pool-" + nic,
+				Labels: map[string]string{
+					constant.LabelIPPoolOwnerApplicationUID: string(podController.UID),
+					constant.LabelIPPoolVersion:             constant.LabelIPPoolVersionV4,
+					constant.LabelIPPoolOwnerSpiderSubnet:   "subnet-" + nic,
+					constant.LabelIPPoolOwnerApplication:    subnetmanagercontrollers.AppLabelValue(podController.Kind, podController.Namespace, podController.Name),
+					constant.LabelIPPoolInterface:           nic,
+				},
+			},
+		}
+		pool.Spec.IPVersion = pointer.Int64(constant.IPv4)
+		pool.Spec.Subnet = "172.18.40.0/24"
+		pool.Spec.IPs = []string{"172.18.40.10"}
+		return pool
+	}
+
+	var i *ipam
+	BeforeEach(func() {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newAutoPool("eth0"), newAutoPool("net1")).Build()
+		rIPManager, err := reservedipmanager.NewReservedIPManager(fakeClient)
+		Expect(err).NotTo(HaveOccurred())
+		ipPoolManager, err := ippoolmanager.NewIPPoolManager(ippoolmanager.IPPoolManagerConfig{}, fakeClient, rIPManager)
+		Expect(err).NotTo(HaveOccurred())
+		subnetManager, err := subnetmanager.NewSubnetManager(subnetmanager.SubnetManagerConfig{}, fakeClient, ipPoolManager, scheme)
+		Expect(err).NotTo(HaveOccurred())
+
+		i = &ipam{
+			config:        setDefaultsForIPAMConfig(IPAMConfig{EnableIPv4: true, WaitSubnetPoolTimeout: 10 * time.Millisecond}),
+			subnetManager: subnetManager,
+		}
+	})
+
+	newPod := func(subnets string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   metav1.NamespaceDefault,
+			Name:        "deploy-pod",
+			Labels:      map[string]string{"app": "deploy"},
+			Annotations: map[string]string{constant.AnnoSpiderSubnets: subnets},
+		}}
+	}
+
+	DescribeTable("selects the auto-created IPPool of the NIC",
+		func(nic string) {
+			pod := newPod(`[{"interface":"eth0","ipv4":["subnet-eth0"]},{"interface":"net1","ipv4":["subnet-net1"]}]`)
+
+			t, err := i.getPoolFromSubnetAnno(context.TODO(), pod, nic, false, podController)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t.NIC).To(Equal(nic))
+			Expect(t.PoolCandidates).To(HaveLen(1))
+			Expect(t.PoolCandidates[0].IPVersion).To(Equal(constant.IPv4))
+			Expect(t.PoolCandidates[0].Pools).To(Equal([]string{"auto-pool-" + nic}))
+		},
+		Entry("the first NIC", "eth0"),
+		Entry("the second NIC", "net1"),
+	)
+
+	It("leaves the NIC not listed to the other ways of selecting the IPPools", func() {
+		pod := newPod(`[{"interface":"eth0","ipv4":["subnet-eth0"]}]`)
+
+		t, err := i.getPoolFromSubnetAnno(context.TODO(), pod, "net1", false, podController)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(t).To(BeNil())
+	})
+
+	It("refuses the SpiderSubnets missing the NIC", func() {
+		pod := newPod(`[{"interface":"eth0","ipv4":["subnet-eth0"]},{"ipv4":["subnet-net1"]}]`)
+
+		_, err := i.getPoolFromSubnetAnno(context.TODO(), pod, "eth0", false, podController)
+		Expect(err).To(MatchError(ContainSubstring("empty interface name")))
+	})
+})
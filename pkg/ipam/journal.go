@@ -0,0 +1,275 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/limiter"
+	"github.com/spidernet-io/spiderpool/pkg/lock"
+	"github.com/spidernet-io/spiderpool/pkg/metric"
+)
+
+// ReleaseIntent records a CNI DEL request which could not be completed
+// because the API server was unreachable or busy at that time.
+type ReleaseIntent struct {
+	PodNamespace string    `json:"podNamespace"`
+	PodName      string    `json:"podName"`
+	ContainerID  string    `json:"containerID"`
+	NIC          string    `json:"nic"`
+	CreationTime time.Time `json:"creationTime"`
+
+	// Attempts is the number of failed replays, the next replay is
+	// postponed until NextReplayTime with exponential backoff.
+	Attempts       int       `json:"attempts,omitempty"`
+	NextReplayTime time.Time `json:"nextReplayTime"`
+}
+
+func (r ReleaseIntent) key() string {
+	return r.PodNamespace + "/" + r.PodName + "/" + r.ContainerID + "/" + r.NIC
+}
+
+// errReleaseJournalFull is returned when appending to the journal which
+// holds as many intents as its max size.
+var errReleaseJournalFull = errors.New("release journal is full")
+
+// releaseJournal is a node-local write-ahead journal persisting release
+// intents to a file, so that they survive the restart of spiderpool-agent
+// and can be replayed once the API server is reachable again.
+type releaseJournal struct {
+	lock    lock.Mutex
+	path    string
+	maxSize int
+	intents map[string]ReleaseIntent
+}
+
+// newReleaseJournal loads the journal from path, a non-positive maxSize
+// leaves the journal unbounded.
+func newReleaseJournal(path string, maxSize int) (*releaseJournal, error) {
+	j := &releaseJournal{
+		path:    path,
+		maxSize: maxSize,
+		intents: map[string]ReleaseIntent{},
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return j, nil
+		}
+		return nil, fmt.Errorf("failed to read release journal %s: %v", path, err)
+	}
+
+	if len(data) == 0 {
+		return j, nil
+	}
+
+	var intents []ReleaseIntent
+	if err := json.Unmarshal(data, &intents); err != nil {
+		return nil, fmt.Errorf("failed to parse release journal %s: %v", path, err)
+	}
+	for _, intent := range intents {
+		j.intents[intent.key()] = intent
+	}
+	j.recordDepth()
+
+	return j, nil
+}
+
+// Append persists the release intent, it is a no-op if the same intent
+// has been recorded. The intent is rejected with errReleaseJournalFull if
+// the journal is full, the caller fails the release rather than evicting
+// the older intents, whose IP addresses would be leaked otherwise.
+func (j *releaseJournal) Append(intent ReleaseIntent) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if _, ok := j.intents[intent.key()]; ok {
+		return nil
+	}
+	if j.maxSize > 0 && len(j.intents) >= j.maxSize {
+		return fmt.Errorf("%w with %d intents", errReleaseJournalFull, len(j.intents))
+	}
+
+	j.intents[intent.key()] = intent
+	if err := j.flush(); err != nil {
+		delete(j.intents, intent.key())
+		return err
+	}
+	j.recordDepth()
+
+	return nil
+}
+
+// Update persists the changes of the release intent, it is a no-op if the
+// intent has been removed.
+func (j *releaseJournal) Update(intent ReleaseIntent) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	old, ok := j.intents[intent.key()]
+	if !ok {
+		return nil
+	}
+
+	j.intents[intent.key()] = intent
+	if err := j.flush(); err != nil {
+		j.intents[intent.key()] = old
+		return err
+	}
+
+	return nil
+}
+
+// Remove deletes the release intent from the journal.
+func (j *releaseJournal) Remove(intent ReleaseIntent) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if _, ok := j.intents[intent.key()]; !ok {
+		return nil
+	}
+
+	delete(j.intents, intent.key())
+	j.recordDepth()
+
+	return j.flush()
+}
+
+// List returns a snapshot of all release intents in the journal.
+func (j *releaseJournal) List() []ReleaseIntent {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	intents := make([]ReleaseIntent, 0, len(j.intents))
+	for _, intent := range j.intents {
+		intents = append(intents, intent)
+	}
+
+	return intents
+}
+
+func (j *releaseJournal) recordDepth() {
+	metric.IpamReleaseJournalDepth.Record(int64(len(j.intents)))
+}
+
+// flush writes all intents to a temporary file and renames it to the
+// journal path, so that a crash never leaves a truncated journal behind.
+func (j *releaseJournal) flush() error {
+	intents := make([]ReleaseIntent, 0, len(j.intents))
+	for _, intent := range j.intents {
+		intents = append(intents, intent)
+	}
+
+	data, err := json.Marshal(intents)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return fmt.Errorf("failed to create the directory of release journal: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary release journal: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write release journal: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync release journal: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), j.path)
+}
+
+// isReleaseRetriable reports whether the failed release is worth replaying
+// later, because the API server is unreachable or busy, or the IPPools are
+// under contention.
+func isReleaseRetriable(err error) bool {
+	if isAPIServerUnreachable(err) {
+		return true
+	}
+
+	return apierrors.IsConflict(err) || errors.Is(err, constant.ErrRetriesExhausted) ||
+		errors.Is(err, limiter.ErrFullQueue) || errors.Is(err, limiter.ErrQueueTimeout)
+}
+
+// isReleasePermanentFailure reports whether the failed release never
+// succeeds however many times it is replayed, because the objects it refers
+// to are gone or the request is rejected by the API server.
+func isReleasePermanentFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var agg utilerrors.Aggregate
+	if errors.As(err, &agg) {
+		for _, e := range agg.Errors() {
+			if !isReleasePermanentFailure(e) {
+				return false
+			}
+		}
+		return len(agg.Errors()) > 0
+	}
+
+	return apierrors.IsNotFound(err) || apierrors.IsInvalid(err)
+}
+
+// isAPIServerUnreachable reports whether the error is caused by the API
+// server being unavailable, rather than the request itself. Only refused
+// connections, timeouts and DNS failures count on the network level, the
+// other network errors may well be caused by the request.
+func isAPIServerUnreachable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var agg utilerrors.Aggregate
+	if errors.As(err, &agg) {
+		for _, e := range agg.Errors() {
+			if isAPIServerUnreachable(e) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) ||
+		apierrors.IsServiceUnavailable(err) || apierrors.IsTooManyRequests(err) {
+		return true
+	}
+
+	if utilnet.IsConnectionRefused(err) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,404 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/utils/pointer"
+
+	"github.com/spidernet-io/spiderpool/api/v1/agent/models"
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
+)
+
+// timeoutError is a net.Error timing out.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// fakeEndpointManager fails to get the Endpoints of the Pods in errs, the
+// other Endpoints are not found.
+type fakeEndpointManager struct {
+	workloadendpointmanager.WorkloadEndpointManager
+
+	errs map[string]error
+	gets []string
+}
+
+func (f *fakeEndpointManager) GetCachedEndpointByName(ctx context.Context, namespace, podName string) (*spiderpoolv1.SpiderEndpoint, error) {
+	f.gets = append(f.gets, podName)
+	if err, ok := f.errs[podName]; ok {
+		return nil, err
+	}
+
+	return nil, apierrors.NewNotFound(spiderpoolv1.Resource(constant.SpiderEndpointKind), podName)
+}
+
+var _ = Describe("release journal", Label("journal_test"), func() {
+	newIntent := func(podName string) ReleaseIntent {
+		return ReleaseIntent{
+			PodNamespace: "default",
+			PodName:      podName,
+			ContainerID:  podName + "-sandbox",
+			NIC:          constant.ClusterDefaultInterfaceName,
+		}
+	}
+	podNames := func(intents []ReleaseIntent) []string {
+		var names []string
+		for _, intent := range intents {
+			names = append(names, intent.PodName)
+		}
+		return names
+	}
+
+	var path string
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "journal", "release.json")
+	})
+
+	Describe("releaseJournal", func() {
+		It("starts empty without the journal file", func() {
+			j, err := newReleaseJournal(path, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(j.List()).To(BeEmpty())
+		})
+
+		It("starts empty with an empty journal file", func() {
+			Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+			Expect(os.WriteFile(path, nil, 0o644)).To(Succeed())
+
+			j, err := newReleaseJournal(path, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(j.List()).To(BeEmpty())
+		})
+
+		It("fails to load the corrupt journal file", func() {
+			Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+			Expect(os.WriteFile(path, []byte(`[{"podName":`), 0o644)).To(Succeed())
+
+			_, err := newReleaseJournal(path, 0)
+			Expect(err).To(MatchError(ContainSubstring("failed to parse release journal")))
+		})
+
+		It("persists the intents across restarts", func() {
+			j, err := newReleaseJournal(path, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(j.Append(newIntent("pod0"))).To(Succeed())
+			Expect(j.Append(newIntent("pod1"))).To(Succeed())
+			Expect(j.Append(newIntent("pod1"))).To(Succeed())
+
+			Expect(j.Remove(newIntent("pod1"))).To(Succeed())
+
+			restarted, err := newReleaseJournal(path, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(podNames(restarted.List())).To(ConsistOf("pod0"))
+		})
+
+		It("drops the intent failed to be persisted", func() {
+			j, err := newReleaseJournal(path, 0)
+			Expect(err).NotTo(HaveOccurred())
+			// The directory of the journal is taken by a regular file.
+			Expect(os.WriteFile(filepath.Dir(path), nil, 0o644)).To(Succeed())
+
+			Expect(j.Append(newIntent("pod0"))).NotTo(Succeed())
+			Expect(j.List()).To(BeEmpty())
+		})
+
+		It("rejects the intents beyond the max size", func() {
+			j, err := newReleaseJournal(path, 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(j.Append(newIntent("pod0"))).To(Succeed())
+			Expect(j.Append(newIntent("pod0"))).To(Succeed())
+			Expect(j.Append(newIntent("pod1"))).To(MatchError(errReleaseJournalFull))
+			Expect(podNames(j.List())).To(ConsistOf("pod0"))
+
+			Expect(j.Remove(newIntent("pod0"))).To(Succeed())
+			Expect(j.Append(newIntent("pod1"))).To(Succeed())
+		})
+
+		It("leaves no temporary file behind", func() {
+			j, err := newReleaseJournal(path, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(j.Append(newIntent("pod0"))).To(Succeed())
+
+			entries, err := os.ReadDir(filepath.Dir(path))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Name()).To(Equal(filepath.Base(path)))
+		})
+	})
+
+	DescribeTable("isAPIServerUnreachable",
+		func(err error, unreachable bool) {
+			Expect(isAPIServerUnreachable(err)).To(Equal(unreachable))
+		},
+		Entry("API server unavailable", apierrors.NewServiceUnavailable("unavailable"), true),
+		Entry("throttled", apierrors.NewTooManyRequests("throttled", 1), true),
+		Entry("connection refused", fmt.Errorf("failed to get Endpoint: %w", syscall.ECONNREFUSED), true),
+		Entry("aggregated timeout", utilerrors.NewAggregate([]error{errors.New("bad request"), apierrors.NewTimeoutError("timeout", 1)}), true),
+		Entry("not found", apierrors.NewNotFound(schema.GroupResource{}, "pool"), false),
+		Entry("dial timeout", &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}, true),
+		Entry("DNS failure", fmt.Errorf("failed to get Endpoint: %w", &net.DNSError{Err: "no such host", Name: "kubernetes.default"}), true),
+		Entry("deadline exceeded", fmt.Errorf("failed to get Endpoint: %w", context.DeadlineExceeded), true),
+		Entry("connection reset", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, false),
+		Entry("invalid address", &net.AddrError{Err: "missing port in address", Addr: "10.0.0.1"}, false),
+	)
+
+	DescribeTable("isReleasePermanentFailure",
+		func(err error, permanent bool) {
+			Expect(isReleasePermanentFailure(err)).To(Equal(permanent))
+		},
+		Entry("no error", nil, false),
+		Entry("IPPool not found", fmt.Errorf("failed to release: %w", apierrors.NewNotFound(schema.GroupResource{}, "pool")), true),
+		Entry("invalid", apierrors.NewInvalid(schema.GroupKind{}, "pool", nil), true),
+		Entry("conflict", apierrors.NewConflict(schema.GroupResource{}, "pool", errors.New("conflict")), false),
+		Entry("all aggregated not found", utilerrors.NewAggregate([]error{
+			apierrors.NewNotFound(schema.GroupResource{}, "pool0"),
+			apierrors.NewNotFound(schema.GroupResource{}, "pool1"),
+		}), true),
+		Entry("partly aggregated not found", utilerrors.NewAggregate([]error{
+			apierrors.NewNotFound(schema.GroupResource{}, "pool0"),
+			apierrors.NewServiceUnavailable("unavailable"),
+		}), false),
+	)
+
+	Describe("replay", func() {
+		var i *ipam
+		var endpointManager *fakeEndpointManager
+		BeforeEach(func() {
+			j, err := newReleaseJournal(path, 0)
+			Expect(err).NotTo(HaveOccurred())
+			endpointManager = &fakeEndpointManager{errs: map[string]error{}}
+			i = &ipam{
+				config: setDefaultsForIPAMConfig(IPAMConfig{
+					ReleaseJournalPath:           path,
+					ReleaseJournalReplayDuration: 10 * time.Second,
+					ReleaseJournalMaxBackoff:     time.Minute,
+				}),
+				journal:         j,
+				endpointManager: endpointManager,
+			}
+		})
+
+		appendIntent := func(podName string, nextReplayTime time.Time) {
+			intent := newIntent(podName)
+			intent.NextReplayTime = nextReplayTime
+			Expect(i.journal.Append(intent)).To(Succeed())
+		}
+		getIntent := func(podName string) ReleaseIntent {
+			for _, intent := range i.journal.List() {
+				if intent.PodName == podName {
+					return intent
+				}
+			}
+			Fail(fmt.Sprintf("no release intent of pod %s", podName))
+			return ReleaseIntent{}
+		}
+
+		It("journals the release failed due to unreachable API server", func() {
+			endpointManager.errs["pod0"] = apierrors.NewServiceUnavailable("unavailable")
+			err := i.Release(context.TODO(), &models.IpamDelArgs{
+				PodNamespace: pointer.String("default"),
+				PodName:      pointer.String("pod0"),
+				ContainerID:  pointer.String("pod0-sandbox"),
+				IfName:       pointer.String(constant.ClusterDefaultInterfaceName),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(podNames(i.journal.List())).To(ConsistOf("pod0"))
+		})
+
+		It("does not journal the release failed due to the request itself", func() {
+			endpointManager.errs["pod0"] = errors.New("bad request")
+			err := i.Release(context.TODO(), &models.IpamDelArgs{
+				PodNamespace: pointer.String("default"),
+				PodName:      pointer.String("pod0"),
+				ContainerID:  pointer.String("pod0-sandbox"),
+				IfName:       pointer.String(constant.ClusterDefaultInterfaceName),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(i.journal.List()).To(BeEmpty())
+		})
+
+		It("removes the intents replayed successfully", func() {
+			appendIntent("pod0", time.Time{})
+			i.replayReleaseJournal(context.TODO())
+			Expect(endpointManager.gets).To(ConsistOf("pod0"))
+			Expect(i.journal.List()).To(BeEmpty())
+		})
+
+		It("skips the intents which are not due", func() {
+			appendIntent("pod0", time.Now().Add(time.Hour))
+			i.replayReleaseJournal(context.TODO())
+			Expect(endpointManager.gets).To(BeEmpty())
+			Expect(podNames(i.journal.List())).To(ConsistOf("pod0"))
+		})
+
+		It("goes on with the other intents once a replay fails", func() {
+			endpointManager.errs["pod0"] = errors.New("bad request")
+			appendIntent("pod0", time.Time{})
+			appendIntent("pod1", time.Time{})
+			i.replayReleaseJournal(context.TODO())
+			Expect(endpointManager.gets).To(ConsistOf("pod0", "pod1"))
+			Expect(podNames(i.journal.List())).To(ConsistOf("pod0"))
+			Expect(getIntent("pod0").Attempts).To(Equal(1))
+		})
+
+		It("drops the intents failing permanently", func() {
+			endpointManager.errs["pod0"] = apierrors.NewInvalid(schema.GroupKind{}, "pod0", nil)
+			appendIntent("pod0", time.Time{})
+			i.replayReleaseJournal(context.TODO())
+			Expect(endpointManager.gets).To(ConsistOf("pod0"))
+			Expect(i.journal.List()).To(BeEmpty())
+		})
+
+		It("fails the release once the journal is full", func() {
+			j, err := newReleaseJournal(path, 1)
+			Expect(err).NotTo(HaveOccurred())
+			i.journal = j
+			appendIntent("pod0", time.Now().Add(time.Hour))
+
+			endpointManager.errs["pod1"] = apierrors.NewServiceUnavailable("unavailable")
+			err = i.Release(context.TODO(), &models.IpamDelArgs{
+				PodNamespace: pointer.String("default"),
+				PodName:      pointer.String("pod1"),
+				ContainerID:  pointer.String("pod1-sandbox"),
+				IfName:       pointer.String(constant.ClusterDefaultInterfaceName),
+			})
+			Expect(err).To(MatchError(ContainSubstring(errReleaseJournalFull.Error())))
+			Expect(podNames(i.journal.List())).To(ConsistOf("pod0"))
+		})
+
+		It("stops replaying while the API server is still unreachable", func() {
+			endpointManager.errs["pod0"] = apierrors.NewServiceUnavailable("unavailable")
+			endpointManager.errs["pod1"] = apierrors.NewServiceUnavailable("unavailable")
+			appendIntent("pod0", time.Time{})
+			appendIntent("pod1", time.Time{})
+			i.replayReleaseJournal(context.TODO())
+			Expect(endpointManager.gets).To(HaveLen(1))
+			Expect(i.journal.List()).To(HaveLen(2))
+		})
+
+		It("doubles the backoff of the failed intent up to the max", func() {
+			appendIntent("pod0", time.Time{})
+			now := time.Now()
+			var backoffs []time.Duration
+			for n := 0; n < 5; n++ {
+				i.postponeReleaseIntent(context.TODO(), getIntent("pod0"), now)
+				backoffs = append(backoffs, getIntent("pod0").NextReplayTime.Sub(now))
+			}
+			Expect(backoffs).To(Equal([]time.Duration{
+				10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute,
+			}))
+			Expect(getIntent("pod0").Attempts).To(Equal(5))
+		})
+
+		It("does not postpone the removed intent", func() {
+			i.postponeReleaseIntent(context.TODO(), newIntent("pod0"), time.Now())
+			Expect(i.journal.List()).To(BeEmpty())
+		})
+	})
+})
+
+// fakeReleaseIPPoolManager fails to release the IP addresses of the IPPools
+// in errs, and records the IP addresses of each release.
+type fakeReleaseIPPoolManager struct {
+	ippoolmanager.IPPoolManager
+
+	errs     map[string]error
+	releases []map[string][]types.IPAndCID
+}
+
+func (f *fakeReleaseIPPoolManager) ReleaseIPs(ctx context.Context, poolToIPAndCIDs map[string][]types.IPAndCID) ([]ippoolmanager.IPReleaseResult, error) {
+	f.releases = append(f.releases, poolToIPAndCIDs)
+
+	var results []ippoolmanager.IPReleaseResult
+	var errs []error
+	for pool, ipAndCIDs := range poolToIPAndCIDs {
+		err := f.errs[pool]
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, ic := range ipAndCIDs {
+			results = append(results, ippoolmanager.IPReleaseResult{IPPool: pool, IPAndCID: ic, Released: err == nil, Err: err})
+		}
+	}
+
+	return results, utilerrors.NewAggregate(errs)
+}
+
+// nopLimiter never limits the operations.
+type nopLimiter struct{}
+
+func (nopLimiter) AcquireTicket(ctx context.Context, tickets ...string) error { return nil }
+func (nopLimiter) ReleaseTicket(ctx context.Context, tickets ...string)       {}
+func (nopLimiter) Start(ctx context.Context) error                            { return nil }
+func (nopLimiter) Started() bool                                              { return true }
+
+var _ = Describe("release", Label("journal_test"), func() {
+	var i *ipam
+	var ipPoolManager *fakeReleaseIPPoolManager
+	var details []spiderpoolv1.IPAllocationDetail
+	BeforeEach(func() {
+		ipPoolManager = &fakeReleaseIPPoolManager{errs: map[string]error{}}
+		i = &ipam{
+			config:        setDefaultsForIPAMConfig(IPAMConfig{OperationRetries: 2}),
+			ipPoolManager: ipPoolManager,
+			ipamLimiter:   nopLimiter{},
+		}
+		details = []spiderpoolv1.IPAllocationDetail{{
+			NIC:      constant.ClusterDefaultInterfaceName,
+			IPv4:     pointer.String("172.18.40.10/24"),
+			IPv4Pool: pointer.String("v4-pool"),
+			IPv6:     pointer.String("abcd:1234::10/120"),
+			IPv6Pool: pointer.String("v6-pool"),
+		}}
+	})
+
+	It("retries only the IP addresses failed to be released", func() {
+		ipPoolManager.errs["v6-pool"] = fmt.Errorf("%w, conflict", constant.ErrRetriesExhausted)
+
+		err := i.release(context.TODO(), "container", details)
+		Expect(err).To(MatchError(constant.ErrRetriesExhausted))
+		Expect(ipPoolManager.releases).To(HaveLen(3))
+		Expect(ipPoolManager.releases[0]).To(HaveLen(2))
+		for _, r := range ipPoolManager.releases[1:] {
+			Expect(r).To(HaveLen(1))
+			Expect(r).To(HaveKey("v6-pool"))
+		}
+	})
+
+	It("leaves the release failed due to unreachable API server to the journal", func() {
+		ipPoolManager.errs["v6-pool"] = apierrors.NewServiceUnavailable("unavailable")
+
+		err := i.release(context.TODO(), "container", details)
+		Expect(err).To(HaveOccurred())
+		Expect(ipPoolManager.releases).To(HaveLen(1))
+	})
+
+	It("does not retry the release failed due to the request itself", func() {
+		ipPoolManager.errs["v6-pool"] = errors.New("bad request")
+
+		err := i.release(context.TODO(), "container", details)
+		Expect(err).To(HaveOccurred())
+		Expect(ipPoolManager.releases).To(HaveLen(1))
+	})
+})
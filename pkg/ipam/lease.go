@@ -0,0 +1,92 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+// renewLocalIPLeases renews the leases of the current IP allocations of the
+// alive Pods on the local node, so that they are never reclaimed by
+// spiderpool-controller. The IPPools are listed first, and nothing else is
+// read from the API server unless some IP allocations on the local node are
+// due for renewal.
+func (i *ipam) renewLocalIPLeases(ctx context.Context) {
+	logger := logutils.Logger.Named("IPAM").With(zap.String("Action", "RenewLocalIPLeases"))
+
+	ipPoolList, err := i.ipPoolManager.ListIPPools(ctx)
+	if err != nil {
+		logger.Sugar().Warnf("Failed to list IPPools: %v", err)
+		return
+	}
+
+	leasedPools := map[string]*spiderpoolv1.SpiderIPPool{}
+	now := time.Now()
+	for j := range ipPoolList.Items {
+		pool := &ipPoolList.Items[j]
+		for _, allocation := range pool.Status.AllocatedIPs {
+			if allocation.Node == i.config.NodeName && ippoolmanager.IsIPAllocationLeaseDue(pool, allocation, now) {
+				leasedPools[pool.Name] = pool
+				break
+			}
+		}
+	}
+	if len(leasedPools) == 0 {
+		return
+	}
+
+	endpoints, err := i.endpointManager.ListEndpointsByNode(ctx, i.config.NodeName)
+	if err != nil {
+		logger.Sugar().Warnf("Failed to list Endpoints: %v", err)
+		return
+	}
+
+	pics := PoolNameToIPAndCIDs{}
+	for _, endpoint := range endpoints {
+		current := endpoint.Status.Current
+		if current == nil || current.Node == nil || *current.Node != i.config.NodeName || len(current.IPs) == 0 {
+			continue
+		}
+
+		due := PoolNameToIPAndCIDs{}
+		for pool, ics := range GroupIPDetails(current.ContainerID, *current.Node, current.IPs) {
+			if ipPool, ok := leasedPools[pool]; ok {
+				for _, ic := range ics {
+					allocation, ok := ipPool.Status.AllocatedIPs[ic.IP]
+					if ok && allocation.ContainerID == ic.ContainerID && ippoolmanager.IsIPAllocationLeaseDue(ipPool, allocation, now) {
+						due[pool] = append(due[pool], ic)
+					}
+				}
+			}
+		}
+		if len(due) == 0 {
+			continue
+		}
+
+		if _, err := i.podManager.GetPodByName(ctx, endpoint.Namespace, endpoint.Name); err != nil {
+			if !apierrors.IsNotFound(err) {
+				logger.Sugar().Warnf("Failed to get Pod %s/%s: %v", endpoint.Namespace, endpoint.Name, err)
+			}
+			continue
+		}
+
+		for pool, ics := range due {
+			pics[pool] = append(pics[pool], ics...)
+		}
+	}
+
+	for pool, ics := range pics {
+		if err := i.ipPoolManager.RenewIPLeases(ctx, pool, ics); err != nil && !apierrors.IsNotFound(err) {
+			logger.Sugar().Warnf("Failed to renew the IP leases of IPPool %s: %v", pool, err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/podmanager"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
+)
+
+// readCountingClient counts the Endpoint Lists and the Pod GETs.
+type readCountingClient struct {
+	client.Client
+	endpointLists int64
+	podGets       int64
+}
+
+func (c *readCountingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if _, ok := obj.(*corev1.Pod); ok {
+		atomic.AddInt64(&c.podGets, 1)
+	}
+
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func (c *readCountingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if _, ok := list.(*spiderpoolv1.SpiderEndpointList); ok {
+		atomic.AddInt64(&c.endpointLists, 1)
+	}
+
+	return c.Client.List(ctx, list, opts...)
+}
+
+var _ = Describe("renewLocalIPLeases", Label("lease_test"), func() {
+	const nodeName = "node1"
+	const ip = "172.18.40.10"
+
+	var i *ipam
+	var countingClient *readCountingClient
+	var poolT *spiderpoolv1.SpiderIPPool
+	var endpointT *spiderpoolv1.SpiderEndpoint
+	var podT *corev1.Pod
+
+	BeforeEach(func() {
+		poolT = &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: "pool"}}
+		poolT.Spec.Subnet = "172.18.40.0/24"
+		poolT.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{
+			ip: {ContainerID: "container", NIC: "eth0", Node: nodeName, Namespace: "default", Pod: "pod"},
+		}
+
+		endpointT = &spiderpoolv1.SpiderEndpoint{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod"}}
+		endpointT.Status.Current = &spiderpoolv1.PodIPAllocation{
+			ContainerID: "container",
+			Node:        pointer.String(nodeName),
+			IPs:         []spiderpoolv1.IPAllocationDetail{{NIC: "eth0", IPv4: pointer.String(ip + "/24"), IPv4Pool: pointer.String(poolT.Name)}},
+		}
+
+		podT = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod"}}
+	})
+
+	setup := func() {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(poolT, endpointT, podT).Build()
+		countingClient = &readCountingClient{Client: fakeClient}
+
+		rIPManager, err := reservedipmanager.NewReservedIPManager(countingClient)
+		Expect(err).NotTo(HaveOccurred())
+		ipPoolManager, err := ippoolmanager.NewIPPoolManager(ippoolmanager.IPPoolManagerConfig{}, countingClient, rIPManager)
+		Expect(err).NotTo(HaveOccurred())
+		endpointManager, err := workloadendpointmanager.NewWorkloadEndpointManager(workloadendpointmanager.EndpointManagerConfig{}, countingClient)
+		Expect(err).NotTo(HaveOccurred())
+		podManager, err := podmanager.NewPodManager(podmanager.PodManagerConfig{}, countingClient)
+		Expect(err).NotTo(HaveOccurred())
+
+		i = &ipam{
+			config:          IPAMConfig{NodeName: nodeName},
+			ipPoolManager:   ipPoolManager,
+			endpointManager: endpointManager,
+			podManager:      podManager,
+		}
+	}
+
+	leaseRenewTime := func() *metav1.Time {
+		var pool spiderpoolv1.SpiderIPPool
+		err := countingClient.Client.Get(context.TODO(), client.ObjectKeyFromObject(poolT), &pool)
+		Expect(err).NotTo(HaveOccurred())
+		return pool.Status.AllocatedIPs[ip].LeaseRenewTime
+	}
+
+	It("reads nothing but the IPPools if none of them has leases", func() {
+		setup()
+
+		i.renewLocalIPLeases(context.TODO())
+		Expect(atomic.LoadInt64(&countingClient.endpointLists)).To(BeZero())
+		Expect(atomic.LoadInt64(&countingClient.podGets)).To(BeZero())
+		Expect(leaseRenewTime()).To(BeNil())
+	})
+
+	It("reads nothing but the IPPools if no lease is due", func() {
+		poolT.Spec.LeaseDurationSeconds = pointer.Int64(600)
+		renewTime := metav1.NewTime(time.Now().Add(-time.Minute))
+		allocation := poolT.Status.AllocatedIPs[ip]
+		allocation.LeaseRenewTime = &renewTime
+		poolT.Status.AllocatedIPs[ip] = allocation
+		setup()
+
+		i.renewLocalIPLeases(context.TODO())
+		Expect(atomic.LoadInt64(&countingClient.endpointLists)).To(BeZero())
+		Expect(atomic.LoadInt64(&countingClient.podGets)).To(BeZero())
+	})
+
+	It("renews the due lease of the alive Pod", func() {
+		poolT.Spec.LeaseDurationSeconds = pointer.Int64(600)
+		setup()
+
+		i.renewLocalIPLeases(context.TODO())
+		Expect(atomic.LoadInt64(&countingClient.endpointLists)).To(BeEquivalentTo(1))
+		Expect(atomic.LoadInt64(&countingClient.podGets)).To(BeEquivalentTo(1))
+		Expect(leaseRenewTime()).NotTo(BeNil())
+	})
+
+	It("does not renew the lease of the Pod which no longer exists", func() {
+		poolT.Spec.LeaseDurationSeconds = pointer.Int64(600)
+		setup()
+		err := countingClient.Client.Delete(context.TODO(), podT)
+		Expect(err).NotTo(HaveOccurred())
+
+		i.renewLocalIPLeases(context.TODO())
+		Expect(leaseRenewTime()).To(BeNil())
+	})
+})
@@ -0,0 +1,48 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// neighborProber probes the IP addresses of the subnet with ARP or NDP from
+// the node, see networkscanner.ProbeNeighbors.
+type neighborProber func(ctx context.Context, subnet string, version types.IPVersion, ips []net.IP, timeout time.Duration) ([]net.IP, bool, error)
+
+// neighborProbe is the IP addresses of a subnet to be probed, and the
+// result of the probe.
+type neighborProbe struct {
+	subnet  string
+	version types.IPVersion
+	ips     []net.IP
+
+	// attached is false if the subnet is not attached to the node, whose
+	// neighbors can't be probed.
+	attached bool
+	replied  []net.IP
+	err      error
+}
+
+// probeNeighbors probes the subnets in parallel, so that the probes of all
+// of them are answered within the same deadline.
+func (i *ipam) probeNeighbors(ctx context.Context, probes []*neighborProbe, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, p := range probes {
+		wg.Add(1)
+		go func(p *neighborProbe) {
+			defer wg.Done()
+			p.replied, p.attached, p.err = i.neighborProber(ctx, p.subnet, p.version, p.ips, timeout)
+		}(p)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,239 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/spidernet-io/spiderpool/api/v1/agent/models"
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/namespacemanager"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+var _ = Describe("getPoolCandidates", Label("pool_candidate_test"), func() {
+	var i *ipam
+	var pod *corev1.Pod
+	var addArgs *models.IpamAddArgs
+	BeforeEach(func() {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: "v4-pool"}},
+			&spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: "v6-pool"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: metav1.NamespaceDefault}},
+		).Build()
+		rIPManager, err := reservedipmanager.NewReservedIPManager(fakeClient)
+		Expect(err).NotTo(HaveOccurred())
+		ipPoolManager, err := ippoolmanager.NewIPPoolManager(ippoolmanager.IPPoolManagerConfig{}, fakeClient, rIPManager)
+		Expect(err).NotTo(HaveOccurred())
+		nsManager, err := namespacemanager.NewNamespaceManager(fakeClient)
+		Expect(err).NotTo(HaveOccurred())
+
+		i = &ipam{
+			config: IPAMConfig{
+				EnableIPv4:               true,
+				EnableIPv6:               true,
+				ClusterDefaultIPv4IPPool: []string{"default-v4-pool"},
+				ClusterDefaultIPv6IPPool: []string{"default-v6-pool"},
+			},
+			ipPoolManager: ipPoolManager,
+			nsManager:     nsManager,
+		}
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   metav1.NamespaceDefault,
+				Name:        "pod",
+				Annotations: map[string]string{},
+			},
+		}
+		addArgs = &models.IpamAddArgs{IfName: pointer.String(constant.ClusterDefaultInterfaceName)}
+	})
+
+	poolsOf := func(tt ToBeAllocateds) []string {
+		var pools []string
+		for _, t := range tt {
+			for _, c := range t.PoolCandidates {
+				pools = append(pools, c.Pools...)
+			}
+		}
+		return pools
+	}
+
+	It("selects the annotated IPPools", func() {
+		pod.Annotations[constant.AnnoPodIPPool] = `{"ipv4":["v4-pool"],"ipv6":["v6-pool"]}`
+		tt, fallback, err := i.getPoolCandidates(context.TODO(), addArgs, pod, types.PodTopController{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fallback).To(BeFalse())
+		Expect(poolsOf(tt)).To(ConsistOf("v4-pool", "v6-pool"))
+		Expect(tt[0].Source).To(Equal(SourceIPPoolAnnotation))
+	})
+
+	It("keeps the nonexistent annotated IPPools without the fallback", func() {
+		pod.Annotations[constant.AnnoPodIPPool] = `{"ipv4":["nonexistent-pool"],"ipv6":["v6-pool"]}`
+		tt, fallback, err := i.getPoolCandidates(context.TODO(), addArgs, pod, types.PodTopController{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fallback).To(BeFalse())
+		Expect(poolsOf(tt)).To(ConsistOf("nonexistent-pool", "v6-pool"))
+	})
+
+	Context("with the fallback", func() {
+		BeforeEach(func() {
+			i.config.EnableAnnotatedPoolFallback = true
+		})
+
+		It("keeps the annotated IPPools which all exist", func() {
+			pod.Annotations[constant.AnnoPodIPPool] = `{"ipv4":["v4-pool"],"ipv6":["v6-pool"]}`
+			tt, fallback, err := i.getPoolCandidates(context.TODO(), addArgs, pod, types.PodTopController{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fallback).To(BeFalse())
+			Expect(poolsOf(tt)).To(ConsistOf("v4-pool", "v6-pool"))
+		})
+
+		It("falls back to the default IPPools only for the IP version whose annotated IPPools do not exist", func() {
+			pod.Annotations[constant.AnnoPodIPPool] = `{"ipv4":["nonexistent-pool"],"ipv6":["v6-pool"]}`
+			tt, fallback, err := i.getPoolCandidates(context.TODO(), addArgs, pod, types.PodTopController{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fallback).To(BeTrue())
+			Expect(poolsOf(tt)).To(ConsistOf("default-v4-pool", "v6-pool"))
+			Expect(tt[0].Source).To(Equal(SourceIPPoolAnnotation))
+		})
+
+		It("skips the nonexistent annotated IPPools if the candidate has others", func() {
+			pod.Annotations[constant.AnnoPodIPPool] = `{"ipv4":["nonexistent-pool","v4-pool"]}`
+			tt, fallback, err := i.getPoolCandidates(context.TODO(), addArgs, pod, types.PodTopController{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fallback).To(BeFalse())
+			Expect(poolsOf(tt)).To(ConsistOf("v4-pool"))
+		})
+
+		It("falls back per NIC and keeps the other NICs of the multiple NICs annotation", func() {
+			pod.Annotations[constant.AnnoPodIPPools] = `[
+				{"interface":"eth0","ipv4":["v4-pool"],"ipv6":["v6-pool"]},
+				{"interface":"net1","ipv4":["nonexistent-pool"],"ipv6":["v6-pool"],"cleangateway":true}
+			]`
+			tt, fallback, err := i.getPoolCandidates(context.TODO(), addArgs, pod, types.PodTopController{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fallback).To(BeTrue())
+			Expect(tt).To(HaveLen(2))
+
+			Expect(tt[0].NIC).To(Equal("eth0"))
+			Expect(tt[0].CleanGateway).To(BeFalse())
+			Expect(tt[0].Pools()).To(ConsistOf("v4-pool", "v6-pool"))
+
+			Expect(tt[1].NIC).To(Equal("net1"))
+			Expect(tt[1].CleanGateway).To(BeTrue())
+			Expect(tt[1].Pools()).To(ConsistOf("default-v4-pool", "v6-pool"))
+		})
+
+		It("fails if there are no default IPPools of the IP version to fall back to", func() {
+			i.config.ClusterDefaultIPv6IPPool = nil
+			pod.Annotations[constant.AnnoPodIPPool] = `{"ipv4":["v4-pool"],"ipv6":["nonexistent-pool"]}`
+			_, _, err := i.getPoolCandidates(context.TODO(), addArgs, pod, types.PodTopController{})
+			Expect(err).To(MatchError(constant.ErrNoAvailablePool))
+		})
+	})
+})
+
+var _ = Describe("getPodTenant", Label("pool_candidate_test"), func() {
+	var i *ipam
+	var pod *corev1.Pod
+	BeforeEach(func() {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:        "tenant-a-ns",
+				Annotations: map[string]string{constant.AnnoTenant: "tenant-a"},
+			}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: metav1.NamespaceDefault}},
+		).Build()
+		nsManager, err := namespacemanager.NewNamespaceManager(fakeClient)
+		Expect(err).NotTo(HaveOccurred())
+
+		i = &ipam{nsManager: nsManager}
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "tenant-a-ns",
+				Name:        "pod",
+				Annotations: map[string]string{},
+			},
+		}
+	})
+
+	It("takes the tenant of the Namespace", func() {
+		tenant, err := i.getPodTenant(context.TODO(), pod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tenant).To(Equal("tenant-a"))
+	})
+
+	It("accepts the Pod annotation repeating the tenant of the Namespace", func() {
+		pod.Annotations[constant.AnnoTenant] = "tenant-a"
+		tenant, err := i.getPodTenant(context.TODO(), pod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tenant).To(Equal("tenant-a"))
+	})
+
+	It("rejects the Pod annotation claiming another tenant", func() {
+		pod.Annotations[constant.AnnoTenant] = "tenant-b"
+		_, err := i.getPodTenant(context.TODO(), pod)
+		Expect(err).To(MatchError(constant.ErrWrongInput))
+	})
+
+	It("rejects the Pod annotation claiming a tenant in the Namespace without any", func() {
+		pod.Namespace = metav1.NamespaceDefault
+		pod.Annotations[constant.AnnoTenant] = "tenant-a"
+		_, err := i.getPodTenant(context.TODO(), pod)
+		Expect(err).To(MatchError(constant.ErrWrongInput))
+	})
+})
+
+var _ = Describe("orderPoolCandidatesByUtilization", Label("pool_candidate_test"), func() {
+	newIPPool := func(name string, total int64, allocated int) *spiderpoolv1.SpiderIPPool {
+		pool := &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		pool.Status.TotalIPCount = pointer.Int64(total)
+		pool.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{}
+		for n := 0; n < allocated; n++ {
+			pool.Status.AllocatedIPs[fmt.Sprintf("172.18.40.%d", n)] = spiderpoolv1.PoolIPAllocation{}
+		}
+		return pool
+	}
+
+	newCandidate := func(pools ...*spiderpoolv1.SpiderIPPool) *PoolCandidate {
+		c := &PoolCandidate{IPVersion: constant.IPv4, PToIPPool: PoolNameToIPPool{}}
+		for _, pool := range pools {
+			c.Pools = append(c.Pools, pool.Name)
+			c.PToIPPool[pool.Name] = pool
+		}
+		return c
+	}
+
+	It("tries the IPPools with the highest ratio of free IP addresses first", func() {
+		c := newCandidate(newIPPool("busy", 10, 8), newIPPool("idle", 10, 1), newIPPool("half", 4, 2))
+		orderPoolCandidatesByUtilization(ToBeAllocateds{{PoolCandidates: []*PoolCandidate{c}}})
+		Expect(c.Pools).To(Equal([]string{"idle", "half", "busy"}))
+	})
+
+	It("keeps the declared order of the IPPools with the same ratio", func() {
+		c := newCandidate(newIPPool("pool-b", 10, 5), newIPPool("pool-a", 2, 1), newIPPool("pool-c", 4, 0))
+		orderPoolCandidatesByUtilization(ToBeAllocateds{{PoolCandidates: []*PoolCandidate{c}}})
+		Expect(c.Pools).To(Equal([]string{"pool-c", "pool-b", "pool-a"}))
+	})
+
+	It("leaves the shared IPPool names untouched", func() {
+		declared := []string{"busy", "idle"}
+		c := newCandidate(newIPPool("busy", 10, 8), newIPPool("idle", 10, 1))
+		c.Pools = declared
+		orderPoolCandidatesByUtilization(ToBeAllocateds{{PoolCandidates: []*PoolCandidate{c}}})
+		Expect(c.Pools).To(Equal([]string{"idle", "busy"}))
+		Expect(declared).To(Equal([]string{"busy", "idle"}))
+	})
+})
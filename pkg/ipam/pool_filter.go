@@ -0,0 +1,247 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// The names of the built-in IPPool filters, in the order they are run.
+const (
+	FilterTerminating        = "terminating"
+	FilterDisabled           = "disabled"
+	FilterDraining           = "draining"
+	FilterQuarantined        = "quarantined"
+	FilterReshaping          = "reshaping"
+	FilterGatewayUnreachable = "gateway_unreachable"
+	FilterIPVersion          = "ip_version"
+	FilterTenant             = "tenant"
+	FilterAllowedNamespaces  = "allowed_namespaces"
+	FilterExhausted          = "exhausted"
+	FilterNodeAffinity       = "node_affinity"
+	FilterNamespaceAffinity  = "namespace_affinity"
+	FilterPodAffinity        = "pod_affinity"
+)
+
+// PoolFilter is a constraint on the IPPool candidates of the Pod, an error
+// is returned if the IPPool can't allocate IP addresses to the Pod, or a
+// PoolFilterLookupError if the filter can't tell.
+type PoolFilter interface {
+	Name() string
+	Filter(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error
+}
+
+// PoolFilterArgs are what the IPPool candidates are filtered for.
+type PoolFilterArgs struct {
+	IPVersion types.IPVersion
+	Pod       *corev1.Pod
+	Tenant    string
+}
+
+type poolFilterFunc struct {
+	name   string
+	filter func(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error
+}
+
+// NewPoolFilter returns a PoolFilter with the name and the filter function.
+func NewPoolFilter(name string, filter func(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error) PoolFilter {
+	return &poolFilterFunc{
+		name:   name,
+		filter: filter,
+	}
+}
+
+func (f *poolFilterFunc) Name() string {
+	return f.name
+}
+
+func (f *poolFilterFunc) Filter(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error {
+	return f.filter(ctx, ipPool, args)
+}
+
+// PoolFilterError tells which filter eliminates the IPPool.
+type PoolFilterError struct {
+	Filter string
+	Err    error
+}
+
+func (e *PoolFilterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PoolFilterError) Unwrap() error {
+	return e.Err
+}
+
+// PoolFilterLookupError is returned by the filter which fails to look up
+// the resources it filters by, such as the Node of the Pod. The IPPool is
+// not eliminated by it, the IP allocation fails with the error instead.
+type PoolFilterLookupError struct {
+	Filter string
+	Err    error
+}
+
+func (e *PoolFilterLookupError) Error() string {
+	if e.Filter == "" {
+		return e.Err.Error()
+	}
+
+	return fmt.Sprintf("IPPool filter %s: %v", e.Filter, e.Err)
+}
+
+func (e *PoolFilterLookupError) Unwrap() error {
+	return e.Err
+}
+
+// poolFilterChain runs the filters in order, the IPPool is eliminated by
+// the first filter which rejects it.
+type poolFilterChain []PoolFilter
+
+func (c poolFilterChain) filter(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error {
+	for _, f := range c {
+		if err := f.Filter(ctx, ipPool, args); err != nil {
+			var lookupErr *PoolFilterLookupError
+			if errors.As(err, &lookupErr) {
+				return &PoolFilterLookupError{Filter: f.Name(), Err: lookupErr.Err}
+			}
+			return &PoolFilterError{Filter: f.Name(), Err: err}
+		}
+	}
+
+	return nil
+}
+
+// builtinPoolFilters returns the built-in IPPool filters, the cheap ones
+// on the IPPool itself come before the ones querying other resources.
+func (i *ipam) builtinPoolFilters() poolFilterChain {
+	return poolFilterChain{
+		NewPoolFilter(FilterTerminating, func(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error {
+			if ipPool.DeletionTimestamp != nil {
+				return fmt.Errorf("terminating IPPool %s", ipPool.Name)
+			}
+			return nil
+		}),
+		NewPoolFilter(FilterDisabled, func(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error {
+			if *ipPool.Spec.Disable {
+				return fmt.Errorf("disabled IPPool %s", ipPool.Name)
+			}
+			return nil
+		}),
+		NewPoolFilter(FilterDraining, func(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error {
+			if ippoolmanager.IsDrainingIPPool(ipPool) {
+				return fmt.Errorf("draining IPPool %s", ipPool.Name)
+			}
+			return nil
+		}),
+		NewPoolFilter(FilterQuarantined, func(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error {
+			if cond := apimeta.FindStatusCondition(ipPool.Status.Conditions, constant.IPPoolConditionQuarantined); cond != nil && cond.Status == metav1.ConditionTrue {
+				return fmt.Errorf("quarantined IPPool %s: %s", ipPool.Name, cond.Message)
+			}
+			return nil
+		}),
+		NewPoolFilter(FilterReshaping, func(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error {
+			if ippoolmanager.IsReshapingIPPool(ipPool) {
+				return fmt.Errorf("IPPool %s is being split or merged", ipPool.Name)
+			}
+			return nil
+		}),
+		NewPoolFilter(FilterGatewayUnreachable, func(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error {
+			if i.config.SkipGatewayUnreachableIPPools && ippoolmanager.IsGatewayUnreachableIPPool(ipPool) {
+				return fmt.Errorf("the gateway of IPPool %s is unreachable", ipPool.Name)
+			}
+			return nil
+		}),
+		NewPoolFilter(FilterIPVersion, func(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error {
+			if *ipPool.Spec.IPVersion != args.IPVersion {
+				return fmt.Errorf("expect an IPv%d IPPool, but the version of the IPPool %s is IPv%d", args.IPVersion, ipPool.Name, *ipPool.Spec.IPVersion)
+			}
+			return nil
+		}),
+		// Pods in a tenant never see the IPPools of other tenants, even if
+		// they are the cluster default IPPools, since these IPPools may
+		// overlap.
+		NewPoolFilter(FilterTenant, func(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error {
+			if poolTenant := ippoolmanager.GetIPPoolTenant(ipPool); poolTenant != args.Tenant {
+				return fmt.Errorf("expect an IPPool in tenant '%s', but the tenant of the IPPool %s is '%s'", args.Tenant, ipPool.Name, poolTenant)
+			}
+			return nil
+		}),
+		// The allowed Namespaces are enforced for all IPPools as well, so
+		// that the IPPools owned by a team are never drained by the Pods of
+		// others through the default IPPools.
+		NewPoolFilter(FilterAllowedNamespaces, func(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error {
+			if !ippoolmanager.IsNamespaceAllowed(ipPool, args.Pod.Namespace) {
+				return fmt.Errorf("the Pods in Namespace %s are not allowed to use IPPool %s", args.Pod.Namespace, ipPool.Name)
+			}
+			return nil
+		}),
+		NewPoolFilter(FilterExhausted, func(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error {
+			if ipPool.Status.TotalIPCount != nil && ipPool.Status.AllocatedIPCount != nil {
+				if *ipPool.Status.TotalIPCount-*ipPool.Status.AllocatedIPCount <= 0 {
+					return constant.ErrIPUsedOut
+				}
+			}
+			return nil
+		}),
+		NewPoolFilter(FilterNodeAffinity, func(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error {
+			if ipPool.Spec.NodeAffinity == nil {
+				return nil
+			}
+			node, err := i.nodeManager.GetNodeByName(ctx, args.Pod.Spec.NodeName)
+			if err != nil {
+				return &PoolFilterLookupError{Err: fmt.Errorf("failed to get Node %s: %w", args.Pod.Spec.NodeName, err)}
+			}
+			selector, err := metav1.LabelSelectorAsSelector(ipPool.Spec.NodeAffinity)
+			if err != nil {
+				return err
+			}
+			if !selector.Matches(labels.Set(node.Labels)) {
+				return fmt.Errorf("unmatched Node affinity of IPPool %s", ipPool.Name)
+			}
+			return nil
+		}),
+		NewPoolFilter(FilterNamespaceAffinity, func(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error {
+			if ipPool.Spec.NamespaceAffinity == nil {
+				return nil
+			}
+			namespace, err := i.nsManager.GetNamespaceByName(ctx, args.Pod.Namespace)
+			if err != nil {
+				return &PoolFilterLookupError{Err: fmt.Errorf("failed to get Namespace %s: %w", args.Pod.Namespace, err)}
+			}
+			selector, err := metav1.LabelSelectorAsSelector(ipPool.Spec.NamespaceAffinity)
+			if err != nil {
+				return err
+			}
+			if !selector.Matches(labels.Set(namespace.Labels)) {
+				return fmt.Errorf("unmatched Namespace affinity of IPPool %s", ipPool.Name)
+			}
+			return nil
+		}),
+		NewPoolFilter(FilterPodAffinity, func(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) error {
+			if ipPool.Spec.PodAffinity == nil {
+				return nil
+			}
+			selector, err := metav1.LabelSelectorAsSelector(ipPool.Spec.PodAffinity)
+			if err != nil {
+				return err
+			}
+			if !selector.Matches(labels.Set(args.Pod.Labels)) {
+				return fmt.Errorf("unmatched Pod affinity of IPPool %s", ipPool.Name)
+			}
+			return nil
+		}),
+	}
+}
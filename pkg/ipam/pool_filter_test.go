@@ -0,0 +1,185 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/namespacemanager"
+	"github.com/spidernet-io/spiderpool/pkg/nodemanager"
+)
+
+// fakeNodeManager fails to get any Node.
+type fakeNodeManager struct {
+	nodemanager.NodeManager
+}
+
+func (f *fakeNodeManager) GetNodeByName(ctx context.Context, nodeName string) (*corev1.Node, error) {
+	return nil, errors.New("connection refused")
+}
+
+// fakeNamespaceManager fails to get any Namespace.
+type fakeNamespaceManager struct {
+	namespacemanager.NamespaceManager
+}
+
+func (f *fakeNamespaceManager) GetNamespaceByName(ctx context.Context, nsName string) (*corev1.Namespace, error) {
+	return nil, errors.New("connection refused")
+}
+
+var _ = Describe("builtinPoolFilters", Label("pool_filter_test"), func() {
+	var i *ipam
+	var args *PoolFilterArgs
+	BeforeEach(func() {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Labels: map[string]string{"zone": "a"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: metav1.NamespaceDefault, Labels: map[string]string{"team": "a"}}},
+		).Build()
+		nodeManager, err := nodemanager.NewNodeManager(fakeClient)
+		Expect(err).NotTo(HaveOccurred())
+		nsManager, err := namespacemanager.NewNamespaceManager(fakeClient)
+		Expect(err).NotTo(HaveOccurred())
+
+		i = &ipam{
+			nodeManager: nodeManager,
+			nsManager:   nsManager,
+		}
+		i.poolFilters = i.builtinPoolFilters()
+		args = &PoolFilterArgs{
+			IPVersion: constant.IPv4,
+			Pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: metav1.NamespaceDefault,
+					Name:      "pod",
+					Labels:    map[string]string{"app": "a"},
+				},
+				Spec: corev1.PodSpec{NodeName: "node"},
+			},
+		}
+	})
+
+	newIPPool := func() *spiderpoolv1.SpiderIPPool {
+		pool := &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: "pool"}}
+		pool.Spec.IPVersion = pointer.Int64(constant.IPv4)
+		pool.Spec.Disable = pointer.Bool(false)
+		pool.Status.TotalIPCount = pointer.Int64(2)
+		pool.Status.AllocatedIPCount = pointer.Int64(1)
+		return pool
+	}
+
+	It("passes the IPPool matching the Pod", func() {
+		Expect(i.poolFilters.filter(context.TODO(), newIPPool(), args)).To(Succeed())
+	})
+
+	DescribeTable("eliminates the IPPool",
+		func(filter string, mutate func(i *ipam, pool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs)) {
+			pool := newIPPool()
+			mutate(i, pool, args)
+
+			err := i.poolFilters.filter(context.TODO(), pool, args)
+			var filterErr *PoolFilterError
+			Expect(errors.As(err, &filterErr)).To(BeTrue(), "%v", err)
+			Expect(filterErr.Filter).To(Equal(filter))
+		},
+		Entry("being deleted", FilterTerminating, func(i *ipam, pool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) {
+			pool.DeletionTimestamp = &metav1.Time{}
+		}),
+		Entry("disabled", FilterDisabled, func(i *ipam, pool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) {
+			pool.Spec.Disable = pointer.Bool(true)
+		}),
+		Entry("being drained", FilterDraining, func(i *ipam, pool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) {
+			pool.Spec.Drain = pointer.Bool(true)
+		}),
+		Entry("quarantined", FilterQuarantined, func(i *ipam, pool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) {
+			pool.Status.Conditions = []metav1.Condition{{Type: constant.IPPoolConditionQuarantined, Status: metav1.ConditionTrue}}
+		}),
+		Entry("being reshaped", FilterReshaping, func(i *ipam, pool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) {
+			pool.Annotations = map[string]string{constant.AnnoIPPoolReshaping: "split"}
+		}),
+		Entry("whose gateway is unreachable", FilterGatewayUnreachable, func(i *ipam, pool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) {
+			i.config.SkipGatewayUnreachableIPPools = true
+			pool.Status.Conditions = []metav1.Condition{{Type: constant.IPPoolConditionGatewayUnreachable, Status: metav1.ConditionTrue}}
+		}),
+		Entry("of another IP version", FilterIPVersion, func(i *ipam, pool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) {
+			args.IPVersion = constant.IPv6
+		}),
+		Entry("of another tenant", FilterTenant, func(i *ipam, pool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) {
+			pool.Spec.Tenant = pointer.String("b")
+		}),
+		Entry("not allowing the Namespace", FilterAllowedNamespaces, func(i *ipam, pool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) {
+			pool.Spec.AllowedNamespaces = []string{"kube-*"}
+		}),
+		Entry("exhausted", FilterExhausted, func(i *ipam, pool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) {
+			pool.Status.AllocatedIPCount = pointer.Int64(2)
+		}),
+		Entry("not matching the Node", FilterNodeAffinity, func(i *ipam, pool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) {
+			pool.Spec.NodeAffinity = &metav1.LabelSelector{MatchLabels: map[string]string{"zone": "b"}}
+		}),
+		Entry("not matching the Namespace", FilterNamespaceAffinity, func(i *ipam, pool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) {
+			pool.Spec.NamespaceAffinity = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}}
+		}),
+		Entry("not matching the Pod", FilterPodAffinity, func(i *ipam, pool *spiderpoolv1.SpiderIPPool, args *PoolFilterArgs) {
+			pool.Spec.PodAffinity = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "b"}}
+		}),
+	)
+
+	DescribeTable("fails to look up the resources filtered by",
+		func(filter string, mutate func(i *ipam, pool *spiderpoolv1.SpiderIPPool)) {
+			pool := newIPPool()
+			mutate(i, pool)
+
+			err := i.poolFilters.filter(context.TODO(), pool, args)
+			var filterErr *PoolFilterError
+			Expect(errors.As(err, &filterErr)).To(BeFalse())
+			var lookupErr *PoolFilterLookupError
+			Expect(errors.As(err, &lookupErr)).To(BeTrue(), "%v", err)
+			Expect(lookupErr.Filter).To(Equal(filter))
+		},
+		Entry("the Node", FilterNodeAffinity, func(i *ipam, pool *spiderpoolv1.SpiderIPPool) {
+			i.nodeManager = &fakeNodeManager{}
+			pool.Spec.NodeAffinity = &metav1.LabelSelector{MatchLabels: map[string]string{"zone": "a"}}
+		}),
+		Entry("the Namespace", FilterNamespaceAffinity, func(i *ipam, pool *spiderpoolv1.SpiderIPPool) {
+			i.nsManager = &fakeNamespaceManager{}
+			pool.Spec.NamespaceAffinity = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}
+		}),
+	)
+
+	It("fails the IP allocation rather than eliminating the IPPool on lookup failures", func() {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: metav1.NamespaceDefault}},
+		).Build()
+		nsManager, err := namespacemanager.NewNamespaceManager(fakeClient)
+		Expect(err).NotTo(HaveOccurred())
+		i.nsManager = nsManager
+		i.nodeManager = &fakeNodeManager{}
+
+		pool := newIPPool()
+		pool.Spec.NodeAffinity = &metav1.LabelSelector{MatchLabels: map[string]string{"zone": "a"}}
+		tt := ToBeAllocateds{{
+			NIC: constant.ClusterDefaultInterfaceName,
+			PoolCandidates: []*PoolCandidate{{
+				IPVersion: constant.IPv4,
+				Pools:     []string{pool.Name},
+				PToIPPool: PoolNameToIPPool{pool.Name: pool},
+			}},
+		}}
+
+		err = i.filterPoolCandidates(context.TODO(), tt, args.Pod)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, constant.ErrNoAvailablePool)).To(BeFalse())
+		Expect(tt[0].PoolCandidates[0].Pools).To(ConsistOf(pool.Name))
+		Expect(tt[0].PoolCandidates[0].Filtered).To(BeEmpty())
+	})
+})
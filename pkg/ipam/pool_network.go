@@ -0,0 +1,110 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/spidernet-io/spiderpool/api/v1/agent/models"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+// applyIPPoolNetworkConfig switches to the reachable standby gateways of
+// IPPools, which are probed all at once, sets the metric of the default
+// routes via the gateways of IPPools, and returns the DNS of the IPPools
+// which allocate IP addresses to the NIC. The IPPools are got by the names recorded in the IP
+// configurations, so that the retrieved IP allocations follow the latest
+// IPPools as well.
+func (i *ipam) applyIPPoolNetworkConfig(ctx context.Context, nic string, pod *corev1.Pod, addResp *models.IpamAddResponse) {
+	logger := logutils.FromContext(ctx)
+
+	pools := map[string]*spiderpoolv1.SpiderIPPool{}
+	probed := map[string]bool{}
+	var probedPools []*spiderpoolv1.SpiderIPPool
+	for _, ip := range addResp.Ips {
+		if ip.IPPool == "" {
+			continue
+		}
+
+		pool, ok := pools[ip.IPPool]
+		if !ok {
+			var err error
+			pool, err = i.ipPoolManager.GetIPPoolByName(ctx, ip.IPPool)
+			if err != nil {
+				logger.Sugar().Warnf("Failed to get IPPool %s for its DNS and default route metric: %v", ip.IPPool, err)
+			}
+			pools[ip.IPPool] = pool
+		}
+		if pool != nil && !probed[pool.Name] && i.needGatewayProbe(pool, ip.Gateway) {
+			probed[pool.Name] = true
+			probedPools = append(probedPools, pool)
+		}
+	}
+
+	var gateways map[string]string
+	if len(probedPools) != 0 {
+		gateways = i.selectIPPoolGateways(ctx, probedPools, pod)
+	}
+
+	var dns *models.DNS
+	for _, ip := range addResp.Ips {
+		pool := pools[ip.IPPool]
+		if pool == nil {
+			continue
+		}
+
+		if gw, ok := gateways[pool.Name]; ok && gw != ip.Gateway && i.needGatewayProbe(pool, ip.Gateway) {
+			for _, r := range addResp.Routes {
+				if *r.IfName == *ip.Nic && *r.Gw == ip.Gateway {
+					r.Gw = &gw
+				}
+			}
+			ip.Gateway = gw
+		}
+
+		if pool.Spec.DefaultRouteMetric != nil && *pool.Spec.DefaultRouteMetric != 0 && ip.Gateway != "" {
+			for _, r := range addResp.Routes {
+				if isDefaultRoute(r) && *r.IfName == *ip.Nic && *r.Gw == ip.Gateway {
+					r.Metric = *pool.Spec.DefaultRouteMetric
+				}
+			}
+		}
+
+		if *ip.Nic == nic && pool.Spec.DNS != nil {
+			if dns == nil {
+				dns = &models.DNS{}
+			}
+			dns.Nameservers = appendUniqueStrings(dns.Nameservers, pool.Spec.DNS.Nameservers...)
+			dns.Search = appendUniqueStrings(dns.Search, pool.Spec.DNS.Search...)
+		}
+	}
+
+	if dns != nil {
+		addResp.DNS = dns
+	}
+}
+
+func isDefaultRoute(route *models.Route) bool {
+	return *route.Dst == "0.0.0.0/0" || *route.Dst == "::/0"
+}
+
+func appendUniqueStrings(s []string, elems ...string) []string {
+	for _, e := range elems {
+		exist := false
+		for _, v := range s {
+			if v == e {
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			s = append(s, e)
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,279 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+
+	"github.com/spidernet-io/spiderpool/api/v1/agent/models"
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	"github.com/spidernet-io/spiderpool/pkg/namespacemanager"
+	"github.com/spidernet-io/spiderpool/pkg/nodemanager"
+	"github.com/spidernet-io/spiderpool/pkg/podmanager"
+	"github.com/spidernet-io/spiderpool/pkg/singletons"
+	subnetmanagercontrollers "github.com/spidernet-io/spiderpool/pkg/subnetmanager/controllers"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
+)
+
+// PodIPAMPreview tells whether the IP allocation of a Pod would succeed
+// with the current IPPools, Namespaces and Nodes.
+type PodIPAMPreview struct {
+	Allowed bool
+	// Messages explain why the IP allocation would fail, or what is not
+	// previewed.
+	Messages []string
+	Pools    []PoolPreview
+}
+
+// PoolPreview tells whether an IPPool candidate of the Pod could allocate IP
+// addresses, regardless of the Node.
+type PoolPreview struct {
+	NIC       string
+	IPVersion types.IPVersion
+	Pool      string
+	Usable    bool
+	Reason    string
+	// Filter is the name of the PoolFilter eliminating the IPPool.
+	Filter string
+}
+
+// PreviewPodIPAM previews whether the IP allocation of the Pod would succeed
+// with the current IPPools, Namespaces and Nodes. The IPPool candidates are
+// selected and filtered just like the allocation of spiderpool-agent with
+// the config, on each Node where the Pod may run: the Node named by the Pod,
+// or the schedulable Nodes matching its node selector and required node
+// affinity whose taints are tolerated. The IP allocation would succeed if
+// the IPPools of all NICs serve one of the Nodes, within the limit of IP
+// addresses of the workload. The IPPools auto-created from SpiderSubnets
+// and the default IPPools of the CNI network configuration are not
+// previewed.
+func PreviewPodIPAM(ctx context.Context, pod *corev1.Pod, config IPAMConfig, ipPoolManager ippoolmanager.IPPoolManager, endpointManager workloadendpointmanager.WorkloadEndpointManager,
+	nodeManager nodemanager.NodeManager, nsManager namespacemanager.NamespaceManager, podManager podmanager.PodManager) (*PodIPAMPreview, error) {
+	if pod == nil {
+		return nil, fmt.Errorf("pod %w", constant.ErrMissingRequiredParam)
+	}
+
+	i := &ipam{
+		config:          setDefaultsForIPAMConfig(config),
+		ipPoolManager:   ipPoolManager,
+		endpointManager: endpointManager,
+		nodeManager:     nodeManager,
+		nsManager:       nsManager,
+		podManager:      podManager,
+		preview:         true,
+	}
+	// The IPPools are never created or scaled from SpiderSubnets here.
+	i.config.EnableSpiderSubnet = false
+	i.poolFilters = i.builtinPoolFilters()
+
+	preview := &PodIPAMPreview{}
+	if config.EnableSpiderSubnet && hasSubnetAnnotation(pod) {
+		preview.Allowed = true
+		preview.Messages = append(preview.Messages, "IPPools are created from the SpiderSubnets on allocation, which is not previewed")
+		return preview, nil
+	}
+
+	podController, err := podManager.GetPodTopController(ctx, pod)
+	if err != nil {
+		preview.Messages = append(preview.Messages, fmt.Sprintf("failed to get the top controller of the Pod: %v", err))
+		return preview, nil
+	}
+
+	addArgs := &models.IpamAddArgs{IfName: pointer.String(constant.ClusterDefaultInterfaceName)}
+	tt, fallback, err := i.getPoolCandidates(ctx, addArgs, pod, podController)
+	if err != nil {
+		preview.Messages = append(preview.Messages, err.Error())
+		return preview, nil
+	}
+	for _, source := range tt.Sources() {
+		if source == SourceIPPoolAnnotation && !fallback {
+			continue
+		}
+		if config.EnableSpiderSubnet && hasClusterDefaultSubnet(config) {
+			preview.Allowed = true
+			preview.Messages = append(preview.Messages, "IPPools are created from the cluster default SpiderSubnets on allocation, which is not previewed")
+			return preview, nil
+		}
+		if source == SourceClusterDefault {
+			preview.Messages = append(preview.Messages, "The default IPPools of the CNI network configuration are not previewed, they take precedence over the cluster default IPPools if set")
+		}
+	}
+
+	if err := i.precheckPoolCandidates(ctx, tt); err != nil {
+		preview.Messages = append(preview.Messages, err.Error())
+		return preview, nil
+	}
+
+	nodes, err := previewSchedulableNodes(ctx, nodeManager, pod)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		preview.Messages = append(preview.Messages, "No schedulable Node matches the Pod")
+		return preview, nil
+	}
+
+	reports := newPoolPreviews(tt)
+	var served int
+	var firstErr error
+	for _, node := range nodes {
+		nodePod := pod.DeepCopy()
+		nodePod.Spec.NodeName = node.Name
+		nodeTT := tt.copy()
+
+		err := i.filterPoolCandidates(ctx, nodeTT, nodePod)
+		var lookupErr *PoolFilterLookupError
+		if errors.As(err, &lookupErr) {
+			return nil, fmt.Errorf("on Node %s: %w", node.Name, err)
+		}
+		reports.update(nodeTT)
+		if err == nil {
+			err = i.verifyPoolCandidates(nodeTT)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("on Node %s: %w", node.Name, err)
+			}
+			continue
+		}
+		served++
+	}
+	preview.Pools = reports.list()
+
+	if served == 0 {
+		preview.Messages = append(preview.Messages, fmt.Sprintf("No schedulable Node is served by the IPPools of all NICs, such as %v", firstErr))
+		return preview, nil
+	}
+	if err := i.checkWorkloadIPLimit(ctx, pod, podController, tt); err != nil {
+		preview.Messages = append(preview.Messages, err.Error())
+		return preview, nil
+	}
+	preview.Allowed = true
+
+	return preview, nil
+}
+
+func hasSubnetAnnotation(pod *corev1.Pod) bool {
+	for _, anno := range []string{constant.AnnoSpiderSubnet, constant.AnnoSpiderSubnets, constant.AnnoSpiderSubnetDualStack} {
+		if _, ok := pod.Annotations[anno]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasClusterDefaultSubnet reports whether the cluster default SpiderSubnets
+// of all enabled IP versions are set, which take precedence over the other
+// default IPPools.
+func hasClusterDefaultSubnet(config IPAMConfig) bool {
+	if config.EnableIPv4 && len(singletons.ClusterDefaultPool.ClusterDefaultIPv4Subnet) == 0 {
+		return false
+	}
+	if config.EnableIPv6 && len(singletons.ClusterDefaultPool.ClusterDefaultIPv6Subnet) == 0 {
+		return false
+	}
+
+	return true
+}
+
+// poolPreviews are the previews of the IPPool candidates in the order they
+// are selected.
+type poolPreviews []*PoolPreview
+
+func newPoolPreviews(tt ToBeAllocateds) poolPreviews {
+	var previews poolPreviews
+	for _, t := range tt {
+		for _, c := range t.PoolCandidates {
+			for _, pool := range c.Pools {
+				previews = append(previews, &PoolPreview{
+					NIC:       t.NIC,
+					IPVersion: c.IPVersion,
+					Pool:      pool,
+				})
+			}
+		}
+	}
+
+	return previews
+}
+
+// update marks the IPPools left by the filters on a Node as usable, the
+// IPPools never usable keep the reason of the first Node filtering them.
+func (previews poolPreviews) update(tt ToBeAllocateds) {
+	for _, p := range previews {
+		for _, t := range tt {
+			if t.NIC != p.NIC {
+				continue
+			}
+			for _, c := range t.PoolCandidates {
+				if c.IPVersion != p.IPVersion {
+					continue
+				}
+				if err, ok := c.Filtered[p.Pool]; ok {
+					if !p.Usable && p.Reason == "" {
+						p.Reason = err.Error()
+						var filterErr *PoolFilterError
+						if errors.As(err, &filterErr) {
+							p.Filter = filterErr.Filter
+						}
+					}
+					continue
+				}
+				for _, pool := range c.Pools {
+					if pool == p.Pool {
+						p.Usable = true
+						p.Reason = ""
+						p.Filter = ""
+					}
+				}
+			}
+		}
+	}
+}
+
+func (previews poolPreviews) list() []PoolPreview {
+	list := make([]PoolPreview, 0, len(previews))
+	for _, p := range previews {
+		list = append(list, *p)
+	}
+
+	return list
+}
+
+// previewSchedulableNodes returns the Nodes where the Pod may run. It's only
+// the Node named by the Pod if any, which bypasses the scheduler.
+func previewSchedulableNodes(ctx context.Context, nodeManager nodemanager.NodeManager, pod *corev1.Pod) ([]corev1.Node, error) {
+	nodeList, err := nodeManager.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Nodes: %v", err)
+	}
+
+	var nodes []corev1.Node
+	for _, node := range nodeList.Items {
+		if pod.Spec.NodeName != "" {
+			if node.Name == pod.Spec.NodeName {
+				nodes = append(nodes, node)
+			}
+			continue
+		}
+		if node.Spec.Unschedulable || !subnetmanagercontrollers.PodFitsNode(&pod.Spec, &node) {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(a, b int) bool {
+		return nodes[a].Name < nodes[b].Name
+	})
+
+	return nodes, nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/spidernet-io/spiderpool/api/v1/agent/models"
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/namespacemanager"
+	"github.com/spidernet-io/spiderpool/pkg/nodemanager"
+	"github.com/spidernet-io/spiderpool/pkg/podmanager"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
+)
+
+var _ = Describe("PreviewPodIPAM", Label("preview_test"), func() {
+	newIPPool := func(name, zone string) *spiderpoolv1.SpiderIPPool {
+		pool := &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		pool.Spec.IPVersion = pointer.Int64(constant.IPv4)
+		pool.Spec.Subnet = "172.18.40.0/24"
+		pool.Spec.Vlan = pointer.Int64(0)
+		pool.Spec.Disable = pointer.Bool(false)
+		if zone != "" {
+			pool.Spec.NodeAffinity = &metav1.LabelSelector{MatchLabels: map[string]string{"zone": zone}}
+		}
+		return pool
+	}
+
+	// objects are a Node in zone a, a tainted Node in zone b, the IPPools
+	// of either zone, an IPPool whose gateway is unreachable, and a
+	// Deployment with a Pod holding an IP address.
+	objects := func() []client.Object {
+		gatewayUnreachable := newIPPool("pool-gw", "")
+		gatewayUnreachable.Status.Conditions = []metav1.Condition{{
+			Type:   constant.IPPoolConditionGatewayUnreachable,
+			Status: metav1.ConditionTrue,
+		}}
+
+		endpoint := &spiderpoolv1.SpiderEndpoint{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "deploy-pod-0"}}
+		endpoint.Status.OwnerControllerType = constant.KindDeployment
+		endpoint.Status.OwnerControllerName = "deploy"
+		endpoint.Status.Current = &spiderpoolv1.PodIPAllocation{
+			ContainerID: "container",
+			IPs:         []spiderpoolv1.IPAllocationDetail{{NIC: "eth0", IPv4: pointer.String("172.18.40.10/24")}},
+		}
+
+		return []client.Object{
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: metav1.NamespaceDefault}},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: map[string]string{"zone": "a"}}},
+			&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-b", Labels: map[string]string{"zone": "b"}},
+				Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+					{Key: "dedicated", Value: "b", Effect: corev1.TaintEffectNoSchedule},
+				}},
+			},
+			newIPPool("pool-a", "a"),
+			newIPPool("pool-b", "b"),
+			gatewayUnreachable,
+			&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "deploy", UID: "deploy-uid"}},
+			&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+				Namespace: metav1.NamespaceDefault,
+				Name:      "deploy-rs",
+				UID:       "deploy-rs-uid",
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: appsv1.SchemeGroupVersion.String(),
+					Kind:       constant.KindDeployment,
+					Name:       "deploy",
+					UID:        "deploy-uid",
+					Controller: pointer.Bool(true),
+				}},
+			}},
+			endpoint,
+		}
+	}
+
+	newPod := func(pools string, mutate func(pod *corev1.Pod)) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   metav1.NamespaceDefault,
+				Name:        "deploy-pod-1",
+				Annotations: map[string]string{constant.AnnoPodIPPool: `{"ipv4":[` + pools + `]}`},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: appsv1.SchemeGroupVersion.String(),
+					Kind:       constant.KindReplicaSet,
+					Name:       "deploy-rs",
+					UID:        "deploy-rs-uid",
+					Controller: pointer.Bool(true),
+				}},
+			},
+		}
+		if mutate != nil {
+			mutate(pod)
+		}
+		return pod
+	}
+
+	DescribeTable("agrees with the IP allocation on the Nodes where the Pod may run",
+		func(pod *corev1.Pod, config IPAMConfig, allowed bool) {
+			ctx := context.TODO()
+			config.EnableIPv4 = true
+
+			fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects()...).Build()
+			rIPManager, err := reservedipmanager.NewReservedIPManager(fakeClient)
+			Expect(err).NotTo(HaveOccurred())
+			ipPoolManager, err := ippoolmanager.NewIPPoolManager(ippoolmanager.IPPoolManagerConfig{}, fakeClient, rIPManager)
+			Expect(err).NotTo(HaveOccurred())
+			endpointManager, err := workloadendpointmanager.NewWorkloadEndpointManager(workloadendpointmanager.EndpointManagerConfig{}, fakeClient)
+			Expect(err).NotTo(HaveOccurred())
+			nodeManager, err := nodemanager.NewNodeManager(fakeClient)
+			Expect(err).NotTo(HaveOccurred())
+			nsManager, err := namespacemanager.NewNamespaceManager(fakeClient)
+			Expect(err).NotTo(HaveOccurred())
+			podManager, err := podmanager.NewPodManager(podmanager.PodManagerConfig{}, fakeClient)
+			Expect(err).NotTo(HaveOccurred())
+
+			preview, err := PreviewPodIPAM(ctx, pod, config, ipPoolManager, endpointManager, nodeManager, nsManager, podManager)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(preview.Allowed).To(Equal(allowed), "%v", preview.Messages)
+			if !allowed {
+				Expect(preview.Messages).NotTo(BeEmpty())
+			}
+
+			// The IP allocation on each Node where the Pod may run selects
+			// the IPPool candidates of its own.
+			i := &ipam{
+				config:          setDefaultsForIPAMConfig(config),
+				ipPoolManager:   ipPoolManager,
+				endpointManager: endpointManager,
+				nodeManager:     nodeManager,
+				nsManager:       nsManager,
+				podManager:      podManager,
+			}
+			i.poolFilters = i.builtinPoolFilters()
+			podController, err := podManager.GetPodTopController(ctx, pod)
+			Expect(err).NotTo(HaveOccurred())
+
+			nodes, err := previewSchedulableNodes(ctx, nodeManager, pod)
+			Expect(err).NotTo(HaveOccurred())
+			allocated := false
+			for _, node := range nodes {
+				nodePod := pod.DeepCopy()
+				nodePod.Spec.NodeName = node.Name
+				addArgs := &models.IpamAddArgs{IfName: pointer.String(constant.ClusterDefaultInterfaceName)}
+				tt, _, err := i.genToBeAllocatedSet(ctx, addArgs, nodePod, podController)
+				if err == nil {
+					err = i.checkWorkloadIPLimit(ctx, nodePod, podController, tt)
+				}
+				if err == nil {
+					allocated = true
+				}
+			}
+			Expect(preview.Allowed).To(Equal(allocated))
+		},
+		Entry("the IPPool matching the Node of the Pod",
+			newPod(`"pool-a"`, func(pod *corev1.Pod) { pod.Spec.NodeName = "node-a" }), IPAMConfig{}, true),
+		Entry("the IPPool not matching the Node of the Pod",
+			newPod(`"pool-b"`, func(pod *corev1.Pod) { pod.Spec.NodeName = "node-a" }), IPAMConfig{}, false),
+		Entry("the IPPool not matching the Nodes selected by the Pod",
+			newPod(`"pool-a"`, func(pod *corev1.Pod) { pod.Spec.NodeSelector = map[string]string{"zone": "b"} }), IPAMConfig{}, false),
+		Entry("the IPPool matching the required node affinity of the Pod",
+			newPod(`"pool-a"`, func(pod *corev1.Pod) {
+				pod.Spec.Affinity = &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+							MatchExpressions: []corev1.NodeSelectorRequirement{
+								{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}},
+							},
+						}},
+					},
+				}}
+			}), IPAMConfig{}, true),
+		Entry("the IPPool of the tainted Node not tolerated",
+			newPod(`"pool-b"`, nil), IPAMConfig{}, false),
+		Entry("the IPPool of the tainted Node tolerated",
+			newPod(`"pool-b"`, func(pod *corev1.Pod) {
+				pod.Spec.Tolerations = []corev1.Toleration{
+					{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "b", Effect: corev1.TaintEffectNoSchedule},
+				}
+			}), IPAMConfig{}, true),
+		Entry("the IPPool whose gateway is unreachable",
+			newPod(`"pool-gw"`, nil), IPAMConfig{}, true),
+		Entry("the IPPool whose gateway is unreachable skipped",
+			newPod(`"pool-gw"`, nil), IPAMConfig{SkipGatewayUnreachableIPPools: true}, false),
+		Entry("the workload within the limit",
+			newPod(`"pool-a"`, nil), IPAMConfig{MaxIPsPerWorkload: 2}, true),
+		Entry("the workload beyond the limit",
+			newPod(`"pool-a"`, nil), IPAMConfig{MaxIPsPerWorkload: 1}, false),
+	)
+})
@@ -0,0 +1,106 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/spidernet-io/spiderpool/pkg/lock"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/metric"
+)
+
+// failureTracker counts the datapath-level allocation failures of each
+// IPPool in a sliding time window.
+type failureTracker struct {
+	lock      lock.Mutex
+	threshold int
+	window    time.Duration
+	failures  map[string][]time.Time
+}
+
+func newFailureTracker(threshold int, window time.Duration) *failureTracker {
+	return &failureTracker{
+		threshold: threshold,
+		window:    window,
+		failures:  map[string][]time.Time{},
+	}
+}
+
+// Record records a failure of the IPPool, and reports whether the failures
+// in the time window reach the threshold. The records of the IPPool will
+// be reset once the threshold is reached.
+func (t *failureTracker) Record(poolName string, now time.Time) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var recent []time.Time
+	for _, f := range t.failures[poolName] {
+		if now.Sub(f) < t.window {
+			recent = append(recent, f)
+		}
+	}
+	recent = append(recent, now)
+
+	if len(recent) >= t.threshold {
+		delete(t.failures, poolName)
+		return true
+	}
+	t.failures[poolName] = recent
+
+	return false
+}
+
+// recordDatapathFailure records a datapath-level failure of the IPPool
+// found on the node, such as an IP conflict or none of its gateways
+// replying to the probe. Once the failures reach the threshold within the
+// failure window, the node reports them to the IPPool, which is quarantined
+// by spiderpool-controller when more than one node reports them. The
+// cluster default IPPools are never quarantined, since they are the last
+// resort of all Pods.
+func (i *ipam) recordDatapathFailure(ctx context.Context, poolName string, pod *corev1.Pod, reason string) {
+	if i.failureTracker == nil {
+		return
+	}
+
+	// The datapath of the finished Pods is never set up again.
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return
+	}
+
+	logger := logutils.FromContext(ctx)
+	logger.Sugar().Warnf("Record a datapath failure of IPPool %s: %s", poolName, reason)
+	metric.IPPoolDatapathFailureCounts.Add(ctx, 1)
+	if !i.failureTracker.Record(poolName, time.Now()) {
+		return
+	}
+
+	if i.isClusterDefaultIPPool(poolName) {
+		logger.Sugar().Warnf("Repeated datapath failures of cluster default IPPool %s, but it's never quarantined", poolName)
+		return
+	}
+
+	message := fmt.Sprintf("%d datapath failures within %s on node %s, %s", i.config.QuarantineFailureThreshold, i.config.QuarantineFailureWindow, pod.Spec.NodeName, reason)
+	if err := i.ipPoolManager.ReportRepeatedDatapathFailures(ctx, poolName, pod.Spec.NodeName, message); err != nil {
+		logger.Sugar().Errorf("Failed to report the datapath failures of IPPool %s: %v", poolName, err)
+		return
+	}
+	logger.Sugar().Warnf("Report the repeated datapath failures of IPPool %s: %s", poolName, message)
+}
+
+func (i *ipam) isClusterDefaultIPPool(poolName string) bool {
+	for _, pools := range [][]string{i.config.ClusterDefaultIPv4IPPool, i.config.ClusterDefaultIPv6IPPool} {
+		for _, pool := range pools {
+			if pool == poolName {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,299 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/spidernet-io/spiderpool/api/v1/agent/models"
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/namespacemanager"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+var _ = Describe("recordDatapathFailure", Label("quarantine_test"), func() {
+	var i *ipam
+	var fakeClient client.Client
+	var pod *corev1.Pod
+	var ipPool *spiderpoolv1.SpiderIPPool
+	BeforeEach(func() {
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "pod"},
+			Spec:       corev1.PodSpec{NodeName: "node1"},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		}
+		ipPool = &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: "v4-pool"}}
+	})
+
+	JustBeforeEach(func() {
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(ipPool).Build()
+		rIPManager, err := reservedipmanager.NewReservedIPManager(fakeClient)
+		Expect(err).NotTo(HaveOccurred())
+		ipPoolManager, err := ippoolmanager.NewIPPoolManager(ippoolmanager.IPPoolManagerConfig{}, fakeClient, rIPManager)
+		Expect(err).NotTo(HaveOccurred())
+
+		config := setDefaultsForIPAMConfig(IPAMConfig{
+			ClusterDefaultIPv4IPPool:   []string{"default-v4-pool"},
+			QuarantineFailureThreshold: 2,
+			QuarantineFailureWindow:    time.Minute,
+		})
+		i = &ipam{
+			config:         config,
+			ipPoolManager:  ipPoolManager,
+			failureTracker: newFailureTracker(config.QuarantineFailureThreshold, config.QuarantineFailureWindow),
+		}
+	})
+
+	getIPPool := func() *spiderpoolv1.SpiderIPPool {
+		var pool spiderpoolv1.SpiderIPPool
+		err := fakeClient.Get(context.TODO(), client.ObjectKey{Name: ipPool.Name}, &pool)
+		Expect(err).NotTo(HaveOccurred())
+		return &pool
+	}
+
+	recordFailures := func(n int) {
+		for k := 0; k < n; k++ {
+			i.recordDatapathFailure(context.TODO(), ipPool.Name, pod, "gateway 172.18.40.1 is unreachable")
+		}
+	}
+
+	It("reports the failures reaching the threshold without quarantining the IPPool", func() {
+		recordFailures(1)
+		Expect(getIPPool().Status.DatapathFailureNodes).To(BeEmpty())
+
+		recordFailures(1)
+		pool := getIPPool()
+		Expect(pool.Status.DatapathFailureNodes).To(HaveKey("node1"))
+		Expect(pool.Status.DatapathFailureNodes["node1"].RepeatedFailureTime).NotTo(BeNil())
+		Expect(pool.Status.DatapathFailureNodes["node1"].Message).To(ContainSubstring("gateway 172.18.40.1 is unreachable"))
+		Expect(ippoolmanager.IsQuarantinedIPPool(pool)).To(BeFalse())
+	})
+
+	Context("with the cluster default IPPool", func() {
+		BeforeEach(func() {
+			ipPool.Name = "default-v4-pool"
+		})
+
+		It("never reports the failures", func() {
+			recordFailures(2)
+			Expect(getIPPool().Status.DatapathFailureNodes).To(BeEmpty())
+		})
+	})
+
+	DescribeTable("ignores the failures of the finished Pod",
+		func(phase corev1.PodPhase) {
+			pod.Status.Phase = phase
+			recordFailures(2)
+			Expect(getIPPool().Status.DatapathFailureNodes).To(BeEmpty())
+		},
+		Entry("succeeded", corev1.PodSucceeded),
+		Entry("failed", corev1.PodFailed),
+	)
+
+	It("does nothing without the quarantine", func() {
+		i.failureTracker = nil
+		recordFailures(2)
+		Expect(getIPPool().Status.DatapathFailureNodes).To(BeEmpty())
+	})
+})
+
+var _ = Describe("detectIPConflicts", Label("quarantine_test"), func() {
+	var i *ipam
+	var fakeClient client.Client
+	var pod *corev1.Pod
+	var ipPool *spiderpoolv1.SpiderIPPool
+	var probed []string
+	var replies []net.IP
+	var attached bool
+	BeforeEach(func() {
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "pod"},
+			Spec:       corev1.PodSpec{NodeName: "node1"},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		}
+		ipPool = &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: "v4-pool"}}
+		probed = nil
+		replies = nil
+		attached = true
+
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(ipPool).Build()
+		rIPManager, err := reservedipmanager.NewReservedIPManager(fakeClient)
+		Expect(err).NotTo(HaveOccurred())
+		ipPoolManager, err := ippoolmanager.NewIPPoolManager(ippoolmanager.IPPoolManagerConfig{}, fakeClient, rIPManager)
+		Expect(err).NotTo(HaveOccurred())
+
+		config := setDefaultsForIPAMConfig(IPAMConfig{
+			QuarantineFailureThreshold: 1,
+			IPConflictProbeTimeout:     time.Second,
+		})
+		var lock sync.Mutex
+		i = &ipam{
+			config:         config,
+			ipPoolManager:  ipPoolManager,
+			failureTracker: newFailureTracker(config.QuarantineFailureThreshold, config.QuarantineFailureWindow),
+			neighborProber: func(ctx context.Context, subnet string, version types.IPVersion, ips []net.IP, timeout time.Duration) ([]net.IP, bool, error) {
+				lock.Lock()
+				defer lock.Unlock()
+				probed = append(probed, fmt.Sprintf("%s%v", subnet, ips))
+				return replies, attached, nil
+			},
+		}
+	})
+
+	newResult := func(address string) *types.AllocationResult {
+		return &types.AllocationResult{IP: &models.IPConfig{
+			Address: pointer.String(address),
+			IPPool:  ipPool.Name,
+			Nic:     pointer.String(constant.ClusterDefaultInterfaceName),
+			Version: pointer.Int64(constant.IPv4),
+		}}
+	}
+
+	It("probes the IP addresses of each subnet once", func() {
+		err := i.detectIPConflicts(context.TODO(), []*types.AllocationResult{
+			newResult("172.18.40.10/24"),
+			newResult("172.18.40.11/24"),
+			newResult("172.19.40.10/24"),
+		}, pod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(probed).To(ConsistOf("172.18.40.0/24[172.18.40.10 172.18.40.11]", "172.19.40.0/24[172.19.40.10]"))
+	})
+
+	It("fails the allocation and reports the conflict of the IPPool", func() {
+		replies = []net.IP{net.ParseIP("172.18.40.10")}
+		err := i.detectIPConflicts(context.TODO(), []*types.AllocationResult{newResult("172.18.40.10/24")}, pod)
+		Expect(err).To(MatchError(constant.ErrIPInUse))
+		Expect(err).To(MatchError(ContainSubstring("IP 172.18.40.10 of IPPool v4-pool")))
+
+		var pool spiderpoolv1.SpiderIPPool
+		err = fakeClient.Get(context.TODO(), client.ObjectKey{Name: ipPool.Name}, &pool)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pool.Status.DatapathFailureNodes["node1"].Message).To(ContainSubstring("IP 172.18.40.10 conflicts with a host on the network"))
+	})
+
+	It("skips the subnet not attached to the node", func() {
+		replies = []net.IP{net.ParseIP("172.18.40.10")}
+		attached = false
+		err := i.detectIPConflicts(context.TODO(), []*types.AllocationResult{newResult("172.18.40.10/24")}, pod)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("does nothing without the probe timeout", func() {
+		i.config.IPConflictProbeTimeout = 0
+		err := i.detectIPConflicts(context.TODO(), []*types.AllocationResult{newResult("172.18.40.10/24")}, pod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(probed).To(BeEmpty())
+	})
+})
+
+var _ = Describe("filterPoolCandidates", Label("quarantine_test"), func() {
+	var i *ipam
+	var pod *corev1.Pod
+	var quarantined, healthy *spiderpoolv1.SpiderIPPool
+	BeforeEach(func() {
+		pod = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "pod"}}
+		newIPPool := func(name string) *spiderpoolv1.SpiderIPPool {
+			return &spiderpoolv1.SpiderIPPool{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion: pointer.Int64(constant.IPv4),
+					Disable:   pointer.Bool(false),
+				},
+			}
+		}
+		quarantined = newIPPool("quarantined-pool")
+		quarantined.Status.Conditions = []metav1.Condition{{
+			Type:    constant.IPPoolConditionQuarantined,
+			Status:  metav1.ConditionTrue,
+			Message: "Nodes node1,node2 report repeated datapath failures, quarantined until 2023-01-01T00:05:00Z",
+		}}
+		healthy = newIPPool("healthy-pool")
+
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: metav1.NamespaceDefault}},
+		).Build()
+		nsManager, err := namespacemanager.NewNamespaceManager(fakeClient)
+		Expect(err).NotTo(HaveOccurred())
+		i = &ipam{nsManager: nsManager}
+		i.poolFilters = i.builtinPoolFilters()
+	})
+
+	newCandidates := func(ipPools ...*spiderpoolv1.SpiderIPPool) ToBeAllocateds {
+		c := &PoolCandidate{IPVersion: constant.IPv4, PToIPPool: PoolNameToIPPool{}}
+		for _, ipPool := range ipPools {
+			c.Pools = append(c.Pools, ipPool.Name)
+			c.PToIPPool[ipPool.Name] = ipPool
+		}
+		return ToBeAllocateds{{NIC: constant.ClusterDefaultInterfaceName, PoolCandidates: []*PoolCandidate{c}}}
+	}
+
+	It("skips the quarantined IPPool", func() {
+		tt := newCandidates(quarantined, healthy)
+		err := i.filterPoolCandidates(context.TODO(), tt, pod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tt[0].PoolCandidates[0].Pools).To(Equal([]string{healthy.Name}))
+	})
+
+	It("fails if the quarantined IPPool is the only candidate", func() {
+		tt := newCandidates(quarantined)
+		err := i.filterPoolCandidates(context.TODO(), tt, pod)
+		Expect(err).To(MatchError(constant.ErrNoAvailablePool))
+		Expect(err).To(MatchError(ContainSubstring("IPPools [quarantined-pool] are quarantined for repeated datapath failures")))
+		Expect(err).To(MatchError(ContainSubstring("quarantined until 2023-01-01T00:05:00Z")))
+	})
+})
+
+var _ = Describe("failureTracker", Label("quarantine_test"), func() {
+	var tracker *failureTracker
+	var now time.Time
+	BeforeEach(func() {
+		tracker = newFailureTracker(3, time.Minute)
+		now = time.Now()
+	})
+
+	It("reports the failures reaching the threshold in the window", func() {
+		Expect(tracker.Record("pool", now)).To(BeFalse())
+		Expect(tracker.Record("pool", now.Add(10*time.Second))).To(BeFalse())
+		Expect(tracker.Record("pool", now.Add(20*time.Second))).To(BeTrue())
+	})
+
+	It("forgets the failures out of the window", func() {
+		Expect(tracker.Record("pool", now)).To(BeFalse())
+		Expect(tracker.Record("pool", now.Add(10*time.Second))).To(BeFalse())
+		Expect(tracker.Record("pool", now.Add(time.Minute))).To(BeFalse())
+		Expect(tracker.Record("pool", now.Add(time.Minute+5*time.Second))).To(BeTrue())
+	})
+
+	It("starts over once the threshold is reached", func() {
+		for n := 0; n < 3; n++ {
+			tracker.Record("pool", now)
+		}
+		Expect(tracker.Record("pool", now)).To(BeFalse())
+		Expect(tracker.Record("pool", now)).To(BeFalse())
+		Expect(tracker.Record("pool", now)).To(BeTrue())
+	})
+
+	It("counts the failures of each IPPool separately", func() {
+		Expect(tracker.Record("pool1", now)).To(BeFalse())
+		Expect(tracker.Record("pool2", now)).To(BeFalse())
+		Expect(tracker.Record("pool1", now)).To(BeFalse())
+		Expect(tracker.Record("pool2", now)).To(BeFalse())
+		Expect(tracker.Record("pool1", now)).To(BeTrue())
+	})
+})
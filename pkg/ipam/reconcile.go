@@ -0,0 +1,158 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/metric"
+)
+
+const (
+	reconcileRetryInterval = 5 * time.Second
+
+	// The reconciliation is aborted if it would release more than
+	// reconcileMaxReleaseRatio of the local IP allocations, and more than
+	// reconcileMinSuspiciousReleases of them, which more likely results
+	// from a misconfigured or broken container runtime than from the
+	// sandboxes vanished during the downtime of the agent.
+	reconcileMaxReleaseRatio       = 0.5
+	reconcileMinSuspiciousReleases = 5
+)
+
+var errTooManyVanishedSandboxes = errors.New("too many vanished sandboxes")
+
+// reconcileLocalEndpoints cross-checks the Endpoints claiming the local node
+// against the Pod sandboxes of the container runtime, and releases the IP
+// allocations of the sandboxes which vanished while the agent was down,
+// rather than leaving the leaked IP addresses blocking the capacity of
+// IPPools until the IP garbage collection reclaims them.
+func (i *ipam) reconcileLocalEndpoints(ctx context.Context) error {
+	logger := logutils.Logger.Named("IPAM").With(zap.String("Action", "ReconcileLocalEndpoints"))
+	ctx = logutils.IntoContext(ctx, logger)
+
+	endpoints, err := i.endpointManager.ListEndpointsByNode(ctx, i.config.NodeName)
+	if err != nil {
+		return fmt.Errorf("failed to list Endpoints: %w", err)
+	}
+
+	intents, err := i.findVanishedSandboxes(ctx, endpoints)
+	if err != nil {
+		if errors.Is(err, errTooManyVanishedSandboxes) {
+			logger.Sugar().Errorf("Skip the reconciliation: %v", err)
+			return nil
+		}
+		return err
+	}
+
+	for _, intent := range intents {
+		rLogger := logger.With(
+			zap.String("ContainerID", intent.ContainerID),
+			zap.String("PodNamespace", intent.PodNamespace),
+			zap.String("PodName", intent.PodName),
+		)
+		rCtx := logutils.IntoContext(ctx, rLogger)
+
+		rLogger.Info("Sandbox vanished while the agent was down, release its IP allocation")
+		if err := i.releaseIntent(rCtx, intent); err != nil {
+			rLogger.Sugar().Errorf("failed to release the IP allocation of vanished sandbox: %v", err)
+			continue
+		}
+		metric.IpamReleaseVanishedSandboxCounts.Add(ctx, 1)
+	}
+
+	return nil
+}
+
+// findVanishedSandboxes returns the release intents of the local Endpoints
+// whose sandboxes are gone. The sandboxes are checked via CRI if it's
+// enabled, the ones still in the sandbox state directory are alive anyway.
+func (i *ipam) findVanishedSandboxes(ctx context.Context, endpoints []spiderpoolv1.SpiderEndpoint) ([]ReleaseIntent, error) {
+	logger := logutils.FromContext(ctx)
+
+	var sandboxes map[string]struct{}
+	if i.config.SandboxStateDir != "" {
+		// A wrong state directory would make all sandboxes look vanished.
+		entries, err := os.ReadDir(i.config.SandboxStateDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sandbox state directory %s: %v", i.config.SandboxStateDir, err)
+		}
+		if len(entries) == 0 && i.sandboxChecker == nil {
+			logger.Sugar().Warnf("No sandbox found in state directory %s, skip the reconciliation", i.config.SandboxStateDir)
+			return nil, nil
+		}
+		sandboxes = make(map[string]struct{}, len(entries))
+		for _, e := range entries {
+			sandboxes[e.Name()] = struct{}{}
+		}
+	}
+
+	var allocated int
+	var intents []ReleaseIntent
+	for _, endpoint := range endpoints {
+		current := endpoint.Status.Current
+		if current == nil || current.Node == nil || *current.Node != i.config.NodeName || len(current.IPs) == 0 {
+			continue
+		}
+		allocated++
+
+		if sandboxes != nil {
+			if _, ok := sandboxes[current.ContainerID]; ok {
+				continue
+			}
+			// The sandbox may be created after the state directory was read.
+			if _, err := os.Stat(i.sandboxStateDir(current.ContainerID)); !os.IsNotExist(err) {
+				continue
+			}
+		}
+
+		rCtx := logutils.IntoContext(ctx, logger.With(zap.String("ContainerID", current.ContainerID)))
+		if !i.sandboxGone(rCtx, current.ContainerID) {
+			continue
+		}
+
+		intents = append(intents, ReleaseIntent{
+			PodNamespace: endpoint.Namespace,
+			PodName:      endpoint.Name,
+			ContainerID:  current.ContainerID,
+			NIC:          current.IPs[0].NIC,
+		})
+	}
+
+	if len(intents) > reconcileMinSuspiciousReleases && float64(len(intents)) > float64(allocated)*reconcileMaxReleaseRatio {
+		return nil, fmt.Errorf("%w, %d of %d local IP allocations would be released", errTooManyVanishedSandboxes, len(intents), allocated)
+	}
+
+	return intents, nil
+}
+
+// startReconcileLocalEndpoints reconciles the local Endpoints once, it's
+// retried until the Endpoints can be listed.
+func (i *ipam) startReconcileLocalEndpoints(ctx context.Context) {
+	err := wait.PollImmediateUntil(reconcileRetryInterval, func() (bool, error) {
+		if err := i.reconcileLocalEndpoints(ctx); err != nil {
+			logutils.Logger.Sugar().Warnf("Failed to reconcile local Endpoints, retry later: %v", err)
+			return false, nil
+		}
+		return true, nil
+	}, ctx.Done())
+	if err != nil {
+		logutils.Logger.Sugar().Debugf("Stop reconciling local Endpoints: %v", err)
+	}
+}
+
+// sandboxStateDir returns the state directory of the sandbox.
+func (i *ipam) sandboxStateDir(containerID string) string {
+	return filepath.Join(i.config.SandboxStateDir, containerID)
+}
@@ -0,0 +1,137 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+)
+
+// fakeSandboxChecker reports the sandboxes in ready as ready, the others
+// as not found.
+type fakeSandboxChecker struct {
+	ready map[string]bool
+	err   error
+}
+
+func (f *fakeSandboxChecker) IsSandboxReady(ctx context.Context, sandboxID string) (bool, error) {
+	return f.ready[sandboxID], f.err
+}
+
+var _ = Describe("findVanishedSandboxes", Label("reconcile_test"), func() {
+	const nodeName = "node1"
+
+	newEndpoint := func(name, node string) spiderpoolv1.SpiderEndpoint {
+		endpoint := spiderpoolv1.SpiderEndpoint{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+		endpoint.Status.Current = &spiderpoolv1.PodIPAllocation{
+			ContainerID: name + "-sandbox",
+			Node:        pointer.String(node),
+			IPs:         []spiderpoolv1.IPAllocationDetail{{NIC: "eth0", IPv4: pointer.String("172.18.40.10/24")}},
+		}
+		return endpoint
+	}
+	newEndpoints := func(n int) []spiderpoolv1.SpiderEndpoint {
+		var endpoints []spiderpoolv1.SpiderEndpoint
+		for j := 0; j < n; j++ {
+			endpoints = append(endpoints, newEndpoint(fmt.Sprintf("pod%d", j), nodeName))
+		}
+		return endpoints
+	}
+	podNames := func(intents []ReleaseIntent) []string {
+		var names []string
+		for _, intent := range intents {
+			names = append(names, intent.PodName)
+		}
+		return names
+	}
+
+	var i *ipam
+	BeforeEach(func() {
+		i = &ipam{config: IPAMConfig{NodeName: nodeName}}
+	})
+
+	Context("with CRI", func() {
+		It("releases the sandboxes which are gone or stopped", func() {
+			i.sandboxChecker = &fakeSandboxChecker{ready: map[string]bool{"pod0-sandbox": true}}
+			endpoints := append(newEndpoints(3), newEndpoint("other", "node2"))
+
+			intents, err := i.findVanishedSandboxes(context.TODO(), endpoints)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(podNames(intents)).To(ConsistOf("pod1", "pod2"))
+			Expect(intents[0].ContainerID).To(Equal("pod1-sandbox"))
+			Expect(intents[0].NIC).To(Equal("eth0"))
+		})
+
+		It("releases nothing if the container runtime is unreachable", func() {
+			i.sandboxChecker = &fakeSandboxChecker{err: errors.New("connection refused")}
+
+			intents, err := i.findVanishedSandboxes(context.TODO(), newEndpoints(3))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(intents).To(BeEmpty())
+		})
+
+		It("skips the sandboxes in the state directory", func() {
+			i.config.SandboxStateDir = GinkgoT().TempDir()
+			err := os.Mkdir(filepath.Join(i.config.SandboxStateDir, "pod1-sandbox"), 0o755)
+			Expect(err).NotTo(HaveOccurred())
+			i.sandboxChecker = &fakeSandboxChecker{}
+
+			intents, err := i.findVanishedSandboxes(context.TODO(), newEndpoints(2))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(podNames(intents)).To(ConsistOf("pod0"))
+		})
+	})
+
+	Context("with sandbox state directory only", func() {
+		It("releases the sandboxes missing from the state directory", func() {
+			i.config.SandboxStateDir = GinkgoT().TempDir()
+			err := os.Mkdir(filepath.Join(i.config.SandboxStateDir, "pod0-sandbox"), 0o755)
+			Expect(err).NotTo(HaveOccurred())
+
+			intents, err := i.findVanishedSandboxes(context.TODO(), newEndpoints(2))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(podNames(intents)).To(ConsistOf("pod1"))
+		})
+
+		It("releases nothing if the state directory is empty", func() {
+			i.config.SandboxStateDir = GinkgoT().TempDir()
+
+			intents, err := i.findVanishedSandboxes(context.TODO(), newEndpoints(2))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(intents).To(BeEmpty())
+		})
+
+		It("fails if the state directory can't be read", func() {
+			i.config.SandboxStateDir = filepath.Join(GinkgoT().TempDir(), "nonexistent")
+
+			_, err := i.findVanishedSandboxes(context.TODO(), newEndpoints(2))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	It("aborts if most local IP allocations would be released", func() {
+		i.sandboxChecker = &fakeSandboxChecker{ready: map[string]bool{"pod0-sandbox": true, "pod1-sandbox": true}}
+
+		_, err := i.findVanishedSandboxes(context.TODO(), newEndpoints(10))
+		Expect(err).To(MatchError(errTooManyVanishedSandboxes))
+	})
+
+	It("releases a few vanished sandboxes even if they are most of the local ones", func() {
+		i.sandboxChecker = &fakeSandboxChecker{}
+
+		intents, err := i.findVanishedSandboxes(context.TODO(), newEndpoints(reconcileMinSuspiciousReleases))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(intents).To(HaveLen(reconcileMinSuspiciousReleases))
+	})
+})
@@ -0,0 +1,316 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/spidernet-io/spiderpool/api/v1/agent/models"
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/lock"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+	"github.com/spidernet-io/spiderpool/pkg/utils/convert"
+	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
+)
+
+// deferredRelease is the IP allocation of a Pod whose release is deferred,
+// so that it could be handed over to the replacement Pod of the same
+// controller on the node.
+type deferredRelease struct {
+	namespace   string
+	podName     string
+	containerID string
+	details     []spiderpoolv1.IPAllocationDetail
+	deadline    time.Time
+}
+
+// releaseDeferrer keeps the deferred releases of the local Pods, keyed by
+// their top controllers.
+type releaseDeferrer struct {
+	lock     lock.Mutex
+	duration time.Duration
+	entries  map[string][]*deferredRelease
+}
+
+func newReleaseDeferrer(duration time.Duration) *releaseDeferrer {
+	return &releaseDeferrer{
+		duration: duration,
+		entries:  map[string][]*deferredRelease{},
+	}
+}
+
+// Add defers the release, false is returned if the release of the container
+// has been deferred.
+func (d *releaseDeferrer) Add(key string, r *deferredRelease, now time.Time) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, e := range d.entries[key] {
+		if e.containerID == r.containerID {
+			return false
+		}
+	}
+	r.deadline = now.Add(d.duration)
+	d.entries[key] = append(d.entries[key], r)
+
+	return true
+}
+
+// Take removes and returns the earliest deferred release of the controller
+// which is matched, nil if none.
+func (d *releaseDeferrer) Take(key string, match func(r *deferredRelease) bool) *deferredRelease {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for n, e := range d.entries[key] {
+		if !match(e) {
+			continue
+		}
+		d.entries[key] = append(d.entries[key][:n], d.entries[key][n+1:]...)
+		if len(d.entries[key]) == 0 {
+			delete(d.entries, key)
+		}
+		return e
+	}
+
+	return nil
+}
+
+// TakeExpired removes and returns the deferred releases which are due.
+func (d *releaseDeferrer) TakeExpired(now time.Time) []*deferredRelease {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	var expired []*deferredRelease
+	for key, entries := range d.entries {
+		var remained []*deferredRelease
+		for _, e := range entries {
+			if now.Before(e.deadline) {
+				remained = append(remained, e)
+				continue
+			}
+			expired = append(expired, e)
+		}
+		if len(remained) == 0 {
+			delete(d.entries, key)
+			continue
+		}
+		d.entries[key] = remained
+	}
+
+	return expired
+}
+
+func deferralKey(kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
+
+// deferRelease defers the release of the IP allocation if the Pod is
+// protected by PodDisruptionBudget, since it's probably being recreated in
+// place, such as being evicted and rescheduled to the node. The IP
+// allocations of orphan Pods are released at once as no replacement will
+// come.
+func (i *ipam) deferRelease(ctx context.Context, endpoint *spiderpoolv1.SpiderEndpoint, allocation *spiderpoolv1.PodIPAllocation) bool {
+	if i.deferrer == nil || len(allocation.IPs) == 0 {
+		return false
+	}
+	kind := endpoint.Status.OwnerControllerType
+	if kind == "" || kind == constant.KindPod || kind == constant.KindStatefulSet {
+		return false
+	}
+
+	logger := logutils.FromContext(ctx)
+	pod, err := i.podManager.GetPodByName(ctx, endpoint.Namespace, endpoint.Name)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			logger.Sugar().Warnf("Failed to get Pod to check whether to defer the release: %v", err)
+		}
+		return false
+	}
+
+	protected, err := i.podManager.IsProtectedByPDB(ctx, pod)
+	if err != nil {
+		logger.Sugar().Warnf("Failed to check whether Pod is protected by PodDisruptionBudget: %v", err)
+		return false
+	}
+	if !protected {
+		return false
+	}
+
+	key := deferralKey(kind, endpoint.Namespace, endpoint.Status.OwnerControllerName)
+	if i.deferrer.Add(key, &deferredRelease{
+		namespace:   endpoint.Namespace,
+		podName:     endpoint.Name,
+		containerID: allocation.ContainerID,
+		details:     allocation.IPs,
+	}, time.Now()) {
+		logger.Sugar().Infof("Defer the release of IP allocation details %+v for %s, waiting for the replacement of %s", allocation.IPs, i.config.ReleaseDeferralDuration, key)
+	}
+
+	return true
+}
+
+// takeOverDeferredRelease hands the deferred IP allocation of another Pod of
+// the same controller over to the Pod, if its IPPools are the candidates of
+// the Pod. The IP allocation details of the Pod are returned, nil if there
+// is nothing to take over.
+func (i *ipam) takeOverDeferredRelease(ctx context.Context, tt ToBeAllocateds, containerID string, pod *corev1.Pod, podController types.PodTopController) []spiderpoolv1.IPAllocationDetail {
+	if i.deferrer == nil {
+		return nil
+	}
+
+	logger := logutils.FromContext(ctx)
+	key := deferralKey(podController.Kind, podController.Namespace, podController.Name)
+	r := i.deferrer.Take(key, func(r *deferredRelease) bool {
+		return matchDeferredRelease(tt, r.details)
+	})
+	if r == nil {
+		return nil
+	}
+
+	pics := GroupIPDetails(r.containerID, "", r.details)
+	tickets := pics.Pools()
+	if err := i.ipamLimiter.AcquireTicket(ctx, tickets...); err != nil {
+		logger.Sugar().Warnf("Failed to queue for taking over the deferred IP allocation of Pod %s/%s, release it: %v", r.namespace, r.podName, err)
+		i.releaseDeferred(ctx, r)
+		return nil
+	}
+	defer i.ipamLimiter.ReleaseTicket(ctx, tickets...)
+
+	var transferred PoolNameToIPAndCIDs
+	for poolName, ipAndCIDs := range pics {
+		if err := i.ipPoolManager.TransferIPs(ctx, poolName, ipAndCIDs, containerID, pod); err != nil {
+			logger.Sugar().Warnf("Failed to take over IP addresses %+v of IPPool %s from Pod %s/%s: %v", ipAndCIDs, poolName, r.namespace, r.podName, err)
+			// Give back the IP addresses taken over, then release the
+			// deferred IP allocation as usual.
+			for p, ics := range transferred {
+				back := make([]types.IPAndCID, len(ics))
+				for n := range ics {
+					back[n] = types.IPAndCID{IP: ics[n].IP, ContainerID: containerID}
+				}
+				if err := i.ipPoolManager.ReleaseIP(ctx, p, back); err != nil {
+					logger.Sugar().Warnf("Failed to release IP addresses %+v of IPPool %s taken over: %v", back, p, err)
+				}
+			}
+			i.releaseDeferred(ctx, r)
+			return nil
+		}
+		if transferred == nil {
+			transferred = PoolNameToIPAndCIDs{}
+		}
+		transferred[poolName] = ipAndCIDs
+	}
+	i.clearDeferredEndpoint(ctx, r)
+	logger.Sugar().Infof("Take over the deferred IP allocation details %+v of Pod %s/%s", r.details, r.namespace, r.podName)
+
+	return r.details
+}
+
+// matchDeferredRelease reports whether the IP allocation details cover the
+// NICs to be allocated, with the IPPools among their candidates.
+func matchDeferredRelease(tt ToBeAllocateds, details []spiderpoolv1.IPAllocationDetail) bool {
+	nicToPools := map[string]map[string]struct{}{}
+	for _, t := range tt {
+		pools := map[string]struct{}{}
+		for _, p := range t.Pools() {
+			pools[p] = struct{}{}
+		}
+		nicToPools[t.NIC] = pools
+	}
+
+	covered := map[string]struct{}{}
+	for _, d := range details {
+		pools, ok := nicToPools[d.NIC]
+		if !ok {
+			return false
+		}
+		for _, p := range []*string{d.IPv4Pool, d.IPv6Pool} {
+			if p == nil {
+				continue
+			}
+			if _, ok := pools[*p]; !ok {
+				return false
+			}
+		}
+		covered[d.NIC] = struct{}{}
+	}
+
+	return len(covered) == len(nicToPools)
+}
+
+// allocateFromDeferredRelease records the IP allocation details taken over
+// to the Endpoint of the Pod, and returns them as the response.
+func (i *ipam) allocateFromDeferredRelease(ctx context.Context, containerID string, details []spiderpoolv1.IPAllocationDetail, endpoint *spiderpoolv1.SpiderEndpoint) (*models.IpamAddResponse, error) {
+	if err := i.endpointManager.PatchIPAllocation(ctx, &spiderpoolv1.PodIPAllocation{
+		ContainerID: containerID,
+		IPs:         details,
+		CNICalls:    []spiderpoolv1.CNICall{workloadendpointmanager.NewCNICall(constant.CNIOperationAdd, i.config.NodeName, constant.CNIResultSuccess)},
+	}, endpoint); err != nil {
+		if rErr := i.release(ctx, containerID, details); rErr != nil {
+			logutils.FromContext(ctx).Sugar().Warnf("Failed to release the IP addresses taken over: %v", rErr)
+		}
+		return nil, fmt.Errorf("failed to patch IP allocation detail to Endpoint %s/%s: %v", endpoint.Namespace, endpoint.Name, err)
+	}
+
+	ips, routes := convert.IPDetailsToIPConfigsAndAllRoutes(details)
+
+	return &models.IpamAddResponse{
+		Ips:    ips,
+		Routes: routes,
+	}, nil
+}
+
+// releaseExpiredDeferrals releases the deferred IP allocations which are
+// not taken over in time.
+func (i *ipam) releaseExpiredDeferrals(ctx context.Context) {
+	for _, r := range i.deferrer.TakeExpired(time.Now()) {
+		logger := logutils.Logger.Named("IPAM").With(
+			zap.String("Action", "ReleaseDeferred"),
+			zap.String("ContainerID", r.containerID),
+			zap.String("PodNamespace", r.namespace),
+			zap.String("PodName", r.podName),
+		)
+		rCtx := logutils.IntoContext(ctx, logger)
+		// Left to be reclaimed by the IP GC of spiderpool-controller.
+		if !i.sandboxGone(rCtx, r.containerID) {
+			continue
+		}
+
+		logger.Sugar().Infof("Release the deferred IP allocation details %+v which are not taken over", r.details)
+		i.releaseDeferred(rCtx, r)
+	}
+}
+
+func (i *ipam) releaseDeferred(ctx context.Context, r *deferredRelease) {
+	logger := logutils.FromContext(ctx)
+	if err := i.release(ctx, r.containerID, r.details); err != nil {
+		// Left to be reclaimed by the IP GC of spiderpool-controller.
+		logger.Sugar().Errorf("Failed to release the deferred IP allocation: %v", err)
+		return
+	}
+	i.clearDeferredEndpoint(ctx, r)
+}
+
+func (i *ipam) clearDeferredEndpoint(ctx context.Context, r *deferredRelease) {
+	logger := logutils.FromContext(ctx)
+	endpoint, err := i.endpointManager.GetEndpointByName(ctx, r.namespace, r.podName)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			logger.Sugar().Warnf("Failed to get Endpoint %s/%s to clear the deferred IP allocation: %v", r.namespace, r.podName, err)
+		}
+		return
+	}
+
+	if err := i.endpointManager.ClearCurrentIPAllocation(ctx, r.containerID, endpoint); err != nil {
+		logger.Sugar().Warnf("Failed to clear the current IP allocation of Endpoint %s/%s: %v", r.namespace, r.podName, err)
+	}
+}
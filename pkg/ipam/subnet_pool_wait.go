@@ -0,0 +1,163 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+// autoPoolReadyFunc reports whether the auto-created IPPool is ready to
+// allocate IP addresses to the Pod.
+type autoPoolReadyFunc func(pool *spiderpoolv1.SpiderIPPool) bool
+
+// autoPoolHasIPs is ready once the auto-created IPPool has at least ipNum IP
+// addresses, the IPPool without any IP address is never ready.
+func autoPoolHasIPs(ipNum int) autoPoolReadyFunc {
+	return func(pool *spiderpoolv1.SpiderIPPool) bool {
+		if len(pool.Spec.IPs) == 0 {
+			return false
+		}
+
+		totalIPs, err := spiderpoolip.AssembleTotalIPs(*pool.Spec.IPVersion, pool.Spec.IPs, pool.Spec.ExcludeIPs)
+		if err != nil {
+			return false
+		}
+
+		return len(totalIPs) >= ipNum
+	}
+}
+
+// getSubnetPoolWaitTimeout returns the deadline to wait for the auto-created
+// IPPools of the Pod, which is overridden by the Pod annotation.
+func (i *ipam) getSubnetPoolWaitTimeout(pod *corev1.Pod) (time.Duration, error) {
+	anno, ok := pod.Annotations[constant.AnnoSpiderSubnetPoolWaitTimeout]
+	if !ok {
+		return i.config.WaitSubnetPoolTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(anno)
+	if err != nil {
+		return 0, fmt.Errorf("%w, invalid format of Pod annotation '%s': %v", constant.ErrWrongInput, constant.AnnoSpiderSubnetPoolWaitTimeout, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("%w, Pod annotation '%s' must be positive", constant.ErrWrongInput, constant.AnnoSpiderSubnetPoolWaitTimeout)
+	}
+
+	return timeout, nil
+}
+
+// waitAutoIPPool waits for the auto-created IPPool with the labels to be
+// ready until the timeout. The IPPools of the application are watched, and
+// the auto-created IPPool is re-checked on each change of them, so that the
+// Pod proceeds as soon as the spiderpool-controller creates or scales it.
+// Without the IPPool watcher, the IPPool is polled instead.
+func (i *ipam) waitAutoIPPool(ctx context.Context, matchLabels client.MatchingLabels, ready autoPoolReadyFunc, timeout time.Duration) (*spiderpoolv1.SpiderIPPool, error) {
+	logger := logutils.FromContext(ctx)
+	logger.Sugar().Infof("Wait %v for the auto-created IPPool with labels '%v' to be ready", timeout, matchLabels)
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var pool *spiderpoolv1.SpiderIPPool
+	var err error
+	if i.ipPoolWatcher == nil {
+		err = wait.PollImmediateUntilWithContext(waitCtx, i.config.OperationGapDuration, func(ctx context.Context) (bool, error) {
+			pool, err = i.getReadyAutoIPPool(ctx, matchLabels, ready)
+			return pool != nil, err
+		})
+	} else {
+		pool, err = i.watchAutoIPPool(waitCtx, matchLabels, ready)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, wait.ErrWaitTimeout) {
+		return nil, fmt.Errorf("no ready auto-created IPPool with labels '%v' within %v", matchLabels, timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+func (i *ipam) watchAutoIPPool(ctx context.Context, matchLabels client.MatchingLabels, ready autoPoolReadyFunc) (*spiderpoolv1.SpiderIPPool, error) {
+	// The auto-created IPPool borrowing IP addresses from a fallback subnet
+	// is owned by another SpiderSubnet, so the owner is not watched.
+	watchLabels := client.MatchingLabels{}
+	for k, v := range matchLabels {
+		if k != constant.LabelIPPoolOwnerSpiderSubnet {
+			watchLabels[k] = v
+		}
+	}
+
+	for {
+		// Start watching before checking the IPPool, so that no change is
+		// missed in between.
+		watcher, err := i.ipPoolWatcher.Watch(ctx, &spiderpoolv1.SpiderIPPoolList{}, watchLabels)
+		if err != nil {
+			return nil, fmt.Errorf("failed to watch IPPools with labels '%v': %w", watchLabels, err)
+		}
+
+		pool, err := i.getReadyAutoIPPool(ctx, matchLabels, ready)
+		if err != nil || pool != nil {
+			watcher.Stop()
+			return pool, err
+		}
+
+	WATCH:
+		for {
+			select {
+			case <-ctx.Done():
+				watcher.Stop()
+				return nil, ctx.Err()
+			case _, ok := <-watcher.ResultChan():
+				if !ok {
+					// The watch is closed by the API server, establish a
+					// new one.
+					break WATCH
+				}
+
+				pool, err := i.getReadyAutoIPPool(ctx, matchLabels, ready)
+				if err != nil || pool != nil {
+					watcher.Stop()
+					return pool, err
+				}
+			}
+		}
+		watcher.Stop()
+	}
+}
+
+// getReadyAutoIPPool returns the auto-created IPPool with the labels if it's
+// ready, nil is returned if it doesn't exist or isn't ready yet.
+func (i *ipam) getReadyAutoIPPool(ctx context.Context, matchLabels client.MatchingLabels, ready autoPoolReadyFunc) (*spiderpoolv1.SpiderIPPool, error) {
+	poolList, err := i.subnetManager.ListAutoIPPools(ctx, matchLabels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IPPoolList with labels '%v', error: %v", matchLabels, err)
+	}
+
+	switch len(poolList.Items) {
+	case 0:
+		return nil, nil
+	case 1:
+		pool := poolList.Items[0].DeepCopy()
+		if !ready(pool) {
+			return nil, nil
+		}
+		return pool, nil
+	default:
+		return nil, fmt.Errorf("it's invalid that multiple IPPools '%v' match labels '%v' for one specify application", poolList.Items, matchLabels)
+	}
+}
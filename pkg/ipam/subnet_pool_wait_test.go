@@ -0,0 +1,162 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+	"github.com/spidernet-io/spiderpool/pkg/subnetmanager"
+)
+
+var _ = Describe("waiting for the auto-created IPPools", Label("subnet_pool_wait_test"), func() {
+	newAutoPool := func(ips ...string) *spiderpoolv1.SpiderIPPool {
+		pool := &spiderpoolv1.SpiderIPPool{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "auto-pool",
+				Labels: map[string]string{
+					constant.LabelIPPoolOwnerApplicationUID: "uid",
+					constant.LabelIPPoolOwnerSpiderSubnet:   "subnet",
+				},
+			},
+		}
+		pool.Spec.IPVersion = pointer.Int64(constant.IPv4)
+		pool.Spec.Subnet = "172.18.40.0/24"
+		pool.Spec.IPs = ips
+		return pool
+	}
+
+	Describe("autoPoolHasIPs", func() {
+		It("is never ready without IP addresses", func() {
+			Expect(autoPoolHasIPs(0)(newAutoPool())).To(BeFalse())
+		})
+
+		It("is ready with enough IP addresses", func() {
+			pool := newAutoPool("172.18.40.1-172.18.40.2")
+			Expect(autoPoolHasIPs(2)(pool)).To(BeTrue())
+			Expect(autoPoolHasIPs(3)(pool)).To(BeFalse())
+
+			pool.Spec.ExcludeIPs = []string{"172.18.40.2"}
+			Expect(autoPoolHasIPs(2)(pool)).To(BeFalse())
+		})
+	})
+
+	Describe("getSubnetPoolWaitTimeout", func() {
+		var i *ipam
+		var pod *corev1.Pod
+		BeforeEach(func() {
+			i = &ipam{config: setDefaultsForIPAMConfig(IPAMConfig{
+				OperationRetries:     5,
+				OperationGapDuration: time.Second,
+			})}
+			pod = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}}}
+		})
+
+		It("defaults to the retries of the operation gap", func() {
+			Expect(i.getSubnetPoolWaitTimeout(pod)).To(Equal(5 * time.Second))
+		})
+
+		It("is overridden by the Pod annotation", func() {
+			pod.Annotations[constant.AnnoSpiderSubnetPoolWaitTimeout] = "1m"
+			Expect(i.getSubnetPoolWaitTimeout(pod)).To(Equal(time.Minute))
+		})
+
+		It("refuses the invalid Pod annotation", func() {
+			for _, anno := range []string{"invalid", "0s", "-1s"} {
+				pod.Annotations[constant.AnnoSpiderSubnetPoolWaitTimeout] = anno
+				_, err := i.getSubnetPoolWaitTimeout(pod)
+				Expect(err).To(MatchError(constant.ErrWrongInput))
+			}
+		})
+	})
+
+	Describe("waitAutoIPPool", func() {
+		var i *ipam
+		var fakeClient client.WithWatch
+		var pool *spiderpoolv1.SpiderIPPool
+		var matchLabels client.MatchingLabels
+		BeforeEach(func() {
+			pool = newAutoPool()
+			matchLabels = client.MatchingLabels{
+				constant.LabelIPPoolOwnerApplicationUID: "uid",
+				constant.LabelIPPoolOwnerSpiderSubnet:   "subnet",
+			}
+		})
+
+		JustBeforeEach(func() {
+			fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(pool).Build()
+			rIPManager, err := reservedipmanager.NewReservedIPManager(fakeClient)
+			Expect(err).NotTo(HaveOccurred())
+			ipPoolManager, err := ippoolmanager.NewIPPoolManager(ippoolmanager.IPPoolManagerConfig{}, fakeClient, rIPManager)
+			Expect(err).NotTo(HaveOccurred())
+			subnetManager, err := subnetmanager.NewSubnetManager(subnetmanager.SubnetManagerConfig{}, fakeClient, ipPoolManager, scheme)
+			Expect(err).NotTo(HaveOccurred())
+
+			i = &ipam{
+				config:        IPAMConfig{OperationGapDuration: 10 * time.Millisecond},
+				subnetManager: subnetManager,
+				ipPoolWatcher: fakeClient,
+			}
+		})
+
+		scaleLater := func() {
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(50 * time.Millisecond)
+				scaled := pool.DeepCopy()
+				Expect(fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(scaled), scaled)).To(Succeed())
+				scaled.Spec.IPs = []string{"172.18.40.1"}
+				Expect(fakeClient.Update(context.TODO(), scaled)).To(Succeed())
+			}()
+		}
+
+		It("returns the auto-created IPPool once it's ready", func() {
+			scaleLater()
+			ready, err := i.waitAutoIPPool(context.TODO(), matchLabels, autoPoolHasIPs(1), 5*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ready.Spec.IPs).To(Equal([]string{"172.18.40.1"}))
+		})
+
+		It("polls the auto-created IPPool without the watcher", func() {
+			i.ipPoolWatcher = nil
+			scaleLater()
+			ready, err := i.waitAutoIPPool(context.TODO(), matchLabels, autoPoolHasIPs(1), 5*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ready.Spec.IPs).To(Equal([]string{"172.18.40.1"}))
+		})
+
+		It("gives up after the timeout", func() {
+			_, err := i.waitAutoIPPool(context.TODO(), matchLabels, autoPoolHasIPs(1), 100*time.Millisecond)
+			Expect(err).To(MatchError(ContainSubstring("no ready auto-created IPPool")))
+
+			i.ipPoolWatcher = nil
+			_, err = i.waitAutoIPPool(context.TODO(), matchLabels, autoPoolHasIPs(1), 100*time.Millisecond)
+			Expect(err).To(MatchError(ContainSubstring("no ready auto-created IPPool")))
+		})
+
+		Context("with the auto-created IPPool already ready", func() {
+			BeforeEach(func() {
+				pool.Spec.IPs = []string{"172.18.40.1"}
+			})
+
+			It("returns it immediately", func() {
+				ready, err := i.waitAutoIPPool(context.TODO(), matchLabels, autoPoolHasIPs(1), time.Second)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ready.Name).To(Equal(pool.Name))
+			})
+		})
+	})
+})
@@ -6,7 +6,6 @@ package ipam
 import (
 	"fmt"
 
-	"github.com/spidernet-io/spiderpool/api/v1/agent/models"
 	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
 	"github.com/spidernet-io/spiderpool/pkg/types"
 )
@@ -31,10 +30,42 @@ func (tt *ToBeAllocateds) Candidates() []*PoolCandidate {
 	return candidates
 }
 
+// The sources of IPPool candidates, used to label the allocation metrics.
+const (
+	SourceSubnetAnnotation = "subnet_annotation"
+	SourceIPPoolAnnotation = "ippool_annotation"
+	SourceNamespaceDefault = "namespace_default"
+	SourceNetConf          = "netconf"
+	SourceClusterDefault   = "cluster_default"
+)
+
+func (tt *ToBeAllocateds) setSource(source string) {
+	for _, t := range *tt {
+		t.Source = source
+	}
+}
+
+// Sources returns the distinct sources of the IPPool candidates.
+func (tt *ToBeAllocateds) Sources() []string {
+	var sources []string
+	seen := map[string]struct{}{}
+	for _, t := range *tt {
+		if _, ok := seen[t.Source]; ok {
+			continue
+		}
+		seen[t.Source] = struct{}{}
+		sources = append(sources, t.Source)
+	}
+
+	return sources
+}
+
 type ToBeAllocated struct {
 	NIC            string
 	CleanGateway   bool
 	PoolCandidates []*PoolCandidate
+	// Source is where the IPPool candidates come from.
+	Source string
 }
 
 func (t *ToBeAllocated) Pools() []string {
@@ -54,6 +85,38 @@ type PoolCandidate struct {
 	IPVersion types.IPVersion
 	Pools     []string
 	PToIPPool PoolNameToIPPool
+
+	// Stripe is the index of the candidate among all candidates of the same
+	// IP version for the NIC. Multi-pool striping allocates one IP address
+	// from each stripe, and only the IP address of stripe 0 provides the
+	// default route.
+	Stripe int
+	// Filtered are the IPPools eliminated by the PoolFilters, with the
+	// reasons.
+	Filtered map[string]error
+}
+
+// copy returns a copy of the IPPool candidates, which could be filtered
+// again without affecting the original ones.
+func (tt ToBeAllocateds) copy() ToBeAllocateds {
+	copied := make(ToBeAllocateds, 0, len(tt))
+	for _, t := range tt {
+		ct := *t
+		ct.PoolCandidates = make([]*PoolCandidate, 0, len(t.PoolCandidates))
+		for _, c := range t.PoolCandidates {
+			cc := *c
+			cc.Pools = append([]string(nil), c.Pools...)
+			cc.PToIPPool = make(PoolNameToIPPool, len(c.PToIPPool))
+			for pool, ipPool := range c.PToIPPool {
+				cc.PToIPPool[pool] = ipPool
+			}
+			cc.Filtered = nil
+			ct.PoolCandidates = append(ct.PoolCandidates, &cc)
+		}
+		copied = append(copied, &ct)
+	}
+
+	return copied
 }
 
 func (c *PoolCandidate) String() string {
@@ -85,9 +148,3 @@ func (pics *PoolNameToIPAndCIDs) Pools() []string {
 
 	return pools
 }
-
-type AllocationResult struct {
-	IP           *models.IPConfig
-	Routes       []*models.Route
-	CleanGateway bool
-}
@@ -13,6 +13,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"github.com/spidernet-io/spiderpool/api/v1/agent/models"
 	"github.com/spidernet-io/spiderpool/pkg/constant"
@@ -21,6 +22,7 @@ import (
 	"github.com/spidernet-io/spiderpool/pkg/singletons"
 	subnetmanagercontrollers "github.com/spidernet-io/spiderpool/pkg/subnetmanager/controllers"
 	"github.com/spidernet-io/spiderpool/pkg/types"
+	"github.com/spidernet-io/spiderpool/pkg/utils/convert"
 )
 
 func getPoolFromPodAnnoPools(ctx context.Context, anno, nic string) (ToBeAllocateds, error) {
@@ -58,18 +60,15 @@ func getPoolFromPodAnnoPools(ctx context.Context, anno, nic string) (ToBeAllocat
 			NIC:          v.NIC,
 			CleanGateway: v.CleanGateway,
 		}
-		if len(v.IPv4Pools) != 0 {
-			t.PoolCandidates = append(t.PoolCandidates, &PoolCandidate{
-				IPVersion: constant.IPv4,
-				Pools:     v.IPv4Pools,
-			})
+		v4Candidates, err := genPoolCandidates(constant.IPv4, v.IPv4Pools, v.Striping)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errPrefix, err)
 		}
-		if len(v.IPv6Pools) != 0 {
-			t.PoolCandidates = append(t.PoolCandidates, &PoolCandidate{
-				IPVersion: constant.IPv6,
-				Pools:     v.IPv6Pools,
-			})
+		v6Candidates, err := genPoolCandidates(constant.IPv6, v.IPv6Pools, v.Striping)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errPrefix, err)
 		}
+		t.PoolCandidates = append(v4Candidates, v6Candidates...)
 		tt = append(tt, t)
 	}
 
@@ -86,24 +85,56 @@ func getPoolFromPodAnnoPool(ctx context.Context, anno, nic string, cleanGateway
 		return nil, fmt.Errorf("%w: %v", errPrefix, err)
 	}
 
+	v4Candidates, err := genPoolCandidates(constant.IPv4, annoPodIPPool.IPv4Pools, annoPodIPPool.Striping)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errPrefix, err)
+	}
+	v6Candidates, err := genPoolCandidates(constant.IPv6, annoPodIPPool.IPv6Pools, annoPodIPPool.Striping)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errPrefix, err)
+	}
+
 	t := &ToBeAllocated{
-		NIC:          nic,
-		CleanGateway: cleanGateway,
+		NIC:            nic,
+		CleanGateway:   cleanGateway,
+		PoolCandidates: append(v4Candidates, v6Candidates...),
 	}
-	if len(annoPodIPPool.IPv4Pools) != 0 {
-		t.PoolCandidates = append(t.PoolCandidates, &PoolCandidate{
-			IPVersion: constant.IPv4,
-			Pools:     annoPodIPPool.IPv4Pools,
-		})
+
+	return t, nil
+}
+
+// genPoolCandidates generates the IPPool candidates of the IP version. By
+// default, the IPPools are alternatives of each other and one IP address
+// will be allocated from the first available one. With multi-pool striping,
+// each IPPool is a separate candidate and provides an IP address.
+func genPoolCandidates(version types.IPVersion, pools []string, striping bool) ([]*PoolCandidate, error) {
+	if len(pools) == 0 {
+		return nil, nil
 	}
-	if len(annoPodIPPool.IPv6Pools) != 0 {
-		t.PoolCandidates = append(t.PoolCandidates, &PoolCandidate{
-			IPVersion: constant.IPv6,
-			Pools:     annoPodIPPool.IPv6Pools,
+
+	if !striping {
+		return []*PoolCandidate{{
+			IPVersion: version,
+			Pools:     pools,
+		}}, nil
+	}
+
+	poolSet := map[string]struct{}{}
+	candidates := make([]*PoolCandidate, 0, len(pools))
+	for i, pool := range pools {
+		if _, ok := poolSet[pool]; ok {
+			return nil, fmt.Errorf("duplicate IPv%d IPPool %s in striping", version, pool)
+		}
+		poolSet[pool] = struct{}{}
+
+		candidates = append(candidates, &PoolCandidate{
+			IPVersion: version,
+			Pools:     []string{pool},
+			Stripe:    i,
 		})
 	}
 
-	return t, nil
+	return candidates, nil
 }
 
 func getPoolFromNetConf(ctx context.Context, nic string, netConfV4Pool, netConfV6Pool []string, cleanGateway bool) *ToBeAllocated {
@@ -153,10 +184,10 @@ func getCustomRoutes(pod *corev1.Pod) ([]*models.Route, error) {
 		}
 	}
 
-	return convertAnnoPodRoutesToOAIRoutes(annoPodRoutes), nil
+	return convert.AnnoPodRoutesToOAIRoutes(annoPodRoutes), nil
 }
 
-func groupCustomRoutes(ctx context.Context, customRoutes []*models.Route, results []*AllocationResult) error {
+func groupCustomRoutes(ctx context.Context, customRoutes []*models.Route, results []*types.AllocationResult) error {
 	if len(customRoutes) == 0 {
 		return nil
 	}
@@ -187,6 +218,123 @@ func groupCustomRoutes(ctx context.Context, customRoutes []*models.Route, result
 	return nil
 }
 
+// getCustomMACs parses the fixed MAC addresses of NICs from the Pod
+// annotation, whose value is either "auto" for all NICs or a JSON object
+// mapping the NICs to their MAC addresses or "auto".
+func getCustomMACs(pod *corev1.Pod) (types.AnnoPodMACValue, error) {
+	anno, ok := pod.Annotations[constant.AnnoPodMAC]
+	if !ok {
+		return nil, nil
+	}
+
+	if anno == constant.MACAuto {
+		return types.AnnoPodMACValue{"": constant.MACAuto}, nil
+	}
+
+	var annoPodMAC types.AnnoPodMACValue
+	errPrefix := fmt.Errorf("%w, invalid format of Pod annotation '%s'", constant.ErrWrongInput, constant.AnnoPodMAC)
+	if err := json.Unmarshal([]byte(anno), &annoPodMAC); err != nil {
+		return nil, fmt.Errorf("%w: %v", errPrefix, err)
+	}
+
+	for nic, mac := range annoPodMAC {
+		if nic == "" {
+			return nil, fmt.Errorf("%w: empty interface", errPrefix)
+		}
+		if mac == constant.MACAuto {
+			continue
+		}
+		hw, err := net.ParseMAC(mac)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errPrefix, err)
+		}
+		if len(hw) != 6 || hw[0]&0x01 != 0 {
+			return nil, fmt.Errorf("%w: MAC address %s of interface %s is not a unicast EUI-48 address", errPrefix, mac, nic)
+		}
+		annoPodMAC[nic] = hw.String()
+	}
+
+	return annoPodMAC, nil
+}
+
+// groupCustomMACs sets the fixed MAC addresses on the IP allocation results
+// of their NICs. The MAC address "auto" is derived from the IPv4 address of
+// the NIC, or from the IPv6 one if the NIC is IPv6-only, so that it keeps
+// stable as long as the IP address is fixed. They override the MAC addresses
+// derived by IPPools.
+func groupCustomMACs(customMACs types.AnnoPodMACValue, results []*types.AllocationResult) error {
+	if len(customMACs) == 0 {
+		return nil
+	}
+
+	nicToIPs := map[string][]net.IP{}
+	for _, res := range results {
+		ip, _, err := net.ParseCIDR(*res.IP.Address)
+		if err != nil {
+			return err
+		}
+		nicToIPs[*res.IP.Nic] = append(nicToIPs[*res.IP.Nic], ip)
+	}
+
+	nicToMAC := map[string]string{}
+	for nic, ips := range nicToIPs {
+		mac, ok := customMACs[nic]
+		if !ok {
+			if mac, ok = customMACs[""]; !ok {
+				continue
+			}
+		}
+		if mac == constant.MACAuto {
+			mac = genMACFromIPs(ips).String()
+		}
+		nicToMAC[nic] = mac
+	}
+
+	for _, res := range results {
+		if mac, ok := nicToMAC[*res.IP.Nic]; ok {
+			res.IP.Mac = mac
+		}
+	}
+
+	return nil
+}
+
+// unifyIPPoolMACs makes the IP allocation results of a NIC share one MAC
+// address derived by their IPPools, the one of the IPv4 address takes
+// precedence if the IPv4 and IPv6 IPPools derive different ones.
+func unifyIPPoolMACs(results []*types.AllocationResult) {
+	nicToMAC := map[string]string{}
+	for _, res := range results {
+		if res.IP.Mac == "" {
+			continue
+		}
+		if _, ok := nicToMAC[*res.IP.Nic]; !ok || *res.IP.Version == constant.IPv4 {
+			nicToMAC[*res.IP.Nic] = res.IP.Mac
+		}
+	}
+
+	for _, res := range results {
+		if mac, ok := nicToMAC[*res.IP.Nic]; ok {
+			res.IP.Mac = mac
+		}
+	}
+}
+
+// genMACFromIPs derives a locally administered unicast MAC address from the
+// IP addresses, "0a:58" followed by the IPv4 address, or "0a:59" followed by
+// the last 4 bytes of the IPv6 address.
+func genMACFromIPs(ips []net.IP) net.HardwareAddr {
+	var ipv6 net.IP
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			return net.HardwareAddr{0x0a, 0x58, ip4[0], ip4[1], ip4[2], ip4[3]}
+		}
+		ipv6 = ip.To16()
+	}
+
+	return net.HardwareAddr{0x0a, 0x59, ipv6[12], ipv6[13], ipv6[14], ipv6[15]}
+}
+
 // getAutoPoolIPNumberAndSelector calculates the auto-created IPPool IP number with the given params pod and pod top controller.
 // If it's an orphan pod, it will return 1.
 func getAutoPoolIPNumberAndSelector(pod *corev1.Pod, podController types.PodTopController) (int, *metav1.LabelSelector, error) {
@@ -221,13 +369,31 @@ func getAutoPoolIPNumberAndSelector(pod *corev1.Pod, podController types.PodTopC
 	case constant.KindCronJob:
 		cronJob := podController.APP.(*batchv1.CronJob)
 		appReplicas = subnetmanagercontrollers.CalculateJobPodNum(cronJob.Spec.JobTemplate.Spec.Parallelism, cronJob.Spec.JobTemplate.Spec.Completions)
-		podSelector = cronJob.Spec.JobTemplate.Spec.Selector
+		podSelector = subnetmanagercontrollers.GetCronJobPodSelector(cronJob)
 	default:
-		isThirdPartyController = true
+		// the configured third party controllers are taken care of by the spiderpool-controller like the kubernetes original controllers
+		app, ok := podController.APP.(*unstructured.Unstructured)
+		if !ok {
+			isThirdPartyController = true
+			break
+		}
+
+		var err error
+		appReplicas, err = subnetmanagercontrollers.GetThirdPartyAppReplicas(app)
+		if nil != err {
+			return -1, nil, err
+		}
+		podSelector, err = subnetmanagercontrollers.GetThirdPartyAppPodSelector(app)
+		if nil != err {
+			return -1, nil, err
+		}
 	}
 
 	var flexibleIPNum int
-	poolIPNumStr, ok := pod.Annotations[constant.AnnoSpiderSubnetPoolIPNumber]
+	poolIPNumStr, ok, err := subnetmanagercontrollers.GetPoolIPNumberAnno(pod.Annotations)
+	if nil != err {
+		return -1, nil, err
+	}
 	if ok {
 		isFlexible, ipNum, err := subnetmanagercontrollers.GetPoolIPNumber(poolIPNumStr)
 		if nil != err {
@@ -247,8 +413,9 @@ func getAutoPoolIPNumberAndSelector(pod *corev1.Pod, podController types.PodTopC
 		// flexible IP Number
 		flexibleIPNum = ipNum
 	} else {
-		// third party controller only supports fixed auto-created IPPool IP number
-		if isThirdPartyController {
+		// third party controller only supports fixed auto-created IPPool IP number, which is also fixed by the pinned IPs
+		_, pinned := pod.Annotations[constant.AnnoSpiderSubnetPoolIPs]
+		if isThirdPartyController && !pinned {
 			return -1, nil, fmt.Errorf("%s/%s/%s only supports fixed auto-created IPPool IP Number", podController.Kind, podController.Namespace, podController.Name)
 		}
 
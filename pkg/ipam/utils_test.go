@@ -0,0 +1,73 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+)
+
+var _ = Describe("multi-pool striping", Label("utils_test"), func() {
+	Describe("genPoolCandidates", func() {
+		It("generates nothing without IPPools", func() {
+			candidates, err := genPoolCandidates(constant.IPv4, nil, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(candidates).To(BeEmpty())
+		})
+
+		It("treats the IPPools as alternatives without striping", func() {
+			candidates, err := genPoolCandidates(constant.IPv4, []string{"pool1", "pool2"}, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(candidates).To(Equal([]*PoolCandidate{
+				{IPVersion: constant.IPv4, Pools: []string{"pool1", "pool2"}},
+			}))
+		})
+
+		It("generates a stripe for each IPPool with striping", func() {
+			candidates, err := genPoolCandidates(constant.IPv6, []string{"pool1", "pool2"}, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(candidates).To(Equal([]*PoolCandidate{
+				{IPVersion: constant.IPv6, Pools: []string{"pool1"}, Stripe: 0},
+				{IPVersion: constant.IPv6, Pools: []string{"pool2"}, Stripe: 1},
+			}))
+		})
+
+		It("refuses the duplicate IPPools with striping", func() {
+			_, err := genPoolCandidates(constant.IPv4, []string{"pool1", "pool1"}, true)
+			Expect(err).To(MatchError(ContainSubstring("duplicate IPv4 IPPool pool1")))
+		})
+	})
+
+	Describe("Pod annotations", func() {
+		It("stripes the IPPools of the single NIC annotation", func() {
+			t, err := getPoolFromPodAnnoPool(context.TODO(), `{"ipv4":["pool1","pool2"],"ipv6":["pool3"],"striping":true}`, "eth0", false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t.PoolCandidates).To(Equal([]*PoolCandidate{
+				{IPVersion: constant.IPv4, Pools: []string{"pool1"}, Stripe: 0},
+				{IPVersion: constant.IPv4, Pools: []string{"pool2"}, Stripe: 1},
+				{IPVersion: constant.IPv6, Pools: []string{"pool3"}, Stripe: 0},
+			}))
+		})
+
+		It("stripes the IPPools of the NICs enabling striping in multiple NICs annotation", func() {
+			tt, err := getPoolFromPodAnnoPools(context.TODO(),
+				`[{"interface":"eth0","ipv4":["pool1","pool2"],"striping":true},{"interface":"net1","ipv4":["pool3","pool4"]}]`, "eth0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tt).To(HaveLen(2))
+			Expect(tt[0].PoolCandidates).To(HaveLen(2))
+			Expect(tt[1].PoolCandidates).To(Equal([]*PoolCandidate{
+				{IPVersion: constant.IPv4, Pools: []string{"pool3", "pool4"}},
+			}))
+		})
+
+		It("refuses the annotation striping the duplicate IPPools", func() {
+			_, err := getPoolFromPodAnnoPool(context.TODO(), `{"ipv4":["pool1","pool1"],"striping":true}`, "eth0", false)
+			Expect(err).To(MatchError(constant.ErrWrongInput))
+		})
+	})
+})
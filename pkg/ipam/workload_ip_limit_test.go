@@ -0,0 +1,107 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
+)
+
+var _ = Describe("checkWorkloadIPLimit", Label("workload_ip_limit_test"), func() {
+	var i *ipam
+	var pod *corev1.Pod
+	var podController types.PodTopController
+	var tt ToBeAllocateds
+
+	// newEndpoint returns the Endpoint of the Pod of the Deployment, which
+	// holds one IPv4 and one IPv6 address.
+	newEndpoint := func(name, namespace, deployment string) client.Object {
+		endpoint := &spiderpoolv1.SpiderEndpoint{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+		endpoint.Status.OwnerControllerType = constant.KindDeployment
+		endpoint.Status.OwnerControllerName = deployment
+		endpoint.Status.Current = &spiderpoolv1.PodIPAllocation{
+			ContainerID: name,
+			IPs: []spiderpoolv1.IPAllocationDetail{{
+				NIC:  "eth0",
+				IPv4: pointer.String("172.18.40.10/24"),
+				IPv6: pointer.String("abcd:1234::a/120"),
+			}},
+		}
+		return endpoint
+	}
+
+	setup := func(objs ...client.Object) {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+		endpointManager, err := workloadendpointmanager.NewWorkloadEndpointManager(workloadendpointmanager.EndpointManagerConfig{}, fakeClient)
+		Expect(err).NotTo(HaveOccurred())
+		i.endpointManager = endpointManager
+	}
+
+	BeforeEach(func() {
+		i = &ipam{config: IPAMConfig{MaxIPsPerWorkload: 4}}
+		pod = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod"}}
+		podController = types.PodTopController{Kind: constant.KindDeployment, Namespace: "default", Name: "deploy"}
+		tt = ToBeAllocateds{{
+			NIC: "eth0",
+			PoolCandidates: []*PoolCandidate{
+				{IPVersion: constant.IPv4, Pools: []string{"v4-pool"}},
+				{IPVersion: constant.IPv6, Pools: []string{"v6-pool"}},
+			},
+		}}
+	})
+
+	It("allows the allocation within the limit", func() {
+		setup(newEndpoint("pod-0", "default", "deploy"))
+
+		err := i.checkWorkloadIPLimit(context.TODO(), pod, podController, tt)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("rejects the allocation beyond the limit", func() {
+		setup(newEndpoint("pod-0", "default", "deploy"), newEndpoint("pod-1", "default", "deploy"))
+
+		err := i.checkWorkloadIPLimit(context.TODO(), pod, podController, tt)
+		Expect(err).To(MatchError(constant.ErrWorkloadIPLimitExceeded))
+	})
+
+	It("does not count the IP addresses of the Pod itself, or of the other workloads", func() {
+		setup(
+			newEndpoint("pod-0", "default", "deploy"),
+			newEndpoint(pod.Name, "default", "deploy"),
+			newEndpoint("pod-1", "default", "other-deploy"),
+			newEndpoint("pod-2", "kube-system", "deploy"),
+		)
+
+		err := i.checkWorkloadIPLimit(context.TODO(), pod, podController, tt)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("skips the orphan Pods and the disabled limit", func() {
+		var objs []client.Object
+		for j := 0; j < 3; j++ {
+			objs = append(objs, newEndpoint(fmt.Sprintf("pod-%d", j), "default", "deploy"))
+		}
+		setup(objs...)
+
+		err := i.checkWorkloadIPLimit(context.TODO(), pod, types.PodTopController{Kind: constant.KindPod, Namespace: "default", Name: pod.Name}, tt)
+		Expect(err).NotTo(HaveOccurred())
+
+		i.config.MaxIPsPerWorkload = 0
+		err = i.checkWorkloadIPLimit(context.TODO(), pod, podController, tt)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -6,13 +6,20 @@ package ippoolmanager
 import "time"
 
 const (
-	defaultMaxAllocatedIPs = 5000
+	defaultMaxAllocatedIPs       = 5000
+	defaultMaxReleaseParallelism = 4
 )
 
 type IPPoolManagerConfig struct {
 	MaxConflictRetries    int
 	ConflictRetryUnitTime time.Duration
 	MaxAllocatedIPs       *int
+	// MaxReleaseParallelism bounds the IPPools updated at the same time
+	// by ReleaseIPs.
+	MaxReleaseParallelism int
+	// ReserveSpecialIPs keeps the network, broadcast and gateway addresses
+	// from being allocated, unless overridden by the IPPools.
+	ReserveSpecialIPs bool
 }
 
 func setDefaultsForIPPoolManagerConfig(config IPPoolManagerConfig) IPPoolManagerConfig {
@@ -20,6 +27,9 @@ func setDefaultsForIPPoolManagerConfig(config IPPoolManagerConfig) IPPoolManager
 		maxAllocatedIPs := defaultMaxAllocatedIPs
 		config.MaxAllocatedIPs = &maxAllocatedIPs
 	}
+	if config.MaxReleaseParallelism <= 0 {
+		config.MaxReleaseParallelism = defaultMaxReleaseParallelism
+	}
 
 	return config
 }
@@ -0,0 +1,496 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"net"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/labels"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	ctrlcache "sigs.k8s.io/controller-runtime/pkg/cache"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/lock"
+)
+
+// freeIPCache caches a bitmap of the IP addresses in use for each IPPool,
+// so that picking a free IP address of a very large IPPool doesn't parse
+// its 'spec.ips' and 'spec.excludeIPs' or scan its 'status.allocatedIPs'
+// on every allocation. The bitmap of an IPPool is rebuilt once its IP ranges,
+// 'status.excludedIPs' or the IP addresses being vacated are changed. Its
+// bits follow the allocations and releases of this manager. The bits of the
+// IP addresses allocated by others are corrected when picking, and the ones
+// released by others are found only once the bitmap is resynchronized with
+// 'status.allocatedIPs', when no free IP address is found.
+//
+// Once fed by the SpiderReservedIP informer, it also caches the
+// SpiderReservedIPs of each IPPool, which are assembled again only after
+// the informer tells any of them is changed, or the labels of the IPPool
+// are changed. Otherwise, they're assembled on every allocation. The
+// SpiderReservedIPs are read from the cache of spiderpool-agent anyway, so
+// the informer adds no watch. The IP addresses of the child IPPools are
+// parsed again only once they are changed.
+type freeIPCache struct {
+	lock    lock.Mutex
+	entries map[string]*freeIPEntry
+
+	informed bool
+	// version is bumped once any SpiderReservedIP is changed, which the
+	// cached reserved IP addresses are assembled with.
+	version  uint64
+	reserved map[string]*reservedIPsEntry
+	// parsed caches the IP addresses of the SpiderReservedIPs keyed by
+	// their names, they're parsed again only once their resourceVersions
+	// are changed.
+	parsed map[string]parsedIPs
+	// children caches the IP addresses of the child IPPools of each IPPool
+	// keyed by their names, which are replaced every time the child IPPools
+	// are listed.
+	children map[string]map[string]parsedIPs
+}
+
+type freeIPEntry struct {
+	uid             apitypes.UID
+	resourceVersion string
+	ips             []string
+	excludeIPs      []string
+	excludedIPs     []string
+	vacatingIPs     string
+
+	// segments are the contiguous IP ranges of the IPPool in order, the
+	// bit of an IP address is its offset in all segments.
+	segments []ipSegment
+	size     int
+	used     []uint64
+	// cursor is the bit where the next search starts, so that the
+	// released IP addresses are not reused immediately.
+	cursor int
+}
+
+type ipSegment struct {
+	start  net.IP
+	offset int
+	size   int
+}
+
+type reservedIPsEntry struct {
+	uid     apitypes.UID
+	labels  map[string]string
+	version uint64
+	rIPs    []poolReservedIP
+}
+
+// poolReservedIP is a SpiderReservedIP of an IPPool with the IP addresses
+// it reserves, whose expiration and namespace selector are checked on
+// every allocation.
+type poolReservedIP struct {
+	rIP *spiderpoolv1.SpiderReservedIP
+	ips []net.IP
+}
+
+type parsedIPs struct {
+	resourceVersion string
+	ips             []net.IP
+}
+
+// SetupFreeIPCacheInformers feeds the free IP cache with the changes of the
+// SpiderReservedIPs told by the informer.
+func (im *ipPoolManager) SetupFreeIPCacheInformers(ctx context.Context, informers ctrlcache.Informers) error {
+	rIPInformer, err := informers.GetInformer(ctx, &spiderpoolv1.SpiderReservedIP{})
+	if err != nil {
+		return fmt.Errorf("failed to get the informer of SpiderReservedIPs: %w", err)
+	}
+
+	c := im.freeIPs
+	rIPInformer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if rIP, ok := obj.(*spiderpoolv1.SpiderReservedIP); ok {
+				c.onReservedIPChange(nil, rIP)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldRIP, ok1 := oldObj.(*spiderpoolv1.SpiderReservedIP)
+			newRIP, ok2 := newObj.(*spiderpoolv1.SpiderReservedIP)
+			if ok1 && ok2 {
+				c.onReservedIPChange(oldRIP, newRIP)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if rIP, ok := obj.(*spiderpoolv1.SpiderReservedIP); ok {
+				c.onReservedIPChange(rIP, nil)
+			}
+		},
+	})
+
+	c.lock.Lock()
+	c.informed = true
+	c.lock.Unlock()
+
+	return nil
+}
+
+func newFreeIPCache() *freeIPCache {
+	return &freeIPCache{
+		entries:  map[string]*freeIPEntry{},
+		reserved: map[string]*reservedIPsEntry{},
+		parsed:   map[string]parsedIPs{},
+		children: map[string]map[string]parsedIPs{},
+	}
+}
+
+// Pick returns a free IP address of the IPPool which is not one of the
+// reserved IP addresses.
+func (c *freeIPCache) Pick(ipPool *spiderpoolv1.SpiderIPPool, reservedIPs []net.IP) (net.IP, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, err := c.sync(ipPool)
+	if err != nil {
+		return nil, err
+	}
+
+	reserved := make(map[string]struct{}, len(reservedIPs))
+	for _, ip := range reservedIPs {
+		reserved[ip.String()] = struct{}{}
+	}
+
+	if ip, ok := e.pick(ipPool, reserved); ok {
+		return ip, nil
+	}
+
+	// The bits may miss the releases of others.
+	if e.resourceVersion != ipPool.ResourceVersion {
+		e.resync(ipPool)
+		if ip, ok := e.pick(ipPool, reserved); ok {
+			return ip, nil
+		}
+	}
+
+	return nil, constant.ErrIPUsedOut
+}
+
+// Update marks the IP addresses as used or free in the bitmap of the IPPool
+// which has been updated from oldVersion to newVersion by this manager. The
+// bitmap is not taken as the one of newVersion if it is not the one of
+// oldVersion, which still lags behind the IPPool.
+func (c *freeIPCache) Update(poolName, oldVersion, newVersion string, ips []string, used bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, ok := c.entries[poolName]
+	if !ok {
+		return
+	}
+
+	e.mark(ips, used)
+	if e.resourceVersion == oldVersion {
+		e.resourceVersion = newVersion
+	}
+}
+
+// Delete drops all caches of the IPPool.
+func (c *freeIPCache) Delete(poolName string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.entries, poolName)
+	delete(c.reserved, poolName)
+	delete(c.children, poolName)
+}
+
+func (c *freeIPCache) sync(ipPool *spiderpoolv1.SpiderIPPool) (*freeIPEntry, error) {
+	e, ok := c.entries[ipPool.Name]
+	// The layout is compared only if the IPPool has been changed since.
+	if !ok || (e.resourceVersion != ipPool.ResourceVersion && !e.matches(ipPool)) {
+		var err error
+		if e, err = newFreeIPEntry(ipPool); err != nil {
+			return nil, err
+		}
+		e.resync(ipPool)
+		c.entries[ipPool.Name] = e
+	}
+
+	if e.size == 0 {
+		return nil, constant.ErrIPUsedOut
+	}
+
+	return e, nil
+}
+
+// onReservedIPChange drops the cached reserved IP addresses of all IPPools
+// once a SpiderReservedIP is changed, except its status.
+func (c *freeIPCache) onReservedIPChange(oldRIP, newRIP *spiderpoolv1.SpiderReservedIP) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if oldRIP != nil && newRIP != nil && oldRIP.Generation == newRIP.Generation &&
+		(oldRIP.DeletionTimestamp == nil) == (newRIP.DeletionTimestamp == nil) {
+		return
+	}
+
+	c.version++
+	if newRIP == nil {
+		delete(c.parsed, oldRIP.Name)
+	}
+}
+
+// getReservedIPs returns the cached SpiderReservedIPs of the IPPool, and the
+// version of the cache to assemble them with if they're not cached.
+func (c *freeIPCache) getReservedIPs(ipPool *spiderpoolv1.SpiderIPPool) ([]poolReservedIP, uint64, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	r, ok := c.reserved[ipPool.Name]
+	if !c.informed || !ok || r.uid != ipPool.UID || r.version != c.version || !labels.Equals(r.labels, ipPool.Labels) {
+		return nil, c.version, false
+	}
+
+	return r.rIPs, 0, true
+}
+
+func (c *freeIPCache) setReservedIPs(ipPool *spiderpoolv1.SpiderIPPool, version uint64, rIPs []poolReservedIP) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.informed {
+		c.reserved[ipPool.Name] = &reservedIPsEntry{uid: ipPool.UID, labels: ipPool.Labels, version: version, rIPs: rIPs}
+	}
+}
+
+// parseReservedIP returns the IP addresses of the SpiderReservedIP parsed by
+// parseFn, which are parsed again only once its resourceVersion is changed.
+func (c *freeIPCache) parseReservedIP(rIP *spiderpoolv1.SpiderReservedIP, parseFn func() ([]net.IP, error)) ([]net.IP, error) {
+	c.lock.Lock()
+	p, ok := c.parsed[rIP.Name]
+	informed := c.informed
+	c.lock.Unlock()
+	if ok && p.resourceVersion == rIP.ResourceVersion {
+		return p.ips, nil
+	}
+
+	ips, err := parseFn()
+	if err != nil {
+		return nil, err
+	}
+
+	// The deleted ones are only told by the informer.
+	if informed {
+		c.lock.Lock()
+		c.parsed[rIP.Name] = parsedIPs{resourceVersion: rIP.ResourceVersion, ips: ips}
+		c.lock.Unlock()
+	}
+
+	return ips, nil
+}
+
+// parseChildren returns the IP addresses of the child IPPools of the IPPool
+// parsed by parseFn, which are parsed again only once their resourceVersions
+// are changed. The cached ones of the children which are gone are dropped.
+func (c *freeIPCache) parseChildren(ipPool *spiderpoolv1.SpiderIPPool, children []*spiderpoolv1.SpiderIPPool, parseFn func(*spiderpoolv1.SpiderIPPool) ([]net.IP, error)) ([]net.IP, error) {
+	c.lock.Lock()
+	cached := c.children[ipPool.Name]
+	c.lock.Unlock()
+
+	parsed := make(map[string]parsedIPs, len(children))
+	var ips []net.IP
+	for _, child := range children {
+		p, ok := cached[child.Name]
+		if !ok || p.resourceVersion != child.ResourceVersion {
+			childIPs, err := parseFn(child)
+			if err != nil {
+				return nil, err
+			}
+			p = parsedIPs{resourceVersion: child.ResourceVersion, ips: childIPs}
+		}
+		parsed[child.Name] = p
+		ips = append(ips, p.ips...)
+	}
+
+	c.lock.Lock()
+	if len(parsed) == 0 {
+		delete(c.children, ipPool.Name)
+	} else {
+		c.children[ipPool.Name] = parsed
+	}
+	c.lock.Unlock()
+
+	return ips, nil
+}
+
+func newFreeIPEntry(ipPool *spiderpoolv1.SpiderIPPool) (*freeIPEntry, error) {
+	// The reserved IP addresses synchronized to 'status.excludedIPs' are
+	// never picked, the others reserved recently are skipped by Pick. The
+	// IP addresses being vacated are never picked even before synchronized.
+	excludeIPs := append(append(append([]string(nil), ipPool.Spec.ExcludeIPs...), ipPool.Status.ExcludedIPs...), GetVacatingIPs(ipPool)...)
+	totalIPs, err := spiderpoolip.AssembleTotalIPs(*ipPool.Spec.IPVersion, ipPool.Spec.IPs, excludeIPs)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(totalIPs, func(i, j int) bool {
+		return bytes.Compare(totalIPs[i].To16(), totalIPs[j].To16()) < 0
+	})
+
+	e := &freeIPEntry{
+		uid:         ipPool.UID,
+		ips:         append([]string(nil), ipPool.Spec.IPs...),
+		excludeIPs:  append([]string(nil), ipPool.Spec.ExcludeIPs...),
+		excludedIPs: append([]string(nil), ipPool.Status.ExcludedIPs...),
+		vacatingIPs: ipPool.Annotations[constant.AnnoIPPoolVacatingIPs],
+	}
+	for i, ip := range totalIPs {
+		n := len(e.segments)
+		// a segment never crosses the boundary of /64
+		if n != 0 && ip.Equal(spiderpoolip.NextIP(totalIPs[i-1])) && bytes.Equal(ip.To16()[:8], e.segments[n-1].start[:8]) {
+			e.segments[n-1].size++
+			continue
+		}
+		e.segments = append(e.segments, ipSegment{start: ip.To16(), offset: i, size: 1})
+	}
+	e.size = len(totalIPs)
+	e.used = make([]uint64, (e.size+63)/64)
+
+	return e, nil
+}
+
+// matches reports whether the bitmap is laid out for the IP ranges of the
+// IPPool.
+func (e *freeIPEntry) matches(ipPool *spiderpoolv1.SpiderIPPool) bool {
+	return e.uid == ipPool.UID &&
+		equalStrings(e.ips, ipPool.Spec.IPs) &&
+		equalStrings(e.excludeIPs, ipPool.Spec.ExcludeIPs) &&
+		equalStrings(e.excludedIPs, ipPool.Status.ExcludedIPs) &&
+		e.vacatingIPs == ipPool.Annotations[constant.AnnoIPPoolVacatingIPs]
+}
+
+// resync marks exactly the IP addresses in 'status.allocatedIPs' of the
+// IPPool as used.
+func (e *freeIPEntry) resync(ipPool *spiderpoolv1.SpiderIPPool) {
+	for i := range e.used {
+		e.used[i] = 0
+	}
+	for s := range ipPool.Status.AllocatedIPs {
+		if i, ok := e.indexOf(net.ParseIP(s)); ok {
+			e.used[i/64] |= uint64(1) << (i % 64)
+		}
+	}
+	e.resourceVersion = ipPool.ResourceVersion
+}
+
+func (e *freeIPEntry) mark(ips []string, used bool) {
+	for _, s := range ips {
+		i, ok := e.indexOf(net.ParseIP(s))
+		if !ok {
+			continue
+		}
+		if used {
+			e.used[i/64] |= uint64(1) << (i % 64)
+		} else {
+			e.used[i/64] &^= uint64(1) << (i % 64)
+		}
+	}
+}
+
+// pick searches the free bits from the cursor. The ones allocated in the
+// IPPool are marked as used and skipped, which the bits lag behind.
+func (e *freeIPEntry) pick(ipPool *spiderpoolv1.SpiderIPPool, reserved map[string]struct{}) (net.IP, bool) {
+	// Search word by word from the cursor, the word of the cursor is
+	// searched twice so that the bits before the cursor are covered.
+	words := len(e.used)
+	for n := 0; n <= words; n++ {
+		w := (e.cursor/64 + n) % words
+		free := ^e.used[w]
+		if n == 0 {
+			free &= ^uint64(0) << (e.cursor % 64)
+		}
+		if w == words-1 && e.size%64 != 0 {
+			free &= (uint64(1) << (e.size % 64)) - 1
+		}
+
+		for free != 0 {
+			b := bits.TrailingZeros64(free)
+			free &^= uint64(1) << b
+
+			i := w*64 + b
+			ip := e.ipAt(i)
+			s := ip.String()
+			if _, ok := reserved[s]; ok {
+				continue
+			}
+			if _, ok := ipPool.Status.AllocatedIPs[s]; ok {
+				e.used[w] |= uint64(1) << b
+				continue
+			}
+			e.cursor = (i + 1) % e.size
+			return ip, true
+		}
+	}
+
+	return nil, false
+}
+
+// indexOf returns the bit of the IP address.
+func (e *freeIPEntry) indexOf(ip net.IP) (int, bool) {
+	if ip == nil {
+		return 0, false
+	}
+	ip = ip.To16()
+
+	// the last segment starting at or before the IP address
+	n := sort.Search(len(e.segments), func(i int) bool {
+		return bytes.Compare(e.segments[i].start, ip) > 0
+	}) - 1
+	if n < 0 {
+		return 0, false
+	}
+
+	s := e.segments[n]
+	if !bytes.Equal(s.start[:8], ip[:8]) {
+		return 0, false
+	}
+	d := binary.BigEndian.Uint64(ip[8:]) - binary.BigEndian.Uint64(s.start[8:])
+	if d >= uint64(s.size) {
+		return 0, false
+	}
+
+	return s.offset + int(d), true
+}
+
+// ipAt returns the IP address of the bit.
+func (e *freeIPEntry) ipAt(i int) net.IP {
+	n := sort.Search(len(e.segments), func(j int) bool {
+		return e.segments[j].offset > i
+	}) - 1
+	s := e.segments[n]
+
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, s.start)
+	binary.BigEndian.PutUint64(ip[8:], binary.BigEndian.Uint64(s.start[8:])+uint64(i-s.offset))
+
+	return ip
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
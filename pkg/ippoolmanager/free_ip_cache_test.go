@@ -0,0 +1,259 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// listCountingClient counts the List calls per kind of list.
+type listCountingClient struct {
+	client.Client
+	rIPLists    int64
+	ipPoolLists int64
+}
+
+func (c *listCountingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	switch list.(type) {
+	case *spiderpoolv1.SpiderReservedIPList:
+		atomic.AddInt64(&c.rIPLists, 1)
+	case *spiderpoolv1.SpiderIPPoolList:
+		atomic.AddInt64(&c.ipPoolLists, 1)
+	}
+
+	return c.Client.List(ctx, list, opts...)
+}
+
+var _ = Describe("Free IP cache", Label("free_ip_cache_test"), func() {
+	var count uint64
+	var countingClient *listCountingClient
+	var manager ippoolmanager.IPPoolManager
+	var rIPInformer *controllertest.FakeInformer
+	var ipPoolT *spiderpoolv1.SpiderIPPool
+	var rIPT *spiderpoolv1.SpiderReservedIP
+	var podT *corev1.Pod
+	var podController types.PodTopController
+
+	BeforeEach(func() {
+		atomic.AddUint64(&count, 1)
+		countingClient = &listCountingClient{Client: fakeClient}
+
+		rIPManager, err := reservedipmanager.NewReservedIPManager(countingClient)
+		Expect(err).NotTo(HaveOccurred())
+
+		manager, err = ippoolmanager.NewIPPoolManager(
+			ippoolmanager.IPPoolManagerConfig{
+				MaxConflictRetries:    3,
+				ConflictRetryUnitTime: time.Millisecond,
+			},
+			countingClient,
+			rIPManager,
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		informers := &informertest.FakeInformers{Scheme: scheme}
+		err = manager.SetupFreeIPCacheInformers(context.TODO(), informers)
+		Expect(err).NotTo(HaveOccurred())
+
+		rIPInformer, err = informers.FakeInformerFor(&spiderpoolv1.SpiderReservedIP{})
+		Expect(err).NotTo(HaveOccurred())
+
+		ipPoolT = &spiderpoolv1.SpiderIPPool{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: fmt.Sprintf("cached-ippool-%v", count),
+			},
+			Spec: spiderpoolv1.IPPoolSpec{
+				IPVersion:  pointer.Int64(constant.IPv4),
+				Subnet:     "172.18.41.0/24",
+				IPs:        []string{"172.18.41.1-172.18.41.5", "172.18.41.100"},
+				ExcludeIPs: []string{"172.18.41.2"},
+				Vlan:       pointer.Int64(0),
+			},
+		}
+		rIPT = &spiderpoolv1.SpiderReservedIP{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       fmt.Sprintf("cached-reservedip-%v", count),
+				Generation: 1,
+			},
+			Spec: spiderpoolv1.ReservedIPSpec{
+				IPVersion: pointer.Int64(constant.IPv4),
+				IPs:       []string{"172.18.41.4"},
+			},
+		}
+		podT = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "pod",
+				UID:       apitypes.UID("pod-uid"),
+			},
+			Spec: corev1.PodSpec{NodeName: "node"},
+		}
+		podController = types.PodTopController{
+			Kind:      constant.KindPod,
+			Namespace: podT.Namespace,
+			Name:      podT.Name,
+		}
+	})
+
+	AfterEach(func() {
+		ctx := context.TODO()
+		err := fakeClient.Delete(ctx, ipPoolT, client.GracePeriodSeconds(0))
+		Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+		err = fakeClient.Delete(ctx, rIPT, client.GracePeriodSeconds(0))
+		Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+	})
+
+	It("lists the SpiderReservedIPs again only after they changed", func() {
+		ctx := context.TODO()
+		err := fakeClient.Create(ctx, ipPoolT)
+		Expect(err).NotTo(HaveOccurred())
+		err = fakeClient.Create(ctx, rIPT)
+		Expect(err).NotTo(HaveOccurred())
+		rIPInformer.Add(rIPT)
+
+		// Every allocation lists the SpiderReservedIPs once more to look for
+		// the IP address claimed by the Pod.
+		ipConfig, err := manager.AllocateIP(ctx, ipPoolT.Name, "container-0", "eth0", podT, podController)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*ipConfig.Address).To(Equal("172.18.41.1/24"))
+		Expect(atomic.LoadInt64(&countingClient.rIPLists)).To(BeEquivalentTo(2))
+		Expect(atomic.LoadInt64(&countingClient.ipPoolLists)).To(BeEquivalentTo(1))
+
+		ipConfig, err = manager.AllocateIP(ctx, ipPoolT.Name, "container-1", "eth0", podT, podController)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*ipConfig.Address).To(Equal("172.18.41.3/24"))
+		Expect(atomic.LoadInt64(&countingClient.rIPLists)).To(BeEquivalentTo(3))
+		Expect(atomic.LoadInt64(&countingClient.ipPoolLists)).To(BeEquivalentTo(2))
+
+		oldRIP := rIPT.DeepCopy()
+		rIPT.Spec.IPs = []string{"172.18.41.4", "172.18.41.5"}
+		err = fakeClient.Update(ctx, rIPT)
+		Expect(err).NotTo(HaveOccurred())
+		rIPT.Generation = oldRIP.Generation + 1
+		rIPInformer.Update(oldRIP, rIPT)
+
+		ipConfig, err = manager.AllocateIP(ctx, ipPoolT.Name, "container-2", "eth0", podT, podController)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*ipConfig.Address).To(Equal("172.18.41.100/24"))
+		Expect(atomic.LoadInt64(&countingClient.rIPLists)).To(BeEquivalentTo(5))
+		Expect(atomic.LoadInt64(&countingClient.ipPoolLists)).To(BeEquivalentTo(3))
+
+		childT := &spiderpoolv1.SpiderIPPool{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   fmt.Sprintf("cached-child-ippool-%v", count),
+				Labels: map[string]string{constant.LabelIPPoolParent: ipPoolT.Name},
+			},
+			Spec: spiderpoolv1.IPPoolSpec{
+				IPVersion:  pointer.Int64(constant.IPv4),
+				Subnet:     ipPoolT.Spec.Subnet,
+				IPs:        []string{"172.18.41.100"},
+				ParentPool: pointer.String(ipPoolT.Name),
+			},
+		}
+		err = fakeClient.Create(ctx, childT)
+		Expect(err).NotTo(HaveOccurred())
+		defer func() {
+			err := fakeClient.Delete(ctx, childT, client.GracePeriodSeconds(0))
+			Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+		}()
+
+		err = manager.ReleaseIP(ctx, ipPoolT.Name, []types.IPAndCID{{IP: "172.18.41.100", ContainerID: "container-2"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		// The child IPPools are listed on every allocation.
+		_, err = manager.AllocateIP(ctx, ipPoolT.Name, "container-3", "eth0", podT, podController)
+		Expect(err).To(MatchError(constant.ErrIPUsedOut))
+		Expect(atomic.LoadInt64(&countingClient.ipPoolLists)).To(BeEquivalentTo(4))
+	})
+
+	It("lists the SpiderReservedIPs again once the labels of the IPPool are changed", func() {
+		ctx := context.TODO()
+		err := fakeClient.Create(ctx, ipPoolT)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = manager.AllocateIP(ctx, ipPoolT.Name, "container-0", "eth0", podT, podController)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt64(&countingClient.rIPLists)).To(BeEquivalentTo(2))
+
+		var ipPool spiderpoolv1.SpiderIPPool
+		err = fakeClient.Get(ctx, apitypes.NamespacedName{Name: ipPoolT.Name}, &ipPool)
+		Expect(err).NotTo(HaveOccurred())
+		ipPool.Labels = map[string]string{"zone": "a"}
+		err = fakeClient.Update(ctx, &ipPool)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = manager.AllocateIP(ctx, ipPoolT.Name, "container-1", "eth0", podT, podController)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt64(&countingClient.rIPLists)).To(BeEquivalentTo(4))
+	})
+
+	It("allocates the IP addresses released by others once the others are used out", func() {
+		ctx := context.TODO()
+		ipPoolT.Spec.IPs = []string{"172.18.41.1-172.18.41.3"}
+		ipPoolT.Spec.ExcludeIPs = nil
+		err := fakeClient.Create(ctx, ipPoolT)
+		Expect(err).NotTo(HaveOccurred())
+
+		for i := 0; i < 2; i++ {
+			_, err := manager.AllocateIP(ctx, ipPoolT.Name, fmt.Sprintf("container-%d", i), "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		var ipPool spiderpoolv1.SpiderIPPool
+		err = fakeClient.Get(ctx, apitypes.NamespacedName{Name: ipPoolT.Name}, &ipPool)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ipPool.Status.AllocatedIPs).To(HaveLen(2))
+		delete(ipPool.Status.AllocatedIPs, "172.18.41.1")
+		err = fakeClient.Status().Update(ctx, &ipPool)
+		Expect(err).NotTo(HaveOccurred())
+
+		ipConfig, err := manager.AllocateIP(ctx, ipPoolT.Name, "container-2", "eth0", podT, podController)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*ipConfig.Address).To(Equal("172.18.41.3/24"))
+
+		ipConfig, err = manager.AllocateIP(ctx, ipPoolT.Name, "container-3", "eth0", podT, podController)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*ipConfig.Address).To(Equal("172.18.41.1/24"))
+	})
+
+	It("skips the IP addresses allocated by others", func() {
+		ctx := context.TODO()
+		err := fakeClient.Create(ctx, ipPoolT)
+		Expect(err).NotTo(HaveOccurred())
+
+		ipConfig, err := manager.AllocateIP(ctx, ipPoolT.Name, "container-0", "eth0", podT, podController)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*ipConfig.Address).To(Equal("172.18.41.1/24"))
+
+		var ipPool spiderpoolv1.SpiderIPPool
+		err = fakeClient.Get(ctx, apitypes.NamespacedName{Name: ipPoolT.Name}, &ipPool)
+		Expect(err).NotTo(HaveOccurred())
+		ipPool.Status.AllocatedIPs["172.18.41.3"] = ipPool.Status.AllocatedIPs["172.18.41.1"]
+		err = fakeClient.Status().Update(ctx, &ipPool)
+		Expect(err).NotTo(HaveOccurred())
+
+		ipConfig, err = manager.AllocateIP(ctx, ipPoolT.Name, "container-1", "eth0", podT, podController)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*ipConfig.Address).To(Equal("172.18.41.4/24"))
+	})
+})
@@ -0,0 +1,285 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
+)
+
+// The categories of the mismatches found by the IP consistency audit.
+const (
+	// AuditAllocatedWithoutEndpoint is the IP address allocated in the
+	// IPPool, but not recorded by the SpiderEndpoint of the Pod.
+	AuditAllocatedWithoutEndpoint = "AllocatedWithoutEndpoint"
+	// AuditAllocatedWithoutPod is the IP address allocated in the IPPool to
+	// the Pod which does not exist.
+	AuditAllocatedWithoutPod = "AllocatedWithoutPod"
+	// AuditEndpointWithoutPoolRecord is the current IP address of the
+	// SpiderEndpoint, which is not allocated to the same container in the
+	// IPPool.
+	AuditEndpointWithoutPoolRecord = "EndpointWithoutPoolRecord"
+	// AuditEndpointWithoutPod is the SpiderEndpoint of the Pod which does
+	// not exist.
+	AuditEndpointWithoutPod = "EndpointWithoutPod"
+	// AuditDoubleAllocation is the IP address allocated in more than one
+	// IPPool, or currently taken by more than one SpiderEndpoint, of the
+	// same tenant.
+	AuditDoubleAllocation = "DoubleAllocation"
+)
+
+// AuditMismatch is an inconsistency among the IPPools, SpiderEndpoints and
+// Pods.
+type AuditMismatch struct {
+	Category    string
+	IP          string
+	Pool        string
+	Tenant      string
+	Namespace   string
+	Pod         string
+	ContainerID string
+	Message     string
+}
+
+// AuditIPAllocations cross-checks the IP allocations of the IPPools, the
+// SpiderEndpoints and the Pods, and returns all the mismatches in order.
+// The objects are listed at different moments, so the allocations in
+// progress may be reported as well, they are transient unlike the real
+// inconsistencies.
+func AuditIPAllocations(pools []spiderpoolv1.SpiderIPPool, endpoints []spiderpoolv1.SpiderEndpoint, pods []corev1.Pod) []AuditMismatch {
+	podSet := make(map[types.NamespacedName]struct{}, len(pods))
+	for _, pod := range pods {
+		podSet[types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}] = struct{}{}
+	}
+	endpointMap := make(map[types.NamespacedName]*spiderpoolv1.SpiderEndpoint, len(endpoints))
+	for i := range endpoints {
+		endpointMap[types.NamespacedName{Namespace: endpoints[i].Namespace, Name: endpoints[i].Name}] = &endpoints[i]
+	}
+	poolMap := make(map[string]*spiderpoolv1.SpiderIPPool, len(pools))
+	for i := range pools {
+		poolMap[pools[i].Name] = &pools[i]
+	}
+
+	var mismatches []AuditMismatch
+	for _, pool := range pools {
+		for ip, allocation := range pool.Status.AllocatedIPs {
+			m := AuditMismatch{
+				IP:          ip,
+				Pool:        pool.Name,
+				Namespace:   allocation.Namespace,
+				Pod:         allocation.Pod,
+				ContainerID: allocation.ContainerID,
+			}
+
+			key := types.NamespacedName{Namespace: allocation.Namespace, Name: allocation.Pod}
+			if _, ok := podSet[key]; !ok {
+				m.Category = AuditAllocatedWithoutPod
+				m.Message = "the Pod does not exist"
+				mismatches = append(mismatches, m)
+			}
+
+			endpoint, ok := endpointMap[key]
+			switch {
+			case !ok:
+				m.Category = AuditAllocatedWithoutEndpoint
+				m.Message = "the SpiderEndpoint does not exist"
+				mismatches = append(mismatches, m)
+			case !hasEndpointRecord(endpoint, pool.Name, ip):
+				m.Category = AuditAllocatedWithoutEndpoint
+				m.Message = "the SpiderEndpoint has no record of the IP address"
+				mismatches = append(mismatches, m)
+			}
+		}
+	}
+
+	for _, endpoint := range endpoints {
+		key := types.NamespacedName{Namespace: endpoint.Namespace, Name: endpoint.Name}
+		if _, ok := podSet[key]; !ok {
+			mismatches = append(mismatches, AuditMismatch{
+				Category:  AuditEndpointWithoutPod,
+				Namespace: endpoint.Namespace,
+				Pod:       endpoint.Name,
+				Message:   "the Pod does not exist",
+			})
+		}
+
+		for _, m := range currentEndpointClaims(&endpoint) {
+			if msg := checkPoolRecord(poolMap[m.Pool], m.IP, m.Namespace, m.Pod, m.ContainerID); msg != "" {
+				m.Category = AuditEndpointWithoutPoolRecord
+				m.Message = msg
+				mismatches = append(mismatches, m)
+			}
+		}
+	}
+
+	mismatches = append(mismatches, FindDoubleAllocations(pools, endpoints)...)
+	sortAuditMismatches(mismatches)
+
+	return mismatches
+}
+
+// FindDoubleAllocations returns every claimant of the IP addresses allocated
+// in more than one IPPool, or currently taken by more than one
+// SpiderEndpoint, in order. The IPPools of different tenants are allowed to
+// overlap, so the claims are only compared within the same tenant.
+func FindDoubleAllocations(pools []spiderpoolv1.SpiderIPPool, endpoints []spiderpoolv1.SpiderEndpoint) []AuditMismatch {
+	poolTenants := make(map[string]string, len(pools))
+	poolClaims := map[tenantIP][]AuditMismatch{}
+	for i := range pools {
+		pool := &pools[i]
+		tenant := GetIPPoolTenant(pool)
+		poolTenants[pool.Name] = tenant
+		for ip, allocation := range pool.Status.AllocatedIPs {
+			key := tenantIP{tenant: tenant, ip: ip}
+			poolClaims[key] = append(poolClaims[key], AuditMismatch{
+				IP:          ip,
+				Pool:        pool.Name,
+				Tenant:      tenant,
+				Namespace:   allocation.Namespace,
+				Pod:         allocation.Pod,
+				ContainerID: allocation.ContainerID,
+			})
+		}
+	}
+
+	endpointClaims := map[tenantIP][]AuditMismatch{}
+	for i := range endpoints {
+		for _, m := range currentEndpointClaims(&endpoints[i]) {
+			m.Tenant = poolTenants[m.Pool]
+			key := tenantIP{tenant: m.Tenant, ip: m.IP}
+			endpointClaims[key] = append(endpointClaims[key], m)
+		}
+	}
+
+	mismatches := doubleAllocations(poolClaims, "IPPools")
+	mismatches = append(mismatches, doubleAllocations(endpointClaims, "SpiderEndpoints")...)
+	sortAuditMismatches(mismatches)
+
+	return mismatches
+}
+
+// tenantIP is an IP address in the scope of a tenant.
+type tenantIP struct {
+	tenant string
+	ip     string
+}
+
+// currentEndpointClaims returns the current IP addresses of the
+// SpiderEndpoint.
+func currentEndpointClaims(endpoint *spiderpoolv1.SpiderEndpoint) []AuditMismatch {
+	current := endpoint.Status.Current
+	if current == nil {
+		return nil
+	}
+
+	var claims []AuditMismatch
+	for _, d := range current.IPs {
+		for _, a := range []struct{ ip, pool *string }{{d.IPv4, d.IPv4Pool}, {d.IPv6, d.IPv6Pool}} {
+			if a.ip == nil || a.pool == nil {
+				continue
+			}
+			ip, _, _ := strings.Cut(*a.ip, "/")
+			claims = append(claims, AuditMismatch{
+				IP:          ip,
+				Pool:        *a.pool,
+				Namespace:   endpoint.Namespace,
+				Pod:         endpoint.Name,
+				ContainerID: current.ContainerID,
+			})
+		}
+	}
+
+	return claims
+}
+
+func sortAuditMismatches(mismatches []AuditMismatch) {
+	sort.Slice(mismatches, func(i, j int) bool {
+		a, b := mismatches[i], mismatches[j]
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		if a.IP != b.IP {
+			return a.IP < b.IP
+		}
+		if a.Tenant != b.Tenant {
+			return a.Tenant < b.Tenant
+		}
+		if a.Pool != b.Pool {
+			return a.Pool < b.Pool
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Pod != b.Pod {
+			return a.Pod < b.Pod
+		}
+		return a.Message < b.Message
+	})
+}
+
+// hasEndpointRecord reports whether the IP address of the IPPool is among
+// the current or historical IP allocations of the SpiderEndpoint.
+func hasEndpointRecord(endpoint *spiderpoolv1.SpiderEndpoint, poolName, ip string) bool {
+	if workloadendpointmanager.HasCurrentIP(endpoint, poolName, ip) {
+		return true
+	}
+
+	for _, ipAndCID := range workloadendpointmanager.ListAllHistoricalIPs(endpoint)[poolName] {
+		if ipAndCID.IP == ip {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkPoolRecord tells why the IP address of the IPPool is not allocated
+// to the container of the Pod, empty if it is.
+func checkPoolRecord(pool *spiderpoolv1.SpiderIPPool, ip, namespace, podName, containerID string) string {
+	if pool == nil {
+		return "the IPPool does not exist"
+	}
+
+	allocation, ok := pool.Status.AllocatedIPs[ip]
+	switch {
+	case !ok:
+		return "the IP address is not allocated in the IPPool"
+	case allocation.Namespace != namespace || allocation.Pod != podName:
+		return fmt.Sprintf("the IP address is allocated to Pod '%s/%s' in the IPPool", allocation.Namespace, allocation.Pod)
+	case allocation.ContainerID != containerID:
+		return fmt.Sprintf("the IP address is allocated to container '%s' in the IPPool", allocation.ContainerID)
+	}
+
+	return ""
+}
+
+// doubleAllocations reports every claimant of the IP addresses claimed more
+// than once in the same tenant.
+func doubleAllocations(claims map[tenantIP][]AuditMismatch, kind string) []AuditMismatch {
+	var mismatches []AuditMismatch
+	for key, claimants := range claims {
+		if len(claimants) < 2 {
+			continue
+		}
+
+		msg := fmt.Sprintf("the IP address is claimed by %d %s", len(claimants), kind)
+		if key.tenant != "" {
+			msg += fmt.Sprintf(" of tenant '%s'", key.tenant)
+		}
+		for _, m := range claimants {
+			m.Category = AuditDoubleAllocation
+			m.Message = msg
+			mismatches = append(mismatches, m)
+		}
+	}
+
+	return mismatches
+}
@@ -0,0 +1,200 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+)
+
+var _ = Describe("AuditIPAllocations", Label("ippool_audit_test"), func() {
+	var pools []spiderpoolv1.SpiderIPPool
+	var endpoints []spiderpoolv1.SpiderEndpoint
+	var pods []corev1.Pod
+
+	newPool := func(name string, allocatedIPs spiderpoolv1.PoolIPAllocations) spiderpoolv1.SpiderIPPool {
+		pool := spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		pool.Status.AllocatedIPs = allocatedIPs
+		return pool
+	}
+	newEndpoint := func(namespace, name, containerID, pool, ip string) spiderpoolv1.SpiderEndpoint {
+		endpoint := spiderpoolv1.SpiderEndpoint{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+		endpoint.Status.Current = &spiderpoolv1.PodIPAllocation{
+			ContainerID: containerID,
+			IPs: []spiderpoolv1.IPAllocationDetail{{
+				NIC:      "eth0",
+				IPv4:     pointer.String(ip + "/24"),
+				IPv4Pool: pointer.String(pool),
+			}},
+		}
+		return endpoint
+	}
+	newPod := func(namespace, name string) corev1.Pod {
+		return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	}
+
+	BeforeEach(func() {
+		pools = []spiderpoolv1.SpiderIPPool{
+			newPool("pool1", spiderpoolv1.PoolIPAllocations{
+				"172.18.40.10": {ContainerID: "c1", NIC: "eth0", Namespace: "default", Pod: "pod1"},
+			}),
+		}
+		endpoints = []spiderpoolv1.SpiderEndpoint{newEndpoint("default", "pod1", "c1", "pool1", "172.18.40.10")}
+		pods = []corev1.Pod{newPod("default", "pod1")}
+	})
+
+	It("reports nothing if the IP allocations are consistent", func() {
+		Expect(ippoolmanager.AuditIPAllocations(pools, endpoints, pods)).To(BeEmpty())
+	})
+
+	It("reports the IP address allocated to the Pod without SpiderEndpoint or Pod", func() {
+		endpoints = nil
+		pods = nil
+
+		mismatches := ippoolmanager.AuditIPAllocations(pools, endpoints, pods)
+		Expect(mismatches).To(HaveLen(2))
+		Expect(mismatches[0].Category).To(Equal(ippoolmanager.AuditAllocatedWithoutEndpoint))
+		Expect(mismatches[1].Category).To(Equal(ippoolmanager.AuditAllocatedWithoutPod))
+		Expect(mismatches[0].IP).To(Equal("172.18.40.10"))
+		Expect(mismatches[0].Pool).To(Equal("pool1"))
+	})
+
+	It("does not report the IP address in the history of the SpiderEndpoint", func() {
+		endpoints[0].Status.History = []spiderpoolv1.PodIPAllocation{*endpoints[0].Status.Current}
+		endpoints[0].Status.Current = nil
+
+		Expect(ippoolmanager.AuditIPAllocations(pools, endpoints, pods)).To(BeEmpty())
+	})
+
+	It("reports the current IP address of the SpiderEndpoint not allocated in the IPPool", func() {
+		pools[0].Status.AllocatedIPs = nil
+
+		mismatches := ippoolmanager.AuditIPAllocations(pools, endpoints, pods)
+		Expect(mismatches).To(HaveLen(1))
+		Expect(mismatches[0].Category).To(Equal(ippoolmanager.AuditEndpointWithoutPoolRecord))
+		Expect(mismatches[0].Namespace).To(Equal("default"))
+		Expect(mismatches[0].Pod).To(Equal("pod1"))
+		Expect(mismatches[0].ContainerID).To(Equal("c1"))
+	})
+
+	It("reports the current IP address of the SpiderEndpoint allocated to another container", func() {
+		allocation := pools[0].Status.AllocatedIPs["172.18.40.10"]
+		allocation.ContainerID = "c0"
+		pools[0].Status.AllocatedIPs["172.18.40.10"] = allocation
+
+		mismatches := ippoolmanager.AuditIPAllocations(pools, endpoints, pods)
+		Expect(mismatches).To(HaveLen(1))
+		Expect(mismatches[0].Category).To(Equal(ippoolmanager.AuditEndpointWithoutPoolRecord))
+		Expect(mismatches[0].Message).To(ContainSubstring("c0"))
+	})
+
+	It("reports the SpiderEndpoint without Pod", func() {
+		pods = append(pods, newPod("default", "pod2"))
+		endpoints = append(endpoints, spiderpoolv1.SpiderEndpoint{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod3"}})
+
+		mismatches := ippoolmanager.AuditIPAllocations(pools, endpoints, pods)
+		Expect(mismatches).To(HaveLen(1))
+		Expect(mismatches[0].Category).To(Equal(ippoolmanager.AuditEndpointWithoutPod))
+		Expect(mismatches[0].Pod).To(Equal("pod3"))
+	})
+
+	It("reports every claimant of the IP address allocated more than once", func() {
+		pools = append(pools, newPool("pool2", spiderpoolv1.PoolIPAllocations{
+			"172.18.40.10": {ContainerID: "c2", NIC: "eth0", Namespace: "default", Pod: "pod2"},
+		}))
+		endpoints = append(endpoints, newEndpoint("default", "pod2", "c2", "pool2", "172.18.40.10"))
+		pods = append(pods, newPod("default", "pod2"))
+
+		mismatches := ippoolmanager.AuditIPAllocations(pools, endpoints, pods)
+		Expect(mismatches).To(HaveLen(4))
+		for _, m := range mismatches {
+			Expect(m.Category).To(Equal(ippoolmanager.AuditDoubleAllocation))
+			Expect(m.IP).To(Equal("172.18.40.10"))
+		}
+		Expect(mismatches[0].Pool).To(Equal("pool1"))
+		Expect(mismatches[2].Pool).To(Equal("pool2"))
+	})
+
+	It("does not report the IP address allocated in the IPPools of different tenants", func() {
+		pools[0].Spec.Tenant = pointer.String("tenant1")
+		pool2 := newPool("pool2", spiderpoolv1.PoolIPAllocations{
+			"172.18.40.10": {ContainerID: "c2", NIC: "eth0", Namespace: "default", Pod: "pod2"},
+		})
+		pool2.Spec.Tenant = pointer.String("tenant2")
+		pools = append(pools, pool2)
+		endpoints = append(endpoints, newEndpoint("default", "pod2", "c2", "pool2", "172.18.40.10"))
+		pods = append(pods, newPod("default", "pod2"))
+
+		Expect(ippoolmanager.AuditIPAllocations(pools, endpoints, pods)).To(BeEmpty())
+	})
+
+	It("reports the IP address allocated twice in the IPPools of the same tenant", func() {
+		pools[0].Spec.Tenant = pointer.String("tenant1")
+		pool2 := newPool("pool2", spiderpoolv1.PoolIPAllocations{
+			"172.18.40.10": {ContainerID: "c2", NIC: "eth0", Namespace: "default", Pod: "pod2"},
+		})
+		pool2.Spec.Tenant = pointer.String("tenant1")
+		pools = append(pools, pool2)
+		endpoints = append(endpoints, newEndpoint("default", "pod2", "c2", "pool2", "172.18.40.10"))
+		pods = append(pods, newPod("default", "pod2"))
+
+		mismatches := ippoolmanager.AuditIPAllocations(pools, endpoints, pods)
+		Expect(mismatches).To(HaveLen(4))
+		for _, m := range mismatches {
+			Expect(m.Category).To(Equal(ippoolmanager.AuditDoubleAllocation))
+			Expect(m.Tenant).To(Equal("tenant1"))
+			Expect(m.Message).To(ContainSubstring("tenant1"))
+		}
+	})
+})
+
+var _ = Describe("FindDoubleAllocations", Label("ippool_audit_test"), func() {
+	newPool := func(name, tenant, podName string) spiderpoolv1.SpiderIPPool {
+		pool := spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if tenant != "" {
+			pool.Spec.Tenant = pointer.String(tenant)
+		}
+		pool.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{
+			"172.18.40.10": {ContainerID: podName, NIC: "eth0", Namespace: "default", Pod: podName},
+		}
+		return pool
+	}
+	newEndpoint := func(name, pool string) spiderpoolv1.SpiderEndpoint {
+		endpoint := spiderpoolv1.SpiderEndpoint{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+		endpoint.Status.Current = &spiderpoolv1.PodIPAllocation{
+			ContainerID: name,
+			IPs: []spiderpoolv1.IPAllocationDetail{{
+				NIC:      "eth0",
+				IPv4:     pointer.String("172.18.40.10/24"),
+				IPv4Pool: pointer.String(pool),
+			}},
+		}
+		return endpoint
+	}
+
+	It("ignores the IP address claimed in overlapping IPPools of different tenants", func() {
+		pools := []spiderpoolv1.SpiderIPPool{newPool("pool1", "tenant1", "pod1"), newPool("pool2", "tenant2", "pod2"), newPool("pool3", "", "pod3")}
+		endpoints := []spiderpoolv1.SpiderEndpoint{newEndpoint("pod1", "pool1"), newEndpoint("pod2", "pool2"), newEndpoint("pod3", "pool3")}
+
+		Expect(ippoolmanager.FindDoubleAllocations(pools, endpoints)).To(BeEmpty())
+	})
+
+	It("reports the IP address claimed twice in the same tenant with the tenant", func() {
+		pools := []spiderpoolv1.SpiderIPPool{newPool("pool1", "tenant1", "pod1"), newPool("pool2", "tenant1", "pod2"), newPool("pool3", "tenant2", "pod3")}
+		endpoints := []spiderpoolv1.SpiderEndpoint{newEndpoint("pod1", "pool1"), newEndpoint("pod2", "pool2"), newEndpoint("pod3", "pool3")}
+
+		mismatches := ippoolmanager.FindDoubleAllocations(pools, endpoints)
+		Expect(mismatches).To(HaveLen(4))
+		for _, m := range mismatches {
+			Expect(m.Tenant).To(Equal("tenant1"))
+			Expect(m.Pool).NotTo(Equal("pool3"))
+		}
+	})
+})
@@ -0,0 +1,223 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// maxIPPoolSpecChanges caps the spec changes kept in the annotation of the
+// IPPool waiting to be moved into its status.
+const maxIPPoolSpecChanges = 10
+
+// recordIPPoolSpecChange captures the diff between the spec of the IPPool
+// being updated and the stored one, and appends it with the requesting user
+// to the spec changes in the annotation of the stored IPPool, which keeps
+// the latest maxIPPoolSpecChanges ones. The annotation is persisted along
+// with the update, then the IPPool informer moves the changes into the
+// changelog of the IPPool status.
+func (iw *IPPoolWebhook) recordIPPoolSpecChange(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool) error {
+	if ipPool.DeletionTimestamp != nil || ipPool.Spec.IPVersion == nil {
+		return nil
+	}
+
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil || req.Operation != admissionv1.Update {
+		return nil
+	}
+
+	var oldIPPool spiderpoolv1.SpiderIPPool
+	if err := json.Unmarshal(req.OldObject.Raw, &oldIPPool); err != nil {
+		return fmt.Errorf("failed to decode the old IPPool: %v", err)
+	}
+
+	change, err := genIPPoolSpecChange(*ipPool.Spec.IPVersion, &oldIPPool.Spec, &ipPool.Spec)
+	if err != nil {
+		return err
+	}
+	if change == nil {
+		return nil
+	}
+	change.User = req.UserInfo.Username
+	change.Time = metav1.Now()
+
+	// The changes are taken from the stored IPPool, the request may carry
+	// a stale annotation.
+	logger := logutils.FromContext(ctx)
+	changes, err := getIPPoolSpecChanges(&oldIPPool)
+	if err != nil {
+		logger.Sugar().Warnf("Drop the recorded spec changes: %v", err)
+		changes = nil
+	}
+	changes = append(changes, *change)
+	if n := len(changes); n > maxIPPoolSpecChanges {
+		changes = changes[n-maxIPPoolSpecChanges:]
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	if ipPool.Annotations == nil {
+		ipPool.Annotations = make(map[string]string)
+	}
+	ipPool.Annotations[constant.AnnoIPPoolSpecChanges] = string(data)
+	logger.Sugar().Infof("Spec is changed by %s: %s", change.User, ipPoolSpecChangeString(change))
+
+	return nil
+}
+
+// genIPPoolSpecChange returns the change from the old spec to the new one,
+// or nil if nothing is changed.
+func genIPPoolSpecChange(version types.IPVersion, oldSpec, newSpec *spiderpoolv1.IPPoolSpec) (*spiderpoolv1.IPPoolSpecChange, error) {
+	change := &spiderpoolv1.IPPoolSpecChange{}
+
+	var err error
+	change.AddedIPs, change.RemovedIPs, err = diffIPRanges(version, oldSpec.IPs, newSpec.IPs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare 'spec.ips': %v", err)
+	}
+
+	change.AddedExcludeIPs, change.RemovedExcludeIPs, err = diffIPRanges(version, oldSpec.ExcludeIPs, newSpec.ExcludeIPs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare 'spec.excludeIPs': %v", err)
+	}
+
+	fields := []struct {
+		name     string
+		old, new interface{}
+	}{
+		{"subnet", oldSpec.Subnet, newSpec.Subnet},
+		{"disable", oldSpec.Disable, newSpec.Disable},
+		{"drain", oldSpec.Drain, newSpec.Drain},
+		{"gateway", oldSpec.Gateway, newSpec.Gateway},
+		{"standbyGateways", oldSpec.StandbyGateways, newSpec.StandbyGateways},
+		{"excludeGateways", oldSpec.ExcludeGateways, newSpec.ExcludeGateways},
+		{"autoExcludeGateways", oldSpec.AutoExcludeGateways, newSpec.AutoExcludeGateways},
+		{"vlan", oldSpec.Vlan, newSpec.Vlan},
+		{"vlanRanges", oldSpec.VlanRanges, newSpec.VlanRanges},
+		{"routes", oldSpec.Routes, newSpec.Routes},
+		{"macPrefix", oldSpec.MACPrefix, newSpec.MACPrefix},
+		{"inheritSubnetRoutes", oldSpec.InheritSubnetRoutes, newSpec.InheritSubnetRoutes},
+		{"defaultRouteMetric", oldSpec.DefaultRouteMetric, newSpec.DefaultRouteMetric},
+		{"dns", oldSpec.DNS, newSpec.DNS},
+		{"leaseDurationSeconds", oldSpec.LeaseDurationSeconds, newSpec.LeaseDurationSeconds},
+		{"podAffinity", oldSpec.PodAffinity, newSpec.PodAffinity},
+		{"namespaceAffinity", oldSpec.NamespaceAffinity, newSpec.NamespaceAffinity},
+		{"allowedNamespaces", oldSpec.AllowedNamespaces, newSpec.AllowedNamespaces},
+		{"nodeAffinity", oldSpec.NodeAffinity, newSpec.NodeAffinity},
+	}
+	for _, f := range fields {
+		if !reflect.DeepEqual(f.old, f.new) {
+			change.Fields = append(change.Fields, f.name)
+		}
+	}
+
+	if len(change.AddedIPs) == 0 && len(change.RemovedIPs) == 0 &&
+		len(change.AddedExcludeIPs) == 0 && len(change.RemovedExcludeIPs) == 0 &&
+		len(change.Fields) == 0 {
+		return nil, nil
+	}
+
+	return change, nil
+}
+
+// diffIPRanges returns the IP ranges added to and removed from the old IP
+// ranges.
+func diffIPRanges(version types.IPVersion, oldIPRanges, newIPRanges []string) ([]string, []string, error) {
+	oldIPs, err := spiderpoolip.ParseIPRanges(version, oldIPRanges)
+	if err != nil {
+		return nil, nil, err
+	}
+	newIPs, err := spiderpoolip.ParseIPRanges(version, newIPRanges)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	added, err := spiderpoolip.ConvertIPsToIPRanges(version, spiderpoolip.IPsDiffSet(newIPs, oldIPs, false))
+	if err != nil {
+		return nil, nil, err
+	}
+	removed, err := spiderpoolip.ConvertIPsToIPRanges(version, spiderpoolip.IPsDiffSet(oldIPs, newIPs, false))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return added, removed, nil
+}
+
+// getIPPoolSpecChanges returns the spec changes recorded in the annotation
+// of the IPPool by the webhook.
+func getIPPoolSpecChanges(ipPool *spiderpoolv1.SpiderIPPool) ([]spiderpoolv1.IPPoolSpecChange, error) {
+	anno, ok := ipPool.Annotations[constant.AnnoIPPoolSpecChanges]
+	if !ok {
+		return nil, nil
+	}
+
+	var changes []spiderpoolv1.IPPoolSpecChange
+	if err := json.Unmarshal([]byte(anno), &changes); err != nil {
+		return nil, fmt.Errorf("%w, invalid format of annotation '%s': %v", constant.ErrWrongInput, constant.AnnoIPPoolSpecChanges, err)
+	}
+
+	return changes, nil
+}
+
+// getUnrecordedIPPoolSpecChanges returns the spec changes recorded in the
+// annotation of the IPPool by the webhook which come after the last one in
+// the changelog of the IPPool status. All of them are unrecorded if the
+// last one is not found, since it has been dropped from the annotation.
+func getUnrecordedIPPoolSpecChanges(ipPool *spiderpoolv1.SpiderIPPool) ([]spiderpoolv1.IPPoolSpecChange, error) {
+	changes, err := getIPPoolSpecChanges(ipPool)
+	if err != nil {
+		return nil, err
+	}
+
+	if n := len(ipPool.Status.SpecChangelog); n != 0 {
+		last := ipPool.Status.SpecChangelog[n-1]
+		for j := len(changes) - 1; j >= 0; j-- {
+			if changes[j].User == last.User && changes[j].Time.Equal(&last.Time) &&
+				ipPoolSpecChangeString(&changes[j]) == ipPoolSpecChangeString(&last) {
+				return changes[j+1:], nil
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+func ipPoolSpecChangeString(change *spiderpoolv1.IPPoolSpecChange) string {
+	var items []string
+	if len(change.AddedIPs) != 0 {
+		items = append(items, fmt.Sprintf("added IPs %v", change.AddedIPs))
+	}
+	if len(change.RemovedIPs) != 0 {
+		items = append(items, fmt.Sprintf("removed IPs %v", change.RemovedIPs))
+	}
+	if len(change.AddedExcludeIPs) != 0 {
+		items = append(items, fmt.Sprintf("added excluded IPs %v", change.AddedExcludeIPs))
+	}
+	if len(change.RemovedExcludeIPs) != 0 {
+		items = append(items, fmt.Sprintf("removed excluded IPs %v", change.RemovedExcludeIPs))
+	}
+	if len(change.Fields) != 0 {
+		items = append(items, fmt.Sprintf("changed fields %v", change.Fields))
+	}
+
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,84 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+)
+
+var _ = Describe("IPPool spec changelog", Label("ippool_changelog_test"), func() {
+	now := time.Now().Truncate(time.Second)
+	newChange := func(user string, offset time.Duration) spiderpoolv1.IPPoolSpecChange {
+		return spiderpoolv1.IPPoolSpecChange{
+			User:   user,
+			Time:   metav1.NewTime(now.Add(offset)),
+			Fields: []string{"gateway"},
+		}
+	}
+	newIPPool := func(changes ...spiderpoolv1.IPPoolSpecChange) *spiderpoolv1.SpiderIPPool {
+		data, err := json.Marshal(changes)
+		Expect(err).NotTo(HaveOccurred())
+		return &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{
+			Name:        "pool",
+			Annotations: map[string]string{constant.AnnoIPPoolSpecChanges: string(data)},
+		}}
+	}
+	usersOf := func(changes []spiderpoolv1.IPPoolSpecChange) []string {
+		var users []string
+		for _, change := range changes {
+			users = append(users, change.User)
+		}
+		return users
+	}
+
+	It("returns nothing without the annotation", func() {
+		changes, err := getUnrecordedIPPoolSpecChanges(&spiderpoolv1.SpiderIPPool{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(BeEmpty())
+	})
+
+	It("fails with the invalid annotation", func() {
+		pool := &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{constant.AnnoIPPoolSpecChanges: "{"},
+		}}
+		_, err := getUnrecordedIPPoolSpecChanges(pool)
+		Expect(err).To(MatchError(constant.ErrWrongInput))
+	})
+
+	It("returns all changes without the changelog", func() {
+		pool := newIPPool(newChange("alice", 0), newChange("bob", time.Second))
+		changes, err := getUnrecordedIPPoolSpecChanges(pool)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(usersOf(changes)).To(Equal([]string{"alice", "bob"}))
+	})
+
+	It("returns the changes after the last one in the changelog", func() {
+		pool := newIPPool(newChange("alice", 0), newChange("bob", time.Second), newChange("carol", 2*time.Second))
+		pool.Status.SpecChangelog = []spiderpoolv1.IPPoolSpecChange{newChange("alice", 0)}
+		changes, err := getUnrecordedIPPoolSpecChanges(pool)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(usersOf(changes)).To(Equal([]string{"bob", "carol"}))
+
+		pool.Status.SpecChangelog = append(pool.Status.SpecChangelog, changes...)
+		changes, err = getUnrecordedIPPoolSpecChanges(pool)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(BeEmpty())
+	})
+
+	It("returns all changes if the last one in the changelog has been dropped", func() {
+		pool := newIPPool(newChange("bob", time.Second), newChange("carol", 2*time.Second))
+		pool.Status.SpecChangelog = []spiderpoolv1.IPPoolSpecChange{newChange("alice", 0)}
+		changes, err := getUnrecordedIPPoolSpecChanges(pool)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(usersOf(changes)).To(Equal([]string{"bob", "carol"}))
+	})
+})
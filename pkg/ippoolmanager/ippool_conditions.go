@@ -0,0 +1,481 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+)
+
+// genIPPoolConditions returns the conditions "Exhausted", "Conflicting",
+// "Orphaned", "GatewayUnreachable" and "Ready" of the IPPool. The condition
+// "Conflicting" is computed by listing the IPPools and SpiderReservedIPs,
+// which is expensive, so the existing one is reused if fresh is false.
+func (ic *IPPoolController) genIPPoolConditions(ctx context.Context, pool *spiderpoolv1.SpiderIPPool, fresh bool) ([]metav1.Condition, error) {
+	exhausted := genIPPoolExhaustedCondition(pool)
+
+	var conflicting metav1.Condition
+	if cond := apimeta.FindStatusCondition(pool.Status.Conditions, constant.IPPoolConditionConflicting); cond != nil && !fresh {
+		conflicting = *cond
+	} else {
+		overlaps, err := ic.overlappingIPPools(pool)
+		if err != nil {
+			return nil, err
+		}
+		reservedIPs, err := ic.allocatedReservedIPs(ctx, pool)
+		if err != nil {
+			return nil, err
+		}
+		conflicting = genIPPoolConflictingCondition(pool, overlaps, reservedIPs)
+	}
+
+	conditions := []metav1.Condition{exhausted, conflicting}
+
+	notReady := map[string]bool{
+		constant.IPPoolReasonDisabled:    pool.Spec.Disable != nil && *pool.Spec.Disable,
+		constant.IPPoolReasonDraining:    IsDrainingIPPool(pool),
+		constant.IPPoolReasonQuarantined: IsQuarantinedIPPool(pool),
+		constant.IPPoolReasonReshaping:   IsReshapingIPPool(pool),
+		constant.IPPoolReasonExhausted:   exhausted.Status == metav1.ConditionTrue,
+		constant.IPPoolReasonConflicting: conflicting.Status == metav1.ConditionTrue,
+	}
+
+	if ic.EnableSpiderSubnet {
+		orphaned := ic.genIPPoolOrphanedCondition(pool)
+		conditions = append(conditions, orphaned)
+		notReady[constant.IPPoolReasonOrphaned] = orphaned.Status == metav1.ConditionTrue
+	}
+
+	// An unreachable gateway doesn't stop the allocation by itself, it's up
+	// to spiderpool-agent whether to skip the IPPool.
+	if ic.GatewayUnreachableNodeThreshold > 0 {
+		conditions = append(conditions, genIPPoolGatewayUnreachableCondition(pool, ic.GatewayUnreachableNodeThreshold, ic.DatapathFailureWindow, time.Now()))
+	}
+
+	var reasons []string
+	for reason, ok := range notReady {
+		if ok {
+			reasons = append(reasons, reason)
+		}
+	}
+	sort.Strings(reasons)
+
+	ready := metav1.Condition{
+		Type:               constant.IPPoolConditionReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: pool.Generation,
+		Reason:             constant.IPPoolReasonAvailable,
+		Message:            "IP addresses can be allocated from the IPPool",
+	}
+	if len(reasons) != 0 {
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = reasons[0]
+		ready.Message = fmt.Sprintf("No IP address can be allocated from the IPPool: %s", strings.Join(reasons, ", "))
+	}
+
+	return append(conditions, ready), nil
+}
+
+func genIPPoolExhaustedCondition(pool *spiderpoolv1.SpiderIPPool) metav1.Condition {
+	var total, allocated int64
+	if pool.Status.TotalIPCount != nil {
+		total = *pool.Status.TotalIPCount
+	}
+	if pool.Status.AllocatedIPCount != nil {
+		allocated = *pool.Status.AllocatedIPCount
+	}
+
+	// The message doesn't carry the counts, otherwise the condition would
+	// be updated on every allocation.
+	if total > allocated {
+		return metav1.Condition{
+			Type:               constant.IPPoolConditionExhausted,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: pool.Generation,
+			Reason:             constant.IPPoolReasonFreeIPsAvailable,
+			Message:            "Free IP addresses are available",
+		}
+	}
+
+	return metav1.Condition{
+		Type:               constant.IPPoolConditionExhausted,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: pool.Generation,
+		Reason:             constant.IPPoolReasonNoFreeIPs,
+		Message:            "All IP addresses are allocated",
+	}
+}
+
+func genIPPoolConflictingCondition(pool *spiderpoolv1.SpiderIPPool, overlaps, reservedIPs []string) metav1.Condition {
+	if len(overlaps) == 0 && len(reservedIPs) == 0 {
+		return metav1.Condition{
+			Type:               constant.IPPoolConditionConflicting,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: pool.Generation,
+			Reason:             constant.IPPoolReasonNoOverlap,
+			Message:            "No IP address overlaps with other IPPools",
+		}
+	}
+
+	cond := metav1.Condition{
+		Type:               constant.IPPoolConditionConflicting,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: pool.Generation,
+	}
+
+	var messages []string
+	if len(overlaps) != 0 {
+		cond.Reason = constant.IPPoolReasonOverlappingIPPools
+		messages = append(messages, fmt.Sprintf("IP addresses overlap with IPPools %s", strings.Join(overlaps, ",")))
+	}
+	if len(reservedIPs) != 0 {
+		if cond.Reason == "" {
+			cond.Reason = constant.IPPoolReasonReservedIPsInUse
+		}
+		messages = append(messages, fmt.Sprintf("reserved IP addresses %s are allocated", strings.Join(reservedIPs, ",")))
+	}
+	cond.Message = strings.Join(messages, "; ")
+
+	return cond
+}
+
+// genIPPoolGatewayUnreachableCondition reports whether the gateway of the
+// IPPool is unreachable from at least threshold nodes within the window.
+func genIPPoolGatewayUnreachableCondition(pool *spiderpoolv1.SpiderIPPool, threshold int, window time.Duration, now time.Time) metav1.Condition {
+	var nodes []string
+	for node, failure := range pool.Status.DatapathFailureNodes {
+		if failure.GatewayUnreachableTime != nil && now.Sub(failure.GatewayUnreachableTime.Time) < window {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Strings(nodes)
+
+	if len(nodes) < threshold {
+		return metav1.Condition{
+			Type:               constant.IPPoolConditionGatewayUnreachable,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: pool.Generation,
+			Reason:             constant.IPPoolReasonGatewayReachable,
+			Message:            "Gateway is reachable from the nodes",
+		}
+	}
+
+	gateway := "Gateway"
+	if pool.Spec.Gateway != nil {
+		gateway = fmt.Sprintf("Gateway %s", *pool.Spec.Gateway)
+	}
+
+	return metav1.Condition{
+		Type:               constant.IPPoolConditionGatewayUnreachable,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: pool.Generation,
+		Reason:             constant.IPPoolReasonUnreachableFromNodes,
+		Message:            fmt.Sprintf("%s is unreachable from nodes %s within %s", gateway, strings.Join(nodes, ","), window),
+	}
+}
+
+// genIPPoolQuarantinedCondition quarantines the IPPool if more than one node
+// reports repeated datapath failures of it within the window, so that the
+// failures local to a single node never take the IPPool out of the IPPool
+// candidates of the whole cluster. It returns nil if the IPPool is not to
+// be quarantined.
+func genIPPoolQuarantinedCondition(pool *spiderpoolv1.SpiderIPPool, window, coolDown time.Duration, now time.Time) *metav1.Condition {
+	var nodes []string
+	var last *spiderpoolv1.DatapathFailure
+	for node, failure := range pool.Status.DatapathFailureNodes {
+		if failure.RepeatedFailureTime == nil || now.Sub(failure.RepeatedFailureTime.Time) >= window {
+			continue
+		}
+		nodes = append(nodes, node)
+		if last == nil || failure.RepeatedFailureTime.After(last.RepeatedFailureTime.Time) {
+			last = failure.DeepCopy()
+		}
+	}
+	if len(nodes) < 2 {
+		return nil
+	}
+	sort.Strings(nodes)
+
+	return &metav1.Condition{
+		Type:               constant.IPPoolConditionQuarantined,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: pool.Generation,
+		Reason:             constant.IPPoolReasonRepeatedAllocationFailures,
+		Message: fmt.Sprintf("Nodes %s report repeated datapath failures, quarantined until %s, the last one: %s",
+			strings.Join(nodes, ","), now.Add(coolDown).UTC().Format(time.RFC3339), last.Message),
+	}
+}
+
+// pruneDatapathFailureNodes removes the datapath failures reported by the
+// nodes which are out of the window, and reports whether any is removed.
+func pruneDatapathFailureNodes(pool *spiderpoolv1.SpiderIPPool, window time.Duration, now time.Time) bool {
+	pruned := false
+	for node, failure := range pool.Status.DatapathFailureNodes {
+		if failure.GatewayUnreachableTime != nil && now.Sub(failure.GatewayUnreachableTime.Time) >= window {
+			failure.GatewayUnreachableTime = nil
+			pruned = true
+		}
+		if failure.RepeatedFailureTime != nil && now.Sub(failure.RepeatedFailureTime.Time) >= window {
+			failure.RepeatedFailureTime = nil
+			failure.Message = ""
+			pruned = true
+		}
+
+		if failure.GatewayUnreachableTime == nil && failure.RepeatedFailureTime == nil {
+			delete(pool.Status.DatapathFailureNodes, node)
+		} else {
+			pool.Status.DatapathFailureNodes[node] = failure
+		}
+	}
+
+	return pruned
+}
+
+// clearRepeatedDatapathFailures removes the repeated datapath failures
+// reported by the nodes, which are reported afresh after the quarantine.
+func clearRepeatedDatapathFailures(pool *spiderpoolv1.SpiderIPPool) {
+	for node, failure := range pool.Status.DatapathFailureNodes {
+		if failure.GatewayUnreachableTime == nil {
+			delete(pool.Status.DatapathFailureNodes, node)
+			continue
+		}
+		failure.RepeatedFailureTime = nil
+		failure.Message = ""
+		pool.Status.DatapathFailureNodes[node] = failure
+	}
+}
+
+// nextDatapathFailureExpiry returns when the earliest datapath failure
+// reported by the nodes falls out of the window.
+func nextDatapathFailureExpiry(pool *spiderpoolv1.SpiderIPPool, window time.Duration) (time.Time, bool) {
+	var expiry time.Time
+	for _, failure := range pool.Status.DatapathFailureNodes {
+		for _, t := range []*metav1.Time{failure.GatewayUnreachableTime, failure.RepeatedFailureTime} {
+			if t == nil {
+				continue
+			}
+			if e := t.Add(window); expiry.IsZero() || e.Before(expiry) {
+				expiry = e
+			}
+		}
+	}
+
+	return expiry, !expiry.IsZero()
+}
+
+// genIPPoolOrphanedCondition reports whether the IPPool is not controlled by
+// an existing Subnet, which is required once the feature SpiderSubnet is
+// enabled.
+func (ic *IPPoolController) genIPPoolOrphanedCondition(pool *spiderpoolv1.SpiderIPPool) metav1.Condition {
+	cond := metav1.Condition{
+		Type:               constant.IPPoolConditionOrphaned,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: pool.Generation,
+		Reason:             constant.IPPoolReasonSubnetControlled,
+	}
+
+	owner := metav1.GetControllerOf(pool)
+	if owner == nil || owner.Kind != constant.SpiderSubnetKind {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = constant.IPPoolReasonNoControllerSubnet
+		cond.Message = "Not controlled by any Subnet"
+		return cond
+	}
+
+	if _, err := ic.subnetsLister.Get(owner.Name); apierrors.IsNotFound(err) {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = constant.IPPoolReasonNoControllerSubnet
+		cond.Message = fmt.Sprintf("Controller Subnet %s does not exist", owner.Name)
+		return cond
+	}
+
+	cond.Message = fmt.Sprintf("Controlled by Subnet %s", owner.Name)
+	return cond
+}
+
+// overlappingIPPools returns the names of IPPools in the same tenant whose
+// subnet or IP addresses overlap with the ones of the IPPool. The admission
+// webhook rejects them, but they may still exist if they were created before
+// the webhook is ready or while the feature SpiderSubnet was toggled.
+func (ic *IPPoolController) overlappingIPPools(pool *spiderpoolv1.SpiderIPPool) ([]string, error) {
+	pools, err := ic.listSiblingIPPools(pool)
+	if err != nil {
+		return nil, err
+	}
+	if len(pools) == 0 {
+		return nil, nil
+	}
+
+	ips, err := spiderpoolip.AssembleTotalIPs(*pool.Spec.IPVersion, pool.Spec.IPs, pool.Spec.ExcludeIPs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to assemble the total IP addresses of SpiderIPPool '%s': %v", constant.ErrWrongInput, pool.Name, err)
+	}
+
+	var overlaps []string
+	for _, p := range pools {
+		if GetIPPoolTenant(p) != GetIPPoolTenant(pool) {
+			continue
+		}
+		if p.Spec.Subnet != pool.Spec.Subnet {
+			overlaps = append(overlaps, p.Name)
+			continue
+		}
+
+		existIPs, err := spiderpoolip.AssembleTotalIPs(*p.Spec.IPVersion, p.Spec.IPs, p.Spec.ExcludeIPs)
+		if err != nil {
+			continue
+		}
+		if len(spiderpoolip.IPsIntersectionSet(ips, existIPs, false)) != 0 {
+			overlaps = append(overlaps, p.Name)
+		}
+	}
+	sort.Strings(overlaps)
+
+	return overlaps, nil
+}
+
+// listSiblingIPPools lists the other IPPools whose subnet is the same as or
+// overlaps with the one of the IPPool.
+func (ic *IPPoolController) listSiblingIPPools(pool *spiderpoolv1.SpiderIPPool) ([]*spiderpoolv1.SpiderIPPool, error) {
+	pools, err := ic.poolLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var siblings []*spiderpoolv1.SpiderIPPool
+	for _, p := range pools {
+		if p.Name == pool.Name || p.Spec.IPVersion == nil || *p.Spec.IPVersion != *pool.Spec.IPVersion {
+			continue
+		}
+		if p.Spec.Subnet != pool.Spec.Subnet {
+			overlap, err := spiderpoolip.IsCIDROverlap(*pool.Spec.IPVersion, pool.Spec.Subnet, p.Spec.Subnet)
+			if err != nil {
+				return nil, fmt.Errorf("%w: failed to compare the subnet of SpiderIPPool '%s' with SpiderIPPool '%s': %v", constant.ErrWrongInput, pool.Name, p.Name, err)
+			}
+			if !overlap {
+				continue
+			}
+		}
+		siblings = append(siblings, p)
+	}
+
+	return siblings, nil
+}
+
+// allocatedReservedIPs returns the IP addresses of the IPPool which are
+// reserved by SpiderReservedIPs but still allocated, they were allocated
+// before the SpiderReservedIPs were created.
+func (ic *IPPoolController) allocatedReservedIPs(ctx context.Context, pool *spiderpoolv1.SpiderIPPool) ([]string, error) {
+	if len(pool.Status.AllocatedIPs) == 0 {
+		return nil, nil
+	}
+
+	reservedIPs, err := ic.rIPManager.AssembleScopedReservedIPs(ctx, *pool.Spec.IPVersion, pool, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to assemble reserved IP addresses: %v", err)
+	}
+
+	var inUse []string
+	for _, ip := range reservedIPs {
+		if _, ok := pool.Status.AllocatedIPs[ip.String()]; ok {
+			inUse = append(inUse, ip.String())
+		}
+	}
+	sort.Strings(inUse)
+
+	return inUse, nil
+}
+
+// enqueueAllIPPools enqueues all IPPools to check their conflicts
+// periodically, in case any change of IPPools or SpiderReservedIPs is missed.
+func (ic *IPPoolController) enqueueAllIPPools() {
+	pools, err := ic.poolLister.List(labels.Everything())
+	if err != nil {
+		informerLogger.Sugar().Warnf("failed to list SpiderIPPools to check conflicts: %v", err)
+		return
+	}
+
+	for _, p := range pools {
+		if p.DeletionTimestamp == nil && p.Spec.IPVersion != nil {
+			ic.enqueueIPPool(p)
+		}
+	}
+}
+
+// enqueueSiblingIPPools enqueues the other IPPools in the same subnet of the
+// IPPool, so that their condition "Conflicting" is refreshed once the IP
+// addresses of the IPPool are changed.
+func (ic *IPPoolController) enqueueSiblingIPPools(pool *spiderpoolv1.SpiderIPPool) {
+	if pool.Spec.IPVersion == nil {
+		return
+	}
+
+	pools, err := ic.listSiblingIPPools(pool)
+	if err != nil {
+		informerLogger.Sugar().Warnf("failed to list the sibling IPPools of SpiderIPPool '%s': %v", pool.Name, err)
+		return
+	}
+
+	for _, p := range pools {
+		ic.enqueueIPPool(p)
+	}
+}
+
+// needSyncIPPoolConditions reports whether the conditions of the IPPool are
+// out of date, without refreshing the condition "Conflicting".
+func (ic *IPPoolController) needSyncIPPoolConditions(pool *spiderpoolv1.SpiderIPPool) bool {
+	if pool.Spec.IPVersion == nil {
+		return false
+	}
+
+	conditions, err := ic.genIPPoolConditions(context.TODO(), pool, false)
+	if err != nil {
+		return false
+	}
+
+	return !equalIPPoolConditions(pool.Status.Conditions, conditions)
+}
+
+// syncIPPoolConditions refreshes the conditions of the IPPool, and reports
+// whether they are changed.
+func (ic *IPPoolController) syncIPPoolConditions(ctx context.Context, pool *spiderpoolv1.SpiderIPPool) (bool, error) {
+	conditions, err := ic.genIPPoolConditions(ctx, pool, true)
+	if err != nil {
+		return false, err
+	}
+
+	if equalIPPoolConditions(pool.Status.Conditions, conditions) {
+		return false, nil
+	}
+
+	for _, cond := range conditions {
+		apimeta.SetStatusCondition(&pool.Status.Conditions, cond)
+	}
+
+	return true, nil
+}
+
+func equalIPPoolConditions(existing, expected []metav1.Condition) bool {
+	for _, e := range expected {
+		cond := apimeta.FindStatusCondition(existing, e.Type)
+		if cond == nil || cond.Status != e.Status || cond.Reason != e.Reason ||
+			cond.Message != e.Message || cond.ObservedGeneration != e.ObservedGeneration {
+			return false
+		}
+	}
+
+	return true
+}
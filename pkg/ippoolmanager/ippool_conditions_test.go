@@ -0,0 +1,469 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	listers "github.com/spidernet-io/spiderpool/pkg/k8s/client/listers/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+var _ = Describe("IPPool conditions", Label("ippool_conditions_test"), func() {
+	newIPPool := func(name string, ips ...string) *spiderpoolv1.SpiderIPPool {
+		pool := &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 1}}
+		pool.Spec.IPVersion = pointer.Int64(constant.IPv4)
+		pool.Spec.Subnet = "172.18.40.0/24"
+		pool.Spec.IPs = ips
+		pool.Status.TotalIPCount = pointer.Int64(10)
+		pool.Status.AllocatedIPCount = pointer.Int64(0)
+		return pool
+	}
+
+	timeAt := func(t time.Time) *metav1.Time {
+		mt := metav1.NewTime(t)
+		return &mt
+	}
+
+	Describe("genIPPoolExhaustedCondition", func() {
+		It("reports the free IP addresses", func() {
+			cond := genIPPoolExhaustedCondition(newIPPool("pool"))
+			Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+			Expect(cond.Reason).To(Equal(constant.IPPoolReasonFreeIPsAvailable))
+		})
+
+		It("reports the IPPool whose IP addresses are all allocated", func() {
+			pool := newIPPool("pool")
+			pool.Status.AllocatedIPCount = pointer.Int64(10)
+			cond := genIPPoolExhaustedCondition(pool)
+			Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+			Expect(cond.Reason).To(Equal(constant.IPPoolReasonNoFreeIPs))
+		})
+
+		It("regards the IPPool without counts as exhausted", func() {
+			pool := newIPPool("pool")
+			pool.Status.TotalIPCount = nil
+			Expect(genIPPoolExhaustedCondition(pool).Status).To(Equal(metav1.ConditionTrue))
+		})
+	})
+
+	Describe("genIPPoolConflictingCondition", func() {
+		It("reports no conflict", func() {
+			cond := genIPPoolConflictingCondition(newIPPool("pool"), nil, nil)
+			Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+			Expect(cond.Reason).To(Equal(constant.IPPoolReasonNoOverlap))
+		})
+
+		It("reports the allocated reserved IP addresses", func() {
+			cond := genIPPoolConflictingCondition(newIPPool("pool"), nil, []string{"172.18.40.10"})
+			Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+			Expect(cond.Reason).To(Equal(constant.IPPoolReasonReservedIPsInUse))
+			Expect(cond.Message).To(Equal("reserved IP addresses 172.18.40.10 are allocated"))
+		})
+
+		It("reports the overlapping IPPools in preference to the reserved IP addresses", func() {
+			cond := genIPPoolConflictingCondition(newIPPool("pool"), []string{"pool1", "pool2"}, []string{"172.18.40.10"})
+			Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+			Expect(cond.Reason).To(Equal(constant.IPPoolReasonOverlappingIPPools))
+			Expect(cond.Message).To(Equal("IP addresses overlap with IPPools pool1,pool2; reserved IP addresses 172.18.40.10 are allocated"))
+		})
+	})
+
+	Describe("genIPPoolConditions", func() {
+		var ic *IPPoolController
+		var poolIndexer, subnetIndexer cache.Indexer
+		var objs []client.Object
+		BeforeEach(func() {
+			poolIndexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			subnetIndexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			objs = nil
+		})
+
+		JustBeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(spiderpoolv1.AddToScheme(scheme)).To(Succeed())
+			fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+			rIPManager, err := reservedipmanager.NewReservedIPManager(fakeClient)
+			Expect(err).NotTo(HaveOccurred())
+
+			ic = &IPPoolController{
+				client:        fakeClient,
+				rIPManager:    rIPManager,
+				poolLister:    listers.NewSpiderIPPoolLister(poolIndexer),
+				subnetsLister: listers.NewSpiderSubnetLister(subnetIndexer),
+			}
+		})
+
+		findCondition := func(conditions []metav1.Condition, condType string) metav1.Condition {
+			cond := apimeta.FindStatusCondition(conditions, condType)
+			Expect(cond).NotTo(BeNil())
+			return *cond
+		}
+
+		It("reports the available IPPool as ready", func() {
+			pool := newIPPool("pool", "172.18.40.1-172.18.40.10")
+			conditions, err := ic.genIPPoolConditions(context.TODO(), pool, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conditions).To(HaveLen(3))
+
+			ready := findCondition(conditions, constant.IPPoolConditionReady)
+			Expect(ready.Status).To(Equal(metav1.ConditionTrue))
+			Expect(ready.Reason).To(Equal(constant.IPPoolReasonAvailable))
+			Expect(ready.ObservedGeneration).To(BeEquivalentTo(1))
+		})
+
+		It("reports the IPPools overlapping in the same tenant", func() {
+			Expect(poolIndexer.Add(newIPPool("overlapping", "172.18.40.10-172.18.40.20"))).To(Succeed())
+			Expect(poolIndexer.Add(newIPPool("disjoint", "172.18.40.11-172.18.40.20"))).To(Succeed())
+			other := newIPPool("other-tenant", "172.18.40.1-172.18.40.10")
+			other.Spec.Tenant = pointer.String("tenant-a")
+			Expect(poolIndexer.Add(other)).To(Succeed())
+
+			pool := newIPPool("pool", "172.18.40.1-172.18.40.10")
+			conditions, err := ic.genIPPoolConditions(context.TODO(), pool, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			conflicting := findCondition(conditions, constant.IPPoolConditionConflicting)
+			Expect(conflicting.Status).To(Equal(metav1.ConditionTrue))
+			Expect(conflicting.Message).To(Equal("IP addresses overlap with IPPools overlapping"))
+
+			ready := findCondition(conditions, constant.IPPoolConditionReady)
+			Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+			Expect(ready.Reason).To(Equal(constant.IPPoolReasonConflicting))
+		})
+
+		Context("with the reserved IP addresses", func() {
+			BeforeEach(func() {
+				rIP := &spiderpoolv1.SpiderReservedIP{ObjectMeta: metav1.ObjectMeta{Name: "reserved"}}
+				rIP.Spec.IPVersion = pointer.Int64(constant.IPv4)
+				rIP.Spec.IPs = []string{"172.18.40.10"}
+				objs = append(objs, rIP)
+			})
+
+			It("reports the reserved IP addresses allocated before they are reserved", func() {
+				pool := newIPPool("pool", "172.18.40.1-172.18.40.10")
+				pool.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{
+					"172.18.40.9":  {ContainerID: "c1", NIC: "eth0", Namespace: "default", Pod: "pod1"},
+					"172.18.40.10": {ContainerID: "c2", NIC: "eth0", Namespace: "default", Pod: "pod2"},
+				}
+				conditions, err := ic.genIPPoolConditions(context.TODO(), pool, true)
+				Expect(err).NotTo(HaveOccurred())
+
+				conflicting := findCondition(conditions, constant.IPPoolConditionConflicting)
+				Expect(conflicting.Reason).To(Equal(constant.IPPoolReasonReservedIPsInUse))
+				Expect(conflicting.Message).To(ContainSubstring("172.18.40.10"))
+				Expect(conflicting.Message).NotTo(ContainSubstring("172.18.40.9"))
+			})
+		})
+
+		It("reuses the existing condition Conflicting unless it is refreshed", func() {
+			Expect(poolIndexer.Add(newIPPool("overlapping", "172.18.40.10-172.18.40.20"))).To(Succeed())
+			pool := newIPPool("pool", "172.18.40.1-172.18.40.10")
+			pool.Status.Conditions = []metav1.Condition{genIPPoolConflictingCondition(pool, nil, nil)}
+
+			conditions, err := ic.genIPPoolConditions(context.TODO(), pool, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(findCondition(conditions, constant.IPPoolConditionConflicting).Status).To(Equal(metav1.ConditionFalse))
+
+			conditions, err = ic.genIPPoolConditions(context.TODO(), pool, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(findCondition(conditions, constant.IPPoolConditionConflicting).Status).To(Equal(metav1.ConditionTrue))
+		})
+
+		It("lists all the reasons why the IPPool is not ready", func() {
+			pool := newIPPool("pool", "172.18.40.1-172.18.40.10")
+			pool.Spec.Disable = pointer.Bool(true)
+			pool.Status.AllocatedIPCount = pointer.Int64(10)
+			conditions, err := ic.genIPPoolConditions(context.TODO(), pool, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			ready := findCondition(conditions, constant.IPPoolConditionReady)
+			Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+			Expect(ready.Reason).To(Equal(constant.IPPoolReasonDisabled))
+			Expect(ready.Message).To(ContainSubstring(constant.IPPoolReasonDisabled + ", " + constant.IPPoolReasonExhausted))
+		})
+
+		Context("with SpiderSubnet", func() {
+			JustBeforeEach(func() {
+				ic.EnableSpiderSubnet = true
+			})
+
+			setController := func(pool *spiderpoolv1.SpiderIPPool, subnetName string) {
+				pool.OwnerReferences = []metav1.OwnerReference{{
+					APIVersion: spiderpoolv1.SchemeGroupVersion.String(),
+					Kind:       constant.SpiderSubnetKind,
+					Name:       subnetName,
+					UID:        "uid",
+					Controller: pointer.Bool(true),
+				}}
+			}
+
+			It("reports the IPPool controlled by an existing Subnet", func() {
+				Expect(subnetIndexer.Add(&spiderpoolv1.SpiderSubnet{ObjectMeta: metav1.ObjectMeta{Name: "subnet"}})).To(Succeed())
+				pool := newIPPool("pool", "172.18.40.1-172.18.40.10")
+				setController(pool, "subnet")
+				conditions, err := ic.genIPPoolConditions(context.TODO(), pool, true)
+				Expect(err).NotTo(HaveOccurred())
+
+				orphaned := findCondition(conditions, constant.IPPoolConditionOrphaned)
+				Expect(orphaned.Status).To(Equal(metav1.ConditionFalse))
+				Expect(findCondition(conditions, constant.IPPoolConditionReady).Status).To(Equal(metav1.ConditionTrue))
+			})
+
+			It("reports the IPPool whose controller Subnet is gone as orphaned", func() {
+				pool := newIPPool("pool", "172.18.40.1-172.18.40.10")
+				setController(pool, "subnet")
+				conditions, err := ic.genIPPoolConditions(context.TODO(), pool, true)
+				Expect(err).NotTo(HaveOccurred())
+
+				orphaned := findCondition(conditions, constant.IPPoolConditionOrphaned)
+				Expect(orphaned.Status).To(Equal(metav1.ConditionTrue))
+				Expect(orphaned.Message).To(Equal("Controller Subnet subnet does not exist"))
+				Expect(findCondition(conditions, constant.IPPoolConditionReady).Reason).To(Equal(constant.IPPoolReasonOrphaned))
+			})
+
+			It("reports the IPPool without controller Subnet as orphaned", func() {
+				conditions, err := ic.genIPPoolConditions(context.TODO(), newIPPool("pool", "172.18.40.1-172.18.40.10"), true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(findCondition(conditions, constant.IPPoolConditionOrphaned).Reason).To(Equal(constant.IPPoolReasonNoControllerSubnet))
+			})
+		})
+
+		It("refreshes the changed conditions only", func() {
+			pool := newIPPool("pool", "172.18.40.1-172.18.40.10")
+			changed, err := ic.syncIPPoolConditions(context.TODO(), pool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeTrue())
+			Expect(ic.needSyncIPPoolConditions(pool)).To(BeFalse())
+
+			changed, err = ic.syncIPPoolConditions(context.TODO(), pool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeFalse())
+
+			pool.Status.AllocatedIPCount = pointer.Int64(10)
+			Expect(ic.needSyncIPPoolConditions(pool)).To(BeTrue())
+		})
+	})
+
+	Describe("genIPPoolGatewayUnreachableCondition", func() {
+		It("reports the gateway unreachable from enough nodes within the window", func() {
+			now := time.Now()
+			pool := newIPPool("pool")
+			pool.Spec.Gateway = pointer.String("172.18.40.1")
+			pool.Status.DatapathFailureNodes = map[string]spiderpoolv1.DatapathFailure{
+				"node1": {GatewayUnreachableTime: timeAt(now.Add(-time.Second))},
+				"node2": {GatewayUnreachableTime: timeAt(now.Add(-2 * time.Minute))},
+				"node4": {RepeatedFailureTime: timeAt(now)},
+			}
+			Expect(genIPPoolGatewayUnreachableCondition(pool, 2, time.Minute, now).Status).To(Equal(metav1.ConditionFalse))
+
+			pool.Status.DatapathFailureNodes["node3"] = spiderpoolv1.DatapathFailure{GatewayUnreachableTime: timeAt(now)}
+			cond := genIPPoolGatewayUnreachableCondition(pool, 2, time.Minute, now)
+			Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+			Expect(cond.Message).To(Equal("Gateway 172.18.40.1 is unreachable from nodes node1,node3 within 1m0s"))
+		})
+	})
+
+	Describe("genIPPoolQuarantinedCondition", func() {
+		It("quarantines the IPPool only if more than one node reports repeated failures within the window", func() {
+			now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+			pool := newIPPool("pool")
+			pool.Status.DatapathFailureNodes = map[string]spiderpoolv1.DatapathFailure{
+				"node1": {RepeatedFailureTime: timeAt(now.Add(-time.Second)), Message: "IP 172.18.40.2 conflicts"},
+				"node2": {RepeatedFailureTime: timeAt(now.Add(-2 * time.Minute)), Message: "expired"},
+				"node3": {GatewayUnreachableTime: timeAt(now)},
+			}
+			Expect(genIPPoolQuarantinedCondition(pool, time.Minute, 5*time.Minute, now)).To(BeNil())
+
+			pool.Status.DatapathFailureNodes["node4"] = spiderpoolv1.DatapathFailure{RepeatedFailureTime: timeAt(now.Add(-10 * time.Second)), Message: "gateway 172.18.40.1 is unreachable"}
+			cond := genIPPoolQuarantinedCondition(pool, time.Minute, 5*time.Minute, now)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+			Expect(cond.Reason).To(Equal(constant.IPPoolReasonRepeatedAllocationFailures))
+			Expect(cond.Message).To(Equal("Nodes node1,node4 report repeated datapath failures, quarantined until 2023-01-01T00:05:00Z, the last one: IP 172.18.40.2 conflicts"))
+		})
+	})
+
+	Describe("pruning the datapath failures", func() {
+		It("removes the expired failures and the nodes without any failure", func() {
+			now := time.Now()
+			pool := newIPPool("pool")
+			pool.Status.DatapathFailureNodes = map[string]spiderpoolv1.DatapathFailure{
+				"node1": {GatewayUnreachableTime: timeAt(now.Add(-2 * time.Minute)), RepeatedFailureTime: timeAt(now.Add(-time.Second)), Message: "failure"},
+				"node2": {GatewayUnreachableTime: timeAt(now.Add(-2 * time.Minute))},
+				"node3": {GatewayUnreachableTime: timeAt(now.Add(-30 * time.Second))},
+			}
+
+			Expect(pruneDatapathFailureNodes(pool, time.Minute, now)).To(BeTrue())
+			Expect(pool.Status.DatapathFailureNodes).To(HaveLen(2))
+			Expect(pool.Status.DatapathFailureNodes["node1"].GatewayUnreachableTime).To(BeNil())
+			Expect(pool.Status.DatapathFailureNodes["node1"].Message).To(Equal("failure"))
+			Expect(pruneDatapathFailureNodes(pool, time.Minute, now)).To(BeFalse())
+
+			expiry, ok := nextDatapathFailureExpiry(pool, time.Minute)
+			Expect(ok).To(BeTrue())
+			Expect(expiry).To(Equal(now.Add(30 * time.Second)))
+
+			clearRepeatedDatapathFailures(pool)
+			Expect(pool.Status.DatapathFailureNodes).To(HaveLen(1))
+			Expect(pool.Status.DatapathFailureNodes).To(HaveKey("node3"))
+		})
+	})
+
+	Describe("checking the conflicts in the background", func() {
+		var ic *IPPoolController
+		var poolIndexer cache.Indexer
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(spiderpoolv1.AddToScheme(scheme)).To(Succeed())
+			fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+			rIPManager, err := reservedipmanager.NewReservedIPManager(fakeClient)
+			Expect(err).NotTo(HaveOccurred())
+
+			poolIndexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			ic = NewIPPoolController(IPPoolControllerConfig{MaxWorkqueueLength: 10}, fakeClient, rIPManager, nil, nil)
+			ic.poolLister = listers.NewSpiderIPPoolLister(poolIndexer)
+			ic.normalPoolWorkQueue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Normal-SpiderIPPools")
+			DeferCleanup(ic.normalPoolWorkQueue.ShutDown)
+		})
+
+		queuedIPPools := func() []string {
+			var names []string
+			for ic.normalPoolWorkQueue.Len() > 0 {
+				item, _ := ic.normalPoolWorkQueue.Get()
+				names = append(names, item.(string))
+				ic.normalPoolWorkQueue.Done(item)
+			}
+			return names
+		}
+
+		It("reports the IPPools whose subnets overlap", func() {
+			supernet := newIPPool("supernet", "172.18.0.1-172.18.0.10")
+			supernet.Spec.Subnet = "172.18.0.0/16"
+			Expect(poolIndexer.Add(supernet)).To(Succeed())
+			other := newIPPool("other", "172.19.0.1-172.19.0.10")
+			other.Spec.Subnet = "172.19.0.0/16"
+			Expect(poolIndexer.Add(other)).To(Succeed())
+
+			overlaps, err := ic.overlappingIPPools(newIPPool("pool", "172.18.40.1-172.18.40.10"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(overlaps).To(Equal([]string{"supernet"}))
+		})
+
+		It("enqueues all the IPPools periodically", func() {
+			Expect(poolIndexer.Add(newIPPool("pool1"))).To(Succeed())
+			Expect(poolIndexer.Add(newIPPool("pool2"))).To(Succeed())
+			terminating := newIPPool("terminating")
+			now := metav1.Now()
+			terminating.DeletionTimestamp = &now
+			Expect(poolIndexer.Add(terminating)).To(Succeed())
+
+			ic.enqueueAllIPPools()
+			Expect(queuedIPPools()).To(ConsistOf("pool1", "pool2"))
+		})
+
+		It("enqueues the sibling IPPools in the same or overlapping subnets", func() {
+			Expect(poolIndexer.Add(newIPPool("sibling"))).To(Succeed())
+			supernet := newIPPool("supernet")
+			supernet.Spec.Subnet = "172.18.0.0/16"
+			Expect(poolIndexer.Add(supernet)).To(Succeed())
+			other := newIPPool("other")
+			other.Spec.Subnet = "172.19.0.0/16"
+			Expect(poolIndexer.Add(other)).To(Succeed())
+			v6 := newIPPool("v6")
+			v6.Spec.IPVersion = pointer.Int64(constant.IPv6)
+			v6.Spec.Subnet = "abcd:1234::/120"
+			Expect(poolIndexer.Add(v6)).To(Succeed())
+
+			pool := newIPPool("pool")
+			Expect(poolIndexer.Add(pool)).To(Succeed())
+			ic.enqueueSiblingIPPools(pool)
+			Expect(queuedIPPools()).To(ConsistOf("sibling", "supernet"))
+		})
+	})
+
+	Describe("draining", func() {
+		var ic *IPPoolController
+		var manager IPPoolManager
+		var fakeClient client.Client
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(spiderpoolv1.AddToScheme(scheme)).To(Succeed())
+			fakeClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+			rIPManager, err := reservedipmanager.NewReservedIPManager(fakeClient)
+			Expect(err).NotTo(HaveOccurred())
+			manager, err = NewIPPoolManager(IPPoolManagerConfig{}, fakeClient, rIPManager)
+			Expect(err).NotTo(HaveOccurred())
+
+			ic = NewIPPoolController(IPPoolControllerConfig{MaxWorkqueueLength: 10}, fakeClient, rIPManager, manager, nil)
+			ic.poolLister = listers.NewSpiderIPPoolLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}))
+			ic.subnetsLister = listers.NewSpiderSubnetLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}))
+			ic.normalPoolWorkQueue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Normal-SpiderIPPools")
+			DeferCleanup(ic.normalPoolWorkQueue.ShutDown)
+		})
+
+		syncDrainedCondition := func(name string) *metav1.Condition {
+			ctx := context.TODO()
+			pool, err := manager.GetIPPoolByName(ctx, name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ic.syncHandleAllIPPool(ctx, pool)).To(Succeed())
+
+			pool, err = manager.GetIPPoolByName(ctx, name)
+			Expect(err).NotTo(HaveOccurred())
+			return apimeta.FindStatusCondition(pool.Status.Conditions, constant.IPPoolConditionDrained)
+		}
+
+		It("reports the IPPool drained once the last allocation is released", func() {
+			ctx := context.TODO()
+			pool := newIPPool("pool", "172.18.40.1-172.18.40.10")
+			pool.Spec.Drain = pointer.Bool(true)
+			pool.Status.AllocatedIPCount = pointer.Int64(1)
+			pool.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{
+				"172.18.40.1": {ContainerID: "container", NIC: "eth0", Node: "node", Namespace: "default", Pod: "pod"},
+			}
+			Expect(fakeClient.Create(ctx, pool)).To(Succeed())
+
+			cond := syncDrainedCondition(pool.Name)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+			Expect(cond.Reason).To(Equal(constant.IPPoolReasonAllocationsRemaining))
+
+			err := manager.ReleaseIP(ctx, pool.Name, []types.IPAndCID{{IP: "172.18.40.1", ContainerID: "container"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			cond = syncDrainedCondition(pool.Name)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+			Expect(cond.Reason).To(Equal(constant.IPPoolReasonAllocationsReleased))
+		})
+
+		It("removes the condition once the IPPool is not draining", func() {
+			ctx := context.TODO()
+			pool := newIPPool("pool", "172.18.40.1-172.18.40.10")
+			pool.Spec.Drain = pointer.Bool(true)
+			Expect(fakeClient.Create(ctx, pool)).To(Succeed())
+			Expect(syncDrainedCondition(pool.Name)).NotTo(BeNil())
+
+			Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(pool), pool)).To(Succeed())
+			pool.Spec.Drain = pointer.Bool(false)
+			Expect(fakeClient.Update(ctx, pool)).To(Succeed())
+			Expect(syncDrainedCondition(pool.Name)).To(BeNil())
+		})
+	})
+})
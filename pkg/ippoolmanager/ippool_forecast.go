@@ -0,0 +1,154 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/metric"
+)
+
+// MaxForecastHorizon is the farthest exhaustion time to be predicted, the
+// IPPools predicted to be exhausted later are regarded as not growing.
+const MaxForecastHorizon = 365 * 24 * time.Hour
+
+// PredictExhaustionTime forecasts the time when the free IP addresses are
+// used out, at the velocity of the net allocations over the time window
+// ending at now. It returns false if the allocations are not growing.
+func PredictExhaustionTime(freeIPCount, netAllocations int64, window time.Duration, now time.Time) (time.Time, bool) {
+	if netAllocations <= 0 || window <= 0 {
+		return time.Time{}, false
+	}
+	if freeIPCount <= 0 {
+		return now, true
+	}
+
+	d := float64(window) * float64(freeIPCount) / float64(netAllocations)
+	if d > float64(MaxForecastHorizon) {
+		return time.Time{}, false
+	}
+
+	return now.Add(time.Duration(d)), true
+}
+
+// forecastIPPoolExhaustion publishes the predicted exhaustion time of each
+// IPPool to its 'status.predictedExhaustionTime' and the metric, which are
+// forecasted from the allocation statistics.
+func (ic *IPPoolController) forecastIPPoolExhaustion() {
+	if ic.stats == nil {
+		return
+	}
+
+	now := time.Now()
+	// The statistics only cover the period since the controller started.
+	window := ic.UsageForecastWindow
+	if elapsed := now.Sub(ic.startTime).Truncate(AllocationStatsBucketDuration); elapsed < window {
+		window = elapsed
+	}
+	if window < AllocationStatsBucketDuration {
+		return
+	}
+	if window > MaxAllocationStatsWindow {
+		window = MaxAllocationStatsWindow
+	}
+
+	pools, err := ic.poolLister.List(labels.Everything())
+	if err != nil {
+		informerLogger.Sugar().Warnf("failed to list SpiderIPPools to forecast the exhaustion: %v", err)
+		return
+	}
+
+	ctx := context.TODO()
+	forecasted := make(map[string]struct{}, len(pools))
+	for _, pool := range pools {
+		forecasted[pool.Name] = struct{}{}
+
+		predicted, err := ic.predictIPPoolExhaustion(pool, window, now)
+		if err != nil {
+			informerLogger.Sugar().Warnf("failed to forecast the exhaustion of SpiderIPPool '%s': %v", pool.Name, err)
+			continue
+		}
+
+		if predicted != nil {
+			metric.IPPoolPredictedExhaustionSeconds.Record(pool.Name, predicted.Sub(now).Seconds())
+		} else {
+			metric.IPPoolPredictedExhaustionSeconds.Delete(pool.Name)
+		}
+
+		if !isForecastChanged(pool.Status.PredictedExhaustionTime, predicted, now) {
+			continue
+		}
+
+		newPool := pool.DeepCopy()
+		newPool.Status.PredictedExhaustionTime = predicted
+		if err := ic.client.Status().Update(ctx, newPool); err != nil {
+			// Retry in the next round.
+			if !apierrors.IsConflict(err) && !apierrors.IsNotFound(err) {
+				informerLogger.Sugar().Warnf("failed to update the predicted exhaustion time of SpiderIPPool '%s': %v", pool.Name, err)
+			}
+			continue
+		}
+		if predicted != nil {
+			informerLogger.Sugar().Infof("SpiderIPPool '%s' is predicted to be exhausted at %s", pool.Name, predicted.Format(time.RFC3339))
+		}
+	}
+
+	for name := range ic.forecastedPools {
+		if _, ok := forecasted[name]; !ok {
+			metric.IPPoolPredictedExhaustionSeconds.Delete(name)
+		}
+	}
+	ic.forecastedPools = forecasted
+}
+
+func (ic *IPPoolController) predictIPPoolExhaustion(pool *spiderpoolv1.SpiderIPPool, window time.Duration, now time.Time) (*metav1.Time, error) {
+	if pool.Status.TotalIPCount == nil {
+		return nil, nil
+	}
+
+	items, err := ic.stats.Query(window, pool.Name, "", now)
+	if err != nil {
+		return nil, err
+	}
+
+	var netAllocations int64
+	for _, item := range items {
+		netAllocations += item.Allocations - item.Releases
+	}
+
+	free := *pool.Status.TotalIPCount - int64(len(pool.Status.AllocatedIPs))
+	t, ok := PredictExhaustionTime(free, netAllocations, window, now)
+	if !ok {
+		return nil, nil
+	}
+
+	predicted := metav1.NewTime(t.Truncate(time.Second))
+	return &predicted, nil
+}
+
+// isForecastChanged tells whether the predicted exhaustion time should be
+// updated. To limit the updates of IPPools, the small deviations of the
+// prediction are ignored, which are less than a tenth of the time remaining.
+func isForecastChanged(old, new *metav1.Time, now time.Time) bool {
+	if old == nil || new == nil {
+		return old != new
+	}
+
+	diff := old.Sub(new.Time)
+	if diff < 0 {
+		diff = -diff
+	}
+	tolerance := new.Sub(now) / 10
+	if tolerance < time.Minute {
+		tolerance = time.Minute
+	}
+
+	return diff >= tolerance
+}
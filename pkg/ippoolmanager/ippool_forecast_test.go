@@ -0,0 +1,46 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+)
+
+var _ = Describe("PredictExhaustionTime", Label("ippool_forecast_test"), func() {
+	var now time.Time
+
+	BeforeEach(func() {
+		now = time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	})
+
+	It("does not predict if the allocations are not growing", func() {
+		_, ok := ippoolmanager.PredictExhaustionTime(10, 0, time.Hour, now)
+		Expect(ok).To(BeFalse())
+
+		_, ok = ippoolmanager.PredictExhaustionTime(10, -5, time.Hour, now)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("predicts at the velocity of the net allocations", func() {
+		t, ok := ippoolmanager.PredictExhaustionTime(30, 10, time.Hour, now)
+		Expect(ok).To(BeTrue())
+		Expect(t).To(Equal(now.Add(3 * time.Hour)))
+	})
+
+	It("predicts now if the IPPool has been exhausted", func() {
+		t, ok := ippoolmanager.PredictExhaustionTime(0, 1, time.Hour, now)
+		Expect(ok).To(BeTrue())
+		Expect(t).To(Equal(now))
+	})
+
+	It("does not predict beyond the max forecast horizon", func() {
+		_, ok := ippoolmanager.PredictExhaustionTime(1<<40, 1, time.Hour, now)
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -16,6 +16,9 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	apitypes "k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -47,8 +50,10 @@ var informerLogger *zap.Logger
 type IPPoolController struct {
 	IPPoolControllerConfig
 
-	client     client.Client
-	rIPManager reservedipmanager.ReservedIPManager
+	client        client.Client
+	rIPManager    reservedipmanager.ReservedIPManager
+	ipPoolManager IPPoolManager
+	stats         *AllocationStats
 
 	poolLister    listers.SpiderIPPoolLister
 	poolSynced    cache.InformerSynced
@@ -65,6 +70,9 @@ type IPPoolController struct {
 	// the v6AutoPoolWorkQueue serves for Auto-Created IPv6 IPPools
 	v6AutoPoolWorkQueue workqueue.RateLimitingInterface
 	v6GenIPsCursor      bool
+
+	startTime       time.Time
+	forecastedPools map[string]struct{}
 }
 
 type IPPoolControllerConfig struct {
@@ -76,15 +84,44 @@ type IPPoolControllerConfig struct {
 	MaxWorkqueueLength            int
 	WorkQueueRequeueDelayDuration time.Duration
 	WorkQueueMaxRetries           int
+	QuarantineCoolDownDuration    time.Duration
+	// ConflictCheckInterval is the interval to check the conflicts of all
+	// IPPools, a non-positive value disables the periodic check.
+	ConflictCheckInterval time.Duration
+	// MaxSpecChangelogs is the max number of spec changes recorded in the
+	// status of IPPool, a non-positive value disables the changelog.
+	MaxSpecChangelogs int
+	// LeaseCheckInterval is the interval to reclaim the IP allocations with
+	// expired leases, a non-positive value disables the reclamation.
+	LeaseCheckInterval time.Duration
+	// UsageForecastInterval is the interval to forecast the exhaustion of
+	// all IPPools, a non-positive value disables the forecast.
+	UsageForecastInterval time.Duration
+	// UsageForecastWindow is the time window of the allocation velocity
+	// which the forecast is based on.
+	UsageForecastWindow time.Duration
+	// GatewayUnreachableNodeThreshold is the number of nodes reporting the
+	// gateway of an IPPool unreachable within DatapathFailureWindow, which
+	// sets the condition GatewayUnreachable of the IPPool. A non-positive
+	// value disables the condition.
+	GatewayUnreachableNodeThreshold int
+	// DatapathFailureWindow is how long the datapath failures reported by
+	// the nodes to 'status.datapathFailureNodes' of IPPools count.
+	DatapathFailureWindow time.Duration
+	// ReserveSpecialIPs is the global policy 'reserveSpecialIPs', the
+	// special IP addresses it skips are not counted in the total IP count.
+	ReserveSpecialIPs bool
 }
 
-func NewIPPoolController(poolControllerConfig IPPoolControllerConfig, client client.Client, rIPManager reservedipmanager.ReservedIPManager) *IPPoolController {
+func NewIPPoolController(poolControllerConfig IPPoolControllerConfig, client client.Client, rIPManager reservedipmanager.ReservedIPManager, ipPoolManager IPPoolManager, stats *AllocationStats) *IPPoolController {
 	informerLogger = logutils.Logger.Named("SpiderIPPool-Informer")
 
 	c := &IPPoolController{
 		IPPoolControllerConfig: poolControllerConfig,
 		client:                 client,
 		rIPManager:             rIPManager,
+		ipPoolManager:          ipPoolManager,
+		stats:                  stats,
 	}
 
 	return c
@@ -132,6 +169,7 @@ func (ic *IPPoolController) SetupInformer(ctx context.Context, client crdclients
 			ic.addEventHandlers(
 				factory.Spiderpool().V1().SpiderIPPools(),
 				factory.Spiderpool().V1().SpiderSubnets(),
+				factory.Spiderpool().V1().SpiderReservedIPs(),
 			)
 			factory.Start(innerCtx.Done())
 
@@ -145,7 +183,7 @@ func (ic *IPPoolController) SetupInformer(ctx context.Context, client crdclients
 	return nil
 }
 
-func (ic *IPPoolController) addEventHandlers(poolInformer informers.SpiderIPPoolInformer, subnetInformer informers.SpiderSubnetInformer) {
+func (ic *IPPoolController) addEventHandlers(poolInformer informers.SpiderIPPoolInformer, subnetInformer informers.SpiderSubnetInformer, rIPInformer informers.SpiderReservedIPInformer) {
 	ic.poolLister = poolInformer.Lister()
 	ic.poolSynced = poolInformer.Informer().HasSynced
 	ic.subnetsLister = subnetInformer.Lister()
@@ -162,6 +200,19 @@ func (ic *IPPoolController) addEventHandlers(poolInformer informers.SpiderIPPool
 		DeleteFunc: nil,
 	})
 
+	// the IP addresses reserved by SpiderReservedIPs are excluded from IPPools
+	rIPInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			ic.enqueueReservedIPPools(obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			ic.enqueueReservedIPPools(oldObj, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			ic.enqueueReservedIPPools(obj)
+		},
+	})
+
 	// for auto-created IPPool processing
 	if ic.EnableSpiderSubnet {
 		// for all updated subnets, we need to list their corresponding auto-created IPPools,
@@ -170,12 +221,24 @@ func (ic *IPPoolController) addEventHandlers(poolInformer informers.SpiderIPPool
 			AddFunc: ic.syncSubnetIPPools,
 			UpdateFunc: func(oldObj, newObj interface{}) {
 				ic.syncSubnetIPPools(newObj)
+				ic.syncSubnetRoutesIPPools(oldObj, newObj)
+				ic.syncSubnetDefaultsIPPools(oldObj, newObj)
 			},
 			DeleteFunc: nil,
 		})
 	}
 }
 
+// enqueueAutoIPPoolAfter enqueues the auto-created IPPool into the corresponding
+// AutoPoolWorkqueue after the given duration.
+func (ic *IPPoolController) enqueueAutoIPPoolAfter(pool *spiderpoolv1.SpiderIPPool, duration time.Duration) {
+	if pool.Spec.IPVersion != nil && *pool.Spec.IPVersion == constant.IPv6 {
+		ic.v6AutoPoolWorkQueue.AddAfter(pool.Name, duration)
+	} else {
+		ic.v4AutoPoolWorkQueue.AddAfter(pool.Name, duration)
+	}
+}
+
 // enqueueIPPool will check the given pool and enqueue them into different workqueue
 func (ic *IPPoolController) enqueueIPPool(pool *spiderpoolv1.SpiderIPPool) {
 	// Auto-created IPPools enqueue the corresponding AutoPoolWorkqueue
@@ -216,6 +279,56 @@ func (ic *IPPoolController) enqueueIPPool(pool *spiderpoolv1.SpiderIPPool) {
 	}
 }
 
+// enqueueReservedIPPools enqueues the IPPools whose subnet contains the IP
+// addresses of the SpiderReservedIPs, so that their total IP count and
+// excluded IP addresses are recalculated.
+func (ic *IPPoolController) enqueueReservedIPPools(objs ...interface{}) {
+	var rIPs []*spiderpoolv1.SpiderReservedIP
+	for _, obj := range objs {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		rIP, ok := obj.(*spiderpoolv1.SpiderReservedIP)
+		if !ok || rIP.Spec.IPVersion == nil {
+			continue
+		}
+		rIPs = append(rIPs, rIP)
+	}
+	if len(rIPs) == 0 {
+		return
+	}
+
+	pools, err := ic.poolLister.List(labels.Everything())
+	if err != nil {
+		informerLogger.Sugar().Warnf("failed to list SpiderIPPools to exclude reserved IP addresses: %v", err)
+		return
+	}
+
+	for _, rIP := range rIPs {
+		ips, err := reservedipmanager.GetReservedIPs(*rIP.Spec.IPVersion, rIP)
+		if err != nil {
+			informerLogger.Sugar().Warnf("failed to parse the IP addresses of SpiderReservedIP '%s': %v", rIP.Name, err)
+			continue
+		}
+
+		for _, pool := range pools {
+			if pool.DeletionTimestamp != nil || pool.Spec.IPVersion == nil || *pool.Spec.IPVersion != *rIP.Spec.IPVersion {
+				continue
+			}
+			_, ipNet, err := net.ParseCIDR(pool.Spec.Subnet)
+			if err != nil {
+				continue
+			}
+			for _, ip := range ips {
+				if ipNet.Contains(ip) {
+					ic.enqueueIPPool(pool)
+					break
+				}
+			}
+		}
+	}
+}
+
 // onAllIPPoolAdd represents SpiderIPPool informer Add Event
 func (ic *IPPoolController) onIPPoolAdd(obj interface{}) {
 	pool := obj.(*spiderpoolv1.SpiderIPPool)
@@ -231,6 +344,10 @@ func (ic *IPPoolController) onIPPoolUpdate(oldObj interface{}, newObj interface{
 	oldPool := oldObj.(*spiderpoolv1.SpiderIPPool)
 	newPool := newObj.(*spiderpoolv1.SpiderIPPool)
 
+	if ic.stats != nil {
+		ic.stats.RecordIPPoolChange(oldPool, newPool, time.Now())
+	}
+
 	err := ic.updateSpiderIPPool(oldPool, newPool, informerLogger.With(zap.String("onIPPoolUpdate", newPool.Name)))
 	if nil != err {
 		informerLogger.Sugar().Errorf("onAllIPPoolUpdate error: %v", err)
@@ -247,6 +364,52 @@ func (ic *IPPoolController) updateSpiderIPPool(oldIPPool, currentIPPool *spiderp
 		return nil
 	}
 
+	// the quarantine of IPPool will be lifted after the cool-down period
+	if IsQuarantinedIPPool(currentIPPool) {
+		log.Debug("try to add quarantined IPPool to IPPool workqueue to lift the quarantine")
+		ic.enqueueIPPool(currentIPPool)
+	} else if genIPPoolQuarantinedCondition(currentIPPool, ic.DatapathFailureWindow, ic.QuarantineCoolDownDuration, time.Now()) != nil {
+		log.Debug("try to add IPPool to IPPool workqueue to quarantine it")
+		ic.enqueueIPPool(currentIPPool)
+	}
+
+	// the IP addresses of the sibling IPPools may overlap with the new ones
+	if oldIPPool == nil ||
+		!reflect.DeepEqual(oldIPPool.Spec.IPs, currentIPPool.Spec.IPs) ||
+		!reflect.DeepEqual(oldIPPool.Spec.ExcludeIPs, currentIPPool.Spec.ExcludeIPs) {
+		ic.enqueueSiblingIPPools(currentIPPool)
+	}
+
+	// refresh the conditions reporting the availability
+	if ic.needSyncIPPoolConditions(currentIPPool) {
+		log.Debug("try to add IPPool to IPPool workqueue to update its conditions")
+		ic.enqueueIPPool(currentIPPool)
+		return nil
+	}
+
+	// inherit the routes of the controller Subnet
+	if ic.needSyncIPPoolInheritedRoutes(currentIPPool) {
+		log.Debug("try to add IPPool to IPPool workqueue to sync its inherited routes")
+		ic.enqueueIPPool(currentIPPool)
+		return nil
+	}
+
+	// report the progress of draining
+	if needSyncIPPoolDrainedCondition(currentIPPool) {
+		log.Debug("try to add IPPool to IPPool workqueue to update its drained condition")
+		ic.enqueueIPPool(currentIPPool)
+		return nil
+	}
+
+	// record the spec changes captured by the webhook
+	if ic.MaxSpecChangelogs > 0 {
+		if changes, err := getUnrecordedIPPoolSpecChanges(currentIPPool); err == nil && len(changes) != 0 {
+			log.Debug("try to add IPPool to IPPool workqueue to record its spec changes")
+			ic.enqueueIPPool(currentIPPool)
+			return nil
+		}
+	}
+
 	// update the TotalIPCount if needed
 	needCalculate := false
 	if currentIPPool.Status.TotalIPCount == nil || currentIPPool.Status.AllocatedIPCount == nil {
@@ -264,6 +427,10 @@ func (ic *IPPoolController) updateSpiderIPPool(oldIPPool, currentIPPool *spiderp
 				// case: SpiderIPPool spec ExcludeIPs changed
 				needCalculate = true
 
+			case !reflect.DeepEqual(GetVacatingIPs(oldIPPool), GetVacatingIPs(currentIPPool)):
+				// case: SpiderIPPool IPs being vacated changed
+				needCalculate = true
+
 			default:
 				needCalculate = false
 			}
@@ -294,6 +461,7 @@ func (ic *IPPoolController) Run(stopCh <-chan struct{}) error {
 	defer utilruntime.HandleCrash()
 	defer ic.normalPoolWorkQueue.ShutDown()
 
+	ic.startTime = time.Now()
 	informerLogger.Debug("Waiting for informer caches to sync")
 	if ok := cache.WaitForCacheSync(stopCh, ic.poolSynced, ic.subnetsSynced); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
@@ -315,6 +483,18 @@ func (ic *IPPoolController) Run(stopCh <-chan struct{}) error {
 		go wait.Until(ic.runV6AutoPoolWorker, 1*time.Second, stopCh)
 	}
 
+	if ic.ConflictCheckInterval > 0 {
+		go wait.Until(ic.enqueueAllIPPools, ic.ConflictCheckInterval, stopCh)
+	}
+
+	if ic.LeaseCheckInterval > 0 {
+		go wait.Until(ic.reclaimExpiredIPAllocations, ic.LeaseCheckInterval, stopCh)
+	}
+
+	if ic.UsageForecastInterval > 0 {
+		go wait.Until(ic.forecastIPPoolExhaustion, ic.UsageForecastInterval, stopCh)
+	}
+
 	informerLogger.Info("IPPool controller workers started")
 
 	<-stopCh
@@ -435,7 +615,8 @@ func (ic *IPPoolController) scaleIPPoolIfNeeded(ctx context.Context, pool *spide
 		return fmt.Errorf("%w: there's no owner SpiderSubnet for IPPool '%s'", constant.ErrWrongInput, pool.Name)
 	}
 
-	if pool.Status.AutoDesiredIPCount == nil {
+	desiredIPNum, ok := AutoPoolTargetIPCount(pool)
+	if !ok {
 		informerLogger.Sugar().Debugf("maybe IPPool '%s' is just created for a while, wait for updating status DesiredIPCount", pool.Name)
 		return nil
 	}
@@ -445,7 +626,6 @@ func (ic *IPPoolController) scaleIPPoolIfNeeded(ctx context.Context, pool *spide
 		return fmt.Errorf("%w: failed to assemble Total IP addresses: %v", constant.ErrWrongInput, err)
 	}
 
-	desiredIPNum := int(*pool.Status.AutoDesiredIPCount)
 	totalIPCount := len(totalIPs)
 
 	if desiredIPNum == totalIPCount {
@@ -555,8 +735,15 @@ func (ic *IPPoolController) cleanAutoIPPoolLegacy(ctx context.Context, pool *spi
 		case constant.KindCronJob:
 			object = &batchv1.CronJob{}
 		default:
-			// pod and other controllers will clean up legacy ippools in IPAM
-			return false, nil
+			c, ok := subnetmanagercontrollers.GetThirdPartyControllerByKind(kind)
+			if !ok {
+				// pod and other controllers will clean up legacy ippools in IPAM
+				return false, nil
+			}
+			app := &unstructured.Unstructured{}
+			app.SetAPIVersion(c.APIVersion)
+			app.SetKind(c.Kind)
+			object = app
 		}
 
 		enableDelete := false
@@ -583,18 +770,51 @@ func (ic *IPPoolController) cleanAutoIPPoolLegacy(ctx context.Context, pool *spi
 		}
 
 		if enableDelete {
-			err := ic.client.Delete(ctx, pool)
-			if client.IgnoreNotFound(err) != nil {
-				return true, err
-			}
-			return true, nil
+			return ic.reclaimAutoIPPool(ctx, pool)
 		}
 	}
 
 	return false, nil
 }
 
+// reclaimAutoIPPool reclaims the auto-created IPPool whose application no longer
+// exists with the reclaim policy of its SpiderSubnet, unless the application
+// controller has delayed its reclaim already.
+func (ic *IPPoolController) reclaimAutoIPPool(ctx context.Context, pool *spiderpoolv1.SpiderIPPool) (isCleaned bool, err error) {
+	var policy *types.AutoPoolReclaimPolicy
+	if _, ok := GetIPPoolReclaimAfter(pool); ok {
+		policy = &types.AutoPoolReclaimPolicy{Policy: constant.ReclaimPolicyDelayed}
+	} else {
+		var subnet spiderpoolv1.SpiderSubnet
+		err = ic.client.Get(ctx, apitypes.NamespacedName{Name: GetAutoIPPoolSubnet(pool)}, &subnet)
+		if client.IgnoreNotFound(err) != nil {
+			return false, err
+		}
+		policy, err = subnetmanagercontrollers.GetAutoPoolReclaimPolicy(nil, &subnet)
+		if nil != err {
+			informerLogger.Sugar().Warnf("failed to get the reclaim policy of SpiderSubnet '%s', reclaim auto-created IPPool '%s' at once: %v", subnet.Name, pool.Name, err)
+		}
+	}
+
+	isCleaned, err = ic.ipPoolManager.ReclaimAutoIPPool(logutils.IntoContext(ctx, informerLogger), pool, policy)
+	if nil != err {
+		return false, err
+	}
+
+	// check the IPPool again once the grace period of the Delayed reclaim policy expires
+	if reclaimAfter, ok := GetIPPoolReclaimAfter(pool); ok && !isCleaned {
+		ic.enqueueAutoIPPoolAfter(pool, time.Until(reclaimAfter))
+	}
+
+	return isCleaned, nil
+}
+
 func (ic *IPPoolController) handleIPPool(ctx context.Context, pool *spiderpoolv1.SpiderIPPool) error {
+	// the IPPool will be handled again once the split or merge request is removed
+	if pool.DeletionTimestamp == nil && hasIPPoolReshapeRequest(pool) {
+		return ic.reshapeIPPool(ctx, pool)
+	}
+
 	// checkout the Auto-created IPPools whether need to scale or clean up legacies
 	if ic.EnableSpiderSubnet && IsAutoCreatedIPPool(pool) {
 		isCleaned, err := ic.cleanAutoIPPoolLegacy(ctx, pool)
@@ -604,6 +824,11 @@ func (ic *IPPoolController) handleIPPool(ctx context.Context, pool *spiderpoolv1
 
 		// there's no need to scale the IPPool if the IPPool is terminating.
 		if !isCleaned {
+			err = ic.syncAutoIPPoolSubnetDefaults(ctx, pool)
+			if nil != err {
+				return err
+			}
+
 			err = ic.scaleIPPoolIfNeeded(ctx, pool)
 			if nil != err {
 				if apierrors.IsConflict(err) {
@@ -642,6 +867,7 @@ func (ic *IPPoolController) syncHandleAllIPPool(ctx context.Context, pool *spide
 			}
 
 			informerLogger.Sugar().Infof("remove SpiderIPPool '%s' finalizer successfully", pool.Name)
+			ic.enqueueSiblingIPPools(pool)
 		}
 	} else {
 		needUpdate := false
@@ -653,7 +879,12 @@ func (ic *IPPoolController) syncHandleAllIPPool(ctx context.Context, pool *spide
 			informerLogger.Sugar().Infof("initial SpiderIPPool '%s' status AllocatedIPCount to 0", pool.Name)
 		}
 
-		totalIPs, err := spiderpoolip.AssembleTotalIPs(*pool.Spec.IPVersion, pool.Spec.IPs, pool.Spec.ExcludeIPs)
+		// the IP addresses reserved by SpiderReservedIPs and the special ones skipped when allocating are excluded
+		reservedIPs, err := ic.rIPManager.AssembleScopedReservedIPs(ctx, *pool.Spec.IPVersion, pool, "")
+		if nil != err {
+			return fmt.Errorf("failed to assemble reserved IP addresses: %w", err)
+		}
+		totalIPs, excludedIPs, err := assembleEffectiveIPs(pool, reservedIPs, ic.ReserveSpecialIPs)
 		if nil != err {
 			return fmt.Errorf("%w: failed to calculate SpiderIPPool '%s' total IP count, error: %v", constant.ErrWrongInput, pool.Name, err)
 		}
@@ -662,6 +893,84 @@ func (ic *IPPoolController) syncHandleAllIPPool(ctx context.Context, pool *spide
 			needUpdate = true
 			pool.Status.TotalIPCount = pointer.Int64(int64(len(totalIPs)))
 		}
+		if !reflect.DeepEqual(pool.Status.ExcludedIPs, excludedIPs) {
+			needUpdate = true
+			pool.Status.ExcludedIPs = excludedIPs
+		}
+
+		now := time.Now()
+		if pruneDatapathFailureNodes(pool, ic.DatapathFailureWindow, now) {
+			needUpdate = true
+		}
+		// re-evaluate the conditions GatewayUnreachable and Quarantined once
+		// the earliest datapath failure expires
+		if expiry, ok := nextDatapathFailureExpiry(pool, ic.DatapathFailureWindow); ok {
+			ic.normalPoolWorkQueue.AddAfter(pool.Name, expiry.Sub(now))
+		}
+
+		liftQuarantine := false
+		var quarantine *metav1.Condition
+		if cond := apimeta.FindStatusCondition(pool.Status.Conditions, constant.IPPoolConditionQuarantined); cond != nil && cond.Status == metav1.ConditionTrue {
+			remaining := cond.LastTransitionTime.Add(ic.QuarantineCoolDownDuration).Sub(now)
+			if remaining > 0 {
+				informerLogger.Sugar().Debugf("SpiderIPPool '%s' is quarantined, check it again after '%v'", pool.Name, remaining)
+				ic.normalPoolWorkQueue.AddAfter(pool.Name, remaining)
+			} else {
+				needUpdate = true
+				liftQuarantine = true
+				apimeta.SetStatusCondition(&pool.Status.Conditions, metav1.Condition{
+					Type:               constant.IPPoolConditionQuarantined,
+					Status:             metav1.ConditionFalse,
+					ObservedGeneration: pool.Generation,
+					Reason:             constant.IPPoolReasonCoolDownExpired,
+					Message:            fmt.Sprintf("Cool-down period %s expired", ic.QuarantineCoolDownDuration),
+				})
+				// the nodes report the failures afresh after the quarantine
+				clearRepeatedDatapathFailures(pool)
+			}
+		} else if quarantine = genIPPoolQuarantinedCondition(pool, ic.DatapathFailureWindow, ic.QuarantineCoolDownDuration, now); quarantine != nil {
+			needUpdate = true
+			apimeta.SetStatusCondition(&pool.Status.Conditions, *quarantine)
+			ic.normalPoolWorkQueue.AddAfter(pool.Name, ic.QuarantineCoolDownDuration)
+		}
+
+		oldConflicting := apimeta.FindStatusCondition(pool.Status.Conditions, constant.IPPoolConditionConflicting).DeepCopy()
+		oldGatewayUnreachable := apimeta.FindStatusCondition(pool.Status.Conditions, constant.IPPoolConditionGatewayUnreachable).DeepCopy()
+		conditionsChanged, err := ic.syncIPPoolConditions(ctx, pool)
+		if nil != err {
+			return fmt.Errorf("failed to generate SpiderIPPool '%s' conditions: %w", pool.Name, err)
+		}
+		if conditionsChanged {
+			needUpdate = true
+		}
+
+		if needSyncIPPoolDrainedCondition(pool) {
+			needUpdate = true
+			syncIPPoolDrainedCondition(pool)
+		}
+
+		inheritedRoutes, err := ic.genIPPoolInheritedRoutes(pool)
+		if nil != err {
+			return fmt.Errorf("failed to get the routes inherited by SpiderIPPool '%s': %w", pool.Name, err)
+		}
+		if !equalRoutes(pool.Status.InheritedRoutes, inheritedRoutes) {
+			needUpdate = true
+			pool.Status.InheritedRoutes = inheritedRoutes
+		}
+
+		var specChanges []spiderpoolv1.IPPoolSpecChange
+		if ic.MaxSpecChangelogs > 0 {
+			specChanges, err = getUnrecordedIPPoolSpecChanges(pool)
+			if nil != err {
+				informerLogger.Sugar().Warnf("failed to get the spec changes of SpiderIPPool '%s': %v", pool.Name, err)
+			} else if len(specChanges) != 0 {
+				needUpdate = true
+				pool.Status.SpecChangelog = append(pool.Status.SpecChangelog, specChanges...)
+				if n := len(pool.Status.SpecChangelog); n > ic.MaxSpecChangelogs {
+					pool.Status.SpecChangelog = pool.Status.SpecChangelog[n-ic.MaxSpecChangelogs:]
+				}
+			}
+		}
 
 		if needUpdate {
 			err = ic.client.Status().Update(ctx, pool)
@@ -669,12 +978,163 @@ func (ic *IPPoolController) syncHandleAllIPPool(ctx context.Context, pool *spide
 				return err
 			}
 			informerLogger.Sugar().Debugf("update SpiderIPPool '%s' status TotalIPCount to '%d' successfully", pool.Name, *pool.Status.TotalIPCount)
+
+			for j := range specChanges {
+				event.EventRecorder.Eventf(pool, corev1.EventTypeNormal, constant.EventReasonUpdateIPPoolSpec,
+					"Spec is changed by %s: %s", specChanges[j].User, ipPoolSpecChangeString(&specChanges[j]))
+			}
+
+			if conflicting := apimeta.FindStatusCondition(pool.Status.Conditions, constant.IPPoolConditionConflicting); conflicting != nil &&
+				(oldConflicting == nil || oldConflicting.Status != conflicting.Status || oldConflicting.Message != conflicting.Message) {
+				if conflicting.Status == metav1.ConditionTrue {
+					informerLogger.Sugar().Warnf("SpiderIPPool '%s' conflicts: %s", pool.Name, conflicting.Message)
+					event.EventRecorder.Event(pool, corev1.EventTypeWarning, constant.EventReasonConflictIPPool, conflicting.Message)
+				} else if oldConflicting != nil && oldConflicting.Status == metav1.ConditionTrue {
+					event.EventRecorder.Event(pool, corev1.EventTypeNormal, constant.EventReasonConflictIPPool, "Conflicts resolved")
+				}
+			}
+
+			if unreachable := apimeta.FindStatusCondition(pool.Status.Conditions, constant.IPPoolConditionGatewayUnreachable); unreachable != nil &&
+				(oldGatewayUnreachable == nil || oldGatewayUnreachable.Status != unreachable.Status || oldGatewayUnreachable.Message != unreachable.Message) {
+				if unreachable.Status == metav1.ConditionTrue {
+					informerLogger.Sugar().Warnf("the gateway of SpiderIPPool '%s' is unreachable: %s", pool.Name, unreachable.Message)
+					event.EventRecorder.Event(pool, corev1.EventTypeWarning, constant.EventReasonGatewayUnreachable, unreachable.Message)
+				} else if oldGatewayUnreachable != nil && oldGatewayUnreachable.Status == metav1.ConditionTrue {
+					event.EventRecorder.Event(pool, corev1.EventTypeNormal, constant.EventReasonGatewayUnreachable, "Gateway is reachable again")
+				}
+			}
+
+			if quarantine != nil {
+				informerLogger.Sugar().Warnf("quarantine SpiderIPPool '%s': %s", pool.Name, quarantine.Message)
+				event.EventRecorder.Eventf(pool, corev1.EventTypeWarning, constant.EventReasonQuarantineIPPool,
+					"Quarantined for %s: %s", ic.QuarantineCoolDownDuration, quarantine.Message)
+			}
+
+			if liftQuarantine {
+				informerLogger.Sugar().Infof("lift the quarantine of SpiderIPPool '%s'", pool.Name)
+				event.EventRecorder.Event(pool, corev1.EventTypeNormal, constant.EventReasonQuarantineIPPool, "Quarantine lifted")
+			}
 		}
 	}
 
 	return nil
 }
 
+// genIPPoolDrainedCondition returns the condition "Drained" reporting how
+// many IP addresses remain allocated from the draining IPPool.
+func genIPPoolDrainedCondition(pool *spiderpoolv1.SpiderIPPool) metav1.Condition {
+	allocated := len(pool.Status.AllocatedIPs)
+	if allocated == 0 {
+		return metav1.Condition{
+			Type:               constant.IPPoolConditionDrained,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: pool.Generation,
+			Reason:             constant.IPPoolReasonAllocationsReleased,
+			Message:            "All IP addresses are released",
+		}
+	}
+
+	return metav1.Condition{
+		Type:               constant.IPPoolConditionDrained,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: pool.Generation,
+		Reason:             constant.IPPoolReasonAllocationsRemaining,
+		Message:            fmt.Sprintf("%d IP addresses remain allocated", allocated),
+	}
+}
+
+// needSyncIPPoolDrainedCondition reports whether the condition "Drained" of
+// the IPPool is out of date, it's removed once the IPPool is not draining.
+func needSyncIPPoolDrainedCondition(pool *spiderpoolv1.SpiderIPPool) bool {
+	cond := apimeta.FindStatusCondition(pool.Status.Conditions, constant.IPPoolConditionDrained)
+	if !IsDrainingIPPool(pool) {
+		return cond != nil
+	}
+	if cond == nil {
+		return true
+	}
+
+	expected := genIPPoolDrainedCondition(pool)
+	return cond.Status != expected.Status || cond.Message != expected.Message || cond.ObservedGeneration != expected.ObservedGeneration
+}
+
+func syncIPPoolDrainedCondition(pool *spiderpoolv1.SpiderIPPool) {
+	if !IsDrainingIPPool(pool) {
+		apimeta.RemoveStatusCondition(&pool.Status.Conditions, constant.IPPoolConditionDrained)
+		return
+	}
+	apimeta.SetStatusCondition(&pool.Status.Conditions, genIPPoolDrainedCondition(pool))
+}
+
+// genIPPoolInheritedRoutes returns the routes which the IPPool inherits from
+// its controller Subnet.
+func (ic *IPPoolController) genIPPoolInheritedRoutes(pool *spiderpoolv1.SpiderIPPool) ([]spiderpoolv1.Route, error) {
+	if !ic.EnableSpiderSubnet || (pool.Spec.InheritSubnetRoutes != nil && !*pool.Spec.InheritSubnetRoutes) {
+		return nil, nil
+	}
+
+	subnetName, ok := pool.Labels[constant.LabelIPPoolOwnerSpiderSubnet]
+	if !ok {
+		return nil, nil
+	}
+
+	subnet, err := ic.subnetsLister.Get(subnetName)
+	if nil != err {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(subnet.Spec.Routes) == 0 {
+		return nil, nil
+	}
+
+	return append([]spiderpoolv1.Route(nil), subnet.Spec.Routes...), nil
+}
+
+// needSyncIPPoolInheritedRoutes reports whether the inherited routes of the
+// IPPool differ from the routes of its controller Subnet.
+func (ic *IPPoolController) needSyncIPPoolInheritedRoutes(pool *spiderpoolv1.SpiderIPPool) bool {
+	routes, err := ic.genIPPoolInheritedRoutes(pool)
+	if nil != err {
+		return false
+	}
+
+	return !equalRoutes(pool.Status.InheritedRoutes, routes)
+}
+
+func equalRoutes(routes1, routes2 []spiderpoolv1.Route) bool {
+	if len(routes1) == 0 && len(routes2) == 0 {
+		return true
+	}
+
+	return reflect.DeepEqual(routes1, routes2)
+}
+
+// syncSubnetRoutesIPPools enqueues the IPPools controlled by the Subnet once
+// its routes are changed, so that the routes they inherit are synchronized.
+func (ic *IPPoolController) syncSubnetRoutesIPPools(oldObj, newObj interface{}) {
+	oldSubnet := oldObj.(*spiderpoolv1.SpiderSubnet)
+	newSubnet := newObj.(*spiderpoolv1.SpiderSubnet)
+	if equalRoutes(oldSubnet.Spec.Routes, newSubnet.Spec.Routes) {
+		return
+	}
+
+	selector := labels.Set{constant.LabelIPPoolOwnerSpiderSubnet: newSubnet.Name}.AsSelector()
+	ipPools, err := ic.poolLister.List(selector)
+	if nil != err {
+		informerLogger.Sugar().Errorf("syncSubnetRoutesIPPools error: %v", err)
+		return
+	}
+
+	for _, pool := range ipPools {
+		if ic.needSyncIPPoolInheritedRoutes(pool) {
+			informerLogger.Sugar().Debugf("try to add IPPool %s to sync the routes of SpiderSubnet %s", pool.Name, newSubnet.Name)
+			ic.enqueueIPPool(pool)
+		}
+	}
+}
+
 // syncSubnetIPPools will enqueue all SpiderSubnet object corresponding IPPools name into workQueue
 func (ic *IPPoolController) syncSubnetIPPools(obj interface{}) {
 	subnet := obj.(*spiderpoolv1.SpiderSubnet)
@@ -779,7 +1239,8 @@ func (ic *IPPoolController) scaleIPPoolWithIPs(ctx context.Context, pool *spider
 func (ic *IPPoolController) generateIPsFromSubnetWhenScaleUpIP(ctx context.Context, subnetName string, pool *spiderpoolv1.SpiderIPPool, cursor bool) ([]string, error) {
 	log := logutils.FromContext(ctx)
 
-	if pool.Status.AutoDesiredIPCount == nil {
+	desiredIPNum, ok := AutoPoolTargetIPCount(pool)
+	if !ok {
 		return nil, fmt.Errorf("%w: we can't generate IPs for the IPPool '%s' who doesn't have Status AutoDesiredIPCount", constant.ErrWrongInput, pool.Name)
 	}
 
@@ -800,7 +1261,6 @@ func (ic *IPPoolController) generateIPsFromSubnetWhenScaleUpIP(ctx context.Conte
 
 	var beforeAllocatedIPs []net.IP
 
-	desiredIPNum := int(*pool.Status.AutoDesiredIPCount)
 	poolTotalIPs, err := spiderpoolip.AssembleTotalIPs(ipVersion, pool.Spec.IPs, pool.Spec.ExcludeIPs)
 	if nil != err {
 		return nil, fmt.Errorf("%w: failed to assemble IPPool '%s' total IPs, error: %v", constant.ErrWrongInput, pool.Name, err)
@@ -846,7 +1306,7 @@ func (ic *IPPoolController) generateIPsFromSubnetWhenScaleUpIP(ctx context.Conte
 	}
 
 	// filter reserved IPs
-	reservedIPs, err := ic.rIPManager.AssembleReservedIPs(ctx, ipVersion)
+	reservedIPs, err := ic.rIPManager.AssembleScopedReservedIPs(ctx, ipVersion, pool, "")
 	if err != nil {
 		return nil, fmt.Errorf("%w: failed to filter reservedIPs '%v' by IP version '%d', error: %v",
 			constant.ErrWrongInput, reservedIPs, ipVersion, err)
@@ -864,6 +1324,15 @@ func (ic *IPPoolController) generateIPsFromSubnetWhenScaleUpIP(ctx context.Conte
 		freeIPs = spiderpoolip.IPsDiffSet(freeIPs, excludeIPs, true)
 	}
 
+	// the pinned IPPool only takes its pinned IPs
+	pinnedIPs, err := GetAutoIPPoolPinnedIPs(pool)
+	if nil != err {
+		return nil, fmt.Errorf("%w: failed to parse IPPool '%s' pinned IPs, error: %v", constant.ErrWrongInput, pool.Name, err)
+	}
+	if len(pinnedIPs) != 0 {
+		freeIPs = spiderpoolip.IPsIntersectionSet(freeIPs, pinnedIPs, true)
+	}
+
 	// check the filtered subnet free IP number is enough or not
 	if len(freeIPs) < ipNum {
 		return nil, fmt.Errorf("insufficient subnet FreeIPs, required '%d' but only left '%d'", ipNum, len(freeIPs))
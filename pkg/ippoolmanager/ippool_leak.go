@@ -0,0 +1,78 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+// ReportIPLeaks replaces 'status.suspectedLeakedIPs' of the IPPool with the
+// leaks found by the IP garbage collection. The leaks of the IP addresses no
+// longer allocated to the same containers are dropped, and the time when
+// each leak was detected first is kept.
+func (im *ipPoolManager) ReportIPLeaks(ctx context.Context, poolName string, leaks map[string]spiderpoolv1.IPLeak) error {
+	logger := logutils.FromContext(ctx)
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i <= im.config.MaxConflictRetries; i++ {
+		ipPool, err := im.GetIPPoolByName(ctx, poolName)
+		if err != nil {
+			return err
+		}
+
+		suspected := genSuspectedLeakedIPs(ipPool, leaks)
+		if reflect.DeepEqual(ipPool.Status.SuspectedLeakedIPs, suspected) {
+			return nil
+		}
+
+		ipPool.Status.SuspectedLeakedIPs = suspected
+		if err := im.client.Status().Update(ctx, ipPool); err != nil {
+			if !apierrors.IsConflict(err) {
+				return err
+			}
+			if i == im.config.MaxConflictRetries {
+				return fmt.Errorf("%w (%d times), failed to report the leaked IP addresses of IPPool %s", constant.ErrRetriesExhausted, im.config.MaxConflictRetries, poolName)
+			}
+
+			interval := time.Duration(r.Intn(1<<(i+1))) * im.config.ConflictRetryUnitTime
+			logger.Sugar().Debugf("An conflict occurred when reporting the leaked IP addresses of IPPool %s, it will be retried in %s", poolName, interval)
+
+			time.Sleep(interval)
+			continue
+		}
+		break
+	}
+
+	return nil
+}
+
+func genSuspectedLeakedIPs(ipPool *spiderpoolv1.SpiderIPPool, leaks map[string]spiderpoolv1.IPLeak) map[string]spiderpoolv1.IPLeak {
+	var suspected map[string]spiderpoolv1.IPLeak
+	for ip, leak := range leaks {
+		allocation, ok := ipPool.Status.AllocatedIPs[ip]
+		if !ok || allocation.ContainerID != leak.ContainerID {
+			continue
+		}
+
+		if last, ok := ipPool.Status.SuspectedLeakedIPs[ip]; ok && last.ContainerID == leak.ContainerID {
+			leak.DetectedAt = last.DetectedAt
+		}
+		if suspected == nil {
+			suspected = map[string]spiderpoolv1.IPLeak{}
+		}
+		suspected[ip] = leak
+	}
+
+	return suspected
+}
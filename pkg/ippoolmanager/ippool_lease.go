@@ -0,0 +1,158 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	apitypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// GetIPPoolLeaseDuration returns the lease of the IP allocations of the
+// IPPool, zero means the lease is disabled.
+func GetIPPoolLeaseDuration(pool *spiderpoolv1.SpiderIPPool) time.Duration {
+	if pool.Spec.LeaseDurationSeconds == nil || *pool.Spec.LeaseDurationSeconds <= 0 {
+		return 0
+	}
+
+	return time.Duration(*pool.Spec.LeaseDurationSeconds) * time.Second
+}
+
+// IsIPAllocationLeaseExpired tells whether the lease of the IP allocation of
+// the IPPool has expired at now. The allocations recorded before the lease
+// of the IPPool was set are regarded as expired.
+func IsIPAllocationLeaseExpired(pool *spiderpoolv1.SpiderIPPool, allocation spiderpoolv1.PoolIPAllocation, now time.Time) bool {
+	lease := GetIPPoolLeaseDuration(pool)
+	if lease == 0 {
+		return false
+	}
+	if allocation.LeaseRenewTime == nil {
+		return true
+	}
+
+	return allocation.LeaseRenewTime.Add(lease).Before(now)
+}
+
+// IsIPAllocationLeaseDue tells whether the lease of the IP allocation of the
+// IPPool is due for renewal at now, which is once half of it has passed.
+func IsIPAllocationLeaseDue(pool *spiderpoolv1.SpiderIPPool, allocation spiderpoolv1.PoolIPAllocation, now time.Time) bool {
+	lease := GetIPPoolLeaseDuration(pool)
+	if lease == 0 {
+		return false
+	}
+
+	return allocation.LeaseRenewTime == nil || now.Sub(allocation.LeaseRenewTime.Time) >= lease/2
+}
+
+// RenewIPLeases renews the leases of the IP allocations of the IPPool. To
+// limit the updates of the IPPool, a lease is renewed only if half of it
+// has passed.
+func (im *ipPoolManager) RenewIPLeases(ctx context.Context, poolName string, ipAndCIDs []types.IPAndCID) error {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i <= im.config.MaxConflictRetries; i++ {
+		ipPool, err := im.GetIPPoolByName(ctx, poolName)
+		if err != nil {
+			return err
+		}
+
+		if GetIPPoolLeaseDuration(ipPool) == 0 {
+			return nil
+		}
+
+		now := metav1.Now()
+		renewed := false
+		for _, cur := range ipAndCIDs {
+			record, ok := ipPool.Status.AllocatedIPs[cur.IP]
+			if !ok || record.ContainerID != cur.ContainerID {
+				continue
+			}
+			if !IsIPAllocationLeaseDue(ipPool, record, now.Time) {
+				continue
+			}
+
+			record.LeaseRenewTime = &now
+			ipPool.Status.AllocatedIPs[cur.IP] = record
+			renewed = true
+		}
+
+		if !renewed {
+			return nil
+		}
+
+		resourceVersion := ipPool.ResourceVersion
+		if err := im.client.Status().Update(ctx, ipPool); err != nil {
+			if !apierrors.IsConflict(err) {
+				return err
+			}
+			if i == im.config.MaxConflictRetries {
+				return fmt.Errorf("%w (%d times), failed to renew the leases of IP addresses %+v of IPPool %s", constant.ErrRetriesExhausted, im.config.MaxConflictRetries, ipAndCIDs, poolName)
+			}
+
+			time.Sleep(time.Duration(r.Intn(1<<(i+1))) * im.config.ConflictRetryUnitTime)
+			continue
+		}
+		im.freeIPs.Update(ipPool.Name, resourceVersion, ipPool.ResourceVersion, nil, true)
+		break
+	}
+
+	return nil
+}
+
+// reclaimExpiredIPAllocations releases the IP allocations with expired leases
+// of the Pods which no longer exist, which bounds the duration of the IP
+// addresses leaked by the missing CNI DEL.
+func (ic *IPPoolController) reclaimExpiredIPAllocations() {
+	pools, err := ic.poolLister.List(labels.Everything())
+	if err != nil {
+		informerLogger.Sugar().Warnf("failed to list SpiderIPPools to reclaim expired IP allocations: %v", err)
+		return
+	}
+
+	ctx := context.TODO()
+	now := time.Now()
+	for _, pool := range pools {
+		if GetIPPoolLeaseDuration(pool) == 0 {
+			continue
+		}
+
+		var expired []types.IPAndCID
+		for ip, allocation := range pool.Status.AllocatedIPs {
+			if !IsIPAllocationLeaseExpired(pool, allocation, now) {
+				continue
+			}
+
+			err := ic.client.Get(ctx, apitypes.NamespacedName{Namespace: allocation.Namespace, Name: allocation.Pod}, &corev1.Pod{})
+			if err == nil {
+				continue
+			}
+			if !apierrors.IsNotFound(err) {
+				informerLogger.Sugar().Warnf("failed to get Pod %s/%s of the expired IP allocation %s: %v", allocation.Namespace, allocation.Pod, ip, err)
+				continue
+			}
+
+			expired = append(expired, types.IPAndCID{IP: ip, ContainerID: allocation.ContainerID, Node: allocation.Node})
+		}
+
+		if len(expired) == 0 {
+			continue
+		}
+
+		if err := ic.ipPoolManager.ReleaseIP(ctx, pool.Name, expired); err != nil {
+			informerLogger.Sugar().Warnf("failed to reclaim the expired IP allocations %+v of SpiderIPPool '%s': %v", expired, pool.Name, err)
+			continue
+		}
+		informerLogger.Sugar().Infof("reclaim the expired IP allocations %+v of SpiderIPPool '%s'", expired, pool.Name)
+	}
+}
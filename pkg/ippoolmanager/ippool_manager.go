@@ -5,15 +5,22 @@ package ippoolmanager
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
+	"reflect"
 	"time"
 
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	ctrlcache "sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/spidernet-io/spiderpool/api/v1/agent/models"
@@ -22,6 +29,7 @@ import (
 	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
 	"github.com/spidernet-io/spiderpool/pkg/logutils"
 	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+	subnetmanagercontrollers "github.com/spidernet-io/spiderpool/pkg/subnetmanager/controllers"
 	"github.com/spidernet-io/spiderpool/pkg/types"
 )
 
@@ -30,15 +38,33 @@ type IPPoolManager interface {
 	ListIPPools(ctx context.Context, opts ...client.ListOption) (*spiderpoolv1.SpiderIPPoolList, error)
 	AllocateIP(ctx context.Context, poolName, containerID, nic string, pod *corev1.Pod, podController types.PodTopController) (*models.IPConfig, error)
 	ReleaseIP(ctx context.Context, poolName string, ipAndCIDs []types.IPAndCID) error
+	ReleaseIPs(ctx context.Context, poolToIPAndCIDs map[string][]types.IPAndCID) ([]IPReleaseResult, error)
 	UpdateAllocatedIPs(ctx context.Context, poolName string, ipAndCIDs []types.IPAndCID) error
+	TransferIPs(ctx context.Context, poolName string, ipAndCIDs []types.IPAndCID, containerID string, pod *corev1.Pod) error
 	DeleteAllIPPools(ctx context.Context, pool *spiderpoolv1.SpiderIPPool, opts ...client.DeleteAllOfOption) error
 	UpdateDesiredIPNumber(ctx context.Context, pool *spiderpoolv1.SpiderIPPool, ipNum int) error
+	ReclaimAutoIPPool(ctx context.Context, pool *spiderpoolv1.SpiderIPPool, policy *types.AutoPoolReclaimPolicy) (bool, error)
+	ReportRepeatedDatapathFailures(ctx context.Context, poolName, nodeName, message string) error
+	SetIPPoolCondition(ctx context.Context, poolName string, condition metav1.Condition) error
+	ReportGatewayReachability(ctx context.Context, poolName, nodeName string, reachable bool) error
+	ClaimNetworkScan(ctx context.Context, poolName, nodeName string, interval, timeout time.Duration) (*spiderpoolv1.SpiderIPPool, error)
+	ReportNetworkScan(ctx context.Context, poolName, nodeName string, inUseIPs []net.IP) error
+	ExpandIPPool(ctx context.Context, poolName string, ipRanges []string) (*spiderpoolv1.SpiderIPPool, error)
+	SplitIPPool(ctx context.Context, poolName string, splits map[string][]string) error
+	MergeIPPools(ctx context.Context, poolName string, siblings []string) error
+	RenewIPLeases(ctx context.Context, poolName string, ipAndCIDs []types.IPAndCID) error
+	GetIPAllocationByIP(ctx context.Context, ip string) (*spiderpoolv1.SpiderIPPool, *spiderpoolv1.PoolIPAllocation, error)
+	ExportIPAllocations(ctx context.Context, poolName string) (*IPPoolAllocationState, error)
+	ImportIPAllocations(ctx context.Context, poolName string, state *IPPoolAllocationState) error
+	ReportIPLeaks(ctx context.Context, poolName string, leaks map[string]spiderpoolv1.IPLeak) error
+	SetupFreeIPCacheInformers(ctx context.Context, informers ctrlcache.Informers) error
 }
 
 type ipPoolManager struct {
 	config     IPPoolManagerConfig
 	client     client.Client
 	rIPManager reservedipmanager.ReservedIPManager
+	freeIPs    *freeIPCache
 }
 
 func NewIPPoolManager(config IPPoolManagerConfig, client client.Client, rIPManager reservedipmanager.ReservedIPManager) (IPPoolManager, error) {
@@ -53,6 +79,7 @@ func NewIPPoolManager(config IPPoolManagerConfig, client client.Client, rIPManag
 		config:     setDefaultsForIPPoolManagerConfig(config),
 		client:     client,
 		rIPManager: rIPManager,
+		freeIPs:    newFreeIPCache(),
 	}, nil
 }
 
@@ -85,15 +112,37 @@ func (im *ipPoolManager) AllocateIP(ctx context.Context, poolName, containerID,
 
 		ipPool, err := im.GetIPPoolByName(ctx, poolName)
 		if err != nil {
+			if apierrors.IsNotFound(err) {
+				im.freeIPs.Delete(poolName)
+			}
 			return nil, err
 		}
 
-		logger.Debug("Generate a random IP address")
-		allocatedIP, err := im.genRandomIP(ctx, ipPool)
+		// The IPPool may be fenced after it was selected as a candidate.
+		if IsReshapingIPPool(ipPool) {
+			return nil, fmt.Errorf("IPPool %s is being split or merged", ipPool.Name)
+		}
+		if IsDrainingIPPool(ipPool) {
+			return nil, fmt.Errorf("IPPool %s is being drained", ipPool.Name)
+		}
+
+		claimedIP, err := im.bindClaimedIP(ctx, ipPool, pod)
 		if err != nil {
 			return nil, err
 		}
 
+		var allocatedIP net.IP
+		if claimedIP != nil {
+			logger.Sugar().Debugf("Use the IP address %s claimed by SpiderReservedIP %s", claimedIP.IP, claimedIP.ReservedIP)
+			allocatedIP = claimedIP.IP
+		} else {
+			logger.Debug("Generate a random IP address")
+			allocatedIP, err = im.genRandomIP(ctx, ipPool, pod)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		if ipPool.Status.AllocatedIPs == nil {
 			ipPool.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{}
 		}
@@ -104,9 +153,14 @@ func (im *ipPoolManager) AllocateIP(ctx context.Context, poolName, containerID,
 			Node:                pod.Spec.NodeName,
 			Namespace:           pod.Namespace,
 			Pod:                 pod.Name,
+			PodUID:              string(pod.UID),
 			OwnerControllerType: podController.Kind,
 			OwnerControllerName: podController.Name,
 		}
+		if GetIPPoolLeaseDuration(ipPool) != 0 {
+			now := metav1.Now()
+			allocation.LeaseRenewTime = &now
+		}
 
 		ip := allocatedIP.String()
 		ipPool.Status.AllocatedIPs[ip] = allocation
@@ -121,6 +175,7 @@ func (im *ipPoolManager) AllocateIP(ctx context.Context, poolName, containerID,
 		}
 
 		logger.Sugar().Debugf("Try to update the allocation status of IPPool %s with random IP %s", ipPool.Name, ip)
+		resourceVersion := ipPool.ResourceVersion
 		if err := im.client.Status().Update(ctx, ipPool); err != nil {
 			if !apierrors.IsConflict(err) {
 				return nil, err
@@ -136,42 +191,201 @@ func (im *ipPoolManager) AllocateIP(ctx context.Context, poolName, containerID,
 			continue
 		}
 
+		im.freeIPs.Update(ipPool.Name, resourceVersion, ipPool.ResourceVersion, []string{ip}, true)
 		ipConfig = genResIPConfig(allocatedIP, nic, ipPool)
+
+		// The claim is converted into the allocation, the IP address is no
+		// longer reserved once the allocation is recorded in the IPPool. It
+		// stays bound to the Pod if the consumption fails, which is retried
+		// by spiderpool-controller then.
+		if claimedIP != nil {
+			if err := im.rIPManager.ConsumeClaimedIP(ctx, *claimedIP); err != nil {
+				logger.Sugar().Errorf("Failed to consume the IP address %s claimed by SpiderReservedIP %s, leave it to spiderpool-controller: %v", ip, claimedIP.ReservedIP, err)
+			}
+		}
 		break
 	}
 
 	return ipConfig, nil
 }
 
-func (im *ipPoolManager) genRandomIP(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool) (net.IP, error) {
-	reservedIPs, err := im.rIPManager.AssembleReservedIPs(ctx, *ipPool.Spec.IPVersion)
+func (im *ipPoolManager) genRandomIP(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, pod *corev1.Pod) (net.IP, error) {
+	reservedIPs, err := im.assembleScopedReservedIPs(ctx, ipPool, pod.Namespace)
 	if err != nil {
 		return nil, err
 	}
 
-	var used []string
-	for ip := range ipPool.Status.AllocatedIPs {
-		used = append(used, ip)
+	// The IP addresses delegated to the child IPPools are not allocated by
+	// the parent.
+	delegatedIPs, err := im.assembleDelegatedIPs(ctx, ipPool)
+	if err != nil {
+		return nil, err
+	}
+
+	reservedIPs = append(reservedIPs, delegatedIPs...)
+	if ShouldReserveSpecialIPs(ipPool, im.config.ReserveSpecialIPs) {
+		specialIPs, err := GetSpecialIPs(ipPool)
+		if err != nil {
+			return nil, err
+		}
+		reservedIPs = append(reservedIPs, specialIPs...)
+	}
+
+	return im.freeIPs.Pick(ipPool, reservedIPs)
+}
+
+// assembleScopedReservedIPs returns the IP addresses of the IPPool reserved
+// for the Pods in the namespace. The SpiderReservedIPs of the IPPool are
+// cached until the informer tells any SpiderReservedIP is changed, while
+// their expiration and namespace selectors are checked every time.
+func (im *ipPoolManager) assembleScopedReservedIPs(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, namespace string) ([]net.IP, error) {
+	rIPs, version, ok := im.freeIPs.getReservedIPs(ipPool)
+	if !ok {
+		rIPList, err := im.rIPManager.ListPoolReservedIPs(ctx, *ipPool.Spec.IPVersion, ipPool)
+		if err != nil {
+			return nil, err
+		}
+
+		rIPs = make([]poolReservedIP, 0, len(rIPList))
+		for i := range rIPList {
+			rIP := &rIPList[i]
+			ips, err := im.freeIPs.parseReservedIP(rIP, func() ([]net.IP, error) {
+				return reservedipmanager.GetReservedIPs(*ipPool.Spec.IPVersion, rIP)
+			})
+			if err != nil {
+				return nil, err
+			}
+			rIPs = append(rIPs, poolReservedIP{rIP: rIP, ips: ips})
+		}
+		im.freeIPs.setReservedIPs(ipPool, version, rIPs)
+	}
+
+	// The expired SpiderReservedIPs are skipped before they're deleted.
+	now := time.Now()
+	var nsLabels labels.Set
+	var reservedIPs []net.IP
+	for _, r := range rIPs {
+		if reservedipmanager.IsReservedIPExpired(r.rIP, now) {
+			continue
+		}
+		// The namespace is got lazily, only once.
+		if r.rIP.Spec.NamespaceSelector != nil && namespace != "" && nsLabels == nil {
+			var ns corev1.Namespace
+			if err := im.client.Get(ctx, apitypes.NamespacedName{Name: namespace}, &ns); err != nil {
+				return nil, err
+			}
+			nsLabels = labels.Set(ns.Labels)
+			if nsLabels == nil {
+				nsLabels = labels.Set{}
+			}
+		}
+		ok, err := reservedipmanager.MatchReservedIPNamespace(r.rIP, nsLabels)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			reservedIPs = append(reservedIPs, r.ips...)
+		}
 	}
-	usedIPs, err := spiderpoolip.ParseIPRanges(*ipPool.Spec.IPVersion, used)
+
+	return reservedIPs, nil
+}
+
+// bindClaimedIP returns an IP address of the IPPool which is reserved for
+// the Pod by the claim of a SpiderReservedIP and not yet allocated, after
+// binding it to the Pod. The IP address already bound to the Pod is
+// preferred, so that a retried allocation reuses it.
+func (im *ipPoolManager) bindClaimedIP(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, pod *corev1.Pod) (*reservedipmanager.ClaimedIP, error) {
+	claimedIPs, err := im.rIPManager.ListClaimedIPs(ctx, *ipPool.Spec.IPVersion, ipPool, pod)
 	if err != nil {
 		return nil, err
 	}
+	if len(claimedIPs) == 0 {
+		return nil, nil
+	}
 
 	totalIPs, err := spiderpoolip.AssembleTotalIPs(*ipPool.Spec.IPVersion, ipPool.Spec.IPs, ipPool.Spec.ExcludeIPs)
 	if err != nil {
 		return nil, err
 	}
+	total := make(map[string]struct{}, len(totalIPs))
+	for _, ip := range totalIPs {
+		total[ip.String()] = struct{}{}
+	}
+
+	var candidates []*reservedipmanager.ClaimedIP
+	for i := range claimedIPs {
+		c := &claimedIPs[i]
+		ip := c.IP.String()
+		if _, ok := total[ip]; !ok {
+			continue
+		}
+		if _, ok := ipPool.Status.AllocatedIPs[ip]; ok {
+			continue
+		}
+
+		if c.BoundPodUID == string(pod.UID) {
+			return c, nil
+		}
+		if c.BoundPodUID == "" {
+			candidates = append(candidates, c)
+		}
+	}
+
+	// Another claimant may bind the IP address meanwhile, then the next one
+	// is tried.
+	for _, c := range candidates {
+		if err := im.rIPManager.BindClaimedIP(ctx, *c, pod); err != nil {
+			if errors.Is(err, constant.ErrClaimedIPTaken) {
+				continue
+			}
+			return nil, err
+		}
+		return c, nil
+	}
+
+	return nil, nil
+}
+
+// assembleDelegatedIPs returns the total IP addresses of the child IPPools
+// carved from the IPPool, whose parsing is cached until they're changed.
+func (im *ipPoolManager) assembleDelegatedIPs(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool) ([]net.IP, error) {
+	if ipPool.Spec.ParentPool != nil {
+		return nil, nil
+	}
+
+	childList, err := im.ListIPPools(ctx, client.MatchingLabels{constant.LabelIPPoolParent: ipPool.Name})
+	if err != nil {
+		return nil, err
+	}
 
-	availableIPs := spiderpoolip.IPsDiffSet(totalIPs, append(reservedIPs, usedIPs...), false)
-	if len(availableIPs) == 0 {
-		return nil, constant.ErrIPUsedOut
+	var children []*spiderpoolv1.SpiderIPPool
+	for i := range childList.Items {
+		if GetIPPoolParent(&childList.Items[i]) == ipPool.Name {
+			children = append(children, &childList.Items[i])
+		}
 	}
 
-	return availableIPs[0], nil
+	return im.freeIPs.parseChildren(ipPool, children, func(child *spiderpoolv1.SpiderIPPool) ([]net.IP, error) {
+		ips, err := spiderpoolip.AssembleTotalIPs(*ipPool.Spec.IPVersion, child.Spec.IPs, child.Spec.ExcludeIPs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to assemble the total IP addresses of the child IPPool %s: %v", child.Name, err)
+		}
+		return ips, nil
+	})
 }
 
 func (im *ipPoolManager) ReleaseIP(ctx context.Context, poolName string, ipAndCIDs []types.IPAndCID) error {
+	_, err := im.releaseIP(ctx, poolName, ipAndCIDs)
+	return err
+}
+
+// releaseIP removes the allocation records of the IP addresses which are
+// still allocated to the containers, and returns the released ones. The
+// status of the IPPool is patched with the records and the count of the
+// allocated IP addresses guarded by 'test' operations, so that the release
+// only conflicts with the allocations and releases of the IPPool.
+func (im *ipPoolManager) releaseIP(ctx context.Context, poolName string, ipAndCIDs []types.IPAndCID) ([]string, error) {
 	logger := logutils.FromContext(ctx)
 
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -181,50 +395,81 @@ func (im *ipPoolManager) ReleaseIP(ctx context.Context, poolName string, ipAndCI
 
 		ipPool, err := im.GetIPPoolByName(ctx, poolName)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		if ipPool.Status.AllocatedIPs == nil {
-			ipPool.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{}
+		var released []string
+		var ops []jsonPatchOperation
+		for _, cur := range ipAndCIDs {
+			if record, ok := ipPool.Status.AllocatedIPs[cur.IP]; ok && record.ContainerID == cur.ContainerID {
+				path := "/status/allocatedIPs/" + escapeJSONPointer(cur.IP)
+				ops = append(ops,
+					jsonPatchOperation{Op: "test", Path: path + "/containerID", Value: cur.ContainerID},
+					jsonPatchOperation{Op: "remove", Path: path},
+				)
+				released = append(released, cur.IP)
+			}
 		}
-		if ipPool.Status.AllocatedIPCount == nil {
-			ipPool.Status.AllocatedIPCount = new(int64)
+
+		if len(released) == 0 {
+			return nil, nil
 		}
 
-		release := false
-		for _, cur := range ipAndCIDs {
-			if record, ok := ipPool.Status.AllocatedIPs[cur.IP]; ok {
-				if record.ContainerID == cur.ContainerID {
-					delete(ipPool.Status.AllocatedIPs, cur.IP)
-					*ipPool.Status.AllocatedIPCount--
-					release = true
-				}
-			}
+		var count int64
+		if ipPool.Status.AllocatedIPCount != nil {
+			count = *ipPool.Status.AllocatedIPCount
+			ops = append(ops, jsonPatchOperation{Op: "test", Path: "/status/allocatedIPCount", Value: count})
+		}
+		count -= int64(len(released))
+		if count < 0 {
+			count = 0
 		}
+		ops = append(ops, jsonPatchOperation{Op: "add", Path: "/status/allocatedIPCount", Value: count})
 
-		if !release {
-			return nil
+		patch, err := newJSONPatch(ops...)
+		if err != nil {
+			return nil, err
 		}
 
 		logger.Sugar().Debugf("Try to clean the allocation status of IPPool %s with IP addresses %+v", ipPool.Name, ipAndCIDs)
-		if err := im.client.Status().Update(ctx, ipPool); err != nil {
-			if !apierrors.IsConflict(err) {
-				return err
+		resourceVersion := ipPool.ResourceVersion
+		if err := im.client.Status().Patch(ctx, ipPool, patch); err != nil {
+			if !isJSONPatchTestFailed(err) && !apierrors.IsConflict(err) {
+				return nil, err
 			}
 			if i == im.config.MaxConflictRetries {
-				return fmt.Errorf("%w (%d times), failed to release IP addresses %+v from IPPool %s", constant.ErrRetriesExhausted, im.config.MaxConflictRetries, ipAndCIDs, poolName)
+				return nil, fmt.Errorf("%w (%d times), failed to release IP addresses %+v from IPPool %s", constant.ErrRetriesExhausted, im.config.MaxConflictRetries, ipAndCIDs, poolName)
 			}
 
 			interval := time.Duration(r.Intn(1<<(i+1))) * im.config.ConflictRetryUnitTime
-			logger.Sugar().Debugf("An conflict occurred when releasing form the IPPool %s, it will be retried in %s", ipPool.Name, interval)
+			logger.Sugar().Debugf("IPPool %s is changed when releasing from it, it will be retried in %s", ipPool.Name, interval)
 
 			time.Sleep(interval)
 			continue
 		}
-		break
+		im.freeIPs.Update(ipPool.Name, resourceVersion, ipPool.ResourceVersion, released, false)
+
+		return released, nil
 	}
 
-	return nil
+	return nil, nil
+}
+
+// GetIPAllocationByIP returns the IPPool which the IP address is allocated
+// from and the allocation record, which tells who owns the IP address. Nil
+// is returned if the IP address is not allocated.
+func (im *ipPoolManager) GetIPAllocationByIP(ctx context.Context, ip string) (*spiderpoolv1.SpiderIPPool, *spiderpoolv1.PoolIPAllocation, error) {
+	if net.ParseIP(ip) == nil {
+		return nil, nil, fmt.Errorf("%w, invalid IP address '%s'", constant.ErrWrongInput, ip)
+	}
+
+	ipPoolList, err := im.ListIPPools(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pool, allocation := FindIPAllocation(ipPoolList.Items, ip)
+	return pool, allocation, nil
 }
 
 func (im *ipPoolManager) UpdateAllocatedIPs(ctx context.Context, poolName string, ipAndCIDs []types.IPAndCID) error {
@@ -236,14 +481,29 @@ func (im *ipPoolManager) UpdateAllocatedIPs(ctx context.Context, poolName string
 		}
 
 		recreate := false
+		var vacatingIPs map[string]struct{}
 		for _, cur := range ipAndCIDs {
 			if record, ok := ipPool.Status.AllocatedIPs[cur.IP]; ok {
 				if record.ContainerID == cur.ContainerID {
 					continue
 				}
 
+				// The IP addresses reserved with 'spec.vacateAllocatedIPs'
+				// are not handed over to the restarted Pods.
+				if vacatingIPs == nil {
+					vacatingIPs, err = im.getVacatingReservedIPs(ctx, ipPool)
+					if err != nil {
+						return err
+					}
+				}
+				if _, ok := vacatingIPs[cur.IP]; ok {
+					return im.newReservedIPDeniedError(ctx, ipPool, cur.IP)
+				}
+
 				record.ContainerID = cur.ContainerID
 				record.Node = cur.Node
+				record.PodUID = cur.PodUID
+				ipPool.Status.AllocatedIPs[cur.IP] = record
 				recreate = true
 			}
 		}
@@ -269,6 +529,98 @@ func (im *ipPoolManager) UpdateAllocatedIPs(ctx context.Context, poolName string
 	return nil
 }
 
+// getVacatingReservedIPs returns the IP addresses reserved from the IPPool
+// whose allocations are vacated when their Pods restart.
+func (im *ipPoolManager) getVacatingReservedIPs(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool) (map[string]struct{}, error) {
+	ips, err := im.rIPManager.AssembleVacatingReservedIPs(ctx, *ipPool.Spec.IPVersion, ipPool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assemble the reserved IP addresses being vacated: %w", err)
+	}
+
+	vacatingIPs := make(map[string]struct{}, len(ips))
+	for _, ip := range ips {
+		vacatingIPs[ip.String()] = struct{}{}
+	}
+
+	return vacatingIPs, nil
+}
+
+// newReservedIPDeniedError tells which SpiderReservedIP denies reusing the
+// IP address of the IPPool being vacated.
+func (im *ipPoolManager) newReservedIPDeniedError(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, ip string) error {
+	deniedErr := &reservedipmanager.ReservedIPDeniedError{
+		IP:   ip,
+		Pool: ipPool.Name,
+		Err:  constant.ErrIPVacating,
+	}
+
+	rIP, err := im.rIPManager.FindVacatingReservedIP(ctx, *ipPool.Spec.IPVersion, ip, ipPool)
+	if err != nil {
+		logutils.FromContext(ctx).Sugar().Warnf("Failed to find the SpiderReservedIP which reserves IP address %s: %v", ip, err)
+	}
+	if rIP != nil {
+		deniedErr.ReservedIP = rIP.Name
+		deniedErr.Description = rIP.Annotations[constant.AnnoReservedIPDescription]
+	}
+
+	return deniedErr
+}
+
+// TransferIPs hands the IP addresses allocated to the containers over to the
+// container of another Pod. Either all of them are transferred, or none of
+// them if any one has been released or re-allocated by others.
+func (im *ipPoolManager) TransferIPs(ctx context.Context, poolName string, ipAndCIDs []types.IPAndCID, containerID string, pod *corev1.Pod) error {
+	logger := logutils.FromContext(ctx)
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i <= im.config.MaxConflictRetries; i++ {
+		ipPool, err := im.GetIPPoolByName(ctx, poolName)
+		if err != nil {
+			return err
+		}
+
+		ips := make([]string, 0, len(ipAndCIDs))
+		for _, cur := range ipAndCIDs {
+			record, ok := ipPool.Status.AllocatedIPs[cur.IP]
+			if !ok || record.ContainerID != cur.ContainerID {
+				return fmt.Errorf("%w, IP address %s of IPPool %s is no longer allocated to container %s", constant.ErrWrongInput, cur.IP, poolName, cur.ContainerID)
+			}
+
+			record.ContainerID = containerID
+			record.Node = pod.Spec.NodeName
+			record.Namespace = pod.Namespace
+			record.Pod = pod.Name
+			record.PodUID = string(pod.UID)
+			if record.LeaseRenewTime != nil {
+				now := metav1.Now()
+				record.LeaseRenewTime = &now
+			}
+			ipPool.Status.AllocatedIPs[cur.IP] = record
+			ips = append(ips, cur.IP)
+		}
+
+		resourceVersion := ipPool.ResourceVersion
+		if err := im.client.Status().Update(ctx, ipPool); err != nil {
+			if !apierrors.IsConflict(err) {
+				return err
+			}
+			if i == im.config.MaxConflictRetries {
+				return fmt.Errorf("%w (%d times), failed to transfer the IP addresses %+v of IPPool %s", constant.ErrRetriesExhausted, im.config.MaxConflictRetries, ipAndCIDs, poolName)
+			}
+
+			interval := time.Duration(r.Intn(1<<(i+1))) * im.config.ConflictRetryUnitTime
+			logger.Sugar().Debugf("An conflict occurred when transferring the IP addresses of IPPool %s, it will be retried in %s", poolName, interval)
+
+			time.Sleep(interval)
+			continue
+		}
+		im.freeIPs.Update(ipPool.Name, resourceVersion, ipPool.ResourceVersion, ips, true)
+		break
+	}
+
+	return nil
+}
+
 func (im *ipPoolManager) CreateIPPool(ctx context.Context, pool *spiderpoolv1.SpiderIPPool) error {
 	err := im.client.Create(ctx, pool)
 	if nil != err {
@@ -308,3 +660,407 @@ func (im *ipPoolManager) UpdateDesiredIPNumber(ctx context.Context, pool *spider
 
 	return nil
 }
+
+// ReclaimAutoIPPool reclaims the auto-created IPPool whose application is
+// deleted with the reclaim policy, nil means the Immediate policy. It
+// returns whether the IPPool is deleted.
+func (im *ipPoolManager) ReclaimAutoIPPool(ctx context.Context, pool *spiderpoolv1.SpiderIPPool, policy *types.AutoPoolReclaimPolicy) (bool, error) {
+	logger := logutils.FromContext(ctx)
+
+	if policy == nil {
+		policy = &types.AutoPoolReclaimPolicy{Policy: constant.ReclaimPolicyImmediate}
+	}
+
+	switch policy.Policy {
+	case constant.ReclaimPolicyRetain:
+		// The IPPool without the reclaim label is never reclaimed, just like
+		// the one of the application with 'ipam.spidernet.io/ippool-reclaim: false'.
+		delete(pool.Labels, constant.LabelIPPoolReclaimIPPool)
+		delete(pool.Annotations, constant.AnnoIPPoolReclaimAfter)
+		if err := im.client.Update(ctx, pool); err != nil {
+			return false, fmt.Errorf("failed to retain IPPool '%s': %w", pool.Name, err)
+		}
+		logger.Sugar().Infof("Retain auto-created IPPool '%s' with reclaim policy %s", pool.Name, policy.Policy)
+
+		return false, nil
+
+	case constant.ReclaimPolicyDelayed:
+		reclaimAfter, ok := GetIPPoolReclaimAfter(pool)
+		if !ok {
+			reclaimAfter = time.Now().Add(policy.Delay)
+			if pool.Annotations == nil {
+				pool.Annotations = map[string]string{}
+			}
+			pool.Annotations[constant.AnnoIPPoolReclaimAfter] = reclaimAfter.UTC().Format(time.RFC3339)
+			if err := im.client.Update(ctx, pool); err != nil {
+				return false, fmt.Errorf("failed to delay the reclaim of IPPool '%s': %w", pool.Name, err)
+			}
+			logger.Sugar().Infof("Delay the reclaim of auto-created IPPool '%s' until %s", pool.Name, pool.Annotations[constant.AnnoIPPoolReclaimAfter])
+		}
+		if time.Now().Before(reclaimAfter) {
+			return false, nil
+		}
+	}
+
+	if err := im.client.Delete(ctx, pool); client.IgnoreNotFound(err) != nil {
+		return false, fmt.Errorf("failed to delete IPPool '%s': %w", pool.Name, err)
+	}
+	logger.Sugar().Infof("Reclaim auto-created IPPool '%s'", pool.Name)
+
+	return true, nil
+}
+
+// ReportRepeatedDatapathFailures records in 'status.datapathFailureNodes'
+// that the datapath failures of the IPPool on the node reach the threshold.
+// spiderpool-controller quarantines the IPPool once more than one node
+// reports them, so that the failures local to a single node never take the
+// IPPool out of the IPPool candidates of the whole cluster.
+func (im *ipPoolManager) ReportRepeatedDatapathFailures(ctx context.Context, poolName, nodeName, message string) error {
+	return im.reportDatapathFailure(ctx, poolName, nodeName, func(failure *spiderpoolv1.DatapathFailure, now time.Time) bool {
+		if failure.RepeatedFailureTime != nil && failure.Message == message && now.Sub(failure.RepeatedFailureTime.Time) < datapathFailureRefreshInterval {
+			return false
+		}
+		t := metav1.NewTime(now)
+		failure.RepeatedFailureTime = &t
+		failure.Message = message
+		return true
+	})
+}
+
+// SetIPPoolCondition sets the condition in the status of the IPPool, the
+// ObservedGeneration of the condition is filled with the generation of the
+// IPPool.
+func (im *ipPoolManager) SetIPPoolCondition(ctx context.Context, poolName string, condition metav1.Condition) error {
+	logger := logutils.FromContext(ctx)
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i <= im.config.MaxConflictRetries; i++ {
+		ipPool, err := im.GetIPPoolByName(ctx, poolName)
+		if err != nil {
+			return err
+		}
+
+		condition.ObservedGeneration = ipPool.Generation
+		existing := apimeta.FindStatusCondition(ipPool.Status.Conditions, condition.Type)
+		if existing != nil && existing.Status == condition.Status && existing.Reason == condition.Reason &&
+			existing.Message == condition.Message && existing.ObservedGeneration == condition.ObservedGeneration {
+			return nil
+		}
+		apimeta.SetStatusCondition(&ipPool.Status.Conditions, condition)
+
+		if err := im.client.Status().Update(ctx, ipPool); err != nil {
+			if !apierrors.IsConflict(err) {
+				return err
+			}
+			if i == im.config.MaxConflictRetries {
+				return fmt.Errorf("%w (%d times), failed to set condition %s of IPPool %s", constant.ErrRetriesExhausted, im.config.MaxConflictRetries, condition.Type, poolName)
+			}
+
+			interval := time.Duration(r.Intn(1<<(i+1))) * im.config.ConflictRetryUnitTime
+			logger.Sugar().Debugf("An conflict occurred when setting condition %s of IPPool %s, it will be retried in %s", condition.Type, poolName, interval)
+
+			time.Sleep(interval)
+			continue
+		}
+		break
+	}
+
+	return nil
+}
+
+// ExpandIPPool merges the IP ranges into 'spec.ips' of the IPPool in place,
+// and then updates the total IP count in the status of the IPPool, so that
+// a nearly-full IPPool can be grown without recreation while Pods keep
+// allocating IP addresses from it. For the IPPool controlled by a Subnet,
+// the IP ranges are taken from the Subnet first.
+func (im *ipPoolManager) ExpandIPPool(ctx context.Context, poolName string, ipRanges []string) (*spiderpoolv1.SpiderIPPool, error) {
+	logger := logutils.FromContext(ctx)
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var ipPool *spiderpoolv1.SpiderIPPool
+	for i := 0; i <= im.config.MaxConflictRetries; i++ {
+		var err error
+		ipPool, err = im.GetIPPoolByName(ctx, poolName)
+		if err != nil {
+			return nil, err
+		}
+
+		if ipPool.DeletionTimestamp != nil {
+			return nil, fmt.Errorf("%w, terminating IPPool %s can not be expanded", constant.ErrWrongInput, poolName)
+		}
+		if IsAutoCreatedIPPool(ipPool) {
+			return nil, fmt.Errorf("%w, auto-created IPPool %s is scaled by its SpiderSubnet", constant.ErrWrongInput, poolName)
+		}
+
+		version := *ipPool.Spec.IPVersion
+		for _, ipRange := range ipRanges {
+			if err := spiderpoolip.IsIPRange(version, ipRange); err != nil {
+				return nil, fmt.Errorf("%w, invalid IP range %s: %v", constant.ErrWrongInput, ipRange, err)
+			}
+			contains, err := spiderpoolip.ContainsIPRange(version, ipPool.Spec.Subnet, ipRange)
+			if err != nil {
+				return nil, err
+			}
+			if !contains {
+				return nil, fmt.Errorf("%w, IP range %s does not pertain to subnet %s of IPPool %s", constant.ErrWrongInput, ipRange, ipPool.Spec.Subnet, poolName)
+			}
+		}
+
+		oldIPs, err := spiderpoolip.ParseIPRanges(version, ipPool.Spec.IPs)
+		if err != nil {
+			return nil, err
+		}
+		newIPs, err := spiderpoolip.ParseIPRanges(version, ipRanges)
+		if err != nil {
+			return nil, err
+		}
+		addedIPs := spiderpoolip.IPsDiffSet(newIPs, oldIPs, false)
+		if len(addedIPs) == 0 {
+			logger.Sugar().Debugf("IP ranges %v are already in IPPool %s", ipRanges, poolName)
+			break
+		}
+
+		merged, err := spiderpoolip.MergeIPRanges(version, append(ipPool.Spec.IPs, ipRanges...))
+		if err != nil {
+			return nil, err
+		}
+
+		// the IP addresses are taken from the controller Subnet before being
+		// added to the IPPool, and given back if the IPPool fails to update
+		var subnetName string
+		if owner := metav1.GetControllerOf(ipPool); owner != nil && owner.Kind == constant.SpiderSubnetKind {
+			subnetName = owner.Name
+			if err := im.updateSubnetControlledIPs(ctx, subnetName, poolName, version, addedIPs, true); err != nil {
+				return nil, err
+			}
+		}
+
+		ipPool.Spec.IPs = merged
+		if err := im.client.Update(ctx, ipPool); err != nil {
+			if !apierrors.IsConflict(err) || i == im.config.MaxConflictRetries {
+				if subnetName != "" {
+					if err := im.updateSubnetControlledIPs(ctx, subnetName, poolName, version, addedIPs, false); err != nil {
+						logger.Sugar().Errorf("Failed to give back IP ranges %v of IPPool %s to Subnet %s: %v", ipRanges, poolName, subnetName, err)
+					}
+				}
+				if !apierrors.IsConflict(err) {
+					return nil, err
+				}
+				return nil, fmt.Errorf("%w (%d times), failed to expand IPPool %s", constant.ErrRetriesExhausted, im.config.MaxConflictRetries, poolName)
+			}
+
+			interval := time.Duration(r.Intn(1<<(i+1))) * im.config.ConflictRetryUnitTime
+			logger.Sugar().Debugf("An conflict occurred when expanding the IPPool %s, it will be retried in %s", poolName, interval)
+
+			time.Sleep(interval)
+			continue
+		}
+		logger.Sugar().Infof("Expand IPPool %s with IP ranges %v", poolName, ipRanges)
+		break
+	}
+
+	return im.updateTotalIPCount(ctx, ipPool)
+}
+
+// updateSubnetControlledIPs adds the IP addresses to, or removes them from,
+// the ones controlled by the IPPool in the status of the Subnet. The added
+// ones must be free in the Subnet or already controlled by the IPPool.
+func (im *ipPoolManager) updateSubnetControlledIPs(ctx context.Context, subnetName, poolName string, version types.IPVersion, ips []net.IP, add bool) error {
+	logger := logutils.FromContext(ctx)
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i <= im.config.MaxConflictRetries; i++ {
+		var subnet spiderpoolv1.SpiderSubnet
+		if err := im.client.Get(ctx, apitypes.NamespacedName{Name: subnetName}, &subnet); err != nil {
+			return err
+		}
+
+		var controlledIPs []net.IP
+		if allocation, ok := subnet.Status.ControlledIPPools[poolName]; ok {
+			var err error
+			controlledIPs, err = spiderpoolip.ParseIPRanges(version, allocation.IPs)
+			if err != nil {
+				return err
+			}
+		}
+
+		var ranges []string
+		if add {
+			if subnet.DeletionTimestamp != nil {
+				return fmt.Errorf("%w, terminating Subnet %s can not offer IP addresses to IPPool %s", constant.ErrWrongInput, subnetName, poolName)
+			}
+			freeIPs, err := subnetmanagercontrollers.GenSubnetFreeIPs(&subnet)
+			if err != nil {
+				return err
+			}
+			unavailableIPs := spiderpoolip.IPsDiffSet(spiderpoolip.IPsDiffSet(ips, controlledIPs, false), freeIPs, false)
+			if len(unavailableIPs) != 0 {
+				unavailable, _ := spiderpoolip.ConvertIPsToIPRanges(version, unavailableIPs)
+				return fmt.Errorf("%w, IP ranges %v are not free in Subnet %s", constant.ErrWrongInput, unavailable, subnetName)
+			}
+			if len(spiderpoolip.IPsDiffSet(ips, controlledIPs, false)) == 0 {
+				return nil
+			}
+			ranges, err = spiderpoolip.ConvertIPsToIPRanges(version, append(controlledIPs, ips...))
+			if err != nil {
+				return err
+			}
+		} else {
+			if len(spiderpoolip.IPsIntersectionSet(controlledIPs, ips, false)) == 0 {
+				return nil
+			}
+			var err error
+			ranges, err = spiderpoolip.ConvertIPsToIPRanges(version, spiderpoolip.IPsDiffSet(controlledIPs, ips, false))
+			if err != nil {
+				return err
+			}
+		}
+
+		if subnet.Status.ControlledIPPools == nil {
+			subnet.Status.ControlledIPPools = spiderpoolv1.PoolIPPreAllocations{}
+		}
+		subnet.Status.ControlledIPPools[poolName] = spiderpoolv1.PoolIPPreAllocation{IPs: ranges}
+		if err := im.client.Status().Update(ctx, &subnet); err != nil {
+			if !apierrors.IsConflict(err) {
+				return err
+			}
+			if i == im.config.MaxConflictRetries {
+				return fmt.Errorf("%w (%d times), failed to update the IP addresses of IPPool %s controlled by Subnet %s", constant.ErrRetriesExhausted, im.config.MaxConflictRetries, poolName, subnetName)
+			}
+
+			interval := time.Duration(r.Intn(1<<(i+1))) * im.config.ConflictRetryUnitTime
+			logger.Sugar().Debugf("An conflict occurred when updating the Subnet %s, it will be retried in %s", subnetName, interval)
+
+			time.Sleep(interval)
+			continue
+		}
+		break
+	}
+
+	return nil
+}
+
+// updateTotalIPCount recalculates the total IP count of the IPPool with its
+// latest spec and the SpiderReservedIPs.
+func (im *ipPoolManager) updateTotalIPCount(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool) (*spiderpoolv1.SpiderIPPool, error) {
+	logger := logutils.FromContext(ctx)
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i <= im.config.MaxConflictRetries; i++ {
+		if i != 0 {
+			var err error
+			ipPool, err = im.GetIPPoolByName(ctx, ipPool.Name)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		reservedIPs, err := im.rIPManager.AssembleScopedReservedIPs(ctx, *ipPool.Spec.IPVersion, ipPool, "")
+		if err != nil {
+			return nil, err
+		}
+		totalIPs, excludedIPs, err := assembleEffectiveIPs(ipPool, reservedIPs, im.config.ReserveSpecialIPs)
+		if err != nil {
+			return nil, err
+		}
+		if ipPool.Status.TotalIPCount != nil && *ipPool.Status.TotalIPCount == int64(len(totalIPs)) &&
+			reflect.DeepEqual(ipPool.Status.ExcludedIPs, excludedIPs) {
+			return ipPool, nil
+		}
+
+		ipPool.Status.TotalIPCount = pointer.Int64(int64(len(totalIPs)))
+		ipPool.Status.ExcludedIPs = excludedIPs
+		if err := im.client.Status().Update(ctx, ipPool); err != nil {
+			if !apierrors.IsConflict(err) {
+				return nil, err
+			}
+			if i == im.config.MaxConflictRetries {
+				return nil, fmt.Errorf("%w (%d times), failed to update the total IP count of IPPool %s", constant.ErrRetriesExhausted, im.config.MaxConflictRetries, ipPool.Name)
+			}
+
+			interval := time.Duration(r.Intn(1<<(i+1))) * im.config.ConflictRetryUnitTime
+			logger.Sugar().Debugf("An conflict occurred when updating the total IP count of IPPool %s, it will be retried in %s", ipPool.Name, interval)
+
+			time.Sleep(interval)
+			continue
+		}
+		break
+	}
+
+	return ipPool, nil
+}
+
+// datapathFailureRefreshInterval is the min interval to refresh the time of
+// the same datapath failure reported by the node again, so that the IPPool
+// is not updated on every probe.
+const datapathFailureRefreshInterval = time.Minute
+
+// ReportGatewayReachability records in 'status.datapathFailureNodes'
+// whether the gateway of the IPPool is reachable from the node, the
+// controller sets the condition GatewayUnreachable of the IPPool once
+// enough nodes report it unreachable.
+func (im *ipPoolManager) ReportGatewayReachability(ctx context.Context, poolName, nodeName string, reachable bool) error {
+	return im.reportDatapathFailure(ctx, poolName, nodeName, func(failure *spiderpoolv1.DatapathFailure, now time.Time) bool {
+		if reachable {
+			if failure.GatewayUnreachableTime == nil {
+				return false
+			}
+			failure.GatewayUnreachableTime = nil
+			return true
+		}
+
+		if failure.GatewayUnreachableTime != nil && now.Sub(failure.GatewayUnreachableTime.Time) < datapathFailureRefreshInterval {
+			return false
+		}
+		t := metav1.NewTime(now)
+		failure.GatewayUnreachableTime = &t
+		return true
+	})
+}
+
+// reportDatapathFailure updates the datapath failure reported by the node in
+// 'status.datapathFailureNodes' of the IPPool with the update function,
+// which returns whether the failure is changed. The failures cleared are
+// removed from the IPPool.
+func (im *ipPoolManager) reportDatapathFailure(ctx context.Context, poolName, nodeName string, update func(failure *spiderpoolv1.DatapathFailure, now time.Time) bool) error {
+	logger := logutils.FromContext(ctx)
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i <= im.config.MaxConflictRetries; i++ {
+		ipPool, err := im.GetIPPoolByName(ctx, poolName)
+		if err != nil {
+			return err
+		}
+
+		failure := ipPool.Status.DatapathFailureNodes[nodeName]
+		if !update(&failure, time.Now()) {
+			return nil
+		}
+		if failure.GatewayUnreachableTime == nil && failure.RepeatedFailureTime == nil {
+			delete(ipPool.Status.DatapathFailureNodes, nodeName)
+		} else {
+			if ipPool.Status.DatapathFailureNodes == nil {
+				ipPool.Status.DatapathFailureNodes = map[string]spiderpoolv1.DatapathFailure{}
+			}
+			ipPool.Status.DatapathFailureNodes[nodeName] = failure
+		}
+
+		if err := im.client.Status().Update(ctx, ipPool); err != nil {
+			if !apierrors.IsConflict(err) {
+				return err
+			}
+			if i == im.config.MaxConflictRetries {
+				return fmt.Errorf("%w (%d times), failed to report the datapath failure of IPPool %s", constant.ErrRetriesExhausted, im.config.MaxConflictRetries, poolName)
+			}
+
+			interval := time.Duration(r.Intn(1<<(i+1))) * im.config.ConflictRetryUnitTime
+			logger.Sugar().Debugf("An conflict occurred when reporting the datapath failure of IPPool %s, it will be retried in %s", poolName, interval)
+
+			time.Sleep(interval)
+			continue
+		}
+		break
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ package ippoolmanager_test
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -14,10 +15,12 @@ import (
 
 	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
 	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
 )
 
 var scheme *runtime.Scheme
 var fakeClient client.Client
+var ipPoolManager ippoolmanager.IPPoolManager
 var ipPoolWebhook *ippoolmanager.IPPoolWebhook
 
 func TestIPPoolManager(t *testing.T) {
@@ -34,6 +37,19 @@ var _ = BeforeSuite(func() {
 		WithScheme(scheme).
 		Build()
 
+	rIPManager, err := reservedipmanager.NewReservedIPManager(fakeClient)
+	Expect(err).NotTo(HaveOccurred())
+
+	ipPoolManager, err = ippoolmanager.NewIPPoolManager(
+		ippoolmanager.IPPoolManagerConfig{
+			MaxConflictRetries:    3,
+			ConflictRetryUnitTime: time.Millisecond,
+		},
+		fakeClient,
+		rIPManager,
+	)
+	Expect(err).NotTo(HaveOccurred())
+
 	ipPoolWebhook = &ippoolmanager.IPPoolWebhook{
 		Client:             fakeClient,
 		Scheme:             scheme,
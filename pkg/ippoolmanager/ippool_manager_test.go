@@ -0,0 +1,1650 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// rIPUpdateFailingClient fails to update the spec of any SpiderReservedIP.
+type rIPUpdateFailingClient struct {
+	client.Client
+}
+
+func (c *rIPUpdateFailingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if _, ok := obj.(*spiderpoolv1.SpiderReservedIP); ok {
+		return apierrors.NewServiceUnavailable("mock failure")
+	}
+
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+// ipPoolUpdateFailingClient fails to update the spec of any SpiderIPPool.
+type ipPoolUpdateFailingClient struct {
+	client.Client
+}
+
+func (c *ipPoolUpdateFailingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if _, ok := obj.(*spiderpoolv1.SpiderIPPool); ok {
+		return apierrors.NewServiceUnavailable("mock failure")
+	}
+
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+var _ = Describe("IPPoolManager", Label("ippool_manager_test"), func() {
+	Describe("New IPPoolManager", func() {
+		It("inputs nil client", func() {
+			manager, err := ippoolmanager.NewIPPoolManager(ippoolmanager.IPPoolManagerConfig{}, nil, nil)
+			Expect(err).To(MatchError(constant.ErrMissingRequiredParam))
+			Expect(manager).To(BeNil())
+		})
+	})
+
+	Describe("ExpandIPPool", func() {
+		var count uint64
+		var ipPoolT *spiderpoolv1.SpiderIPPool
+
+		BeforeEach(func() {
+			atomic.AddUint64(&count, 1)
+			ipPoolT = &spiderpoolv1.SpiderIPPool{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       constant.SpiderIPPoolKind,
+					APIVersion: fmt.Sprintf("%s/%s", constant.SpiderpoolAPIGroup, constant.SpiderpoolAPIVersionV1),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: fmt.Sprintf("expanded-ippool-%v", count),
+				},
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion: pointer.Int64(constant.IPv4),
+					Subnet:    "172.18.40.0/24",
+					IPs:       []string{"172.18.40.1-172.18.40.10"},
+				},
+			}
+		})
+
+		AfterEach(func() {
+			err := fakeClient.Delete(context.TODO(), ipPoolT, client.GracePeriodSeconds(0))
+			Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+		})
+
+		It("expands a nonexistent IPPool", func() {
+			ipPool, err := ipPoolManager.ExpandIPPool(context.TODO(), ipPoolT.Name, []string{"172.18.40.11"})
+			Expect(err).To(HaveOccurred())
+			Expect(ipPool).To(BeNil())
+		})
+
+		It("expands with IP ranges out of the subnet", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err := ipPoolManager.ExpandIPPool(ctx, ipPoolT.Name, []string{"172.18.41.1-172.18.41.10"})
+			Expect(err).To(MatchError(constant.ErrWrongInput))
+			Expect(ipPool).To(BeNil())
+		})
+
+		It("expands with invalid IP ranges", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err := ipPoolManager.ExpandIPPool(ctx, ipPoolT.Name, []string{constant.InvalidIPRange})
+			Expect(err).To(MatchError(constant.ErrWrongInput))
+			Expect(ipPool).To(BeNil())
+		})
+
+		It("expands an auto-created IPPool", func() {
+			ipPoolT.Labels = map[string]string{constant.LabelIPPoolOwnerApplication: "deployment_default_test"}
+
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err := ipPoolManager.ExpandIPPool(ctx, ipPoolT.Name, []string{"172.18.40.11"})
+			Expect(err).To(MatchError(constant.ErrWrongInput))
+			Expect(ipPool).To(BeNil())
+		})
+
+		It("merges the IP ranges and updates the total IP count", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err := ipPoolManager.ExpandIPPool(ctx, ipPoolT.Name, []string{"172.18.40.11-172.18.40.20", "172.18.40.40"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Spec.IPs).To(Equal([]string{"172.18.40.1-172.18.40.20", "172.18.40.40"}))
+			Expect(ipPool.Status.TotalIPCount).To(Equal(pointer.Int64(21)))
+		})
+
+		It("expands with IP ranges already in the IPPool", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err := ipPoolManager.ExpandIPPool(ctx, ipPoolT.Name, []string{"172.18.40.2-172.18.40.5"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Spec.IPs).To(Equal([]string{"172.18.40.1-172.18.40.10"}))
+			Expect(ipPool.Status.TotalIPCount).To(Equal(pointer.Int64(10)))
+		})
+
+		It("excludes the reserved IP addresses from the total IP count", func() {
+			rIPT := &spiderpoolv1.SpiderReservedIP{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: fmt.Sprintf("expanded-reservedip-%v", count),
+				},
+				Spec: spiderpoolv1.ReservedIPSpec{
+					IPVersion: pointer.Int64(constant.IPv4),
+					IPs:       []string{"172.18.40.12-172.18.40.13"},
+				},
+			}
+			ipPoolT.Spec.ExcludeIPs = []string{"172.18.40.10"}
+
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+			err = fakeClient.Create(ctx, rIPT)
+			Expect(err).NotTo(HaveOccurred())
+			defer func() {
+				err := fakeClient.Delete(ctx, rIPT, client.GracePeriodSeconds(0))
+				Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+			}()
+
+			ipPool, err := ipPoolManager.ExpandIPPool(ctx, ipPoolT.Name, []string{"172.18.40.11-172.18.40.20"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.TotalIPCount).To(Equal(pointer.Int64(17)))
+			Expect(ipPool.Status.ExcludedIPs).To(Equal([]string{"172.18.40.10", "172.18.40.12-172.18.40.13"}))
+		})
+
+		It("excludes the special IP addresses skipped when allocating from the total IP count", func() {
+			ipPoolT.Spec.ReserveSpecialIPs = pointer.Bool(true)
+			ipPoolT.Spec.Gateway = pointer.String("172.18.40.1")
+
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err := ipPoolManager.ExpandIPPool(ctx, ipPoolT.Name, []string{"172.18.40.0"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.TotalIPCount).To(Equal(pointer.Int64(9)))
+			Expect(ipPool.Status.ExcludedIPs).To(Equal([]string{"172.18.40.0-172.18.40.1"}))
+		})
+
+		Context("with the controller Subnet", func() {
+			var subnetT *spiderpoolv1.SpiderSubnet
+
+			BeforeEach(func() {
+				subnetT = &spiderpoolv1.SpiderSubnet{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: fmt.Sprintf("expanded-subnet-%v", count),
+						UID:  apitypes.UID(fmt.Sprintf("expanded-subnet-uid-%v", count)),
+					},
+					Spec: spiderpoolv1.SubnetSpec{
+						IPVersion: pointer.Int64(constant.IPv4),
+						Subnet:    "172.18.40.0/24",
+						IPs:       []string{"172.18.40.1-172.18.40.100"},
+					},
+					Status: spiderpoolv1.SubnetStatus{
+						ControlledIPPools: spiderpoolv1.PoolIPPreAllocations{
+							ipPoolT.Name: {IPs: []string{"172.18.40.1-172.18.40.10"}},
+							"other":      {IPs: []string{"172.18.40.50-172.18.40.60"}},
+						},
+					},
+				}
+				ipPoolT.OwnerReferences = []metav1.OwnerReference{{
+					APIVersion: fmt.Sprintf("%s/%s", constant.SpiderpoolAPIGroup, constant.SpiderpoolAPIVersionV1),
+					Kind:       constant.SpiderSubnetKind,
+					Name:       subnetT.Name,
+					UID:        subnetT.UID,
+					Controller: pointer.Bool(true),
+				}}
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, subnetT)
+				Expect(err).NotTo(HaveOccurred())
+				err = fakeClient.Create(ctx, ipPoolT)
+				Expect(err).NotTo(HaveOccurred())
+				DeferCleanup(func() {
+					err := fakeClient.Delete(context.TODO(), subnetT, client.GracePeriodSeconds(0))
+					Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+				})
+			})
+
+			controlledIPs := func() []string {
+				var subnet spiderpoolv1.SpiderSubnet
+				err := fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(subnetT), &subnet)
+				Expect(err).NotTo(HaveOccurred())
+				return subnet.Status.ControlledIPPools[ipPoolT.Name].IPs
+			}
+
+			It("takes the IP ranges from the Subnet", func() {
+				ipPool, err := ipPoolManager.ExpandIPPool(context.TODO(), ipPoolT.Name, []string{"172.18.40.11-172.18.40.20"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ipPool.Spec.IPs).To(Equal([]string{"172.18.40.1-172.18.40.20"}))
+				Expect(controlledIPs()).To(Equal([]string{"172.18.40.1-172.18.40.20"}))
+			})
+
+			It("refuses the IP ranges controlled by other IPPools", func() {
+				ipPool, err := ipPoolManager.ExpandIPPool(context.TODO(), ipPoolT.Name, []string{"172.18.40.11-172.18.40.50"})
+				Expect(err).To(MatchError(constant.ErrWrongInput))
+				Expect(ipPool).To(BeNil())
+				Expect(controlledIPs()).To(Equal([]string{"172.18.40.1-172.18.40.10"}))
+
+				ipPool, err = ipPoolManager.GetIPPoolByName(context.TODO(), ipPoolT.Name)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ipPool.Spec.IPs).To(Equal([]string{"172.18.40.1-172.18.40.10"}))
+			})
+
+			It("gives the IP ranges back to the Subnet if the IPPool fails to update", func() {
+				rIPManager, err := reservedipmanager.NewReservedIPManager(fakeClient)
+				Expect(err).NotTo(HaveOccurred())
+				manager, err := ippoolmanager.NewIPPoolManager(ippoolmanager.IPPoolManagerConfig{}, &ipPoolUpdateFailingClient{fakeClient}, rIPManager)
+				Expect(err).NotTo(HaveOccurred())
+
+				ipPool, err := manager.ExpandIPPool(context.TODO(), ipPoolT.Name, []string{"172.18.40.11-172.18.40.20"})
+				Expect(apierrors.IsServiceUnavailable(err)).To(BeTrue())
+				Expect(ipPool).To(BeNil())
+				Expect(controlledIPs()).To(Equal([]string{"172.18.40.1-172.18.40.10"}))
+			})
+		})
+	})
+
+	Describe("SplitIPPool and MergeIPPools", func() {
+		var count uint64
+		var ipPoolT *spiderpoolv1.SpiderIPPool
+		var endpointT *spiderpoolv1.SpiderEndpoint
+		var newPoolName string
+
+		BeforeEach(func() {
+			atomic.AddUint64(&count, 1)
+			newPoolName = fmt.Sprintf("split-ippool-%v", count)
+			ipPoolT = &spiderpoolv1.SpiderIPPool{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       constant.SpiderIPPoolKind,
+					APIVersion: fmt.Sprintf("%s/%s", constant.SpiderpoolAPIGroup, constant.SpiderpoolAPIVersionV1),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: fmt.Sprintf("reshaped-ippool-%v", count),
+				},
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion:  pointer.Int64(constant.IPv4),
+					Subnet:     "172.18.40.0/24",
+					IPs:        []string{"172.18.40.1-172.18.40.10"},
+					ExcludeIPs: []string{"172.18.40.8"},
+				},
+				Status: spiderpoolv1.IPPoolStatus{
+					AllocatedIPs: spiderpoolv1.PoolIPAllocations{
+						"172.18.40.2": {ContainerID: "container-a", Namespace: "default", Pod: "pod-a"},
+						"172.18.40.7": {ContainerID: "container-b", Namespace: "default", Pod: fmt.Sprintf("pod-b-%v", count)},
+					},
+					AllocatedIPCount: pointer.Int64(2),
+				},
+			}
+			endpointT = &spiderpoolv1.SpiderEndpoint{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       constant.SpiderEndpointKind,
+					APIVersion: fmt.Sprintf("%s/%s", constant.SpiderpoolAPIGroup, constant.SpiderpoolAPIVersionV1),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      fmt.Sprintf("pod-b-%v", count),
+				},
+				Status: spiderpoolv1.WorkloadEndpointStatus{
+					Current: &spiderpoolv1.PodIPAllocation{
+						ContainerID: "container-b",
+						IPs: []spiderpoolv1.IPAllocationDetail{{
+							NIC:      "eth0",
+							IPv4:     pointer.String("172.18.40.7/24"),
+							IPv4Pool: pointer.String(ipPoolT.Name),
+						}},
+					},
+				},
+			}
+		})
+
+		AfterEach(func() {
+			ctx := context.TODO()
+			for _, name := range []string{ipPoolT.Name, newPoolName} {
+				pool := &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: name}}
+				err := fakeClient.Delete(ctx, pool, client.GracePeriodSeconds(0))
+				Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+			}
+			err := fakeClient.Delete(ctx, endpointT, client.GracePeriodSeconds(0))
+			Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+		})
+
+		It("splits with IP ranges out of the IPPool", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ipPoolManager.SplitIPPool(ctx, ipPoolT.Name, map[string][]string{newPoolName: {"172.18.40.11-172.18.40.20"}})
+			Expect(err).To(MatchError(constant.ErrWrongInput))
+		})
+
+		It("splits all IP addresses out of the IPPool", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ipPoolManager.SplitIPPool(ctx, ipPoolT.Name, map[string][]string{newPoolName: ipPoolT.Spec.IPs})
+			Expect(err).To(MatchError(constant.ErrWrongInput))
+		})
+
+		It("splits an auto-created IPPool", func() {
+			ipPoolT.Labels = map[string]string{constant.LabelIPPoolOwnerApplication: "deployment_default_test"}
+
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ipPoolManager.SplitIPPool(ctx, ipPoolT.Name, map[string][]string{newPoolName: {"172.18.40.6-172.18.40.10"}})
+			Expect(err).To(MatchError(constant.ErrWrongInput))
+		})
+
+		It("splits the IPPool and merges it back", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+			err = fakeClient.Create(ctx, endpointT)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ipPoolManager.SplitIPPool(ctx, ipPoolT.Name, map[string][]string{newPoolName: {"172.18.40.6-172.18.40.10"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Spec.IPs).To(Equal([]string{"172.18.40.1-172.18.40.5"}))
+			Expect(ipPool.Spec.ExcludeIPs).To(BeEmpty())
+			Expect(ipPool.Status.AllocatedIPs).To(HaveLen(1))
+			Expect(ipPool.Status.AllocatedIPs).To(HaveKey("172.18.40.2"))
+			Expect(ippoolmanager.IsReshapingIPPool(ipPool)).To(BeFalse())
+
+			newPool, err := ipPoolManager.GetIPPoolByName(ctx, newPoolName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newPool.Spec.Subnet).To(Equal(ipPoolT.Spec.Subnet))
+			Expect(newPool.Spec.IPs).To(Equal([]string{"172.18.40.6-172.18.40.10"}))
+			Expect(newPool.Spec.ExcludeIPs).To(Equal([]string{"172.18.40.8"}))
+			Expect(newPool.Status.AllocatedIPs).To(HaveLen(1))
+			Expect(newPool.Status.AllocatedIPs).To(HaveKey("172.18.40.7"))
+			Expect(newPool.Status.AllocatedIPCount).To(Equal(pointer.Int64(1)))
+
+			var endpoint spiderpoolv1.SpiderEndpoint
+			err = fakeClient.Get(ctx, client.ObjectKeyFromObject(endpointT), &endpoint)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(endpoint.Status.Current.IPs[0].IPv4Pool).To(Equal(pointer.String(newPoolName)))
+
+			err = ipPoolManager.MergeIPPools(ctx, ipPoolT.Name, []string{newPoolName})
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err = ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Spec.IPs).To(Equal([]string{"172.18.40.1-172.18.40.10"}))
+			Expect(ipPool.Spec.ExcludeIPs).To(Equal([]string{"172.18.40.8"}))
+			Expect(ipPool.Status.AllocatedIPs).To(HaveLen(2))
+			Expect(ipPool.Status.AllocatedIPCount).To(Equal(pointer.Int64(2)))
+
+			_, err = ipPoolManager.GetIPPoolByName(ctx, newPoolName)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+			err = fakeClient.Get(ctx, client.ObjectKeyFromObject(endpointT), &endpoint)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(endpoint.Status.Current.IPs[0].IPv4Pool).To(Equal(pointer.String(ipPoolT.Name)))
+		})
+
+		It("resumes the half-done transfer of the split", func() {
+			// The IP addresses have left the IPPool, but they are still
+			// held in the new IPPool.
+			ipPoolT.Spec.IPs = []string{"172.18.40.1-172.18.40.5"}
+			ipPoolT.Spec.ExcludeIPs = nil
+			delete(ipPoolT.Status.AllocatedIPs, "172.18.40.7")
+			ipPoolT.Status.AllocatedIPCount = pointer.Int64(1)
+
+			newPool := &spiderpoolv1.SpiderIPPool{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: newPoolName,
+					Annotations: map[string]string{
+						constant.AnnoIPPoolTransfer: fmt.Sprintf(`{"src":"%s","ips":["172.18.40.6-172.18.40.10"],"excludeIPs":["172.18.40.8"],"recordsMoved":true}`, ipPoolT.Name),
+					},
+				},
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion:  pointer.Int64(constant.IPv4),
+					Subnet:     ipPoolT.Spec.Subnet,
+					IPs:        []string{"172.18.40.6-172.18.40.10"},
+					ExcludeIPs: []string{"172.18.40.6-172.18.40.10"},
+				},
+				Status: spiderpoolv1.IPPoolStatus{
+					AllocatedIPs: spiderpoolv1.PoolIPAllocations{
+						"172.18.40.7": {ContainerID: "container-b", Namespace: "default", Pod: endpointT.Name},
+					},
+					AllocatedIPCount: pointer.Int64(1),
+				},
+			}
+
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+			err = fakeClient.Create(ctx, newPool)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ipPoolManager.SplitIPPool(ctx, ipPoolT.Name, map[string][]string{newPoolName: {"172.18.40.6-172.18.40.10"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			newPool, err = ipPoolManager.GetIPPoolByName(ctx, newPoolName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newPool.Spec.IPs).To(Equal([]string{"172.18.40.6-172.18.40.10"}))
+			Expect(newPool.Spec.ExcludeIPs).To(Equal([]string{"172.18.40.8"}))
+			Expect(newPool.Annotations).NotTo(HaveKey(constant.AnnoIPPoolTransfer))
+			Expect(newPool.Status.AllocatedIPs).To(HaveKey("172.18.40.7"))
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ippoolmanager.IsReshapingIPPool(ipPool)).To(BeFalse())
+		})
+
+		It("drops the allocation records released before the Endpoints are repointed", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			// The record was copied by a previous attempt, and then
+			// released from the IPPool.
+			newPool := &spiderpoolv1.SpiderIPPool{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: newPoolName,
+					Annotations: map[string]string{
+						constant.AnnoIPPoolTransfer: fmt.Sprintf(`{"src":"%s","ips":["172.18.40.6-172.18.40.10"],"excludeIPs":["172.18.40.8"]}`, ipPoolT.Name),
+					},
+				},
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion:  pointer.Int64(constant.IPv4),
+					Subnet:     ipPoolT.Spec.Subnet,
+					IPs:        []string{"172.18.40.6-172.18.40.10"},
+					ExcludeIPs: []string{"172.18.40.6-172.18.40.10"},
+				},
+				Status: spiderpoolv1.IPPoolStatus{
+					AllocatedIPs: spiderpoolv1.PoolIPAllocations{
+						"172.18.40.9": {ContainerID: "container-c", Namespace: "default", Pod: "pod-c"},
+					},
+					AllocatedIPCount: pointer.Int64(1),
+				},
+			}
+			err = fakeClient.Create(ctx, newPool)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ipPoolManager.SplitIPPool(ctx, ipPoolT.Name, map[string][]string{newPoolName: {"172.18.40.6-172.18.40.10"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			newPool, err = ipPoolManager.GetIPPoolByName(ctx, newPoolName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newPool.Status.AllocatedIPs).To(HaveLen(1))
+			Expect(newPool.Status.AllocatedIPs).To(HaveKey("172.18.40.7"))
+			Expect(newPool.Status.AllocatedIPCount).To(Equal(pointer.Int64(1)))
+		})
+
+		It("unfences the sibling IPPool if the merge fails", func() {
+			ipPoolT.Annotations = map[string]string{
+				constant.AnnoIPPoolTransfer: `{"src":"another-ippool","ips":["172.18.40.1"]}`,
+			}
+
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+			sibling := &spiderpoolv1.SpiderIPPool{
+				ObjectMeta: metav1.ObjectMeta{Name: newPoolName},
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion: pointer.Int64(constant.IPv4),
+					Subnet:    ipPoolT.Spec.Subnet,
+					IPs:       []string{"172.18.40.11-172.18.40.20"},
+				},
+			}
+			err = fakeClient.Create(ctx, sibling)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ipPoolManager.MergeIPPools(ctx, ipPoolT.Name, []string{newPoolName})
+			Expect(err).To(MatchError(constant.ErrWrongInput))
+
+			sibling, err = ipPoolManager.GetIPPoolByName(ctx, newPoolName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ippoolmanager.IsReshapingIPPool(sibling)).To(BeFalse())
+			Expect(sibling.Spec.IPs).To(Equal([]string{"172.18.40.11-172.18.40.20"}))
+		})
+
+		It("merges an IPPool in another subnet", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			other := &spiderpoolv1.SpiderIPPool{
+				ObjectMeta: metav1.ObjectMeta{Name: newPoolName},
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion: pointer.Int64(constant.IPv4),
+					Subnet:    "172.18.41.0/24",
+					IPs:       []string{"172.18.41.1-172.18.41.10"},
+				},
+			}
+			err = fakeClient.Create(ctx, other)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ipPoolManager.MergeIPPools(ctx, ipPoolT.Name, []string{newPoolName})
+			Expect(err).To(MatchError(constant.ErrWrongInput))
+		})
+	})
+
+	Describe("AllocateIP and ReleaseIP", func() {
+		var count uint64
+		var ipPoolT *spiderpoolv1.SpiderIPPool
+		var rIPT *spiderpoolv1.SpiderReservedIP
+		var podT *corev1.Pod
+		var podController types.PodTopController
+
+		BeforeEach(func() {
+			atomic.AddUint64(&count, 1)
+			ipPoolT = &spiderpoolv1.SpiderIPPool{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       constant.SpiderIPPoolKind,
+					APIVersion: fmt.Sprintf("%s/%s", constant.SpiderpoolAPIGroup, constant.SpiderpoolAPIVersionV1),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: fmt.Sprintf("allocated-ippool-%v", count),
+				},
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion:  pointer.Int64(constant.IPv4),
+					Subnet:     "172.18.40.0/24",
+					IPs:        []string{"172.18.40.1-172.18.40.5", "172.18.40.100"},
+					ExcludeIPs: []string{"172.18.40.2"},
+					Vlan:       pointer.Int64(0),
+				},
+			}
+			rIPT = &spiderpoolv1.SpiderReservedIP{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: fmt.Sprintf("reservedip-%v", count),
+				},
+				Spec: spiderpoolv1.ReservedIPSpec{
+					IPVersion: pointer.Int64(constant.IPv4),
+					IPs:       []string{"172.18.40.4"},
+				},
+			}
+			podT = &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "pod",
+					UID:       apitypes.UID("pod-uid"),
+				},
+				Spec: corev1.PodSpec{NodeName: "node"},
+			}
+			podController = types.PodTopController{
+				Kind:      constant.KindPod,
+				Namespace: podT.Namespace,
+				Name:      podT.Name,
+			}
+		})
+
+		AfterEach(func() {
+			ctx := context.TODO()
+			err := fakeClient.Delete(ctx, ipPoolT, client.GracePeriodSeconds(0))
+			Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+			err = fakeClient.Delete(ctx, rIPT, client.GracePeriodSeconds(0))
+			Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+		})
+
+		It("allocates all free IP addresses and reuses the released one", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+			err = fakeClient.Create(ctx, rIPT)
+			Expect(err).NotTo(HaveOccurred())
+
+			var allocated []string
+			for i := 0; i < 4; i++ {
+				ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, fmt.Sprintf("container-%d", i), "eth0", podT, podController)
+				Expect(err).NotTo(HaveOccurred())
+				allocated = append(allocated, *ipConfig.Address)
+			}
+			Expect(allocated).To(ConsistOf("172.18.40.1/24", "172.18.40.3/24", "172.18.40.5/24", "172.18.40.100/24"))
+
+			_, err = ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container-4", "eth0", podT, podController)
+			Expect(err).To(MatchError(constant.ErrIPUsedOut))
+
+			err = ipPoolManager.ReleaseIP(ctx, ipPoolT.Name, []types.IPAndCID{{IP: "172.18.40.3", ContainerID: "container-1"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container-4", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*ipConfig.Address).To(Equal("172.18.40.3/24"))
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.AllocatedIPs).To(HaveLen(4))
+			Expect(ipPool.Status.AllocatedIPs["172.18.40.3"].ContainerID).To(Equal("container-4"))
+		})
+
+		It("skips the special IP addresses of the IPPool if reserved", func() {
+			ctx := context.TODO()
+			ipPoolT.Spec.Subnet = "172.18.40.0/30"
+			ipPoolT.Spec.IPs = []string{"172.18.40.0-172.18.40.3"}
+			ipPoolT.Spec.ExcludeIPs = nil
+			ipPoolT.Spec.Gateway = pointer.String("172.18.40.1")
+			ipPoolT.Spec.ReserveSpecialIPs = pointer.Bool(true)
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container-0", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*ipConfig.Address).To(Equal("172.18.40.2/30"))
+
+			_, err = ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container-1", "eth0", podT, podController)
+			Expect(err).To(MatchError(constant.ErrIPUsedOut))
+		})
+
+		It("allocates both IP addresses of the /31 subnet except the gateway", func() {
+			ctx := context.TODO()
+			ipPoolT.Spec.Subnet = "172.18.40.0/31"
+			ipPoolT.Spec.IPs = []string{"172.18.40.0-172.18.40.1"}
+			ipPoolT.Spec.ExcludeIPs = nil
+			ipPoolT.Spec.Gateway = pointer.String("172.18.40.0")
+			ipPoolT.Spec.ReserveSpecialIPs = pointer.Bool(true)
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container-0", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*ipConfig.Address).To(Equal("172.18.40.1/31"))
+
+			_, err = ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container-1", "eth0", podT, podController)
+			Expect(err).To(MatchError(constant.ErrIPUsedOut))
+		})
+
+		It("allocates both IP addresses of the /127 subnet without gateway", func() {
+			ctx := context.TODO()
+			ipPoolT.Spec.IPVersion = pointer.Int64(constant.IPv6)
+			ipPoolT.Spec.Subnet = "abcd:1234::/127"
+			ipPoolT.Spec.IPs = []string{"abcd:1234::-abcd:1234::1"}
+			ipPoolT.Spec.ExcludeIPs = nil
+			ipPoolT.Spec.ReserveSpecialIPs = pointer.Bool(true)
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			var allocated []string
+			for i := 0; i < 2; i++ {
+				ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, fmt.Sprintf("container-%d", i), "eth0", podT, podController)
+				Expect(err).NotTo(HaveOccurred())
+				allocated = append(allocated, *ipConfig.Address)
+			}
+			Expect(allocated).To(ConsistOf("abcd:1234::/127", "abcd:1234::1/127"))
+		})
+
+		It("allocates the IP address claimed for the Pod and consumes the claim", func() {
+			ctx := context.TODO()
+			rIPT.Spec.Claim = &spiderpoolv1.ReservedIPClaim{
+				Namespace: podT.Namespace,
+				PodName:   podT.Name,
+			}
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+			err = fakeClient.Create(ctx, rIPT)
+			Expect(err).NotTo(HaveOccurred())
+
+			otherPodT := podT.DeepCopy()
+			otherPodT.Name = "other-pod"
+			for i := 0; i < 3; i++ {
+				ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, fmt.Sprintf("container-%d", i), "eth0", otherPodT, podController)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(*ipConfig.Address).NotTo(Equal("172.18.40.4/24"))
+			}
+
+			ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container-3", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*ipConfig.Address).To(Equal("172.18.40.4/24"))
+
+			err = fakeClient.Get(ctx, client.ObjectKeyFromObject(rIPT), rIPT)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("keeps the claimed IP address bound to the Pod if the SpiderReservedIP update fails", func() {
+			ctx := context.TODO()
+			podT.Labels = map[string]string{"app": "db"}
+			rIPT.Spec.IPs = []string{"172.18.40.4-172.18.40.5"}
+			rIPT.Spec.Claim = &spiderpoolv1.ReservedIPClaim{
+				Namespace:   podT.Namespace,
+				PodSelector: &metav1.LabelSelector{MatchLabels: podT.Labels},
+			}
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+			err = fakeClient.Create(ctx, rIPT)
+			Expect(err).NotTo(HaveOccurred())
+
+			failingClient := &rIPUpdateFailingClient{Client: fakeClient}
+			failingRIPManager, err := reservedipmanager.NewReservedIPManager(failingClient)
+			Expect(err).NotTo(HaveOccurred())
+			manager, err := ippoolmanager.NewIPPoolManager(
+				ippoolmanager.IPPoolManagerConfig{
+					MaxConflictRetries:    3,
+					ConflictRetryUnitTime: time.Millisecond,
+				},
+				failingClient,
+				failingRIPManager,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipConfig, err := manager.AllocateIP(ctx, ipPoolT.Name, "container-0", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*ipConfig.Address).To(Equal("172.18.40.4/24"))
+
+			var rIP spiderpoolv1.SpiderReservedIP
+			err = fakeClient.Get(ctx, client.ObjectKeyFromObject(rIPT), &rIP)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rIP.Spec.IPs).To(Equal([]string{"172.18.40.4-172.18.40.5"}))
+			Expect(rIP.Status.BoundClaims).To(HaveKeyWithValue("172.18.40.4", spiderpoolv1.ReservedIPBoundClaim{
+				Pod:    podT.Name,
+				PodUID: string(podT.UID),
+			}))
+
+			// The bound IP address is not handed out to another claimant.
+			otherPodT := podT.DeepCopy()
+			otherPodT.Name = "other-pod"
+			otherPodT.UID = apitypes.UID("other-pod-uid")
+			ipConfig, err = manager.AllocateIP(ctx, ipPoolT.Name, "container-1", "eth0", otherPodT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*ipConfig.Address).To(Equal("172.18.40.5/24"))
+
+			rIPManager, err := reservedipmanager.NewReservedIPManager(fakeClient)
+			Expect(err).NotTo(HaveOccurred())
+			consumed, err := rIPManager.ConsumeBoundClaims(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(consumed).To(Equal(2))
+
+			err = fakeClient.Get(ctx, client.ObjectKeyFromObject(rIPT), &rIP)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("releases the IP addresses of multiple IPPools with per-IP results", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container-0", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			ip := strings.Split(*ipConfig.Address, "/")[0]
+
+			results, err := ipPoolManager.ReleaseIPs(ctx, map[string][]types.IPAndCID{
+				ipPoolT.Name: {
+					{IP: ip, ContainerID: "container-0"},
+					{IP: "172.18.40.100", ContainerID: "container-1"},
+				},
+				"non-existent-ippool": {{IP: "172.18.40.1", ContainerID: "container-0"}},
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(results).To(HaveLen(3))
+
+			Expect(results[0].IPPool).To(Equal(ipPoolT.Name))
+			Expect(results[0].Released).To(BeTrue())
+			Expect(results[0].Err).NotTo(HaveOccurred())
+			Expect(results[1].Released).To(BeFalse())
+			Expect(results[1].Err).NotTo(HaveOccurred())
+			Expect(results[2].IPPool).To(Equal("non-existent-ippool"))
+			Expect(results[2].Released).To(BeFalse())
+			Expect(apierrors.IsNotFound(results[2].Err)).To(BeTrue())
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.AllocatedIPs).To(BeEmpty())
+		})
+
+		It("transfers the IP addresses to the replacement Pod", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container-0", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			ip := strings.Split(*ipConfig.Address, "/")[0]
+
+			newPodT := podT.DeepCopy()
+			newPodT.Name = "new-pod"
+			newPodT.UID = apitypes.UID("new-pod-uid")
+
+			err = ipPoolManager.TransferIPs(ctx, ipPoolT.Name, []types.IPAndCID{{IP: ip, ContainerID: "container-1"}}, "container-2", newPodT)
+			Expect(err).To(MatchError(constant.ErrWrongInput))
+
+			err = ipPoolManager.TransferIPs(ctx, ipPoolT.Name, []types.IPAndCID{{IP: ip, ContainerID: "container-0"}}, "container-2", newPodT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.AllocatedIPs).To(HaveLen(1))
+			Expect(ipPool.Status.AllocatedIPs[ip].ContainerID).To(Equal("container-2"))
+			Expect(ipPool.Status.AllocatedIPs[ip].Pod).To(Equal(newPodT.Name))
+			Expect(ipPool.Status.AllocatedIPs[ip].PodUID).To(Equal(string(newPodT.UID)))
+		})
+
+		It("does not allocate the IP addresses delegated to the child IPPools", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			childT := &spiderpoolv1.SpiderIPPool{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   fmt.Sprintf("child-ippool-%v", count),
+					Labels: map[string]string{constant.LabelIPPoolParent: ipPoolT.Name},
+				},
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion:  pointer.Int64(constant.IPv4),
+					Subnet:     ipPoolT.Spec.Subnet,
+					IPs:        []string{"172.18.40.1-172.18.40.5"},
+					ParentPool: pointer.String(ipPoolT.Name),
+				},
+			}
+			err = fakeClient.Create(ctx, childT)
+			Expect(err).NotTo(HaveOccurred())
+			defer func() {
+				err := fakeClient.Delete(ctx, childT, client.GracePeriodSeconds(0))
+				Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+			}()
+
+			ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container-0", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*ipConfig.Address).To(Equal("172.18.40.100/24"))
+
+			_, err = ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container-1", "eth0", podT, podController)
+			Expect(err).To(MatchError(constant.ErrIPUsedOut))
+
+			ipConfig, err = ipPoolManager.AllocateIP(ctx, childT.Name, "container-1", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*ipConfig.Address).To(Equal("172.18.40.1/24"))
+		})
+
+		It("returns the VLAN of the IP range which the allocated IP address pertains to", func() {
+			ctx := context.TODO()
+			ipPoolT.Spec.IPs = []string{"172.18.40.1"}
+			ipPoolT.Spec.ExcludeIPs = nil
+			ipPoolT.Spec.VlanRanges = []spiderpoolv1.VlanRange{
+				{IPs: []string{"172.18.40.1-172.18.40.2"}, Vlan: 100},
+			}
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipConfig.Vlan).To(BeEquivalentTo(100))
+			Expect(ippoolmanager.GetIPVlan(ipPoolT, net.ParseIP("172.18.40.100"))).To(BeEquivalentTo(0))
+		})
+
+		It("derives the MAC address from the allocated IP address", func() {
+			ctx := context.TODO()
+			ipPoolT.Spec.IPs = []string{"172.18.40.1"}
+			ipPoolT.Spec.ExcludeIPs = nil
+			ipPoolT.Spec.MACPrefix = pointer.String("0A:60")
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipConfig.Mac).To(Equal("0a:60:ac:12:28:01"))
+
+			ipPoolT.Spec.MACPrefix = nil
+			Expect(ippoolmanager.GetIPMAC(ipPoolT, net.ParseIP("172.18.40.1"))).To(BeEmpty())
+		})
+
+		It("rejects the allocation from draining IPPool", func() {
+			ctx := context.TODO()
+			ipPoolT.Spec.Drain = pointer.Bool(true)
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container", "eth0", podT, podController)
+			Expect(err).To(HaveOccurred())
+			Expect(ipConfig).To(BeNil())
+		})
+
+		It("allocates the IP addresses released by others", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			for i := 0; i < 5; i++ {
+				_, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, fmt.Sprintf("container-%d", i), "eth0", podT, podController)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			delete(ipPool.Status.AllocatedIPs, "172.18.40.100")
+			err = fakeClient.Status().Update(ctx, ipPool)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container-5", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*ipConfig.Address).To(Equal("172.18.40.100/24"))
+		})
+
+		It("records and renews the leases of IP allocations", func() {
+			ipPoolT.Spec.LeaseDurationSeconds = pointer.Int64(60)
+
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			ip := strings.Split(*ipConfig.Address, "/")[0]
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.AllocatedIPs[ip].LeaseRenewTime).NotTo(BeNil())
+
+			allocation := ipPool.Status.AllocatedIPs[ip]
+			allocation.LeaseRenewTime = &metav1.Time{Time: time.Now().Add(-2 * time.Minute)}
+			ipPool.Status.AllocatedIPs[ip] = allocation
+			err = fakeClient.Status().Update(ctx, ipPool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ippoolmanager.IsIPAllocationLeaseExpired(ipPool, allocation, time.Now())).To(BeTrue())
+
+			err = ipPoolManager.RenewIPLeases(ctx, ipPoolT.Name, []types.IPAndCID{{IP: ip, ContainerID: "container"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err = ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ippoolmanager.IsIPAllocationLeaseExpired(ipPool, ipPool.Status.AllocatedIPs[ip], time.Now())).To(BeFalse())
+		})
+
+		It("exports the IP allocations and imports them into another IPPool", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+			err = fakeClient.Create(ctx, rIPT)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			state, err := ipPoolManager.ExportIPAllocations(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state.AllocatedIPs).To(HaveLen(1))
+
+			// Release the IP address as if it is migrated to another cluster.
+			var ip string
+			for ip = range state.AllocatedIPs {
+			}
+			err = ipPoolManager.ReleaseIP(ctx, ipPoolT.Name, []types.IPAndCID{{IP: ip, ContainerID: "container"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ipPoolManager.ImportIPAllocations(ctx, ipPoolT.Name, state)
+			Expect(err).NotTo(HaveOccurred())
+			err = ipPoolManager.ImportIPAllocations(ctx, ipPoolT.Name, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.AllocatedIPs).To(Equal(state.AllocatedIPs))
+			Expect(*ipPool.Status.AllocatedIPCount).To(BeEquivalentTo(1))
+
+			// The imported IP address is not allocated again.
+			ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container-1", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*ipConfig.Address).NotTo(HavePrefix(ip + "/"))
+		})
+
+		It("rejects importing the invalid IP allocations", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+			err = fakeClient.Create(ctx, rIPT)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+
+			newState := func(ip string) *ippoolmanager.IPPoolAllocationState {
+				return &ippoolmanager.IPPoolAllocationState{
+					IPPool:       ipPoolT.Name,
+					IPVersion:    constant.IPv4,
+					Subnet:       ipPoolT.Spec.Subnet,
+					AllocatedIPs: spiderpoolv1.PoolIPAllocations{ip: {ContainerID: "other", NIC: "eth0", Node: "node", Namespace: "default", Pod: "other"}},
+				}
+			}
+
+			state := newState("172.18.40.5")
+			state.Subnet = "172.18.41.0/24"
+			err = ipPoolManager.ImportIPAllocations(ctx, ipPoolT.Name, state)
+			Expect(err).To(MatchError(constant.ErrWrongInput))
+
+			// Out of the IPPool, excluded, reserved and allocated to others.
+			for _, ip := range []string{"172.18.40.10", "172.18.40.2", "172.18.40.4"} {
+				err = ipPoolManager.ImportIPAllocations(ctx, ipPoolT.Name, newState(ip))
+				Expect(err).To(MatchError(constant.ErrWrongInput))
+			}
+			for ip := range ipPool.Status.AllocatedIPs {
+				err = ipPoolManager.ImportIPAllocations(ctx, ipPoolT.Name, newState(ip))
+				Expect(err).To(MatchError(constant.ErrWrongInput))
+			}
+
+			err = ipPoolManager.ImportIPAllocations(ctx, ipPoolT.Name, nil)
+			Expect(err).To(MatchError(constant.ErrWrongInput))
+		})
+
+		It("finds the owner of the IP address", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			ip := strings.Split(*ipConfig.Address, "/")[0]
+
+			ipPool, allocation, err := ipPoolManager.GetIPAllocationByIP(ctx, ip)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Name).To(Equal(ipPoolT.Name))
+			Expect(allocation.Namespace).To(Equal(podT.Namespace))
+			Expect(allocation.Pod).To(Equal(podT.Name))
+			Expect(allocation.PodUID).To(Equal(string(podT.UID)))
+			Expect(allocation.Node).To(Equal(podT.Spec.NodeName))
+			Expect(allocation.OwnerControllerType).To(Equal(podController.Kind))
+
+			ipPool, allocation, err = ipPoolManager.GetIPAllocationByIP(ctx, "172.18.41.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool).To(BeNil())
+			Expect(allocation).To(BeNil())
+
+			_, _, err = ipPoolManager.GetIPAllocationByIP(ctx, constant.InvalidIP)
+			Expect(err).To(MatchError(constant.ErrWrongInput))
+		})
+
+		It("does not hand the reserved IP addresses being vacated over to the restarted Pod", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			ip := strings.Split(*ipConfig.Address, "/")[0]
+
+			rIPT.Spec.IPs = []string{ip}
+			rIPT.Spec.VacateAllocatedIPs = pointer.Bool(true)
+			rIPT.Annotations = map[string]string{constant.AnnoReservedIPDescription: "database"}
+			err = fakeClient.Create(ctx, rIPT)
+			Expect(err).NotTo(HaveOccurred())
+
+			// The same container is not a restart.
+			err = ipPoolManager.UpdateAllocatedIPs(ctx, ipPoolT.Name, []types.IPAndCID{{IP: ip, ContainerID: "container", Node: "node"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ipPoolManager.UpdateAllocatedIPs(ctx, ipPoolT.Name, []types.IPAndCID{{IP: ip, ContainerID: "restarted", Node: "node"}})
+			Expect(err).To(MatchError(constant.ErrIPVacating))
+
+			var deniedErr *reservedipmanager.ReservedIPDeniedError
+			Expect(errors.As(err, &deniedErr)).To(BeTrue())
+			Expect(deniedErr.ReservedIP).To(Equal(rIPT.Name))
+			Expect(deniedErr.Message()).To(Equal(fmt.Sprintf("IP address %s of IPPool %s is reserved by SpiderReservedIP %s (database)", ip, ipPoolT.Name, rIPT.Name)))
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.AllocatedIPs[ip].ContainerID).To(Equal("container"))
+		})
+
+		It("does not record the leases if the lease of IPPool is not set", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipConfig, err := ipPoolManager.AllocateIP(ctx, ipPoolT.Name, "container", "eth0", podT, podController)
+			Expect(err).NotTo(HaveOccurred())
+			ip := strings.Split(*ipConfig.Address, "/")[0]
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.AllocatedIPs[ip].LeaseRenewTime).To(BeNil())
+			Expect(ippoolmanager.IsIPAllocationLeaseExpired(ipPool, ipPool.Status.AllocatedIPs[ip], time.Now())).To(BeFalse())
+		})
+	})
+
+	Describe("GetSpecialIPs", func() {
+		It("returns the network, broadcast and gateway addresses", func() {
+			pool := &spiderpoolv1.SpiderIPPool{
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion:       pointer.Int64(constant.IPv4),
+					Subnet:          "172.18.40.0/24",
+					Gateway:         pointer.String("172.18.40.1"),
+					StandbyGateways: []string{"172.18.40.2"},
+				},
+			}
+			ips, err := ippoolmanager.GetSpecialIPs(pool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal([]net.IP{
+				net.IPv4(172, 18, 40, 0).To4(),
+				net.IPv4(172, 18, 40, 255).To4(),
+				net.ParseIP("172.18.40.1"),
+				net.ParseIP("172.18.40.2"),
+			}))
+		})
+
+		It("returns the Subnet-Router anycast address of IPv6 subnet", func() {
+			pool := &spiderpoolv1.SpiderIPPool{
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion: pointer.Int64(constant.IPv6),
+					Subnet:    "abcd:1234::/120",
+				},
+			}
+			ips, err := ippoolmanager.GetSpecialIPs(pool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal([]net.IP{net.ParseIP("abcd:1234::")}))
+		})
+
+		It("returns only the gateway of the point-to-point subnets", func() {
+			pool := &spiderpoolv1.SpiderIPPool{
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion: pointer.Int64(constant.IPv4),
+					Subnet:    "172.18.40.0/31",
+					Gateway:   pointer.String("172.18.40.0"),
+				},
+			}
+			ips, err := ippoolmanager.GetSpecialIPs(pool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal([]net.IP{net.ParseIP("172.18.40.0")}))
+
+			pool.Spec.IPVersion = pointer.Int64(constant.IPv6)
+			pool.Spec.Subnet = "abcd:1234::/127"
+			pool.Spec.Gateway = nil
+			ips, err = ippoolmanager.GetSpecialIPs(pool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(BeEmpty())
+		})
+
+		It("overrides the global policy by the IPPool", func() {
+			pool := &spiderpoolv1.SpiderIPPool{}
+			Expect(ippoolmanager.ShouldReserveSpecialIPs(pool, true)).To(BeTrue())
+			pool.Spec.ReserveSpecialIPs = pointer.Bool(false)
+			Expect(ippoolmanager.ShouldReserveSpecialIPs(pool, true)).To(BeFalse())
+			pool.Spec.ReserveSpecialIPs = pointer.Bool(true)
+			Expect(ippoolmanager.ShouldReserveSpecialIPs(pool, false)).To(BeTrue())
+		})
+	})
+
+	Describe("GetIPPoolRoutes", func() {
+		var ipPoolT *spiderpoolv1.SpiderIPPool
+
+		BeforeEach(func() {
+			ipPoolT = &spiderpoolv1.SpiderIPPool{
+				Spec: spiderpoolv1.IPPoolSpec{
+					Routes: []spiderpoolv1.Route{
+						{Dst: "10.0.0.0/8", Gw: "172.18.40.2"},
+					},
+				},
+				Status: spiderpoolv1.IPPoolStatus{
+					InheritedRoutes: []spiderpoolv1.Route{
+						{Dst: "10.0.0.0/8", Gw: "172.18.40.1"},
+						{Dst: "192.168.0.0/16", Gw: "172.18.40.1"},
+					},
+				},
+			}
+		})
+
+		It("overrides the inherited routes with the same destination", func() {
+			Expect(ippoolmanager.GetIPPoolRoutes(ipPoolT)).To(Equal([]spiderpoolv1.Route{
+				{Dst: "192.168.0.0/16", Gw: "172.18.40.1"},
+				{Dst: "10.0.0.0/8", Gw: "172.18.40.2"},
+			}))
+		})
+
+		It("opts out of the inherited routes", func() {
+			ipPoolT.Spec.InheritSubnetRoutes = pointer.Bool(false)
+			Expect(ippoolmanager.GetIPPoolRoutes(ipPoolT)).To(Equal(ipPoolT.Spec.Routes))
+		})
+	})
+
+	Describe("ReportGatewayReachability", func() {
+		var ipPoolT *spiderpoolv1.SpiderIPPool
+
+		BeforeEach(func() {
+			ipPoolT = &spiderpoolv1.SpiderIPPool{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       constant.SpiderIPPoolKind,
+					APIVersion: fmt.Sprintf("%s/%s", constant.SpiderpoolAPIGroup, constant.SpiderpoolAPIVersionV1),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "gateway-ippool",
+				},
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion: pointer.Int64(constant.IPv4),
+					Subnet:    "172.18.40.0/24",
+					IPs:       []string{"172.18.40.2-172.18.40.5"},
+					Gateway:   pointer.String("172.18.40.1"),
+				},
+			}
+		})
+
+		AfterEach(func() {
+			ctx := context.TODO()
+			err := fakeClient.Delete(ctx, ipPoolT, client.GracePeriodSeconds(0))
+			Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+		})
+
+		It("records the nodes which find the gateway unreachable", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ipPoolManager.ReportGatewayReachability(ctx, ipPoolT.Name, "node1", false)
+			Expect(err).NotTo(HaveOccurred())
+			err = ipPoolManager.ReportGatewayReachability(ctx, ipPoolT.Name, "node2", false)
+			Expect(err).NotTo(HaveOccurred())
+			err = ipPoolManager.ReportGatewayReachability(ctx, ipPoolT.Name, "node3", true)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.DatapathFailureNodes).To(HaveLen(2))
+			Expect(ipPool.Status.DatapathFailureNodes["node1"].GatewayUnreachableTime).NotTo(BeNil())
+			Expect(ipPool.Status.DatapathFailureNodes["node2"].GatewayUnreachableTime).NotTo(BeNil())
+
+			err = ipPoolManager.ReportGatewayReachability(ctx, ipPoolT.Name, "node1", true)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err = ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.DatapathFailureNodes).To(HaveLen(1))
+			Expect(ipPool.Status.DatapathFailureNodes).To(HaveKey("node2"))
+		})
+
+		It("keeps the repeated failures of the node finding the gateway reachable again", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ipPoolManager.ReportGatewayReachability(ctx, ipPoolT.Name, "node1", false)
+			Expect(err).NotTo(HaveOccurred())
+			err = ipPoolManager.ReportRepeatedDatapathFailures(ctx, ipPoolT.Name, "node1", "3 datapath failures")
+			Expect(err).NotTo(HaveOccurred())
+			err = ipPoolManager.ReportGatewayReachability(ctx, ipPoolT.Name, "node1", true)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.DatapathFailureNodes).To(HaveKey("node1"))
+			Expect(ipPool.Status.DatapathFailureNodes["node1"].GatewayUnreachableTime).To(BeNil())
+			Expect(ipPool.Status.DatapathFailureNodes["node1"].RepeatedFailureTime).NotTo(BeNil())
+		})
+
+		It("reports the gateway of the non-existent IPPool", func() {
+			err := ipPoolManager.ReportGatewayReachability(context.TODO(), ipPoolT.Name, "node1", false)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Describe("NetworkScan", func() {
+		var ipPoolT *spiderpoolv1.SpiderIPPool
+
+		BeforeEach(func() {
+			ipPoolT = &spiderpoolv1.SpiderIPPool{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       constant.SpiderIPPoolKind,
+					APIVersion: fmt.Sprintf("%s/%s", constant.SpiderpoolAPIGroup, constant.SpiderpoolAPIVersionV1),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "scan-ippool",
+				},
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion: pointer.Int64(constant.IPv4),
+					Subnet:    "172.18.40.0/24",
+					IPs:       []string{"172.18.40.2-172.18.40.5"},
+				},
+				Status: spiderpoolv1.IPPoolStatus{
+					AllocatedIPs: spiderpoolv1.PoolIPAllocations{
+						"172.18.40.5": {ContainerID: "container", NIC: "eth0", Namespace: "default", Pod: "pod"},
+					},
+				},
+			}
+		})
+
+		AfterEach(func() {
+			ctx := context.TODO()
+			err := fakeClient.Delete(ctx, ipPoolT, client.GracePeriodSeconds(0))
+			Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+		})
+
+		It("claims the network scan for one node at a time", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err := ipPoolManager.ClaimNetworkScan(ctx, ipPoolT.Name, "node1", time.Hour, time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool).NotTo(BeNil())
+			Expect(ipPool.Status.NetworkScan.Node).To(Equal("node1"))
+
+			ipPool, err = ipPoolManager.ClaimNetworkScan(ctx, ipPoolT.Name, "node2", time.Hour, time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool).To(BeNil())
+
+			ipPool, err = ipPoolManager.ClaimNetworkScan(ctx, ipPoolT.Name, "node2", time.Hour, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool).NotTo(BeNil())
+			Expect(ipPool.Status.NetworkScan.Node).To(Equal("node2"))
+		})
+
+		It("reports the IP addresses in use except the allocated ones", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = ipPoolManager.ClaimNetworkScan(ctx, ipPoolT.Name, "node1", time.Hour, time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+
+			inUseIPs := []net.IP{net.ParseIP("172.18.40.2"), net.ParseIP("172.18.40.3"), net.ParseIP("172.18.40.5")}
+			err = ipPoolManager.ReportNetworkScan(ctx, ipPoolT.Name, "node2", inUseIPs)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.NetworkScan.CompletionTime).To(BeNil())
+
+			err = ipPoolManager.ReportNetworkScan(ctx, ipPoolT.Name, "node1", inUseIPs)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err = ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.NetworkScan.CompletionTime).NotTo(BeNil())
+			Expect(ipPool.Status.NetworkScan.InUseIPs).To(Equal([]string{"172.18.40.2-172.18.40.3"}))
+
+			ipPool, err = ipPoolManager.ClaimNetworkScan(ctx, ipPoolT.Name, "node1", time.Hour, time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool).To(BeNil())
+		})
+	})
+
+	Describe("ReportIPLeaks", func() {
+		var ipPoolT *spiderpoolv1.SpiderIPPool
+
+		BeforeEach(func() {
+			ipPoolT = &spiderpoolv1.SpiderIPPool{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       constant.SpiderIPPoolKind,
+					APIVersion: fmt.Sprintf("%s/%s", constant.SpiderpoolAPIGroup, constant.SpiderpoolAPIVersionV1),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "leak-ippool",
+				},
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion: pointer.Int64(constant.IPv4),
+					Subnet:    "172.18.40.0/24",
+					IPs:       []string{"172.18.40.2-172.18.40.5"},
+				},
+				Status: spiderpoolv1.IPPoolStatus{
+					AllocatedIPs: spiderpoolv1.PoolIPAllocations{
+						"172.18.40.2": {ContainerID: "container1", NIC: "eth0", Namespace: "default", Pod: "pod1"},
+						"172.18.40.3": {ContainerID: "container2", NIC: "eth0", Namespace: "default", Pod: "pod2"},
+					},
+				},
+			}
+		})
+
+		AfterEach(func() {
+			ctx := context.TODO()
+			err := fakeClient.Delete(ctx, ipPoolT, client.GracePeriodSeconds(0))
+			Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+		})
+
+		It("records the leaks of the IP addresses still allocated", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			detectedAt := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+			leaks := map[string]spiderpoolv1.IPLeak{
+				"172.18.40.2": {
+					Namespace:   "default",
+					Pod:         "pod1",
+					ContainerID: "container1",
+					Evidence:    constant.IPLeakEvidencePodNotFound,
+					Action:      constant.IPLeakActionReleaseIPAndRemoveFinalizer,
+					DetectedAt:  detectedAt,
+				},
+				"172.18.40.3": {
+					Namespace:   "default",
+					Pod:         "pod2",
+					ContainerID: "stale-container",
+					Evidence:    constant.IPLeakEvidenceStaleContainerID,
+					Action:      constant.IPLeakActionReleaseIP,
+					DetectedAt:  detectedAt,
+				},
+			}
+			err = ipPoolManager.ReportIPLeaks(ctx, ipPoolT.Name, leaks)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.SuspectedLeakedIPs).To(HaveLen(1))
+			Expect(ipPool.Status.SuspectedLeakedIPs).To(HaveKey("172.18.40.2"))
+
+			leak := leaks["172.18.40.2"]
+			leak.DetectedAt = metav1.Now()
+			err = ipPoolManager.ReportIPLeaks(ctx, ipPoolT.Name, map[string]spiderpoolv1.IPLeak{"172.18.40.2": leak})
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err = ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.SuspectedLeakedIPs["172.18.40.2"].DetectedAt.Time).To(BeTemporally("==", detectedAt.Time))
+
+			err = ipPoolManager.ReportIPLeaks(ctx, ipPoolT.Name, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err = ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.SuspectedLeakedIPs).To(BeEmpty())
+		})
+
+		It("reports the leaks of the non-existent IPPool", func() {
+			err := ipPoolManager.ReportIPLeaks(context.TODO(), ipPoolT.Name, nil)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Describe("ReportRepeatedDatapathFailures", func() {
+		var ipPoolT *spiderpoolv1.SpiderIPPool
+
+		BeforeEach(func() {
+			ipPoolT = &spiderpoolv1.SpiderIPPool{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       constant.SpiderIPPoolKind,
+					APIVersion: fmt.Sprintf("%s/%s", constant.SpiderpoolAPIGroup, constant.SpiderpoolAPIVersionV1),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "quarantined-ippool",
+				},
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion: pointer.Int64(constant.IPv4),
+					Subnet:    "172.18.40.0/24",
+					IPs:       []string{"172.18.40.2-172.18.40.5"},
+				},
+			}
+		})
+
+		AfterEach(func() {
+			ctx := context.TODO()
+			err := fakeClient.Delete(ctx, ipPoolT, client.GracePeriodSeconds(0))
+			Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+		})
+
+		It("records the repeated failures of the node without quarantining the IPPool", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ipPoolManager.ReportRepeatedDatapathFailures(ctx, ipPoolT.Name, "node1", "3 datapath failures")
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ippoolmanager.IsQuarantinedIPPool(ipPool)).To(BeFalse())
+			Expect(ipPool.Status.DatapathFailureNodes).To(HaveKey("node1"))
+			Expect(ipPool.Status.DatapathFailureNodes["node1"].RepeatedFailureTime).NotTo(BeNil())
+			Expect(ipPool.Status.DatapathFailureNodes["node1"].Message).To(Equal("3 datapath failures"))
+		})
+
+		It("skips the same failures reported again shortly", func() {
+			ctx := context.TODO()
+			reported := metav1.NewTime(time.Now().Add(-10 * time.Second).Truncate(time.Second))
+			ipPoolT.Status.DatapathFailureNodes = map[string]spiderpoolv1.DatapathFailure{
+				"node1": {RepeatedFailureTime: &reported, Message: "3 datapath failures"},
+			}
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ipPoolManager.ReportRepeatedDatapathFailures(ctx, ipPoolT.Name, "node1", "3 datapath failures")
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Status.DatapathFailureNodes["node1"].RepeatedFailureTime.Time).To(BeTemporally("==", reported.Time))
+		})
+
+		It("reports the failures of the non-existent IPPool", func() {
+			err := ipPoolManager.ReportRepeatedDatapathFailures(context.TODO(), ipPoolT.Name, "node1", "3 datapath failures")
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Describe("ReclaimAutoIPPool", func() {
+		var ipPoolT *spiderpoolv1.SpiderIPPool
+
+		BeforeEach(func() {
+			ipPoolT = &spiderpoolv1.SpiderIPPool{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       constant.SpiderIPPoolKind,
+					APIVersion: fmt.Sprintf("%s/%s", constant.SpiderpoolAPIGroup, constant.SpiderpoolAPIVersionV1),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "auto-ippool",
+					Labels: map[string]string{
+						constant.LabelIPPoolOwnerApplication: "Deployment_default_demo",
+						constant.LabelIPPoolReclaimIPPool:    constant.True,
+					},
+				},
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion: pointer.Int64(constant.IPv4),
+					Subnet:    "172.18.40.0/24",
+					IPs:       []string{"172.18.40.2-172.18.40.5"},
+				},
+			}
+		})
+
+		AfterEach(func() {
+			ctx := context.TODO()
+			err := fakeClient.Delete(ctx, ipPoolT, client.GracePeriodSeconds(0))
+			Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+		})
+
+		It("deletes the IPPool at once without policy", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			deleted, err := ipPoolManager.ReclaimAutoIPPool(ctx, ipPoolT, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(BeTrue())
+
+			_, err = ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("retains the IPPool with the Retain policy", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			deleted, err := ipPoolManager.ReclaimAutoIPPool(ctx, ipPoolT, &types.AutoPoolReclaimPolicy{Policy: constant.ReclaimPolicyRetain})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(BeFalse())
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipPool.Labels).NotTo(HaveKey(constant.LabelIPPoolReclaimIPPool))
+		})
+
+		It("deletes the IPPool after the grace period of the Delayed policy", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPoolT)
+			Expect(err).NotTo(HaveOccurred())
+
+			policy := &types.AutoPoolReclaimPolicy{Policy: constant.ReclaimPolicyDelayed, Delay: 10 * time.Minute}
+			deleted, err := ipPoolManager.ReclaimAutoIPPool(ctx, ipPoolT, policy)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(BeFalse())
+
+			ipPool, err := ipPoolManager.GetIPPoolByName(ctx, ipPoolT.Name)
+			Expect(err).NotTo(HaveOccurred())
+			reclaimAfter, ok := ippoolmanager.GetIPPoolReclaimAfter(ipPool)
+			Expect(ok).To(BeTrue())
+			Expect(reclaimAfter).To(BeTemporally("~", time.Now().Add(10*time.Minute), time.Minute))
+
+			ipPool.Annotations[constant.AnnoIPPoolReclaimAfter] = time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+			err = fakeClient.Update(ctx, ipPool)
+			Expect(err).NotTo(HaveOccurred())
+
+			deleted, err = ipPoolManager.ReclaimAutoIPPool(ctx, ipPool, policy)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(BeTrue())
+		})
+	})
+
+	Describe("GetAutoIPPoolPinnedIPs", func() {
+		It("returns nothing for the IPPool without pinned IPs", func() {
+			ipPool := &spiderpoolv1.SpiderIPPool{
+				Spec: spiderpoolv1.IPPoolSpec{IPVersion: pointer.Int64(constant.IPv4)},
+			}
+			ips, err := ippoolmanager.GetAutoIPPoolPinnedIPs(ipPool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(BeEmpty())
+		})
+
+		It("parses the pinned IPs of the IPPool", func() {
+			ipPool := &spiderpoolv1.SpiderIPPool{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						constant.AnnoSpiderSubnetPoolIPs: "172.18.40.10-172.18.40.12, 172.18.40.20",
+					},
+				},
+				Spec: spiderpoolv1.IPPoolSpec{IPVersion: pointer.Int64(constant.IPv4)},
+			}
+			ips, err := ippoolmanager.GetAutoIPPoolPinnedIPs(ipPool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(HaveLen(4))
+		})
+
+		It("fails to parse the pinned IPs of another IP version", func() {
+			ipPool := &spiderpoolv1.SpiderIPPool{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						constant.AnnoSpiderSubnetPoolIPs: "abcd:1234::10-abcd:1234::12",
+					},
+				},
+				Spec: spiderpoolv1.IPPoolSpec{IPVersion: pointer.Int64(constant.IPv4)},
+			}
+			_, err := ippoolmanager.GetAutoIPPoolPinnedIPs(ipPool)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
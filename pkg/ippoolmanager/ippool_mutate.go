@@ -6,9 +6,14 @@ package ippoolmanager
 import (
 	"context"
 	"fmt"
+	"net"
+	"sort"
+	"strings"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -62,13 +67,33 @@ func (iw *IPPoolWebhook) mutateIPPool(ctx context.Context, ipPool *spiderpoolv1.
 		logger.Sugar().Infof("Set label %s: %s", constant.LabelIPPoolCIDR, cidr)
 	}
 
+	if tenant := GetIPPoolTenant(ipPool); tenant != "" {
+		if v, ok := ipPool.Labels[constant.LabelIPPoolTenant]; !ok || v != tenant {
+			if errs := validation.IsValidLabelValue(tenant); len(errs) != 0 {
+				logger.Sugar().Warnf("Tenant %s is not a valid label value: %v", tenant, errs)
+			} else {
+				if ipPool.Labels == nil {
+					ipPool.Labels = make(map[string]string)
+				}
+				ipPool.Labels[constant.LabelIPPoolTenant] = tenant
+				logger.Sugar().Infof("Set label %s: %s", constant.LabelIPPoolTenant, tenant)
+			}
+		}
+	}
+
+	if ipPool.Spec.ParentPool != nil {
+		if err := iw.inheritParentIPPool(ctx, ipPool); err != nil {
+			return apierrors.NewInternalError(fmt.Errorf("failed to inherit the parent IPPool: %v", err))
+		}
+	}
+
 	if iw.EnableSpiderSubnet {
 		if err := iw.setControllerSubnet(ctx, ipPool); err != nil {
 			return apierrors.NewInternalError(fmt.Errorf("failed to set the reference of the controller Subnet: %v", err))
 		}
 	}
 
-	if len(ipPool.Spec.IPs) > 1 {
+	if len(ipPool.Spec.IPs) > 1 || spiderpoolip.HasCompactIPRanges(*ipPool.Spec.IPVersion, ipPool.Spec.IPs) {
 		mergedIPs, err := spiderpoolip.MergeIPRanges(*ipPool.Spec.IPVersion, ipPool.Spec.IPs)
 		if err != nil {
 			return fmt.Errorf("failed to merge 'spec.ips': %v", err)
@@ -78,7 +103,15 @@ func (iw *IPPoolWebhook) mutateIPPool(ctx context.Context, ipPool *spiderpoolv1.
 		logger.Sugar().Debugf("Merge 'spec.ips':\n%v\n\nto:\n\n%v", ipPool.Spec.IPs, mergedIPs)
 	}
 
-	if len(ipPool.Spec.ExcludeIPs) > 1 {
+	autoExcludeGateways := iw.AutoExcludeGateways
+	if ipPool.Spec.AutoExcludeGateways != nil {
+		autoExcludeGateways = *ipPool.Spec.AutoExcludeGateways
+	}
+	if err := excludeNeighborGateways(ctx, ipPool, autoExcludeGateways); err != nil {
+		return err
+	}
+
+	if len(ipPool.Spec.ExcludeIPs) > 1 || spiderpoolip.HasCompactIPRanges(*ipPool.Spec.IPVersion, ipPool.Spec.ExcludeIPs) {
 		mergedExcludeIPs, err := spiderpoolip.MergeIPRanges(*ipPool.Spec.IPVersion, ipPool.Spec.ExcludeIPs)
 		if err != nil {
 			return fmt.Errorf("failed to merge 'spec.excludeIPs': %v", err)
@@ -91,6 +124,55 @@ func (iw *IPPoolWebhook) mutateIPPool(ctx context.Context, ipPool *spiderpoolv1.
 	return nil
 }
 
+// inheritParentIPPool labels the child IPPool with its parent and the UID
+// of the parent, and sets the gateway, VLAN and routes of the parent to the
+// child if they are not set. The recorded UID is never overwritten, so that
+// a re-created parent is rejected by the validation, as is a non-existent
+// one.
+func (iw *IPPoolWebhook) inheritParentIPPool(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool) error {
+	logger := logutils.FromContext(ctx)
+
+	parentName := *ipPool.Spec.ParentPool
+	if v, ok := ipPool.Labels[constant.LabelIPPoolParent]; !ok || v != parentName {
+		if ipPool.Labels == nil {
+			ipPool.Labels = make(map[string]string)
+		}
+		ipPool.Labels[constant.LabelIPPoolParent] = parentName
+		logger.Sugar().Infof("Set label %s: %s", constant.LabelIPPoolParent, parentName)
+	}
+
+	var parent spiderpoolv1.SpiderIPPool
+	if err := iw.Client.Get(ctx, apitypes.NamespacedName{Name: parentName}, &parent); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if _, ok := ipPool.Labels[constant.LabelIPPoolParentUID]; !ok {
+		ipPool.Labels[constant.LabelIPPoolParentUID] = string(parent.UID)
+		logger.Sugar().Infof("Set label %s: %s", constant.LabelIPPoolParentUID, parent.UID)
+	}
+
+	if ipPool.Spec.Gateway == nil && parent.Spec.Gateway != nil {
+		ipPool.Spec.Gateway = new(string)
+		*ipPool.Spec.Gateway = *parent.Spec.Gateway
+		logger.Sugar().Infof("Inherit 'spec.gateway' %s from the parent IPPool %s", *parent.Spec.Gateway, parentName)
+	}
+
+	// 'spec.vlan' is defaulted to 0 by the API server.
+	if (ipPool.Spec.Vlan == nil || *ipPool.Spec.Vlan == 0) && parent.Spec.Vlan != nil && *parent.Spec.Vlan != 0 {
+		ipPool.Spec.Vlan = new(int64)
+		*ipPool.Spec.Vlan = *parent.Spec.Vlan
+		logger.Sugar().Infof("Inherit 'spec.vlan' %d from the parent IPPool %s", *parent.Spec.Vlan, parentName)
+	}
+
+	if len(ipPool.Spec.Routes) == 0 && len(parent.Spec.Routes) != 0 {
+		ipPool.Spec.Routes = make([]spiderpoolv1.Route, len(parent.Spec.Routes))
+		copy(ipPool.Spec.Routes, parent.Spec.Routes)
+		logger.Sugar().Infof("Inherit 'spec.routes' %+v from the parent IPPool %s", parent.Spec.Routes, parentName)
+	}
+
+	return nil
+}
+
 func (iw *IPPoolWebhook) setControllerSubnet(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool) error {
 	logger := logutils.FromContext(ctx)
 
@@ -133,3 +215,105 @@ func (iw *IPPoolWebhook) setControllerSubnet(ctx context.Context, ipPool *spider
 
 	return nil
 }
+
+// excludeNeighborGateways appends the gateways of neighboring subnets to
+// 'spec.excludeIPs' of the IPPool, and records them in the annotation
+// 'auto-excluded-gateways'. The recorded ones no longer listed in
+// 'spec.excludeGateways', or all of them if disabled, are removed from
+// 'spec.excludeIPs' again. The ones being allocated are left alone,
+// otherwise the IPPools which have allocated them before can no longer be
+// updated, they're excluded by the later updates once released.
+func excludeNeighborGateways(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, enabled bool) error {
+	logger := logutils.FromContext(ctx)
+
+	version := *ipPool.Spec.IPVersion
+	var excluded []net.IP
+	if anno, ok := ipPool.Annotations[constant.AnnoIPPoolAutoExcludedGateways]; ok && anno != "" {
+		for _, ip := range strings.Split(anno, ",") {
+			if parsed := net.ParseIP(ip); parsed != nil {
+				excluded = append(excluded, parsed)
+			}
+		}
+	}
+
+	var gateways []net.IP
+	if enabled {
+		gateways = getNeighborGateways(ipPool)
+	}
+	if len(gateways) == 0 && len(excluded) == 0 {
+		return nil
+	}
+
+	excludeIPs, err := spiderpoolip.ParseIPRanges(version, ipPool.Spec.ExcludeIPs)
+	if err != nil {
+		return fmt.Errorf("failed to parse 'spec.excludeIPs': %v", err)
+	}
+
+	if stale := spiderpoolip.IPsDiffSet(excluded, gateways, false); len(stale) > 0 {
+		excludeIPs = spiderpoolip.IPsDiffSet(excludeIPs, stale, false)
+		ranges, err := spiderpoolip.ConvertIPsToIPRanges(version, excludeIPs)
+		if err != nil {
+			return fmt.Errorf("failed to convert 'spec.excludeIPs' %v to IP ranges: %v", excludeIPs, err)
+		}
+		ipPool.Spec.ExcludeIPs = ranges
+		excluded = spiderpoolip.IPsDiffSet(excluded, stale, false)
+		logger.Sugar().Infof("Stop excluding the gateways of neighboring subnets %v", stale)
+	}
+
+	// The recorded ones removed from 'spec.excludeIPs' by hand are
+	// excluded again below.
+	excluded = spiderpoolip.IPsIntersectionSet(excluded, excludeIPs, false)
+
+	var newExcludeIPs []net.IP
+	for _, ip := range spiderpoolip.IPsDiffSet(gateways, excludeIPs, false) {
+		if allocation, ok := ipPool.Status.AllocatedIPs[ip.String()]; ok {
+			logger.Sugar().Warnf("Gateway %s of neighboring subnet is being used by Pod %s/%s, not to exclude it", ip, allocation.Namespace, allocation.Pod)
+			continue
+		}
+		newExcludeIPs = append(newExcludeIPs, ip)
+	}
+
+	if len(newExcludeIPs) > 0 {
+		ranges, err := spiderpoolip.ConvertIPsToIPRanges(version, newExcludeIPs)
+		if err != nil {
+			return fmt.Errorf("failed to convert the gateways %v to IP ranges: %v", newExcludeIPs, err)
+		}
+		ipPool.Spec.ExcludeIPs = append(ipPool.Spec.ExcludeIPs, ranges...)
+		excluded = append(excluded, newExcludeIPs...)
+		logger.Sugar().Infof("Exclude the gateways of neighboring subnets %v", ranges)
+	}
+
+	if len(excluded) == 0 {
+		delete(ipPool.Annotations, constant.AnnoIPPoolAutoExcludedGateways)
+		return nil
+	}
+
+	recorded := make([]string, 0, len(excluded))
+	for _, ip := range excluded {
+		recorded = append(recorded, ip.String())
+	}
+	sort.Strings(recorded)
+	if ipPool.Annotations == nil {
+		ipPool.Annotations = make(map[string]string)
+	}
+	ipPool.Annotations[constant.AnnoIPPoolAutoExcludedGateways] = strings.Join(recorded, ",")
+
+	return nil
+}
+
+// getNeighborGateways returns the gateways of neighboring subnets pertaining
+// to the subnet of the IPPool, which should never be allocated from it. The
+// network and broadcast addresses are left to the policy 'reserveSpecialIPs'
+// applied when allocating.
+func getNeighborGateways(ipPool *spiderpoolv1.SpiderIPPool) []net.IP {
+	var gateways []net.IP
+	for _, gateway := range ipPool.Spec.ExcludeGateways {
+		// The invalid ones are left to the validating webhook.
+		contains, err := spiderpoolip.ContainsIP(*ipPool.Spec.IPVersion, ipPool.Spec.Subnet, gateway)
+		if err == nil && contains {
+			gateways = append(gateways, net.ParseIP(gateway))
+		}
+	}
+
+	return gateways
+}
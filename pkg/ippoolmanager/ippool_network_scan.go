@@ -0,0 +1,131 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+// ClaimNetworkScan claims the scan of the free IP addresses of the IPPool
+// for the node in 'status.networkScan', so that only one spiderpool-agent
+// scans the IPPool at a time. The IPPool is not claimed if it was scanned
+// within interval, or if the scan of another node started within timeout
+// is still in progress, and nil is returned.
+func (im *ipPoolManager) ClaimNetworkScan(ctx context.Context, poolName, nodeName string, interval, timeout time.Duration) (*spiderpoolv1.SpiderIPPool, error) {
+	logger := logutils.FromContext(ctx)
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i <= im.config.MaxConflictRetries; i++ {
+		ipPool, err := im.GetIPPoolByName(ctx, poolName)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		if scan := ipPool.Status.NetworkScan; scan != nil {
+			if scan.CompletionTime == nil && now.Sub(scan.StartTime.Time) < timeout {
+				return nil, nil
+			}
+			if scan.CompletionTime != nil && now.Sub(scan.CompletionTime.Time) < interval {
+				return nil, nil
+			}
+		}
+
+		var inUseIPs []string
+		if ipPool.Status.NetworkScan != nil {
+			inUseIPs = ipPool.Status.NetworkScan.InUseIPs
+		}
+		ipPool.Status.NetworkScan = &spiderpoolv1.IPPoolNetworkScan{
+			Node:      nodeName,
+			StartTime: metav1.NewTime(now),
+			InUseIPs:  inUseIPs,
+		}
+
+		if err := im.client.Status().Update(ctx, ipPool); err != nil {
+			if !apierrors.IsConflict(err) {
+				return nil, err
+			}
+			if i == im.config.MaxConflictRetries {
+				return nil, fmt.Errorf("%w (%d times), failed to claim the network scan of IPPool %s", constant.ErrRetriesExhausted, im.config.MaxConflictRetries, poolName)
+			}
+
+			interval := time.Duration(r.Intn(1<<(i+1))) * im.config.ConflictRetryUnitTime
+			logger.Sugar().Debugf("An conflict occurred when claiming the network scan of IPPool %s, it will be retried in %s", poolName, interval)
+
+			time.Sleep(interval)
+			continue
+		}
+
+		return ipPool, nil
+	}
+
+	return nil, nil
+}
+
+// ReportNetworkScan completes the network scan of the IPPool claimed by the
+// node with the IP addresses observed in use. The ones allocated in the
+// meantime are dropped, since they reply to the probes on behalf of the
+// Pods. The report is discarded if the claim was taken over by another node.
+func (im *ipPoolManager) ReportNetworkScan(ctx context.Context, poolName, nodeName string, inUseIPs []net.IP) error {
+	logger := logutils.FromContext(ctx)
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i <= im.config.MaxConflictRetries; i++ {
+		ipPool, err := im.GetIPPoolByName(ctx, poolName)
+		if err != nil {
+			return err
+		}
+
+		scan := ipPool.Status.NetworkScan
+		if scan == nil || scan.Node != nodeName || scan.CompletionTime != nil {
+			logger.Sugar().Debugf("The network scan of IPPool %s is no longer claimed by node %s, discard the report", poolName, nodeName)
+			return nil
+		}
+
+		var ips []net.IP
+		for _, ip := range inUseIPs {
+			if _, ok := ipPool.Status.AllocatedIPs[ip.String()]; !ok {
+				ips = append(ips, ip)
+			}
+		}
+		ranges, err := spiderpoolip.ConvertIPsToIPRanges(*ipPool.Spec.IPVersion, ips)
+		if err != nil {
+			return err
+		}
+
+		now := metav1.Now()
+		scan.CompletionTime = &now
+		scan.InUseIPs = ranges
+
+		if err := im.client.Status().Update(ctx, ipPool); err != nil {
+			if !apierrors.IsConflict(err) {
+				return err
+			}
+			if i == im.config.MaxConflictRetries {
+				return fmt.Errorf("%w (%d times), failed to report the network scan of IPPool %s", constant.ErrRetriesExhausted, im.config.MaxConflictRetries, poolName)
+			}
+
+			interval := time.Duration(r.Intn(1<<(i+1))) * im.config.ConflictRetryUnitTime
+			logger.Sugar().Debugf("An conflict occurred when reporting the network scan of IPPool %s, it will be retried in %s", poolName, interval)
+
+			time.Sleep(interval)
+			continue
+		}
+		break
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// jsonPatchOperation is an operation of the JSON patch (RFC 6902).
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// newJSONPatch builds the JSON patch of the operations. The status of the
+// IPPool is patched with the 'test' operations guarding the fields it
+// depends on, rather than the resourceVersion, so that the patch doesn't
+// conflict with the updates of the unrelated fields.
+func newJSONPatch(ops ...jsonPatchOperation) (client.Patch, error) {
+	data, err := json.Marshal(ops)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.RawPatch(apitypes.JSONPatchType, data), nil
+}
+
+// isJSONPatchTestFailed reports whether the JSON patch is rejected since one
+// of its 'test' operations fails, which is returned by the API server as
+// 422 Unprocessable Entity.
+func isJSONPatchTestFailed(err error) bool {
+	return apierrors.IsInvalid(err) || errors.Is(err, jsonpatch.ErrTestFailed)
+}
+
+// escapeJSONPointer escapes the reference token of the JSON pointer
+// (RFC 6901).
+func escapeJSONPointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
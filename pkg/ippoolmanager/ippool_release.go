@@ -0,0 +1,81 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// IPReleaseResult is the result of releasing an IP address by ReleaseIPs.
+type IPReleaseResult struct {
+	IPPool string
+	types.IPAndCID
+
+	// Released tells whether the allocation record of the IP address is
+	// removed. It is false if the IP address is not allocated to the
+	// container, which needs no retry.
+	Released bool
+	// Err is the error failing the release of the IP address, which should
+	// be retried.
+	Err error
+}
+
+// ReleaseIPs releases the IP addresses from multiple IPPools, with at most
+// 'MaxReleaseParallelism' IPPools updated at the same time. The IP
+// addresses of each IPPool are released in a single update, so that they
+// are either all released or none of them. The results are returned in the
+// order of IPPool names, along with the aggregated error of the failed
+// IPPools, so that the caller could retry the failed ones precisely.
+func (im *ipPoolManager) ReleaseIPs(ctx context.Context, poolToIPAndCIDs map[string][]types.IPAndCID) ([]IPReleaseResult, error) {
+	pools := make([]string, 0, len(poolToIPAndCIDs))
+	for pool := range poolToIPAndCIDs {
+		pools = append(pools, pool)
+	}
+	sort.Strings(pools)
+
+	results := make([][]IPReleaseResult, len(pools))
+	errs := make([]error, len(pools))
+	sem := make(chan struct{}, im.config.MaxReleaseParallelism)
+	wg := sync.WaitGroup{}
+	for i, pool := range pools {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, pool string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			ipAndCIDs := poolToIPAndCIDs[pool]
+			released, err := im.releaseIP(ctx, pool, ipAndCIDs)
+			errs[i] = err
+
+			releasedSet := make(map[string]struct{}, len(released))
+			for _, ip := range released {
+				releasedSet[ip] = struct{}{}
+			}
+			for _, ic := range ipAndCIDs {
+				_, ok := releasedSet[ic.IP]
+				results[i] = append(results[i], IPReleaseResult{
+					IPPool:   pool,
+					IPAndCID: ic,
+					Released: ok,
+					Err:      err,
+				})
+			}
+		}(i, pool)
+	}
+	wg.Wait()
+
+	var all []IPReleaseResult
+	for _, r := range results {
+		all = append(all, r...)
+	}
+
+	return all, utilerrors.NewAggregate(errs)
+}
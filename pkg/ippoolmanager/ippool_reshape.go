@@ -0,0 +1,735 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/event"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// SplitIPPool moves the IP ranges of the IPPool into new IPPools, along with
+// the allocation records of the IP addresses in them, splits maps the names
+// of the new IPPools to their IP ranges. The new IPPools inherit the spec of
+// the IPPool except 'spec.ips' and 'spec.excludeIPs'. No IP address is
+// allocated from the IPPool until the split is done.
+func (im *ipPoolManager) SplitIPPool(ctx context.Context, poolName string, splits map[string][]string) error {
+	logger := logutils.FromContext(ctx)
+
+	ipPool, err := im.GetIPPoolByName(ctx, poolName)
+	if err != nil {
+		return err
+	}
+	if err := validateReshapableIPPool(ipPool); err != nil {
+		return err
+	}
+	if len(splits) == 0 {
+		return fmt.Errorf("%w, no new IPPool to split IPPool %s into", constant.ErrWrongInput, poolName)
+	}
+
+	version := *ipPool.Spec.IPVersion
+	ips, err := spiderpoolip.ParseIPRanges(version, ipPool.Spec.IPs)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(splits))
+	for name := range splits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var moving []net.IP
+	newIPs := map[string][]net.IP{}
+	for _, name := range names {
+		if name == poolName {
+			return fmt.Errorf("%w, IPPool %s can not be split into itself", constant.ErrWrongInput, poolName)
+		}
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+			return fmt.Errorf("%w, invalid IPPool name %s: %v", constant.ErrWrongInput, name, errs)
+		}
+
+		for _, ipRange := range splits[name] {
+			if err := spiderpoolip.IsIPRange(version, ipRange); err != nil {
+				return fmt.Errorf("%w, invalid IP range %s: %v", constant.ErrWrongInput, ipRange, err)
+			}
+		}
+		rIPs, err := spiderpoolip.ParseIPRanges(version, splits[name])
+		if err != nil {
+			return err
+		}
+		if len(rIPs) == 0 {
+			return fmt.Errorf("%w, no IP range specified for IPPool %s", constant.ErrWrongInput, name)
+		}
+		if len(spiderpoolip.IPsIntersectionSet(moving, rIPs, false)) != 0 {
+			return fmt.Errorf("%w, IP ranges of IPPool %s overlap with other new IPPools", constant.ErrWrongInput, name)
+		}
+
+		// IP addresses which are already in the new IPPool have been moved
+		// by a previous attempt.
+		remaining := spiderpoolip.IPsDiffSet(rIPs, ips, false)
+		if len(remaining) != 0 {
+			existing, err := im.GetIPPoolByName(ctx, name)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			var existingIPs []net.IP
+			if err == nil {
+				if existingIPs, err = spiderpoolip.ParseIPRanges(version, existing.Spec.IPs); err != nil {
+					return err
+				}
+			}
+			if len(spiderpoolip.IPsDiffSet(remaining, existingIPs, false)) != 0 {
+				return fmt.Errorf("%w, IP ranges %v of IPPool %s are not in 'spec.ips' of IPPool %s", constant.ErrWrongInput, splits[name], name, poolName)
+			}
+		}
+
+		moving = append(moving, rIPs...)
+		newIPs[name] = rIPs
+	}
+	if len(spiderpoolip.IPsDiffSet(ips, moving, false)) == 0 {
+		return fmt.Errorf("%w, IPPool %s can not be emptied by split, merge it instead", constant.ErrWrongInput, poolName)
+	}
+
+	if err := im.setIPPoolFence(ctx, poolName, true); err != nil {
+		return err
+	}
+	defer func() {
+		if err := im.setIPPoolFence(ctx, poolName, false); err != nil {
+			logger.Sugar().Errorf("Failed to unfence IPPool %s: %v", poolName, err)
+		}
+	}()
+
+	for _, name := range names {
+		if err := im.createSplitIPPool(ctx, ipPool, name); err != nil {
+			return err
+		}
+		if err := im.transferIPs(ctx, poolName, name, newIPs[name]); err != nil {
+			return err
+		}
+		logger.Sugar().Infof("Split IP ranges %v of IPPool %s into IPPool %s", splits[name], poolName, name)
+	}
+
+	return nil
+}
+
+// MergeIPPools moves all IP addresses of the sibling IPPools into the
+// IPPool, along with their allocation records, and then deletes the sibling
+// IPPools. The sibling IPPools must share the subnet of the IPPool.
+func (im *ipPoolManager) MergeIPPools(ctx context.Context, poolName string, siblings []string) error {
+	logger := logutils.FromContext(ctx)
+
+	ipPool, err := im.GetIPPoolByName(ctx, poolName)
+	if err != nil {
+		return err
+	}
+	if err := validateReshapableIPPool(ipPool); err != nil {
+		return err
+	}
+
+	for _, name := range siblings {
+		if name == poolName {
+			return fmt.Errorf("%w, IPPool %s can not be merged into itself", constant.ErrWrongInput, poolName)
+		}
+
+		sibling, err := im.GetIPPoolByName(ctx, name)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				logger.Sugar().Debugf("Sibling IPPool %s has been merged", name)
+				continue
+			}
+			return err
+		}
+		if sibling.DeletionTimestamp != nil {
+			logger.Sugar().Debugf("Sibling IPPool %s is terminating", name)
+			continue
+		}
+		if err := validateReshapableIPPool(sibling); err != nil {
+			return err
+		}
+		if sibling.Spec.Subnet != ipPool.Spec.Subnet || GetIPPoolTenant(sibling) != GetIPPoolTenant(ipPool) {
+			return fmt.Errorf("%w, IPPool %s is not a sibling of IPPool %s, they must share the subnet and tenant", constant.ErrWrongInput, name, poolName)
+		}
+
+		if err := im.mergeSiblingIPPool(ctx, sibling, poolName); err != nil {
+			return err
+		}
+		logger.Sugar().Infof("Merge IPPool %s into IPPool %s", name, poolName)
+	}
+
+	return nil
+}
+
+// mergeSiblingIPPool moves all IP addresses of the sibling IPPool into the
+// IPPool and deletes it. The sibling IPPool is fenced during the transfer,
+// and unfenced if the transfer fails.
+func (im *ipPoolManager) mergeSiblingIPPool(ctx context.Context, sibling *spiderpoolv1.SpiderIPPool, poolName string) error {
+	logger := logutils.FromContext(ctx)
+
+	ips, err := spiderpoolip.ParseIPRanges(*sibling.Spec.IPVersion, sibling.Spec.IPs)
+	if err != nil {
+		return err
+	}
+
+	if err := im.setIPPoolFence(ctx, sibling.Name, true); err != nil {
+		return err
+	}
+	defer func() {
+		if err := im.setIPPoolFence(ctx, sibling.Name, false); client.IgnoreNotFound(err) != nil {
+			logger.Sugar().Errorf("Failed to unfence IPPool %s: %v", sibling.Name, err)
+		}
+	}()
+
+	if err := im.transferIPs(ctx, sibling.Name, poolName, ips); err != nil {
+		return err
+	}
+
+	return client.IgnoreNotFound(im.client.Delete(ctx, sibling))
+}
+
+func validateReshapableIPPool(ipPool *spiderpoolv1.SpiderIPPool) error {
+	if ipPool.DeletionTimestamp != nil {
+		return fmt.Errorf("%w, terminating IPPool %s can not be split or merged", constant.ErrWrongInput, ipPool.Name)
+	}
+	if IsAutoCreatedIPPool(ipPool) || metav1.GetControllerOf(ipPool) != nil {
+		return fmt.Errorf("%w, IPPool %s is controlled by SpiderSubnet and can not be split or merged", constant.ErrWrongInput, ipPool.Name)
+	}
+
+	return nil
+}
+
+// createSplitIPPool creates an empty IPPool inheriting the spec of the
+// IPPool to be split, or reuses the one created by a previous attempt.
+func (im *ipPoolManager) createSplitIPPool(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, name string) error {
+	newIPPool := &spiderpoolv1.SpiderIPPool{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       *ipPool.Spec.DeepCopy(),
+	}
+	newIPPool.Spec.IPs = nil
+	newIPPool.Spec.ExcludeIPs = nil
+
+	if err := im.client.Create(ctx, newIPPool); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+
+		existing, err := im.GetIPPoolByName(ctx, name)
+		if err != nil {
+			return err
+		}
+		if existing.Spec.Subnet != ipPool.Spec.Subnet || GetIPPoolTenant(existing) != GetIPPoolTenant(ipPool) {
+			return fmt.Errorf("%w, IPPool %s already exists with a different subnet or tenant", constant.ErrWrongInput, name)
+		}
+	}
+
+	return nil
+}
+
+// ipPoolTransfer is the transfer of IP addresses into an IPPool, which is
+// recorded in the annotation "ipam.spidernet.io/ippool-transfer" of the
+// IPPool until the transfer is done.
+type ipPoolTransfer struct {
+	Src        string   `json:"src"`
+	IPs        []string `json:"ips"`
+	ExcludeIPs []string `json:"excludeIPs,omitempty"`
+	// RecordsMoved is set once the allocation records released from the
+	// src IPPool during the transfer have been dropped from the IPPool,
+	// before the records are removed from the src IPPool.
+	RecordsMoved bool `json:"recordsMoved,omitempty"`
+}
+
+func getIPPoolTransfer(pool *spiderpoolv1.SpiderIPPool) (*ipPoolTransfer, error) {
+	anno, ok := pool.Annotations[constant.AnnoIPPoolTransfer]
+	if !ok {
+		return nil, nil
+	}
+
+	var transfer ipPoolTransfer
+	if err := json.Unmarshal([]byte(anno), &transfer); err != nil {
+		return nil, fmt.Errorf("invalid format of annotation '%s' of IPPool %s: %v", constant.AnnoIPPoolTransfer, pool.Name, err)
+	}
+
+	return &transfer, nil
+}
+
+// setIPPoolTransfer records the transfer in the annotation of the IPPool,
+// or removes the annotation if transfer is nil.
+func setIPPoolTransfer(pool *spiderpoolv1.SpiderIPPool, transfer *ipPoolTransfer) error {
+	if transfer == nil {
+		delete(pool.Annotations, constant.AnnoIPPoolTransfer)
+		return nil
+	}
+
+	b, err := json.Marshal(transfer)
+	if err != nil {
+		return err
+	}
+	if pool.Annotations == nil {
+		pool.Annotations = map[string]string{}
+	}
+	pool.Annotations[constant.AnnoIPPoolTransfer] = string(b)
+
+	return nil
+}
+
+// transferIPs moves the IP addresses and their allocation records from the
+// src IPPool to the dst IPPool. The src IPPool must have been fenced, so that
+// no IP address is allocated from it during the transfer. The Endpoints of
+// the moved allocations are repointed to the dst IPPool, so that the IP
+// addresses are released from the right IPPool.
+//
+// The transfer is recorded in the annotation of the dst IPPool until it's
+// done, and each step is idempotent, so that a half-done transfer is resumed
+// with the same IP addresses once retried.
+func (im *ipPoolManager) transferIPs(ctx context.Context, src, dst string, ips []net.IP) error {
+	logger := logutils.FromContext(ctx)
+
+	srcPool, err := im.GetIPPoolByName(ctx, src)
+	if err != nil {
+		return err
+	}
+	dstPool, err := im.GetIPPoolByName(ctx, dst)
+	if err != nil {
+		return err
+	}
+
+	version := *srcPool.Spec.IPVersion
+	transfer, err := getIPPoolTransfer(dstPool)
+	if err != nil {
+		return err
+	}
+	if transfer != nil && transfer.Src != src {
+		return fmt.Errorf("%w, IPPool %s has a half-done transfer from IPPool %s, retry the split or merge of it first", constant.ErrWrongInput, dst, transfer.Src)
+	}
+
+	var moving, movingExcludeIPs []net.IP
+	if transfer != nil {
+		logger.Sugar().Infof("Resume the transfer of IP addresses %v from IPPool %s to IPPool %s", transfer.IPs, src, dst)
+		if moving, err = spiderpoolip.ParseIPRanges(version, transfer.IPs); err != nil {
+			return err
+		}
+		if movingExcludeIPs, err = spiderpoolip.ParseIPRanges(version, transfer.ExcludeIPs); err != nil {
+			return err
+		}
+	} else {
+		srcIPs, err := spiderpoolip.ParseIPRanges(version, srcPool.Spec.IPs)
+		if err != nil {
+			return err
+		}
+		moving = spiderpoolip.IPsIntersectionSet(srcIPs, ips, true)
+		if len(moving) == 0 {
+			return nil
+		}
+		srcExcludeIPs, err := spiderpoolip.ParseIPRanges(version, srcPool.Spec.ExcludeIPs)
+		if err != nil {
+			return err
+		}
+		movingExcludeIPs = spiderpoolip.IPsIntersectionSet(srcExcludeIPs, moving, false)
+
+		transfer = &ipPoolTransfer{Src: src}
+		if transfer.IPs, err = spiderpoolip.ConvertIPsToIPRanges(version, moving); err != nil {
+			return err
+		}
+		if transfer.ExcludeIPs, err = spiderpoolip.ConvertIPsToIPRanges(version, movingExcludeIPs); err != nil {
+			return err
+		}
+	}
+
+	movingSet := map[string]bool{}
+	for _, ip := range moving {
+		movingSet[ip.String()] = true
+	}
+	records := spiderpoolv1.PoolIPAllocations{}
+	for ip, allocation := range srcPool.Status.AllocatedIPs {
+		if movingSet[ip] {
+			records[ip] = allocation
+		}
+	}
+
+	// Hold the IP addresses in the dst IPPool as excluded ones, they can't
+	// be allocated from the dst IPPool until they leave the src IPPool.
+	err = im.updateIPPool(ctx, dst, false, func(pool *spiderpoolv1.SpiderIPPool) (bool, error) {
+		if pool.Spec.IPs, err = unionIPRanges(version, pool.Spec.IPs, moving); err != nil {
+			return false, err
+		}
+		if pool.Spec.ExcludeIPs, err = unionIPRanges(version, pool.Spec.ExcludeIPs, moving); err != nil {
+			return false, err
+		}
+		if _, ok := pool.Annotations[constant.AnnoIPPoolTransfer]; !ok {
+			if err := setIPPoolTransfer(pool, transfer); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to hold IP addresses in IPPool %s: %w", dst, err)
+	}
+
+	if !transfer.RecordsMoved {
+		if err := im.moveAllocationRecords(ctx, src, dst, records, movingSet); err != nil {
+			return err
+		}
+
+		transfer.RecordsMoved = true
+		err = im.updateIPPool(ctx, dst, false, func(pool *spiderpoolv1.SpiderIPPool) (bool, error) {
+			return true, setIPPoolTransfer(pool, transfer)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record the transfer of IPPool %s: %w", dst, err)
+		}
+	}
+
+	err = im.updateIPPool(ctx, src, true, func(pool *spiderpoolv1.SpiderIPPool) (bool, error) {
+		changed := false
+		for ip := range pool.Status.AllocatedIPs {
+			if movingSet[ip] {
+				delete(pool.Status.AllocatedIPs, ip)
+				changed = true
+			}
+		}
+		pool.Status.AllocatedIPCount = pointer.Int64(int64(len(pool.Status.AllocatedIPs)))
+		return changed, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove allocation records from IPPool %s: %w", src, err)
+	}
+
+	err = im.updateIPPool(ctx, src, false, func(pool *spiderpoolv1.SpiderIPPool) (bool, error) {
+		if pool.Spec.IPs, err = subtractIPRanges(version, pool.Spec.IPs, moving); err != nil {
+			return false, err
+		}
+		if pool.Spec.ExcludeIPs, err = subtractIPRanges(version, pool.Spec.ExcludeIPs, moving); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove IP addresses from IPPool %s: %w", src, err)
+	}
+
+	err = im.updateIPPool(ctx, dst, false, func(pool *spiderpoolv1.SpiderIPPool) (bool, error) {
+		excludeIPs, err := subtractIPRanges(version, pool.Spec.ExcludeIPs, moving)
+		if err != nil {
+			return false, err
+		}
+		if pool.Spec.ExcludeIPs, err = unionIPRanges(version, excludeIPs, movingExcludeIPs); err != nil {
+			return false, err
+		}
+		return true, setIPPoolTransfer(pool, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release the held IP addresses of IPPool %s: %w", dst, err)
+	}
+
+	logger.Sugar().Infof("Transfer %d IP addresses with %d allocation records from IPPool %s to IPPool %s", len(moving), len(records), src, dst)
+
+	return nil
+}
+
+// moveAllocationRecords copies the allocation records of the moving IP
+// addresses from the src IPPool to the dst IPPool, and repoints their
+// Endpoints to the dst IPPool. The records released from the src IPPool
+// before their Endpoints were repointed are stale in the dst IPPool, which
+// are dropped then. The records are left in the src IPPool.
+func (im *ipPoolManager) moveAllocationRecords(ctx context.Context, src, dst string, records spiderpoolv1.PoolIPAllocations, movingSet map[string]bool) error {
+	err := im.updateIPPool(ctx, dst, true, func(pool *spiderpoolv1.SpiderIPPool) (bool, error) {
+		if pool.Status.AllocatedIPs == nil {
+			pool.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{}
+		}
+		if pool.Status.AllocatedIPCount == nil {
+			pool.Status.AllocatedIPCount = new(int64)
+		}
+
+		changed := false
+		for ip, allocation := range records {
+			if _, ok := pool.Status.AllocatedIPs[ip]; !ok {
+				pool.Status.AllocatedIPs[ip] = allocation
+				*pool.Status.AllocatedIPCount++
+				changed = true
+			}
+		}
+		return changed, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy allocation records to IPPool %s: %w", dst, err)
+	}
+
+	if err := im.repointEndpoints(ctx, src, dst, records); err != nil {
+		return err
+	}
+
+	// The Endpoints have been repointed, the records are released from the
+	// dst IPPool since then, so the ones missing in the src IPPool now were
+	// released before.
+	srcPool, err := im.GetIPPoolByName(ctx, src)
+	if err != nil {
+		return err
+	}
+	err = im.updateIPPool(ctx, dst, true, func(pool *spiderpoolv1.SpiderIPPool) (bool, error) {
+		changed := false
+		for ip, allocation := range pool.Status.AllocatedIPs {
+			if !movingSet[ip] {
+				continue
+			}
+			if cur, ok := srcPool.Status.AllocatedIPs[ip]; !ok || cur.ContainerID != allocation.ContainerID {
+				delete(pool.Status.AllocatedIPs, ip)
+				*pool.Status.AllocatedIPCount--
+				changed = true
+			}
+		}
+		return changed, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clean released allocation records of IPPool %s: %w", dst, err)
+	}
+
+	return nil
+}
+
+// repointEndpoints changes the IPPool of the allocation records in the
+// Endpoints from the src IPPool to the dst IPPool. The Endpoints are patched
+// rather than updated, and the patches are retried if conflicts occur.
+func (im *ipPoolManager) repointEndpoints(ctx context.Context, src, dst string, records spiderpoolv1.PoolIPAllocations) error {
+	pods := map[apitypes.NamespacedName]map[string]bool{}
+	for ip, allocation := range records {
+		key := apitypes.NamespacedName{Namespace: allocation.Namespace, Name: allocation.Pod}
+		if pods[key] == nil {
+			pods[key] = map[string]bool{}
+		}
+		pods[key][ip] = true
+	}
+
+	dstPool, err := im.GetIPPoolByName(ctx, dst)
+	if err != nil {
+		return err
+	}
+	dstUID := string(dstPool.UID)
+
+	repoint := func(ip *string, pool, uid **string, ips map[string]bool) bool {
+		if ip == nil || *pool == nil || **pool != src {
+			return false
+		}
+		addr, _, err := net.ParseCIDR(*ip)
+		if err != nil || !ips[addr.String()] {
+			return false
+		}
+		*pool = &dst
+		*uid = &dstUID
+		return true
+	}
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for key, ips := range pods {
+		for i := 0; i <= im.config.MaxConflictRetries; i++ {
+			var endpoint spiderpoolv1.SpiderEndpoint
+			if err := im.client.Get(ctx, key, &endpoint); err != nil {
+				if apierrors.IsNotFound(err) {
+					break
+				}
+				return err
+			}
+			patch := client.MergeFromWithOptions(endpoint.DeepCopy(), client.MergeFromWithOptimisticLock{})
+
+			changed := false
+			for j := range endpoint.Status.History {
+				for k := range endpoint.Status.History[j].IPs {
+					d := &endpoint.Status.History[j].IPs[k]
+					changed = repoint(d.IPv4, &d.IPv4Pool, &d.IPv4PoolUID, ips) || changed
+					changed = repoint(d.IPv6, &d.IPv6Pool, &d.IPv6PoolUID, ips) || changed
+				}
+			}
+			if endpoint.Status.Current != nil {
+				for k := range endpoint.Status.Current.IPs {
+					d := &endpoint.Status.Current.IPs[k]
+					changed = repoint(d.IPv4, &d.IPv4Pool, &d.IPv4PoolUID, ips) || changed
+					changed = repoint(d.IPv6, &d.IPv6Pool, &d.IPv6PoolUID, ips) || changed
+				}
+			}
+			if !changed {
+				break
+			}
+
+			if err := im.client.Status().Patch(ctx, &endpoint, patch); err != nil {
+				if apierrors.IsNotFound(err) {
+					break
+				}
+				if !apierrors.IsConflict(err) {
+					return err
+				}
+				if i == im.config.MaxConflictRetries {
+					return fmt.Errorf("%w (%d times), failed to repoint Endpoint %s to IPPool %s", constant.ErrRetriesExhausted, im.config.MaxConflictRetries, key, dst)
+				}
+
+				time.Sleep(time.Duration(r.Intn(1<<(i+1))) * im.config.ConflictRetryUnitTime)
+				continue
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+// setIPPoolFence fences the IPPool off from IP allocation or unfences it.
+func (im *ipPoolManager) setIPPoolFence(ctx context.Context, poolName string, fenced bool) error {
+	return im.updateIPPool(ctx, poolName, false, func(pool *spiderpoolv1.SpiderIPPool) (bool, error) {
+		if IsReshapingIPPool(pool) == fenced {
+			return false, nil
+		}
+
+		if fenced {
+			if pool.Annotations == nil {
+				pool.Annotations = map[string]string{}
+			}
+			pool.Annotations[constant.AnnoIPPoolReshaping] = constant.True
+		} else {
+			delete(pool.Annotations, constant.AnnoIPPoolReshaping)
+		}
+		return true, nil
+	})
+}
+
+// updateIPPool applies the mutation to the latest IPPool and updates it, or
+// its status if status is true. It's retried if conflicts occur.
+func (im *ipPoolManager) updateIPPool(ctx context.Context, poolName string, status bool, mutate func(pool *spiderpoolv1.SpiderIPPool) (bool, error)) error {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i <= im.config.MaxConflictRetries; i++ {
+		ipPool, err := im.GetIPPoolByName(ctx, poolName)
+		if err != nil {
+			return err
+		}
+
+		changed, err := mutate(ipPool)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			return nil
+		}
+
+		if status {
+			err = im.client.Status().Update(ctx, ipPool)
+		} else {
+			err = im.client.Update(ctx, ipPool)
+		}
+		if err != nil {
+			if !apierrors.IsConflict(err) {
+				return err
+			}
+			if i == im.config.MaxConflictRetries {
+				return fmt.Errorf("%w (%d times), failed to update IPPool %s", constant.ErrRetriesExhausted, im.config.MaxConflictRetries, poolName)
+			}
+
+			time.Sleep(time.Duration(r.Intn(1<<(i+1))) * im.config.ConflictRetryUnitTime)
+			continue
+		}
+		break
+	}
+
+	return nil
+}
+
+func unionIPRanges(version types.IPVersion, ipRanges []string, ips []net.IP) ([]string, error) {
+	cur, err := spiderpoolip.ParseIPRanges(version, ipRanges)
+	if err != nil {
+		return nil, err
+	}
+
+	return spiderpoolip.ConvertIPsToIPRanges(version, spiderpoolip.IPsUnionSet(cur, ips, false))
+}
+
+func subtractIPRanges(version types.IPVersion, ipRanges []string, ips []net.IP) ([]string, error) {
+	cur, err := spiderpoolip.ParseIPRanges(version, ipRanges)
+	if err != nil {
+		return nil, err
+	}
+
+	return spiderpoolip.ConvertIPsToIPRanges(version, spiderpoolip.IPsDiffSet(cur, ips, false))
+}
+
+func hasIPPoolReshapeRequest(pool *spiderpoolv1.SpiderIPPool) bool {
+	_, split := pool.Annotations[constant.AnnoIPPoolSplit]
+	_, merge := pool.Annotations[constant.AnnoIPPoolMerge]
+	return split || merge
+}
+
+// reshapeIPPool splits the IPPool or merges its siblings into it as requested
+// by its annotations, the annotations are removed once the request is done
+// or turns out to be invalid.
+func (ic *IPPoolController) reshapeIPPool(ctx context.Context, pool *spiderpoolv1.SpiderIPPool) error {
+	log := informerLogger.With(zap.String("IPPool", pool.Name))
+	ctx = logutils.IntoContext(ctx, log)
+
+	var action string
+	var err error
+	if anno, ok := pool.Annotations[constant.AnnoIPPoolSplit]; ok {
+		splits := map[string][]string{}
+		if err = json.Unmarshal([]byte(anno), &splits); err != nil {
+			err = fmt.Errorf("%w, invalid format of annotation '%s': %v", constant.ErrWrongInput, constant.AnnoIPPoolSplit, err)
+		} else {
+			err = ic.ipPoolManager.SplitIPPool(ctx, pool.Name, splits)
+		}
+		action = fmt.Sprintf("Split with %s", anno)
+	} else {
+		var siblings []string
+		for _, name := range strings.Split(pool.Annotations[constant.AnnoIPPoolMerge], ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				siblings = append(siblings, name)
+			}
+		}
+		err = ic.ipPoolManager.MergeIPPools(ctx, pool.Name, siblings)
+		action = fmt.Sprintf("Merge IPPools %v", siblings)
+	}
+
+	if err != nil {
+		if !errors.Is(err, constant.ErrWrongInput) {
+			return err
+		}
+		log.Sugar().Errorf("%s failed: %v", action, err)
+		event.EventRecorder.Eventf(pool, corev1.EventTypeWarning, constant.EventReasonReshapeIPPool, "%s failed: %v", action, err)
+	} else {
+		log.Sugar().Infof("%s successfully", action)
+		event.EventRecorder.Eventf(pool, corev1.EventTypeNormal, constant.EventReasonReshapeIPPool, "%s successfully", action)
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := ic.ipPoolManager.GetIPPoolByName(ctx, pool.Name)
+		if err != nil {
+			return err
+		}
+		if !hasIPPoolReshapeRequest(latest) {
+			return nil
+		}
+		delete(latest.Annotations, constant.AnnoIPPoolSplit)
+		delete(latest.Annotations, constant.AnnoIPPoolMerge)
+
+		return ic.client.Update(ctx, latest)
+	})
+
+	return client.IgnoreNotFound(err)
+}
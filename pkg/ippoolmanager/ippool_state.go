@@ -0,0 +1,189 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+// IPPoolAllocationState is the exported allocation map of an IPPool, which
+// is imported into the IPPool with the same subnet in another cluster, so
+// that the Pods keep their IP addresses after the migration.
+type IPPoolAllocationState struct {
+	IPPool       string                         `json:"ippool"`
+	IPVersion    int64                          `json:"ipVersion"`
+	Subnet       string                         `json:"subnet"`
+	AllocatedIPs spiderpoolv1.PoolIPAllocations `json:"allocatedIPs"`
+}
+
+// ExportIPAllocations returns the full allocation map of the IPPool.
+func (im *ipPoolManager) ExportIPAllocations(ctx context.Context, poolName string) (*IPPoolAllocationState, error) {
+	ipPool, err := im.GetIPPoolByName(ctx, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewIPPoolAllocationState(ipPool), nil
+}
+
+// NewIPPoolAllocationState snapshots the allocation map of the IPPool.
+func NewIPPoolAllocationState(ipPool *spiderpoolv1.SpiderIPPool) *IPPoolAllocationState {
+	state := &IPPoolAllocationState{
+		IPPool:       ipPool.Name,
+		Subnet:       ipPool.Spec.Subnet,
+		AllocatedIPs: spiderpoolv1.PoolIPAllocations{},
+	}
+	if ipPool.Spec.IPVersion != nil {
+		state.IPVersion = *ipPool.Spec.IPVersion
+	}
+	for ip, allocation := range ipPool.Status.AllocatedIPs {
+		state.AllocatedIPs[ip] = allocation
+	}
+
+	return state
+}
+
+// ImportIPAllocations records the exported IP allocations in the IPPool. The
+// IPPool must have the same IP version and subnet as the exported one, and
+// each IP address must be a free one of the IPPool, or has been imported
+// with the same container ID before, so that importing is idempotent.
+func (im *ipPoolManager) ImportIPAllocations(ctx context.Context, poolName string, state *IPPoolAllocationState) error {
+	logger := logutils.FromContext(ctx)
+
+	if state == nil {
+		return fmt.Errorf("%w, allocation state to import is nil", constant.ErrWrongInput)
+	}
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i <= im.config.MaxConflictRetries; i++ {
+		logger := logger.With(zap.Int("times", i+1))
+		logger.Sugar().Debugf("Re-get IPPool %s for importing IP allocations", poolName)
+
+		ipPool, err := im.GetIPPoolByName(ctx, poolName)
+		if err != nil {
+			return err
+		}
+
+		imported, err := im.validateIPAllocationState(ctx, ipPool, state)
+		if err != nil {
+			return err
+		}
+		if len(imported) == 0 {
+			return nil
+		}
+
+		if ipPool.Status.AllocatedIPs == nil {
+			ipPool.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{}
+		}
+		if ipPool.Status.AllocatedIPCount == nil {
+			ipPool.Status.AllocatedIPCount = new(int64)
+		}
+		for _, ip := range imported {
+			ipPool.Status.AllocatedIPs[ip] = state.AllocatedIPs[ip]
+		}
+		*ipPool.Status.AllocatedIPCount += int64(len(imported))
+
+		resourceVersion := ipPool.ResourceVersion
+		if err := im.client.Status().Update(ctx, ipPool); err != nil {
+			if !apierrors.IsConflict(err) {
+				return err
+			}
+			if i == im.config.MaxConflictRetries {
+				return fmt.Errorf("%w (%d times), failed to import IP allocations into IPPool %s", constant.ErrRetriesExhausted, im.config.MaxConflictRetries, poolName)
+			}
+
+			interval := time.Duration(r.Intn(1<<(i+1))) * im.config.ConflictRetryUnitTime
+			logger.Sugar().Debugf("An conflict occurred when importing IP allocations into IPPool %s, it will be retried in %s", poolName, interval)
+
+			time.Sleep(interval)
+			continue
+		}
+		im.freeIPs.Update(ipPool.Name, resourceVersion, ipPool.ResourceVersion, imported, true)
+		logger.Sugar().Infof("Import %d IP allocations into IPPool %s", len(imported), poolName)
+		break
+	}
+
+	return nil
+}
+
+// validateIPAllocationState checks the exported IP allocations against the
+// IPPool, and returns the IP addresses to be imported in order.
+func (im *ipPoolManager) validateIPAllocationState(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, state *IPPoolAllocationState) ([]string, error) {
+	if IsReshapingIPPool(ipPool) {
+		return nil, fmt.Errorf("IPPool %s is being split or merged", ipPool.Name)
+	}
+	if ipPool.Spec.IPVersion == nil || *ipPool.Spec.IPVersion != state.IPVersion {
+		return nil, fmt.Errorf("%w, IP version %d of the allocation state mismatches IPPool %s", constant.ErrWrongInput, state.IPVersion, ipPool.Name)
+	}
+
+	subnet, err := spiderpoolip.ParseCIDR(state.IPVersion, state.Subnet)
+	if err != nil {
+		return nil, fmt.Errorf("%w, invalid subnet of the allocation state: %v", constant.ErrWrongInput, err)
+	}
+	poolSubnet, err := spiderpoolip.ParseCIDR(state.IPVersion, ipPool.Spec.Subnet)
+	if err != nil {
+		return nil, err
+	}
+	if subnet.String() != poolSubnet.String() {
+		return nil, fmt.Errorf("%w, subnet %s of the allocation state mismatches subnet %s of IPPool %s", constant.ErrWrongInput, state.Subnet, ipPool.Spec.Subnet, ipPool.Name)
+	}
+
+	totalIPs, err := spiderpoolip.AssembleTotalIPs(state.IPVersion, ipPool.Spec.IPs, ipPool.Spec.ExcludeIPs)
+	if err != nil {
+		return nil, err
+	}
+	total := make(map[string]struct{}, len(totalIPs))
+	for _, ip := range totalIPs {
+		total[ip.String()] = struct{}{}
+	}
+
+	reservedIPs, err := im.rIPManager.AssembleScopedReservedIPs(ctx, state.IPVersion, ipPool, "")
+	if err != nil {
+		return nil, err
+	}
+	reserved := make(map[string]struct{}, len(reservedIPs))
+	for _, ip := range reservedIPs {
+		reserved[ip.String()] = struct{}{}
+	}
+
+	var imported []string
+	for ip, allocation := range state.AllocatedIPs {
+		if err := spiderpoolip.IsIP(state.IPVersion, ip); err != nil {
+			return nil, fmt.Errorf("%w, invalid IP address '%s' of the allocation state", constant.ErrWrongInput, ip)
+		}
+		if allocation.ContainerID == "" {
+			return nil, fmt.Errorf("%w, IP address %s of the allocation state has no container ID", constant.ErrWrongInput, ip)
+		}
+		if _, ok := total[net.ParseIP(ip).String()]; !ok {
+			return nil, fmt.Errorf("%w, IP address %s of the allocation state is not in IPPool %s", constant.ErrWrongInput, ip, ipPool.Name)
+		}
+		if _, ok := reserved[net.ParseIP(ip).String()]; ok {
+			return nil, fmt.Errorf("%w, IP address %s of the allocation state is reserved", constant.ErrWrongInput, ip)
+		}
+
+		if record, ok := ipPool.Status.AllocatedIPs[ip]; ok {
+			if record.ContainerID != allocation.ContainerID {
+				return nil, fmt.Errorf("%w, IP address %s of the allocation state has been allocated to Pod %s/%s in IPPool %s", constant.ErrWrongInput, ip, record.Namespace, record.Pod, ipPool.Name)
+			}
+			continue
+		}
+		imported = append(imported, ip)
+	}
+	sort.Strings(imported)
+
+	return imported, nil
+}
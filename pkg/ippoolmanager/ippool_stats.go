@@ -0,0 +1,170 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/lock"
+)
+
+const (
+	// AllocationStatsBucketDuration is the granularity of the time windows
+	// of allocation statistics.
+	AllocationStatsBucketDuration = time.Minute
+	// MaxAllocationStatsWindow is the longest time window of allocation
+	// statistics.
+	MaxAllocationStatsWindow = 24 * time.Hour
+
+	allocationStatsBuckets = int(MaxAllocationStatsWindow / AllocationStatsBucketDuration)
+)
+
+// AllocationStats keeps the counts of IP allocations and releases of each
+// IPPool and Namespace in a ring of one-minute buckets, which covers the
+// last 24 hours. They are observed from the changes of 'status.allocatedIPs'
+// of IPPools.
+type AllocationStats struct {
+	lock    lock.RWMutex
+	buckets [allocationStatsBuckets]allocationStatsBucket
+}
+
+type allocationStatsBucket struct {
+	// start is the beginning of the bucket, in the unit of bucket duration.
+	start  int64
+	counts map[allocationStatsKey]*allocationCounts
+}
+
+type allocationStatsKey struct {
+	pool      string
+	namespace string
+}
+
+type allocationCounts struct {
+	allocations int64
+	releases    int64
+}
+
+// AllocationStatsItem is the statistics of an IPPool in a Namespace over a
+// time window.
+type AllocationStatsItem struct {
+	Pool        string
+	Namespace   string
+	Allocations int64
+	Releases    int64
+}
+
+func NewAllocationStats() *AllocationStats {
+	return &AllocationStats{}
+}
+
+// Record adds the counts of IP allocations and releases of the IPPool in the
+// Namespace at time t.
+func (s *AllocationStats) Record(pool, namespace string, allocations, releases int64, t time.Time) {
+	if allocations == 0 && releases == 0 {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	start := t.UnixNano() / int64(AllocationStatsBucketDuration)
+	b := &s.buckets[start%int64(allocationStatsBuckets)]
+	if b.start != start || b.counts == nil {
+		b.start = start
+		b.counts = map[allocationStatsKey]*allocationCounts{}
+	}
+
+	key := allocationStatsKey{pool: pool, namespace: namespace}
+	c, ok := b.counts[key]
+	if !ok {
+		c = &allocationCounts{}
+		b.counts[key] = c
+	}
+	c.allocations += allocations
+	c.releases += releases
+}
+
+// RecordIPPoolChange records the IP allocations and releases made between
+// the two versions of the IPPool.
+func (s *AllocationStats) RecordIPPoolChange(oldIPPool, newIPPool *spiderpoolv1.SpiderIPPool, t time.Time) {
+	counts := map[string]*allocationCounts{}
+	get := func(namespace string) *allocationCounts {
+		c, ok := counts[namespace]
+		if !ok {
+			c = &allocationCounts{}
+			counts[namespace] = c
+		}
+		return c
+	}
+
+	for ip, n := range newIPPool.Status.AllocatedIPs {
+		o, ok := oldIPPool.Status.AllocatedIPs[ip]
+		if ok && o.ContainerID == n.ContainerID {
+			continue
+		}
+		get(n.Namespace).allocations++
+		if ok {
+			get(o.Namespace).releases++
+		}
+	}
+	for ip, o := range oldIPPool.Status.AllocatedIPs {
+		if _, ok := newIPPool.Status.AllocatedIPs[ip]; !ok {
+			get(o.Namespace).releases++
+		}
+	}
+
+	for namespace, c := range counts {
+		s.Record(newIPPool.Name, namespace, c.allocations, c.releases, t)
+	}
+}
+
+// Query returns the statistics over the time window ending at now, which
+// are filtered by the IPPool and Namespace if they are not empty.
+func (s *AllocationStats) Query(window time.Duration, pool, namespace string, now time.Time) ([]AllocationStatsItem, error) {
+	if window < AllocationStatsBucketDuration || window > MaxAllocationStatsWindow {
+		return nil, fmt.Errorf("time window %s is out of range [%s, %s]", window, AllocationStatsBucketDuration, MaxAllocationStatsWindow)
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	end := now.UnixNano() / int64(AllocationStatsBucketDuration)
+	begin := end - int64(window/AllocationStatsBucketDuration) + 1
+
+	sum := map[allocationStatsKey]*AllocationStatsItem{}
+	for i := range s.buckets {
+		b := &s.buckets[i]
+		if b.counts == nil || b.start < begin || b.start > end {
+			continue
+		}
+		for key, c := range b.counts {
+			if (pool != "" && key.pool != pool) || (namespace != "" && key.namespace != namespace) {
+				continue
+			}
+			item, ok := sum[key]
+			if !ok {
+				item = &AllocationStatsItem{Pool: key.pool, Namespace: key.namespace}
+				sum[key] = item
+			}
+			item.Allocations += c.allocations
+			item.Releases += c.releases
+		}
+	}
+
+	items := make([]AllocationStatsItem, 0, len(sum))
+	for _, item := range sum {
+		items = append(items, *item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Pool != items[j].Pool {
+			return items[i].Pool < items[j].Pool
+		}
+		return items[i].Namespace < items[j].Namespace
+	})
+
+	return items, nil
+}
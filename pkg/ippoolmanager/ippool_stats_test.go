@@ -0,0 +1,88 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+)
+
+var _ = Describe("AllocationStats", Label("ippool_stats_test"), func() {
+	var stats *ippoolmanager.AllocationStats
+	var now time.Time
+
+	BeforeEach(func() {
+		stats = ippoolmanager.NewAllocationStats()
+		now = time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	})
+
+	It("inputs invalid time window", func() {
+		_, err := stats.Query(time.Second, "", "", now)
+		Expect(err).To(HaveOccurred())
+
+		_, err = stats.Query(48*time.Hour, "", "", now)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("sums up the counts in the time window", func() {
+		stats.Record("pool", "default", 1, 0, now.Add(-2*time.Hour))
+		stats.Record("pool", "default", 2, 1, now.Add(-30*time.Minute))
+		stats.Record("pool", "default", 3, 2, now)
+		stats.Record("pool", "kube-system", 1, 1, now)
+
+		items, err := stats.Query(time.Hour, "", "", now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(items).To(Equal([]ippoolmanager.AllocationStatsItem{
+			{Pool: "pool", Namespace: "default", Allocations: 5, Releases: 3},
+			{Pool: "pool", Namespace: "kube-system", Allocations: 1, Releases: 1},
+		}))
+
+		items, err = stats.Query(24*time.Hour, "pool", "default", now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(items).To(Equal([]ippoolmanager.AllocationStatsItem{
+			{Pool: "pool", Namespace: "default", Allocations: 6, Releases: 3},
+		}))
+	})
+
+	It("drops the counts older than 24 hours", func() {
+		stats.Record("pool", "default", 1, 0, now.Add(-24*time.Hour))
+		stats.Record("pool", "default", 1, 0, now)
+
+		items, err := stats.Query(24*time.Hour, "", "", now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(items).To(HaveLen(1))
+		Expect(items[0].Allocations).To(BeEquivalentTo(1))
+	})
+
+	It("records the changes of IPPool", func() {
+		oldIPPool := &spiderpoolv1.SpiderIPPool{
+			ObjectMeta: metav1.ObjectMeta{Name: "pool"},
+			Status: spiderpoolv1.IPPoolStatus{
+				AllocatedIPs: spiderpoolv1.PoolIPAllocations{
+					"172.18.40.1": {ContainerID: "a", Namespace: "default"},
+					"172.18.40.2": {ContainerID: "b", Namespace: "default"},
+				},
+			},
+		}
+		newIPPool := oldIPPool.DeepCopy()
+		delete(newIPPool.Status.AllocatedIPs, "172.18.40.1")
+		newIPPool.Status.AllocatedIPs["172.18.40.2"] = spiderpoolv1.PoolIPAllocation{ContainerID: "c", Namespace: "kube-system"}
+		newIPPool.Status.AllocatedIPs["172.18.40.3"] = spiderpoolv1.PoolIPAllocation{ContainerID: "d", Namespace: "default"}
+
+		stats.RecordIPPoolChange(oldIPPool, newIPPool, now)
+
+		items, err := stats.Query(time.Minute, "pool", "", now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(items).To(Equal([]ippoolmanager.AllocationStatsItem{
+			{Pool: "pool", Namespace: "default", Allocations: 1, Releases: 2},
+			{Pool: "pool", Namespace: "kube-system", Allocations: 1, Releases: 0},
+		}))
+	})
+})
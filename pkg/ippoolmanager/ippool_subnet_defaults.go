@@ -0,0 +1,115 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/event"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+)
+
+// applySubnetDefaults copies the gateway, VLAN and default affinities of the
+// Subnet into the auto-created IPPool, and returns the names of the changed
+// fields.
+func applySubnetDefaults(pool *spiderpoolv1.SpiderIPPool, subnet *spiderpoolv1.SpiderSubnet) []string {
+	var changed []string
+	if !reflect.DeepEqual(pool.Spec.Gateway, subnet.Spec.Gateway) {
+		changed = append(changed, "gateway")
+		if subnet.Spec.Gateway == nil {
+			pool.Spec.Gateway = nil
+		} else {
+			gateway := *subnet.Spec.Gateway
+			pool.Spec.Gateway = &gateway
+		}
+	}
+	if !reflect.DeepEqual(pool.Spec.Vlan, subnet.Spec.Vlan) {
+		changed = append(changed, "vlan")
+		if subnet.Spec.Vlan == nil {
+			pool.Spec.Vlan = nil
+		} else {
+			vlan := *subnet.Spec.Vlan
+			pool.Spec.Vlan = &vlan
+		}
+	}
+	if !reflect.DeepEqual(pool.Spec.NamespaceAffinity, subnet.Spec.DefaultNamespaceAffinity) {
+		changed = append(changed, "namespaceAffinity")
+		pool.Spec.NamespaceAffinity = subnet.Spec.DefaultNamespaceAffinity.DeepCopy()
+	}
+	if !reflect.DeepEqual(pool.Spec.NodeAffinity, subnet.Spec.DefaultNodeAffinity) {
+		changed = append(changed, "nodeAffinity")
+		pool.Spec.NodeAffinity = subnet.Spec.DefaultNodeAffinity.DeepCopy()
+	}
+
+	return changed
+}
+
+// syncAutoIPPoolSubnetDefaults keeps the gateway, VLAN and affinities of the
+// auto-created IPPool in sync with its controller Subnet, so that changing
+// them once on the Subnet updates all the IPPools of the applications.
+func (ic *IPPoolController) syncAutoIPPoolSubnetDefaults(ctx context.Context, pool *spiderpoolv1.SpiderIPPool) error {
+	subnetName, ok := pool.Labels[constant.LabelIPPoolOwnerSpiderSubnet]
+	if !ok {
+		return nil
+	}
+
+	subnet, err := ic.subnetsLister.Get(subnetName)
+	if nil != err {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	changed := applySubnetDefaults(pool, subnet)
+	if len(changed) == 0 {
+		return nil
+	}
+
+	err = ic.client.Update(ctx, pool)
+	if nil != err {
+		return fmt.Errorf("failed to sync the defaults of SpiderSubnet '%s' to auto-created IPPool '%s': %w", subnet.Name, pool.Name, err)
+	}
+
+	informerLogger.Sugar().Infof("sync %v of SpiderSubnet '%s' to auto-created IPPool '%s'", changed, subnet.Name, pool.Name)
+	event.EventRecorder.Eventf(pool, corev1.EventTypeNormal, constant.EventReasonSyncSubnetDefaults,
+		"Synced %s with SpiderSubnet %s", strings.Join(changed, ", "), subnet.Name)
+
+	return nil
+}
+
+// syncSubnetDefaultsIPPools enqueues the auto-created IPPools controlled by the
+// Subnet once its gateway, VLAN or default affinities are changed.
+func (ic *IPPoolController) syncSubnetDefaultsIPPools(oldObj, newObj interface{}) {
+	oldSubnet := oldObj.(*spiderpoolv1.SpiderSubnet)
+	newSubnet := newObj.(*spiderpoolv1.SpiderSubnet)
+	if reflect.DeepEqual(oldSubnet.Spec.Gateway, newSubnet.Spec.Gateway) &&
+		reflect.DeepEqual(oldSubnet.Spec.Vlan, newSubnet.Spec.Vlan) &&
+		reflect.DeepEqual(oldSubnet.Spec.DefaultNamespaceAffinity, newSubnet.Spec.DefaultNamespaceAffinity) &&
+		reflect.DeepEqual(oldSubnet.Spec.DefaultNodeAffinity, newSubnet.Spec.DefaultNodeAffinity) {
+		return
+	}
+
+	selector := labels.Set{constant.LabelIPPoolOwnerSpiderSubnet: newSubnet.Name}.AsSelector()
+	ipPools, err := ic.poolLister.List(selector)
+	if nil != err {
+		informerLogger.Sugar().Errorf("syncSubnetDefaultsIPPools error: %v", err)
+		return
+	}
+
+	for _, pool := range ipPools {
+		if IsAutoCreatedIPPool(pool) {
+			informerLogger.Sugar().Debugf("try to add IPPool %s to sync the defaults of SpiderSubnet %s", pool.Name, newSubnet.Name)
+			ic.enqueueIPPool(pool)
+		}
+	}
+}
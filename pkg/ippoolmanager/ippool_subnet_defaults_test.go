@@ -0,0 +1,139 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	listers "github.com/spidernet-io/spiderpool/pkg/k8s/client/listers/spiderpool.spidernet.io/v1"
+)
+
+var _ = Describe("SpiderSubnet defaults", Label("ippool_subnet_defaults_test"), func() {
+	var subnet *spiderpoolv1.SpiderSubnet
+	var pool *spiderpoolv1.SpiderIPPool
+	BeforeEach(func() {
+		subnet = &spiderpoolv1.SpiderSubnet{ObjectMeta: metav1.ObjectMeta{Name: "subnet"}}
+		subnet.Spec.Gateway = pointer.String("172.18.40.1")
+		subnet.Spec.Vlan = pointer.Int64(100)
+		subnet.Spec.DefaultNamespaceAffinity = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}
+		subnet.Spec.DefaultNodeAffinity = &metav1.LabelSelector{MatchLabels: map[string]string{"zone": "a"}}
+
+		pool = &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{
+			Name: "auto-pool",
+			Labels: map[string]string{
+				constant.LabelIPPoolOwnerSpiderSubnet: subnet.Name,
+				constant.LabelIPPoolOwnerApplication:  "Deployment_default_app",
+			},
+		}}
+	})
+
+	Describe("applySubnetDefaults", func() {
+		It("copies the defaults of the SpiderSubnet", func() {
+			changed := applySubnetDefaults(pool, subnet)
+			Expect(changed).To(Equal([]string{"gateway", "vlan", "namespaceAffinity", "nodeAffinity"}))
+			Expect(pool.Spec.Gateway).To(Equal(subnet.Spec.Gateway))
+			Expect(pool.Spec.Vlan).To(Equal(subnet.Spec.Vlan))
+			Expect(pool.Spec.NamespaceAffinity).To(Equal(subnet.Spec.DefaultNamespaceAffinity))
+			Expect(pool.Spec.NodeAffinity).To(Equal(subnet.Spec.DefaultNodeAffinity))
+
+			*subnet.Spec.Gateway = "172.18.40.254"
+			subnet.Spec.DefaultNodeAffinity.MatchLabels["zone"] = "b"
+			Expect(*pool.Spec.Gateway).To(Equal("172.18.40.1"))
+			Expect(pool.Spec.NodeAffinity.MatchLabels).To(HaveKeyWithValue("zone", "a"))
+		})
+
+		It("changes nothing once in sync", func() {
+			applySubnetDefaults(pool, subnet)
+			Expect(applySubnetDefaults(pool, subnet)).To(BeEmpty())
+		})
+
+		It("clears the defaults removed from the SpiderSubnet", func() {
+			applySubnetDefaults(pool, subnet)
+			subnet.Spec.Vlan = nil
+			subnet.Spec.DefaultNamespaceAffinity = nil
+			Expect(applySubnetDefaults(pool, subnet)).To(Equal([]string{"vlan", "namespaceAffinity"}))
+			Expect(pool.Spec.Vlan).To(BeNil())
+			Expect(pool.Spec.NamespaceAffinity).To(BeNil())
+		})
+	})
+
+	Describe("IPPoolController", func() {
+		var ic *IPPoolController
+		var fakeClient client.Client
+		var poolIndexer, subnetIndexer cache.Indexer
+		JustBeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(spiderpoolv1.AddToScheme(scheme)).To(Succeed())
+			fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(pool).Build()
+			Expect(fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(pool), pool)).To(Succeed())
+
+			poolIndexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			Expect(poolIndexer.Add(pool)).To(Succeed())
+			subnetIndexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			Expect(subnetIndexer.Add(subnet)).To(Succeed())
+
+			ic = NewIPPoolController(IPPoolControllerConfig{MaxWorkqueueLength: 10}, fakeClient, nil, nil, nil)
+			ic.poolLister = listers.NewSpiderIPPoolLister(poolIndexer)
+			ic.subnetsLister = listers.NewSpiderSubnetLister(subnetIndexer)
+			ic.normalPoolWorkQueue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Normal-SpiderIPPools")
+			ic.v4AutoPoolWorkQueue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "AutoCreated-SpiderIPPools-IPv4")
+			DeferCleanup(ic.normalPoolWorkQueue.ShutDown)
+			DeferCleanup(ic.v4AutoPoolWorkQueue.ShutDown)
+		})
+
+		It("syncs the defaults of the SpiderSubnet to the auto-created IPPool", func() {
+			Expect(ic.syncAutoIPPoolSubnetDefaults(context.TODO(), pool)).To(Succeed())
+
+			var updated spiderpoolv1.SpiderIPPool
+			Expect(fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(pool), &updated)).To(Succeed())
+			Expect(updated.Spec.Gateway).To(Equal(subnet.Spec.Gateway))
+			Expect(updated.Spec.NodeAffinity).To(Equal(subnet.Spec.DefaultNodeAffinity))
+		})
+
+		It("skips the IPPool whose SpiderSubnet is gone", func() {
+			Expect(subnetIndexer.Delete(subnet)).To(Succeed())
+			Expect(ic.syncAutoIPPoolSubnetDefaults(context.TODO(), pool)).To(Succeed())
+			Expect(pool.Spec.Gateway).To(BeNil())
+		})
+
+		It("skips the IPPool without SpiderSubnet", func() {
+			delete(pool.Labels, constant.LabelIPPoolOwnerSpiderSubnet)
+			Expect(ic.syncAutoIPPoolSubnetDefaults(context.TODO(), pool)).To(Succeed())
+			Expect(pool.Spec.Gateway).To(BeNil())
+		})
+
+		Describe("syncSubnetDefaultsIPPools", func() {
+			BeforeEach(func() {
+				pool.Spec.IPVersion = pointer.Int64(constant.IPv4)
+				pool.Status.AutoDesiredIPCount = pointer.Int64(1)
+			})
+
+			It("enqueues the auto-created IPPools once the defaults are changed", func() {
+				newSubnet := subnet.DeepCopy()
+				newSubnet.Spec.Vlan = pointer.Int64(200)
+				ic.syncSubnetDefaultsIPPools(subnet, newSubnet)
+				Expect(ic.v4AutoPoolWorkQueue.Len()).To(Equal(1))
+			})
+
+			It("enqueues nothing if the defaults are not changed", func() {
+				newSubnet := subnet.DeepCopy()
+				newSubnet.Spec.IPs = []string{"172.18.40.1-172.18.40.10"}
+				ic.syncSubnetDefaultsIPPools(subnet, newSubnet)
+				Expect(ic.v4AutoPoolWorkQueue.Len()).To(BeZero())
+			})
+		})
+	})
+})
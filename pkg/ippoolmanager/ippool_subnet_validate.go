@@ -25,7 +25,7 @@ func (iw *IPPoolWebhook) validateCreateIPPoolWhileEnableSpiderSubnet(ctx context
 		if err != nil {
 			return field.ErrorList{err}
 		}
-		if err := validateSubnetTotalIPsContainsIPPoolTotalIPs(subnet, ipPool); err != nil {
+		if err := validateSubnetTotalIPsContainsIPPoolTotalIPs(subnet, nil, ipPool); err != nil {
 			return field.ErrorList{err}
 		}
 	}
@@ -43,7 +43,7 @@ func (iw *IPPoolWebhook) validateUpdateIPPoolWhileEnableSpiderSubnet(ctx context
 		if err != nil {
 			return field.ErrorList{err}
 		}
-		if err := validateSubnetTotalIPsContainsIPPoolTotalIPs(subnet, newIPPool); err != nil {
+		if err := validateSubnetTotalIPsContainsIPPoolTotalIPs(subnet, oldIPPool, newIPPool); err != nil {
 			return field.ErrorList{err}
 		}
 
@@ -76,7 +76,10 @@ func (iw *IPPoolWebhook) validateSubnetControllerExist(ctx context.Context, ipPo
 	return &subnet, nil
 }
 
-func validateSubnetTotalIPsContainsIPPoolTotalIPs(subnet *spiderpoolv1.SpiderSubnet, ipPool *spiderpoolv1.SpiderIPPool) *field.Error {
+// validateSubnetTotalIPsContainsIPPoolTotalIPs checks the IP addresses added to
+// the IPPool, the ones excluded by the Subnet after being added are kept until
+// they're vacated.
+func validateSubnetTotalIPsContainsIPPoolTotalIPs(subnet *spiderpoolv1.SpiderSubnet, oldIPPool, ipPool *spiderpoolv1.SpiderIPPool) *field.Error {
 	poolTotalIPs, err := spiderpoolip.AssembleTotalIPs(*ipPool.Spec.IPVersion, ipPool.Spec.IPs, ipPool.Spec.ExcludeIPs)
 	if err != nil {
 		return field.InternalError(ipsField, fmt.Errorf("failed to assemble the total IP addresses of the IPPool %s: %v", ipPool.Name, err))
@@ -87,6 +90,13 @@ func validateSubnetTotalIPsContainsIPPoolTotalIPs(subnet *spiderpoolv1.SpiderSub
 	}
 
 	outIPs := spiderpoolip.IPsDiffSet(poolTotalIPs, subnetTotalIPs, false)
+	if oldIPPool != nil && len(outIPs) > 0 {
+		oldPoolTotalIPs, err := spiderpoolip.AssembleTotalIPs(*oldIPPool.Spec.IPVersion, oldIPPool.Spec.IPs, oldIPPool.Spec.ExcludeIPs)
+		if err != nil {
+			return field.InternalError(ipsField, fmt.Errorf("failed to assemble the total IP addresses of the IPPool %s: %v", oldIPPool.Name, err))
+		}
+		outIPs = spiderpoolip.IPsDiffSet(outIPs, oldPoolTotalIPs, false)
+	}
 	if len(outIPs) > 0 {
 		ranges, _ := spiderpoolip.ConvertIPsToIPRanges(*ipPool.Spec.IPVersion, outIPs)
 		return field.Forbidden(
@@ -6,8 +6,13 @@ package ippoolmanager
 import (
 	"context"
 	"fmt"
+	"net"
 	"strconv"
+	"strings"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -23,7 +28,15 @@ var (
 	ipsField        *field.Path = field.NewPath("spec").Child("ips")
 	excludeIPsField *field.Path = field.NewPath("spec").Child("excludeIPs")
 	gatewayField    *field.Path = field.NewPath("spec").Child("gateway")
+	standbyGWsField *field.Path = field.NewPath("spec").Child("standbyGateways")
+	excludeGWsField *field.Path = field.NewPath("spec").Child("excludeGateways")
+	vlanRangesField *field.Path = field.NewPath("spec").Child("vlanRanges")
 	routesField     *field.Path = field.NewPath("spec").Child("routes")
+	dnsField        *field.Path = field.NewPath("spec").Child("dns")
+	macPrefixField  *field.Path = field.NewPath("spec").Child("macPrefix")
+	tenantField     *field.Path = field.NewPath("spec").Child("tenant")
+	allowedNSField  *field.Path = field.NewPath("spec").Child("allowedNamespaces")
+	parentPoolField *field.Path = field.NewPath("spec").Child("parentPool")
 )
 
 func (iw *IPPoolWebhook) validateCreateIPPool(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool) field.ErrorList {
@@ -88,6 +101,20 @@ func validateIPPoolShouldNotBeChanged(oldIPPool, newIPPool *spiderpoolv1.SpiderI
 		)
 	}
 
+	if GetIPPoolTenant(newIPPool) != GetIPPoolTenant(oldIPPool) {
+		return field.Forbidden(
+			tenantField,
+			"is not changeable",
+		)
+	}
+
+	if GetIPPoolParent(newIPPool) != GetIPPoolParent(oldIPPool) {
+		return field.Forbidden(
+			parentPoolField,
+			"is not changeable",
+		)
+	}
+
 	return nil
 }
 
@@ -98,8 +125,30 @@ func (iw *IPPoolWebhook) validateIPPoolSpec(ctx context.Context, ipPool *spiderp
 	if err := validateIPPoolGateway(*ipPool.Spec.IPVersion, ipPool.Spec.Subnet, ipPool.Spec.Gateway); err != nil {
 		return err
 	}
+	if err := validateIPPoolStandbyGateways(*ipPool.Spec.IPVersion, ipPool.Spec.Subnet, ipPool.Spec.Gateway, ipPool.Spec.StandbyGateways); err != nil {
+		return err
+	}
+	if err := validateIPPoolExcludeGateways(*ipPool.Spec.IPVersion, ipPool.Spec.ExcludeGateways); err != nil {
+		return err
+	}
+
+	if err := validateIPPoolVlanRanges(*ipPool.Spec.IPVersion, ipPool.Spec.Subnet, ipPool.Spec.VlanRanges); err != nil {
+		return err
+	}
+
+	if err := validateIPPoolRoutes(*ipPool.Spec.IPVersion, ipPool.Spec.Subnet, ipPool.Spec.Routes); err != nil {
+		return err
+	}
+
+	if err := validateIPPoolDNS(ipPool.Spec.DNS); err != nil {
+		return err
+	}
 
-	return validateIPPoolRoutes(*ipPool.Spec.IPVersion, ipPool.Spec.Subnet, ipPool.Spec.Routes)
+	if err := validateIPPoolMACPrefix(ipPool.Spec.MACPrefix); err != nil {
+		return err
+	}
+
+	return validateIPPoolAllowedNamespaces(ipPool.Spec.AllowedNamespaces)
 }
 
 func validateIPPoolIPInUse(ipPool *spiderpoolv1.SpiderIPPool) *field.Error {
@@ -186,7 +235,8 @@ func (iw *IPPoolWebhook) validateIPPoolCIDR(ctx context.Context, ipPool *spiderp
 				return field.InternalError(subnetField, fmt.Errorf("IPPool %s already exists", ipPool.Name))
 			}
 
-			if pool.Spec.Subnet == ipPool.Spec.Subnet {
+			// IPPools in different tenants are allowed to overlap.
+			if pool.Spec.Subnet == ipPool.Spec.Subnet || GetIPPoolTenant(&pool) != GetIPPoolTenant(ipPool) {
 				continue
 			}
 
@@ -237,7 +287,33 @@ func (iw *IPPoolWebhook) validateIPPoolAvailableIPs(ctx context.Context, ipPool
 	}
 
 	for _, pool := range ipPoolList.Items {
-		if pool.Name != ipPool.Name {
+		if pool.Name == ipPool.Name {
+			continue
+		}
+
+		// The IP addresses of a child IPPool are delegated from its parent,
+		// they are not the overlapping ones.
+		if GetIPPoolParent(ipPool) == pool.Name {
+			continue
+		}
+		if GetIPPoolParent(&pool) == ipPool.Name {
+			childIPs, err := spiderpoolip.AssembleTotalIPs(*pool.Spec.IPVersion, pool.Spec.IPs, pool.Spec.ExcludeIPs)
+			if err != nil {
+				return field.InternalError(ipsField, fmt.Errorf("failed to assemble the total IP addresses of the child IPPool %s: %v", pool.Name, err))
+			}
+
+			removedIPs := spiderpoolip.IPsDiffSet(childIPs, newIPs, false)
+			if len(removedIPs) > 0 {
+				removedRanges, _ := spiderpoolip.ConvertIPsToIPRanges(*pool.Spec.IPVersion, removedIPs)
+				return field.Forbidden(
+					ipsField,
+					fmt.Sprintf("remove IP ranges %v which are delegated to the child IPPool %s", removedRanges, pool.Name),
+				)
+			}
+			continue
+		}
+
+		if GetIPPoolTenant(&pool) == GetIPPoolTenant(ipPool) {
 			existIPs, err := spiderpoolip.AssembleTotalIPs(*pool.Spec.IPVersion, pool.Spec.IPs, pool.Spec.ExcludeIPs)
 			if err != nil {
 				return field.InternalError(ipsField, fmt.Errorf("failed to assemble the total IP addresses of the existing IPPool %s: %v", pool.Name, err))
@@ -254,6 +330,90 @@ func (iw *IPPoolWebhook) validateIPPoolAvailableIPs(ctx context.Context, ipPool
 		}
 	}
 
+	if ipPool.Spec.ParentPool != nil {
+		return iw.validateIPPoolParent(ctx, ipPool, newIPs)
+	}
+
+	return nil
+}
+
+// validateIPPoolParent checks that the IP addresses of the child IPPool are
+// the free ones of its parent, which must be a top-level IPPool with the
+// same subnet in the same tenant, and must not be re-created since the
+// child was carved from it.
+func (iw *IPPoolWebhook) validateIPPoolParent(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, ips []net.IP) *field.Error {
+	parentName := *ipPool.Spec.ParentPool
+	if parentName == ipPool.Name {
+		return field.Invalid(
+			parentPoolField,
+			parentName,
+			"could not be the IPPool itself",
+		)
+	}
+
+	var parent spiderpoolv1.SpiderIPPool
+	if err := iw.Client.Get(ctx, apitypes.NamespacedName{Name: parentName}, &parent); err != nil {
+		if apierrors.IsNotFound(err) {
+			return field.Invalid(
+				parentPoolField,
+				parentName,
+				"the parent IPPool does not exist",
+			)
+		}
+		return field.InternalError(parentPoolField, fmt.Errorf("failed to get the parent IPPool %s: %v", parentName, err))
+	}
+
+	if uid, ok := ipPool.Labels[constant.LabelIPPoolParentUID]; ok && uid != string(parent.UID) {
+		return field.Forbidden(
+			parentPoolField,
+			fmt.Sprintf("the parent IPPool %s has been re-created since the IPPool was carved from it, remove label %s to carve from the new one", parentName, constant.LabelIPPoolParentUID),
+		)
+	}
+
+	if parent.Spec.ParentPool != nil {
+		return field.Forbidden(
+			parentPoolField,
+			fmt.Sprintf("the parent IPPool %s is a child of IPPool %s, nested delegation is not supported", parentName, *parent.Spec.ParentPool),
+		)
+	}
+	if parent.Spec.IPVersion == nil || *parent.Spec.IPVersion != *ipPool.Spec.IPVersion ||
+		parent.Spec.Subnet != ipPool.Spec.Subnet {
+		return field.Invalid(
+			parentPoolField,
+			parentName,
+			fmt.Sprintf("'spec.subnet' %s mismatches the one %s of the parent IPPool", ipPool.Spec.Subnet, parent.Spec.Subnet),
+		)
+	}
+	if GetIPPoolTenant(&parent) != GetIPPoolTenant(ipPool) {
+		return field.Invalid(
+			parentPoolField,
+			parentName,
+			"the parent IPPool belongs to another tenant",
+		)
+	}
+
+	parentIPs, err := spiderpoolip.AssembleTotalIPs(*parent.Spec.IPVersion, parent.Spec.IPs, parent.Spec.ExcludeIPs)
+	if err != nil {
+		return field.InternalError(ipsField, fmt.Errorf("failed to assemble the total IP addresses of the parent IPPool %s: %v", parentName, err))
+	}
+
+	if outIPs := spiderpoolip.IPsDiffSet(ips, parentIPs, false); len(outIPs) > 0 {
+		outRanges, _ := spiderpoolip.ConvertIPsToIPRanges(*ipPool.Spec.IPVersion, outIPs)
+		return field.Forbidden(
+			ipsField,
+			fmt.Sprintf("IP ranges %v do not pertain to the parent IPPool %s, total IP addresses of an IPPool are jointly determined by 'spec.ips' and 'spec.excludeIPs'", outRanges, parentName),
+		)
+	}
+
+	for _, ip := range ips {
+		if allocation, ok := parent.Status.AllocatedIPs[ip.String()]; ok {
+			return field.Forbidden(
+				ipsField,
+				fmt.Sprintf("IP address %s has been allocated to Pod %s/%s by the parent IPPool %s", ip, allocation.Namespace, allocation.Pod, parentName),
+			)
+		}
+	}
+
 	return nil
 }
 
@@ -285,6 +445,84 @@ func validateIPPoolGateway(version types.IPVersion, subnet string, gateway *stri
 	return nil
 }
 
+// validateIPPoolStandbyGateways validates the standby gateways, which only
+// make sense along with 'spec.gateway'.
+func validateIPPoolStandbyGateways(version types.IPVersion, subnet string, gateway *string, standbyGateways []string) *field.Error {
+	if len(standbyGateways) == 0 {
+		return nil
+	}
+	if gateway == nil {
+		return field.Forbidden(
+			standbyGWsField,
+			"requires 'spec.gateway' to be set",
+		)
+	}
+
+	for i, gw := range standbyGateways {
+		if err := ValidateContainsIP(standbyGWsField.Index(i), version, subnet, gw); err != nil {
+			return err
+		}
+		if gw == *gateway {
+			return field.Invalid(
+				standbyGWsField.Index(i),
+				gw,
+				"duplicates with 'spec.gateway'",
+			)
+		}
+	}
+
+	return nil
+}
+
+func validateIPPoolExcludeGateways(version types.IPVersion, gateways []string) *field.Error {
+	for i, gateway := range gateways {
+		if err := spiderpoolip.IsIP(version, gateway); err != nil {
+			return field.Invalid(
+				excludeGWsField.Index(i),
+				gateway,
+				err.Error(),
+			)
+		}
+	}
+
+	return nil
+}
+
+// validateIPPoolVlanRanges validates the VLAN overrides of IP ranges, which
+// may be out of 'spec.ips', such as the ones inherited by the IPPools split
+// from the IPPool, but must not overlap with each other.
+func validateIPPoolVlanRanges(version types.IPVersion, subnet string, vlanRanges []spiderpoolv1.VlanRange) *field.Error {
+	for i, vr := range vlanRanges {
+		for j, r := range vr.IPs {
+			if err := ValidateContainsIPRange(vlanRangesField.Index(i).Child("ips").Index(j), version, subnet, r); err != nil {
+				return err
+			}
+
+			for k := 0; k < i; k++ {
+				for _, other := range vlanRanges[k].IPs {
+					overlap, err := spiderpoolip.IsIPRangeOverlap(version, r, other)
+					if err != nil {
+						return field.Invalid(
+							vlanRangesField.Index(k).Child("ips"),
+							other,
+							err.Error(),
+						)
+					}
+					if overlap {
+						return field.Invalid(
+							vlanRangesField.Index(i).Child("ips").Index(j),
+							r,
+							fmt.Sprintf("overlaps with 'spec.vlanRanges[%d]'", k),
+						)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 func validateIPPoolRoutes(version types.IPVersion, subnet string, routes []spiderpoolv1.Route) *field.Error {
 	for i, r := range routes {
 		if err := spiderpoolip.IsCIDR(version, r.Dst); err != nil {
@@ -303,6 +541,58 @@ func validateIPPoolRoutes(version types.IPVersion, subnet string, routes []spide
 	return nil
 }
 
+func validateIPPoolDNS(dns *spiderpoolv1.IPPoolDNS) *field.Error {
+	if dns == nil {
+		return nil
+	}
+
+	// The nameservers may be of the other IP version, such as the IPv4
+	// resolvers of a dual-stack network in its IPv6 IPPool.
+	for i, nameserver := range dns.Nameservers {
+		if net.ParseIP(nameserver) == nil {
+			return field.Invalid(
+				dnsField.Child("nameservers").Index(i),
+				nameserver,
+				"invalid IP address",
+			)
+		}
+	}
+
+	return nil
+}
+
+func validateIPPoolMACPrefix(prefix *string) *field.Error {
+	if prefix == nil {
+		return nil
+	}
+
+	if _, err := ParseMACPrefix(*prefix); err != nil {
+		return field.Invalid(
+			macPrefixField,
+			*prefix,
+			err.Error(),
+		)
+	}
+
+	return nil
+}
+
+// validateIPPoolAllowedNamespaces validates the names of Namespaces with the
+// wildcards, which are regarded as any letter.
+func validateIPPoolAllowedNamespaces(namespaces []string) *field.Error {
+	for i, ns := range namespaces {
+		if errs := validation.IsDNS1123Label(strings.ReplaceAll(ns, "*", "a")); len(errs) != 0 {
+			return field.Invalid(
+				allowedNSField.Index(i),
+				ns,
+				strings.Join(errs, "; "),
+			)
+		}
+	}
+
+	return nil
+}
+
 func ValidateContainsIPRange(fieldPath *field.Path, version types.IPVersion, subnet string, ipRange string) *field.Error {
 	contains, err := spiderpoolip.ContainsIPRange(version, subnet, ipRange)
 	if err != nil {
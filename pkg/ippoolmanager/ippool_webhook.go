@@ -19,6 +19,7 @@ import (
 	"github.com/spidernet-io/spiderpool/pkg/constant"
 	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
 	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/reportonly"
 )
 
 var WebhookLogger *zap.Logger
@@ -30,6 +31,14 @@ type IPPoolWebhook struct {
 	EnableIPv4         bool
 	EnableIPv6         bool
 	EnableSpiderSubnet bool
+
+	// AutoExcludeGateways excludes the gateways of neighboring subnets
+	// from IPPools automatically.
+	AutoExcludeGateways bool
+
+	// ReportOnly admits the requests which would be denied, the denials
+	// are logged and counted only.
+	ReportOnly bool
 }
 
 func (iw *IPPoolWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
@@ -37,10 +46,15 @@ func (iw *IPPoolWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
 		WebhookLogger = logutils.Logger.Named("IPPool-Webhook")
 	}
 
+	var validator webhook.CustomValidator = iw
+	if iw.ReportOnly {
+		validator = reportonly.NewValidator(constant.SpiderIPPoolKind, iw)
+	}
+
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(&spiderpoolv1.SpiderIPPool{}).
 		WithDefaulter(iw).
-		WithValidator(iw).
+		WithValidator(validator).
 		Complete()
 }
 
@@ -54,12 +68,16 @@ func (iw *IPPoolWebhook) Default(ctx context.Context, obj runtime.Object) error
 		zap.String("IPPoolName", ipPool.Name),
 		zap.String("Operation", "DEFAULT"),
 	)
-	logger.Sugar().Debugf("Request IPPool: %+v", *ipPool)
+	logger.Sugar().Debugf("Request IPPool: %v", ipPool)
 
 	if err := iw.mutateIPPool(logutils.IntoContext(ctx, logger), ipPool); err != nil {
 		logger.Sugar().Errorf("Failed to mutate IPPool: %v", err)
 	}
 
+	if err := iw.recordIPPoolSpecChange(logutils.IntoContext(ctx, logger), ipPool); err != nil {
+		logger.Sugar().Errorf("Failed to record the spec change of IPPool: %v", err)
+	}
+
 	return nil
 }
 
@@ -73,7 +91,7 @@ func (iw *IPPoolWebhook) ValidateCreate(ctx context.Context, obj runtime.Object)
 		zap.String("IPPoolName", ipPool.Name),
 		zap.String("Operation", "CREATE"),
 	)
-	logger.Sugar().Debugf("Request IPPool: %+v", *ipPool)
+	logger.Sugar().Debugf("Request IPPool: %v", ipPool)
 
 	if errs := iw.validateCreateIPPoolWhileEnableSpiderSubnet(logutils.IntoContext(ctx, logger), ipPool); len(errs) != 0 {
 		logger.Sugar().Errorf("Failed to create IPPool: %v", errs.ToAggregate().Error())
@@ -5,14 +5,19 @@ package ippoolmanager_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync/atomic"
 
 	"github.com/agiledragon/gomonkey/v2"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	apitypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -20,6 +25,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	"github.com/spidernet-io/spiderpool/pkg/constant"
 	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
@@ -55,6 +61,7 @@ var _ = Describe("IPPoolWebhook", Label("ippool_webhook_test"), func() {
 			ipPoolWebhook.EnableIPv4 = true
 			ipPoolWebhook.EnableIPv6 = true
 			ipPoolWebhook.EnableSpiderSubnet = false
+			ipPoolWebhook.AutoExcludeGateways = false
 
 			atomic.AddUint64(&count, 1)
 			subnetName = fmt.Sprintf("subnet-%v", count)
@@ -167,6 +174,142 @@ var _ = Describe("IPPoolWebhook", Label("ippool_webhook_test"), func() {
 				Expect(v).To(Equal(cidr))
 			})
 
+			It("sets tenant label", func() {
+				ipPoolT.Spec.Subnet = "172.18.40.0/24"
+				ipPoolT.Spec.Tenant = pointer.String("tenant1")
+
+				err := ipPoolWebhook.Default(context.TODO(), ipPoolT)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ipPoolT.Labels).To(HaveKeyWithValue(constant.LabelIPPoolTenant, "tenant1"))
+			})
+
+			It("inherits the gateway, VLAN and routes of the parent IPPool", func() {
+				existIPPoolT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+				existIPPoolT.Spec.Subnet = "172.18.40.0/24"
+				existIPPoolT.Spec.Gateway = pointer.String("172.18.40.1")
+				existIPPoolT.Spec.Vlan = pointer.Int64(100)
+				existIPPoolT.Spec.Routes = []spiderpoolv1.Route{{Dst: "10.0.0.0/8", Gw: "172.18.40.254"}}
+				existIPPoolT.UID = apitypes.UID("parent-uid")
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, existIPPoolT)
+				Expect(err).NotTo(HaveOccurred())
+
+				ipPoolT.Spec.Subnet = "172.18.40.0/24"
+				ipPoolT.Spec.Vlan = pointer.Int64(0)
+				ipPoolT.Spec.ParentPool = pointer.String(existIPPoolName)
+				err = ipPoolWebhook.Default(ctx, ipPoolT)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(ipPoolT.Labels).To(HaveKeyWithValue(constant.LabelIPPoolParent, existIPPoolName))
+				Expect(ipPoolT.Labels).To(HaveKeyWithValue(constant.LabelIPPoolParentUID, string(existIPPoolT.UID)))
+				Expect(ipPoolT.Spec.Gateway).To(Equal(existIPPoolT.Spec.Gateway))
+				Expect(ipPoolT.Spec.Vlan).To(Equal(existIPPoolT.Spec.Vlan))
+				Expect(ipPoolT.Spec.Routes).To(Equal(existIPPoolT.Spec.Routes))
+			})
+
+			It("excludes the gateways of neighboring subnets", func() {
+				ipPoolWebhook.AutoExcludeGateways = true
+				ipPoolT.Spec.Subnet = "172.18.40.0/24"
+				ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.0-172.18.40.255")
+				ipPoolT.Spec.ExcludeIPs = append(ipPoolT.Spec.ExcludeIPs, "172.18.40.10")
+				ipPoolT.Spec.ExcludeGateways = append(ipPoolT.Spec.ExcludeGateways, "172.18.40.1", "172.18.41.1")
+
+				ctx := context.TODO()
+				err := ipPoolWebhook.Default(ctx, ipPoolT)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ipPoolT.Spec.ExcludeIPs).To(Equal([]string{"172.18.40.1", "172.18.40.10"}))
+				Expect(ipPoolT.Annotations).To(HaveKeyWithValue(constant.AnnoIPPoolAutoExcludedGateways, "172.18.40.1"))
+			})
+
+			It("stops excluding the gateways of neighboring subnets no longer listed", func() {
+				ipPoolWebhook.AutoExcludeGateways = true
+				ipPoolT.Spec.Subnet = "172.18.40.0/24"
+				ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.0-172.18.40.255")
+				ipPoolT.Spec.ExcludeIPs = append(ipPoolT.Spec.ExcludeIPs, "172.18.40.1-172.18.40.3")
+				ipPoolT.Spec.ExcludeGateways = append(ipPoolT.Spec.ExcludeGateways, "172.18.40.3", "172.18.40.254")
+				ipPoolT.SetAnnotations(map[string]string{constant.AnnoIPPoolAutoExcludedGateways: "172.18.40.2,172.18.40.3"})
+
+				ctx := context.TODO()
+				err := ipPoolWebhook.Default(ctx, ipPoolT)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ipPoolT.Spec.ExcludeIPs).To(Equal([]string{"172.18.40.1", "172.18.40.3", "172.18.40.254"}))
+				Expect(ipPoolT.Annotations).To(HaveKeyWithValue(constant.AnnoIPPoolAutoExcludedGateways, "172.18.40.254,172.18.40.3"))
+			})
+
+			It("does not exclude the gateways of neighboring subnets being allocated", func() {
+				ipPoolWebhook.AutoExcludeGateways = true
+				ipPoolT.Spec.Subnet = "172.18.40.0/24"
+				ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.0-172.18.40.255")
+				ipPoolT.Spec.ExcludeGateways = append(ipPoolT.Spec.ExcludeGateways, "172.18.40.1", "172.18.40.254")
+				ipPoolT.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{
+					"172.18.40.254": spiderpoolv1.PoolIPAllocation{
+						ContainerID: "container",
+						NIC:         "eth0",
+						Namespace:   "default",
+						Pod:         "pod",
+					},
+				}
+
+				ctx := context.TODO()
+				err := ipPoolWebhook.Default(ctx, ipPoolT)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ipPoolT.Spec.ExcludeIPs).To(Equal([]string{"172.18.40.1"}))
+			})
+
+			It("does not exclude the gateways of neighboring subnets if the IPPool opts out", func() {
+				ipPoolWebhook.AutoExcludeGateways = true
+				ipPoolT.Spec.AutoExcludeGateways = pointer.Bool(false)
+				ipPoolT.Spec.Subnet = "172.18.40.0/24"
+				ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.0-172.18.40.255")
+				ipPoolT.Spec.ExcludeGateways = append(ipPoolT.Spec.ExcludeGateways, "172.18.40.1")
+
+				ctx := context.TODO()
+				err := ipPoolWebhook.Default(ctx, ipPoolT)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ipPoolT.Spec.ExcludeIPs).To(BeEmpty())
+			})
+
+			It("removes the excluded gateways of neighboring subnets if the IPPool opts out", func() {
+				ipPoolWebhook.AutoExcludeGateways = true
+				ipPoolT.Spec.AutoExcludeGateways = pointer.Bool(false)
+				ipPoolT.Spec.Subnet = "172.18.40.0/24"
+				ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.0-172.18.40.255")
+				ipPoolT.Spec.ExcludeIPs = append(ipPoolT.Spec.ExcludeIPs, "172.18.40.1", "172.18.40.10")
+				ipPoolT.Spec.ExcludeGateways = append(ipPoolT.Spec.ExcludeGateways, "172.18.40.1")
+				ipPoolT.SetAnnotations(map[string]string{constant.AnnoIPPoolAutoExcludedGateways: "172.18.40.1"})
+
+				ctx := context.TODO()
+				err := ipPoolWebhook.Default(ctx, ipPoolT)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ipPoolT.Spec.ExcludeIPs).To(Equal([]string{"172.18.40.10"}))
+				Expect(ipPoolT.Annotations).NotTo(HaveKey(constant.AnnoIPPoolAutoExcludedGateways))
+			})
+
+			It("excludes the gateways of neighboring subnets if the IPPool opts in", func() {
+				ipPoolT.Spec.AutoExcludeGateways = pointer.Bool(true)
+				ipPoolT.Spec.ReserveSpecialIPs = pointer.Bool(false)
+				ipPoolT.Spec.Subnet = "172.18.40.0/24"
+				ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.0-172.18.40.255")
+				ipPoolT.Spec.ExcludeGateways = append(ipPoolT.Spec.ExcludeGateways, "172.18.40.1")
+
+				ctx := context.TODO()
+				err := ipPoolWebhook.Default(ctx, ipPoolT)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ipPoolT.Spec.ExcludeIPs).To(Equal([]string{"172.18.40.1"}))
+			})
+
+			It("does not exclude the gateways of neighboring subnets if disabled", func() {
+				ipPoolT.Spec.Subnet = "172.18.40.0/24"
+				ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.0-172.18.40.255")
+				ipPoolT.Spec.ExcludeGateways = append(ipPoolT.Spec.ExcludeGateways, "172.18.40.1")
+
+				ctx := context.TODO()
+				err := ipPoolWebhook.Default(ctx, ipPoolT)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ipPoolT.Spec.ExcludeIPs).To(BeEmpty())
+			})
+
 			It("is orphan IPPool", func() {
 				ipPoolWebhook.EnableSpiderSubnet = true
 				ipPoolT.Spec.Subnet = "172.18.40.0/24"
@@ -410,6 +553,22 @@ var _ = Describe("IPPoolWebhook", Label("ippool_webhook_test"), func() {
 				))
 			})
 
+			It("converts 'spec.ips' in compact notations", func() {
+				ipPoolT.Spec.Subnet = "172.18.40.0/24"
+				ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.0/24@10-15:2")
+
+				ctx := context.TODO()
+				err := ipPoolWebhook.Default(ctx, ipPoolT)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ipPoolT.Spec.IPs).To(Equal(
+					[]string{
+						"172.18.40.10",
+						"172.18.40.12",
+						"172.18.40.14",
+					},
+				))
+			})
+
 			It("failed to merge 'spec.excludeIPs' due to the invalid 'spec.ipVersion'", func() {
 				ipPoolT.Spec.IPVersion = pointer.Int64(constant.InvalidIPVersion)
 				ipPoolT.Spec.Subnet = "172.18.40.0/24"
@@ -499,6 +658,112 @@ var _ = Describe("IPPoolWebhook", Label("ippool_webhook_test"), func() {
 					},
 				))
 			})
+
+			It("records the spec change with the requesting user", func() {
+				ipPoolT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+				ipPoolT.Spec.Subnet = "172.18.40.0/24"
+				ipPoolT.Spec.IPs = []string{"172.18.40.1-172.18.40.10"}
+
+				oldIPPool := ipPoolT.DeepCopy()
+				oldRaw, err := json.Marshal(oldIPPool)
+				Expect(err).NotTo(HaveOccurred())
+
+				ipPoolT.Spec.IPs = []string{"172.18.40.1-172.18.40.5"}
+				ipPoolT.Spec.ExcludeIPs = []string{"172.18.40.2"}
+				ipPoolT.Spec.Gateway = pointer.String("172.18.40.254")
+
+				ctx := admission.NewContextWithRequest(context.TODO(), admission.Request{
+					AdmissionRequest: admissionv1.AdmissionRequest{
+						Operation: admissionv1.Update,
+						UserInfo:  authenticationv1.UserInfo{Username: "alice"},
+						OldObject: runtime.RawExtension{Raw: oldRaw},
+					},
+				})
+				err = ipPoolWebhook.Default(ctx, ipPoolT)
+				Expect(err).NotTo(HaveOccurred())
+
+				anno, ok := ipPoolT.Annotations[constant.AnnoIPPoolSpecChanges]
+				Expect(ok).To(BeTrue())
+
+				var changes []spiderpoolv1.IPPoolSpecChange
+				err = json.Unmarshal([]byte(anno), &changes)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(changes).To(HaveLen(1))
+				change := changes[0]
+				Expect(change.User).To(Equal("alice"))
+				Expect(change.AddedIPs).To(BeEmpty())
+				Expect(change.RemovedIPs).To(Equal([]string{"172.18.40.6-172.18.40.10"}))
+				Expect(change.AddedExcludeIPs).To(Equal([]string{"172.18.40.2"}))
+				Expect(change.Fields).To(Equal([]string{"gateway"}))
+			})
+
+			It("appends the spec change to the ones of the stored IPPool", func() {
+				ipPoolT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+				ipPoolT.Spec.Subnet = "172.18.40.0/24"
+				ipPoolT.Spec.IPs = []string{"172.18.40.1-172.18.40.10"}
+
+				var recorded []spiderpoolv1.IPPoolSpecChange
+				for j := 0; j < 10; j++ {
+					recorded = append(recorded, spiderpoolv1.IPPoolSpecChange{
+						User:   fmt.Sprintf("user%d", j),
+						Fields: []string{"gateway"},
+					})
+				}
+				data, err := json.Marshal(recorded)
+				Expect(err).NotTo(HaveOccurred())
+				oldIPPool := ipPoolT.DeepCopy()
+				oldIPPool.Annotations = map[string]string{constant.AnnoIPPoolSpecChanges: string(data)}
+				oldRaw, err := json.Marshal(oldIPPool)
+				Expect(err).NotTo(HaveOccurred())
+
+				// The annotation of the request is stale.
+				ipPoolT.Annotations = map[string]string{constant.AnnoIPPoolSpecChanges: "[]"}
+				ipPoolT.Spec.Gateway = pointer.String("172.18.40.254")
+
+				ctx := admission.NewContextWithRequest(context.TODO(), admission.Request{
+					AdmissionRequest: admissionv1.AdmissionRequest{
+						Operation: admissionv1.Update,
+						UserInfo:  authenticationv1.UserInfo{Username: "alice"},
+						OldObject: runtime.RawExtension{Raw: oldRaw},
+					},
+				})
+				err = ipPoolWebhook.Default(ctx, ipPoolT)
+				Expect(err).NotTo(HaveOccurred())
+
+				var changes []spiderpoolv1.IPPoolSpecChange
+				err = json.Unmarshal([]byte(ipPoolT.Annotations[constant.AnnoIPPoolSpecChanges]), &changes)
+				Expect(err).NotTo(HaveOccurred())
+				var users []string
+				for _, change := range changes {
+					users = append(users, change.User)
+				}
+				Expect(users).To(Equal([]string{
+					"user1", "user2", "user3", "user4", "user5",
+					"user6", "user7", "user8", "user9", "alice",
+				}))
+			})
+
+			It("does not record anything if the spec is not changed", func() {
+				ipPoolT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+				ipPoolT.Spec.Subnet = "172.18.40.0/24"
+				ipPoolT.Spec.IPs = []string{"172.18.40.1-172.18.40.10"}
+
+				oldRaw, err := json.Marshal(ipPoolT)
+				Expect(err).NotTo(HaveOccurred())
+
+				ctx := admission.NewContextWithRequest(context.TODO(), admission.Request{
+					AdmissionRequest: admissionv1.AdmissionRequest{
+						Operation: admissionv1.Update,
+						UserInfo:  authenticationv1.UserInfo{Username: "alice"},
+						OldObject: runtime.RawExtension{Raw: oldRaw},
+					},
+				})
+				err = ipPoolWebhook.Default(ctx, ipPoolT)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, ok := ipPoolT.Annotations[constant.AnnoIPPoolSpecChanges]
+				Expect(ok).To(BeFalse())
+			})
 		})
 
 		Describe("ValidateCreate", func() {
@@ -677,6 +942,30 @@ var _ = Describe("IPPoolWebhook", Label("ippool_webhook_test"), func() {
 					err = ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
 					Expect(apierrors.IsInvalid(err)).To(BeTrue())
 				})
+
+				It("overlaps with existing Subnet in another tenant", func() {
+					existIPPoolT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					existIPPoolT.Spec.Subnet = "172.18.40.0/25"
+					existIPPoolT.Spec.IPs = append(existIPPoolT.Spec.IPs, "172.18.40.10")
+					existIPPoolT.Spec.Tenant = pointer.String("tenant-a")
+
+					ctx := context.TODO()
+					err := fakeClient.Create(ctx, existIPPoolT)
+					Expect(err).NotTo(HaveOccurred())
+
+					ipPoolT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					ipPoolT.Spec.Subnet = "172.18.40.0/24"
+					ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs,
+						[]string{
+							"172.18.40.1-172.18.40.2",
+							"172.18.40.10",
+						}...,
+					)
+					ipPoolT.Spec.Tenant = pointer.String("tenant-b")
+
+					err = ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
+					Expect(err).NotTo(HaveOccurred())
+				})
 			})
 
 			When("Validating 'spec.ips'", func() {
@@ -897,6 +1186,228 @@ var _ = Describe("IPPoolWebhook", Label("ippool_webhook_test"), func() {
 				})
 			})
 
+			When("Validating 'spec.dns'", func() {
+				It("inputs invalid nameserver", func() {
+					ipPoolT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					ipPoolT.Spec.Subnet = "172.18.40.0/24"
+					ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs,
+						[]string{
+							"172.18.40.2-172.18.40.3",
+							"172.18.40.10",
+						}...,
+					)
+					ipPoolT.Spec.DNS = &spiderpoolv1.IPPoolDNS{
+						Nameservers: []string{"172.18.40.53", constant.InvalidIP},
+					}
+
+					ctx := context.TODO()
+					err := ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+			})
+
+			When("Validating 'spec.allowedNamespaces'", func() {
+				It("inputs invalid Namespace", func() {
+					ipPoolT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					ipPoolT.Spec.Subnet = "172.18.40.0/24"
+					ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs,
+						[]string{
+							"172.18.40.2-172.18.40.3",
+							"172.18.40.10",
+						}...,
+					)
+					ipPoolT.Spec.AllowedNamespaces = []string{"team-a-*", "Team_B"}
+
+					ctx := context.TODO()
+					err := ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+			})
+
+			When("Validating 'spec.macPrefix'", func() {
+				BeforeEach(func() {
+					ipPoolT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					ipPoolT.Spec.Subnet = "172.18.40.0/24"
+					ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.10-172.18.40.20")
+				})
+
+				It("inputs invalid MAC prefix", func() {
+					ipPoolT.Spec.MACPrefix = pointer.String("0a:58:01")
+
+					ctx := context.TODO()
+					err := ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs the MAC prefix of multicast MAC addresses", func() {
+					ipPoolT.Spec.MACPrefix = pointer.String("01:00")
+
+					ctx := context.TODO()
+					err := ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+			})
+
+			When("Validating 'spec.standbyGateways'", func() {
+				BeforeEach(func() {
+					ipPoolT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					ipPoolT.Spec.Subnet = "172.18.40.0/24"
+					ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs,
+						[]string{
+							"172.18.40.10-172.18.40.20",
+						}...,
+					)
+				})
+
+				It("sets standby gateways without gateway", func() {
+					ipPoolT.Spec.StandbyGateways = []string{"172.18.40.2"}
+
+					ctx := context.TODO()
+					err := ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs the standby gateway out of the subnet", func() {
+					ipPoolT.Spec.Gateway = pointer.String("172.18.40.1")
+					ipPoolT.Spec.StandbyGateways = []string{"172.18.41.2"}
+
+					ctx := context.TODO()
+					err := ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs the standby gateway duplicated with the gateway", func() {
+					ipPoolT.Spec.Gateway = pointer.String("172.18.40.1")
+					ipPoolT.Spec.StandbyGateways = []string{"172.18.40.1"}
+
+					ctx := context.TODO()
+					err := ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+			})
+
+			When("Validating 'spec.vlanRanges'", func() {
+				BeforeEach(func() {
+					ipPoolT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					ipPoolT.Spec.Subnet = "172.18.40.0/24"
+					ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs,
+						[]string{
+							"172.18.40.10-172.18.40.20",
+						}...,
+					)
+				})
+
+				It("inputs the IP range out of the subnet", func() {
+					ipPoolT.Spec.VlanRanges = []spiderpoolv1.VlanRange{
+						{IPs: []string{"172.18.41.10-172.18.41.15"}, Vlan: 100},
+					}
+
+					ctx := context.TODO()
+					err := ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs the overlapping IP ranges", func() {
+					ipPoolT.Spec.VlanRanges = []spiderpoolv1.VlanRange{
+						{IPs: []string{"172.18.40.10-172.18.40.15"}, Vlan: 100},
+						{IPs: []string{"172.18.40.15-172.18.40.20"}, Vlan: 200},
+					}
+
+					ctx := context.TODO()
+					err := ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+			})
+
+			When("Validating 'spec.parentPool'", func() {
+				BeforeEach(func() {
+					ipVersion := constant.IPv4
+					subnet := "172.18.40.0/24"
+					cidr, err := spiderpoolip.CIDRToLabelValue(ipVersion, subnet)
+					Expect(err).NotTo(HaveOccurred())
+
+					existIPPoolT.Labels[constant.LabelIPPoolCIDR] = cidr
+					existIPPoolT.Spec.IPVersion = pointer.Int64(ipVersion)
+					existIPPoolT.Spec.Subnet = subnet
+					existIPPoolT.Spec.IPs = append(existIPPoolT.Spec.IPs, "172.18.40.10-172.18.40.20")
+					existIPPoolT.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{
+						"172.18.40.20": spiderpoolv1.PoolIPAllocation{ContainerID: "container", Namespace: "default", Pod: "pod"},
+					}
+
+					ipPoolT.Spec.IPVersion = pointer.Int64(ipVersion)
+					ipPoolT.Spec.Subnet = subnet
+					ipPoolT.Spec.ParentPool = pointer.String(existIPPoolName)
+				})
+
+				It("carves the free IP addresses of the parent IPPool", func() {
+					ctx := context.TODO()
+					err := fakeClient.Create(ctx, existIPPoolT)
+					Expect(err).NotTo(HaveOccurred())
+
+					ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.10-172.18.40.15")
+					err = ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("sets the non-existent parent IPPool", func() {
+					ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.10-172.18.40.15")
+
+					ctx := context.TODO()
+					err := ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs the IP addresses out of the parent IPPool", func() {
+					ctx := context.TODO()
+					err := fakeClient.Create(ctx, existIPPoolT)
+					Expect(err).NotTo(HaveOccurred())
+
+					ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.5-172.18.40.15")
+					err = ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs the IP addresses allocated by the parent IPPool", func() {
+					ctx := context.TODO()
+					err := fakeClient.Create(ctx, existIPPoolT)
+					Expect(err).NotTo(HaveOccurred())
+
+					ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.16-172.18.40.20")
+					err = ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("carves from the parent IPPool re-created since", func() {
+					existIPPoolT.UID = apitypes.UID("new-parent-uid")
+
+					ctx := context.TODO()
+					err := fakeClient.Create(ctx, existIPPoolT)
+					Expect(err).NotTo(HaveOccurred())
+
+					ipPoolT.Labels = map[string]string{constant.LabelIPPoolParentUID: "old-parent-uid"}
+					ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.10-172.18.40.15")
+					err = ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+					Expect(err.Error()).To(ContainSubstring("re-created"))
+
+					ipPoolT.Labels[constant.LabelIPPoolParentUID] = string(existIPPoolT.UID)
+					err = ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("sets a child IPPool as the parent", func() {
+					existIPPoolT.Spec.ParentPool = pointer.String("grandparent")
+
+					ctx := context.TODO()
+					err := fakeClient.Create(ctx, existIPPoolT)
+					Expect(err).NotTo(HaveOccurred())
+
+					ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.10-172.18.40.15")
+					err = ipPoolWebhook.ValidateCreate(ctx, ipPoolT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+			})
+
 			When("Validating the existence of the controller Subnet", func() {
 				BeforeEach(func() {
 					ipPoolWebhook.EnableSpiderSubnet = true
@@ -1188,6 +1699,63 @@ var _ = Describe("IPPoolWebhook", Label("ippool_webhook_test"), func() {
 				})
 			})
 
+			When("Validating 'spec.parentPool'", func() {
+				It("changes 'spec.parentPool'", func() {
+					ipPoolT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					ipPoolT.Spec.Subnet = "172.18.40.0/24"
+					ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.1-172.18.40.2")
+
+					newIPPoolT := ipPoolT.DeepCopy()
+					newIPPoolT.Spec.ParentPool = pointer.String(existIPPoolName)
+
+					ctx := context.TODO()
+					err := ipPoolWebhook.ValidateUpdate(ctx, ipPoolT, newIPPoolT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("removes the IP addresses delegated to the child IPPool", func() {
+					ipVersion := constant.IPv4
+					subnet := "172.18.40.0/24"
+					cidr, err := spiderpoolip.CIDRToLabelValue(ipVersion, subnet)
+					Expect(err).NotTo(HaveOccurred())
+
+					existIPPoolT.Labels[constant.LabelIPPoolCIDR] = cidr
+					existIPPoolT.Spec.IPVersion = pointer.Int64(ipVersion)
+					existIPPoolT.Spec.Subnet = subnet
+					existIPPoolT.Spec.IPs = append(existIPPoolT.Spec.IPs, "172.18.40.10-172.18.40.15")
+					existIPPoolT.Spec.ParentPool = pointer.String(ipPoolName)
+
+					ctx := context.TODO()
+					err = fakeClient.Create(ctx, existIPPoolT)
+					Expect(err).NotTo(HaveOccurred())
+
+					ipPoolT.Spec.IPVersion = pointer.Int64(ipVersion)
+					ipPoolT.Spec.Subnet = subnet
+					ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.1-172.18.40.20")
+
+					newIPPoolT := ipPoolT.DeepCopy()
+					newIPPoolT.Spec.IPs = []string{"172.18.40.1-172.18.40.12"}
+
+					err = ipPoolWebhook.ValidateUpdate(ctx, ipPoolT, newIPPoolT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+			})
+
+			When("Validating 'spec.tenant'", func() {
+				It("changes 'spec.tenant'", func() {
+					ipPoolT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					ipPoolT.Spec.Subnet = "172.18.40.0/24"
+					ipPoolT.Spec.IPs = append(ipPoolT.Spec.IPs, "172.18.40.1-172.18.40.2")
+
+					newIPPoolT := ipPoolT.DeepCopy()
+					newIPPoolT.Spec.Tenant = pointer.String("tenant-a")
+
+					ctx := context.TODO()
+					err := ipPoolWebhook.ValidateUpdate(ctx, ipPoolT, newIPPoolT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+			})
+
 			When("Validating 'spec.ips'", func() {
 				It("appends invalid IP range to 'spec.ips'", func() {
 					ipPoolT.Spec.IPVersion = pointer.Int64(constant.IPv4)
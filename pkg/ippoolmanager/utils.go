@@ -4,7 +4,13 @@
 package ippoolmanager
 
 import (
+	"fmt"
 	"net"
+	"path"
+	"strings"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 
 	"github.com/spidernet-io/spiderpool/api/v1/agent/models"
 	"github.com/spidernet-io/spiderpool/pkg/constant"
@@ -26,17 +32,181 @@ func genResIPConfig(allocateIP net.IP, nic string, ipPool *spiderpoolv1.SpiderIP
 		Address: &address,
 		Gateway: gateway,
 		IPPool:  ipPool.Name,
+		Mac:     GetIPMAC(ipPool, allocateIP),
 		Nic:     &nic,
 		Version: ipPool.Spec.IPVersion,
-		Vlan:    *ipPool.Spec.Vlan,
+		Vlan:    GetIPVlan(ipPool, allocateIP),
+	}
+}
+
+// ParseMACPrefix parses the MAC prefix of IPPool, which must be two octets
+// of a unicast MAC address.
+func ParseMACPrefix(prefix string) ([]byte, error) {
+	hw, err := net.ParseMAC(prefix + ":00:00:00:00")
+	if err != nil || len(hw) != 6 {
+		return nil, fmt.Errorf("invalid MAC prefix '%s', it should be two octets like '0a:58'", prefix)
+	}
+	if hw[0]&0x01 != 0 {
+		return nil, fmt.Errorf("MAC prefix '%s' is not of a unicast MAC address", prefix)
+	}
+
+	return hw[:2], nil
+}
+
+// GetIPMAC returns the MAC address derived from the IP address of the
+// IPPool, which is 'spec.macPrefix' followed by the last four bytes of the
+// IP address, empty if 'spec.macPrefix' is not set.
+func GetIPMAC(ipPool *spiderpoolv1.SpiderIPPool, ip net.IP) string {
+	if ipPool.Spec.MACPrefix == nil {
+		return ""
+	}
+
+	prefix, err := ParseMACPrefix(*ipPool.Spec.MACPrefix)
+	if err != nil {
+		return ""
+	}
+
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return ""
+	}
+
+	return net.HardwareAddr{prefix[0], prefix[1], ip16[12], ip16[13], ip16[14], ip16[15]}.String()
+}
+
+// GetIPVlan returns the VLAN of the IP address of the IPPool, which is the
+// one of the first 'spec.vlanRanges' containing the IP address, or
+// 'spec.vlan' otherwise.
+func GetIPVlan(ipPool *spiderpoolv1.SpiderIPPool, ip net.IP) int64 {
+	for _, r := range ipPool.Spec.VlanRanges {
+		ips, err := spiderpoolip.ParseIPRanges(*ipPool.Spec.IPVersion, r.IPs)
+		if err != nil {
+			continue
+		}
+		for _, e := range ips {
+			if e.Equal(ip) {
+				return r.Vlan
+			}
+		}
+	}
+
+	var vlan int64
+	if ipPool.Spec.Vlan != nil {
+		vlan = *ipPool.Spec.Vlan
+	}
+
+	return vlan
+}
+
+// GetVacatingIPs returns the IP ranges of the IPPool being vacated for the
+// exclusion of its controller Subnet.
+func GetVacatingIPs(pool *spiderpoolv1.SpiderIPPool) []string {
+	anno, ok := pool.Annotations[constant.AnnoIPPoolVacatingIPs]
+	if !ok || anno == "" {
+		return nil
+	}
+
+	return strings.Split(anno, ",")
+}
+
+// assembleEffectiveIPs returns the IP addresses of the IPPool which can be
+// allocated, which exclude 'spec.excludeIPs', the IP addresses being vacated,
+// the reserved IP addresses and the special IP addresses skipped following
+// the global policy 'reserveSpecialIPs', and the IP ranges of the excluded
+// ones in 'spec.ips'.
+func assembleEffectiveIPs(pool *spiderpoolv1.SpiderIPPool, reservedIPs []net.IP, reserveSpecialIPs bool) ([]net.IP, []string, error) {
+	version := *pool.Spec.IPVersion
+	ips, err := spiderpoolip.ParseIPRanges(version, pool.Spec.IPs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	excludeIPs := append(append([]string(nil), pool.Spec.ExcludeIPs...), GetVacatingIPs(pool)...)
+	totalIPs, err := spiderpoolip.AssembleTotalIPs(version, pool.Spec.IPs, excludeIPs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ShouldReserveSpecialIPs(pool, reserveSpecialIPs) {
+		specialIPs, err := GetSpecialIPs(pool)
+		if err != nil {
+			return nil, nil, err
+		}
+		reservedIPs = append(append([]net.IP(nil), reservedIPs...), specialIPs...)
+	}
+	if len(reservedIPs) != 0 {
+		totalIPs = spiderpoolip.IPsDiffSet(totalIPs, reservedIPs, false)
+	}
+
+	excludedIPs, err := spiderpoolip.ConvertIPsToIPRanges(version, spiderpoolip.IPsDiffSet(ips, totalIPs, false))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return totalIPs, excludedIPs, nil
+}
+
+// ShouldReserveSpecialIPs reports whether the network, broadcast and
+// gateway addresses of the IPPool are kept from being allocated, which is
+// the global policy unless overridden by the IPPool.
+func ShouldReserveSpecialIPs(pool *spiderpoolv1.SpiderIPPool, global bool) bool {
+	if pool.Spec.ReserveSpecialIPs != nil {
+		return *pool.Spec.ReserveSpecialIPs
+	}
+
+	return global
+}
+
+// GetSpecialIPs returns the network and broadcast addresses of the subnet
+// of the IPPool, and its gateway and standby gateways. Nothing but the
+// gateways is returned for the point-to-point subnets (/31, /127), whose
+// IP addresses are all assignable to hosts.
+func GetSpecialIPs(pool *spiderpoolv1.SpiderIPPool) ([]net.IP, error) {
+	ips, err := spiderpoolip.ReservedIPsOfCIDR(*pool.Spec.IPVersion, pool.Spec.Subnet)
+	if err != nil {
+		return nil, err
+	}
+
+	gateways := pool.Spec.StandbyGateways
+	if pool.Spec.Gateway != nil {
+		gateways = append([]string{*pool.Spec.Gateway}, gateways...)
+	}
+	for _, gateway := range gateways {
+		if ip := net.ParseIP(gateway); ip != nil {
+			ips = append(ips, ip)
+		}
 	}
+
+	return ips, nil
+}
+
+// GetIPPoolRoutes returns the routes of the IPPool, including the ones
+// inherited from its controller Subnet which aren't overridden by the routes
+// of the IPPool with the same destination.
+func GetIPPoolRoutes(pool *spiderpoolv1.SpiderIPPool) []spiderpoolv1.Route {
+	if len(pool.Status.InheritedRoutes) == 0 || (pool.Spec.InheritSubnetRoutes != nil && !*pool.Spec.InheritSubnetRoutes) {
+		return pool.Spec.Routes
+	}
+
+	overridden := make(map[string]struct{}, len(pool.Spec.Routes))
+	for _, r := range pool.Spec.Routes {
+		overridden[r.Dst] = struct{}{}
+	}
+
+	var routes []spiderpoolv1.Route
+	for _, r := range pool.Status.InheritedRoutes {
+		if _, ok := overridden[r.Dst]; !ok {
+			routes = append(routes, r)
+		}
+	}
+
+	return append(routes, pool.Spec.Routes...)
 }
 
 func ShouldScaleIPPool(pool *spiderpoolv1.SpiderIPPool) bool {
 	ips, _ := spiderpoolip.AssembleTotalIPs(*pool.Spec.IPVersion, pool.Spec.IPs, pool.Spec.ExcludeIPs)
 
-	if pool.Status.AutoDesiredIPCount != nil {
-		if int64(len(ips)) != *pool.Status.AutoDesiredIPCount {
+	if targetIPNum, ok := AutoPoolTargetIPCount(pool); ok {
+		if len(ips) != targetIPNum {
 			return true
 		}
 	}
@@ -44,9 +214,172 @@ func ShouldScaleIPPool(pool *spiderpoolv1.SpiderIPPool) bool {
 	return false
 }
 
+// AutoPoolTargetIPCount returns the IP number which the auto-created IPPool
+// is scaled to, the larger one of status AutoDesiredIPCount following the
+// replicas and AutoElasticIPCount following the actual usage. False is
+// returned if AutoDesiredIPCount is not marked yet.
+func AutoPoolTargetIPCount(pool *spiderpoolv1.SpiderIPPool) (int, bool) {
+	if pool.Status.AutoDesiredIPCount == nil {
+		return 0, false
+	}
+
+	targetIPNum := *pool.Status.AutoDesiredIPCount
+	if pool.Status.AutoElasticIPCount != nil && *pool.Status.AutoElasticIPCount > targetIPNum {
+		targetIPNum = *pool.Status.AutoElasticIPCount
+	}
+
+	return int(targetIPNum), true
+}
+
 func IsAutoCreatedIPPool(pool *spiderpoolv1.SpiderIPPool) bool {
 	// only the auto-created IPPool owns the label "ipam.spidernet.io/owner-application"
 	poolLabels := pool.GetLabels()
 	_, ok := poolLabels[constant.LabelIPPoolOwnerApplication]
 	return ok
 }
+
+// IsQuarantinedIPPool reports whether the IPPool is quarantined because of
+// repeated allocation failures.
+func IsQuarantinedIPPool(pool *spiderpoolv1.SpiderIPPool) bool {
+	return apimeta.IsStatusConditionTrue(pool.Status.Conditions, constant.IPPoolConditionQuarantined)
+}
+
+// IsGatewayUnreachableIPPool reports whether the gateway of the IPPool is
+// reported unreachable by enough nodes.
+func IsGatewayUnreachableIPPool(pool *spiderpoolv1.SpiderIPPool) bool {
+	return apimeta.IsStatusConditionTrue(pool.Status.Conditions, constant.IPPoolConditionGatewayUnreachable)
+}
+
+// IsReshapingIPPool reports whether the IPPool is being split or merged.
+func IsReshapingIPPool(pool *spiderpoolv1.SpiderIPPool) bool {
+	_, ok := pool.Annotations[constant.AnnoIPPoolReshaping]
+	return ok
+}
+
+// GetAutoIPPoolSubnet returns the SpiderSubnet specified by the application of
+// the auto-created IPPool, which differs from its owner SpiderSubnet if the
+// IPPool borrows IP addresses from a fallback subnet.
+func GetAutoIPPoolSubnet(pool *spiderpoolv1.SpiderIPPool) string {
+	if v, ok := pool.Labels[constant.LabelIPPoolBorrowedBy]; ok {
+		return v
+	}
+
+	return pool.Labels[constant.LabelIPPoolOwnerSpiderSubnet]
+}
+
+// GetAutoIPPoolPinnedIPs returns the pinned IP addresses which the
+// auto-created IPPool takes exactly from its SpiderSubnet, nil is returned if
+// the IPPool isn't pinned.
+func GetAutoIPPoolPinnedIPs(pool *spiderpoolv1.SpiderIPPool) ([]net.IP, error) {
+	v, ok := pool.Annotations[constant.AnnoSpiderSubnetPoolIPs]
+	if !ok {
+		return nil, nil
+	}
+
+	ipRanges := strings.Split(v, ",")
+	for i := range ipRanges {
+		ipRanges[i] = strings.TrimSpace(ipRanges[i])
+	}
+
+	return spiderpoolip.ParseIPRanges(*pool.Spec.IPVersion, ipRanges)
+}
+
+// GetIPPoolReclaimAfter returns the time after which the auto-created IPPool
+// retained by the Delayed reclaim policy is reclaimed, false is returned if
+// the IPPool is not waiting to be reclaimed.
+func GetIPPoolReclaimAfter(pool *spiderpoolv1.SpiderIPPool) (time.Time, bool) {
+	v, ok := pool.Annotations[constant.AnnoIPPoolReclaimAfter]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	// The annotation is only set by the controller, reclaim the IPPool at
+	// once if it is mangled.
+	reclaimAfter, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, true
+	}
+
+	return reclaimAfter, true
+}
+
+// IsDrainingIPPool reports whether the IPPool rejects new IP allocations
+// until its existing ones are released.
+func IsDrainingIPPool(pool *spiderpoolv1.SpiderIPPool) bool {
+	return pool.Spec.Drain != nil && *pool.Spec.Drain
+}
+
+// GetIPPoolTenant returns the tenant of the IPPool, an empty string means
+// the IPPool does not belong to any tenant.
+func GetIPPoolTenant(pool *spiderpoolv1.SpiderIPPool) string {
+	if pool.Spec.Tenant == nil {
+		return ""
+	}
+
+	return *pool.Spec.Tenant
+}
+
+// GetIPPoolParent returns the name of the parent IPPool which the IPPool is
+// carved from, empty if it is a top-level IPPool.
+func GetIPPoolParent(pool *spiderpoolv1.SpiderIPPool) string {
+	if pool.Spec.ParentPool == nil {
+		return ""
+	}
+
+	return *pool.Spec.ParentPool
+}
+
+// GetIPPoolFreeRatio returns the ratio of the free IP addresses of the
+// IPPool, zero is returned if the total IP count is not counted yet.
+func GetIPPoolFreeRatio(pool *spiderpoolv1.SpiderIPPool) float64 {
+	if pool.Status.TotalIPCount == nil || *pool.Status.TotalIPCount <= 0 {
+		return 0
+	}
+
+	free := *pool.Status.TotalIPCount - int64(len(pool.Status.AllocatedIPs))
+	if free <= 0 {
+		return 0
+	}
+
+	return float64(free) / float64(*pool.Status.TotalIPCount)
+}
+
+// IsNamespaceAllowed tells whether the Pods in the Namespace are allowed to
+// allocate IP addresses from the IPPool.
+func IsNamespaceAllowed(pool *spiderpoolv1.SpiderIPPool, namespace string) bool {
+	if len(pool.Spec.AllowedNamespaces) == 0 {
+		return true
+	}
+
+	for _, pattern := range pool.Spec.AllowedNamespaces {
+		if ok, err := path.Match(pattern, namespace); err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FindIPAllocation returns the IPPool which the IP address is allocated
+// from and the allocation record, the IPPools whose subnets do not contain
+// the IP address are skipped. Nil is returned if the IP address is not
+// allocated.
+func FindIPAllocation(pools []spiderpoolv1.SpiderIPPool, ip string) (*spiderpoolv1.SpiderIPPool, *spiderpoolv1.PoolIPAllocation) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil, nil
+	}
+
+	for i := range pools {
+		pool := &pools[i]
+		_, ipNet, err := net.ParseCIDR(pool.Spec.Subnet)
+		if err != nil || !ipNet.Contains(addr) {
+			continue
+		}
+		if allocation, ok := pool.Status.AllocatedIPs[addr.String()]; ok {
+			return pool, &allocation
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package ippoolmanager_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/pointer"
+
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+)
+
+var _ = Describe("IPPoolManager utils", Label("ippool_manager_utils_test"), func() {
+	Describe("GetIPPoolFreeRatio", func() {
+		newIPPool := func(total int64, allocated ...string) *spiderpoolv1.SpiderIPPool {
+			pool := &spiderpoolv1.SpiderIPPool{}
+			pool.Status.TotalIPCount = pointer.Int64(total)
+			pool.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{}
+			for _, ip := range allocated {
+				pool.Status.AllocatedIPs[ip] = spiderpoolv1.PoolIPAllocation{}
+			}
+			return pool
+		}
+
+		It("returns the ratio of the free IP addresses", func() {
+			Expect(ippoolmanager.GetIPPoolFreeRatio(newIPPool(4))).To(Equal(1.0))
+			Expect(ippoolmanager.GetIPPoolFreeRatio(newIPPool(4, "172.18.40.10"))).To(Equal(0.75))
+		})
+
+		It("returns zero for the exhausted IPPool", func() {
+			Expect(ippoolmanager.GetIPPoolFreeRatio(newIPPool(1, "172.18.40.10"))).To(BeZero())
+			Expect(ippoolmanager.GetIPPoolFreeRatio(newIPPool(1, "172.18.40.10", "172.18.40.11"))).To(BeZero())
+		})
+
+		It("returns zero until the total IP count is counted", func() {
+			pool := newIPPool(0)
+			Expect(ippoolmanager.GetIPPoolFreeRatio(pool)).To(BeZero())
+
+			pool.Status.TotalIPCount = nil
+			Expect(ippoolmanager.GetIPPoolFreeRatio(pool)).To(BeZero())
+		})
+	})
+})
@@ -20,6 +20,31 @@ type WorkloadEndpointStatus struct {
 
 	// +kubebuilder:validation:Required
 	OwnerControllerName string `json:"ownerControllerName"`
+
+	// Summary is maintained with the current and historical IP allocations,
+	// for scanning the Endpoints quickly.
+	// +kubebuilder:validation:Optional
+	Summary *EndpointSummary `json:"summary,omitempty"`
+}
+
+// EndpointSummary is the compact form of the status of SpiderEndpoint.
+type EndpointSummary struct {
+	// IPs are the IP addresses of all interfaces of the current IP
+	// allocation without the prefix length, separated by commas.
+	// +kubebuilder:validation:Optional
+	IPs string `json:"ips,omitempty"`
+
+	// Pools are the IPPools of the current IP allocation, separated by
+	// commas.
+	// +kubebuilder:validation:Optional
+	Pools string `json:"pools,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	Node string `json:"node,omitempty"`
+
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Optional
+	HistoryCount int `json:"historyCount,omitempty"`
 }
 
 type PodIPAllocation struct {
@@ -34,6 +59,36 @@ type PodIPAllocation struct {
 
 	// +kubebuilder:validation:Optional
 	CreationTime *metav1.Time `json:"creationTime,omitempty"`
+
+	// CNICalls records the CNI calls of the container in order, which are
+	// only recorded in the historical IP allocations.
+	// +kubebuilder:validation:Optional
+	CNICalls []CNICall `json:"cniCalls,omitempty"`
+
+	// LastCreationTime is the creation time of the latest container of the
+	// historical IP allocation, which is set once the consecutive identical
+	// allocations of the restarted containers are collapsed into it, while
+	// CreationTime is the one of the first container.
+	// +kubebuilder:validation:Optional
+	LastCreationTime *metav1.Time `json:"lastCreationTime,omitempty"`
+}
+
+// CNICall is a CNI call of the container, and how the IPAM handled it.
+type CNICall struct {
+	// +kubebuilder:validation:Enum=ADD;DEL
+	// +kubebuilder:validation:Required
+	Operation string `json:"operation"`
+
+	// Node is the node of the spiderpool-agent handling the call.
+	// +kubebuilder:validation:Optional
+	Node string `json:"node,omitempty"`
+
+	// +kubebuilder:validation:Enum=success;failure;rollback
+	// +kubebuilder:validation:Required
+	Result string `json:"result"`
+
+	// +kubebuilder:validation:Optional
+	Time *metav1.Time `json:"time,omitempty"`
 }
 
 type IPAllocationDetail struct {
@@ -52,6 +107,16 @@ type IPAllocationDetail struct {
 	// +kubebuilder:validation:Optional
 	IPv6Pool *string `json:"ipv6Pool,omitempty"`
 
+	// IPv4PoolUID is the UID of the IPv4 IPPool, which tells whether the
+	// IPPool with the same name has been re-created.
+	// +kubebuilder:validation:Optional
+	IPv4PoolUID *string `json:"ipv4PoolUID,omitempty"`
+
+	// IPv6PoolUID is the UID of the IPv6 IPPool, which tells whether the
+	// IPPool with the same name has been re-created.
+	// +kubebuilder:validation:Optional
+	IPv6PoolUID *string `json:"ipv6PoolUID,omitempty"`
+
 	// +kubebuilder:default=0
 	// +kubebuilder:validation:Maximum=4095
 	// +kubebuilder:validation:Minimum=0
@@ -69,16 +134,32 @@ type IPAllocationDetail struct {
 
 	// +kubebuilder:validation:Optional
 	Routes []Route `json:"routes,omitempty"`
+
+	// Stripe is the index of the IP addresses among all IP addresses of the
+	// interface allocated by multi-pool striping. Only the IP addresses of
+	// stripe 0 provide the default route.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Optional
+	Stripe *int `json:"stripe,omitempty"`
+
+	// MAC is the fixed MAC address of the interface, which is requested by
+	// the Pod annotation "ipam.spidernet.io/mac".
+	// +kubebuilder:validation:Optional
+	MAC *string `json:"mac,omitempty"`
 }
 
 // +kubebuilder:resource:categories={spiderpool},path="spiderendpoints",scope="Namespaced",shortName={se},singular="spiderendpoint"
-// +kubebuilder:printcolumn:JSONPath=".status.current.ips[0].interface",description="interface",name="INTERFACE",type=string
-// +kubebuilder:printcolumn:JSONPath=".status.current.ips[0].ipv4Pool",description="ipv4Pool",name="IPV4POOL",type=string
-// +kubebuilder:printcolumn:JSONPath=".status.current.ips[0].ipv4",description="ipv4",name="IPV4",type=string
-// +kubebuilder:printcolumn:JSONPath=".status.current.ips[0].ipv6Pool",description="ipv6Pool",name="IPV6POOL",type=string
-// +kubebuilder:printcolumn:JSONPath=".status.current.ips[0].ipv6",description="ipv6",name="IPV6",type=string
+// +kubebuilder:printcolumn:JSONPath=".status.summary.ips",description="ips",name="IPS",type=string
+// +kubebuilder:printcolumn:JSONPath=".status.summary.pools",description="pools",name="POOLS",type=string
 // +kubebuilder:printcolumn:JSONPath=".status.current.node",description="node",name="NODE",type=string
-// +kubebuilder:printcolumn:JSONPath=".status.current.creationTime",description="creationTime",name="CREATETION TIME",type=date
+// +kubebuilder:printcolumn:JSONPath=".status.current.ips[0].interface",description="interface",name="INTERFACE",type=string,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.current.ips[0].ipv4Pool",description="ipv4Pool",name="IPV4POOL",type=string,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.current.ips[0].ipv4",description="ipv4",name="IPV4",type=string,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.current.ips[0].ipv6Pool",description="ipv6Pool",name="IPV6POOL",type=string,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.current.ips[0].ipv6",description="ipv6",name="IPV6",type=string,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.summary.historyCount",description="historyCount",name="HISTORY",type=integer,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.current.creationTime",description="creationTime",name="CREATETION TIME",type=date,priority=1
+// +kubebuilder:printcolumn:JSONPath=".metadata.creationTimestamp",description="age",name="AGE",type=date
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 
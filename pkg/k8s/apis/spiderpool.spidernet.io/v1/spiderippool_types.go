@@ -23,29 +23,130 @@ type IPPoolSpec struct {
 	// +kubebuilder:validation:Optional
 	Disable *bool `json:"disable,omitempty"`
 
+	// Drain rejects any new IP allocation from the IPPool, while the
+	// existing ones survive until they are released, so that the IPPool
+	// can be decommissioned safely. The progress is reported by the
+	// condition "Drained" of the IPPool status.
+	// +kubebuilder:default=false
+	// +kubebuilder:validation:Optional
+	Drain *bool `json:"drain,omitempty"`
+
 	// +kubebuilder:validation:Optional
 	ExcludeIPs []string `json:"excludeIPs,omitempty"`
 
 	// +kubebuilder:validation:Optional
 	Gateway *string `json:"gateway,omitempty"`
 
+	// StandbyGateways are the gateways to fall back to in order when
+	// 'spec.gateway' is unreachable, such as the backup router of a VRRP
+	// pair. They take effect only if the gateway probe of spiderpool-agent
+	// is enabled.
+	// +kubebuilder:validation:Optional
+	StandbyGateways []string `json:"standbyGateways,omitempty"`
+
+	// ExcludeGateways are the gateway addresses of neighboring subnets,
+	// which are excluded from the IPPool if they pertain to its subnet.
+	// +kubebuilder:validation:Optional
+	ExcludeGateways []string `json:"excludeGateways,omitempty"`
+
+	// AutoExcludeGateways appends 'spec.excludeGateways' pertaining to the
+	// subnet to 'spec.excludeIPs', and removes them again once they are
+	// dropped from 'spec.excludeGateways'. The global switch applies if not
+	// set.
+	// +kubebuilder:validation:Optional
+	AutoExcludeGateways *bool `json:"autoExcludeGateways,omitempty"`
+
+	// ReserveSpecialIPs keeps the network, broadcast and gateway addresses
+	// of the IPPool from being allocated, without listing them in
+	// 'spec.excludeIPs'. The global policy 'reserveSpecialIPs' applies if
+	// not set.
+	// +kubebuilder:validation:Optional
+	ReserveSpecialIPs *bool `json:"reserveSpecialIPs,omitempty"`
+
 	// +kubebuilder:default=0
 	// +kubebuilder:validation:Maximum=4095
 	// +kubebuilder:validation:Minimum=0
 	// +kubebuilder:validation:Optional
 	Vlan *int64 `json:"vlan,omitempty"`
 
+	// VlanRanges override 'spec.vlan' for the IP addresses in their IP
+	// ranges, so that an IPPool may span several VLANs.
+	// +kubebuilder:validation:Optional
+	VlanRanges []VlanRange `json:"vlanRanges,omitempty"`
+
 	// +kubebuilder:validation:Optional
 	Routes []Route `json:"routes,omitempty"`
 
+	// MACPrefix is the first two octets of the MAC addresses derived from
+	// the IP addresses of the IPPool, which are followed by the last four
+	// bytes of the IP address, such as "0a:58". The MAC addresses are
+	// returned to the main CNI plugin to be set on the interfaces.
+	// +kubebuilder:validation:Optional
+	MACPrefix *string `json:"macPrefix,omitempty"`
+
+	// InheritSubnetRoutes makes the IPPool inherit the routes of its
+	// controller Subnet, which are overridden by the routes of the IPPool
+	// with the same destination.
+	// +kubebuilder:default=true
+	// +kubebuilder:validation:Optional
+	InheritSubnetRoutes *bool `json:"inheritSubnetRoutes,omitempty"`
+
+	// DefaultRouteMetric is the metric of the default route via the gateway
+	// of the IPPool, zero means the default one of the system.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Optional
+	DefaultRouteMetric *int64 `json:"defaultRouteMetric,omitempty"`
+
+	// DNS is returned to the Pods which are allocated IP addresses from the
+	// IPPool, such as the resolvers of its VLAN.
+	// +kubebuilder:validation:Optional
+	DNS *IPPoolDNS `json:"dns,omitempty"`
+
+	// LeaseDurationSeconds is the lease of the IP allocations, which are
+	// renewed by spiderpool-agent while their Pods are alive. The expired
+	// allocations of the Pods which no longer exist are reclaimed by
+	// spiderpool-controller, it is disabled if not set.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Optional
+	LeaseDurationSeconds *int64 `json:"leaseDurationSeconds,omitempty"`
+
 	// +kubebuilder:validation:Optional
 	PodAffinity *metav1.LabelSelector `json:"podAffinity,omitempty"`
 
 	// +kubebuilder:validation:Optional
 	NamespaceAffinity *metav1.LabelSelector `json:"namespaceAffinity,omitempty"`
 
+	// AllowedNamespaces are the Namespaces whose Pods are allowed to
+	// allocate IP addresses from the IPPool, however the IPPool is selected,
+	// even as a cluster default IPPool. A '*' matches any sequence of
+	// characters of the name. It is unrestricted if empty.
+	// +kubebuilder:validation:Optional
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+
 	// +kubebuilder:validation:Optional
 	NodeAffinity *metav1.LabelSelector `json:"nodeAffinity,omitempty"`
+
+	// Tenant is the multi-tenant VRF which the IPPool belongs to. IPPools
+	// in different tenants are allowed to overlap, and only the Pods in the
+	// same tenant can allocate IP addresses from the IPPool.
+	// +kubebuilder:validation:Optional
+	Tenant *string `json:"tenant,omitempty"`
+
+	// ParentPool is the IPPool which the IPPool is carved from. The IP
+	// ranges of the IPPool must be the free ones of its parent, which are
+	// delegated to the IPPool rather than allocated by the parent. The
+	// gateway, VLAN and routes of the parent are inherited if not set.
+	// +kubebuilder:validation:Optional
+	ParentPool *string `json:"parentPool,omitempty"`
+}
+
+// IPPoolDNS defines the DNS configuration of SpiderIPPool.
+type IPPoolDNS struct {
+	// +kubebuilder:validation:Optional
+	Nameservers []string `json:"nameservers,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	Search []string `json:"search,omitempty"`
 }
 
 type Route struct {
@@ -56,6 +157,16 @@ type Route struct {
 	Gw string `json:"gw"`
 }
 
+type VlanRange struct {
+	// +kubebuilder:validation:Required
+	IPs []string `json:"ips"`
+
+	// +kubebuilder:validation:Maximum=4095
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Required
+	Vlan int64 `json:"vlan"`
+}
+
 // IPPoolStatus defines the observed state of SpiderIPPool.
 type IPPoolStatus struct {
 	// +kubebuilder:validation:Optional
@@ -65,6 +176,34 @@ type IPPoolStatus struct {
 	// +kubebuilder:validation:Optional
 	TotalIPCount *int64 `json:"totalIPCount,omitempty"`
 
+	// ExcludedIPs are the IP ranges of 'spec.ips' which can't be allocated,
+	// including 'spec.excludeIPs' and the IP addresses reserved by
+	// SpiderReservedIPs. They are not counted in TotalIPCount.
+	// +kubebuilder:validation:Optional
+	ExcludedIPs []string `json:"excludedIPs,omitempty"`
+
+	// InheritedRoutes are the routes inherited from the controller Subnet,
+	// which are synchronized once the routes of the Subnet are changed.
+	// +kubebuilder:validation:Optional
+	InheritedRoutes []Route `json:"inheritedRoutes,omitempty"`
+
+	// DatapathFailureNodes are the datapath failures of the IPPool reported
+	// by the nodes, keyed by the node names, from which spiderpool-controller
+	// sets the conditions GatewayUnreachable and Quarantined of the IPPool.
+	// +kubebuilder:validation:Optional
+	DatapathFailureNodes map[string]DatapathFailure `json:"datapathFailureNodes,omitempty"`
+
+	// NetworkScan is the last scan of the free IP addresses of the IPPool
+	// on the network, which is done by spiderpool-agent.
+	// +kubebuilder:validation:Optional
+	NetworkScan *IPPoolNetworkScan `json:"networkScan,omitempty"`
+
+	// SuspectedLeakedIPs are the allocated IP addresses which the IP garbage
+	// collection found leaked but didn't release, such as in its dry-run
+	// mode, keyed by the IP addresses.
+	// +kubebuilder:validation:Optional
+	SuspectedLeakedIPs map[string]IPLeak `json:"suspectedLeakedIPs,omitempty"`
+
 	// +kubebuilder:validation:Minimum=0
 	// +kubebuilder:validation:Optional
 	AllocatedIPCount *int64 `json:"allocatedIPCount,omitempty"`
@@ -72,6 +211,122 @@ type IPPoolStatus struct {
 	// +kubebuilder:validation:Minimum=0
 	// +kubebuilder:validation:Optional
 	AutoDesiredIPCount *int64 `json:"autoDesiredIPCount,omitempty"`
+
+	// AutoElasticIPCount is the IP number of the auto-created IPPool to
+	// keep the headroom of free IP addresses for the actual usage. The
+	// auto-created IPPool is scaled to the larger one of it and
+	// AutoDesiredIPCount.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Optional
+	AutoElasticIPCount *int64 `json:"autoElasticIPCount,omitempty"`
+
+	// PredictedExhaustionTime is the time when all IP addresses of the
+	// IPPool are predicted to be allocated, which is forecasted from the
+	// recent allocation velocity. It is unset if the allocated IP addresses
+	// are not growing.
+	// +kubebuilder:validation:Optional
+	PredictedExhaustionTime *metav1.Time `json:"predictedExhaustionTime,omitempty"`
+
+	// +listType=map
+	// +listMapKey=type
+	// +kubebuilder:validation:Optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	SpecChangelog []IPPoolSpecChange `json:"specChangelog,omitempty"`
+}
+
+// IPLeak is the evidence that an allocated IP address is leaked, and the
+// action planned by the IP garbage collection.
+type IPLeak struct {
+	// +kubebuilder:validation:Required
+	Namespace string `json:"namespace"`
+
+	// +kubebuilder:validation:Required
+	Pod string `json:"pod"`
+
+	// +kubebuilder:validation:Optional
+	ContainerID string `json:"containerID,omitempty"`
+
+	// Evidence tells why the IP address is suspected leaked.
+	// +kubebuilder:validation:Enum=PodNotFound;PodTerminated;StaleContainerID
+	// +kubebuilder:validation:Required
+	Evidence string `json:"evidence"`
+
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
+
+	// +kubebuilder:validation:Enum=ReleaseIP;ReleaseIPAndRemoveFinalizer
+	// +kubebuilder:validation:Required
+	Action string `json:"action"`
+
+	// DetectedAt is the time when the leak was detected first.
+	// +kubebuilder:validation:Required
+	DetectedAt metav1.Time `json:"detectedAt"`
+}
+
+// DatapathFailure is the last datapath failures of the IPPool reported by
+// a node, which expire after a window of spiderpool-controller.
+type DatapathFailure struct {
+	// GatewayUnreachableTime is the last time the node found the gateway of
+	// the IPPool unreachable.
+	// +kubebuilder:validation:Optional
+	GatewayUnreachableTime *metav1.Time `json:"gatewayUnreachableTime,omitempty"`
+
+	// RepeatedFailureTime is the last time the datapath failures of the
+	// IPPool on the node, such as the IP conflicts or the unreachable
+	// gateways, reached the threshold of the node.
+	// +kubebuilder:validation:Optional
+	RepeatedFailureTime *metav1.Time `json:"repeatedFailureTime,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
+}
+
+// IPPoolNetworkScan records which node scans the free IP addresses of the
+// IPPool, and the ones observed in use on the network outside Kubernetes.
+type IPPoolNetworkScan struct {
+	// Node is the node whose spiderpool-agent scans the IPPool.
+	// +kubebuilder:validation:Required
+	Node string `json:"node"`
+
+	// +kubebuilder:validation:Required
+	StartTime metav1.Time `json:"startTime"`
+
+	// CompletionTime is unset while the scan is in progress.
+	// +kubebuilder:validation:Optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// InUseIPs are the free IP ranges of the IPPool which replied to the
+	// ARP or NDP probes of the last completed scan.
+	// +kubebuilder:validation:Optional
+	InUseIPs []string `json:"inUseIPs,omitempty"`
+}
+
+// IPPoolSpecChange records who changed the spec of SpiderIPPool and what
+// was changed.
+type IPPoolSpecChange struct {
+	// +kubebuilder:validation:Required
+	User string `json:"user"`
+
+	// +kubebuilder:validation:Required
+	Time metav1.Time `json:"time"`
+
+	// +kubebuilder:validation:Optional
+	AddedIPs []string `json:"addedIPs,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	RemovedIPs []string `json:"removedIPs,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	AddedExcludeIPs []string `json:"addedExcludeIPs,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	RemovedExcludeIPs []string `json:"removedExcludeIPs,omitempty"`
+
+	// Fields are the other changed fields of spec.
+	// +kubebuilder:validation:Optional
+	Fields []string `json:"fields,omitempty"`
 }
 
 // PoolIPAllocations is a map of IP allocation details indexed by IP address.
@@ -93,11 +348,21 @@ type PoolIPAllocation struct {
 	// +kubebuilder:validation:Required
 	Pod string `json:"pod"`
 
+	// PodUID tells the Pod apart from the re-created ones with the same
+	// name, it is not recorded by the earlier versions.
+	// +kubebuilder:validation:Optional
+	PodUID string `json:"podUID,omitempty"`
+
 	// +kubebuilder:validation:Required
 	OwnerControllerType string `json:"ownerControllerType"`
 
 	// +kubebuilder:validation:Required
 	OwnerControllerName string `json:"ownerControllerName"`
+
+	// LeaseRenewTime is the last time the lease of the IP allocation was
+	// renewed, it is only recorded if the lease of the IPPool is set.
+	// +kubebuilder:validation:Optional
+	LeaseRenewTime *metav1.Time `json:"leaseRenewTime,omitempty"`
 }
 
 // +kubebuilder:resource:categories={spiderpool},path="spiderippools",scope="Cluster",shortName={sp},singular="spiderippool"
@@ -15,18 +15,136 @@ type ReservedIPSpec struct {
 
 	// +kubebuilder:validation:Optional
 	IPs []string `json:"ips,omitempty"`
+
+	// ExcludeIPs are carved out of 'spec.ips', they're left to the IPPools
+	// rather than reserved.
+	// +kubebuilder:validation:Optional
+	ExcludeIPs []string `json:"excludeIPs,omitempty"`
+
+	// PoolSelector selects the IPPools whose IP addresses are reserved, all
+	// IPPools are selected if it's not set.
+	// +kubebuilder:validation:Optional
+	PoolSelector *metav1.LabelSelector `json:"poolSelector,omitempty"`
+
+	// NamespaceSelector selects the namespaces of the Pods which the IP
+	// addresses are reserved from, all namespaces are selected if it's not
+	// set.
+	// +kubebuilder:validation:Optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// ExpireAt is the time when the reservation expires. Once it expires,
+	// the IP addresses are returned to the IPPools and the SpiderReservedIP
+	// is deleted.
+	// +kubebuilder:validation:Optional
+	ExpireAt *metav1.Time `json:"expireAt,omitempty"`
+
+	// TTL is the lifetime of the reservation since the SpiderReservedIP is
+	// created. The earlier one of it and ExpireAt takes effect if both are
+	// set.
+	// +kubebuilder:validation:Optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
+	// Claim names the Pods which will consume the reserved IP addresses
+	// later. The IP addresses are still reserved from all the other Pods.
+	// +kubebuilder:validation:Optional
+	Claim *ReservedIPClaim `json:"claim,omitempty"`
+
+	// VacateAllocatedIPs admits reserving the IP addresses which are still
+	// allocated to Pods. These allocations are released rather than reused
+	// when their Pods restart.
+	// +kubebuilder:default=false
+	// +kubebuilder:validation:Optional
+	VacateAllocatedIPs *bool `json:"vacateAllocatedIPs,omitempty"`
+}
+
+// ReservedIPClaim names the Pods which the reserved IP addresses are handed
+// out to. Once an IP address is allocated to a claimant Pod, it's removed
+// from the SpiderReservedIP.
+type ReservedIPClaim struct {
+	// +kubebuilder:validation:Required
+	Namespace string `json:"namespace"`
+
+	// PodName is the name of the claimant Pod. Exactly one of it and
+	// PodSelector must be set.
+	// +kubebuilder:validation:Optional
+	PodName string `json:"podName,omitempty"`
+
+	// PodSelector selects the claimant Pods in the namespace.
+	// +kubebuilder:validation:Optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+}
+
+// ReservedIPStatus defines the observed state of SpiderReservedIP.
+type ReservedIPStatus struct {
+	// +listType=map
+	// +listMapKey=type
+	// +kubebuilder:validation:Optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// AffectedIPPools are the IPPools whose IP addresses are reserved by
+	// the SpiderReservedIP, sorted by name.
+	// +kubebuilder:validation:Optional
+	AffectedIPPools []ReservedIPAffectedPool `json:"affectedIPPools,omitempty"`
+
+	// BlockedIPCount is the count of the IP addresses of AffectedIPPools
+	// which would be free for allocation without the SpiderReservedIP.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Optional
+	BlockedIPCount *int64 `json:"blockedIPCount,omitempty"`
+
+	// BoundClaims are the claimed IP addresses bound to the claimant Pods
+	// before they're allocated, keyed by IP address. A bound IP address is
+	// only handed out to its Pod.
+	// +kubebuilder:validation:Optional
+	BoundClaims map[string]ReservedIPBoundClaim `json:"boundClaims,omitempty"`
+}
+
+// ReservedIPBoundClaim is the claimant Pod which a claimed IP address is
+// bound to.
+type ReservedIPBoundClaim struct {
+	// +kubebuilder:validation:Required
+	Pod string `json:"pod"`
+
+	// +kubebuilder:validation:Required
+	PodUID string `json:"podUID"`
+}
+
+// ReservedIPAffectedPool is an IPPool whose IP addresses are reserved.
+type ReservedIPAffectedPool struct {
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// ReservedIPCount is the count of the IP addresses of the IPPool
+	// reserved by the SpiderReservedIP, excluding 'spec.excludeIPs' of the
+	// IPPool.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Required
+	ReservedIPCount int64 `json:"reservedIPCount"`
+
+	// BlockedIPCount is the count of the reserved IP addresses which are
+	// not allocated from the IPPool.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Required
+	BlockedIPCount int64 `json:"blockedIPCount"`
 }
 
 // +kubebuilder:resource:categories={spiderpool},path="spiderreservedips",scope="Cluster",shortName={sr},singular="spiderreservedip"
 // +kubebuilder:printcolumn:JSONPath=".spec.ipVersion",description="ipVersion",name="VERSION",type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.expireAt",description="expireAt",name="EXPIRE AT",type=date
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type==\"Conflicting\")].status",description="conflicting",name="CONFLICTING",type=string
+// +kubebuilder:printcolumn:JSONPath=".status.blockedIPCount",description="blockedIPCount",name="BLOCKED-IP-COUNT",type=integer
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +genclient
+// +genclient:nonNamespaced
 
 // SpiderReservedIP is the Schema for the spiderreservedips API.
 type SpiderReservedIP struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec ReservedIPSpec `json:"spec,omitempty"`
+	Spec   ReservedIPSpec   `json:"spec,omitempty"`
+	Status ReservedIPStatus `json:"status,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -33,6 +33,22 @@ type SubnetSpec struct {
 
 	// +kubebuilder:validation:Optional
 	Routes []Route `json:"routes,omitempty"`
+
+	// FallbackSubnets are the SpiderSubnets, in order, to borrow IP
+	// addresses from for the auto-created IPPools once this one runs out
+	// of free IP addresses. They must have the same IP version and VLAN.
+	// +kubebuilder:validation:Optional
+	FallbackSubnets []string `json:"fallbackSubnets,omitempty"`
+
+	// DefaultNamespaceAffinity is copied into the auto-created IPPools of
+	// this subnet, and kept in sync once it's changed.
+	// +kubebuilder:validation:Optional
+	DefaultNamespaceAffinity *metav1.LabelSelector `json:"defaultNamespaceAffinity,omitempty"`
+
+	// DefaultNodeAffinity is copied into the auto-created IPPools of this
+	// subnet, and kept in sync once it's changed.
+	// +kubebuilder:validation:Optional
+	DefaultNodeAffinity *metav1.LabelSelector `json:"defaultNodeAffinity,omitempty"`
 }
 
 // SubnetStatus defines the observed state of SpiderSubnet.
@@ -47,6 +63,55 @@ type SubnetStatus struct {
 	// +kubebuilder:validation:Minimum=0
 	// +kubebuilder:validation:Optional
 	AllocatedIPCount *int64 `json:"allocatedIPCount,omitempty"`
+
+	// BorrowedIPPools are the auto-created IPPools borrowing IP addresses
+	// from the fallback subnets on behalf of this one, indexed by pool name.
+	// The IP addresses are returned once the IPPools are reclaimed.
+	// +kubebuilder:validation:Optional
+	BorrowedIPPools BorrowedIPPools `json:"borrowedIPPools,omitempty"`
+
+	// ApplicationIPPools breaks down the auto-created IPPools controlled by
+	// this subnet by application, sorted by pool name.
+	// +kubebuilder:validation:Optional
+	ApplicationIPPools []ApplicationIPPool `json:"applicationIPPools,omitempty"`
+}
+
+// BorrowedIPPools is a map of borrowed IPPool details indexed by pool name.
+type BorrowedIPPools map[string]BorrowedIPPool
+
+type BorrowedIPPool struct {
+	// +kubebuilder:validation:Required
+	Subnet string `json:"subnet"`
+
+	// +kubebuilder:validation:Optional
+	IPs []string `json:"ips"`
+}
+
+// ApplicationIPPool is the usage of the auto-created IPPool of an application.
+type ApplicationIPPool struct {
+	// +kubebuilder:validation:Required
+	Kind string `json:"kind"`
+
+	// +kubebuilder:validation:Required
+	Namespace string `json:"namespace"`
+
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:Required
+	IPPool string `json:"ipPool"`
+
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Optional
+	DesiredIPCount *int64 `json:"desiredIPCount,omitempty"`
+
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Optional
+	TotalIPCount *int64 `json:"totalIPCount,omitempty"`
+
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Optional
+	AllocatedIPCount *int64 `json:"allocatedIPCount,omitempty"`
 }
 
 // PoolIPPreAllocations is a map of pool IP pre-allocation details indexed by pool name.
@@ -5,11 +5,66 @@ package v1
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"sync/atomic"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	stringutil "github.com/spidernet-io/spiderpool/pkg/utils/string"
 )
 
+// StringOptions controls the String() outputs of the Spiderpool objects,
+// which are mostly used in logs.
+type StringOptions struct {
+	// SummarizeThreshold is the maximum number of the entries of
+	// 'status.allocatedIPs' of SpiderIPPool and 'status.history' of
+	// SpiderEndpoint to be printed in full, the larger ones are summarized
+	// as the count with the first and last entries. Zero means that they
+	// are never summarized.
+	SummarizeThreshold int
+
+	// RedactAnnotations hides the values of the annotations of objects,
+	// which may carry sensitive data or large blobs like the last applied
+	// configuration of kubectl. The keys are kept.
+	RedactAnnotations bool
+}
+
+const redactedValue = "<redacted>"
+
+var stringOptions atomic.Pointer[StringOptions]
+
+// SetStringOptions sets the options of the String() outputs of all
+// Spiderpool objects.
+func SetStringOptions(opts StringOptions) {
+	stringOptions.Store(&opts)
+}
+
+func getStringOptions() StringOptions {
+	if opts := stringOptions.Load(); opts != nil {
+		return *opts
+	}
+
+	return StringOptions{}
+}
+
+func objectMetaString(meta metav1.ObjectMeta) string {
+	if getStringOptions().RedactAnnotations && len(meta.Annotations) != 0 {
+		annotations := make(map[string]string, len(meta.Annotations))
+		for k := range meta.Annotations {
+			annotations[k] = redactedValue
+		}
+		meta.Annotations = annotations
+	}
+
+	return strings.Replace(fmt.Sprintf("%v", meta), `&`, ``, 1)
+}
+
+func shouldSummarize(n int) bool {
+	threshold := getStringOptions().SummarizeThreshold
+	return threshold > 0 && n > threshold
+}
+
 // String serves for SpiderIPPool
 func (in *SpiderIPPool) String() string {
 	if in == nil {
@@ -17,7 +72,7 @@ func (in *SpiderIPPool) String() string {
 	}
 
 	s := strings.Join([]string{`&SpiderIPPool{`,
-		`ObjectMeta:` + strings.Replace(fmt.Sprintf("%v", in.ObjectMeta), `&`, ``, 1) + `,`,
+		`ObjectMeta:` + objectMetaString(in.ObjectMeta) + `,`,
 		`Spec:` + strings.Replace(strings.Replace(in.Spec.String(), "IPPoolSpec", "IPPoolSpec", 1), `&`, ``, 1) + `,`,
 		`Status:` + strings.Replace(strings.Replace(in.Status.String(), "IPPoolStatus", "IPPoolStatus", 1), `&`, ``, 1) + `,`,
 		`}`,
@@ -36,13 +91,27 @@ func (in *IPPoolSpec) String() string {
 		`Subnet:` + fmt.Sprintf("%v", in.Subnet) + `,`,
 		`IPs:` + fmt.Sprintf("%v", in.IPs) + `,`,
 		`Disable:` + stringutil.ValueToStringGenerated(in.Disable) + `,`,
+		`Drain:` + stringutil.ValueToStringGenerated(in.Drain) + `,`,
 		`ExcludeIPs:` + fmt.Sprintf("%v", in.ExcludeIPs) + `,`,
 		`Gateway:` + stringutil.ValueToStringGenerated(in.Gateway) + `,`,
+		`StandbyGateways:` + fmt.Sprintf("%v", in.StandbyGateways) + `,`,
+		`ExcludeGateways:` + fmt.Sprintf("%v", in.ExcludeGateways) + `,`,
+		`AutoExcludeGateways:` + stringutil.ValueToStringGenerated(in.AutoExcludeGateways) + `,`,
+		`ReserveSpecialIPs:` + stringutil.ValueToStringGenerated(in.ReserveSpecialIPs) + `,`,
 		`Vlan:` + stringutil.ValueToStringGenerated(in.Vlan) + `,`,
+		`VlanRanges:` + fmt.Sprintf("%+v", in.VlanRanges) + `,`,
 		`Routes:` + fmt.Sprintf("%+v", in.Routes) + `,`,
+		`MACPrefix:` + stringutil.ValueToStringGenerated(in.MACPrefix) + `,`,
+		`InheritSubnetRoutes:` + stringutil.ValueToStringGenerated(in.InheritSubnetRoutes) + `,`,
+		`DefaultRouteMetric:` + stringutil.ValueToStringGenerated(in.DefaultRouteMetric) + `,`,
+		`DNS:` + fmt.Sprintf("%+v", in.DNS) + `,`,
+		`LeaseDurationSeconds:` + stringutil.ValueToStringGenerated(in.LeaseDurationSeconds) + `,`,
 		`PodAffinity:` + fmt.Sprintf("%v", in.PodAffinity) + `,`,
 		`NamespaceAffinity:` + fmt.Sprintf("%v", in.NamespaceAffinity) + `,`,
+		`AllowedNamespaces:` + fmt.Sprintf("%v", in.AllowedNamespaces) + `,`,
 		`NodeAffinity:` + fmt.Sprintf("%v", in.NodeAffinity) + `,`,
+		`Tenant:` + stringutil.ValueToStringGenerated(in.Tenant) + `,`,
+		`ParentPool:` + stringutil.ValueToStringGenerated(in.ParentPool) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -55,15 +124,38 @@ func (in *IPPoolStatus) String() string {
 	}
 
 	s := strings.Join([]string{`&IPPoolStatus{`,
-		`AllocatedIPs:` + fmt.Sprintf("%+v", in.AllocatedIPs) + `,`,
+		`AllocatedIPs:` + in.AllocatedIPs.String() + `,`,
 		`TotalIPCount:` + stringutil.ValueToStringGenerated(in.TotalIPCount) + `,`,
+		`ExcludedIPs:` + fmt.Sprintf("%v", in.ExcludedIPs) + `,`,
+		`InheritedRoutes:` + fmt.Sprintf("%+v", in.InheritedRoutes) + `,`,
+		`DatapathFailureNodes:` + fmt.Sprintf("%+v", in.DatapathFailureNodes) + `,`,
+		`NetworkScan:` + fmt.Sprintf("%+v", in.NetworkScan) + `,`,
+		`SuspectedLeakedIPs:` + fmt.Sprintf("%+v", in.SuspectedLeakedIPs) + `,`,
 		`AllocatedIPCount:` + stringutil.ValueToStringGenerated(in.AllocatedIPCount) + `,`,
 		`AutoDesiredIPCount:` + stringutil.ValueToStringGenerated(in.AutoDesiredIPCount) + `,`,
+		`AutoElasticIPCount:` + stringutil.ValueToStringGenerated(in.AutoElasticIPCount) + `,`,
+		`PredictedExhaustionTime:` + fmt.Sprintf("%v", in.PredictedExhaustionTime) + `,`,
 		`}`,
 	}, "")
 	return s
 }
 
+// String serves for SpiderIPPool Status PoolIPAllocations
+func (in PoolIPAllocations) String() string {
+	if !shouldSummarize(len(in)) {
+		return fmt.Sprintf("%+v", map[string]PoolIPAllocation(in))
+	}
+
+	ips := make([]string, 0, len(in))
+	for ip := range in {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	first, last := ips[0], ips[len(ips)-1]
+
+	return fmt.Sprintf("PoolIPAllocations{Count:%d,First:%s:%+v,Last:%s:%+v,}", len(in), first, in[first], last, in[last])
+}
+
 // String serves for SpiderEndpoint
 func (in *SpiderEndpoint) String() string {
 	if in == nil {
@@ -71,7 +163,7 @@ func (in *SpiderEndpoint) String() string {
 	}
 
 	s := strings.Join([]string{`&SpiderEndpoint{`,
-		`ObjectMeta:` + strings.Replace(fmt.Sprintf("%v", in.ObjectMeta), `&`, ``, 1) + `,`,
+		`ObjectMeta:` + objectMetaString(in.ObjectMeta) + `,`,
 		`Status:` + in.Status.String() + `,`,
 		`}`,
 	}, "")
@@ -85,8 +177,15 @@ func (in *WorkloadEndpointStatus) String() string {
 	}
 
 	repeatedStringForHistory := "[]History{"
-	for _, f := range in.History {
-		repeatedStringForHistory += strings.Replace(strings.Replace(f.String(), "History", "History", 1), `&`, ``, 1) + ","
+	if shouldSummarize(len(in.History)) {
+		first, last := in.History[0], in.History[len(in.History)-1]
+		repeatedStringForHistory += fmt.Sprintf("Count:%d,", len(in.History)) +
+			`First:` + strings.Replace(first.String(), `&`, ``, 1) + "," +
+			`Last:` + strings.Replace(last.String(), `&`, ``, 1) + ","
+	} else {
+		for _, f := range in.History {
+			repeatedStringForHistory += strings.Replace(strings.Replace(f.String(), "History", "History", 1), `&`, ``, 1) + ","
+		}
 	}
 	repeatedStringForHistory += "}"
 
@@ -95,6 +194,7 @@ func (in *WorkloadEndpointStatus) String() string {
 		`History:` + repeatedStringForHistory + `,`,
 		`OwnerControllerType:` + fmt.Sprintf("%v", in.OwnerControllerType) + `,`,
 		`OwnerControllerName` + fmt.Sprintf("%v", in.OwnerControllerName) + `,`,
+		`Summary:` + fmt.Sprintf("%+v", in.Summary) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -117,6 +217,8 @@ func (in *PodIPAllocation) String() string {
 		`Node:` + stringutil.ValueToStringGenerated(in.Node) + `,`,
 		`IPs:` + repeatedStringForIPs + `,`,
 		`CreationTime:` + fmt.Sprintf("%v", in.CreationTime) + `,`,
+		`CNICalls:` + fmt.Sprintf("%+v", in.CNICalls) + `,`,
+		`LastCreationTime:` + fmt.Sprintf("%v", in.LastCreationTime) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -134,11 +236,15 @@ func (in *IPAllocationDetail) String() string {
 		`IPv6:` + stringutil.ValueToStringGenerated(in.IPv6) + `,`,
 		`IPv4Pool:` + stringutil.ValueToStringGenerated(in.IPv4Pool) + `,`,
 		`IPv6Pool:` + stringutil.ValueToStringGenerated(in.IPv6Pool) + `,`,
+		`IPv4PoolUID:` + stringutil.ValueToStringGenerated(in.IPv4PoolUID) + `,`,
+		`IPv6PoolUID:` + stringutil.ValueToStringGenerated(in.IPv6PoolUID) + `,`,
 		`Vlan:` + stringutil.ValueToStringGenerated(in.Vlan) + `,`,
 		`IPv4Gateway:` + stringutil.ValueToStringGenerated(in.IPv4Gateway) + `,`,
 		`IPv6Gateway:` + stringutil.ValueToStringGenerated(in.IPv6Gateway) + `,`,
 		`CleanGateway:` + stringutil.ValueToStringGenerated(in.CleanGateway) + `,`,
 		`Routes:` + fmt.Sprintf("%+v", in.Routes) + `,`,
+		`Stripe:` + stringutil.ValueToStringGenerated(in.Stripe) + `,`,
+		`MAC:` + stringutil.ValueToStringGenerated(in.MAC) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -151,8 +257,9 @@ func (in *SpiderReservedIP) String() string {
 	}
 
 	s := strings.Join([]string{`&SpiderReservedIP{`,
-		`ObjectMeta:` + strings.Replace(fmt.Sprintf("%v", in.ObjectMeta), `&`, ``, 1) + `,`,
+		`ObjectMeta:` + objectMetaString(in.ObjectMeta) + `,`,
 		`Spec:` + strings.Replace(strings.Replace(in.Spec.String(), "ReservedIPSpec", "ReservedIPSpec", 1), `&`, ``, 1) + `,`,
+		`Status:` + strings.Replace(strings.Replace(in.Status.String(), "ReservedIPStatus", "ReservedIPStatus", 1), `&`, ``, 1) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -167,6 +274,29 @@ func (in *ReservedIPSpec) String() string {
 	s := strings.Join([]string{`&ReservedIPSpec{`,
 		`IPVersion:` + stringutil.ValueToStringGenerated(in.IPVersion) + `,`,
 		`IPs:` + fmt.Sprintf("%v", in.IPs) + `,`,
+		`ExcludeIPs:` + fmt.Sprintf("%v", in.ExcludeIPs) + `,`,
+		`PoolSelector:` + fmt.Sprintf("%v", in.PoolSelector) + `,`,
+		`NamespaceSelector:` + fmt.Sprintf("%v", in.NamespaceSelector) + `,`,
+		`ExpireAt:` + fmt.Sprintf("%v", in.ExpireAt) + `,`,
+		`TTL:` + fmt.Sprintf("%v", in.TTL) + `,`,
+		`Claim:` + fmt.Sprintf("%+v", in.Claim) + `,`,
+		`VacateAllocatedIPs:` + stringutil.ValueToStringGenerated(in.VacateAllocatedIPs) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+
+// String serves for SpiderReservedIP Status
+func (in *ReservedIPStatus) String() string {
+	if in == nil {
+		return "nil"
+	}
+
+	s := strings.Join([]string{`&ReservedIPStatus{`,
+		`Conditions:` + fmt.Sprintf("%+v", in.Conditions) + `,`,
+		`AffectedIPPools:` + fmt.Sprintf("%+v", in.AffectedIPPools) + `,`,
+		`BlockedIPCount:` + stringutil.ValueToStringGenerated(in.BlockedIPCount) + `,`,
+		`BoundClaims:` + fmt.Sprintf("%+v", in.BoundClaims) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -179,7 +309,7 @@ func (in *SpiderSubnet) String() string {
 	}
 
 	s := strings.Join([]string{`&SpiderSubnet{`,
-		`ObjectMeta:` + strings.Replace(fmt.Sprintf("%v", in.ObjectMeta), `&`, ``, 1) + `,`,
+		`ObjectMeta:` + objectMetaString(in.ObjectMeta) + `,`,
 		`Spec:` + strings.Replace(strings.Replace(in.Spec.String(), "SubnetSpec", "SubnetSpec", 1), `&`, ``, 1) + `,`,
 		`Status:` + strings.Replace(strings.Replace(in.Status.String(), "SubnetStatus", "SubnetStatus", 1), `&`, ``, 1) + `,`,
 		`}`,
@@ -201,6 +331,9 @@ func (in *SubnetSpec) String() string {
 		`Gateway:` + stringutil.ValueToStringGenerated(in.Gateway) + `,`,
 		`Vlan:` + stringutil.ValueToStringGenerated(in.Vlan) + `,`,
 		`Routes:` + fmt.Sprintf("%+v", in.Routes) + `,`,
+		`FallbackSubnets:` + fmt.Sprintf("%v", in.FallbackSubnets) + `,`,
+		`DefaultNamespaceAffinity:` + fmt.Sprintf("%v", in.DefaultNamespaceAffinity) + `,`,
+		`DefaultNodeAffinity:` + fmt.Sprintf("%v", in.DefaultNodeAffinity) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -216,6 +349,8 @@ func (in *SubnetStatus) String() string {
 		`ControlledIPPools:` + fmt.Sprintf("%v", in.ControlledIPPools) + `,`,
 		`TotalIPCount:` + stringutil.ValueToStringGenerated(in.TotalIPCount) + `,`,
 		`AllocatedIPCount:` + stringutil.ValueToStringGenerated(in.AllocatedIPCount) + `,`,
+		`BorrowedIPPools:` + fmt.Sprintf("%v", in.BorrowedIPPools) + `,`,
+		`ApplicationIPPools:` + fmt.Sprintf("%+v", in.ApplicationIPPools) + `,`,
 		`}`,
 	}, "")
 	return s
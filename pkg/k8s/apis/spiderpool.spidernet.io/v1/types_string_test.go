@@ -0,0 +1,124 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+)
+
+var _ = Describe("String outputs", Label("types_string_test"), func() {
+	setStringOptions := func(opts spiderpoolv1.StringOptions) {
+		spiderpoolv1.SetStringOptions(opts)
+		DeferCleanup(spiderpoolv1.SetStringOptions, spiderpoolv1.StringOptions{})
+	}
+
+	newAllocations := func(n int) spiderpoolv1.PoolIPAllocations {
+		allocations := spiderpoolv1.PoolIPAllocations{}
+		for i := 1; i <= n; i++ {
+			allocations[fmt.Sprintf("172.18.40.%d", i)] = spiderpoolv1.PoolIPAllocation{ContainerID: fmt.Sprintf("container%d", i)}
+		}
+		return allocations
+	}
+
+	newHistory := func(n int) []spiderpoolv1.PodIPAllocation {
+		history := make([]spiderpoolv1.PodIPAllocation, 0, n)
+		for i := 1; i <= n; i++ {
+			history = append(history, spiderpoolv1.PodIPAllocation{ContainerID: fmt.Sprintf("container%d", i)})
+		}
+		return history
+	}
+
+	Describe("PoolIPAllocations", func() {
+		It("prints all the allocations by default", func() {
+			s := newAllocations(5).String()
+			for i := 1; i <= 5; i++ {
+				Expect(s).To(ContainSubstring(fmt.Sprintf("container%d", i)))
+			}
+		})
+
+		It("prints all the allocations within the threshold", func() {
+			setStringOptions(spiderpoolv1.StringOptions{SummarizeThreshold: 5})
+			Expect(newAllocations(5).String()).NotTo(ContainSubstring("Count:"))
+		})
+
+		It("summarizes the allocations beyond the threshold", func() {
+			setStringOptions(spiderpoolv1.StringOptions{SummarizeThreshold: 2})
+			s := newAllocations(3).String()
+			Expect(s).To(HavePrefix("PoolIPAllocations{Count:3,"))
+			Expect(s).To(ContainSubstring("First:172.18.40.1:"))
+			Expect(s).To(ContainSubstring("Last:172.18.40.3:"))
+			Expect(s).NotTo(ContainSubstring("container2"))
+		})
+
+		It("summarizes the allocations of SpiderIPPool", func() {
+			setStringOptions(spiderpoolv1.StringOptions{SummarizeThreshold: 2})
+			pool := &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: "pool"}}
+			pool.Status.AllocatedIPs = newAllocations(3)
+			Expect(pool.String()).To(ContainSubstring("AllocatedIPs:PoolIPAllocations{Count:3,"))
+		})
+	})
+
+	Describe("WorkloadEndpointStatus", func() {
+		It("prints the whole history by default", func() {
+			status := &spiderpoolv1.WorkloadEndpointStatus{History: newHistory(3)}
+			s := status.String()
+			for i := 1; i <= 3; i++ {
+				Expect(s).To(ContainSubstring(fmt.Sprintf("container%d", i)))
+			}
+		})
+
+		It("summarizes the history beyond the threshold", func() {
+			setStringOptions(spiderpoolv1.StringOptions{SummarizeThreshold: 2})
+			endpoint := &spiderpoolv1.SpiderEndpoint{ObjectMeta: metav1.ObjectMeta{Name: "pod"}}
+			endpoint.Status.History = newHistory(3)
+			s := endpoint.String()
+			Expect(s).To(ContainSubstring("[]History{Count:3,"))
+			Expect(s).To(ContainSubstring("container1"))
+			Expect(s).To(ContainSubstring("container3"))
+			Expect(s).NotTo(ContainSubstring("container2"))
+		})
+	})
+
+	Describe("annotations", func() {
+		var pool *spiderpoolv1.SpiderIPPool
+		BeforeEach(func() {
+			pool = &spiderpoolv1.SpiderIPPool{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "pool",
+					Annotations: map[string]string{"secret-key": "secret-value"},
+				},
+			}
+		})
+
+		It("prints the annotations by default", func() {
+			Expect(pool.String()).To(ContainSubstring("secret-value"))
+		})
+
+		It("redacts the values of annotations", func() {
+			setStringOptions(spiderpoolv1.StringOptions{RedactAnnotations: true})
+			s := pool.String()
+			Expect(s).To(ContainSubstring("secret-key"))
+			Expect(s).NotTo(ContainSubstring("secret-value"))
+			Expect(pool.Annotations).To(HaveKeyWithValue("secret-key", "secret-value"))
+		})
+
+		It("redacts the annotations of all objects", func() {
+			setStringOptions(spiderpoolv1.StringOptions{RedactAnnotations: true})
+			objs := []fmt.Stringer{
+				&spiderpoolv1.SpiderEndpoint{ObjectMeta: pool.ObjectMeta},
+				&spiderpoolv1.SpiderReservedIP{ObjectMeta: pool.ObjectMeta},
+				&spiderpoolv1.SpiderSubnet{ObjectMeta: pool.ObjectMeta},
+			}
+			for _, obj := range objs {
+				Expect(obj.String()).NotTo(ContainSubstring("secret-value"))
+			}
+		})
+	})
+})
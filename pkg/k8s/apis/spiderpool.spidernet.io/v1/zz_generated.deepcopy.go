@@ -13,6 +13,134 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationIPPool) DeepCopyInto(out *ApplicationIPPool) {
+	*out = *in
+	if in.DesiredIPCount != nil {
+		in, out := &in.DesiredIPCount, &out.DesiredIPCount
+		*out = new(int64)
+		**out = **in
+	}
+	if in.TotalIPCount != nil {
+		in, out := &in.TotalIPCount, &out.TotalIPCount
+		*out = new(int64)
+		**out = **in
+	}
+	if in.AllocatedIPCount != nil {
+		in, out := &in.AllocatedIPCount, &out.AllocatedIPCount
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationIPPool.
+func (in *ApplicationIPPool) DeepCopy() *ApplicationIPPool {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationIPPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BorrowedIPPool) DeepCopyInto(out *BorrowedIPPool) {
+	*out = *in
+	if in.IPs != nil {
+		in, out := &in.IPs, &out.IPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BorrowedIPPool.
+func (in *BorrowedIPPool) DeepCopy() *BorrowedIPPool {
+	if in == nil {
+		return nil
+	}
+	out := new(BorrowedIPPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in BorrowedIPPools) DeepCopyInto(out *BorrowedIPPools) {
+	{
+		in := &in
+		*out = make(BorrowedIPPools, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BorrowedIPPools.
+func (in BorrowedIPPools) DeepCopy() BorrowedIPPools {
+	if in == nil {
+		return nil
+	}
+	out := new(BorrowedIPPools)
+	in.DeepCopyInto(out)
+	return *out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CNICall) DeepCopyInto(out *CNICall) {
+	*out = *in
+	if in.Time != nil {
+		in, out := &in.Time, &out.Time
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CNICall.
+func (in *CNICall) DeepCopy() *CNICall {
+	if in == nil {
+		return nil
+	}
+	out := new(CNICall)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatapathFailure) DeepCopyInto(out *DatapathFailure) {
+	*out = *in
+	if in.GatewayUnreachableTime != nil {
+		in, out := &in.GatewayUnreachableTime, &out.GatewayUnreachableTime
+		*out = (*in).DeepCopy()
+	}
+	if in.RepeatedFailureTime != nil {
+		in, out := &in.RepeatedFailureTime, &out.RepeatedFailureTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatapathFailure.
+func (in *DatapathFailure) DeepCopy() *DatapathFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(DatapathFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EndpointSummary) DeepCopyInto(out *EndpointSummary) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EndpointSummary.
+func (in *EndpointSummary) DeepCopy() *EndpointSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(EndpointSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IPAllocationDetail) DeepCopyInto(out *IPAllocationDetail) {
 	*out = *in
@@ -36,6 +164,16 @@ func (in *IPAllocationDetail) DeepCopyInto(out *IPAllocationDetail) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.IPv4PoolUID != nil {
+		in, out := &in.IPv4PoolUID, &out.IPv4PoolUID
+		*out = new(string)
+		**out = **in
+	}
+	if in.IPv6PoolUID != nil {
+		in, out := &in.IPv6PoolUID, &out.IPv6PoolUID
+		*out = new(string)
+		**out = **in
+	}
 	if in.Vlan != nil {
 		in, out := &in.Vlan, &out.Vlan
 		*out = new(int64)
@@ -61,6 +199,16 @@ func (in *IPAllocationDetail) DeepCopyInto(out *IPAllocationDetail) {
 		*out = make([]Route, len(*in))
 		copy(*out, *in)
 	}
+	if in.Stripe != nil {
+		in, out := &in.Stripe, &out.Stripe
+		*out = new(int)
+		**out = **in
+	}
+	if in.MAC != nil {
+		in, out := &in.MAC, &out.MAC
+		*out = new(string)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPAllocationDetail.
@@ -73,6 +221,72 @@ func (in *IPAllocationDetail) DeepCopy() *IPAllocationDetail {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPPoolDNS) DeepCopyInto(out *IPPoolDNS) {
+	*out = *in
+	if in.Nameservers != nil {
+		in, out := &in.Nameservers, &out.Nameservers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Search != nil {
+		in, out := &in.Search, &out.Search
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPPoolDNS.
+func (in *IPPoolDNS) DeepCopy() *IPPoolDNS {
+	if in == nil {
+		return nil
+	}
+	out := new(IPPoolDNS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPLeak) DeepCopyInto(out *IPLeak) {
+	*out = *in
+	in.DetectedAt.DeepCopyInto(&out.DetectedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPLeak.
+func (in *IPLeak) DeepCopy() *IPLeak {
+	if in == nil {
+		return nil
+	}
+	out := new(IPLeak)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPPoolNetworkScan) DeepCopyInto(out *IPPoolNetworkScan) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.InUseIPs != nil {
+		in, out := &in.InUseIPs, &out.InUseIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPPoolNetworkScan.
+func (in *IPPoolNetworkScan) DeepCopy() *IPPoolNetworkScan {
+	if in == nil {
+		return nil
+	}
+	out := new(IPPoolNetworkScan)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IPPoolSpec) DeepCopyInto(out *IPPoolSpec) {
 	*out = *in
@@ -91,6 +305,11 @@ func (in *IPPoolSpec) DeepCopyInto(out *IPPoolSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Drain != nil {
+		in, out := &in.Drain, &out.Drain
+		*out = new(bool)
+		**out = **in
+	}
 	if in.ExcludeIPs != nil {
 		in, out := &in.ExcludeIPs, &out.ExcludeIPs
 		*out = make([]string, len(*in))
@@ -101,16 +320,68 @@ func (in *IPPoolSpec) DeepCopyInto(out *IPPoolSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.StandbyGateways != nil {
+		in, out := &in.StandbyGateways, &out.StandbyGateways
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeGateways != nil {
+		in, out := &in.ExcludeGateways, &out.ExcludeGateways
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AutoExcludeGateways != nil {
+		in, out := &in.AutoExcludeGateways, &out.AutoExcludeGateways
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ReserveSpecialIPs != nil {
+		in, out := &in.ReserveSpecialIPs, &out.ReserveSpecialIPs
+		*out = new(bool)
+		**out = **in
+	}
 	if in.Vlan != nil {
 		in, out := &in.Vlan, &out.Vlan
 		*out = new(int64)
 		**out = **in
 	}
+	if in.VlanRanges != nil {
+		in, out := &in.VlanRanges, &out.VlanRanges
+		*out = make([]VlanRange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Routes != nil {
 		in, out := &in.Routes, &out.Routes
 		*out = make([]Route, len(*in))
 		copy(*out, *in)
 	}
+	if in.MACPrefix != nil {
+		in, out := &in.MACPrefix, &out.MACPrefix
+		*out = new(string)
+		**out = **in
+	}
+	if in.InheritSubnetRoutes != nil {
+		in, out := &in.InheritSubnetRoutes, &out.InheritSubnetRoutes
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DefaultRouteMetric != nil {
+		in, out := &in.DefaultRouteMetric, &out.DefaultRouteMetric
+		*out = new(int64)
+		**out = **in
+	}
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = new(IPPoolDNS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LeaseDurationSeconds != nil {
+		in, out := &in.LeaseDurationSeconds, &out.LeaseDurationSeconds
+		*out = new(int64)
+		**out = **in
+	}
 	if in.PodAffinity != nil {
 		in, out := &in.PodAffinity, &out.PodAffinity
 		*out = new(metav1.LabelSelector)
@@ -121,11 +392,26 @@ func (in *IPPoolSpec) DeepCopyInto(out *IPPoolSpec) {
 		*out = new(metav1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.NodeAffinity != nil {
 		in, out := &in.NodeAffinity, &out.NodeAffinity
 		*out = new(metav1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Tenant != nil {
+		in, out := &in.Tenant, &out.Tenant
+		*out = new(string)
+		**out = **in
+	}
+	if in.ParentPool != nil {
+		in, out := &in.ParentPool, &out.ParentPool
+		*out = new(string)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPPoolSpec.
@@ -138,6 +424,47 @@ func (in *IPPoolSpec) DeepCopy() *IPPoolSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPPoolSpecChange) DeepCopyInto(out *IPPoolSpecChange) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.AddedIPs != nil {
+		in, out := &in.AddedIPs, &out.AddedIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RemovedIPs != nil {
+		in, out := &in.RemovedIPs, &out.RemovedIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AddedExcludeIPs != nil {
+		in, out := &in.AddedExcludeIPs, &out.AddedExcludeIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RemovedExcludeIPs != nil {
+		in, out := &in.RemovedExcludeIPs, &out.RemovedExcludeIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPPoolSpecChange.
+func (in *IPPoolSpecChange) DeepCopy() *IPPoolSpecChange {
+	if in == nil {
+		return nil
+	}
+	out := new(IPPoolSpecChange)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IPPoolStatus) DeepCopyInto(out *IPPoolStatus) {
 	*out = *in
@@ -145,7 +472,7 @@ func (in *IPPoolStatus) DeepCopyInto(out *IPPoolStatus) {
 		in, out := &in.AllocatedIPs, &out.AllocatedIPs
 		*out = make(PoolIPAllocations, len(*in))
 		for key, val := range *in {
-			(*out)[key] = val
+			(*out)[key] = *val.DeepCopy()
 		}
 	}
 	if in.TotalIPCount != nil {
@@ -153,6 +480,35 @@ func (in *IPPoolStatus) DeepCopyInto(out *IPPoolStatus) {
 		*out = new(int64)
 		**out = **in
 	}
+	if in.ExcludedIPs != nil {
+		in, out := &in.ExcludedIPs, &out.ExcludedIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.InheritedRoutes != nil {
+		in, out := &in.InheritedRoutes, &out.InheritedRoutes
+		*out = make([]Route, len(*in))
+		copy(*out, *in)
+	}
+	if in.DatapathFailureNodes != nil {
+		in, out := &in.DatapathFailureNodes, &out.DatapathFailureNodes
+		*out = make(map[string]DatapathFailure, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.NetworkScan != nil {
+		in, out := &in.NetworkScan, &out.NetworkScan
+		*out = new(IPPoolNetworkScan)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SuspectedLeakedIPs != nil {
+		in, out := &in.SuspectedLeakedIPs, &out.SuspectedLeakedIPs
+		*out = make(map[string]IPLeak, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 	if in.AllocatedIPCount != nil {
 		in, out := &in.AllocatedIPCount, &out.AllocatedIPCount
 		*out = new(int64)
@@ -163,6 +519,29 @@ func (in *IPPoolStatus) DeepCopyInto(out *IPPoolStatus) {
 		*out = new(int64)
 		**out = **in
 	}
+	if in.AutoElasticIPCount != nil {
+		in, out := &in.AutoElasticIPCount, &out.AutoElasticIPCount
+		*out = new(int64)
+		**out = **in
+	}
+	if in.PredictedExhaustionTime != nil {
+		in, out := &in.PredictedExhaustionTime, &out.PredictedExhaustionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SpecChangelog != nil {
+		in, out := &in.SpecChangelog, &out.SpecChangelog
+		*out = make([]IPPoolSpecChange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPPoolStatus.
@@ -194,6 +573,17 @@ func (in *PodIPAllocation) DeepCopyInto(out *PodIPAllocation) {
 		in, out := &in.CreationTime, &out.CreationTime
 		*out = (*in).DeepCopy()
 	}
+	if in.CNICalls != nil {
+		in, out := &in.CNICalls, &out.CNICalls
+		*out = make([]CNICall, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastCreationTime != nil {
+		in, out := &in.LastCreationTime, &out.LastCreationTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodIPAllocation.
@@ -209,6 +599,10 @@ func (in *PodIPAllocation) DeepCopy() *PodIPAllocation {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PoolIPAllocation) DeepCopyInto(out *PoolIPAllocation) {
 	*out = *in
+	if in.LeaseRenewTime != nil {
+		in, out := &in.LeaseRenewTime, &out.LeaseRenewTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PoolIPAllocation.
@@ -227,7 +621,7 @@ func (in PoolIPAllocations) DeepCopyInto(out *PoolIPAllocations) {
 		in := &in
 		*out = make(PoolIPAllocations, len(*in))
 		for key, val := range *in {
-			(*out)[key] = val
+			(*out)[key] = *val.DeepCopy()
 		}
 	}
 }
@@ -283,6 +677,56 @@ func (in PoolIPPreAllocations) DeepCopy() PoolIPPreAllocations {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPClaim) DeepCopyInto(out *ReservedIPClaim) {
+	*out = *in
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPClaim.
+func (in *ReservedIPClaim) DeepCopy() *ReservedIPClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPAffectedPool) DeepCopyInto(out *ReservedIPAffectedPool) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPAffectedPool.
+func (in *ReservedIPAffectedPool) DeepCopy() *ReservedIPAffectedPool {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPAffectedPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPBoundClaim) DeepCopyInto(out *ReservedIPBoundClaim) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPBoundClaim.
+func (in *ReservedIPBoundClaim) DeepCopy() *ReservedIPBoundClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPBoundClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReservedIPSpec) DeepCopyInto(out *ReservedIPSpec) {
 	*out = *in
@@ -296,6 +740,40 @@ func (in *ReservedIPSpec) DeepCopyInto(out *ReservedIPSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ExcludeIPs != nil {
+		in, out := &in.ExcludeIPs, &out.ExcludeIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PoolSelector != nil {
+		in, out := &in.PoolSelector, &out.PoolSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExpireAt != nil {
+		in, out := &in.ExpireAt, &out.ExpireAt
+		*out = (*in).DeepCopy()
+	}
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Claim != nil {
+		in, out := &in.Claim, &out.Claim
+		*out = new(ReservedIPClaim)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VacateAllocatedIPs != nil {
+		in, out := &in.VacateAllocatedIPs, &out.VacateAllocatedIPs
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPSpec.
@@ -308,6 +786,45 @@ func (in *ReservedIPSpec) DeepCopy() *ReservedIPSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPStatus) DeepCopyInto(out *ReservedIPStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AffectedIPPools != nil {
+		in, out := &in.AffectedIPPools, &out.AffectedIPPools
+		*out = make([]ReservedIPAffectedPool, len(*in))
+		copy(*out, *in)
+	}
+	if in.BlockedIPCount != nil {
+		in, out := &in.BlockedIPCount, &out.BlockedIPCount
+		*out = new(int64)
+		**out = **in
+	}
+	if in.BoundClaims != nil {
+		in, out := &in.BoundClaims, &out.BoundClaims
+		*out = make(map[string]ReservedIPBoundClaim, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPStatus.
+func (in *ReservedIPStatus) DeepCopy() *ReservedIPStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Route) DeepCopyInto(out *Route) {
 	*out = *in
@@ -446,6 +963,7 @@ func (in *SpiderReservedIP) DeepCopyInto(out *SpiderReservedIP) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpiderReservedIP.
@@ -590,6 +1108,21 @@ func (in *SubnetSpec) DeepCopyInto(out *SubnetSpec) {
 		*out = make([]Route, len(*in))
 		copy(*out, *in)
 	}
+	if in.FallbackSubnets != nil {
+		in, out := &in.FallbackSubnets, &out.FallbackSubnets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DefaultNamespaceAffinity != nil {
+		in, out := &in.DefaultNamespaceAffinity, &out.DefaultNamespaceAffinity
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DefaultNodeAffinity != nil {
+		in, out := &in.DefaultNodeAffinity, &out.DefaultNodeAffinity
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SubnetSpec.
@@ -622,6 +1155,20 @@ func (in *SubnetStatus) DeepCopyInto(out *SubnetStatus) {
 		*out = new(int64)
 		**out = **in
 	}
+	if in.BorrowedIPPools != nil {
+		in, out := &in.BorrowedIPPools, &out.BorrowedIPPools
+		*out = make(BorrowedIPPools, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.ApplicationIPPools != nil {
+		in, out := &in.ApplicationIPPools, &out.ApplicationIPPools
+		*out = make([]ApplicationIPPool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SubnetStatus.
@@ -634,6 +1181,26 @@ func (in *SubnetStatus) DeepCopy() *SubnetStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VlanRange) DeepCopyInto(out *VlanRange) {
+	*out = *in
+	if in.IPs != nil {
+		in, out := &in.IPs, &out.IPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VlanRange.
+func (in *VlanRange) DeepCopy() *VlanRange {
+	if in == nil {
+		return nil
+	}
+	out := new(VlanRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkloadEndpointStatus) DeepCopyInto(out *WorkloadEndpointStatus) {
 	*out = *in
@@ -649,6 +1216,11 @@ func (in *WorkloadEndpointStatus) DeepCopyInto(out *WorkloadEndpointStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Summary != nil {
+		in, out := &in.Summary, &out.Summary
+		*out = new(EndpointSummary)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadEndpointStatus.
@@ -19,6 +19,10 @@ func (c *FakeSpiderpoolV1) SpiderIPPools() v1.SpiderIPPoolInterface {
 	return &FakeSpiderIPPools{c}
 }
 
+func (c *FakeSpiderpoolV1) SpiderReservedIPs() v1.SpiderReservedIPInterface {
+	return &FakeSpiderReservedIPs{c}
+}
+
 func (c *FakeSpiderpoolV1) SpiderSubnets() v1.SpiderSubnetInterface {
 	return &FakeSpiderSubnets{c}
 }
@@ -7,4 +7,6 @@ package v1
 
 type SpiderIPPoolExpansion interface{}
 
+type SpiderReservedIPExpansion interface{}
+
 type SpiderSubnetExpansion interface{}
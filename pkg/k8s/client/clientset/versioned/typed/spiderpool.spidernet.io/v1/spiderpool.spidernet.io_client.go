@@ -16,6 +16,7 @@ import (
 type SpiderpoolV1Interface interface {
 	RESTClient() rest.Interface
 	SpiderIPPoolsGetter
+	SpiderReservedIPsGetter
 	SpiderSubnetsGetter
 }
 
@@ -28,6 +29,10 @@ func (c *SpiderpoolV1Client) SpiderIPPools() SpiderIPPoolInterface {
 	return newSpiderIPPools(c)
 }
 
+func (c *SpiderpoolV1Client) SpiderReservedIPs() SpiderReservedIPInterface {
+	return newSpiderReservedIPs(c)
+}
+
 func (c *SpiderpoolV1Client) SpiderSubnets() SpiderSubnetInterface {
 	return newSpiderSubnets(c)
 }
@@ -13,6 +13,8 @@ import (
 type Interface interface {
 	// SpiderIPPools returns a SpiderIPPoolInformer.
 	SpiderIPPools() SpiderIPPoolInformer
+	// SpiderReservedIPs returns a SpiderReservedIPInformer.
+	SpiderReservedIPs() SpiderReservedIPInformer
 	// SpiderSubnets returns a SpiderSubnetInformer.
 	SpiderSubnets() SpiderSubnetInformer
 }
@@ -33,6 +35,11 @@ func (v *version) SpiderIPPools() SpiderIPPoolInformer {
 	return &spiderIPPoolInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
 
+// SpiderReservedIPs returns a SpiderReservedIPInformer.
+func (v *version) SpiderReservedIPs() SpiderReservedIPInformer {
+	return &spiderReservedIPInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // SpiderSubnets returns a SpiderSubnetInformer.
 func (v *version) SpiderSubnets() SpiderSubnetInformer {
 	return &spiderSubnetInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
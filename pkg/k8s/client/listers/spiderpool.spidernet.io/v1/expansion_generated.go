@@ -9,6 +9,10 @@ package v1
 // SpiderIPPoolLister.
 type SpiderIPPoolListerExpansion interface{}
 
+// SpiderReservedIPListerExpansion allows custom methods to be added to
+// SpiderReservedIPLister.
+type SpiderReservedIPListerExpansion interface{}
+
 // SpiderSubnetListerExpansion allows custom methods to be added to
 // SpiderSubnetLister.
 type SpiderSubnetListerExpansion interface{}
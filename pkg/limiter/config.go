@@ -3,12 +3,29 @@
 
 package limiter
 
+import "time"
+
 const (
 	defaultMaxQueueSize = 1000
+	defaultTokenTTL     = time.Minute
 )
 
 type LimiterConfig struct {
 	MaxQueueSize *int
+
+	// TicketLimits overrides the limits of the tickets, such as the names of
+	// the IPPools which need different backpressure.
+	TicketLimits map[string]TicketLimit
+}
+
+// TicketLimit is the limit of a ticket.
+type TicketLimit struct {
+	// MaxConcurrency is the maximum number of the holders of the ticket at
+	// the same time, which defaults to 1.
+	MaxConcurrency int
+	// QueueTimeout is the maximum time to wait for the ticket, the queuer
+	// waits forever if it is not positive.
+	QueueTimeout time.Duration
 }
 
 func setDefaultsForLimiterConfig(config LimiterConfig) LimiterConfig {
@@ -19,3 +36,28 @@ func setDefaultsForLimiterConfig(config LimiterConfig) LimiterConfig {
 
 	return config
 }
+
+type TokenIssuerConfig struct {
+	// MaxConcurrency is the maximum number of the tokens issued at the same
+	// time.
+	MaxConcurrency int
+	MaxQueueSize   *int
+	// TokenTTL is the time after which the token not returned is reclaimed,
+	// so that the tokens held by the crashed holders are not leaked.
+	TokenTTL time.Duration
+}
+
+func setDefaultsForTokenIssuerConfig(config TokenIssuerConfig) TokenIssuerConfig {
+	if config.MaxConcurrency <= 0 {
+		config.MaxConcurrency = 1
+	}
+	if config.MaxQueueSize == nil {
+		maxQueueSize := defaultMaxQueueSize
+		config.MaxQueueSize = &maxQueueSize
+	}
+	if config.TokenTTL <= 0 {
+		config.TokenTTL = defaultTokenTTL
+	}
+
+	return config
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spidernet-io/spiderpool/pkg/election"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+var ErrNoLeader = errors.New("no leader to issue tokens")
+
+// LeaderIssuerFunc returns the TokenIssuer which forwards the requests to
+// the replica elected as the leader.
+type LeaderIssuerFunc func(ctx context.Context) (TokenIssuer, error)
+
+// NewLeaderTokenIssuer returns the TokenIssuer whose tokens are only issued
+// by the leader, so that all replicas share one budget. The local issuer
+// serves the requests while the replica is the leader, otherwise they are
+// forwarded to the leader.
+func NewLeaderTokenIssuer(local TokenIssuer, leader election.SpiderLeaseElector, leaderIssuer LeaderIssuerFunc) TokenIssuer {
+	return &leaderTokenIssuer{
+		local:        local,
+		leader:       leader,
+		leaderIssuer: leaderIssuer,
+	}
+}
+
+type leaderTokenIssuer struct {
+	local        TokenIssuer
+	leader       election.SpiderLeaseElector
+	leaderIssuer LeaderIssuerFunc
+}
+
+func (l *leaderTokenIssuer) AcquireToken(ctx context.Context, node string) (string, error) {
+	if l.leader.IsElected() {
+		return l.local.AcquireToken(ctx, node)
+	}
+
+	issuer, err := l.leaderIssuer(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrNoLeader, err)
+	}
+
+	return issuer.AcquireToken(ctx, node)
+}
+
+// ReturnToken returns the token to the leader. It's also returned to the
+// local issuer in case the token was issued before the replica lost the
+// leadership, the unknown ones are ignored by the local issuer.
+func (l *leaderTokenIssuer) ReturnToken(ctx context.Context, token string) {
+	l.local.ReturnToken(ctx, token)
+	if l.leader.IsElected() {
+		return
+	}
+
+	issuer, err := l.leaderIssuer(ctx)
+	if err != nil {
+		logutils.FromContext(ctx).Sugar().Warnf("Failed to return token to the leader, it will be reclaimed on expiration: %v", err)
+		return
+	}
+	issuer.ReturnToken(ctx, token)
+}
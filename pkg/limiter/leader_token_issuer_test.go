@@ -0,0 +1,93 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package limiter_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/spidernet-io/spiderpool/pkg/limiter"
+)
+
+type fakeLeader struct {
+	elected bool
+}
+
+func (f *fakeLeader) Run(ctx context.Context, clientSet kubernetes.Interface) error { return nil }
+
+func (f *fakeLeader) IsElected() bool { return f.elected }
+
+var _ = Describe("LeaderTokenIssuer", Label("leader_token_issuer_test"), func() {
+	var leaderBudget limiter.TokenIssuer
+	var leader1, leader2 *fakeLeader
+	var replica1, replica2 limiter.TokenIssuer
+
+	BeforeEach(func() {
+		// The budget of the replica elected as the leader, which the
+		// other replicas forward to.
+		leaderBudget = limiter.NewTokenIssuer(limiter.TokenIssuerConfig{MaxConcurrency: 1})
+		leader1 = &fakeLeader{elected: true}
+		leader2 = &fakeLeader{}
+
+		toLeader := func(ctx context.Context) (limiter.TokenIssuer, error) {
+			return leaderBudget, nil
+		}
+		replica1 = limiter.NewLeaderTokenIssuer(leaderBudget, leader1, toLeader)
+		replica2 = limiter.NewLeaderTokenIssuer(limiter.NewTokenIssuer(limiter.TokenIssuerConfig{MaxConcurrency: 1}), leader2, toLeader)
+	})
+
+	It("shares the budget of the leader among replicas", func() {
+		ctx := context.TODO()
+		token, err := replica1.AcquireToken(ctx, "node1")
+		Expect(err).NotTo(HaveOccurred())
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err = replica2.AcquireToken(timeoutCtx, "node2")
+		Expect(err).To(MatchError(limiter.ErrQueueTimeout))
+
+		// The token returned through another replica goes back to the
+		// leader.
+		replica2.ReturnToken(ctx, token)
+		timeoutCtx, cancel = context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err = replica2.AcquireToken(timeoutCtx, "node2")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("fails to issue tokens if the leader is unknown", func() {
+		replica := limiter.NewLeaderTokenIssuer(
+			limiter.NewTokenIssuer(limiter.TokenIssuerConfig{MaxConcurrency: 1}),
+			&fakeLeader{},
+			func(ctx context.Context) (limiter.TokenIssuer, error) {
+				return nil, errors.New("no lease holder")
+			},
+		)
+
+		ctx := context.TODO()
+		_, err := replica.AcquireToken(ctx, "node1")
+		Expect(err).To(MatchError(limiter.ErrNoLeader))
+		replica.ReturnToken(ctx, "token")
+	})
+
+	It("returns the token issued before losing the leadership locally", func() {
+		ctx := context.TODO()
+		token, err := replica1.AcquireToken(ctx, "node1")
+		Expect(err).NotTo(HaveOccurred())
+
+		leader1.elected = false
+		leader2.elected = true
+		replica1.ReturnToken(ctx, token)
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err = leaderBudget.AcquireToken(timeoutCtx, "node2")
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
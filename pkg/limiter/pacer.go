@@ -0,0 +1,25 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package limiter
+
+import "context"
+
+// Pacer paces the bulk writes to the API server, such as the ones of the
+// IP garbage collection and the periodic reconcilers, by pausing between
+// batches of writes.
+type Pacer interface {
+	// Pause is called before each write with the number of writes done so
+	// far, it blocks for a while if they fill up a batch, or returns the
+	// error of the context.
+	Pause(ctx context.Context, written int) error
+}
+
+// Pause pauses with the pacer if it is not nil.
+func Pause(ctx context.Context, pacer Pacer, written int) error {
+	if pacer == nil {
+		return nil
+	}
+
+	return pacer.Pause(ctx, written)
+}
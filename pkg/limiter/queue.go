@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/spidernet-io/spiderpool/pkg/lock"
 	"github.com/spidernet-io/spiderpool/pkg/logutils"
@@ -27,6 +28,7 @@ func NewLimiter(c LimiterConfig) Limiter {
 		cond:           sync.NewCond(&lock.Mutex{}),
 		shuttingDown:   true,
 		maxQueueSize:   *c.MaxQueueSize,
+		ticketLimits:   c.TicketLimits,
 		elements:       make([]*e, 0, *c.MaxQueueSize),
 		grantedTickets: map[string]int{},
 	}
@@ -40,12 +42,14 @@ var (
 	ErrStartLimiteRrepeatedly = errors.New("start the limiter repeatedly")
 	ErrShutdownQueue          = errors.New("queue shutdown")
 	ErrFullQueue              = errors.New("queue is full")
+	ErrQueueTimeout           = errors.New("queue timeout")
 )
 
 type queue struct {
 	cond           *sync.Cond
 	shuttingDown   bool
 	maxQueueSize   int
+	ticketLimits   map[string]TicketLimit
 	elements       []*e
 	grantedTickets map[string]int
 }
@@ -69,12 +73,62 @@ func (q *queue) AcquireTicket(ctx context.Context, tickets ...string) error {
 		return err
 	}
 
-	<-e.notifyCheckin
+	timeout := q.queueTimeout(e.wantedTickets...)
+	if timeout <= 0 {
+		<-e.notifyCheckin
+		logger.Debug("Succeed to acquire tickets")
+
+		return nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-e.notifyCheckin:
+	case <-timer.C:
+		if q.leave(e) {
+			return fmt.Errorf("%w after %s waiting for tickets %v", ErrQueueTimeout, timeout, tickets)
+		}
+		// The tickets were granted right before leaving.
+	}
 	logger.Debug("Succeed to acquire tickets")
 
 	return nil
 }
 
+// queueTimeout returns the shortest queue timeout of the tickets, 0 means
+// no timeout.
+func (q *queue) queueTimeout(tickets ...string) time.Duration {
+	var timeout time.Duration
+	for _, t := range tickets {
+		limit, ok := q.ticketLimits[t]
+		if !ok || limit.QueueTimeout <= 0 {
+			continue
+		}
+		if timeout == 0 || limit.QueueTimeout < timeout {
+			timeout = limit.QueueTimeout
+		}
+	}
+
+	return timeout
+}
+
+// leave removes the queuer from the queue, it returns false if the queuer
+// has been granted the tickets.
+func (q *queue) leave(e *e) bool {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	for i, elem := range q.elements {
+		if elem == e {
+			q.elements = append(q.elements[:i], q.elements[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
 func (q *queue) queueUp(tickets ...string) (*e, error) {
 	q.cond.L.Lock()
 	defer q.cond.L.Unlock()
@@ -202,7 +256,11 @@ func (q *queue) checkin() (shuttingDown bool) {
 
 func (q *queue) checkAvailableTicket(tickets ...string) bool {
 	for _, t := range tickets {
-		if _, ok := q.grantedTickets[t]; ok {
+		maxConcurrency := 1
+		if limit, ok := q.ticketLimits[t]; ok && limit.MaxConcurrency > 1 {
+			maxConcurrency = limit.MaxConcurrency
+		}
+		if q.grantedTickets[t] >= maxConcurrency {
 			return false
 		}
 	}
@@ -139,6 +139,46 @@ var _ = Describe("Limiter", Label("queue_test"), func() {
 			})
 		})
 
+		Context("Ticket limits", func() {
+			BeforeEach(func() {
+				ctx, cancel = context.WithCancel(context.Background())
+				DeferCleanup(cancel)
+
+				maxQueueSize := 10
+				config = limiter.LimiterConfig{
+					MaxQueueSize: &maxQueueSize,
+					TicketLimits: map[string]limiter.TicketLimit{
+						"shared-pool": {MaxConcurrency: 2},
+						"tiny-pool":   {QueueTimeout: 100 * time.Millisecond},
+					},
+				}
+			})
+
+			It("grants the ticket to multiple holders", func() {
+				ctx := context.TODO()
+				err := queue.AcquireTicket(ctx, "shared-pool")
+				Expect(err).NotTo(HaveOccurred())
+				err = queue.AcquireTicket(ctx, "shared-pool")
+				Expect(err).NotTo(HaveOccurred())
+				queue.ReleaseTicket(ctx, "shared-pool")
+				queue.ReleaseTicket(ctx, "shared-pool")
+			})
+
+			It("gives up waiting for the ticket after the queue timeout", func() {
+				ctx := context.TODO()
+				err := queue.AcquireTicket(ctx, "tiny-pool")
+				Expect(err).NotTo(HaveOccurred())
+
+				err = queue.AcquireTicket(ctx, "tiny-pool")
+				Expect(err).To(MatchError(limiter.ErrQueueTimeout))
+				queue.ReleaseTicket(ctx, "tiny-pool")
+
+				err = queue.AcquireTicket(ctx, "tiny-pool")
+				Expect(err).NotTo(HaveOccurred())
+				queue.ReleaseTicket(ctx, "tiny-pool")
+			})
+		})
+
 		Context("Shutdown", func() {
 			BeforeEach(func() {
 				ctx, cancel = context.WithCancel(context.Background())
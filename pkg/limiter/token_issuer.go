@@ -0,0 +1,190 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	"github.com/spidernet-io/spiderpool/pkg/lock"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+// TokenIssuer issues the tokens of a concurrency budget shared by the
+// nodes. The nodes waiting for tokens are served in turn, so that a node
+// storming requests does not starve the others.
+type TokenIssuer interface {
+	AcquireToken(ctx context.Context, node string) (string, error)
+	ReturnToken(ctx context.Context, token string)
+}
+
+func NewTokenIssuer(c TokenIssuerConfig) TokenIssuer {
+	c = setDefaultsForTokenIssuerConfig(c)
+
+	return &tokenIssuer{
+		config:  c,
+		issued:  map[string]issuedToken{},
+		waiters: map[string][]*tokenWaiter{},
+	}
+}
+
+type tokenIssuer struct {
+	lock   lock.Mutex
+	config TokenIssuerConfig
+
+	issued  map[string]issuedToken
+	waiters map[string][]*tokenWaiter
+	// nodes is the order in which the nodes with waiters are served.
+	nodes  []string
+	queued int
+}
+
+type issuedToken struct {
+	node       string
+	expiration time.Time
+}
+
+type tokenWaiter struct {
+	token chan string
+}
+
+// AcquireToken blocks until a token is issued to the node, or the context
+// is done.
+func (t *tokenIssuer) AcquireToken(ctx context.Context, node string) (string, error) {
+	logger := logutils.FromContext(ctx)
+
+	t.lock.Lock()
+	t.reclaimExpiredTokens(time.Now())
+	if t.queued == 0 && len(t.issued) < t.config.MaxConcurrency {
+		token := t.issue(node)
+		t.lock.Unlock()
+		return token, nil
+	}
+	if t.queued >= *t.config.MaxQueueSize {
+		t.lock.Unlock()
+		return "", fmt.Errorf("%w with a maximum length of %d", ErrFullQueue, *t.config.MaxQueueSize)
+	}
+
+	w := &tokenWaiter{token: make(chan string, 1)}
+	t.enqueue(node, w)
+	t.lock.Unlock()
+	logger.Sugar().Debugf("Node %s is waiting for token", node)
+
+	// The tokens leaked by the crashed holders are reclaimed while waiting,
+	// in case no one returns any token.
+	ticker := time.NewTicker(t.config.TokenTTL)
+	defer ticker.Stop()
+	for {
+		select {
+		case token := <-w.token:
+			return token, nil
+		case <-ticker.C:
+			t.lock.Lock()
+			t.reclaimExpiredTokens(time.Now())
+			t.dispatch()
+			t.lock.Unlock()
+		case <-ctx.Done():
+			t.lock.Lock()
+			left := t.dequeue(node, w)
+			t.lock.Unlock()
+			if !left {
+				// The token was issued right before leaving.
+				t.ReturnToken(ctx, <-w.token)
+			}
+			return "", fmt.Errorf("%w waiting for token: %v", ErrQueueTimeout, ctx.Err())
+		}
+	}
+}
+
+// ReturnToken returns the token to the budget, the unknown or reclaimed
+// ones are ignored.
+func (t *tokenIssuer) ReturnToken(ctx context.Context, token string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.issued[token]; !ok {
+		return
+	}
+	delete(t.issued, token)
+	t.dispatch()
+}
+
+func (t *tokenIssuer) issue(node string) string {
+	token := string(uuid.NewUUID())
+	t.issued[token] = issuedToken{
+		node:       node,
+		expiration: time.Now().Add(t.config.TokenTTL),
+	}
+
+	return token
+}
+
+func (t *tokenIssuer) reclaimExpiredTokens(now time.Time) {
+	for token, issued := range t.issued {
+		if now.After(issued.expiration) {
+			delete(t.issued, token)
+		}
+	}
+}
+
+func (t *tokenIssuer) enqueue(node string, w *tokenWaiter) {
+	if len(t.waiters[node]) == 0 {
+		t.nodes = append(t.nodes, node)
+	}
+	t.waiters[node] = append(t.waiters[node], w)
+	t.queued++
+}
+
+// dequeue removes the waiter from the queue, it returns false if the token
+// has been issued to the waiter.
+func (t *tokenIssuer) dequeue(node string, w *tokenWaiter) bool {
+	waiters := t.waiters[node]
+	for i, elem := range waiters {
+		if elem != w {
+			continue
+		}
+
+		t.waiters[node] = append(waiters[:i], waiters[i+1:]...)
+		t.queued--
+		if len(t.waiters[node]) == 0 {
+			t.removeNode(node)
+		}
+		return true
+	}
+
+	return false
+}
+
+// dispatch issues the available tokens to the waiters, one for each node
+// in turn.
+func (t *tokenIssuer) dispatch() {
+	for len(t.nodes) != 0 && len(t.issued) < t.config.MaxConcurrency {
+		node := t.nodes[0]
+		w := t.waiters[node][0]
+		t.waiters[node] = t.waiters[node][1:]
+		t.queued--
+
+		t.nodes = t.nodes[1:]
+		if len(t.waiters[node]) == 0 {
+			delete(t.waiters, node)
+		} else {
+			t.nodes = append(t.nodes, node)
+		}
+
+		w.token <- t.issue(node)
+	}
+}
+
+func (t *tokenIssuer) removeNode(node string) {
+	delete(t.waiters, node)
+	for i, n := range t.nodes {
+		if n == node {
+			t.nodes = append(t.nodes[:i], t.nodes[i+1:]...)
+			return
+		}
+	}
+}
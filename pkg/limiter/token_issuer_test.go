@@ -0,0 +1,97 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package limiter_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/pointer"
+
+	"github.com/spidernet-io/spiderpool/pkg/limiter"
+)
+
+var _ = Describe("TokenIssuer", Label("token_issuer_test"), func() {
+	It("issues the tokens within the budget", func() {
+		issuer := limiter.NewTokenIssuer(limiter.TokenIssuerConfig{MaxConcurrency: 2})
+
+		ctx := context.TODO()
+		token1, err := issuer.AcquireToken(ctx, "node1")
+		Expect(err).NotTo(HaveOccurred())
+		token2, err := issuer.AcquireToken(ctx, "node1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token1).NotTo(Equal(token2))
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err = issuer.AcquireToken(timeoutCtx, "node2")
+		Expect(err).To(MatchError(limiter.ErrQueueTimeout))
+
+		issuer.ReturnToken(ctx, token1)
+		issuer.ReturnToken(ctx, token1)
+		_, err = issuer.AcquireToken(ctx, "node2")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("serves the waiting nodes in turn", func() {
+		issuer := limiter.NewTokenIssuer(limiter.TokenIssuerConfig{MaxConcurrency: 1})
+
+		ctx := context.TODO()
+		token, err := issuer.AcquireToken(ctx, "node0")
+		Expect(err).NotTo(HaveOccurred())
+
+		order := make(chan string, 3)
+		acquire := func(node string) {
+			defer GinkgoRecover()
+			token, err := issuer.AcquireToken(ctx, node)
+			Expect(err).NotTo(HaveOccurred())
+			order <- node
+			issuer.ReturnToken(ctx, token)
+		}
+
+		// node1 storms two requests before node2.
+		go acquire("node1")
+		time.Sleep(20 * time.Millisecond)
+		go acquire("node1")
+		time.Sleep(20 * time.Millisecond)
+		go acquire("node2")
+		time.Sleep(20 * time.Millisecond)
+
+		issuer.ReturnToken(ctx, token)
+		Eventually(order).Should(Receive(Equal("node1")))
+		Eventually(order).Should(Receive(Equal("node2")))
+		Eventually(order).Should(Receive(Equal("node1")))
+	})
+
+	It("rejects the requests if the queue is full", func() {
+		issuer := limiter.NewTokenIssuer(limiter.TokenIssuerConfig{
+			MaxConcurrency: 1,
+			MaxQueueSize:   pointer.Int(0),
+		})
+
+		ctx := context.TODO()
+		_, err := issuer.AcquireToken(ctx, "node1")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = issuer.AcquireToken(ctx, "node1")
+		Expect(err).To(MatchError(limiter.ErrFullQueue))
+	})
+
+	It("reclaims the tokens not returned", func() {
+		issuer := limiter.NewTokenIssuer(limiter.TokenIssuerConfig{
+			MaxConcurrency: 1,
+			TokenTTL:       50 * time.Millisecond,
+		})
+
+		ctx := context.TODO()
+		_, err := issuer.AcquireToken(ctx, "node1")
+		Expect(err).NotTo(HaveOccurred())
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		_, err = issuer.AcquireToken(timeoutCtx, "node2")
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
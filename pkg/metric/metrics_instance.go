@@ -11,6 +11,7 @@ import (
 	api "go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/metric/instrument"
 
+	"github.com/spidernet-io/spiderpool/pkg/constant"
 	"github.com/spidernet-io/spiderpool/pkg/lock"
 )
 
@@ -23,6 +24,10 @@ const (
 	ipam_allocation_err_no_available_pool_counts = "ipam_allocation_err_no_available_pool_counts"
 	ipam_allocation_err_retries_exhausted_counts = "ipam_allocation_err_retries_exhausted_counts"
 	ipam_allocation_err_ip_used_out_counts       = "ipam_allocation_err_ip_used_out_counts"
+	ipam_allocation_err_workload_ip_limit_counts = "ipam_allocation_err_workload_ip_limit_counts"
+	ippool_datapath_failure_counts               = "ippool_datapath_failure_counts"
+	ipam_allocation_source_counts                = "ipam_allocation_source_counts"
+	ippool_filter_counts                         = "ippool_filter_counts"
 
 	ipam_allocation_average_duration_seconds   = "ipam_allocation_average_duration_seconds"
 	ipam_allocation_max_duration_seconds       = "ipam_allocation_max_duration_seconds"
@@ -35,6 +40,8 @@ const (
 	ipam_release_failure_counts               = "ipam_release_failure_counts"
 	ipam_release_err_internal_counts          = "ipam_release_err_internal_counts"
 	ipam_release_err_retries_exhausted_counts = "ipam_release_err_retries_exhausted_counts"
+	ipam_release_vanished_sandbox_counts      = "ipam_release_vanished_sandbox_counts"
+	ipam_release_journal_depth                = "ipam_release_journal_depth"
 
 	ipam_release_average_duration_seconds   = "ipam_release_average_duration_seconds"
 	ipam_release_max_duration_seconds       = "ipam_release_max_duration_seconds"
@@ -45,6 +52,32 @@ const (
 	// spiderpool controller IP GC metrics name
 	ip_gc_total_counts   = "ip_gc_total_counts"
 	ip_gc_failure_counts = "ip_gc_failure_counts"
+	ip_gc_churn_rate     = "ip_gc_churn_rate"
+	ip_gc_pace_seconds   = "ip_gc_pace_seconds"
+
+	ip_gc_reclaimed_ip_counts                 = "ip_gc_reclaimed_ip_counts"
+	ip_gc_reclaim_lag_seconds_histogram       = "ip_gc_reclaim_lag_seconds_histogram"
+	ip_gc_scan_all_duration_seconds_histogram = "ip_gc_scan_all_duration_seconds_histogram"
+	ip_gc_skipped_counts                      = "ip_gc_skipped_counts"
+
+	ip_gc_double_allocated_ip_counts           = "ip_gc_double_allocated_ip_counts"
+	ip_gc_quarantined_ip_counts                = "ip_gc_quarantined_ip_counts"
+	ip_gc_double_allocation_evicted_pod_counts = "ip_gc_double_allocation_evicted_pod_counts"
+
+	ip_gc_reclaim_webhook_delivered_counts   = "ip_gc_reclaim_webhook_delivered_counts"
+	ip_gc_reclaim_webhook_dead_letter_counts = "ip_gc_reclaim_webhook_dead_letter_counts"
+
+	// spiderpool controller self verification metrics name
+	self_verification_total_counts            = "self_verification_total_counts"
+	self_verification_failure_counts          = "self_verification_failure_counts"
+	self_verification_latest_duration_seconds = "self_verification_latest_duration_seconds"
+
+	// spiderpool controller report-only mode metrics name
+	report_only_write_counts          = "report_only_write_counts"
+	report_only_webhook_denial_counts = "report_only_webhook_denial_counts"
+
+	// spiderpool controller IPPool usage forecast metrics name
+	ippool_predicted_exhaustion_seconds = "ippool_predicted_exhaustion_seconds"
 
 	subnet_ippool_counts = "subnet_ippool_counts"
 
@@ -64,6 +97,16 @@ const (
 	auto_pool_scale_conflict_counts               = "auto_pool_scale_conflict_counts"
 )
 
+const (
+	// AttrKeySource is the attribute key of the source of IPPool candidates.
+	AttrKeySource = "source"
+	// AttrKeyFilter is the attribute key of the filter eliminating IPPool
+	// candidates.
+	AttrKeyFilter = "filter"
+	// AttrKeyKind is the attribute key of the kind of the object.
+	AttrKeyKind = "kind"
+)
+
 var (
 	// spiderpool agent ipam allocation metrics
 	IpamAllocationTotalCounts               instrument.Int64Counter
@@ -73,6 +116,10 @@ var (
 	IpamAllocationErrNoAvailablePoolCounts  instrument.Int64Counter
 	IpamAllocationErrRetriesExhaustedCounts instrument.Int64Counter
 	IpamAllocationErrIPUsedOutCounts        instrument.Int64Counter
+	IpamAllocationErrWorkloadIPLimitCounts  instrument.Int64Counter
+	IPPoolDatapathFailureCounts             instrument.Int64Counter
+	IpamAllocationSourceCounts              instrument.Int64Counter
+	IPPoolFilterCounts                      instrument.Int64Counter
 	ipamAllocationAverageDurationSeconds    = new(asyncFloat64Gauge)
 	ipamAllocationMaxDurationSeconds        = new(asyncFloat64Gauge)
 	ipamAllocationMinDurationSeconds        = new(asyncFloat64Gauge)
@@ -84,6 +131,8 @@ var (
 	IpamReleaseFailureCounts             instrument.Int64Counter
 	IpamReleaseErrInternalCounts         instrument.Int64Counter
 	IpamReleaseErrRetriesExhaustedCounts instrument.Int64Counter
+	IpamReleaseVanishedSandboxCounts     instrument.Int64Counter
+	IpamReleaseJournalDepth              = new(asyncInt64Gauge)
 	ipamReleaseAverageDurationSeconds    = new(asyncFloat64Gauge)
 	ipamReleaseMaxDurationSeconds        = new(asyncFloat64Gauge)
 	ipamReleaseMinDurationSeconds        = new(asyncFloat64Gauge)
@@ -93,6 +142,32 @@ var (
 	// spiderpool controller IP GC metrics
 	IPGCTotalCounts   instrument.Int64Counter
 	IPGCFailureCounts instrument.Int64Counter
+	IPGCChurnRate     = new(asyncFloat64Gauge)
+	IPGCPaceSeconds   = new(asyncFloat64Gauge)
+
+	IPGCReclaimedIPCounts               instrument.Int64Counter
+	IPGCReclaimLagSecondsHistogram      instrument.Float64Histogram
+	IPGCScanAllDurationSecondsHistogram instrument.Float64Histogram
+	IPGCSkippedCounts                   instrument.Int64Counter
+
+	IPGCDoubleAllocatedIPCounts          = new(asyncInt64Gauge)
+	IPGCQuarantinedIPCounts              instrument.Int64Counter
+	IPGCDoubleAllocationEvictedPodCounts instrument.Int64Counter
+
+	IPGCReclaimWebhookDeliveredCounts  instrument.Int64Counter
+	IPGCReclaimWebhookDeadLetterCounts instrument.Int64Counter
+
+	// spiderpool controller self verification metrics
+	SelfVerificationTotalCounts           instrument.Int64Counter
+	SelfVerificationFailureCounts         instrument.Int64Counter
+	SelfVerificationLatestDurationSeconds = new(asyncFloat64Gauge)
+
+	// spiderpool controller report-only mode metrics
+	ReportOnlyWriteCounts         instrument.Int64Counter
+	ReportOnlyWebhookDenialCounts instrument.Int64Counter
+
+	// spiderpool controller IPPool usage forecast metrics
+	IPPoolPredictedExhaustionSeconds = new(asyncFloat64GaugeVec)
 
 	SubnetPoolCounts = new(asyncInt64Gauge)
 
@@ -194,6 +269,56 @@ func (a *asyncInt64Gauge) Record(value int64, attrs ...attribute.KeyValue) {
 	a.observerLock.Unlock()
 }
 
+// asyncFloat64GaugeVec is custom otel float64 gauge, which reports a value
+// for each value of its attribute
+type asyncFloat64GaugeVec struct {
+	gaugeMetric            instrument.Float64ObservableGauge
+	attrKey                attribute.Key
+	observerValuesToReport map[string]float64
+	observerLock           lock.RWMutex
+}
+
+// initGauge will new an otel float64 gauge metric and register a call back function
+func (a *asyncFloat64GaugeVec) initGauge(metricName string, description string, attrKey string) error {
+	tmpGauge, err := NewMetricFloat64Gauge(metricName, description)
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool metric '%s', error: %v", metricName, err)
+	}
+
+	a.gaugeMetric = tmpGauge
+	a.attrKey = attribute.Key(attrKey)
+	a.observerValuesToReport = map[string]float64{}
+	_, err = meter.RegisterCallback(func(_ context.Context, observer api.Observer) error {
+		a.observerLock.RLock()
+		defer a.observerLock.RUnlock()
+		for attrValue, value := range a.observerValuesToReport {
+			observer.ObserveFloat64(a.gaugeMetric, value, a.attrKey.String(attrValue))
+		}
+		return nil
+	}, a.gaugeMetric)
+	if nil != err {
+		return fmt.Errorf("failed to register callback for spiderpool metric '%s', error: %v", metricName, err)
+	}
+
+	return nil
+}
+
+// Record sets the value reported with the attribute value
+func (a *asyncFloat64GaugeVec) Record(attrValue string, value float64) {
+	a.observerLock.Lock()
+	if a.observerValuesToReport != nil {
+		a.observerValuesToReport[attrValue] = value
+	}
+	a.observerLock.Unlock()
+}
+
+// Delete stops reporting the value with the attribute value
+func (a *asyncFloat64GaugeVec) Delete(attrValue string) {
+	a.observerLock.Lock()
+	delete(a.observerValuesToReport, attrValue)
+	a.observerLock.Unlock()
+}
+
 // InitSpiderpoolAgentMetrics serves for spiderpool agent metrics initialization
 func InitSpiderpoolAgentMetrics(ctx context.Context) error {
 	err := initSpiderpoolAgentAllocationMetrics(ctx)
@@ -221,6 +346,21 @@ func InitSpiderpoolControllerMetrics(ctx context.Context) error {
 		return err
 	}
 
+	err = initSpiderpoolControllerSelfVerificationMetrics(ctx)
+	if nil != err {
+		return err
+	}
+
+	err = initSpiderpoolControllerReportOnlyMetrics(ctx)
+	if nil != err {
+		return err
+	}
+
+	err = initSpiderpoolControllerIPPoolForecastMetrics(ctx)
+	if nil != err {
+		return err
+	}
+
 	err = initAutoPoolCreationMetrics(ctx)
 	if nil != err {
 		return err
@@ -298,6 +438,34 @@ func initSpiderpoolAgentAllocationMetrics(ctx context.Context) error {
 	}
 	IpamAllocationErrIPUsedOutCounts = allocationErrIPUsedOutCounts
 
+	// spiderpool agent ipam allocation workload IP holding limit exceeded error counts, metric type "int64 counter"
+	allocationErrWorkloadIPLimitCounts, err := NewMetricInt64Counter(ipam_allocation_err_workload_ip_limit_counts, "spiderpool agent ipam allocation workload IP holding limit exceeded error counts")
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool agent metric '%s', error: %v", ipam_allocation_err_workload_ip_limit_counts, err)
+	}
+	IpamAllocationErrWorkloadIPLimitCounts = allocationErrWorkloadIPLimitCounts
+
+	// spiderpool agent IPPool datapath failure counts, metric type "int64 counter"
+	poolDatapathFailureCounts, err := NewMetricInt64Counter(ippool_datapath_failure_counts, "spiderpool agent IPPool datapath failure counts, such as the IP conflicts and the unreachable gateways")
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool agent metric '%s', error: %v", ippool_datapath_failure_counts, err)
+	}
+	IPPoolDatapathFailureCounts = poolDatapathFailureCounts
+
+	// spiderpool agent ipam allocation counts by IPPool candidate source, metric type "int64 counter"
+	allocationSourceCounts, err := NewMetricInt64Counter(ipam_allocation_source_counts, "spiderpool agent ipam successful allocation counts by the source of IPPool candidates")
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool agent metric '%s', error: %v", ipam_allocation_source_counts, err)
+	}
+	IpamAllocationSourceCounts = allocationSourceCounts
+
+	// spiderpool agent IPPool candidate elimination counts by filter, metric type "int64 counter"
+	poolFilterCounts, err := NewMetricInt64Counter(ippool_filter_counts, "spiderpool agent IPPool candidate elimination counts by the filter")
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool agent metric '%s', error: %v", ippool_filter_counts, err)
+	}
+	IPPoolFilterCounts = poolFilterCounts
+
 	// spiderpool agent ipam average allocation duration, metric type "float64 gauge"
 	err = ipamAllocationAverageDurationSeconds.initGauge(ipam_allocation_average_duration_seconds, "spiderpool agent ipam average allocation duration")
 	if nil != err {
@@ -369,6 +537,19 @@ func initSpiderpoolAgentReleaseMetrics(ctx context.Context) error {
 	}
 	IpamReleaseErrRetriesExhaustedCounts = releasingErrRetriesExhaustedCounts
 
+	// spiderpool agent ipam vanished sandbox release counts, metric type "int64 counter"
+	releasingVanishedSandboxCounts, err := NewMetricInt64Counter(ipam_release_vanished_sandbox_counts, "spiderpool agent ipam release counts of sandboxes vanished while the agent was down")
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool agent metric '%s', error: %v", ipam_release_vanished_sandbox_counts, err)
+	}
+	IpamReleaseVanishedSandboxCounts = releasingVanishedSandboxCounts
+
+	// spiderpool agent ipam release journal depth, metric type "int64 gauge"
+	err = IpamReleaseJournalDepth.initGauge(ipam_release_journal_depth, "spiderpool agent ipam release intents waiting in the journal to be replayed")
+	if nil != err {
+		return err
+	}
+
 	// spiderpool agent ipam average release duration, metric type "float64 gauge"
 	err = ipamReleaseAverageDurationSeconds.initGauge(ipam_release_average_duration_seconds, "spiderpool agent ipam average release duration")
 	if nil != err {
@@ -424,8 +605,127 @@ func initSpiderpoolControllerGCMetrics(ctx context.Context) error {
 	}
 	IPGCFailureCounts = ipGCFailureCounts
 
+	err = IPGCChurnRate.initGauge(ip_gc_churn_rate, "cluster churn rate observed by spiderpool controller ip gc, in pods per second")
+	if nil != err {
+		return err
+	}
+
+	err = IPGCPaceSeconds.initGauge(ip_gc_pace_seconds, "the interval chosen by spiderpool controller ip gc to trace pods")
+	if nil != err {
+		return err
+	}
+
+	ipGCReclaimedIPCounts, err := NewMetricInt64Counter(ip_gc_reclaimed_ip_counts, "spiderpool controller ip gc reclaimed ip counts")
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool controller metric '%s', error: %v", ip_gc_reclaimed_ip_counts, err)
+	}
+	IPGCReclaimedIPCounts = ipGCReclaimedIPCounts
+
+	reclaimLagHistogram, err := NewMetricFloat64Histogram(ip_gc_reclaim_lag_seconds_histogram, "the lag of spiderpool controller ip gc since the ip became eligible for release")
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool controller metric '%s', error: %v", ip_gc_reclaim_lag_seconds_histogram, err)
+	}
+	IPGCReclaimLagSecondsHistogram = reclaimLagHistogram
+
+	scanAllHistogram, err := NewMetricFloat64Histogram(ip_gc_scan_all_duration_seconds_histogram, "spiderpool controller ip gc scan all duration bucket")
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool controller metric '%s', error: %v", ip_gc_scan_all_duration_seconds_histogram, err)
+	}
+	IPGCScanAllDurationSecondsHistogram = scanAllHistogram
+
+	ipGCSkippedCounts, err := NewMetricInt64Counter(ip_gc_skipped_counts, "spiderpool controller ip gc skipped object counts due to annotation")
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool controller metric '%s', error: %v", ip_gc_skipped_counts, err)
+	}
+	IPGCSkippedCounts = ipGCSkippedCounts
+
+	err = IPGCDoubleAllocatedIPCounts.initGauge(ip_gc_double_allocated_ip_counts, "the number of ips allocated more than once found by spiderpool controller ip gc")
+	if nil != err {
+		return err
+	}
+
+	ipGCQuarantinedIPCounts, err := NewMetricInt64Counter(ip_gc_quarantined_ip_counts, "spiderpool controller ip gc quarantined double allocated ip counts")
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool controller metric '%s', error: %v", ip_gc_quarantined_ip_counts, err)
+	}
+	IPGCQuarantinedIPCounts = ipGCQuarantinedIPCounts
+
+	ipGCDoubleAllocationEvictedPodCounts, err := NewMetricInt64Counter(ip_gc_double_allocation_evicted_pod_counts, "spiderpool controller ip gc evicted pod counts due to double allocated ip")
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool controller metric '%s', error: %v", ip_gc_double_allocation_evicted_pod_counts, err)
+	}
+	IPGCDoubleAllocationEvictedPodCounts = ipGCDoubleAllocationEvictedPodCounts
+
+	ipGCReclaimWebhookDeliveredCounts, err := NewMetricInt64Counter(ip_gc_reclaim_webhook_delivered_counts, "spiderpool controller ip gc reclaimed ip counts published to webhook")
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool controller metric '%s', error: %v", ip_gc_reclaim_webhook_delivered_counts, err)
+	}
+	IPGCReclaimWebhookDeliveredCounts = ipGCReclaimWebhookDeliveredCounts
+
+	ipGCReclaimWebhookDeadLetterCounts, err := NewMetricInt64Counter(ip_gc_reclaim_webhook_dead_letter_counts, "spiderpool controller ip gc reclaimed ip counts failed to publish to webhook")
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool controller metric '%s', error: %v", ip_gc_reclaim_webhook_dead_letter_counts, err)
+	}
+	IPGCReclaimWebhookDeadLetterCounts = ipGCReclaimWebhookDeadLetterCounts
+
 	IPGCTotalCounts.Add(ctx, 0)
 	IPGCFailureCounts.Add(ctx, 0)
+	IPGCReclaimedIPCounts.Add(ctx, 0)
+
+	return nil
+}
+
+// initSpiderpoolControllerSelfVerificationMetrics will init spiderpool-controller self verification metrics
+func initSpiderpoolControllerSelfVerificationMetrics(ctx context.Context) error {
+	selfVerificationTotalCounts, err := NewMetricInt64Counter(self_verification_total_counts, "spiderpool controller self verification total counts")
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool controller metric '%s', error: %v", self_verification_total_counts, err)
+	}
+	SelfVerificationTotalCounts = selfVerificationTotalCounts
+
+	selfVerificationFailureCounts, err := NewMetricInt64Counter(self_verification_failure_counts, "spiderpool controller self verification failure counts")
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool controller metric '%s', error: %v", self_verification_failure_counts, err)
+	}
+	SelfVerificationFailureCounts = selfVerificationFailureCounts
+
+	err = SelfVerificationLatestDurationSeconds.initGauge(self_verification_latest_duration_seconds, "the latest duration of spiderpool controller self verification round")
+	if nil != err {
+		return err
+	}
+
+	SelfVerificationTotalCounts.Add(ctx, 0)
+	SelfVerificationFailureCounts.Add(ctx, 0)
+
+	return nil
+}
+
+// initSpiderpoolControllerReportOnlyMetrics will init spiderpool-controller report-only mode metrics
+func initSpiderpoolControllerReportOnlyMetrics(ctx context.Context) error {
+	reportOnlyWriteCounts, err := NewMetricInt64Counter(report_only_write_counts, "spiderpool controller writes made as dry runs in report-only mode")
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool controller metric '%s', error: %v", report_only_write_counts, err)
+	}
+	ReportOnlyWriteCounts = reportOnlyWriteCounts
+
+	reportOnlyWebhookDenialCounts, err := NewMetricInt64Counter(report_only_webhook_denial_counts, "spiderpool controller webhook denials admitted in report-only mode")
+	if nil != err {
+		return fmt.Errorf("failed to new spiderpool controller metric '%s', error: %v", report_only_webhook_denial_counts, err)
+	}
+	ReportOnlyWebhookDenialCounts = reportOnlyWebhookDenialCounts
+
+	ReportOnlyWriteCounts.Add(ctx, 0)
+	ReportOnlyWebhookDenialCounts.Add(ctx, 0)
+
+	return nil
+}
+
+// initSpiderpoolControllerIPPoolForecastMetrics will init spiderpool-controller IPPool usage forecast metrics
+func initSpiderpoolControllerIPPoolForecastMetrics(ctx context.Context) error {
+	err := IPPoolPredictedExhaustionSeconds.initGauge(ippool_predicted_exhaustion_seconds, "the predicted seconds until all IP addresses of IPPool are allocated", constant.SpiderIPPoolKind)
+	if nil != err {
+		return err
+	}
 
 	return nil
 }
@@ -0,0 +1,151 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package networkscanner
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+func dialNeighborConn(iface *net.Interface, version types.IPVersion) (neighborConn, error) {
+	if version == constant.IPv4 {
+		return dialARPConn(iface)
+	}
+
+	return dialNDPConn(iface)
+}
+
+// arpConn is a packet socket bound to the interface for ARP.
+type arpConn struct {
+	file *os.File
+	mac  net.HardwareAddr
+}
+
+func dialARPConn(iface *net.Interface) (*arpConn, error) {
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, int(htons(unix.ETH_P_ARP)))
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.Bind(fd, &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ARP), Ifindex: iface.Index}); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	// The non-blocking socket is managed by the runtime poller, which
+	// supports the read deadline.
+	if err := unix.SetNonblock(fd, true); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	return &arpConn{
+		file: os.NewFile(uintptr(fd), fmt.Sprintf("arp-%s", iface.Name)),
+		mac:  iface.HardwareAddr,
+	}, nil
+}
+
+func (c *arpConn) Send(ip net.IP) error {
+	_, err := c.file.Write(NewARPProbe(c.mac, ip))
+	return err
+}
+
+func (c *arpConn) Recv() (net.IP, error) {
+	buf := make([]byte, 1500)
+	for {
+		n, err := c.file.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		if ip, ok := ParseARPReply(buf[:n]); ok {
+			return ip, nil
+		}
+	}
+}
+
+func (c *arpConn) SetReadDeadline(t time.Time) error {
+	return c.file.SetReadDeadline(t)
+}
+
+func (c *arpConn) Close() error {
+	return c.file.Close()
+}
+
+// ndpConn is a raw ICMPv6 socket sending the neighbor solicitations out of
+// the interface.
+type ndpConn struct {
+	conn  *net.IPConn
+	iface *net.Interface
+}
+
+func dialNDPConn(iface *net.Interface) (*ndpConn, error) {
+	pc, err := net.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		return nil, err
+	}
+	conn := pc.(*net.IPConn)
+
+	// The neighbor discovery messages with other hop limits are dropped by
+	// the receivers, as RFC 4861.
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	var sockErr error
+	if err := rc.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_MULTICAST_HOPS, 255)
+	}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if sockErr != nil {
+		conn.Close()
+		return nil, sockErr
+	}
+
+	return &ndpConn{conn: conn, iface: iface}, nil
+}
+
+func (c *ndpConn) Send(ip net.IP) error {
+	_, err := c.conn.WriteTo(NewNeighborSolicitation(c.iface.HardwareAddr, ip), &net.IPAddr{IP: solicitedNodeAddr(ip), Zone: c.iface.Name})
+	return err
+}
+
+func (c *ndpConn) Recv() (net.IP, error) {
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := c.conn.ReadFrom(buf)
+		if err != nil {
+			return nil, err
+		}
+		if ip, ok := ParseNeighborAdvertisement(buf[:n]); ok {
+			return ip, nil
+		}
+	}
+}
+
+func (c *ndpConn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *ndpConn) Close() error {
+	return c.conn.Close()
+}
+
+// htons converts the value to the network byte order regardless of the
+// byte order of the host.
+func htons(v uint16) uint16 {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+
+	return *(*uint16)(unsafe.Pointer(&b[0]))
+}
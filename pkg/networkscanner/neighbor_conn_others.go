@@ -0,0 +1,18 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package networkscanner
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+func dialNeighborConn(iface *net.Interface, version types.IPVersion) (neighborConn, error) {
+	return nil, fmt.Errorf("the network scan is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,198 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package networkscanner
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+const (
+	etherTypeARP = 0x0806
+
+	arpRequest = 1
+	arpReply   = 2
+
+	icmpv6NeighborSolicitation  = 135
+	icmpv6NeighborAdvertisement = 136
+)
+
+// neighborProbeRate is the rate of the probes sent by ProbeNeighbors, which
+// probes a few IP addresses at once rather than a whole IPPool.
+const neighborProbeRate = 1000
+
+// ProbeNeighbors probes the IP addresses of the subnet with ARP or NDP out
+// of the interface of the node attached to the subnet, and returns the ones
+// which replied until timeout after the last probe, or until the deadline of
+// the context if it's earlier. It reports false if the subnet is not
+// attached to the node, whose neighbors can't be probed.
+func ProbeNeighbors(ctx context.Context, subnet string, version types.IPVersion, ips []net.IP, timeout time.Duration) ([]net.IP, bool, error) {
+	iface, err := selectInterface(subnet)
+	if err != nil {
+		return nil, false, err
+	}
+	if iface == nil {
+		return nil, false, nil
+	}
+
+	replied, err := probeNeighbors(ctx, iface, version, ips, neighborProbeRate, timeout)
+	if err != nil {
+		return nil, true, err
+	}
+
+	return replied, true, nil
+}
+
+// neighborConn sends the ARP or NDP probes out of an interface and
+// receives the replies.
+type neighborConn interface {
+	Send(ip net.IP) error
+	// Recv returns the IP address of the next reply.
+	Recv() (net.IP, error)
+	SetReadDeadline(t time.Time) error
+	Close() error
+}
+
+// probeNeighbors probes the IP addresses out of the interface at most rate
+// per second, and returns the ones which replied until timeout after the
+// last probe, or until the deadline of the context if it's earlier.
+func probeNeighbors(ctx context.Context, iface *net.Interface, version types.IPVersion, ips []net.IP, rate int, timeout time.Duration) ([]net.IP, error) {
+	conn, err := dialNeighborConn(iface, version)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	probed := make(map[string]struct{}, len(ips))
+	for _, ip := range ips {
+		probed[ip.String()] = struct{}{}
+	}
+
+	var lock sync.Mutex
+	replied := map[string]net.IP{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			ip, err := conn.Recv()
+			if err != nil {
+				return
+			}
+			// Unsolicited replies of the other IP addresses are ignored.
+			if _, ok := probed[ip.String()]; !ok {
+				continue
+			}
+			lock.Lock()
+			replied[ip.String()] = ip
+			lock.Unlock()
+		}
+	}()
+
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+	for _, ip := range ips {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+		if err := conn.Send(ip); err != nil {
+			return nil, err
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+	<-done
+
+	lock.Lock()
+	defer lock.Unlock()
+	inUseIPs := make([]net.IP, 0, len(replied))
+	for _, ip := range ips {
+		if v, ok := replied[ip.String()]; ok {
+			inUseIPs = append(inUseIPs, v)
+		}
+	}
+
+	return inUseIPs, nil
+}
+
+// NewARPProbe returns the Ethernet frame of the ARP probe of the IPv4
+// address. The sender IP address is unspecified as RFC 5227, so that the
+// ARP caches of the neighbors are not polluted.
+func NewARPProbe(mac net.HardwareAddr, ip net.IP) []byte {
+	frame := make([]byte, 14+28)
+	copy(frame[0:6], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	copy(frame[6:12], mac)
+	binary.BigEndian.PutUint16(frame[12:14], etherTypeARP)
+
+	arp := frame[14:]
+	binary.BigEndian.PutUint16(arp[0:2], 1)      // Ethernet
+	binary.BigEndian.PutUint16(arp[2:4], 0x0800) // IPv4
+	arp[4], arp[5] = 6, 4
+	binary.BigEndian.PutUint16(arp[6:8], arpRequest)
+	copy(arp[8:14], mac)
+	copy(arp[24:28], ip.To4())
+
+	return frame
+}
+
+// ParseARPReply returns the sender IP address of the Ethernet frame of an
+// ARP reply.
+func ParseARPReply(frame []byte) (net.IP, bool) {
+	if len(frame) < 14+28 || binary.BigEndian.Uint16(frame[12:14]) != etherTypeARP {
+		return nil, false
+	}
+
+	arp := frame[14:]
+	if binary.BigEndian.Uint16(arp[6:8]) != arpReply || arp[4] != 6 || arp[5] != 4 {
+		return nil, false
+	}
+
+	return net.IP(append([]byte{}, arp[14:18]...)).To16(), true
+}
+
+// NewNeighborSolicitation returns the ICMPv6 message of the neighbor
+// solicitation of the IPv6 address, whose checksum is computed by the
+// kernel.
+func NewNeighborSolicitation(mac net.HardwareAddr, ip net.IP) []byte {
+	msg := make([]byte, 24+8)
+	msg[0] = icmpv6NeighborSolicitation
+	copy(msg[8:24], ip.To16())
+
+	// source link-layer address option
+	msg[24], msg[25] = 1, 1
+	copy(msg[26:32], mac)
+
+	return msg
+}
+
+// ParseNeighborAdvertisement returns the target IP address of the ICMPv6
+// message of a neighbor advertisement.
+func ParseNeighborAdvertisement(msg []byte) (net.IP, bool) {
+	if len(msg) < 24 || msg[0] != icmpv6NeighborAdvertisement {
+		return nil, false
+	}
+
+	return net.IP(append([]byte{}, msg[8:24]...)), true
+}
+
+// solicitedNodeAddr returns the solicited-node multicast address of the
+// IPv6 address.
+func solicitedNodeAddr(ip net.IP) net.IP {
+	addr := net.ParseIP("ff02::1:ff00:0")
+	copy(addr[13:], ip.To16()[13:])
+
+	return addr
+}
@@ -0,0 +1,244 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package networkscanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+)
+
+const (
+	// checkInterval is the interval to check which IPPools are due to be
+	// scanned, each IPPool is scanned at most once per IntervalDuration.
+	checkInterval = time.Minute
+	// claimTimeoutSlack is added to the expected duration of a scan, after
+	// which the claim of the node is taken over by another one.
+	claimTimeoutSlack = time.Minute
+)
+
+type NetworkScannerConfig struct {
+	NodeName   string
+	EnableIPv4 bool
+	EnableIPv6 bool
+
+	// IntervalDuration is the min interval between two scans of an IPPool.
+	IntervalDuration time.Duration
+	// ProbeRate is the max number of ARP or NDP probes sent per second.
+	ProbeRate int
+	// ProbeTimeout is how long to wait for the replies after the last
+	// probe of an IPPool is sent.
+	ProbeTimeout time.Duration
+}
+
+func setDefaultsForNetworkScannerConfig(config NetworkScannerConfig) NetworkScannerConfig {
+	if config.IntervalDuration <= 0 {
+		config.IntervalDuration = time.Hour
+	}
+
+	if config.ProbeRate <= 0 {
+		config.ProbeRate = 20
+	}
+
+	if config.ProbeTimeout <= 0 {
+		config.ProbeTimeout = time.Second
+	}
+
+	return config
+}
+
+// NetworkScanner probes the free IP addresses of the IPPools on the
+// network with ARP or NDP, to find the ones squatted by the hosts outside
+// Kubernetes. spiderpool-agent runs in the host network namespace, so only
+// the IPPools whose subnet is attached to an interface of the node are
+// scanned, and each of them is claimed by one node at a time. The results
+// are reported to 'status.networkScan' of the IPPools, which are reserved
+// by spiderpool-controller.
+type NetworkScanner interface {
+	Start(ctx context.Context) error
+}
+
+type networkScanner struct {
+	config        NetworkScannerConfig
+	ipPoolManager ippoolmanager.IPPoolManager
+	rIPManager    reservedipmanager.ReservedIPManager
+}
+
+func NewNetworkScanner(config NetworkScannerConfig, ipPoolManager ippoolmanager.IPPoolManager, rIPManager reservedipmanager.ReservedIPManager) (NetworkScanner, error) {
+	if ipPoolManager == nil {
+		return nil, fmt.Errorf("ippool manager %w", constant.ErrMissingRequiredParam)
+	}
+	if rIPManager == nil {
+		return nil, fmt.Errorf("reserved-IP manager %w", constant.ErrMissingRequiredParam)
+	}
+	if config.NodeName == "" {
+		return nil, fmt.Errorf("node name %w", constant.ErrMissingRequiredParam)
+	}
+
+	return &networkScanner{
+		config:        setDefaultsForNetworkScannerConfig(config),
+		ipPoolManager: ipPoolManager,
+		rIPManager:    rIPManager,
+	}, nil
+}
+
+func (ns *networkScanner) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, ns.scanAll, checkInterval)
+
+	return nil
+}
+
+func (ns *networkScanner) scanAll(ctx context.Context) {
+	logger := logutils.FromContext(ctx)
+
+	poolList, err := ns.ipPoolManager.ListIPPools(ctx)
+	if err != nil {
+		logger.Sugar().Warnf("Failed to list IPPools for the network scan: %v", err)
+		return
+	}
+
+	for i := range poolList.Items {
+		pool := &poolList.Items[i]
+		if pool.DeletionTimestamp != nil || pool.Spec.IPVersion == nil {
+			continue
+		}
+		if (*pool.Spec.IPVersion == constant.IPv4 && !ns.config.EnableIPv4) ||
+			(*pool.Spec.IPVersion == constant.IPv6 && !ns.config.EnableIPv6) {
+			continue
+		}
+
+		if err := ns.scanIPPool(ctx, pool, poolList.Items); err != nil {
+			logger.Sugar().Warnf("Failed to scan IPPool %s on the network: %v", pool.Name, err)
+		}
+	}
+}
+
+func (ns *networkScanner) scanIPPool(ctx context.Context, pool *spiderpoolv1.SpiderIPPool, pools []spiderpoolv1.SpiderIPPool) error {
+	logger := logutils.FromContext(ctx)
+
+	iface, err := selectInterface(pool.Spec.Subnet)
+	if err != nil {
+		return err
+	}
+	if iface == nil {
+		return nil
+	}
+
+	reservedIPs, err := ns.rIPManager.AssembleReservedIPs(ctx, *pool.Spec.IPVersion)
+	if err != nil {
+		return err
+	}
+	candidates, err := ScanCandidates(pool, pools, reservedIPs)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	timeout := time.Duration(len(candidates))*time.Second/time.Duration(ns.config.ProbeRate) + ns.config.ProbeTimeout + claimTimeoutSlack
+	claimed, err := ns.ipPoolManager.ClaimNetworkScan(ctx, pool.Name, ns.config.NodeName, ns.config.IntervalDuration, timeout)
+	if err != nil {
+		return err
+	}
+	if claimed == nil {
+		return nil
+	}
+
+	logger.Sugar().Infof("Scan %d free IP addresses of IPPool %s on interface %s", len(candidates), pool.Name, iface.Name)
+	inUseIPs, err := probeNeighbors(ctx, iface, *pool.Spec.IPVersion, candidates, ns.config.ProbeRate, ns.config.ProbeTimeout)
+	if err != nil {
+		return err
+	}
+	if len(inUseIPs) > 0 {
+		logger.Sugar().Warnf("Found %d free IP addresses of IPPool %s in use on the network: %v", len(inUseIPs), pool.Name, inUseIPs)
+	}
+
+	return ns.ipPoolManager.ReportNetworkScan(ctx, pool.Name, ns.config.NodeName, inUseIPs)
+}
+
+// ScanCandidates returns the IP addresses of the IPPool to be probed, which
+// are neither excluded, reserved nor allocated to the Pods by any IPPool of
+// the same subnet, such as the child IPPools, since the Pods reply to the
+// probes as well.
+func ScanCandidates(pool *spiderpoolv1.SpiderIPPool, pools []spiderpoolv1.SpiderIPPool, reservedIPs []net.IP) ([]net.IP, error) {
+	ips, err := spiderpoolip.ParseIPRanges(*pool.Spec.IPVersion, pool.Spec.IPs)
+	if err != nil {
+		return nil, err
+	}
+	excludeIPs, err := spiderpoolip.ParseIPRanges(*pool.Spec.IPVersion, pool.Spec.ExcludeIPs)
+	if err != nil {
+		return nil, err
+	}
+	// Scanned in order, so that the IP addresses found in use are reported
+	// the same way each round.
+	totalIPs := spiderpoolip.IPsDiffSet(ips, excludeIPs, true)
+
+	excluded := make(map[string]struct{}, len(reservedIPs))
+	for _, ip := range reservedIPs {
+		excluded[ip.String()] = struct{}{}
+	}
+	for ip := range pool.Status.AllocatedIPs {
+		excluded[ip] = struct{}{}
+	}
+	for _, p := range pools {
+		if p.Name == pool.Name || p.Spec.Subnet != pool.Spec.Subnet {
+			continue
+		}
+		for ip := range p.Status.AllocatedIPs {
+			excluded[ip] = struct{}{}
+		}
+	}
+
+	var candidates []net.IP
+	for _, ip := range totalIPs {
+		if _, ok := excluded[ip.String()]; !ok {
+			candidates = append(candidates, ip)
+		}
+	}
+
+	return candidates, nil
+}
+
+// selectInterface returns the interface of the node with an address in the
+// subnet, or nil if the subnet is not attached to the node.
+func selectInterface(subnet string) (*net.Interface, error) {
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return nil, err
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for i := range ifaces {
+		iface := &ifaces[i]
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) == 0 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			if ip, ok := addr.(*net.IPNet); ok && ipNet.Contains(ip.IP) {
+				return iface, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
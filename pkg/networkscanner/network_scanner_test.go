@@ -0,0 +1,103 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package networkscanner_test
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/networkscanner"
+)
+
+var _ = Describe("NetworkScanner", Label("network_scanner_test"), func() {
+	Describe("New NetworkScanner", func() {
+		It("inputs nil IPPool manager", func() {
+			scanner, err := networkscanner.NewNetworkScanner(networkscanner.NetworkScannerConfig{NodeName: "node"}, nil, nil)
+			Expect(err).To(MatchError(constant.ErrMissingRequiredParam))
+			Expect(scanner).To(BeNil())
+		})
+	})
+
+	Describe("ScanCandidates", func() {
+		It("skips the excluded, reserved and allocated IP addresses", func() {
+			pool := &spiderpoolv1.SpiderIPPool{
+				ObjectMeta: metav1.ObjectMeta{Name: "pool"},
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion:  pointer.Int64(constant.IPv4),
+					Subnet:     "172.18.40.0/24",
+					IPs:        []string{"172.18.40.1-172.18.40.6"},
+					ExcludeIPs: []string{"172.18.40.1"},
+				},
+				Status: spiderpoolv1.IPPoolStatus{
+					AllocatedIPs: spiderpoolv1.PoolIPAllocations{"172.18.40.2": {}},
+				},
+			}
+			child := spiderpoolv1.SpiderIPPool{
+				ObjectMeta: metav1.ObjectMeta{Name: "child"},
+				Spec:       spiderpoolv1.IPPoolSpec{Subnet: "172.18.40.0/24"},
+				Status: spiderpoolv1.IPPoolStatus{
+					AllocatedIPs: spiderpoolv1.PoolIPAllocations{"172.18.40.3": {}},
+				},
+			}
+			other := spiderpoolv1.SpiderIPPool{
+				ObjectMeta: metav1.ObjectMeta{Name: "other"},
+				Spec:       spiderpoolv1.IPPoolSpec{Subnet: "172.18.41.0/24"},
+				Status: spiderpoolv1.IPPoolStatus{
+					AllocatedIPs: spiderpoolv1.PoolIPAllocations{"172.18.40.4": {}},
+				},
+			}
+
+			candidates, err := networkscanner.ScanCandidates(pool, []spiderpoolv1.SpiderIPPool{*pool, child, other}, []net.IP{net.ParseIP("172.18.40.5")})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(candidates).To(Equal([]net.IP{net.ParseIP("172.18.40.4"), net.ParseIP("172.18.40.6")}))
+		})
+	})
+
+	Describe("Neighbor probes", func() {
+		mac := net.HardwareAddr{0x0a, 0x60, 0xac, 0x12, 0x28, 0x01}
+
+		It("builds the ARP probe and parses the reply", func() {
+			frame := networkscanner.NewARPProbe(mac, net.ParseIP("172.18.40.10"))
+			Expect(frame).To(HaveLen(42))
+			Expect(frame[0:6]).To(Equal([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}))
+			Expect(frame[28:32]).To(Equal([]byte{0, 0, 0, 0}))
+			Expect(frame[38:42]).To(Equal([]byte{172, 18, 40, 10}))
+
+			_, ok := networkscanner.ParseARPReply(frame)
+			Expect(ok).To(BeFalse())
+
+			// turn the request into the reply of the target
+			frame[21] = 2
+			copy(frame[28:32], []byte{172, 18, 40, 10})
+			ip, ok := networkscanner.ParseARPReply(frame)
+			Expect(ok).To(BeTrue())
+			Expect(ip.Equal(net.ParseIP("172.18.40.10"))).To(BeTrue())
+
+			_, ok = networkscanner.ParseARPReply(frame[:20])
+			Expect(ok).To(BeFalse())
+		})
+
+		It("builds the neighbor solicitation and parses the advertisement", func() {
+			msg := networkscanner.NewNeighborSolicitation(mac, net.ParseIP("abcd:1234::a"))
+			Expect(msg).To(HaveLen(32))
+			Expect(msg[0]).To(BeEquivalentTo(135))
+			Expect(net.IP(msg[8:24]).Equal(net.ParseIP("abcd:1234::a"))).To(BeTrue())
+			Expect(net.HardwareAddr(msg[26:32])).To(Equal(mac))
+
+			_, ok := networkscanner.ParseNeighborAdvertisement(msg)
+			Expect(ok).To(BeFalse())
+
+			msg[0] = 136
+			ip, ok := networkscanner.ParseNeighborAdvertisement(msg)
+			Expect(ok).To(BeTrue())
+			Expect(ip.Equal(net.ParseIP("abcd:1234::a"))).To(BeTrue())
+		})
+	})
+})
@@ -17,6 +17,7 @@ import (
 type NodeManager interface {
 	GetNodeByName(ctx context.Context, nodeName string) (*corev1.Node, error)
 	ListNodes(ctx context.Context, opts ...client.ListOption) (*corev1.NodeList, error)
+	RemoveNodeTaint(ctx context.Context, nodeName, taintKey string) error
 }
 
 type nodeManager struct {
@@ -50,3 +51,25 @@ func (nm *nodeManager) ListNodes(ctx context.Context, opts ...client.ListOption)
 
 	return &nodeList, nil
 }
+
+// RemoveNodeTaint removes the taints with the key from the Node, it does
+// nothing if the Node has no such taint.
+func (nm *nodeManager) RemoveNodeTaint(ctx context.Context, nodeName, taintKey string) error {
+	node, err := nm.GetNodeByName(ctx, nodeName)
+	if err != nil {
+		return err
+	}
+
+	taints := make([]corev1.Taint, 0, len(node.Spec.Taints))
+	for _, taint := range node.Spec.Taints {
+		if taint.Key != taintKey {
+			taints = append(taints, taint)
+		}
+	}
+	if len(taints) == len(node.Spec.Taints) {
+		return nil
+	}
+
+	node.Spec.Taints = taints
+	return nm.client.Update(ctx, node)
+}
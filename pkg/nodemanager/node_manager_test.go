@@ -159,5 +159,34 @@ var _ = Describe("NodeManager", Label("node_manager_test"), func() {
 				Expect(hasNode).To(BeTrue())
 			})
 		})
+
+		Describe("RemoveNodeTaint", func() {
+			It("removes the taint of non-existent Node", func() {
+				ctx := context.TODO()
+				err := nodeManager.RemoveNodeTaint(ctx, nodeName, constant.TaintAgentNotReady)
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			})
+
+			It("removes the taint with the key only", func() {
+				nodeT.Spec.Taints = []corev1.Taint{
+					{Key: constant.TaintAgentNotReady, Effect: corev1.TaintEffectNoSchedule},
+					{Key: "foo", Value: "bar", Effect: corev1.TaintEffectNoExecute},
+				}
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, nodeT)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = nodeManager.RemoveNodeTaint(ctx, nodeName, constant.TaintAgentNotReady)
+				Expect(err).NotTo(HaveOccurred())
+
+				node, err := nodeManager.GetNodeByName(ctx, nodeName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(node.Spec.Taints).To(Equal(nodeT.Spec.Taints[1:]))
+
+				err = nodeManager.RemoveNodeTaint(ctx, nodeName, constant.TaintAgentNotReady)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
 	})
 })
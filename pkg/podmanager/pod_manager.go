@@ -10,12 +10,16 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	apitypes "k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/spidernet-io/spiderpool/pkg/constant"
 	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	subnetmanagercontrollers "github.com/spidernet-io/spiderpool/pkg/subnetmanager/controllers"
 	"github.com/spidernet-io/spiderpool/pkg/types"
 )
 
@@ -23,6 +27,7 @@ type PodManager interface {
 	GetPodByName(ctx context.Context, namespace, podName string) (*corev1.Pod, error)
 	ListPods(ctx context.Context, opts ...client.ListOption) (*corev1.PodList, error)
 	GetPodTopController(ctx context.Context, pod *corev1.Pod) (types.PodTopController, error)
+	IsProtectedByPDB(ctx context.Context, pod *corev1.Pod) (bool, error)
 }
 
 type podManager struct {
@@ -62,7 +67,8 @@ func (pm *podManager) ListPods(ctx context.Context, opts ...client.ListOption) (
 // GetPodTopController will find the pod top owner controller with the given pod.
 // For example, once we create a deployment then it will create replicaset and the replicaset will create pods.
 // So, the pods' top owner is deployment. That's what the method implements.
-// Notice: if the application is a third party controller, the types.PodTopController property App would be nil!
+// Notice: if the application is a third party controller, the types.PodTopController property App would be nil,
+// unless it's one of the configured third party controllers whose App is an unstructured object!
 func (pm *podManager) GetPodTopController(ctx context.Context, pod *corev1.Pod) (types.PodTopController, error) {
 	logger := logutils.FromContext(ctx)
 
@@ -81,6 +87,9 @@ func (pm *podManager) GetPodTopController(ctx context.Context, pod *corev1.Pod)
 
 	// third party controller
 	if podOwner.APIVersion != appsv1.SchemeGroupVersion.String() && podOwner.APIVersion != batchv1.SchemeGroupVersion.String() {
+		if _, ok := subnetmanagercontrollers.GetThirdPartyController(podOwner.APIVersion, podOwner.Kind); ok {
+			return pm.getThirdPartyController(ctx, pod.Namespace, podOwner)
+		}
 		return types.PodTopController{
 			Kind:      constant.KindUnknown,
 			Namespace: pod.Namespace,
@@ -119,6 +128,11 @@ func (pm *podManager) GetPodTopController(ctx context.Context, pod *corev1.Pod)
 				}, nil
 			}
 
+			// such as the ReplicaSets of Argo Rollouts
+			if _, ok := subnetmanagercontrollers.GetThirdPartyController(replicasetOwner.APIVersion, replicasetOwner.Kind); ok {
+				return pm.getThirdPartyController(ctx, replicaset.Namespace, replicasetOwner)
+			}
+
 			logger.Sugar().Warnf("the controller type '%s' of pod '%s/%s' is unknown", replicasetOwner.Kind, pod.Namespace, pod.Name)
 			return types.PodTopController{
 				Kind:      constant.KindUnknown,
@@ -211,3 +225,47 @@ func (pm *podManager) GetPodTopController(ctx context.Context, pod *corev1.Pod)
 		UID:       podOwner.UID,
 	}, nil
 }
+
+// getThirdPartyController gets the configured third-party controller of the
+// owner reference, whose APP is an unstructured object.
+func (pm *podManager) getThirdPartyController(ctx context.Context, namespace string, owner *metav1.OwnerReference) (types.PodTopController, error) {
+	app := &unstructured.Unstructured{}
+	app.SetAPIVersion(owner.APIVersion)
+	app.SetKind(owner.Kind)
+	if err := pm.client.Get(ctx, apitypes.NamespacedName{Namespace: namespace, Name: owner.Name}, app); nil != err {
+		return types.PodTopController{}, fmt.Errorf("failed to get %s '%s/%s': %v", owner.Kind, namespace, owner.Name, err)
+	}
+
+	return types.PodTopController{
+		Kind:      owner.Kind,
+		Namespace: app.GetNamespace(),
+		Name:      app.GetName(),
+		UID:       app.GetUID(),
+		APP:       app,
+	}, nil
+}
+
+// IsProtectedByPDB reports whether the Pod is selected by any
+// PodDisruptionBudget in its Namespace.
+func (pm *podManager) IsProtectedByPDB(ctx context.Context, pod *corev1.Pod) (bool, error) {
+	var pdbList policyv1.PodDisruptionBudgetList
+	if err := pm.client.List(ctx, &pdbList, client.InNamespace(pod.Namespace)); err != nil {
+		return false, err
+	}
+
+	for _, pdb := range pdbList.Items {
+		if pdb.Spec.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse the selector of PodDisruptionBudget %s/%s: %v", pdb.Namespace, pdb.Name, err)
+		}
+		// An empty selector matches every Pod in the Namespace.
+		if selector.Matches(labels.Set(pod.Labels)) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -16,14 +16,19 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/spidernet-io/spiderpool/pkg/constant"
 	"github.com/spidernet-io/spiderpool/pkg/podmanager"
+	"github.com/spidernet-io/spiderpool/pkg/singletons"
+	subnetmanagercontrollers "github.com/spidernet-io/spiderpool/pkg/subnetmanager/controllers"
+	"github.com/spidernet-io/spiderpool/pkg/types"
 )
 
 var _ = Describe("PodManager", Label("pod_manager_test"), func() {
@@ -215,6 +220,45 @@ var _ = Describe("PodManager", Label("pod_manager_test"), func() {
 				Expect(podTopController.Kind).Should(Equal(constant.KindUnknown))
 			})
 
+			It("Pod with configured third-party controller", func() {
+				singletons.InitThirdPartyControllers([]types.ThirdPartyController{{
+					APIVersion: kruisev1.SchemeGroupVersion.String(),
+					Kind:       "CloneSet",
+					Resource:   "clonesets",
+				}})
+				DeferCleanup(singletons.InitThirdPartyControllers, []types.ThirdPartyController(nil))
+
+				err := kruiseapi.AddToScheme(scheme)
+				Expect(err).NotTo(HaveOccurred())
+
+				cloneSet := &kruisev1.CloneSet{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      podName,
+						Namespace: namespace,
+					},
+					Spec: kruisev1.CloneSetSpec{
+						Replicas: pointer.Int32(3),
+					},
+				}
+				err = fakeClient.Create(ctx, cloneSet)
+				Expect(err).NotTo(HaveOccurred())
+				DeferCleanup(fakeClient.Delete, ctx, cloneSet)
+
+				err = controllerutil.SetControllerReference(cloneSet, podT, scheme)
+				Expect(err).NotTo(HaveOccurred())
+
+				podTopController, err := podManager.GetPodTopController(ctx, podT)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(podTopController.Kind).To(Equal("CloneSet"))
+				Expect(podTopController.UID).To(Equal(cloneSet.UID))
+
+				app, ok := podTopController.APP.(*unstructured.Unstructured)
+				Expect(ok).To(BeTrue())
+				replicas, err := subnetmanagercontrollers.GetThirdPartyAppReplicas(app)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(replicas).To(Equal(3))
+			})
+
 			It("Pod with ReplicaSet controller", func() {
 				err := appsv1.AddToScheme(scheme)
 				Expect(err).NotTo(HaveOccurred())
@@ -573,5 +617,34 @@ var _ = Describe("PodManager", Label("pod_manager_test"), func() {
 				Expect(err).To(HaveOccurred())
 			})
 		})
+
+		Describe("IsProtectedByPDB", func() {
+			It("is selected by a PodDisruptionBudget", func() {
+				pdb := &policyv1.PodDisruptionBudget{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      podName,
+						Namespace: namespace,
+					},
+					Spec: policyv1.PodDisruptionBudgetSpec{
+						Selector: &metav1.LabelSelector{MatchLabels: labels},
+					},
+				}
+				err := fakeClient.Create(ctx, pdb)
+				Expect(err).NotTo(HaveOccurred())
+				defer func() {
+					err := fakeClient.Delete(ctx, pdb)
+					Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+				}()
+
+				protected, err := podManager.IsProtectedByPDB(ctx, podT)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(protected).To(BeTrue())
+
+				podT.Labels = map[string]string{"foo": "other"}
+				protected, err = podManager.IsProtectedByPDB(ctx, podT)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(protected).To(BeFalse())
+			})
+		})
 	})
 })
@@ -0,0 +1,162 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+// Package reportonly serves the report-only mode of spiderpool-controller,
+// in which the changes that Spiderpool would make are reported through logs
+// and metrics, but never applied to the cluster.
+package reportonly
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/metric"
+)
+
+var logger *zap.Logger
+
+func getLogger() *zap.Logger {
+	if logger == nil {
+		logger = logutils.Logger.Named("Report-Only")
+	}
+	return logger
+}
+
+// NewClient implements cluster.NewClientFunc. The writes of the client are
+// sent to the API server as dry runs, so that they are still validated and
+// admitted, but never persisted.
+func NewClient(cache cache.Cache, config *rest.Config, options client.Options, uncachedObjects ...client.Object) (client.Client, error) {
+	c, err := cluster.DefaultNewClient(cache, config, options, uncachedObjects...)
+	if err != nil {
+		return nil, err
+	}
+
+	return WrapClient(c), nil
+}
+
+// WrapClient wraps the client to make dry runs for all writes, each of which
+// is logged and counted.
+func WrapClient(c client.Client) client.Client {
+	return &reportOnlyClient{Client: client.NewDryRunClient(c)}
+}
+
+type reportOnlyClient struct {
+	client.Client
+}
+
+func (c *reportOnlyClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	err := c.Client.Create(ctx, obj, opts...)
+	report(ctx, c.Scheme(), "create", obj, err)
+	return err
+}
+
+func (c *reportOnlyClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	err := c.Client.Update(ctx, obj, opts...)
+	report(ctx, c.Scheme(), "update", obj, err)
+	return err
+}
+
+func (c *reportOnlyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	err := c.Client.Patch(ctx, obj, patch, opts...)
+	report(ctx, c.Scheme(), "patch", obj, err)
+	return err
+}
+
+func (c *reportOnlyClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	err := c.Client.Delete(ctx, obj, opts...)
+	report(ctx, c.Scheme(), "delete", obj, err)
+	return err
+}
+
+func (c *reportOnlyClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	err := c.Client.DeleteAllOf(ctx, obj, opts...)
+	report(ctx, c.Scheme(), "delete all of", obj, err)
+	return err
+}
+
+func (c *reportOnlyClient) Status() client.StatusWriter {
+	return &reportOnlyStatusWriter{StatusWriter: c.Client.Status(), scheme: c.Scheme()}
+}
+
+type reportOnlyStatusWriter struct {
+	client.StatusWriter
+	scheme *runtime.Scheme
+}
+
+func (w *reportOnlyStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	err := w.StatusWriter.Update(ctx, obj, opts...)
+	report(ctx, w.scheme, "update status of", obj, err)
+	return err
+}
+
+func (w *reportOnlyStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	err := w.StatusWriter.Patch(ctx, obj, patch, opts...)
+	report(ctx, w.scheme, "patch status of", obj, err)
+	return err
+}
+
+func report(ctx context.Context, scheme *runtime.Scheme, verb string, obj client.Object, err error) {
+	kind := "object"
+	if gvk, e := apiutil.GVKForObject(obj, scheme); e == nil {
+		kind = gvk.Kind
+	}
+
+	name := obj.GetName()
+	if obj.GetNamespace() != "" {
+		name = obj.GetNamespace() + "/" + name
+	}
+
+	if err != nil {
+		getLogger().Sugar().Warnf("Would fail to %s %s %s: %v", verb, kind, name, err)
+	} else {
+		getLogger().Sugar().Infof("Would %s %s %s", verb, kind, name)
+	}
+	metric.ReportOnlyWriteCounts.Add(ctx, 1)
+}
+
+// NewValidator wraps the validator of the webhook to admit all requests, the
+// rejections are logged and counted only.
+func NewValidator(kind string, v webhook.CustomValidator) webhook.CustomValidator {
+	return &auditValidator{CustomValidator: v, kind: kind}
+}
+
+type auditValidator struct {
+	webhook.CustomValidator
+	kind string
+}
+
+func (a *auditValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return a.audit(ctx, "create", obj, a.CustomValidator.ValidateCreate(ctx, obj))
+}
+
+func (a *auditValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	return a.audit(ctx, "update", newObj, a.CustomValidator.ValidateUpdate(ctx, oldObj, newObj))
+}
+
+func (a *auditValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return a.audit(ctx, "delete", obj, a.CustomValidator.ValidateDelete(ctx, obj))
+}
+
+func (a *auditValidator) audit(ctx context.Context, verb string, obj runtime.Object, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	name := ""
+	if o, ok := obj.(client.Object); ok {
+		name = o.GetName()
+	}
+	getLogger().Sugar().Warnf("Would deny to %s %s %s: %v", verb, a.kind, name, err)
+	metric.ReportOnlyWebhookDenialCounts.Add(ctx, 1)
+
+	return nil
+}
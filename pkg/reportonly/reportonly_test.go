@@ -0,0 +1,104 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package reportonly_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/reportonly"
+)
+
+type denyValidator struct{}
+
+func (denyValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return errors.New("denied")
+}
+
+func (denyValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	return errors.New("denied")
+}
+
+func (denyValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return errors.New("denied")
+}
+
+var _ = Describe("ReportOnly", Label("reportonly_test"), func() {
+	var ipPool *spiderpoolv1.SpiderIPPool
+
+	BeforeEach(func() {
+		ipPool = &spiderpoolv1.SpiderIPPool{
+			ObjectMeta: metav1.ObjectMeta{Name: "ippool"},
+			Spec: spiderpoolv1.IPPoolSpec{
+				Subnet: "172.18.40.0/24",
+			},
+		}
+	})
+
+	Describe("Test WrapClient", func() {
+		var fakeClient, reportOnlyClient client.Client
+
+		BeforeEach(func() {
+			fakeClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+			reportOnlyClient = reportonly.WrapClient(fakeClient)
+		})
+
+		It("does not create the object", func() {
+			ctx := context.TODO()
+			err := reportOnlyClient.Create(ctx, ipPool)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = fakeClient.Get(ctx, client.ObjectKeyFromObject(ipPool), &spiderpoolv1.SpiderIPPool{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("does not update the object", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPool)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipPool.Spec.IPs = []string{"172.18.40.10"}
+			err = reportOnlyClient.Update(ctx, ipPool)
+			Expect(err).NotTo(HaveOccurred())
+
+			var pool spiderpoolv1.SpiderIPPool
+			err = fakeClient.Get(ctx, client.ObjectKeyFromObject(ipPool), &pool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pool.Spec.IPs).To(BeEmpty())
+		})
+
+		It("does not delete the object", func() {
+			ctx := context.TODO()
+			err := fakeClient.Create(ctx, ipPool)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = reportOnlyClient.Delete(ctx, ipPool)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = fakeClient.Get(ctx, client.ObjectKeyFromObject(ipPool), &spiderpoolv1.SpiderIPPool{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("Test NewValidator", func() {
+		It("admits the requests which would be denied", func() {
+			validator := reportonly.NewValidator(constant.SpiderIPPoolKind, denyValidator{})
+
+			ctx := context.TODO()
+			Expect(validator.ValidateCreate(ctx, ipPool)).To(Succeed())
+			Expect(validator.ValidateUpdate(ctx, ipPool, ipPool)).To(Succeed())
+			Expect(validator.ValidateDelete(ctx, ipPool)).To(Succeed())
+		})
+	})
+})
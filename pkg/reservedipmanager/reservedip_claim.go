@@ -0,0 +1,339 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package reservedipmanager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// maxClaimUpdateRetries is the max retries to update the SpiderReservedIP
+// for its claims on conflicts.
+const maxClaimUpdateRetries = 3
+
+// ClaimedIP is an IP address reserved for the Pod by the claim of the
+// SpiderReservedIP.
+type ClaimedIP struct {
+	ReservedIP string
+	IPVersion  types.IPVersion
+	IP         net.IP
+
+	// BoundPodUID is the UID of the Pod which the IP address is bound to,
+	// it's empty if the IP address is not bound yet.
+	BoundPodUID string
+}
+
+// IsReservedIPClaimedBy reports whether the Pod is a claimant of the
+// SpiderReservedIP.
+func IsReservedIPClaimedBy(rIP *spiderpoolv1.SpiderReservedIP, pod *corev1.Pod) (bool, error) {
+	claim := rIP.Spec.Claim
+	if claim == nil || claim.Namespace != pod.Namespace {
+		return false, nil
+	}
+
+	if claim.PodName != "" {
+		return claim.PodName == pod.Name, nil
+	}
+
+	if claim.PodSelector == nil {
+		return false, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(claim.PodSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid pod selector of SpiderReservedIP %s: %w", rIP.Name, err)
+	}
+
+	return selector.Matches(labels.Set(pod.Labels)), nil
+}
+
+// ListClaimedIPs returns the IP addresses reserved from the IPPool for the
+// Pod by the claims of the SpiderReservedIPs.
+func (rm *reservedIPManager) ListClaimedIPs(ctx context.Context, version types.IPVersion, pool *spiderpoolv1.SpiderIPPool, pod *corev1.Pod) ([]ClaimedIP, error) {
+	if err := spiderpoolip.IsIPVersion(version); err != nil {
+		return nil, err
+	}
+
+	rIPList, err := rm.ListReservedIPs(ctx, client.MatchingFields{"spec.ipVersion": strconv.FormatInt(version, 10)})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var claimed []ClaimedIP
+	for i := range rIPList.Items {
+		rIP := &rIPList.Items[i]
+		if rIP.DeletionTimestamp != nil || IsReservedIPExpired(rIP, now) {
+			continue
+		}
+
+		ok, err := IsReservedIPClaimedBy(rIP, pod)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		if rIP.Spec.PoolSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(rIP.Spec.PoolSelector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pool selector of SpiderReservedIP %s: %w", rIP.Name, err)
+			}
+			if !selector.Matches(labels.Set(pool.Labels)) {
+				continue
+			}
+		}
+
+		ips, err := GetReservedIPs(version, rIP)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			claimed = append(claimed, ClaimedIP{
+				ReservedIP:  rIP.Name,
+				IPVersion:   version,
+				IP:          ip,
+				BoundPodUID: rIP.Status.BoundClaims[ip.String()].PodUID,
+			})
+		}
+	}
+
+	return claimed, nil
+}
+
+// BindClaimedIP binds the claimed IP address to the claimant Pod before it's
+// allocated, so that it's handed out to no other claimant. It's a no-op if
+// the IP address is already bound to the Pod.
+func (rm *reservedIPManager) BindClaimedIP(ctx context.Context, claimed ClaimedIP, pod *corev1.Pod) error {
+	ip := claimed.IP.String()
+	for i := 0; i <= maxClaimUpdateRetries; i++ {
+		rIP, err := rm.GetReservedIPByName(ctx, claimed.ReservedIP)
+		if err != nil {
+			return err
+		}
+
+		if bound, ok := rIP.Status.BoundClaims[ip]; ok {
+			if bound.PodUID == string(pod.UID) {
+				return nil
+			}
+			return fmt.Errorf("%w, IP address %s of SpiderReservedIP %s is bound to Pod %s", constant.ErrClaimedIPTaken, ip, rIP.Name, bound.Pod)
+		}
+
+		// The IP address may be consumed by another claimant meanwhile.
+		ips, err := GetReservedIPs(claimed.IPVersion, rIP)
+		if err != nil {
+			return err
+		}
+		if len(spiderpoolip.IPsIntersectionSet(ips, []net.IP{claimed.IP}, false)) == 0 {
+			return fmt.Errorf("%w, IP address %s is no longer reserved by SpiderReservedIP %s", constant.ErrClaimedIPTaken, ip, rIP.Name)
+		}
+
+		if rIP.Status.BoundClaims == nil {
+			rIP.Status.BoundClaims = map[string]spiderpoolv1.ReservedIPBoundClaim{}
+		}
+		rIP.Status.BoundClaims[ip] = spiderpoolv1.ReservedIPBoundClaim{
+			Pod:    pod.Name,
+			PodUID: string(pod.UID),
+		}
+		if err := rm.client.Status().Update(ctx, rIP); err != nil {
+			if apierrors.IsConflict(err) && i < maxClaimUpdateRetries {
+				continue
+			}
+			return err
+		}
+		break
+	}
+
+	return nil
+}
+
+// ConsumeClaimedIP removes the IP address allocated to the claimant Pod from
+// the SpiderReservedIP, which is deleted once all of its IP addresses are
+// consumed, and then unbinds it. It's idempotent, so that the consumption
+// failed in the allocation is retried by ConsumeBoundClaims.
+func (rm *reservedIPManager) ConsumeClaimedIP(ctx context.Context, claimed ClaimedIP) error {
+	for i := 0; i <= maxClaimUpdateRetries; i++ {
+		rIP, err := rm.GetReservedIPByName(ctx, claimed.ReservedIP)
+		if err != nil {
+			return client.IgnoreNotFound(err)
+		}
+
+		ips, err := GetReservedIPs(claimed.IPVersion, rIP)
+		if err != nil {
+			return err
+		}
+		remaining := spiderpoolip.IPsDiffSet(ips, []net.IP{claimed.IP}, false)
+		if len(remaining) == len(ips) {
+			break
+		}
+
+		if len(remaining) == 0 {
+			return client.IgnoreNotFound(rm.client.Delete(ctx, rIP))
+		}
+
+		rIP.Spec.IPs, err = spiderpoolip.ConvertIPsToIPRanges(claimed.IPVersion, remaining)
+		if err != nil {
+			return err
+		}
+		rIP.Spec.ExcludeIPs = nil
+		if err := rm.client.Update(ctx, rIP); err != nil {
+			if apierrors.IsConflict(err) && i < maxClaimUpdateRetries {
+				continue
+			}
+			return client.IgnoreNotFound(err)
+		}
+		break
+	}
+
+	return rm.unbindClaimedIP(ctx, claimed)
+}
+
+// unbindClaimedIP removes the binding of the claimed IP address from the
+// status of the SpiderReservedIP.
+func (rm *reservedIPManager) unbindClaimedIP(ctx context.Context, claimed ClaimedIP) error {
+	ip := claimed.IP.String()
+	for i := 0; i <= maxClaimUpdateRetries; i++ {
+		rIP, err := rm.GetReservedIPByName(ctx, claimed.ReservedIP)
+		if err != nil {
+			return client.IgnoreNotFound(err)
+		}
+
+		if _, ok := rIP.Status.BoundClaims[ip]; !ok {
+			return nil
+		}
+
+		delete(rIP.Status.BoundClaims, ip)
+		if err := rm.client.Status().Update(ctx, rIP); err != nil {
+			if apierrors.IsConflict(err) && i < maxClaimUpdateRetries {
+				continue
+			}
+			return client.IgnoreNotFound(err)
+		}
+		break
+	}
+
+	return nil
+}
+
+// ConsumeBoundClaims consumes the claimed IP addresses already allocated to
+// the Pods they're bound to, whose consumption failed in the allocation, and
+// unbinds the ones whose Pods are gone. It returns the number of the
+// consumed IP addresses.
+func (rm *reservedIPManager) ConsumeBoundClaims(ctx context.Context) (int, error) {
+	rIPList, err := rm.ListReservedIPs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var poolList spiderpoolv1.SpiderIPPoolList
+	if err := rm.client.List(ctx, &poolList); err != nil {
+		return 0, err
+	}
+
+	var consumed int
+	var errs []error
+	for i := range rIPList.Items {
+		rIP := &rIPList.Items[i]
+		if rIP.DeletionTimestamp != nil || rIP.Spec.IPVersion == nil {
+			continue
+		}
+
+		for ip, bound := range rIP.Status.BoundClaims {
+			claimed := ClaimedIP{
+				ReservedIP:  rIP.Name,
+				IPVersion:   *rIP.Spec.IPVersion,
+				IP:          net.ParseIP(ip),
+				BoundPodUID: bound.PodUID,
+			}
+
+			allocated, err := isAllocatedToBoundPod(rIP, ip, bound, poolList.Items)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if allocated {
+				if err := rm.ConsumeClaimedIP(ctx, claimed); err != nil {
+					errs = append(errs, fmt.Errorf("failed to consume IP address %s of SpiderReservedIP %s: %w", ip, rIP.Name, err))
+					continue
+				}
+				consumed++
+				continue
+			}
+
+			gone, err := rm.isBoundPodGone(ctx, rIP, bound)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if gone {
+				if err := rm.unbindClaimedIP(ctx, claimed); err != nil {
+					errs = append(errs, fmt.Errorf("failed to unbind IP address %s of SpiderReservedIP %s: %w", ip, rIP.Name, err))
+				}
+			}
+		}
+	}
+
+	return consumed, utilerrors.NewAggregate(errs)
+}
+
+// isAllocatedToBoundPod reports whether the claimed IP address is allocated
+// to the Pod it's bound to from any IPPool in the scope of the
+// SpiderReservedIP.
+func isAllocatedToBoundPod(rIP *spiderpoolv1.SpiderReservedIP, ip string, bound spiderpoolv1.ReservedIPBoundClaim, pools []spiderpoolv1.SpiderIPPool) (bool, error) {
+	for i := range pools {
+		pool := &pools[i]
+		if pool.Spec.IPVersion == nil || *pool.Spec.IPVersion != *rIP.Spec.IPVersion {
+			continue
+		}
+
+		allocation, ok := pool.Status.AllocatedIPs[ip]
+		if !ok || allocation.PodUID != bound.PodUID {
+			continue
+		}
+
+		ok, err := matchReservedIPPools(rIP, pool)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// isBoundPodGone reports whether the Pod which the claimed IP address is
+// bound to no longer exists, or is no longer a claimant.
+func (rm *reservedIPManager) isBoundPodGone(ctx context.Context, rIP *spiderpoolv1.SpiderReservedIP, bound spiderpoolv1.ReservedIPBoundClaim) (bool, error) {
+	if rIP.Spec.Claim == nil {
+		return true, nil
+	}
+
+	var pod corev1.Pod
+	if err := rm.client.Get(ctx, apitypes.NamespacedName{Namespace: rIP.Spec.Claim.Namespace, Name: bound.Pod}, &pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	return string(pod.UID) != bound.PodUID, nil
+}
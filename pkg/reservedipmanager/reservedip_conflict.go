@@ -0,0 +1,189 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package reservedipmanager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// allocatedReservedIP is an IP address reserved by a SpiderReservedIP but
+// still allocated to a Pod.
+type allocatedReservedIP struct {
+	IP        string
+	Pool      string
+	Namespace string
+	Pod       string
+}
+
+func (a allocatedReservedIP) String() string {
+	return fmt.Sprintf("%s (IPPool %s, Pod %s/%s)", a.IP, a.Pool, a.Namespace, a.Pod)
+}
+
+// ReservedIPDeniedError tells that an IP address of an IPPool is denied to
+// the Pod since it's reserved by a SpiderReservedIP.
+type ReservedIPDeniedError struct {
+	IP          string
+	Pool        string
+	ReservedIP  string
+	Description string
+	Err         error
+}
+
+// Message explains which SpiderReservedIP denies the IP address, such as in
+// the events of the Pod.
+func (e *ReservedIPDeniedError) Message() string {
+	// The SpiderReservedIP may be gone before it's found.
+	if e.ReservedIP == "" {
+		return fmt.Sprintf("IP address %s of IPPool %s is reserved by SpiderReservedIP", e.IP, e.Pool)
+	}
+
+	msg := fmt.Sprintf("IP address %s of IPPool %s is reserved by SpiderReservedIP %s", e.IP, e.Pool, e.ReservedIP)
+	if e.Description != "" {
+		msg += fmt.Sprintf(" (%s)", e.Description)
+	}
+
+	return msg
+}
+
+func (e *ReservedIPDeniedError) Error() string {
+	return fmt.Sprintf("%v, %s", e.Err, e.Message())
+}
+
+func (e *ReservedIPDeniedError) Unwrap() error {
+	return e.Err
+}
+
+// ShouldVacateAllocatedIPs reports whether the allocations of the IP
+// addresses reserved by the SpiderReservedIP are released rather than reused
+// when their Pods restart.
+func ShouldVacateAllocatedIPs(rIP *spiderpoolv1.SpiderReservedIP) bool {
+	return rIP.Spec.VacateAllocatedIPs != nil && *rIP.Spec.VacateAllocatedIPs
+}
+
+// matchReservedIPPools reports whether the IP addresses of the IPPool are
+// reserved by the SpiderReservedIP.
+func matchReservedIPPools(rIP *spiderpoolv1.SpiderReservedIP, pool *spiderpoolv1.SpiderIPPool) (bool, error) {
+	if rIP.Spec.PoolSelector == nil {
+		return true, nil
+	}
+	if pool == nil {
+		return false, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(rIP.Spec.PoolSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid pool selector of SpiderReservedIP %s: %w", rIP.Name, err)
+	}
+
+	return selector.Matches(labels.Set(pool.Labels)), nil
+}
+
+// findAllocatedReservedIPs returns which of the IP addresses reserved by the
+// SpiderReservedIP are allocated from the IPPools in its scope, sorted by the
+// IP addresses.
+func findAllocatedReservedIPs(rIP *spiderpoolv1.SpiderReservedIP, ips []net.IP, pools []spiderpoolv1.SpiderIPPool) ([]allocatedReservedIP, error) {
+	var allocated []allocatedReservedIP
+	for i := range pools {
+		pool := &pools[i]
+		if pool.DeletionTimestamp != nil || len(pool.Status.AllocatedIPs) == 0 ||
+			pool.Spec.IPVersion == nil || *pool.Spec.IPVersion != *rIP.Spec.IPVersion {
+			continue
+		}
+
+		ok, err := matchReservedIPPools(rIP, pool)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		for _, ip := range ips {
+			if allocation, ok := pool.Status.AllocatedIPs[ip.String()]; ok {
+				allocated = append(allocated, allocatedReservedIP{
+					IP:        ip.String(),
+					Pool:      pool.Name,
+					Namespace: allocation.Namespace,
+					Pod:       allocation.Pod,
+				})
+			}
+		}
+	}
+
+	sort.Slice(allocated, func(i, j int) bool {
+		if allocated[i].IP != allocated[j].IP {
+			return allocated[i].IP < allocated[j].IP
+		}
+		return allocated[i].Pool < allocated[j].Pool
+	})
+
+	return allocated, nil
+}
+
+// genReservedIPConflictingCondition generates the condition "Conflicting" of
+// the SpiderReservedIP, which is true if any of its IP addresses is still
+// allocated.
+func genReservedIPConflictingCondition(rIP *spiderpoolv1.SpiderReservedIP, allocated []allocatedReservedIP) metav1.Condition {
+	if len(allocated) == 0 {
+		return metav1.Condition{
+			Type:               constant.ReservedIPConditionConflicting,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: rIP.Generation,
+			Reason:             constant.ReservedIPReasonNoAllocatedIPs,
+			Message:            "none of the reserved IP addresses is allocated",
+		}
+	}
+
+	items := make([]string, 0, len(allocated))
+	for _, a := range allocated {
+		items = append(items, a.String())
+	}
+	message := fmt.Sprintf("reserved IP addresses %s are allocated", strings.Join(items, ", "))
+	if ShouldVacateAllocatedIPs(rIP) {
+		message += ", they are vacated once their Pods restart"
+	}
+
+	return metav1.Condition{
+		Type:               constant.ReservedIPConditionConflicting,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: rIP.Generation,
+		Reason:             constant.ReservedIPReasonAllocatedIPs,
+		Message:            message,
+	}
+}
+
+// FindVacatingReservedIP returns the SpiderReservedIP which reserves the IP
+// address from the IPPool and vacates its allocation, or nil if not found.
+func (rm *reservedIPManager) FindVacatingReservedIP(ctx context.Context, version types.IPVersion, ip string, pool *spiderpoolv1.SpiderIPPool) (*spiderpoolv1.SpiderReservedIP, error) {
+	return rm.findReservedIP(ctx, version, ip, func(rIP *spiderpoolv1.SpiderReservedIP) (bool, error) {
+		if !ShouldVacateAllocatedIPs(rIP) {
+			return false, nil
+		}
+
+		return matchReservedIPPools(rIP, pool)
+	})
+}
+
+// AssembleVacatingReservedIPs assembles the IP addresses reserved from the
+// IPPool whose allocations are vacated when their Pods restart.
+func (rm *reservedIPManager) AssembleVacatingReservedIPs(ctx context.Context, version types.IPVersion, pool *spiderpoolv1.SpiderIPPool) ([]net.IP, error) {
+	return rm.assembleReservedIPs(ctx, version, func(rIP *spiderpoolv1.SpiderReservedIP) (bool, error) {
+		if !ShouldVacateAllocatedIPs(rIP) {
+			return false, nil
+		}
+
+		return matchReservedIPPools(rIP, pool)
+	})
+}
@@ -0,0 +1,74 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package reservedipmanager
+
+import (
+	"context"
+	"time"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+// GetReservedIPExpiration returns when the reservation of the
+// SpiderReservedIP expires, which is the earlier one of 'spec.expireAt' and
+// 'spec.ttl' since its creation, or nil if it never expires.
+func GetReservedIPExpiration(rIP *spiderpoolv1.SpiderReservedIP) *time.Time {
+	var expiration *time.Time
+	if rIP.Spec.ExpireAt != nil {
+		t := rIP.Spec.ExpireAt.Time
+		expiration = &t
+	}
+
+	if rIP.Spec.TTL != nil {
+		t := rIP.CreationTimestamp.Add(rIP.Spec.TTL.Duration)
+		if expiration == nil || t.Before(*expiration) {
+			expiration = &t
+		}
+	}
+
+	return expiration
+}
+
+// IsReservedIPExpired reports whether the reservation of the
+// SpiderReservedIP has expired by the time.
+func IsReservedIPExpired(rIP *spiderpoolv1.SpiderReservedIP, now time.Time) bool {
+	expiration := GetReservedIPExpiration(rIP)
+	return expiration != nil && !now.Before(*expiration)
+}
+
+// DeleteExpiredReservedIPs deletes the SpiderReservedIPs whose reservations
+// have expired, and returns the number of the deleted ones. Their IP
+// addresses are returned to the IPPools once the deletion is observed.
+func (rm *reservedIPManager) DeleteExpiredReservedIPs(ctx context.Context) (int, error) {
+	logger := logutils.FromContext(ctx)
+
+	rIPList, err := rm.ListReservedIPs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var deleted int
+	var errs []error
+	for i := range rIPList.Items {
+		rIP := &rIPList.Items[i]
+		if rIP.DeletionTimestamp != nil || !IsReservedIPExpired(rIP, now) {
+			continue
+		}
+
+		if err := rm.client.Delete(ctx, rIP); client.IgnoreNotFound(err) != nil {
+			logger.Sugar().Warnf("Failed to delete expired SpiderReservedIP %s: %v", rIP.Name, err)
+			errs = append(errs, err)
+			continue
+		}
+		logger.Sugar().Infof("Delete SpiderReservedIP %s expired at %s", rIP.Name, GetReservedIPExpiration(rIP).Format(time.RFC3339))
+		deleted++
+	}
+
+	return deleted, utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,286 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package reservedipmanager
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+const (
+	// DefaultReservedIPImportSource is the source of the imported
+	// SpiderReservedIPs if it's not specified.
+	DefaultReservedIPImportSource = "import"
+
+	// MaxReservedIPImportRecords is the max number of the records imported
+	// at a time, the larger lists are imported in batches.
+	MaxReservedIPImportRecords = 500
+)
+
+// ReservedIPRecord is a reserved IP address maintained outside the cluster,
+// such as in a CMDB. Each record is imported as one SpiderReservedIP.
+type ReservedIPRecord struct {
+	// Name is the name of the SpiderReservedIP, it's generated from the
+	// source and the IP address if empty.
+	Name        string `json:"name,omitempty"`
+	IP          string `json:"ip"`
+	Description string `json:"description,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+}
+
+// ReservedIPImportFailure is a record which failed to import.
+type ReservedIPImportFailure struct {
+	Name  string `json:"name"`
+	IP    string `json:"ip"`
+	Error string `json:"error"`
+}
+
+// ReservedIPImportResult counts the SpiderReservedIPs changed by an import.
+type ReservedIPImportResult struct {
+	Created   int                       `json:"created"`
+	Updated   int                       `json:"updated"`
+	Unchanged int                       `json:"unchanged"`
+	Failures  []ReservedIPImportFailure `json:"failures,omitempty"`
+}
+
+// ParseReservedIPRecordsCSV parses the records from CSV with a header row.
+// The columns 'ip', 'name', 'description' and 'owner' are recognized in any
+// order and case, only 'ip' is required, and the others are ignored.
+func ParseReservedIPRecordsCSV(r io.Reader) ([]ReservedIPRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w, missing the header row", constant.ErrWrongInput)
+		}
+		return nil, fmt.Errorf("%w, invalid CSV: %v", constant.ErrWrongInput, err)
+	}
+
+	columns := map[string]int{}
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := columns["ip"]; !ok {
+		return nil, fmt.Errorf("%w, missing the column 'ip' in the header row", constant.ErrWrongInput)
+	}
+
+	field := func(row []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	var records []ReservedIPRecord
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w, invalid CSV: %v", constant.ErrWrongInput, err)
+		}
+
+		record := ReservedIPRecord{
+			Name:        field(row, "name"),
+			IP:          field(row, "ip"),
+			Description: field(row, "description"),
+			Owner:       field(row, "owner"),
+		}
+		if record.IP == "" {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("%w, empty 'ip' at line %d", constant.ErrWrongInput, line)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// ParseReservedIPRecordsJSON parses the records from a JSON array.
+func ParseReservedIPRecordsJSON(r io.Reader) ([]ReservedIPRecord, error) {
+	var records []ReservedIPRecord
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("%w, invalid JSON: %v", constant.ErrWrongInput, err)
+	}
+
+	for i, record := range records {
+		if record.IP == "" {
+			return nil, fmt.Errorf("%w, empty 'ip' of record %d", constant.ErrWrongInput, i)
+		}
+	}
+
+	return records, nil
+}
+
+// GenReservedIPRecordName generates the name of the SpiderReservedIP
+// imported from the source for the IP address or IP range.
+func GenReservedIPRecordName(source, ip string) string {
+	return strings.ToLower(source + "-" + strings.ReplaceAll(ip, ":", "-"))
+}
+
+// ImportReservedIPs creates or updates one SpiderReservedIP for each of the
+// records, labeled with the source. The SpiderReservedIPs already up to date
+// are skipped, so importing the same records again changes nothing. The
+// records which fail to import, such as the ones conflicting with the
+// SpiderReservedIPs created otherwise, are reported rather than aborting the
+// import.
+func (rm *reservedIPManager) ImportReservedIPs(ctx context.Context, source string, records []ReservedIPRecord) (*ReservedIPImportResult, error) {
+	logger := logutils.FromContext(ctx)
+
+	if source == "" {
+		source = DefaultReservedIPImportSource
+	}
+	if errs := validation.IsValidLabelValue(source); len(errs) != 0 {
+		return nil, fmt.Errorf("%w, invalid source '%s': %s", constant.ErrWrongInput, source, strings.Join(errs, "; "))
+	}
+	if len(records) > MaxReservedIPImportRecords {
+		return nil, fmt.Errorf("%w, %d records exceed the limit %d of one import", constant.ErrWrongInput, len(records), MaxReservedIPImportRecords)
+	}
+
+	result := &ReservedIPImportResult{}
+	names := make(map[string]struct{}, len(records))
+	for _, record := range records {
+		name := record.Name
+		if name == "" {
+			name = GenReservedIPRecordName(source, record.IP)
+		}
+
+		fail := func(err error) {
+			logger.Sugar().Warnf("Failed to import reserved IP address %s as SpiderReservedIP %s: %v", record.IP, name, err)
+			result.Failures = append(result.Failures, ReservedIPImportFailure{
+				Name:  name,
+				IP:    record.IP,
+				Error: err.Error(),
+			})
+		}
+
+		if _, ok := names[name]; ok {
+			fail(fmt.Errorf("%w, duplicate SpiderReservedIP %s", constant.ErrWrongInput, name))
+			continue
+		}
+		names[name] = struct{}{}
+
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+			fail(fmt.Errorf("%w, invalid name: %s", constant.ErrWrongInput, strings.Join(errs, "; ")))
+			continue
+		}
+
+		created, changed, err := rm.importReservedIPRecord(ctx, source, name, record)
+		if err != nil {
+			fail(err)
+			continue
+		}
+
+		switch {
+		case created:
+			result.Created++
+		case changed:
+			result.Updated++
+		default:
+			result.Unchanged++
+		}
+	}
+
+	return result, nil
+}
+
+// importReservedIPRecord creates or updates the SpiderReservedIP of the
+// record, and reports whether it's created or changed.
+func (rm *reservedIPManager) importReservedIPRecord(ctx context.Context, source, name string, record ReservedIPRecord) (bool, bool, error) {
+	var version types.IPVersion
+	switch {
+	case spiderpoolip.IsIPv4IPRange(record.IP):
+		version = constant.IPv4
+	case spiderpoolip.IsIPv6IPRange(record.IP):
+		version = constant.IPv6
+	default:
+		return false, false, fmt.Errorf("%w, invalid IP address or IP range '%s'", constant.ErrWrongInput, record.IP)
+	}
+
+	// Merged the same way as the mutating webhook does, so that the
+	// SpiderReservedIP is found up to date next time.
+	ips, err := spiderpoolip.MergeIPRanges(version, []string{record.IP})
+	if err != nil {
+		return false, false, fmt.Errorf("%w, invalid IP range '%s': %v", constant.ErrWrongInput, record.IP, err)
+	}
+
+	rIP, err := rm.GetReservedIPByName(ctx, name)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, false, err
+		}
+
+		rIP = &spiderpoolv1.SpiderReservedIP{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{constant.LabelReservedIPImportSource: source},
+			},
+			Spec: spiderpoolv1.ReservedIPSpec{
+				IPVersion: &version,
+				IPs:       ips,
+			},
+		}
+		setReservedIPRecordAnnotations(rIP, record)
+		if err := rm.client.Create(ctx, rIP); err != nil {
+			return false, false, err
+		}
+
+		return true, true, nil
+	}
+
+	if rIP.Labels[constant.LabelReservedIPImportSource] != source {
+		return false, false, fmt.Errorf("%w, SpiderReservedIP %s is not imported from source '%s'", constant.ErrWrongInput, name, source)
+	}
+
+	rIPCopy := rIP.DeepCopy()
+	rIPCopy.Spec.IPVersion = &version
+	rIPCopy.Spec.IPs = ips
+	setReservedIPRecordAnnotations(rIPCopy, record)
+	if reflect.DeepEqual(rIP.Spec, rIPCopy.Spec) && reflect.DeepEqual(rIP.Annotations, rIPCopy.Annotations) {
+		return false, false, nil
+	}
+
+	if err := rm.client.Update(ctx, rIPCopy); err != nil {
+		return false, false, err
+	}
+
+	return false, true, nil
+}
+
+func setReservedIPRecordAnnotations(rIP *spiderpoolv1.SpiderReservedIP, record ReservedIPRecord) {
+	set := func(key, value string) {
+		if value == "" {
+			delete(rIP.Annotations, key)
+			return
+		}
+		if rIP.Annotations == nil {
+			rIP.Annotations = map[string]string{}
+		}
+		rIP.Annotations[key] = value
+	}
+
+	set(constant.AnnoReservedIPDescription, record.Description)
+	set(constant.AnnoReservedIPOwner, record.Owner)
+}
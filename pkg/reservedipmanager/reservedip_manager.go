@@ -8,7 +8,11 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	apitypes "k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -22,6 +26,19 @@ type ReservedIPManager interface {
 	GetReservedIPByName(ctx context.Context, rIPName string) (*spiderpoolv1.SpiderReservedIP, error)
 	ListReservedIPs(ctx context.Context, opts ...client.ListOption) (*spiderpoolv1.SpiderReservedIPList, error)
 	AssembleReservedIPs(ctx context.Context, version types.IPVersion) ([]net.IP, error)
+	AssembleScopedReservedIPs(ctx context.Context, version types.IPVersion, pool *spiderpoolv1.SpiderIPPool, namespace string) ([]net.IP, error)
+	ListPoolReservedIPs(ctx context.Context, version types.IPVersion, pool *spiderpoolv1.SpiderIPPool) ([]spiderpoolv1.SpiderReservedIP, error)
+	DeleteExpiredReservedIPs(ctx context.Context) (int, error)
+	ListClaimedIPs(ctx context.Context, version types.IPVersion, pool *spiderpoolv1.SpiderIPPool, pod *corev1.Pod) ([]ClaimedIP, error)
+	BindClaimedIP(ctx context.Context, claimed ClaimedIP, pod *corev1.Pod) error
+	ConsumeClaimedIP(ctx context.Context, claimed ClaimedIP) error
+	ConsumeBoundClaims(ctx context.Context) (int, error)
+	ReserveInUseIPs(ctx context.Context, pool *spiderpoolv1.SpiderIPPool) (int, error)
+	SyncReservedIPStatus(ctx context.Context) (int, error)
+	AssembleVacatingReservedIPs(ctx context.Context, version types.IPVersion, pool *spiderpoolv1.SpiderIPPool) ([]net.IP, error)
+	FindVacatingReservedIP(ctx context.Context, version types.IPVersion, ip string, pool *spiderpoolv1.SpiderIPPool) (*spiderpoolv1.SpiderReservedIP, error)
+	ImportReservedIPs(ctx context.Context, source string, records []ReservedIPRecord) (*ReservedIPImportResult, error)
+	QuarantineIP(ctx context.Context, ip, tenant, reason string, duration time.Duration) (bool, error)
 }
 
 type reservedIPManager struct {
@@ -56,7 +73,51 @@ func (rm *reservedIPManager) ListReservedIPs(ctx context.Context, opts ...client
 	return &rIPList, nil
 }
 
+// AssembleReservedIPs assembles the IP addresses reserved by all
+// SpiderReservedIPs, regardless of their scopes.
 func (rm *reservedIPManager) AssembleReservedIPs(ctx context.Context, version types.IPVersion) ([]net.IP, error) {
+	return rm.assembleReservedIPs(ctx, version, func(*spiderpoolv1.SpiderReservedIP) (bool, error) {
+		return true, nil
+	})
+}
+
+// AssembleScopedReservedIPs assembles the IP addresses reserved from the
+// IPPool for the Pods in the namespace. The SpiderReservedIPs with namespace
+// selector are skipped if the namespace is empty, which means the IP
+// addresses are not allocated for any specific Pod.
+func (rm *reservedIPManager) AssembleScopedReservedIPs(ctx context.Context, version types.IPVersion, pool *spiderpoolv1.SpiderIPPool, namespace string) ([]net.IP, error) {
+	var nsLabels labels.Set
+	return rm.assembleReservedIPs(ctx, version, func(rIP *spiderpoolv1.SpiderReservedIP) (bool, error) {
+		if ok, err := matchReservedIPPools(rIP, pool); err != nil || !ok {
+			return false, err
+		}
+
+		if rIP.Spec.NamespaceSelector != nil {
+			if namespace == "" {
+				return false, nil
+			}
+			// The namespace is got lazily, only once.
+			if nsLabels == nil {
+				var ns corev1.Namespace
+				if err := rm.client.Get(ctx, apitypes.NamespacedName{Name: namespace}, &ns); err != nil {
+					return false, err
+				}
+				nsLabels = labels.Set(ns.Labels)
+				if nsLabels == nil {
+					nsLabels = labels.Set{}
+				}
+			}
+		}
+
+		return MatchReservedIPNamespace(rIP, nsLabels)
+	})
+}
+
+// ListPoolReservedIPs returns the SpiderReservedIPs reserving IP addresses
+// from the IPPool, which are not being deleted. Their expiration and
+// namespace selectors are left to the callers, see IsReservedIPExpired and
+// MatchReservedIPNamespace.
+func (rm *reservedIPManager) ListPoolReservedIPs(ctx context.Context, version types.IPVersion, pool *spiderpoolv1.SpiderIPPool) ([]spiderpoolv1.SpiderReservedIP, error) {
 	if err := spiderpoolip.IsIPVersion(version); err != nil {
 		return nil, err
 	}
@@ -66,17 +127,132 @@ func (rm *reservedIPManager) AssembleReservedIPs(ctx context.Context, version ty
 		return nil, err
 	}
 
-	var ranges []string
-	for _, r := range rIPList.Items {
-		if r.DeletionTimestamp == nil {
-			ranges = append(ranges, r.Spec.IPs...)
+	var rIPs []spiderpoolv1.SpiderReservedIP
+	for i := range rIPList.Items {
+		r := &rIPList.Items[i]
+		if r.DeletionTimestamp != nil {
+			continue
 		}
+		ok, err := matchReservedIPPools(r, pool)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			rIPs = append(rIPs, *r)
+		}
+	}
+
+	return rIPs, nil
+}
+
+// MatchReservedIPNamespace reports whether the SpiderReservedIP reserves
+// IP addresses for the Pods in the namespace with the labels. The ones with
+// namespace selector never match a nil label set, which stands for the IP
+// addresses not allocated for any specific Pod.
+func MatchReservedIPNamespace(rIP *spiderpoolv1.SpiderReservedIP, nsLabels labels.Set) (bool, error) {
+	if rIP.Spec.NamespaceSelector == nil {
+		return true, nil
+	}
+	if nsLabels == nil {
+		return false, nil
 	}
 
-	ips, err := spiderpoolip.ParseIPRanges(version, ranges)
+	selector, err := metav1.LabelSelectorAsSelector(rIP.Spec.NamespaceSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid namespace selector of SpiderReservedIP %s: %w", rIP.Name, err)
+	}
+
+	return selector.Matches(nsLabels), nil
+}
+
+func (rm *reservedIPManager) assembleReservedIPs(ctx context.Context, version types.IPVersion, inScope func(*spiderpoolv1.SpiderReservedIP) (bool, error)) ([]net.IP, error) {
+	if err := spiderpoolip.IsIPVersion(version); err != nil {
+		return nil, err
+	}
+
+	rIPList, err := rm.ListReservedIPs(ctx, client.MatchingFields{"spec.ipVersion": strconv.FormatInt(version, 10)})
 	if err != nil {
 		return nil, err
 	}
 
+	// The expired SpiderReservedIPs are skipped before they're deleted.
+	now := time.Now()
+	var ips []net.IP
+	for i := range rIPList.Items {
+		r := &rIPList.Items[i]
+		if r.DeletionTimestamp != nil || IsReservedIPExpired(r, now) {
+			continue
+		}
+		ok, err := inScope(r)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		reserved, err := GetReservedIPs(version, r)
+		if err != nil {
+			return nil, err
+		}
+		ips = append(ips, reserved...)
+	}
+
 	return ips, nil
 }
+
+// findReservedIP returns the first SpiderReservedIP in scope which reserves
+// the IP address, or nil if not found.
+func (rm *reservedIPManager) findReservedIP(ctx context.Context, version types.IPVersion, ip string, inScope func(*spiderpoolv1.SpiderReservedIP) (bool, error)) (*spiderpoolv1.SpiderReservedIP, error) {
+	target := net.ParseIP(ip)
+	if target == nil {
+		return nil, fmt.Errorf("%w, invalid IP address '%s'", constant.ErrWrongInput, ip)
+	}
+
+	rIPList, err := rm.ListReservedIPs(ctx, client.MatchingFields{"spec.ipVersion": strconv.FormatInt(version, 10)})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range rIPList.Items {
+		r := &rIPList.Items[i]
+		if r.DeletionTimestamp != nil || IsReservedIPExpired(r, now) {
+			continue
+		}
+		ok, err := inScope(r)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		ips, err := GetReservedIPs(version, r)
+		if err != nil {
+			return nil, err
+		}
+		for _, reserved := range ips {
+			if reserved.Equal(target) {
+				return r, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// GetReservedIPs returns the IP addresses reserved by the SpiderReservedIP,
+// which are 'spec.ips' except 'spec.excludeIPs', sorted.
+func GetReservedIPs(version types.IPVersion, rIP *spiderpoolv1.SpiderReservedIP) ([]net.IP, error) {
+	ips, err := spiderpoolip.ParseIPRanges(version, rIP.Spec.IPs)
+	if err != nil {
+		return nil, err
+	}
+	excludeIPs, err := spiderpoolip.ParseIPRanges(version, rIP.Spec.ExcludeIPs)
+	if err != nil {
+		return nil, err
+	}
+
+	return spiderpoolip.IPsDiffSet(ips, excludeIPs, true), nil
+}
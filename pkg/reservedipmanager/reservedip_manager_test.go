@@ -7,13 +7,18 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/agiledragon/gomonkey/v2"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -227,6 +232,26 @@ var _ = Describe("ReservedIPManager", Label("reservedip_manager_test"), func() {
 				))
 			})
 
+			It("does not assemble the IP addresses carved out by 'spec.excludeIPs'", func() {
+				rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+				rIPT.Spec.IPs = []string{"10.6.0.0/28"}
+				rIPT.Spec.ExcludeIPs = []string{"10.6.0.2-10.6.0.14"}
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+
+				ips, err := rIPManager.AssembleReservedIPs(ctx, constant.IPv4)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ips).To(Equal(
+					[]net.IP{
+						net.ParseIP("10.6.0.0"),
+						net.ParseIP("10.6.0.1"),
+						net.ParseIP("10.6.0.15"),
+					},
+				))
+			})
+
 			It("exists invalid ReservedIPs in the cluster", func() {
 				rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
 				rIPT.Spec.IPs = append(rIPT.Spec.IPs, constant.InvalidIPRange)
@@ -240,5 +265,721 @@ var _ = Describe("ReservedIPManager", Label("reservedip_manager_test"), func() {
 				Expect(ips).To(BeEmpty())
 			})
 		})
+
+		Describe("AssembleScopedReservedIPs", func() {
+			var pool *spiderpoolv1.SpiderIPPool
+			var ns *corev1.Namespace
+
+			BeforeEach(func() {
+				rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+				rIPT.Spec.IPs = []string{"172.18.40.10"}
+
+				pool = &spiderpoolv1.SpiderIPPool{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "pool",
+						Labels: map[string]string{"tenant": "a"},
+					},
+				}
+				ns = &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   fmt.Sprintf("ns-%v", count),
+						Labels: map[string]string{"tenant": "a"},
+					},
+				}
+			})
+
+			AfterEach(func() {
+				ctx := context.TODO()
+				err := fakeClient.Delete(ctx, ns)
+				Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+			})
+
+			It("assembles the ReservedIPs without scope", func() {
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+
+				ips, err := rIPManager.AssembleScopedReservedIPs(ctx, constant.IPv4, pool, "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ips).To(Equal([]net.IP{net.IPv4(172, 18, 40, 10)}))
+			})
+
+			It("skips the ReservedIPs not selecting the IPPool", func() {
+				rIPT.Spec.PoolSelector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{"tenant": "b"},
+				}
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+
+				ips, err := rIPManager.AssembleScopedReservedIPs(ctx, constant.IPv4, pool, ns.Name)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ips).To(BeEmpty())
+
+				ips, err = rIPManager.AssembleReservedIPs(ctx, constant.IPv4)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ips).To(HaveLen(1))
+			})
+
+			It("skips the ReservedIPs with namespace selector if the namespace is unknown", func() {
+				rIPT.Spec.NamespaceSelector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{"tenant": "a"},
+				}
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+
+				ips, err := rIPManager.AssembleScopedReservedIPs(ctx, constant.IPv4, pool, "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ips).To(BeEmpty())
+			})
+
+			It("failed to get the namespace", func() {
+				rIPT.Spec.NamespaceSelector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{"tenant": "a"},
+				}
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+
+				ips, err := rIPManager.AssembleScopedReservedIPs(ctx, constant.IPv4, pool, ns.Name)
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+				Expect(ips).To(BeEmpty())
+			})
+
+			It("assembles the ReservedIPs selecting both the IPPool and the namespace", func() {
+				rIPT.Spec.PoolSelector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{"tenant": "a"},
+				}
+				rIPT.Spec.NamespaceSelector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{"tenant": "a"},
+				}
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+				err = fakeClient.Create(ctx, ns)
+				Expect(err).NotTo(HaveOccurred())
+
+				ips, err := rIPManager.AssembleScopedReservedIPs(ctx, constant.IPv4, pool, ns.Name)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ips).To(Equal([]net.IP{net.IPv4(172, 18, 40, 10)}))
+
+				ns.Labels["tenant"] = "b"
+				err = fakeClient.Update(ctx, ns)
+				Expect(err).NotTo(HaveOccurred())
+
+				ips, err = rIPManager.AssembleScopedReservedIPs(ctx, constant.IPv4, pool, ns.Name)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ips).To(BeEmpty())
+			})
+		})
+
+		Describe("DeleteExpiredReservedIPs", func() {
+			BeforeEach(func() {
+				rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+				rIPT.Spec.IPs = []string{"172.18.40.10"}
+			})
+
+			It("computes the expiration of the ReservedIP", func() {
+				Expect(reservedipmanager.GetReservedIPExpiration(rIPT)).To(BeNil())
+
+				now := time.Now()
+				rIPT.CreationTimestamp = metav1.NewTime(now)
+				rIPT.Spec.TTL = &metav1.Duration{Duration: time.Hour}
+				Expect(*reservedipmanager.GetReservedIPExpiration(rIPT)).To(Equal(now.Add(time.Hour)))
+
+				rIPT.Spec.ExpireAt = &metav1.Time{Time: now.Add(time.Minute)}
+				Expect(*reservedipmanager.GetReservedIPExpiration(rIPT)).To(Equal(now.Add(time.Minute)))
+				Expect(reservedipmanager.IsReservedIPExpired(rIPT, now)).To(BeFalse())
+				Expect(reservedipmanager.IsReservedIPExpired(rIPT, now.Add(time.Minute))).To(BeTrue())
+			})
+
+			It("failed to list ReservedIPs due to some unknown errors", func() {
+				patches := gomonkey.ApplyMethodReturn(fakeClient, "List", constant.ErrUnknown)
+				defer patches.Reset()
+
+				ctx := context.TODO()
+				deleted, err := rIPManager.DeleteExpiredReservedIPs(ctx)
+				Expect(err).To(MatchError(constant.ErrUnknown))
+				Expect(deleted).To(BeZero())
+			})
+
+			It("deletes the expired ReservedIPs only", func() {
+				rIPT.Spec.ExpireAt = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+				unexpired := rIPT.DeepCopy()
+				unexpired.Name = rIPName + "-unexpired"
+				unexpired.Spec.ExpireAt = &metav1.Time{Time: time.Now().Add(time.Hour)}
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+				err = fakeClient.Create(ctx, unexpired)
+				Expect(err).NotTo(HaveOccurred())
+				defer func() {
+					err := fakeClient.Delete(ctx, unexpired)
+					Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+				}()
+
+				ips, err := rIPManager.AssembleReservedIPs(ctx, constant.IPv4)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ips).To(HaveLen(1))
+
+				deleted, err := rIPManager.DeleteExpiredReservedIPs(ctx)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deleted).To(Equal(1))
+
+				_, err = rIPManager.GetReservedIPByName(ctx, rIPName)
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+				_, err = rIPManager.GetReservedIPByName(ctx, unexpired.Name)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Describe("Claims", func() {
+			var pool *spiderpoolv1.SpiderIPPool
+			var pod *corev1.Pod
+
+			BeforeEach(func() {
+				rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+				rIPT.Spec.IPs = []string{"172.18.40.10-172.18.40.11"}
+				rIPT.Spec.Claim = &spiderpoolv1.ReservedIPClaim{
+					Namespace: "default",
+					PodName:   "pod",
+				}
+
+				pool = &spiderpoolv1.SpiderIPPool{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "pool",
+						Labels: map[string]string{"tenant": "a"},
+					},
+				}
+				pod = &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "default",
+						Name:      "pod",
+						Labels:    map[string]string{"app": "db"},
+					},
+				}
+			})
+
+			It("matches the claimant Pod by name or by label selector", func() {
+				ok, err := reservedipmanager.IsReservedIPClaimedBy(rIPT, pod)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeTrue())
+
+				other := pod.DeepCopy()
+				other.Namespace = "other"
+				ok, err = reservedipmanager.IsReservedIPClaimedBy(rIPT, other)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeFalse())
+
+				rIPT.Spec.Claim.PodName = ""
+				rIPT.Spec.Claim.PodSelector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{"app": "db"},
+				}
+				other = pod.DeepCopy()
+				other.Name = "pod-1"
+				ok, err = reservedipmanager.IsReservedIPClaimedBy(rIPT, other)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeTrue())
+
+				other.Labels = nil
+				ok, err = reservedipmanager.IsReservedIPClaimedBy(rIPT, other)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeFalse())
+
+				rIPT.Spec.Claim = nil
+				ok, err = reservedipmanager.IsReservedIPClaimedBy(rIPT, pod)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeFalse())
+			})
+
+			It("lists the IP addresses claimed for the Pod from the IPPool", func() {
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+
+				claimed, err := rIPManager.ListClaimedIPs(ctx, constant.IPv4, pool, pod)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(claimed).To(HaveLen(2))
+				Expect(claimed[0].ReservedIP).To(Equal(rIPName))
+				Expect(claimed[0].IP.String()).To(Equal("172.18.40.10"))
+
+				pool.Labels["tenant"] = "b"
+				rIPT.Spec.PoolSelector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{"tenant": "a"},
+				}
+				err = fakeClient.Update(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+
+				claimed, err = rIPManager.ListClaimedIPs(ctx, constant.IPv4, pool, pod)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(claimed).To(BeEmpty())
+			})
+
+			It("consumes the claimed IP addresses", func() {
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+
+				claimed, err := rIPManager.ListClaimedIPs(ctx, constant.IPv4, pool, pod)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(claimed).To(HaveLen(2))
+
+				err = rIPManager.ConsumeClaimedIP(ctx, claimed[0])
+				Expect(err).NotTo(HaveOccurred())
+
+				rIP, err := rIPManager.GetReservedIPByName(ctx, rIPName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rIP.Spec.IPs).To(Equal([]string{"172.18.40.11"}))
+
+				err = rIPManager.ConsumeClaimedIP(ctx, claimed[1])
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = rIPManager.GetReservedIPByName(ctx, rIPName)
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+				err = rIPManager.ConsumeClaimedIP(ctx, claimed[1])
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("binds the claimed IP address to one Pod and unbinds it once the Pod is gone", func() {
+				ctx := context.TODO()
+				pod.UID = "pod-uid"
+				err := fakeClient.Create(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+
+				claimed, err := rIPManager.ListClaimedIPs(ctx, constant.IPv4, pool, pod)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(claimed).To(HaveLen(2))
+				Expect(claimed[0].BoundPodUID).To(BeEmpty())
+
+				err = rIPManager.BindClaimedIP(ctx, claimed[0], pod)
+				Expect(err).NotTo(HaveOccurred())
+				err = rIPManager.BindClaimedIP(ctx, claimed[0], pod)
+				Expect(err).NotTo(HaveOccurred())
+
+				claimed, err = rIPManager.ListClaimedIPs(ctx, constant.IPv4, pool, pod)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(claimed[0].BoundPodUID).To(Equal("pod-uid"))
+
+				recreated := pod.DeepCopy()
+				recreated.UID = "recreated-pod-uid"
+				err = rIPManager.BindClaimedIP(ctx, claimed[0], recreated)
+				Expect(err).To(MatchError(constant.ErrClaimedIPTaken))
+
+				consumed, err := rIPManager.ConsumeBoundClaims(ctx)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(consumed).To(BeZero())
+
+				rIP, err := rIPManager.GetReservedIPByName(ctx, rIPName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rIP.Spec.IPs).To(Equal([]string{"172.18.40.10-172.18.40.11"}))
+				Expect(rIP.Status.BoundClaims).To(BeEmpty())
+			})
+		})
+
+		Describe("ReserveInUseIPs", func() {
+			var pool *spiderpoolv1.SpiderIPPool
+
+			BeforeEach(func() {
+				pool = &spiderpoolv1.SpiderIPPool{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: rIPName,
+						UID:  "pool-uid",
+					},
+					Spec: spiderpoolv1.IPPoolSpec{
+						IPVersion: pointer.Int64(constant.IPv4),
+					},
+					Status: spiderpoolv1.IPPoolStatus{
+						AllocatedIPs: spiderpoolv1.PoolIPAllocations{
+							"172.18.40.12": {ContainerID: "container", NIC: "eth0"},
+						},
+						NetworkScan: &spiderpoolv1.IPPoolNetworkScan{
+							Node:     "node",
+							InUseIPs: []string{"172.18.40.10-172.18.40.12"},
+						},
+					},
+				}
+			})
+
+			AfterEach(func() {
+				ctx := context.TODO()
+				err := fakeClient.Delete(ctx, &spiderpoolv1.SpiderReservedIP{
+					ObjectMeta: metav1.ObjectMeta{Name: reservedipmanager.InUseReservedIPName(pool.Name)},
+				})
+				Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+			})
+
+			It("does nothing without the IP addresses in use", func() {
+				pool.Status.NetworkScan = nil
+
+				reserved, err := rIPManager.ReserveInUseIPs(context.TODO(), pool)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(reserved).To(BeZero())
+			})
+
+			It("accumulates the IP addresses in use except the allocated ones", func() {
+				ctx := context.TODO()
+				reserved, err := rIPManager.ReserveInUseIPs(ctx, pool)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(reserved).To(Equal(2))
+
+				rIP, err := rIPManager.GetReservedIPByName(ctx, reservedipmanager.InUseReservedIPName(pool.Name))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rIP.Spec.IPs).To(Equal([]string{"172.18.40.10-172.18.40.11"}))
+				Expect(rIP.Labels).To(HaveKeyWithValue(constant.LabelReservedIPInUseIPPool, pool.Name))
+				Expect(rIP.OwnerReferences).To(HaveLen(1))
+
+				reserved, err = rIPManager.ReserveInUseIPs(ctx, pool)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(reserved).To(BeZero())
+
+				pool.Status.NetworkScan.InUseIPs = []string{"172.18.40.20"}
+				reserved, err = rIPManager.ReserveInUseIPs(ctx, pool)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(reserved).To(Equal(1))
+
+				rIP, err = rIPManager.GetReservedIPByName(ctx, reservedipmanager.InUseReservedIPName(pool.Name))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rIP.Spec.IPs).To(Equal([]string{"172.18.40.10-172.18.40.11", "172.18.40.20"}))
+			})
+		})
+
+		Describe("QuarantineIP", func() {
+			const ip = "172.18.40.50"
+
+			AfterEach(func() {
+				err := fakeClient.Delete(context.TODO(), &spiderpoolv1.SpiderReservedIP{
+					ObjectMeta: metav1.ObjectMeta{Name: reservedipmanager.QuarantineReservedIPName(ip, "")},
+				})
+				Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+				err = fakeClient.Delete(context.TODO(), &spiderpoolv1.SpiderReservedIP{
+					ObjectMeta: metav1.ObjectMeta{Name: reservedipmanager.QuarantineReservedIPName(ip, "tenant1")},
+				})
+				Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+			})
+
+			It("inputs invalid IP address", func() {
+				quarantined, err := rIPManager.QuarantineIP(context.TODO(), "invalid", "", "test", 0)
+				Expect(err).To(MatchError(constant.ErrWrongInput))
+				Expect(quarantined).To(BeFalse())
+			})
+
+			It("quarantines the IP address once", func() {
+				ctx := context.TODO()
+				quarantined, err := rIPManager.QuarantineIP(ctx, ip, "", "test", time.Hour)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(quarantined).To(BeTrue())
+
+				rIP, err := rIPManager.GetReservedIPByName(ctx, reservedipmanager.QuarantineReservedIPName(ip, ""))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rIP.Spec.IPs).To(Equal([]string{ip}))
+				Expect(*rIP.Spec.IPVersion).To(Equal(constant.IPv4))
+				Expect(reservedipmanager.ShouldVacateAllocatedIPs(rIP)).To(BeTrue())
+				Expect(rIP.Spec.TTL.Duration).To(Equal(time.Hour))
+				Expect(rIP.Labels).To(HaveKeyWithValue(constant.LabelReservedIPQuarantineReason, "test"))
+
+				quarantined, err = rIPManager.QuarantineIP(ctx, ip, "", "test", time.Hour)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(quarantined).To(BeFalse())
+			})
+
+			It("quarantines the IP address of the IPPools in the tenant only", func() {
+				ctx := context.TODO()
+				quarantined, err := rIPManager.QuarantineIP(ctx, ip, "", "test", 0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(quarantined).To(BeTrue())
+				quarantined, err = rIPManager.QuarantineIP(ctx, ip, "tenant1", "test", 0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(quarantined).To(BeTrue())
+
+				defaultPool := k8slabels.Set{}
+				tenantPool := k8slabels.Set{constant.LabelIPPoolTenant: "tenant1"}
+
+				rIP, err := rIPManager.GetReservedIPByName(ctx, reservedipmanager.QuarantineReservedIPName(ip, ""))
+				Expect(err).NotTo(HaveOccurred())
+				selector, err := metav1.LabelSelectorAsSelector(rIP.Spec.PoolSelector)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(selector.Matches(defaultPool)).To(BeTrue())
+				Expect(selector.Matches(tenantPool)).To(BeFalse())
+
+				rIP, err = rIPManager.GetReservedIPByName(ctx, reservedipmanager.QuarantineReservedIPName(ip, "tenant1"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rIP.Spec.TTL).To(BeNil())
+				selector, err = metav1.LabelSelectorAsSelector(rIP.Spec.PoolSelector)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(selector.Matches(defaultPool)).To(BeFalse())
+				Expect(selector.Matches(tenantPool)).To(BeTrue())
+			})
+		})
+
+		Describe("Conflicts", func() {
+			var pool *spiderpoolv1.SpiderIPPool
+
+			BeforeEach(func() {
+				rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+				rIPT.Spec.IPs = []string{"172.18.40.10-172.18.40.11"}
+
+				pool = &spiderpoolv1.SpiderIPPool{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   rIPName,
+						Labels: map[string]string{"tenant": "a"},
+					},
+					Spec: spiderpoolv1.IPPoolSpec{
+						IPVersion: pointer.Int64(constant.IPv4),
+					},
+					Status: spiderpoolv1.IPPoolStatus{
+						AllocatedIPs: spiderpoolv1.PoolIPAllocations{
+							"172.18.40.11": {ContainerID: "container", NIC: "eth0", Namespace: "default", Pod: "web-0"},
+						},
+					},
+				}
+			})
+
+			AfterEach(func() {
+				ctx := context.TODO()
+				err := fakeClient.Delete(ctx, pool)
+				Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+			})
+
+			It("flags the ReservedIPs reserving the allocated IP addresses", func() {
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, pool)
+				Expect(err).NotTo(HaveOccurred())
+				err = fakeClient.Create(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+
+				conflicting, err := rIPManager.SyncReservedIPStatus(ctx)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(conflicting).To(Equal(1))
+
+				rIP, err := rIPManager.GetReservedIPByName(ctx, rIPName)
+				Expect(err).NotTo(HaveOccurred())
+				cond := apimeta.FindStatusCondition(rIP.Status.Conditions, constant.ReservedIPConditionConflicting)
+				Expect(cond).NotTo(BeNil())
+				Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+				Expect(cond.Reason).To(Equal(constant.ReservedIPReasonAllocatedIPs))
+				Expect(cond.Message).To(ContainSubstring("172.18.40.11 (IPPool %s, Pod default/web-0)", pool.Name))
+
+				rIP.Spec.PoolSelector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{"tenant": "b"},
+				}
+				err = fakeClient.Update(ctx, rIP)
+				Expect(err).NotTo(HaveOccurred())
+
+				conflicting, err = rIPManager.SyncReservedIPStatus(ctx)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(conflicting).To(BeZero())
+
+				rIP, err = rIPManager.GetReservedIPByName(ctx, rIPName)
+				Expect(err).NotTo(HaveOccurred())
+				cond = apimeta.FindStatusCondition(rIP.Status.Conditions, constant.ReservedIPConditionConflicting)
+				Expect(cond).NotTo(BeNil())
+				Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+				Expect(cond.Reason).To(Equal(constant.ReservedIPReasonNoAllocatedIPs))
+			})
+
+			It("counts the IP addresses of the IPPools blocked by the ReservedIPs", func() {
+				ctx := context.TODO()
+				pool.Spec.IPs = []string{"172.18.40.1-172.18.40.11"}
+				pool.Spec.ExcludeIPs = []string{"172.18.40.10"}
+				err := fakeClient.Create(ctx, pool)
+				Expect(err).NotTo(HaveOccurred())
+				err = fakeClient.Create(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = rIPManager.SyncReservedIPStatus(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				rIP, err := rIPManager.GetReservedIPByName(ctx, rIPName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rIP.Status.AffectedIPPools).To(Equal([]spiderpoolv1.ReservedIPAffectedPool{
+					{Name: pool.Name, ReservedIPCount: 1, BlockedIPCount: 0},
+				}))
+				Expect(rIP.Status.BlockedIPCount).To(Equal(pointer.Int64(0)))
+
+				pool.Spec.ExcludeIPs = nil
+				err = fakeClient.Update(ctx, pool)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = rIPManager.SyncReservedIPStatus(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				rIP, err = rIPManager.GetReservedIPByName(ctx, rIPName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rIP.Status.AffectedIPPools).To(Equal([]spiderpoolv1.ReservedIPAffectedPool{
+					{Name: pool.Name, ReservedIPCount: 2, BlockedIPCount: 1},
+				}))
+				Expect(rIP.Status.BlockedIPCount).To(Equal(pointer.Int64(1)))
+
+				rIP.Spec.PoolSelector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{"tenant": "b"},
+				}
+				err = fakeClient.Update(ctx, rIP)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = rIPManager.SyncReservedIPStatus(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				rIP, err = rIPManager.GetReservedIPByName(ctx, rIPName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rIP.Status.AffectedIPPools).To(BeEmpty())
+				Expect(rIP.Status.BlockedIPCount).To(Equal(pointer.Int64(0)))
+			})
+
+			It("assembles the reserved IP addresses being vacated", func() {
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+
+				ips, err := rIPManager.AssembleVacatingReservedIPs(ctx, constant.IPv4, pool)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ips).To(BeEmpty())
+
+				rIPT.Spec.VacateAllocatedIPs = pointer.Bool(true)
+				err = fakeClient.Update(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+
+				ips, err = rIPManager.AssembleVacatingReservedIPs(ctx, constant.IPv4, pool)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ips).To(Equal([]net.IP{net.IPv4(172, 18, 40, 10), net.IPv4(172, 18, 40, 11)}))
+			})
+
+			It("finds the ReservedIP vacating the IP address", func() {
+				ctx := context.TODO()
+				rIPT.Spec.VacateAllocatedIPs = pointer.Bool(true)
+				err := fakeClient.Create(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+
+				rIP, err := rIPManager.FindVacatingReservedIP(ctx, constant.IPv4, "172.18.40.11", pool)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rIP).NotTo(BeNil())
+				Expect(rIP.Name).To(Equal(rIPName))
+
+				rIP, err = rIPManager.FindVacatingReservedIP(ctx, constant.IPv4, "172.18.40.12", pool)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rIP).To(BeNil())
+
+				_, err = rIPManager.FindVacatingReservedIP(ctx, constant.IPv4, constant.InvalidIP, pool)
+				Expect(err).To(MatchError(constant.ErrWrongInput))
+			})
+		})
+
+		Describe("Import", func() {
+			var source string
+
+			BeforeEach(func() {
+				source = fmt.Sprintf("cmdb-%v", count)
+			})
+
+			AfterEach(func() {
+				err := fakeClient.DeleteAllOf(
+					context.TODO(),
+					&spiderpoolv1.SpiderReservedIP{},
+					client.MatchingLabels{constant.LabelReservedIPImportSource: source},
+				)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("parses the records from CSV", func() {
+				records, err := reservedipmanager.ParseReservedIPRecordsCSV(strings.NewReader(
+					"Owner, IP, Description, Rack\nnet-team, 172.18.40.10, gateway, r1\nnet-team, abcd:1234::1-abcd:1234::2, , r2\n",
+				))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(records).To(Equal([]reservedipmanager.ReservedIPRecord{
+					{IP: "172.18.40.10", Description: "gateway", Owner: "net-team"},
+					{IP: "abcd:1234::1-abcd:1234::2", Owner: "net-team"},
+				}))
+
+				_, err = reservedipmanager.ParseReservedIPRecordsCSV(strings.NewReader("name,owner\nfoo,bar\n"))
+				Expect(err).To(MatchError(constant.ErrWrongInput))
+
+				_, err = reservedipmanager.ParseReservedIPRecordsCSV(strings.NewReader("ip,owner\n,bar\n"))
+				Expect(err).To(MatchError(constant.ErrWrongInput))
+			})
+
+			It("parses the records from JSON", func() {
+				records, err := reservedipmanager.ParseReservedIPRecordsJSON(strings.NewReader(
+					`[{"ip": "172.18.40.10", "name": "gateway", "owner": "net-team"}]`,
+				))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(records).To(Equal([]reservedipmanager.ReservedIPRecord{
+					{Name: "gateway", IP: "172.18.40.10", Owner: "net-team"},
+				}))
+
+				_, err = reservedipmanager.ParseReservedIPRecordsJSON(strings.NewReader(`[{"name": "gateway"}]`))
+				Expect(err).To(MatchError(constant.ErrWrongInput))
+			})
+
+			It("inputs too many records", func() {
+				records := make([]reservedipmanager.ReservedIPRecord, reservedipmanager.MaxReservedIPImportRecords+1)
+				result, err := rIPManager.ImportReservedIPs(context.TODO(), source, records)
+				Expect(err).To(MatchError(constant.ErrWrongInput))
+				Expect(result).To(BeNil())
+			})
+
+			It("inputs invalid source", func() {
+				result, err := rIPManager.ImportReservedIPs(context.TODO(), "cmdb/v1", nil)
+				Expect(err).To(MatchError(constant.ErrWrongInput))
+				Expect(result).To(BeNil())
+			})
+
+			It("imports the records idempotently", func() {
+				ctx := context.TODO()
+				records := []reservedipmanager.ReservedIPRecord{
+					{IP: "172.18.40.10", Description: "gateway", Owner: "net-team"},
+					{IP: "abcd:1234::1-abcd:1234::2"},
+				}
+
+				result, err := rIPManager.ImportReservedIPs(ctx, source, records)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(*result).To(Equal(reservedipmanager.ReservedIPImportResult{Created: 2}))
+
+				rIP, err := rIPManager.GetReservedIPByName(ctx, reservedipmanager.GenReservedIPRecordName(source, "172.18.40.10"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rIP.Labels).To(HaveKeyWithValue(constant.LabelReservedIPImportSource, source))
+				Expect(rIP.Annotations).To(HaveKeyWithValue(constant.AnnoReservedIPDescription, "gateway"))
+				Expect(rIP.Annotations).To(HaveKeyWithValue(constant.AnnoReservedIPOwner, "net-team"))
+				Expect(*rIP.Spec.IPVersion).To(Equal(constant.IPv4))
+				Expect(rIP.Spec.IPs).To(Equal([]string{"172.18.40.10"}))
+
+				result, err = rIPManager.ImportReservedIPs(ctx, source, records)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(*result).To(Equal(reservedipmanager.ReservedIPImportResult{Unchanged: 2}))
+
+				records[0].Owner = ""
+				result, err = rIPManager.ImportReservedIPs(ctx, source, records)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(*result).To(Equal(reservedipmanager.ReservedIPImportResult{Updated: 1, Unchanged: 1}))
+
+				rIP, err = rIPManager.GetReservedIPByName(ctx, reservedipmanager.GenReservedIPRecordName(source, "172.18.40.10"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rIP.Annotations).NotTo(HaveKey(constant.AnnoReservedIPOwner))
+			})
+
+			It("reports the records failing to import", func() {
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+
+				result, err := rIPManager.ImportReservedIPs(ctx, source, []reservedipmanager.ReservedIPRecord{
+					{Name: rIPName, IP: "172.18.40.10"},
+					{IP: "172.18.40.300"},
+					{IP: "172.18.40.11"},
+					{IP: "172.18.40.11"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Created).To(Equal(1))
+				Expect(result.Failures).To(HaveLen(3))
+				Expect(result.Failures[0].Name).To(Equal(rIPName))
+				Expect(result.Failures[1].IP).To(Equal("172.18.40.300"))
+				Expect(result.Failures[2].Name).To(Equal(reservedipmanager.GenReservedIPRecordName(source, "172.18.40.11")))
+			})
+		})
 	})
 })
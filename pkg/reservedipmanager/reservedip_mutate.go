@@ -43,7 +43,7 @@ func (rw *ReservedIPWebhook) mutateReservedIP(ctx context.Context, rIP *spiderpo
 		logger.Sugar().Infof("Set 'spec.ipVersion' to %d", version)
 	}
 
-	if len(rIP.Spec.IPs) > 1 {
+	if len(rIP.Spec.IPs) > 1 || spiderpoolip.HasCompactIPRanges(*rIP.Spec.IPVersion, rIP.Spec.IPs) {
 		mergedIPs, err := spiderpoolip.MergeIPRanges(*rIP.Spec.IPVersion, rIP.Spec.IPs)
 		if err != nil {
 			return fmt.Errorf("failed to merge 'spec.ips': %v", err)
@@ -53,5 +53,15 @@ func (rw *ReservedIPWebhook) mutateReservedIP(ctx context.Context, rIP *spiderpo
 		logger.Sugar().Debugf("Merge 'spec.ips':\n%v\n\nto:\n\n%v", rIP.Spec.IPs, mergedIPs)
 	}
 
+	if len(rIP.Spec.ExcludeIPs) > 1 || spiderpoolip.HasCompactIPRanges(*rIP.Spec.IPVersion, rIP.Spec.ExcludeIPs) {
+		mergedExcludeIPs, err := spiderpoolip.MergeIPRanges(*rIP.Spec.IPVersion, rIP.Spec.ExcludeIPs)
+		if err != nil {
+			return fmt.Errorf("failed to merge 'spec.excludeIPs': %v", err)
+		}
+
+		rIP.Spec.ExcludeIPs = mergedExcludeIPs
+		logger.Sugar().Debugf("Merge 'spec.excludeIPs':\n%v\n\nto:\n\n%v", rIP.Spec.ExcludeIPs, mergedExcludeIPs)
+	}
+
 	return nil
 }
@@ -0,0 +1,104 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package reservedipmanager
+
+import (
+	"context"
+	"net"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+)
+
+// InUseReservedIPName returns the name of the SpiderReservedIP which holds
+// the IP addresses of the IPPool observed in use on the network.
+func InUseReservedIPName(poolName string) string {
+	return poolName + "-in-use"
+}
+
+// ReserveInUseIPs reserves the IP addresses reported in use on the network
+// by the last scan of the IPPool, and returns how many are newly reserved.
+// They are accumulated in one SpiderReservedIP owned by the IPPool, the
+// ones no longer in use are never removed automatically.
+func (rm *reservedIPManager) ReserveInUseIPs(ctx context.Context, pool *spiderpoolv1.SpiderIPPool) (int, error) {
+	if pool.Status.NetworkScan == nil || len(pool.Status.NetworkScan.InUseIPs) == 0 {
+		return 0, nil
+	}
+
+	version := *pool.Spec.IPVersion
+	inUseIPs, err := spiderpoolip.ParseIPRanges(version, pool.Status.NetworkScan.InUseIPs)
+	if err != nil {
+		return 0, err
+	}
+
+	// The IP addresses allocated after the scan belong to the Pods.
+	var ips []net.IP
+	for _, ip := range inUseIPs {
+		if _, ok := pool.Status.AllocatedIPs[ip.String()]; !ok {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return 0, nil
+	}
+
+	rIP, err := rm.GetReservedIPByName(ctx, InUseReservedIPName(pool.Name))
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return 0, err
+		}
+
+		ranges, err := spiderpoolip.ConvertIPsToIPRanges(version, ips)
+		if err != nil {
+			return 0, err
+		}
+		rIP = &spiderpoolv1.SpiderReservedIP{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   InUseReservedIPName(pool.Name),
+				Labels: map[string]string{constant.LabelReservedIPInUseIPPool: pool.Name},
+			},
+			Spec: spiderpoolv1.ReservedIPSpec{
+				IPVersion: &version,
+				IPs:       ranges,
+			},
+		}
+		if err := controllerutil.SetControllerReference(pool, rIP, rm.client.Scheme()); err != nil {
+			return 0, err
+		}
+		if err := rm.client.Create(ctx, rIP); err != nil {
+			return 0, err
+		}
+
+		return len(ips), nil
+	}
+
+	if rIP.DeletionTimestamp != nil {
+		return 0, nil
+	}
+
+	reservedIPs, err := GetReservedIPs(version, rIP)
+	if err != nil {
+		return 0, err
+	}
+	newIPs := spiderpoolip.IPsDiffSet(ips, reservedIPs, false)
+	if len(newIPs) == 0 {
+		return 0, nil
+	}
+
+	rIP.Spec.IPs, err = spiderpoolip.ConvertIPsToIPRanges(version, append(reservedIPs, newIPs...))
+	if err != nil {
+		return 0, err
+	}
+	rIP.Spec.ExcludeIPs = nil
+	if err := rm.client.Update(ctx, rIP); err != nil {
+		return 0, err
+	}
+
+	return len(newIPs), nil
+}
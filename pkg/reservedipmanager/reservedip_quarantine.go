@@ -0,0 +1,89 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package reservedipmanager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+)
+
+// QuarantineReservedIPName returns the name of the SpiderReservedIP which
+// quarantines the IP address of the tenant.
+func QuarantineReservedIPName(ip, tenant string) string {
+	name := ip
+	if tenant != "" {
+		name = strings.ToLower(tenant) + "-" + ip
+	}
+
+	return "quarantine-" + strings.NewReplacer(":", "-", "_", "-").Replace(name)
+}
+
+// QuarantineIP reserves the IP address of the IPPools in the tenant from all
+// Pods by a SpiderReservedIP labeled with the reason, so that it's not
+// allocated to any other Pod. The IPPools of the other tenants, which may
+// overlap, are not affected. The allocations of the IP address are vacated
+// when their Pods restart, and the quarantine is lifted after the duration
+// if it's positive. It reports whether the IP address is newly quarantined.
+func (rm *reservedIPManager) QuarantineIP(ctx context.Context, ip, tenant, reason string, duration time.Duration) (bool, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false, fmt.Errorf("%w, invalid IP address '%s'", constant.ErrWrongInput, ip)
+	}
+
+	version := constant.IPv6
+	if addr.To4() != nil {
+		version = constant.IPv4
+	}
+
+	rIP := &spiderpoolv1.SpiderReservedIP{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   QuarantineReservedIPName(addr.String(), tenant),
+			Labels: map[string]string{constant.LabelReservedIPQuarantineReason: reason},
+		},
+		Spec: spiderpoolv1.ReservedIPSpec{
+			IPVersion:          &version,
+			IPs:                []string{addr.String()},
+			VacateAllocatedIPs: pointer.Bool(true),
+			PoolSelector:       tenantPoolSelector(tenant),
+		},
+	}
+	if duration > 0 {
+		rIP.Spec.TTL = &metav1.Duration{Duration: duration}
+	}
+
+	if err := rm.client.Create(ctx, rIP); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// tenantPoolSelector selects the IPPools of the tenant by their label.
+func tenantPoolSelector(tenant string) *metav1.LabelSelector {
+	if tenant == "" {
+		return &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key:      constant.LabelIPPoolTenant,
+				Operator: metav1.LabelSelectorOpDoesNotExist,
+			}},
+		}
+	}
+
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{constant.LabelIPPoolTenant: tenant},
+	}
+}
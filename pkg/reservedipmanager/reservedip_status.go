@@ -0,0 +1,179 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package reservedipmanager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/utils/pointer"
+
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+// poolTotalIPs is an IPPool with its IP addresses excluding 'spec.excludeIPs'.
+type poolTotalIPs struct {
+	pool     *spiderpoolv1.SpiderIPPool
+	totalIPs map[string]struct{}
+}
+
+// assemblePoolTotalIPs assembles the IP addresses of the IPPools which are
+// not terminating, sorted by the names of the IPPools.
+func assemblePoolTotalIPs(pools []spiderpoolv1.SpiderIPPool) ([]poolTotalIPs, error) {
+	var errs []error
+	var result []poolTotalIPs
+	for i := range pools {
+		pool := &pools[i]
+		if pool.DeletionTimestamp != nil || pool.Spec.IPVersion == nil {
+			continue
+		}
+
+		ips, err := spiderpoolip.AssembleTotalIPs(*pool.Spec.IPVersion, pool.Spec.IPs, pool.Spec.ExcludeIPs)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid IP addresses of IPPool %s: %w", pool.Name, err))
+			continue
+		}
+
+		totalIPs := make(map[string]struct{}, len(ips))
+		for _, ip := range ips {
+			totalIPs[ip.String()] = struct{}{}
+		}
+		result = append(result, poolTotalIPs{pool: pool, totalIPs: totalIPs})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].pool.Name < result[j].pool.Name
+	})
+
+	return result, utilerrors.NewAggregate(errs)
+}
+
+// genReservedIPAffectedPools counts the IP addresses reserved by the
+// SpiderReservedIP in each IPPool of its scope, and how many of them would
+// be free for allocation otherwise.
+func genReservedIPAffectedPools(rIP *spiderpoolv1.SpiderReservedIP, ips []net.IP, pools []poolTotalIPs) ([]spiderpoolv1.ReservedIPAffectedPool, int64, error) {
+	var affected []spiderpoolv1.ReservedIPAffectedPool
+	var blocked int64
+	for _, p := range pools {
+		if *p.pool.Spec.IPVersion != *rIP.Spec.IPVersion {
+			continue
+		}
+
+		ok, err := matchReservedIPPools(rIP, p.pool)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !ok {
+			continue
+		}
+
+		var reservedCount, blockedCount int64
+		for _, ip := range ips {
+			if _, ok := p.totalIPs[ip.String()]; !ok {
+				continue
+			}
+			reservedCount++
+			if _, ok := p.pool.Status.AllocatedIPs[ip.String()]; !ok {
+				blockedCount++
+			}
+		}
+		if reservedCount == 0 {
+			continue
+		}
+
+		affected = append(affected, spiderpoolv1.ReservedIPAffectedPool{
+			Name:            p.pool.Name,
+			ReservedIPCount: reservedCount,
+			BlockedIPCount:  blockedCount,
+		})
+		blocked += blockedCount
+	}
+
+	return affected, blocked, nil
+}
+
+// SyncReservedIPStatus refreshes the status of all SpiderReservedIPs,
+// including the condition "Conflicting" and the IPPools affected by the
+// reservations, and returns how many of them reserve IP addresses still
+// allocated to Pods.
+func (rm *reservedIPManager) SyncReservedIPStatus(ctx context.Context) (int, error) {
+	logger := logutils.FromContext(ctx)
+
+	var poolList spiderpoolv1.SpiderIPPoolList
+	if err := rm.client.List(ctx, &poolList); err != nil {
+		return 0, err
+	}
+
+	rIPList, err := rm.ListReservedIPs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// The IPPools with invalid IP addresses are skipped, which are rejected
+	// by the webhook anyway.
+	var errs []error
+	pools, err := assemblePoolTotalIPs(poolList.Items)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	var conflicting int
+	for i := range rIPList.Items {
+		rIP := &rIPList.Items[i]
+		if rIP.DeletionTimestamp != nil || rIP.Spec.IPVersion == nil {
+			continue
+		}
+
+		ips, err := GetReservedIPs(*rIP.Spec.IPVersion, rIP)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid IP addresses of SpiderReservedIP %s: %w", rIP.Name, err))
+			continue
+		}
+
+		allocated, err := findAllocatedReservedIPs(rIP, ips, poolList.Items)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(allocated) != 0 {
+			conflicting++
+		}
+
+		affected, blocked, err := genReservedIPAffectedPools(rIP, ips, pools)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		status := rIP.Status.DeepCopy()
+		apimeta.SetStatusCondition(&status.Conditions, genReservedIPConflictingCondition(rIP, allocated))
+		status.AffectedIPPools = affected
+		status.BlockedIPCount = pointer.Int64(blocked)
+		if reflect.DeepEqual(&rIP.Status, status) {
+			continue
+		}
+
+		rIP.Status = *status
+		if err := rm.client.Status().Update(ctx, rIP); err != nil {
+			// The conflicting ones are refreshed in the next round.
+			if apierrors.IsNotFound(err) || apierrors.IsConflict(err) {
+				continue
+			}
+			logger.Sugar().Warnf("Failed to update the status of SpiderReservedIP %s: %v", rIP.Name, err)
+			errs = append(errs, err)
+			continue
+		}
+		logger.Sugar().Debugf("Update the status of SpiderReservedIP %s: %+v", rIP.Name, rIP.Status)
+	}
+
+	return conflicting, utilerrors.NewAggregate(errs)
+}
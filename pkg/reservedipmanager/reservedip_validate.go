@@ -5,8 +5,11 @@ package reservedipmanager
 
 import (
 	"context"
+	"fmt"
 	"strconv"
+	"strings"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	"github.com/spidernet-io/spiderpool/pkg/constant"
@@ -16,8 +19,14 @@ import (
 )
 
 var (
-	ipVersionField *field.Path = field.NewPath("spec").Child("ipVersion")
-	ipsField       *field.Path = field.NewPath("spec").Child("ips")
+	ipVersionField         *field.Path = field.NewPath("spec").Child("ipVersion")
+	ipsField               *field.Path = field.NewPath("spec").Child("ips")
+	excludeIPsField        *field.Path = field.NewPath("spec").Child("excludeIPs")
+	poolSelectorField      *field.Path = field.NewPath("spec").Child("poolSelector")
+	namespaceSelectorField *field.Path = field.NewPath("spec").Child("namespaceSelector")
+	ttlField               *field.Path = field.NewPath("spec").Child("ttl")
+	claimField             *field.Path = field.NewPath("spec").Child("claim")
+	vacateField            *field.Path = field.NewPath("spec").Child("vacateAllocatedIPs")
 )
 
 func (rw *ReservedIPWebhook) validateCreateReservedIP(ctx context.Context, rIP *spiderpoolv1.SpiderReservedIP) field.ErrorList {
@@ -28,6 +37,8 @@ func (rw *ReservedIPWebhook) validateCreateReservedIP(ctx context.Context, rIP *
 	var errs field.ErrorList
 	if err := rw.validateReservedIPSpec(ctx, rIP); err != nil {
 		errs = append(errs, err)
+	} else if err := rw.validateReservedIPAllocations(ctx, nil, rIP); err != nil {
+		errs = append(errs, err)
 	}
 
 	if len(errs) == 0 {
@@ -49,6 +60,8 @@ func (rw *ReservedIPWebhook) validateUpdateReservedIP(ctx context.Context, oldRI
 	var errs field.ErrorList
 	if err := rw.validateReservedIPSpec(ctx, newRIP); err != nil {
 		errs = append(errs, err)
+	} else if err := rw.validateReservedIPAllocations(ctx, oldRIP, newRIP); err != nil {
+		errs = append(errs, err)
 	}
 
 	if len(errs) == 0 {
@@ -71,7 +84,78 @@ func validateReservedIPShouldNotBeChanged(oldRIP, newRIP *spiderpoolv1.SpiderRes
 }
 
 func (rw *ReservedIPWebhook) validateReservedIPSpec(ctx context.Context, rIP *spiderpoolv1.SpiderReservedIP) *field.Error {
-	return rw.validateReservedIPs(ctx, *rIP.Spec.IPVersion, rIP.Spec.IPs)
+	if err := validateReservedIPSelector(poolSelectorField, rIP.Spec.PoolSelector); err != nil {
+		return err
+	}
+
+	if err := validateReservedIPSelector(namespaceSelectorField, rIP.Spec.NamespaceSelector); err != nil {
+		return err
+	}
+
+	if err := validateReservedIPClaim(rIP); err != nil {
+		return err
+	}
+
+	if rIP.Spec.TTL != nil && rIP.Spec.TTL.Duration <= 0 {
+		return field.Invalid(
+			ttlField,
+			rIP.Spec.TTL.Duration.String(),
+			"must be positive",
+		)
+	}
+
+	if err := rw.validateReservedIPs(ctx, *rIP.Spec.IPVersion, rIP.Spec.IPs); err != nil {
+		return err
+	}
+
+	return validateReservedIPExcludeIPs(rIP)
+}
+
+func validateReservedIPSelector(fieldPath *field.Path, selector *metav1.LabelSelector) *field.Error {
+	if selector == nil {
+		return nil
+	}
+
+	if _, err := metav1.LabelSelectorAsSelector(selector); err != nil {
+		return field.Invalid(
+			fieldPath,
+			selector,
+			err.Error(),
+		)
+	}
+
+	return nil
+}
+
+func validateReservedIPClaim(rIP *spiderpoolv1.SpiderReservedIP) *field.Error {
+	claim := rIP.Spec.Claim
+	if claim == nil {
+		return nil
+	}
+
+	if rIP.Spec.NamespaceSelector != nil {
+		return field.Forbidden(
+			claimField,
+			"cannot be used together with namespaceSelector",
+		)
+	}
+
+	if claim.Namespace == "" {
+		return field.Required(
+			claimField.Child("namespace"),
+			"",
+		)
+	}
+
+	if (claim.PodName == "") == (claim.PodSelector == nil) {
+		return field.Invalid(
+			claimField,
+			claim,
+			"exactly one of podName and podSelector must be specified",
+		)
+	}
+
+	return validateReservedIPSelector(claimField.Child("podSelector"), claim.PodSelector)
 }
 
 func (rw *ReservedIPWebhook) validateReservedIPIPVersion(version *types.IPVersion) *field.Error {
@@ -124,3 +208,97 @@ func (rw *ReservedIPWebhook) validateReservedIPs(ctx context.Context, version ty
 
 	return nil
 }
+
+func validateReservedIPExcludeIPs(rIP *spiderpoolv1.SpiderReservedIP) *field.Error {
+	if len(rIP.Spec.ExcludeIPs) == 0 {
+		return nil
+	}
+
+	for i, r := range rIP.Spec.ExcludeIPs {
+		if err := spiderpoolip.IsIPRange(*rIP.Spec.IPVersion, r); err != nil {
+			return field.Invalid(
+				excludeIPsField.Index(i),
+				rIP.Spec.ExcludeIPs[i],
+				err.Error(),
+			)
+		}
+	}
+
+	ips, err := GetReservedIPs(*rIP.Spec.IPVersion, rIP)
+	if err != nil {
+		return field.Invalid(
+			excludeIPsField,
+			rIP.Spec.ExcludeIPs,
+			err.Error(),
+		)
+	}
+	if len(ips) == 0 {
+		return field.Invalid(
+			excludeIPsField,
+			rIP.Spec.ExcludeIPs,
+			"excludes all the IP addresses of 'spec.ips', nothing is reserved",
+		)
+	}
+
+	return nil
+}
+
+// validateReservedIPAllocations denies reserving the IP addresses which are
+// still allocated to Pods, unless their allocations are going to be vacated.
+// Only the IP addresses newly reserved by the update are checked.
+func (rw *ReservedIPWebhook) validateReservedIPAllocations(ctx context.Context, oldRIP, newRIP *spiderpoolv1.SpiderReservedIP) *field.Error {
+	if ShouldVacateAllocatedIPs(newRIP) {
+		return nil
+	}
+
+	version := *newRIP.Spec.IPVersion
+	ips, err := GetReservedIPs(version, newRIP)
+	if err != nil {
+		return field.Invalid(
+			ipsField,
+			newRIP.Spec.IPs,
+			err.Error(),
+		)
+	}
+
+	// The IP addresses reserved before the update have been admitted, unless
+	// their allocations were going to be vacated.
+	if oldRIP != nil && !ShouldVacateAllocatedIPs(oldRIP) {
+		oldIPs, err := GetReservedIPs(version, oldRIP)
+		if err == nil {
+			ips = spiderpoolip.IPsDiffSet(ips, oldIPs, false)
+		}
+	}
+	if len(ips) == 0 {
+		return nil
+	}
+
+	var poolList spiderpoolv1.SpiderIPPoolList
+	if err := rw.Client.List(ctx, &poolList); err != nil {
+		return field.InternalError(
+			ipsField,
+			fmt.Errorf("failed to list IPPools: %v", err),
+		)
+	}
+
+	allocated, err := findAllocatedReservedIPs(newRIP, ips, poolList.Items)
+	if err != nil {
+		return field.InternalError(
+			ipsField,
+			err,
+		)
+	}
+	if len(allocated) == 0 {
+		return nil
+	}
+
+	items := make([]string, 0, len(allocated))
+	for _, a := range allocated {
+		items = append(items, a.String())
+	}
+
+	return field.Forbidden(
+		ipsField,
+		fmt.Sprintf("IP addresses %s are allocated, set '%s' to reserve them once their Pods restart", strings.Join(items, ", "), vacateField),
+	)
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/spidernet-io/spiderpool/pkg/constant"
 	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
 	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/reportonly"
 )
 
 var WebhookLogger *zap.Logger
@@ -27,6 +28,10 @@ type ReservedIPWebhook struct {
 
 	EnableIPv4 bool
 	EnableIPv6 bool
+
+	// ReportOnly admits the requests which would be denied, the denials
+	// are logged and counted only.
+	ReportOnly bool
 }
 
 func (rw *ReservedIPWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
@@ -34,10 +39,15 @@ func (rw *ReservedIPWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
 		WebhookLogger = logutils.Logger.Named("ReservedIP-Webhook")
 	}
 
+	var validator webhook.CustomValidator = rw
+	if rw.ReportOnly {
+		validator = reportonly.NewValidator(constant.SpiderReservedIPKind, rw)
+	}
+
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(&spiderpoolv1.SpiderReservedIP{}).
 		WithDefaulter(rw).
-		WithValidator(rw).
+		WithValidator(validator).
 		Complete()
 }
 
@@ -51,7 +61,7 @@ func (rw *ReservedIPWebhook) Default(ctx context.Context, obj runtime.Object) er
 		zap.String("ReservedIPName", rIP.Name),
 		zap.String("Operation", "DEFAULT"),
 	)
-	logger.Sugar().Debugf("Request ReservedIP: %+v", *rIP)
+	logger.Sugar().Debugf("Request ReservedIP: %v", rIP)
 
 	if err := rw.mutateReservedIP(logutils.IntoContext(ctx, logger), rIP); err != nil {
 		logger.Sugar().Errorf("Failed to mutate ReservedIP: %v", err)
@@ -71,7 +81,7 @@ func (rw *ReservedIPWebhook) ValidateCreate(ctx context.Context, obj runtime.Obj
 		zap.String("ReservedIPName", rIP.Name),
 		zap.String("Operation", "CREATE"),
 	)
-	logger.Sugar().Debugf("Request ReservedIP: %+v", *rIP)
+	logger.Sugar().Debugf("Request ReservedIP: %v", rIP)
 
 	if errs := rw.validateCreateReservedIP(logutils.IntoContext(ctx, logger), rIP); len(errs) != 0 {
 		logger.Sugar().Errorf("Failed to create ReservedIP: %v", errs.ToAggregate().Error())
@@ -95,8 +105,8 @@ func (rw *ReservedIPWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj
 		zap.String("ReservedIPName", newRIP.Name),
 		zap.String("Operation", "UPDATE"),
 	)
-	logger.Sugar().Debugf("Request old ReservedIP: %+v", *oldRIP)
-	logger.Sugar().Debugf("Request new ReservedIP: %+v", *newRIP)
+	logger.Sugar().Debugf("Request old ReservedIP: %v", oldRIP)
+	logger.Sugar().Debugf("Request new ReservedIP: %v", newRIP)
 
 	if newRIP.DeletionTimestamp != nil {
 		if oldRIP.DeletionTimestamp == nil {
@@ -207,6 +207,23 @@ var _ = Describe("ReservedIPWebhook", Label("reservedip_webhook_test"), func() {
 					},
 				))
 			})
+
+			It("merges 'spec.excludeIPs'", func() {
+				rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+				rIPT.Spec.IPs = append(rIPT.Spec.IPs, "10.6.0.0/24")
+				rIPT.Spec.ExcludeIPs = append(rIPT.Spec.ExcludeIPs,
+					[]string{
+						"10.6.0.15-10.6.0.20",
+						"10.6.0.10-10.6.0.14",
+					}...,
+				)
+
+				ctx := context.TODO()
+				err := rIPWebhook.Default(ctx, rIPT)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rIPT.Spec.IPs).To(Equal([]string{"10.6.0.0-10.6.0.255"}))
+				Expect(rIPT.Spec.ExcludeIPs).To(Equal([]string{"10.6.0.10-10.6.0.20"}))
+			})
 		})
 
 		Describe("ValidateCreate", func() {
@@ -255,6 +272,204 @@ var _ = Describe("ReservedIPWebhook", Label("reservedip_webhook_test"), func() {
 				})
 			})
 
+			When("Validating 'spec.excludeIPs'", func() {
+				It("inputs invalid 'spec.excludeIPs'", func() {
+					rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					rIPT.Spec.IPs = append(rIPT.Spec.IPs, "172.18.40.1-172.18.40.10")
+					rIPT.Spec.ExcludeIPs = append(rIPT.Spec.ExcludeIPs, constant.InvalidIPRange)
+
+					ctx := context.TODO()
+					err := rIPWebhook.ValidateCreate(ctx, rIPT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("excludes all the IP addresses of 'spec.ips'", func() {
+					rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					rIPT.Spec.IPs = append(rIPT.Spec.IPs, "172.18.40.1-172.18.40.10")
+					rIPT.Spec.ExcludeIPs = append(rIPT.Spec.ExcludeIPs, "172.18.40.0-172.18.40.20")
+
+					ctx := context.TODO()
+					err := rIPWebhook.ValidateCreate(ctx, rIPT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+					Expect(err.Error()).To(ContainSubstring("nothing is reserved"))
+				})
+			})
+
+			When("Validating the optional fields", func() {
+				It("inputs invalid 'spec.poolSelector'", func() {
+					rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					rIPT.Spec.IPs = append(rIPT.Spec.IPs, "172.18.40.10")
+					rIPT.Spec.PoolSelector = &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{Key: "tenant", Operator: "Unknown"},
+						},
+					}
+
+					ctx := context.TODO()
+					err := rIPWebhook.ValidateCreate(ctx, rIPT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs non-positive 'spec.ttl'", func() {
+					rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					rIPT.Spec.IPs = append(rIPT.Spec.IPs, "172.18.40.10")
+					rIPT.Spec.TTL = &metav1.Duration{}
+
+					ctx := context.TODO()
+					err := rIPWebhook.ValidateCreate(ctx, rIPT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs invalid 'spec.namespaceSelector'", func() {
+					rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					rIPT.Spec.IPs = append(rIPT.Spec.IPs, "172.18.40.10")
+					rIPT.Spec.NamespaceSelector = &metav1.LabelSelector{
+						MatchLabels: map[string]string{"tenant": "invalid value!"},
+					}
+
+					ctx := context.TODO()
+					err := rIPWebhook.ValidateCreate(ctx, rIPT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs 'spec.claim' without 'spec.claim.namespace'", func() {
+					rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					rIPT.Spec.IPs = append(rIPT.Spec.IPs, "172.18.40.10")
+					rIPT.Spec.Claim = &spiderpoolv1.ReservedIPClaim{PodName: "pod"}
+
+					ctx := context.TODO()
+					err := rIPWebhook.ValidateCreate(ctx, rIPT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs 'spec.claim' with both 'podName' and 'podSelector'", func() {
+					rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					rIPT.Spec.IPs = append(rIPT.Spec.IPs, "172.18.40.10")
+					rIPT.Spec.Claim = &spiderpoolv1.ReservedIPClaim{
+						Namespace: "default",
+						PodName:   "pod",
+						PodSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"app": "db"},
+						},
+					}
+
+					ctx := context.TODO()
+					err := rIPWebhook.ValidateCreate(ctx, rIPT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs 'spec.claim' together with 'spec.namespaceSelector'", func() {
+					rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					rIPT.Spec.IPs = append(rIPT.Spec.IPs, "172.18.40.10")
+					rIPT.Spec.Claim = &spiderpoolv1.ReservedIPClaim{Namespace: "default", PodName: "pod"}
+					rIPT.Spec.NamespaceSelector = &metav1.LabelSelector{
+						MatchLabels: map[string]string{"tenant": "a"},
+					}
+
+					ctx := context.TODO()
+					err := rIPWebhook.ValidateCreate(ctx, rIPT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs valid 'spec.claim'", func() {
+					rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					rIPT.Spec.IPs = append(rIPT.Spec.IPs, "172.18.40.10")
+					rIPT.Spec.Claim = &spiderpoolv1.ReservedIPClaim{Namespace: "default", PodName: "pod"}
+
+					ctx := context.TODO()
+					err := rIPWebhook.ValidateCreate(ctx, rIPT)
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+
+			When("Validating the allocated IP addresses", func() {
+				var pool *spiderpoolv1.SpiderIPPool
+
+				BeforeEach(func() {
+					pool = &spiderpoolv1.SpiderIPPool{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: rIPName,
+						},
+						Spec: spiderpoolv1.IPPoolSpec{
+							IPVersion: pointer.Int64(constant.IPv4),
+						},
+						Status: spiderpoolv1.IPPoolStatus{
+							AllocatedIPs: spiderpoolv1.PoolIPAllocations{
+								"172.18.40.10": {ContainerID: "container", NIC: "eth0", Namespace: "default", Pod: "web-0"},
+							},
+						},
+					}
+
+					ctx := context.TODO()
+					err := fakeClient.Create(ctx, pool)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				AfterEach(func() {
+					ctx := context.TODO()
+					err := fakeClient.Delete(ctx, pool)
+					Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+				})
+
+				It("reserves the allocated IP addresses", func() {
+					rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					rIPT.Spec.IPs = append(rIPT.Spec.IPs, "172.18.40.9-172.18.40.10")
+
+					ctx := context.TODO()
+					err := rIPWebhook.ValidateCreate(ctx, rIPT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+					Expect(err.Error()).To(ContainSubstring("172.18.40.10 (IPPool %s, Pod default/web-0)", pool.Name))
+				})
+
+				It("excludes the allocated IP addresses", func() {
+					rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					rIPT.Spec.IPs = append(rIPT.Spec.IPs, "172.18.40.9-172.18.40.11")
+					rIPT.Spec.ExcludeIPs = append(rIPT.Spec.ExcludeIPs, "172.18.40.10")
+
+					ctx := context.TODO()
+					err := rIPWebhook.ValidateCreate(ctx, rIPT)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("reserves the allocated IP addresses of the IPPools out of scope", func() {
+					rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					rIPT.Spec.IPs = append(rIPT.Spec.IPs, "172.18.40.10")
+					rIPT.Spec.PoolSelector = &metav1.LabelSelector{
+						MatchLabels: map[string]string{"tenant": "b"},
+					}
+
+					ctx := context.TODO()
+					err := rIPWebhook.ValidateCreate(ctx, rIPT)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("reserves the allocated IP addresses to be vacated", func() {
+					rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					rIPT.Spec.IPs = append(rIPT.Spec.IPs, "172.18.40.10")
+					rIPT.Spec.VacateAllocatedIPs = pointer.Bool(true)
+
+					ctx := context.TODO()
+					err := rIPWebhook.ValidateCreate(ctx, rIPT)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("only checks the newly reserved IP addresses on update", func() {
+					rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					rIPT.Spec.IPs = append(rIPT.Spec.IPs, "172.18.40.10")
+
+					newRIPT := rIPT.DeepCopy()
+					newRIPT.Spec.IPs = append(newRIPT.Spec.IPs, "172.18.40.11")
+
+					ctx := context.TODO()
+					err := rIPWebhook.ValidateUpdate(ctx, rIPT, newRIPT)
+					Expect(err).NotTo(HaveOccurred())
+
+					rIPT.Spec.VacateAllocatedIPs = pointer.Bool(true)
+					err = rIPWebhook.ValidateUpdate(ctx, rIPT, newRIPT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+			})
+
 			It("creates IPv4 ReservedIP with all fields valid", func() {
 				rIPT.Spec.IPVersion = pointer.Int64(constant.IPv4)
 				rIPT.Spec.IPs = append(rIPT.Spec.IPs,
@@ -0,0 +1,345 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package servicemanager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/election"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+var informerLogger *zap.Logger
+
+type ServiceControllerConfig struct {
+	ControllerWorkers   int
+	MaxWorkqueueLength  int
+	ResyncPeriod        time.Duration
+	LeaderRetryElectGap time.Duration
+}
+
+// ServiceController annotates the Services with the IP addresses allocated
+// by spiderpool to the Pods they select, so that the external load
+// balancers or firewalls could consume the underlay IP addresses of the
+// backends without watching the Pods.
+type ServiceController struct {
+	client    client.Client
+	workqueue workqueue.RateLimitingInterface
+
+	serviceLister corelisters.ServiceLister
+	podLister     corelisters.PodLister
+	serviceSynced cache.InformerSynced
+	podSynced     cache.InformerSynced
+
+	ServiceControllerConfig
+}
+
+func NewServiceController(config ServiceControllerConfig, client client.Client) (*ServiceController, error) {
+	if client == nil {
+		return nil, fmt.Errorf("k8s client %w", constant.ErrMissingRequiredParam)
+	}
+
+	return &ServiceController{
+		client:                  client,
+		ServiceControllerConfig: config,
+	}, nil
+}
+
+func (sc *ServiceController) SetupInformer(ctx context.Context, k8sClient kubernetes.Interface, leader election.SpiderLeaseElector) error {
+	if k8sClient == nil {
+		return fmt.Errorf("k8s clientset %w", constant.ErrMissingRequiredParam)
+	}
+	if leader == nil {
+		return fmt.Errorf("controller leader %w", constant.ErrMissingRequiredParam)
+	}
+
+	informerLogger = logutils.Logger.Named("Service-Informer")
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			if !leader.IsElected() {
+				time.Sleep(sc.LeaderRetryElectGap)
+				continue
+			}
+
+			innerCtx, innerCancel := context.WithCancel(ctx)
+			go func() {
+				for {
+					select {
+					case <-innerCtx.Done():
+						return
+					default:
+					}
+
+					if !leader.IsElected() {
+						informerLogger.Warn("Leader lost, stop Service informer")
+						innerCancel()
+						return
+					}
+					time.Sleep(sc.LeaderRetryElectGap)
+				}
+			}()
+
+			informerLogger.Info("Initialize Service informer")
+			factory := kubeinformers.NewSharedInformerFactory(k8sClient, sc.ResyncPeriod)
+			sc.addEventHandlers(factory)
+
+			factory.Start(innerCtx.Done())
+			if err := sc.run(logutils.IntoContext(innerCtx, informerLogger), sc.ControllerWorkers); err != nil {
+				informerLogger.Sugar().Errorf("failed to run Service informer: %v", err)
+				innerCancel()
+			}
+			informerLogger.Info("Service informer down")
+		}
+	}()
+
+	return nil
+}
+
+func (sc *ServiceController) addEventHandlers(factory kubeinformers.SharedInformerFactory) {
+	serviceInformer := factory.Core().V1().Services()
+	podInformer := factory.Core().V1().Pods()
+	sc.serviceLister = serviceInformer.Lister()
+	sc.podLister = podInformer.Lister()
+	sc.serviceSynced = serviceInformer.Informer().HasSynced
+	sc.podSynced = podInformer.Informer().HasSynced
+
+	// Once we lost the leader but get leader later, we have to use a new workqueue.
+	sc.workqueue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Service")
+
+	serviceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: sc.enqueueService,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			sc.enqueueService(newObj)
+		},
+	})
+
+	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: sc.enqueueServicesOfPod,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPod := oldObj.(*corev1.Pod)
+			newPod := newObj.(*corev1.Pod)
+			if reflect.DeepEqual(oldPod.Labels, newPod.Labels) &&
+				reflect.DeepEqual(oldPod.Status.PodIPs, newPod.Status.PodIPs) &&
+				oldPod.DeletionTimestamp.Equal(newPod.DeletionTimestamp) {
+				return
+			}
+			sc.enqueueServicesOfPod(oldObj)
+			sc.enqueueServicesOfPod(newObj)
+		},
+		DeleteFunc: sc.enqueueServicesOfPod,
+	})
+}
+
+func (sc *ServiceController) enqueueService(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		informerLogger.Sugar().Errorf("failed to get the key of Service %+v: %v", obj, err)
+		return
+	}
+
+	if sc.workqueue.Len() >= sc.MaxWorkqueueLength {
+		informerLogger.Sugar().Errorf("Workqueue is full, dropping Service %s", key)
+		return
+	}
+	sc.workqueue.Add(key)
+}
+
+func (sc *ServiceController) enqueueServicesOfPod(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return
+		}
+		if pod, ok = tombstone.Obj.(*corev1.Pod); !ok {
+			return
+		}
+	}
+
+	services, err := sc.serviceLister.Services(pod.Namespace).List(labels.Everything())
+	if err != nil {
+		informerLogger.Sugar().Errorf("failed to list Services in Namespace %s: %v", pod.Namespace, err)
+		return
+	}
+
+	for _, service := range services {
+		if len(service.Spec.Selector) == 0 {
+			continue
+		}
+		if labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(pod.Labels)) {
+			sc.enqueueService(service)
+		}
+	}
+}
+
+func (sc *ServiceController) run(ctx context.Context, workers int) error {
+	defer utilruntime.HandleCrash()
+	defer sc.workqueue.ShutDown()
+
+	logger := logutils.FromContext(ctx)
+	logger.Info("Waiting for informer caches to sync")
+	if ok := cache.WaitForNamedCacheSync("Service", ctx.Done(), sc.serviceSynced, sc.podSynced); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	logger.Info("Starting workers")
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, sc.runWorker, time.Second)
+	}
+
+	<-ctx.Done()
+	logger.Info("Shutting down workers")
+
+	return nil
+}
+
+func (sc *ServiceController) runWorker(ctx context.Context) {
+	for sc.processNextWorkItem(ctx) {
+	}
+}
+
+func (sc *ServiceController) processNextWorkItem(ctx context.Context) bool {
+	obj, shutdown := sc.workqueue.Get()
+	if shutdown {
+		return false
+	}
+	defer sc.workqueue.Done(obj)
+
+	logger := logutils.FromContext(ctx).With(
+		zap.String("Service", obj.(string)),
+		zap.String("Operation", "PROCESS"),
+	)
+
+	if err := sc.syncHandler(logutils.IntoContext(ctx, logger), obj.(string)); err != nil {
+		logger.Sugar().Warnf("Failed to handle, requeuing: %v", err)
+		sc.workqueue.AddRateLimited(obj)
+		return true
+	}
+	sc.workqueue.Forget(obj)
+
+	return true
+}
+
+func (sc *ServiceController) syncHandler(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil
+	}
+
+	service, err := sc.serviceLister.Services(namespace).Get(name)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	var endpoints []spiderpoolv1.SpiderEndpoint
+	if len(service.Spec.Selector) != 0 {
+		pods, err := sc.podLister.Pods(namespace).List(labels.SelectorFromSet(service.Spec.Selector))
+		if err != nil {
+			return err
+		}
+
+		for _, pod := range pods {
+			if pod.DeletionTimestamp != nil {
+				continue
+			}
+
+			var endpoint spiderpoolv1.SpiderEndpoint
+			if err := sc.client.Get(ctx, apitypes.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &endpoint); err != nil {
+				if apierrors.IsNotFound(err) {
+					continue
+				}
+				return err
+			}
+			if endpoint.DeletionTimestamp == nil {
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+	}
+
+	backendIPs := strings.Join(GetBackendIPs(endpoints), ",")
+	current, ok := service.Annotations[constant.AnnoServiceBackendIPs]
+	if current == backendIPs && (ok || backendIPs == "") {
+		return nil
+	}
+
+	serviceCopy := service.DeepCopy()
+	if backendIPs == "" {
+		delete(serviceCopy.Annotations, constant.AnnoServiceBackendIPs)
+	} else {
+		if serviceCopy.Annotations == nil {
+			serviceCopy.Annotations = map[string]string{}
+		}
+		serviceCopy.Annotations[constant.AnnoServiceBackendIPs] = backendIPs
+	}
+
+	if err := sc.client.Update(ctx, serviceCopy); err != nil {
+		return err
+	}
+	logutils.FromContext(ctx).Sugar().Infof("Update the backend IP addresses of Service to '%s'", backendIPs)
+
+	return nil
+}
+
+// GetBackendIPs returns the IP addresses of the current IP allocations of
+// the Endpoints in order, without the prefix lengths.
+func GetBackendIPs(endpoints []spiderpoolv1.SpiderEndpoint) []string {
+	set := map[string]struct{}{}
+	for _, endpoint := range endpoints {
+		if endpoint.Status.Current == nil {
+			continue
+		}
+
+		for _, detail := range endpoint.Status.Current.IPs {
+			for _, addr := range []*string{detail.IPv4, detail.IPv6} {
+				if addr == nil {
+					continue
+				}
+				ip, _, err := net.ParseCIDR(*addr)
+				if err != nil {
+					ip = net.ParseIP(*addr)
+				}
+				if ip != nil {
+					set[ip.String()] = struct{}{}
+				}
+			}
+		}
+	}
+
+	ips := make([]string, 0, len(set))
+	for ip := range set {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+
+	return ips
+}
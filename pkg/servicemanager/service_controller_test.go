@@ -0,0 +1,62 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package servicemanager_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/pointer"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/servicemanager"
+)
+
+var _ = Describe("ServiceController", Label("service_controller_test"), func() {
+	Describe("New ServiceController", func() {
+		It("inputs nil client", func() {
+			controller, err := servicemanager.NewServiceController(servicemanager.ServiceControllerConfig{}, nil)
+			Expect(err).To(MatchError(constant.ErrMissingRequiredParam))
+			Expect(controller).To(BeNil())
+		})
+	})
+
+	Describe("GetBackendIPs", func() {
+		It("returns the sorted IP addresses of the current allocations", func() {
+			endpoints := []spiderpoolv1.SpiderEndpoint{
+				{
+					Status: spiderpoolv1.WorkloadEndpointStatus{
+						Current: &spiderpoolv1.PodIPAllocation{
+							IPs: []spiderpoolv1.IPAllocationDetail{
+								{NIC: "eth0", IPv4: pointer.String("172.18.40.10/24"), IPv6: pointer.String("abcd:1234::a/120")},
+								{NIC: "net1", IPv4: pointer.String("172.18.41.10/24")},
+							},
+						},
+					},
+				},
+				{
+					Status: spiderpoolv1.WorkloadEndpointStatus{
+						Current: &spiderpoolv1.PodIPAllocation{
+							IPs: []spiderpoolv1.IPAllocationDetail{
+								{NIC: "eth0", IPv4: pointer.String("172.18.40.2/24")},
+							},
+						},
+					},
+				},
+				{},
+			}
+
+			Expect(servicemanager.GetBackendIPs(endpoints)).To(Equal([]string{
+				"172.18.40.10",
+				"172.18.40.2",
+				"172.18.41.10",
+				"abcd:1234::a",
+			}))
+		})
+
+		It("returns empty without the current allocations", func() {
+			Expect(servicemanager.GetBackendIPs(nil)).To(BeEmpty())
+		})
+	})
+})
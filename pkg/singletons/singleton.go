@@ -16,3 +16,12 @@ func InitClusterDefaultPool(clusterDefaultV4IPPool, clusterDefaultV6IPPool, clus
 	ClusterDefaultPool.ClusterDefaultIPv6Subnet = clusterDefaultV6Subnet
 	ClusterDefaultPool.ClusterSubnetDefaultFlexibleIPNumber = flexibleIPNumber
 }
+
+// ThirdPartyControllers is a singleton recording the third-party controllers
+// which SpiderSubnet creates auto-created IPPools for
+var ThirdPartyControllers []types.ThirdPartyController
+
+// InitThirdPartyControllers will init ThirdPartyControllers with the given params
+func InitThirdPartyControllers(controllers []types.ThirdPartyController) {
+	ThirdPartyControllers = controllers
+}
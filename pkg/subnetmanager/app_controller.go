@@ -15,20 +15,33 @@ import (
 	"go.uber.org/zap"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	apitypes "k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	appslisters "k8s.io/client-go/listers/apps/v1"
 	batchlisters "k8s.io/client-go/listers/batch/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/spidernet-io/spiderpool/pkg/constant"
 	"github.com/spidernet-io/spiderpool/pkg/election"
+	"github.com/spidernet-io/spiderpool/pkg/event"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
 	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
 	"github.com/spidernet-io/spiderpool/pkg/logutils"
 	metrics "github.com/spidernet-io/spiderpool/pkg/metric"
@@ -62,6 +75,12 @@ type SubnetAppController struct {
 	cronJobLister   batchlisters.CronJobLister
 	cronJobInformer cache.SharedIndexInformer
 
+	nodeLister   corelisters.NodeLister
+	nodeInformer cache.SharedIndexInformer
+
+	// thirdPartyInformers are the informers of the third-party controllers, keyed by their kinds
+	thirdPartyInformers map[string]cache.SharedIndexInformer
+
 	SubnetAppControllerConfig
 }
 
@@ -72,9 +91,16 @@ type SubnetAppControllerConfig struct {
 	MaxWorkqueueLength            int
 	WorkQueueRequeueDelayDuration time.Duration
 	LeaderRetryElectGap           time.Duration
+	ThirdPartyControllers         []types.ThirdPartyController
+	// EnableCapacityCheck records a warning event on the application whose
+	// SpiderSubnet doesn't have enough free IPs for its replicas.
+	EnableCapacityCheck bool
+	// EnableDaemonSetNodeSizing sizes the auto-created IPPools of DaemonSets
+	// by the nodes they run on, and resizes them once nodes change.
+	EnableDaemonSetNodeSizing bool
 }
 
-func (sac *SubnetAppController) SetupInformer(ctx context.Context, client kubernetes.Interface, controllerLeader election.SpiderLeaseElector) error {
+func (sac *SubnetAppController) SetupInformer(ctx context.Context, client kubernetes.Interface, dynamicClient dynamic.Interface, controllerLeader election.SpiderLeaseElector) error {
 	if controllerLeader == nil {
 		return fmt.Errorf("failed to start SpiderSubnet App informer, controller leader must be specified")
 	}
@@ -114,7 +140,11 @@ func (sac *SubnetAppController) SetupInformer(ctx context.Context, client kubern
 			informerLogger.Info("create SpiderSubnet App informer")
 			factory := kubeinformers.NewSharedInformerFactory(client, 0)
 			sac.addEventHandlers(factory)
+			sac.addThirdPartyEventHandlers(innerCtx, dynamicClient)
 			factory.Start(innerCtx.Done())
+			for _, informer := range sac.thirdPartyInformers {
+				go informer.Run(innerCtx.Done())
+			}
 			err := sac.Run(innerCtx.Done())
 			if nil != err {
 				informerLogger.Sugar().Errorf("failed to run SpiderSubnet App controller, error: %v", err)
@@ -126,8 +156,8 @@ func (sac *SubnetAppController) SetupInformer(ctx context.Context, client kubern
 	return nil
 }
 
-// ControllerAddOrUpdateHandler serves for kubernetes original controller applications(such as: Deployment,ReplicaSet,Job...),
-// to create a new IPPool or scale the IPPool
+// ControllerAddOrUpdateHandler serves for kubernetes original controller applications(such as: Deployment,ReplicaSet,Job...)
+// and the configured third-party controller applications, to create a new IPPool or scale the IPPool
 func (sac *SubnetAppController) ControllerAddOrUpdateHandler() controllers.AppInformersAddOrUpdateFunc {
 	return func(ctx context.Context, oldObj, newObj interface{}) error {
 		log := logutils.FromContext(ctx)
@@ -276,6 +306,20 @@ func (sac *SubnetAppController) ControllerAddOrUpdateHandler() controllers.AppIn
 				return nil
 			}
 
+			// The Pods of the finished Job no longer need IP addresses, so
+			// reclaim its auto-created IPPools at once instead of waiting
+			// for the Job to be deleted, which may be kept for a long time.
+			if controllers.IsJobFinished(newObject) {
+				if oldObj != nil && controllers.IsJobFinished(oldObj.(*batchv1.Job)) {
+					return nil
+				}
+				// The finished Job is never redeployed, and its IPPools wouldn't be
+				// reclaimed by the IPPool informer after a delay as it still exists.
+				log.Info("Job has finished, try to clean up its auto-created IPPools")
+				return sac.tryToCleanUpLegacyIPPools(logutils.IntoContext(ctx, log), newObject,
+					map[string]string{constant.AnnoSpiderSubnetReclaimPolicy: constant.ReclaimPolicyImmediate})
+			}
+
 			newAppReplicas = controllers.CalculateJobPodNum(newObject.Spec.Parallelism, newObject.Spec.Completions)
 			newSubnetConfig, err = controllers.GetSubnetAnnoConfig(newObject.Spec.Template.Annotations, log)
 			if nil != err {
@@ -356,7 +400,7 @@ func (sac *SubnetAppController) ControllerAddOrUpdateHandler() controllers.AppIn
 				return nil
 			}
 
-			newAppReplicas = int(newObject.Status.DesiredNumberScheduled)
+			newAppReplicas = sac.daemonSetReplicas(newObject, log)
 			newSubnetConfig, err = controllers.GetSubnetAnnoConfig(newObject.Spec.Template.Annotations, log)
 			if nil != err {
 				return fmt.Errorf("failed to get app subnet configuration, error: %v", err)
@@ -372,13 +416,68 @@ func (sac *SubnetAppController) ControllerAddOrUpdateHandler() controllers.AppIn
 
 			if oldObj != nil {
 				oldDaemonSet := oldObj.(*appsv1.DaemonSet)
-				oldAppReplicas = int(oldDaemonSet.Status.DesiredNumberScheduled)
+				oldAppReplicas = sac.daemonSetReplicas(oldDaemonSet, log)
 				oldSubnetConfig, err = controllers.GetSubnetAnnoConfig(oldDaemonSet.Spec.Template.Annotations, log)
 				if nil != err {
 					return fmt.Errorf("failed to get old app subnet configuration, error: %v", err)
 				}
 			}
 
+		case *unstructured.Unstructured:
+			appKind = newObject.GetKind()
+			log = log.With(zap.String(appKind, fmt.Sprintf("%s/%s", newObject.GetNamespace(), newObject.GetName())))
+
+			// no need reconcile for HostNetwork application
+			hostNetwork, _, _ := unstructured.NestedBool(newObject.UnstructuredContent(), "spec", "template", "spec", "hostNetwork")
+			if hostNetwork {
+				log.Debug("HostNetwork mode, we would not create or scale IPPool for it")
+				return nil
+			}
+
+			// check the app whether is the top controller or not
+			owner := metav1.GetControllerOf(newObject)
+			if owner != nil {
+				log.Sugar().Debugf("app has a owner '%s/%s', we would not create or scale IPPool for it", owner.Kind, owner.Name)
+				return nil
+			}
+
+			newAppReplicas, err = controllers.GetThirdPartyAppReplicas(newObject)
+			if nil != err {
+				return fmt.Errorf("failed to get app replicas, error: %v", err)
+			}
+			podAnno, err := controllers.GetThirdPartyAppPodAnnotations(newObject)
+			if nil != err {
+				return err
+			}
+			newSubnetConfig, err = controllers.GetSubnetAnnoConfig(podAnno, log)
+			if nil != err {
+				return fmt.Errorf("failed to get app subnet configuration, error: %v", err)
+			}
+
+			// default IPAM mode
+			if controllers.IsDefaultIPPoolMode(newSubnetConfig) {
+				log.Debug("app will use default IPAM mode, because there's no subnet annotation or no ClusterDefaultSubnets")
+				return nil
+			}
+
+			app = newObject.DeepCopy()
+
+			if oldObj != nil {
+				oldApp := oldObj.(*unstructured.Unstructured)
+				oldAppReplicas, err = controllers.GetThirdPartyAppReplicas(oldApp)
+				if nil != err {
+					return fmt.Errorf("failed to get old app replicas, error: %v", err)
+				}
+				oldPodAnno, err := controllers.GetThirdPartyAppPodAnnotations(oldApp)
+				if nil != err {
+					return err
+				}
+				oldSubnetConfig, err = controllers.GetSubnetAnnoConfig(oldPodAnno, log)
+				if nil != err {
+					return fmt.Errorf("failed to get old app subnet configuration, error: %v", err)
+				}
+			}
+
 		default:
 			return fmt.Errorf("unrecognized application: %+v", newObj)
 		}
@@ -437,11 +536,53 @@ func (sac *SubnetAppController) addEventHandlers(factory kubeinformers.SharedInf
 	sac.cronJobInformer = factory.Batch().V1().CronJobs().Informer()
 	sac.appController.AddCronJobHandler(sac.cronJobInformer)
 
+	if sac.EnableDaemonSetNodeSizing {
+		sac.nodeLister = factory.Core().V1().Nodes().Lister()
+		sac.nodeInformer = factory.Core().V1().Nodes().Informer()
+		sac.nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				sac.enqueueDaemonSets()
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				if nodeSchedulingChanged(oldObj.(*corev1.Node), newObj.(*corev1.Node)) {
+					sac.enqueueDaemonSets()
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				sac.enqueueDaemonSets()
+			},
+		})
+	}
+
 	// Once we lost the leader but get leader later, we have to use a new workqueue.
 	// Because the former workqueue was already shut down and wouldn't be re-start forever.
 	sac.workQueue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Application-Controllers")
 }
 
+// addThirdPartyEventHandlers creates the informers of the third-party controllers with the dynamic client.
+func (sac *SubnetAppController) addThirdPartyEventHandlers(ctx context.Context, dynamicClient dynamic.Interface) {
+	sac.thirdPartyInformers = make(map[string]cache.SharedIndexInformer, len(sac.ThirdPartyControllers))
+	for _, c := range sac.ThirdPartyControllers {
+		resourceClient := dynamicClient.Resource(controllers.ThirdPartyControllerGVR(c))
+		informer := cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+					return resourceClient.List(ctx, options)
+				},
+				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+					return resourceClient.Watch(ctx, options)
+				},
+			},
+			&unstructured.Unstructured{},
+			0,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+		)
+
+		sac.thirdPartyInformers[c.Kind] = informer
+		sac.appController.AddThirdPartyHandler(c.Kind, informer)
+	}
+}
+
 // appWorkQueueKey involves application object meta namespaceKey and application kind
 type appWorkQueueKey struct {
 	MetaNamespaceKey string
@@ -474,19 +615,68 @@ func (sac *SubnetAppController) enqueueApp(ctx context.Context, obj interface{},
 	log.Sugar().Debugf("added '%v' to application controller workequeue", appKey)
 }
 
+// daemonSetReplicas returns the number of the nodes the DaemonSet runs on, it
+// falls back to the DaemonSet status without the node sizing.
+func (sac *SubnetAppController) daemonSetReplicas(daemonSet *appsv1.DaemonSet, log *zap.Logger) int {
+	if sac.nodeLister == nil {
+		return int(daemonSet.Status.DesiredNumberScheduled)
+	}
+
+	nodes, err := sac.nodeLister.List(labels.Everything())
+	if nil != err {
+		log.Sugar().Warnf("failed to list nodes, use the desired number scheduled of DaemonSet instead: %v", err)
+		return int(daemonSet.Status.DesiredNumberScheduled)
+	}
+
+	return controllers.CountDaemonSetNodes(daemonSet, nodes)
+}
+
+// enqueueDaemonSets inserts all DaemonSets to the workQueue, so that their
+// auto-created IPPools are resized by the nodes they run on.
+func (sac *SubnetAppController) enqueueDaemonSets() {
+	daemonSets, err := sac.daemonSetLister.List(labels.Everything())
+	if nil != err {
+		informerLogger.Sugar().Errorf("failed to list DaemonSets: %v", err)
+		return
+	}
+
+	ctx := logutils.IntoContext(context.TODO(), informerLogger)
+	for _, daemonSet := range daemonSets {
+		if daemonSet.Spec.Template.Spec.HostNetwork || metav1.GetControllerOf(daemonSet) != nil {
+			continue
+		}
+		sac.enqueueApp(ctx, daemonSet, constant.KindDaemonSet)
+	}
+}
+
+// nodeSchedulingChanged reports whether the change of the node affects which
+// DaemonSets run on it.
+func nodeSchedulingChanged(oldNode, newNode *corev1.Node) bool {
+	return !reflect.DeepEqual(oldNode.Labels, newNode.Labels) ||
+		!reflect.DeepEqual(oldNode.Spec.Taints, newNode.Spec.Taints) ||
+		oldNode.Spec.Unschedulable != newNode.Spec.Unschedulable
+}
+
 func (sac *SubnetAppController) Run(stopCh <-chan struct{}) error {
 	defer utilruntime.HandleCrash()
 	defer sac.workQueue.ShutDown()
 
 	informerLogger.Debug("Waiting for application informers caches to sync")
-	ok := cache.WaitForCacheSync(stopCh,
+	cacheSyncs := []cache.InformerSynced{
 		sac.deploymentInformer.HasSynced,
 		sac.replicaSetInformer.HasSynced,
 		sac.daemonSetInformer.HasSynced,
 		sac.statefulSetInformer.HasSynced,
 		sac.jobInformer.HasSynced,
 		sac.cronJobInformer.HasSynced,
-	)
+	}
+	if sac.nodeInformer != nil {
+		cacheSyncs = append(cacheSyncs, sac.nodeInformer.HasSynced)
+	}
+	for _, informer := range sac.thirdPartyInformers {
+		cacheSyncs = append(cacheSyncs, informer.HasSynced)
+	}
+	ok := cache.WaitForCacheSync(stopCh, cacheSyncs...)
 	if !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
@@ -628,7 +818,7 @@ func (sac *SubnetAppController) syncHandler(appKey appWorkQueueKey, log *zap.Log
 
 		podAnno = daemonSet.Spec.Template.Annotations
 		podSelector = daemonSet.Spec.Selector
-		appReplicas = int(daemonSet.Status.DesiredNumberScheduled)
+		appReplicas = sac.daemonSetReplicas(daemonSet, log)
 		app = daemonSet.DeepCopy()
 
 	case constant.KindStatefulSet:
@@ -656,6 +846,13 @@ func (sac *SubnetAppController) syncHandler(appKey appWorkQueueKey, log *zap.Log
 			return err
 		}
 
+		// The auto-created IPPools of the finished Job are reclaimed, don't
+		// create them again.
+		if controllers.IsJobFinished(job) {
+			log.Debug("Job has finished, no need to create or scale IPPool for it")
+			return nil
+		}
+
 		podAnno = job.Spec.Template.Annotations
 		podSelector = job.Spec.Selector
 		appReplicas = controllers.CalculateJobPodNum(job.Spec.Parallelism, job.Spec.Completions)
@@ -672,12 +869,39 @@ func (sac *SubnetAppController) syncHandler(appKey appWorkQueueKey, log *zap.Log
 		}
 
 		podAnno = cronJob.Spec.JobTemplate.Spec.Template.Annotations
-		podSelector = cronJob.Spec.JobTemplate.Spec.Selector
+		podSelector = controllers.GetCronJobPodSelector(cronJob)
 		appReplicas = controllers.CalculateJobPodNum(cronJob.Spec.JobTemplate.Spec.Parallelism, cronJob.Spec.JobTemplate.Spec.Completions)
 		app = cronJob.DeepCopy()
 
 	default:
-		return fmt.Errorf("%w: unexpected appWorkQueueKey in workQueue '%+v'", constant.ErrWrongInput, appKey)
+		informer, ok := sac.thirdPartyInformers[appKey.AppKind]
+		if !ok {
+			return fmt.Errorf("%w: unexpected appWorkQueueKey in workQueue '%+v'", constant.ErrWrongInput, appKey)
+		}
+
+		obj, exists, err := informer.GetIndexer().GetByKey(appKey.MetaNamespaceKey)
+		if nil != err {
+			return err
+		}
+		if !exists {
+			log.Sugar().Debugf("application in work queue no longer exists")
+			return nil
+		}
+
+		thirdPartyApp := obj.(*unstructured.Unstructured)
+		podAnno, err = controllers.GetThirdPartyAppPodAnnotations(thirdPartyApp)
+		if nil != err {
+			return fmt.Errorf("%w: %v", constant.ErrWrongInput, err)
+		}
+		podSelector, err = controllers.GetThirdPartyAppPodSelector(thirdPartyApp)
+		if nil != err {
+			return fmt.Errorf("%w: %v", constant.ErrWrongInput, err)
+		}
+		appReplicas, err = controllers.GetThirdPartyAppReplicas(thirdPartyApp)
+		if nil != err {
+			return fmt.Errorf("%w: %v", constant.ErrWrongInput, err)
+		}
+		app = thirdPartyApp.DeepCopy()
 	}
 
 	subnetConfig, err = controllers.GetSubnetAnnoConfig(podAnno, log)
@@ -709,21 +933,63 @@ func (sac *SubnetAppController) createOrMarkIPPool(ctx context.Context, podSubne
 	podController types.PodTopController, podSelector *metav1.LabelSelector, appReplicas int) error {
 	log := logutils.FromContext(ctx)
 
+	poolIPNum := func(ipVersion types.IPVersion) (int, error) {
+		if controllers.IsPinnedIPs(&podSubnetConfig) {
+			return controllers.GetPinnedIPNumber(ipVersion, controllers.GetPinnedIPs(&podSubnetConfig, ipVersion))
+		}
+		if podSubnetConfig.FlexibleIPNum != nil {
+			return appReplicas + *(podSubnetConfig.FlexibleIPNum), nil
+		}
+
+		return podSubnetConfig.AssignIPNum, nil
+	}
+
 	// retrieve application pools
 	fn := func(poolList spiderpoolv1.SpiderIPPoolList, subnetName string, ipVersion types.IPVersion, ifName string, matchLabel client.MatchingLabels) (err error) {
-		var ipNum int
-		if podSubnetConfig.FlexibleIPNum != nil {
-			ipNum = appReplicas + *(podSubnetConfig.FlexibleIPNum)
-		} else {
-			ipNum = podSubnetConfig.AssignIPNum
+		pinnedIPs := controllers.GetPinnedIPs(&podSubnetConfig, ipVersion)
+		ipNum, err := poolIPNum(ipVersion)
+		if nil != err {
+			return err
+		}
+
+		// flag the application whose SpiderSubnet is short of free IPs, the dual-stack pair has been checked
+		if sac.EnableCapacityCheck && !podSubnetConfig.DualStack {
+			var subnet *spiderpoolv1.SpiderSubnet
+			subnet, err = sac.subnetMgr.GetSubnetByName(ctx, subnetName)
+			if nil != err {
+				return err
+			}
+			sac.flagInsufficientSubnetIPs(ctx, podController, sac.checkSubnetCapacity(ctx, podController, subnet, ifName, ipNum))
 		}
 
 		// verify whether the pool IPs need to be expanded or not
 		if len(poolList.Items) == 0 {
 			log.Sugar().Debugf("there's no 'IPv%d' IPPoolList retrieved from SpiderSubent '%s' with matchLabel '%v'", ipVersion, subnetName, matchLabel)
+			// adopt the auto-created IPPool of the application whose labels were drifted
+			var pool *spiderpoolv1.SpiderIPPool
+			pool, err = sac.adoptOrphanIPPool(ctx, podController, podSelector, subnetName, ipVersion, ifName, podSubnetConfig.ReclaimIPPool, matchLabel)
+			if nil != err {
+				return err
+			}
+			if pool != nil {
+				_, err = sac.subnetMgr.CheckScaleIPPool(ctx, pool, subnetName, ipNum)
+				return err
+			}
+
+			// adopt the IPPool of the deleted application with the same name, which
+			// is retained by the Delayed reclaim policy, for the redeployed application
+			pool, err = sac.adoptDelayedIPPool(ctx, podController, podSelector, matchLabel)
+			if nil != err {
+				return err
+			}
+			if pool != nil {
+				_, err = sac.subnetMgr.CheckScaleIPPool(ctx, pool, subnetName, ipNum)
+				return err
+			}
+
 			// create an empty IPPool and mark the desired IP number when the subnet name was specified,
 			// and the IPPool informer will implement the scale action
-			_, err = sac.subnetMgr.AllocateEmptyIPPool(ctx, subnetName, podController, podSelector, ipNum, ipVersion, podSubnetConfig.ReclaimIPPool, ifName)
+			_, err = sac.subnetMgr.AllocateEmptyIPPool(ctx, subnetName, podController, podSelector, ipNum, pinnedIPs, ipVersion, podSubnetConfig.ReclaimIPPool, ifName)
 		} else if len(poolList.Items) == 1 {
 			pool := poolList.Items[0]
 			log.Sugar().Debugf("found SpiderSubnet '%s' IPPool '%s' with matchLabel '%v', check it whether need to be scaled", subnetName, pool.Name, matchLabel)
@@ -744,6 +1010,19 @@ func (sac *SubnetAppController) createOrMarkIPPool(ctx context.Context, podSubne
 			return fmt.Errorf("IPv6 SpiderSubnet not specified when configuration enableIPv6 is on")
 		}
 
+		// the auto-created IPPools of the dual-stack SpiderSubnet pair are created or scaled together
+		if podSubnetConfig.DualStack && sac.EnableIPv4 && sac.EnableIPv6 {
+			ipNum, err := poolIPNum(constant.IPv4)
+			if nil != err {
+				return err
+			}
+			err = sac.checkDualStackCapacity(ctx, podController, item, ipNum)
+			if nil != err {
+				sac.flagInsufficientSubnetIPs(ctx, podController, err)
+				return err
+			}
+		}
+
 		var errV4, errV6 error
 		var wg sync.WaitGroup
 		if sac.EnableIPv4 && len(item.IPv4) != 0 {
@@ -751,7 +1030,6 @@ func (sac *SubnetAppController) createOrMarkIPPool(ctx context.Context, podSubne
 			go func() {
 				defer wg.Done()
 
-				var v4PoolList spiderpoolv1.SpiderIPPoolList
 				matchLabel := client.MatchingLabels{
 					constant.LabelIPPoolOwnerApplicationUID: string(podController.UID),
 					constant.LabelIPPoolOwnerSpiderSubnet:   item.IPv4[0],
@@ -759,12 +1037,13 @@ func (sac *SubnetAppController) createOrMarkIPPool(ctx context.Context, podSubne
 					constant.LabelIPPoolVersion:             constant.LabelIPPoolVersionV4,
 					constant.LabelIPPoolInterface:           item.Interface,
 				}
-				errV4 = sac.client.List(ctx, &v4PoolList, matchLabel)
-				if nil != errV4 {
+				v4PoolList, err := sac.subnetMgr.ListAutoIPPools(ctx, matchLabel)
+				if nil != err {
+					errV4 = err
 					return
 				}
 
-				errV4 = fn(v4PoolList, item.IPv4[0], constant.IPv4, item.Interface, matchLabel)
+				errV4 = fn(*v4PoolList, item.IPv4[0], constant.IPv4, item.Interface, matchLabel)
 			}()
 		}
 
@@ -773,7 +1052,6 @@ func (sac *SubnetAppController) createOrMarkIPPool(ctx context.Context, podSubne
 			go func() {
 				defer wg.Done()
 
-				var v6PoolList spiderpoolv1.SpiderIPPoolList
 				matchLabel := client.MatchingLabels{
 					constant.LabelIPPoolOwnerApplicationUID: string(podController.UID),
 					constant.LabelIPPoolOwnerSpiderSubnet:   item.IPv6[0],
@@ -781,12 +1059,13 @@ func (sac *SubnetAppController) createOrMarkIPPool(ctx context.Context, podSubne
 					constant.LabelIPPoolVersion:             constant.LabelIPPoolVersionV6,
 					constant.LabelIPPoolInterface:           item.Interface,
 				}
-				errV6 = sac.client.List(ctx, &v6PoolList, matchLabel)
-				if nil != errV6 {
+				v6PoolList, err := sac.subnetMgr.ListAutoIPPools(ctx, matchLabel)
+				if nil != err {
+					errV6 = err
 					return
 				}
 
-				errV6 = fn(v6PoolList, item.IPv6[0], constant.IPv6, item.Interface, matchLabel)
+				errV6 = fn(*v6PoolList, item.IPv6[0], constant.IPv6, item.Interface, matchLabel)
 			}()
 		}
 
@@ -820,6 +1099,258 @@ func (sac *SubnetAppController) createOrMarkIPPool(ctx context.Context, podSubne
 	return nil
 }
 
+// checkDualStackCapacity checks whether both SpiderSubnets of the dual-stack pair have
+// enough free IPs for the auto-created IPPools of the application to reach the IP number,
+// so that neither IPPool is created or scaled if the other one can't be.
+func (sac *SubnetAppController) checkDualStackCapacity(ctx context.Context, podController types.PodTopController, item types.AnnoSubnetItem, ipNum int) error {
+	subnetPair := []struct {
+		subnetName string
+		ipVersion  types.IPVersion
+	}{
+		{subnetName: item.IPv4[0], ipVersion: constant.IPv4},
+		{subnetName: item.IPv6[0], ipVersion: constant.IPv6},
+	}
+
+	for _, p := range subnetPair {
+		subnet, err := sac.subnetMgr.GetSubnetByName(ctx, p.subnetName)
+		if nil != err {
+			return err
+		}
+		if pointer.Int64Deref(subnet.Spec.IPVersion, 0) != p.ipVersion {
+			return fmt.Errorf("%w: SpiderSubnet '%s' of the dual-stack pair isn't an IPv%d SpiderSubnet", constant.ErrWrongInput, subnet.Name, p.ipVersion)
+		}
+
+		err = sac.checkSubnetCapacity(ctx, podController, subnet, item.Interface, ipNum)
+		if nil != err {
+			return fmt.Errorf("failed to check the dual-stack pair: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// checkSubnetCapacity checks whether the SpiderSubnet has enough free IPs for the
+// auto-created IPPool of the application to reach the IP number, constant.ErrIPUsedOut
+// is returned if it doesn't. The SpiderSubnet with fallback subnets is skipped, whose
+// auto-created IPPool may borrow IPs.
+func (sac *SubnetAppController) checkSubnetCapacity(ctx context.Context, podController types.PodTopController, subnet *spiderpoolv1.SpiderSubnet, ifName string, ipNum int) error {
+	if len(subnet.Spec.FallbackSubnets) != 0 {
+		return nil
+	}
+
+	versionLabel := constant.LabelIPPoolVersionV4
+	if pointer.Int64Deref(subnet.Spec.IPVersion, 0) == constant.IPv6 {
+		versionLabel = constant.LabelIPPoolVersionV6
+	}
+	poolList, err := sac.subnetMgr.ListAutoIPPools(ctx, client.MatchingLabels{
+		constant.LabelIPPoolOwnerApplicationUID: string(podController.UID),
+		constant.LabelIPPoolOwnerSpiderSubnet:   subnet.Name,
+		constant.LabelIPPoolOwnerApplication:    controllers.AppLabelValue(podController.Kind, podController.Namespace, podController.Name),
+		constant.LabelIPPoolVersion:             versionLabel,
+		constant.LabelIPPoolInterface:           ifName,
+	})
+	if nil != err {
+		return err
+	}
+
+	requiredIPNum := ipNum
+	for _, pool := range poolList.Items {
+		requiredIPNum -= int(pointer.Int64Deref(pool.Status.TotalIPCount, 0))
+	}
+	if requiredIPNum <= 0 {
+		return nil
+	}
+
+	freeIPs, err := controllers.GenSubnetFreeIPs(subnet)
+	if nil != err {
+		return fmt.Errorf("failed to generate SpiderSubnet '%s' free IPs, error: %v", subnet.Name, err)
+	}
+	if len(freeIPs) < requiredIPNum {
+		return fmt.Errorf("%w: SpiderSubnet '%s' has only %d free IPs, but %d more IPs are required for %s %s/%s",
+			constant.ErrIPUsedOut, subnet.Name, len(freeIPs), requiredIPNum, podController.Kind, podController.Namespace, podController.Name)
+	}
+
+	return nil
+}
+
+// flagInsufficientSubnetIPs records a warning event on the application if the
+// SpiderSubnet doesn't have enough free IPs for it, so that the failure is
+// noticed before its Pods fail to get IP addresses.
+func (sac *SubnetAppController) flagInsufficientSubnetIPs(ctx context.Context, podController types.PodTopController, err error) {
+	if !errors.Is(err, constant.ErrIPUsedOut) {
+		return
+	}
+
+	logutils.FromContext(ctx).Sugar().Warnf("Insufficient SpiderSubnet IPs: %v", err)
+	if app, ok := podController.APP.(runtime.Object); ok {
+		event.EventRecorder.Event(app, corev1.EventTypeWarning, constant.EventReasonInsufficientSubnetIPs, err.Error())
+	}
+}
+
+// adoptDelayedIPPool adopts the auto-created IPPool waiting to be reclaimed by the
+// Delayed reclaim policy, whose application has the same kind, namespace and name
+// as the given one, nil is returned if there's no such IPPool.
+func (sac *SubnetAppController) adoptDelayedIPPool(ctx context.Context, podController types.PodTopController,
+	podSelector *metav1.LabelSelector, matchLabel client.MatchingLabels) (*spiderpoolv1.SpiderIPPool, error) {
+	log := logutils.FromContext(ctx)
+
+	delayedLabel := client.MatchingLabels{constant.LabelIPPoolReclaimIPPool: constant.True}
+	for k, v := range matchLabel {
+		if k != constant.LabelIPPoolOwnerApplicationUID {
+			delayedLabel[k] = v
+		}
+	}
+
+	var poolList spiderpoolv1.SpiderIPPoolList
+	err := sac.client.List(ctx, &poolList, delayedLabel)
+	if nil != err {
+		return nil, err
+	}
+
+	for i := range poolList.Items {
+		pool := poolList.Items[i]
+		if pool.DeletionTimestamp != nil {
+			continue
+		}
+		if _, ok := ippoolmanager.GetIPPoolReclaimAfter(&pool); !ok {
+			continue
+		}
+
+		pool.Labels[constant.LabelIPPoolOwnerApplicationUID] = string(podController.UID)
+		delete(pool.Annotations, constant.AnnoIPPoolReclaimAfter)
+		pool.Spec.PodAffinity = podSelector
+		err = sac.client.Update(ctx, &pool)
+		if nil != err {
+			return nil, fmt.Errorf("failed to adopt IPPool '%s', error: %w", pool.Name, err)
+		}
+
+		log.Sugar().Infof("adopt IPPool '%s' waiting to be reclaimed by the Delayed reclaim policy", pool.Name)
+		return &pool, nil
+	}
+
+	return nil, nil
+}
+
+// adoptOrphanIPPool adopts the auto-created IPPool named after the application, which
+// can't be found with the labels since they were edited or dropped. The IPPool must be in
+// the same subnet as the SpiderSubnet or one of its fallback subnets, and its IP ranges
+// must belong to it. The labels and the owner reference are restored, nil is returned
+// if there's no such IPPool.
+func (sac *SubnetAppController) adoptOrphanIPPool(ctx context.Context, podController types.PodTopController, podSelector *metav1.LabelSelector,
+	subnetName string, ipVersion types.IPVersion, ifName string, reclaimIPPool bool, matchLabel client.MatchingLabels) (*spiderpoolv1.SpiderIPPool, error) {
+	log := logutils.FromContext(ctx)
+
+	var pool spiderpoolv1.SpiderIPPool
+	poolName := controllers.SubnetPoolName(podController.Kind, podController.Namespace, podController.Name, ipVersion, ifName, podController.UID)
+	err := sac.client.Get(ctx, apitypes.NamespacedName{Name: poolName}, &pool)
+	if nil != err {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if pool.DeletionTimestamp != nil {
+		return nil, fmt.Errorf("IPPool '%s' of the application is terminating", pool.Name)
+	}
+	if pointer.Int64Deref(pool.Spec.IPVersion, 0) != ipVersion {
+		return nil, fmt.Errorf("%w: IPPool '%s' named after the application isn't an IPv%d IPPool", constant.ErrWrongInput, pool.Name, ipVersion)
+	}
+
+	subnet, err := sac.subnetMgr.GetSubnetByName(ctx, subnetName)
+	if nil != err {
+		return nil, err
+	}
+	candidates := append([]string{subnet.Name}, subnet.Spec.FallbackSubnets...)
+
+	var ownerSubnet *spiderpoolv1.SpiderSubnet
+	for _, name := range candidates {
+		candidate := subnet
+		if name != subnet.Name {
+			candidate, err = sac.subnetMgr.GetSubnetByName(ctx, name)
+			if nil != err {
+				if apierrors.IsNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+		}
+
+		belong, err := ipPoolBelongsToSubnet(&pool, candidate)
+		if nil != err {
+			return nil, err
+		}
+		if belong {
+			ownerSubnet = candidate
+			break
+		}
+	}
+	if ownerSubnet == nil {
+		return nil, fmt.Errorf("%w: IPPool '%s' named after the application doesn't belong to SpiderSubnet '%s' or its fallback subnets",
+			constant.ErrWrongInput, pool.Name, subnetName)
+	}
+
+	if pool.Labels == nil {
+		pool.Labels = map[string]string{}
+	}
+	for k, v := range matchLabel {
+		pool.Labels[k] = v
+	}
+	pool.Labels[constant.LabelIPPoolOwnerSpiderSubnet] = ownerSubnet.Name
+	if ownerSubnet.Name != subnetName {
+		pool.Labels[constant.LabelIPPoolBorrowedBy] = subnetName
+	} else {
+		delete(pool.Labels, constant.LabelIPPoolBorrowedBy)
+	}
+	if reclaimIPPool {
+		pool.Labels[constant.LabelIPPoolReclaimIPPool] = constant.True
+	} else {
+		delete(pool.Labels, constant.LabelIPPoolReclaimIPPool)
+	}
+
+	if !metav1.IsControlledBy(&pool, ownerSubnet) {
+		var ownerReferences []metav1.OwnerReference
+		for _, ref := range pool.OwnerReferences {
+			if ref.Controller == nil || !*ref.Controller {
+				ownerReferences = append(ownerReferences, ref)
+			}
+		}
+		pool.OwnerReferences = ownerReferences
+		err = controllerutil.SetControllerReference(ownerSubnet, &pool, sac.client.Scheme())
+		if nil != err {
+			return nil, fmt.Errorf("failed to set SpiderIPPool %s owner reference with SpiderSubnet %s: %v", pool.Name, ownerSubnet.Name, err)
+		}
+	}
+	pool.Spec.PodAffinity = podSelector
+
+	err = sac.client.Update(ctx, &pool)
+	if nil != err {
+		return nil, fmt.Errorf("failed to adopt IPPool '%s', error: %w", pool.Name, err)
+	}
+
+	log.Sugar().Infof("adopt orphan IPPool '%s' of SpiderSubnet '%s'", pool.Name, ownerSubnet.Name)
+	event.EventRecorder.Eventf(&pool, corev1.EventTypeNormal, constant.EventReasonAdoptIPPool,
+		"Adopted by %s %s/%s with the labels restored", podController.Kind, podController.Namespace, podController.Name)
+
+	return &pool, nil
+}
+
+// ipPoolBelongsToSubnet reports whether the IPPool is in the same subnet as the
+// SpiderSubnet, and all its IP ranges belong to the SpiderSubnet.
+func ipPoolBelongsToSubnet(pool *spiderpoolv1.SpiderIPPool, subnet *spiderpoolv1.SpiderSubnet) (bool, error) {
+	if pool.Spec.Subnet != subnet.Spec.Subnet {
+		return false, nil
+	}
+
+	ipVersion := pointer.Int64Deref(subnet.Spec.IPVersion, 0)
+	poolIPs, err := spiderpoolip.ParseIPRanges(ipVersion, pool.Spec.IPs)
+	if nil != err {
+		return false, err
+	}
+	subnetIPs, err := spiderpoolip.ParseIPRanges(ipVersion, subnet.Spec.IPs)
+	if nil != err {
+		return false, err
+	}
+
+	return len(spiderpoolip.IPsDiffSet(poolIPs, subnetIPs, false)) == 0, nil
+}
+
 // hasSubnetConfigChanged checks whether application subnet configuration changed and the application replicas changed or not.
 // The second parameter newSubnetConfig must not be nil.
 func (sac *SubnetAppController) hasSubnetConfigChanged(ctx context.Context, oldSubnetConfig, newSubnetConfig *types.PodSubnetAnnoConfig,
@@ -852,6 +1383,7 @@ func (sac *SubnetAppController) ControllerDeleteHandler() controllers.APPInforme
 
 		var appKind string
 		var app metav1.Object
+		var podAnno map[string]string
 
 		switch object := obj.(type) {
 		case *appsv1.Deployment:
@@ -865,6 +1397,7 @@ func (sac *SubnetAppController) ControllerDeleteHandler() controllers.APPInforme
 			}
 
 			app = object
+			podAnno = object.Spec.Template.Annotations
 
 		case *appsv1.ReplicaSet:
 			appKind = constant.KindReplicaSet
@@ -877,6 +1410,7 @@ func (sac *SubnetAppController) ControllerDeleteHandler() controllers.APPInforme
 			}
 
 			app = object
+			podAnno = object.Spec.Template.Annotations
 
 		case *appsv1.StatefulSet:
 			appKind = constant.KindStatefulSet
@@ -889,6 +1423,7 @@ func (sac *SubnetAppController) ControllerDeleteHandler() controllers.APPInforme
 			}
 
 			app = object
+			podAnno = object.Spec.Template.Annotations
 
 		case *batchv1.Job:
 			appKind = constant.KindJob
@@ -901,6 +1436,7 @@ func (sac *SubnetAppController) ControllerDeleteHandler() controllers.APPInforme
 			}
 
 			app = object
+			podAnno = object.Spec.Template.Annotations
 
 		case *batchv1.CronJob:
 			appKind = constant.KindCronJob
@@ -913,6 +1449,7 @@ func (sac *SubnetAppController) ControllerDeleteHandler() controllers.APPInforme
 			}
 
 			app = object
+			podAnno = object.Spec.JobTemplate.Spec.Template.Annotations
 
 		case *appsv1.DaemonSet:
 			appKind = constant.KindDaemonSet
@@ -925,13 +1462,31 @@ func (sac *SubnetAppController) ControllerDeleteHandler() controllers.APPInforme
 			}
 
 			app = object
+			podAnno = object.Spec.Template.Annotations
+
+		case *unstructured.Unstructured:
+			appKind = object.GetKind()
+			log = log.With(zap.String(appKind, fmt.Sprintf("%s/%s", object.GetNamespace(), object.GetName())))
+
+			owner := metav1.GetControllerOf(object)
+			if owner != nil {
+				log.Sugar().Debugf("the application has a owner '%s/%s', we would not clean up legacy for it", owner.Kind, owner.Name)
+				return nil
+			}
+
+			app = object
+			anno, err := controllers.GetThirdPartyAppPodAnnotations(object)
+			if nil != err {
+				log.Sugar().Warnf("failed to get the Pod template annotations, reclaim the auto-created IPPools with the policy of SpiderSubnet: %v", err)
+			}
+			podAnno = anno
 
 		default:
 			return fmt.Errorf("unrecognized application: %+v", obj)
 		}
 
 		// clean up all legacy IPPools that matched with the application UID
-		err := sac.tryToCleanUpLegacyIPPools(logutils.IntoContext(ctx, log), app)
+		err := sac.tryToCleanUpLegacyIPPools(logutils.IntoContext(ctx, log), app, podAnno)
 		if nil != err {
 			log.Sugar().Errorf("failed to clean up legacy IPPool, error: %v", err)
 		}
@@ -940,7 +1495,9 @@ func (sac *SubnetAppController) ControllerDeleteHandler() controllers.APPInforme
 	}
 }
 
-func (sac *SubnetAppController) tryToCleanUpLegacyIPPools(ctx context.Context, app metav1.Object, labels ...client.MatchingLabels) error {
+// tryToCleanUpLegacyIPPools reclaims the auto-created IPPools of the application
+// with the reclaim policy of its Pod template annotations or the SpiderSubnet.
+func (sac *SubnetAppController) tryToCleanUpLegacyIPPools(ctx context.Context, app metav1.Object, podAnno map[string]string, labels ...client.MatchingLabels) error {
 	log := logutils.FromContext(ctx)
 
 	matchLabel := client.MatchingLabels{
@@ -965,13 +1522,11 @@ func (sac *SubnetAppController) tryToCleanUpLegacyIPPools(ctx context.Context, a
 
 		deletePool := func(pool *spiderpoolv1.SpiderIPPool) {
 			defer wg.Done()
-			err := sac.client.Delete(ctx, pool)
+			_, err := sac.subnetMgr.ReclaimIPPool(ctx, pool, podAnno)
 			if nil != err {
-				log.Sugar().Errorf("failed to delete IPPool '%s', error: %v", pool.Name, err)
+				log.Sugar().Errorf("failed to reclaim IPPool '%s', error: %v", pool.Name, err)
 				return
 			}
-
-			log.Sugar().Infof("delete IPPool '%s' successfully", pool.Name)
 		}
 
 		for i := range poolList.Items {
@@ -0,0 +1,518 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package subnetmanager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/event"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/subnetmanager/controllers"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// fakeSubnetManager serves the SpiderSubnets and auto-created IPPools from
+// memory, the other methods of SubnetManager are not expected to be called.
+type fakeSubnetManager struct {
+	SubnetManager
+	subnets map[string]*spiderpoolv1.SpiderSubnet
+	pools   []spiderpoolv1.SpiderIPPool
+
+	lock        sync.Mutex
+	reclaimed   []string
+	reclaimAnno map[string]string
+}
+
+func (sm *fakeSubnetManager) GetSubnetByName(ctx context.Context, subnetName string) (*spiderpoolv1.SpiderSubnet, error) {
+	subnet, ok := sm.subnets[subnetName]
+	if !ok {
+		return nil, apierrors.NewNotFound(spiderpoolv1.Resource(constant.SpiderSubnetKind), subnetName)
+	}
+
+	return subnet, nil
+}
+
+func (sm *fakeSubnetManager) ListAutoIPPools(ctx context.Context, matchLabels client.MatchingLabels) (*spiderpoolv1.SpiderIPPoolList, error) {
+	var poolList spiderpoolv1.SpiderIPPoolList
+	for _, pool := range sm.pools {
+		if pool.Labels[constant.LabelIPPoolOwnerSpiderSubnet] == matchLabels[constant.LabelIPPoolOwnerSpiderSubnet] {
+			poolList.Items = append(poolList.Items, pool)
+		}
+	}
+
+	return &poolList, nil
+}
+
+func (sm *fakeSubnetManager) ReclaimIPPool(ctx context.Context, pool *spiderpoolv1.SpiderIPPool, podAnno map[string]string) (bool, error) {
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
+	sm.reclaimed = append(sm.reclaimed, pool.Name)
+	sm.reclaimAnno = podAnno
+
+	return true, nil
+}
+
+var _ = Describe("SubnetAppController", Label("app_controller_test"), func() {
+	var sac *SubnetAppController
+	var subnetMgr *fakeSubnetManager
+	var podController types.PodTopController
+	BeforeEach(func() {
+		subnetMgr = &fakeSubnetManager{subnets: map[string]*spiderpoolv1.SpiderSubnet{}}
+		sac = &SubnetAppController{subnetMgr: subnetMgr}
+		podController = types.PodTopController{
+			Kind:      constant.KindDeployment,
+			Namespace: metav1.NamespaceDefault,
+			Name:      "app",
+			UID:       "uid",
+		}
+	})
+
+	newSubnet := func(name string, ipVersion types.IPVersion, subnet string, ips ...string) *spiderpoolv1.SpiderSubnet {
+		s := &spiderpoolv1.SpiderSubnet{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		s.Spec.IPVersion = pointer.Int64(ipVersion)
+		s.Spec.Subnet = subnet
+		s.Spec.IPs = ips
+		subnetMgr.subnets[name] = s
+		return s
+	}
+
+	Describe("multiple SpiderSubnets", func() {
+		It("requires the interface of each item", func() {
+			config, err := controllers.GetSubnetAnnoConfig(map[string]string{
+				constant.AnnoSpiderSubnets: `[{"interface":"eth0","ipv4":["v4-subnet"]},{"interface":"net1","ipv6":["v6-subnet"]}]`,
+			}, logutils.Logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.MultipleSubnets).To(HaveLen(2))
+
+			_, err = controllers.GetSubnetAnnoConfig(map[string]string{
+				constant.AnnoSpiderSubnets: `[{"interface":"eth0","ipv4":["v4-subnet"]},{"ipv6":["v6-subnet"]}]`,
+			}, logutils.Logger)
+			Expect(err).To(MatchError(ContainSubstring("empty interface name")))
+		})
+	})
+
+	Describe("dual-stack SpiderSubnet shorthand", func() {
+		It("parses the shorthand annotation", func() {
+			config, err := controllers.GetSubnetAnnoConfig(map[string]string{
+				constant.AnnoSpiderSubnetDualStack: `{"interface":"eth0","ipv4":"v4-subnet","ipv6":"v6-subnet","ippoolIPNumber":"+1"}`,
+			}, logutils.Logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.DualStack).To(BeTrue())
+			Expect(config.SingleSubnet).To(Equal(&types.AnnoSubnetItem{
+				Interface: "eth0",
+				IPv4:      []string{"v4-subnet"},
+				IPv6:      []string{"v6-subnet"},
+			}))
+			Expect(config.FlexibleIPNum).To(Equal(pointer.Int(1)))
+		})
+
+		It("requires both the IPv4 and IPv6 SpiderSubnets", func() {
+			_, err := controllers.ParseDualStackSubnetAnno(`{"ipv4":"v4-subnet"}`)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("refuses the shorthand annotation along with the full ones", func() {
+			for _, anno := range []string{constant.AnnoSpiderSubnet, constant.AnnoSpiderSubnets, constant.AnnoSpiderSubnetPoolIPNumber} {
+				_, err := controllers.GetSubnetAnnoConfig(map[string]string{
+					constant.AnnoSpiderSubnetDualStack: `{"ipv4":"v4-subnet","ipv6":"v6-subnet"}`,
+					anno:                               "",
+				}, logutils.Logger)
+				Expect(err).To(MatchError(ContainSubstring(anno)))
+			}
+		})
+
+		It("takes the IP number from the shorthand annotation", func() {
+			ipNum, ok, err := controllers.GetPoolIPNumberAnno(map[string]string{
+				constant.AnnoSpiderSubnetDualStack: `{"ipv4":"v4-subnet","ipv6":"v6-subnet","ippoolIPNumber":"3"}`,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(ipNum).To(Equal("3"))
+
+			_, ok, err = controllers.GetPoolIPNumberAnno(map[string]string{
+				constant.AnnoSpiderSubnetDualStack: `{"ipv4":"v4-subnet","ipv6":"v6-subnet"}`,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("checkDualStackCapacity", func() {
+		var item types.AnnoSubnetItem
+		BeforeEach(func() {
+			newSubnet("v4-subnet", constant.IPv4, "172.18.40.0/24", "172.18.40.1-172.18.40.4")
+			newSubnet("v6-subnet", constant.IPv6, "abcd:1234::/120", "abcd:1234::1-abcd:1234::2")
+			item = types.AnnoSubnetItem{Interface: "eth0", IPv4: []string{"v4-subnet"}, IPv6: []string{"v6-subnet"}}
+		})
+
+		It("passes if both SpiderSubnets have enough free IPs", func() {
+			Expect(sac.checkDualStackCapacity(context.TODO(), podController, item, 2)).To(Succeed())
+		})
+
+		It("fails if either SpiderSubnet runs out of free IPs", func() {
+			err := sac.checkDualStackCapacity(context.TODO(), podController, item, 3)
+			Expect(err).To(MatchError(constant.ErrIPUsedOut))
+			Expect(err).To(MatchError(ContainSubstring("SpiderSubnet 'v6-subnet'")))
+		})
+
+		It("counts the IPs already in the auto-created IPPools", func() {
+			pool := spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{
+				Name:   "auto-v6-pool",
+				Labels: map[string]string{constant.LabelIPPoolOwnerSpiderSubnet: "v6-subnet"},
+			}}
+			pool.Status.TotalIPCount = pointer.Int64(1)
+			subnetMgr.pools = append(subnetMgr.pools, pool)
+
+			Expect(sac.checkDualStackCapacity(context.TODO(), podController, item, 3)).To(Succeed())
+		})
+
+		It("skips the SpiderSubnet with fallback subnets", func() {
+			subnetMgr.subnets["v6-subnet"].Spec.FallbackSubnets = []string{"fallback-subnet"}
+			Expect(sac.checkDualStackCapacity(context.TODO(), podController, item, 3)).To(Succeed())
+		})
+
+		It("refuses the SpiderSubnets of wrong IP versions", func() {
+			item.IPv4, item.IPv6 = item.IPv6, item.IPv4
+			err := sac.checkDualStackCapacity(context.TODO(), podController, item, 1)
+			Expect(err).To(MatchError(constant.ErrWrongInput))
+		})
+	})
+	Describe("checkSubnetCapacity", func() {
+		var subnet *spiderpoolv1.SpiderSubnet
+		BeforeEach(func() {
+			subnet = newSubnet("v4-subnet", constant.IPv4, "172.18.40.0/24", "172.18.40.1-172.18.40.4")
+			subnet.Status.ControlledIPPools = spiderpoolv1.PoolIPPreAllocations{
+				"other-pool": {IPs: []string{"172.18.40.1"}},
+			}
+		})
+
+		It("passes if the SpiderSubnet has enough free IPs", func() {
+			Expect(sac.checkSubnetCapacity(context.TODO(), podController, subnet, "eth0", 3)).To(Succeed())
+		})
+
+		It("excludes the IPs controlled by other IPPools", func() {
+			err := sac.checkSubnetCapacity(context.TODO(), podController, subnet, "eth0", 4)
+			Expect(err).To(MatchError(constant.ErrIPUsedOut))
+			Expect(err).To(MatchError(ContainSubstring("only 3 free IPs, but 4 more IPs are required for Deployment default/app")))
+		})
+	})
+
+	Describe("flagInsufficientSubnetIPs", func() {
+		var recorder *record.FakeRecorder
+		BeforeEach(func() {
+			recorder = record.NewFakeRecorder(10)
+			defaultRecorder := event.EventRecorder
+			event.EventRecorder = recorder
+			DeferCleanup(func() {
+				event.EventRecorder = defaultRecorder
+			})
+
+			podController.APP = &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: podController.Namespace, Name: podController.Name}}
+		})
+
+		It("records a warning event on the application short of free IPs", func() {
+			sac.flagInsufficientSubnetIPs(context.TODO(), podController, fmt.Errorf("%w: SpiderSubnet 'v4-subnet'", constant.ErrIPUsedOut))
+			Expect(recorder.Events).To(Receive(HavePrefix(corev1.EventTypeWarning + " " + constant.EventReasonInsufficientSubnetIPs)))
+		})
+
+		It("ignores the other errors", func() {
+			sac.flagInsufficientSubnetIPs(context.TODO(), podController, nil)
+			sac.flagInsufficientSubnetIPs(context.TODO(), podController, constant.ErrWrongInput)
+			Expect(recorder.Events).NotTo(Receive())
+		})
+	})
+	Describe("adoptOrphanIPPool", func() {
+		var pool *spiderpoolv1.SpiderIPPool
+		var matchLabel client.MatchingLabels
+		BeforeEach(func() {
+			subnet := newSubnet("v4-subnet", constant.IPv4, "172.18.40.0/24", "172.18.40.1-172.18.40.10")
+			subnet.UID = "subnet-uid"
+			subnet.Spec.FallbackSubnets = []string{"fallback-subnet"}
+			fallback := newSubnet("fallback-subnet", constant.IPv4, "172.18.41.0/24", "172.18.41.1-172.18.41.10")
+			fallback.UID = "fallback-uid"
+
+			pool = &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{
+				Name:   controllers.SubnetPoolName(podController.Kind, podController.Namespace, podController.Name, constant.IPv4, "eth0", podController.UID),
+				Labels: map[string]string{constant.LabelIPPoolOwnerApplication: "drifted"},
+			}}
+			pool.Spec.IPVersion = pointer.Int64(constant.IPv4)
+			pool.Spec.Subnet = "172.18.40.0/24"
+			pool.Spec.IPs = []string{"172.18.40.1-172.18.40.2"}
+
+			matchLabel = client.MatchingLabels{
+				constant.LabelIPPoolOwnerApplicationUID: string(podController.UID),
+				constant.LabelIPPoolOwnerSpiderSubnet:   "v4-subnet",
+				constant.LabelIPPoolOwnerApplication:    controllers.AppLabelValue(podController.Kind, podController.Namespace, podController.Name),
+			}
+		})
+
+		JustBeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(spiderpoolv1.AddToScheme(scheme)).To(Succeed())
+			sac.client = fake.NewClientBuilder().WithScheme(scheme).WithObjects(pool).Build()
+		})
+
+		adopt := func() (*spiderpoolv1.SpiderIPPool, error) {
+			return sac.adoptOrphanIPPool(context.TODO(), podController, nil, "v4-subnet", constant.IPv4, "eth0", true, matchLabel)
+		}
+
+		It("restores the labels and the owner of the IPPool named after the application", func() {
+			adopted, err := adopt()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(adopted.Labels).To(HaveKeyWithValue(constant.LabelIPPoolOwnerApplication, matchLabel[constant.LabelIPPoolOwnerApplication]))
+			Expect(adopted.Labels).To(HaveKeyWithValue(constant.LabelIPPoolReclaimIPPool, constant.True))
+			Expect(adopted.Labels).NotTo(HaveKey(constant.LabelIPPoolBorrowedBy))
+			Expect(metav1.GetControllerOf(adopted).UID).To(BeEquivalentTo("subnet-uid"))
+
+			var updated spiderpoolv1.SpiderIPPool
+			Expect(sac.client.Get(context.TODO(), client.ObjectKeyFromObject(pool), &updated)).To(Succeed())
+			Expect(updated.Labels).To(Equal(adopted.Labels))
+		})
+
+		Context("with the IPPool borrowing IPs from the fallback subnet", func() {
+			BeforeEach(func() {
+				pool.Spec.Subnet = "172.18.41.0/24"
+				pool.Spec.IPs = []string{"172.18.41.1"}
+			})
+
+			It("restores the labels of the borrowing", func() {
+				adopted, err := adopt()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(adopted.Labels).To(HaveKeyWithValue(constant.LabelIPPoolOwnerSpiderSubnet, "fallback-subnet"))
+				Expect(adopted.Labels).To(HaveKeyWithValue(constant.LabelIPPoolBorrowedBy, "v4-subnet"))
+				Expect(metav1.GetControllerOf(adopted).UID).To(BeEquivalentTo("fallback-uid"))
+			})
+		})
+
+		Context("with the IPPool out of the SpiderSubnets", func() {
+			BeforeEach(func() {
+				pool.Spec.IPs = []string{"172.18.40.100"}
+			})
+
+			It("refuses to adopt it", func() {
+				_, err := adopt()
+				Expect(err).To(MatchError(constant.ErrWrongInput))
+			})
+		})
+
+		Context("without the IPPool named after the application", func() {
+			BeforeEach(func() {
+				pool.Name = "other-pool"
+			})
+
+			It("adopts nothing", func() {
+				adopted, err := adopt()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(adopted).To(BeNil())
+			})
+		})
+	})
+	Describe("DaemonSet node sizing", func() {
+		var daemonSet *appsv1.DaemonSet
+		var nodes []*corev1.Node
+		BeforeEach(func() {
+			informerLogger = logutils.Logger.Named("SpiderSubnet-Application-Controllers")
+
+			daemonSet = &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "ds"}}
+			daemonSet.Spec.Template.Spec.NodeSelector = map[string]string{"role": "worker"}
+			daemonSet.Status.DesiredNumberScheduled = 5
+
+			newNode := func(name string, taints ...corev1.Taint) *corev1.Node {
+				return &corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"role": "worker", "zone": name}},
+					Spec:       corev1.NodeSpec{Taints: taints},
+				}
+			}
+			nodes = []*corev1.Node{
+				newNode("node1"),
+				newNode("node2", corev1.Taint{Key: corev1.TaintNodeUnschedulable, Effect: corev1.TaintEffectNoSchedule}),
+				newNode("node3", corev1.Taint{Key: "dedicated", Effect: corev1.TaintEffectPreferNoSchedule}),
+				newNode("node4", corev1.Taint{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}),
+				{ObjectMeta: metav1.ObjectMeta{Name: "master", Labels: map[string]string{"role": "master"}}},
+			}
+		})
+
+		It("counts the nodes matching the node selector with the taints tolerated", func() {
+			Expect(controllers.CountDaemonSetNodes(daemonSet, nodes)).To(Equal(3))
+
+			daemonSet.Spec.Template.Spec.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "gpu"}}
+			Expect(controllers.CountDaemonSetNodes(daemonSet, nodes)).To(Equal(4))
+		})
+
+		It("counts the nodes matching any term of the required node affinity", func() {
+			daemonSet.Spec.Template.Spec.Affinity = &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"node1"}}}},
+					{MatchFields: []corev1.NodeSelectorRequirement{{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"node2"}}}},
+				}},
+			}}
+			Expect(controllers.CountDaemonSetNodes(daemonSet, nodes)).To(Equal(2))
+		})
+
+		It("sizes the DaemonSet by the nodes", func() {
+			Expect(sac.daemonSetReplicas(daemonSet, logutils.Logger)).To(Equal(5))
+
+			nodeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			for _, node := range nodes {
+				Expect(nodeIndexer.Add(node)).To(Succeed())
+			}
+			sac.nodeLister = corelisters.NewNodeLister(nodeIndexer)
+			Expect(sac.daemonSetReplicas(daemonSet, logutils.Logger)).To(Equal(3))
+		})
+
+		It("enqueues the DaemonSets not controlled by others and not in host network", func() {
+			dsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			Expect(dsIndexer.Add(daemonSet)).To(Succeed())
+			hostNetwork := daemonSet.DeepCopy()
+			hostNetwork.Name = "host-network"
+			hostNetwork.Spec.Template.Spec.HostNetwork = true
+			Expect(dsIndexer.Add(hostNetwork)).To(Succeed())
+			controlled := daemonSet.DeepCopy()
+			controlled.Name = "controlled"
+			controlled.OwnerReferences = []metav1.OwnerReference{{Name: "owner", UID: "owner-uid", Controller: pointer.Bool(true)}}
+			Expect(dsIndexer.Add(controlled)).To(Succeed())
+
+			sac.daemonSetLister = appslisters.NewDaemonSetLister(dsIndexer)
+			sac.workQueue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Application-Controllers")
+			DeferCleanup(sac.workQueue.ShutDown)
+			sac.MaxWorkqueueLength = 10
+
+			sac.enqueueDaemonSets()
+			Expect(sac.workQueue.Len()).To(Equal(1))
+			item, _ := sac.workQueue.Get()
+			Expect(item).To(Equal(appWorkQueueKey{MetaNamespaceKey: "default/ds", AppKind: constant.KindDaemonSet}))
+		})
+
+		It("reports the node changes affecting the scheduling", func() {
+			node := nodes[0]
+			Expect(nodeSchedulingChanged(node, node.DeepCopy())).To(BeFalse())
+
+			relabeled := node.DeepCopy()
+			relabeled.Labels["role"] = "master"
+			Expect(nodeSchedulingChanged(node, relabeled)).To(BeTrue())
+
+			cordoned := node.DeepCopy()
+			cordoned.Spec.Unschedulable = true
+			Expect(nodeSchedulingChanged(node, cordoned)).To(BeTrue())
+
+			heartbeat := node.DeepCopy()
+			heartbeat.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}
+			Expect(nodeSchedulingChanged(node, heartbeat)).To(BeFalse())
+		})
+	})
+
+	Describe("finished Jobs", func() {
+		var job *batchv1.Job
+		BeforeEach(func() {
+			informerLogger = logutils.Logger.Named("SpiderSubnet-Application-Controllers")
+
+			job = &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "job", UID: "job-uid"}}
+			job.Spec.Template.Annotations = map[string]string{constant.AnnoSpiderSubnet: `{"ipv4":["v4-subnet"]}`}
+
+			newAutoPool := func(name string, reclaim bool) *spiderpoolv1.SpiderIPPool {
+				return &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+					Labels: map[string]string{
+						constant.LabelIPPoolOwnerApplicationUID: string(job.UID),
+						constant.LabelIPPoolReclaimIPPool:       fmt.Sprint(reclaim),
+					},
+				}}
+			}
+			scheme := runtime.NewScheme()
+			Expect(spiderpoolv1.AddToScheme(scheme)).To(Succeed())
+			sac.client = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				newAutoPool("auto-pool", true),
+				newAutoPool("kept-pool", false),
+			).Build()
+		})
+
+		finish := func(job *batchv1.Job, conditionType batchv1.JobConditionType) *batchv1.Job {
+			finished := job.DeepCopy()
+			finished.Status.Conditions = []batchv1.JobCondition{{Type: conditionType, Status: corev1.ConditionTrue}}
+			return finished
+		}
+
+		It("tells the Jobs completed or failed", func() {
+			Expect(controllers.IsJobFinished(job)).To(BeFalse())
+			Expect(controllers.IsJobFinished(finish(job, batchv1.JobComplete))).To(BeTrue())
+			Expect(controllers.IsJobFinished(finish(job, batchv1.JobFailed))).To(BeTrue())
+			Expect(controllers.IsJobFinished(finish(job, batchv1.JobSuspended))).To(BeFalse())
+
+			notYet := finish(job, batchv1.JobComplete)
+			notYet.Status.Conditions[0].Status = corev1.ConditionFalse
+			Expect(controllers.IsJobFinished(notYet)).To(BeFalse())
+		})
+
+		It("reclaims the auto-created IPPools once the Job finishes", func() {
+			handler := sac.ControllerAddOrUpdateHandler()
+			finished := finish(job, batchv1.JobComplete)
+
+			err := handler(context.TODO(), job, finished)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(subnetMgr.reclaimed).To(Equal([]string{"auto-pool"}))
+			Expect(subnetMgr.reclaimAnno).To(HaveKeyWithValue(constant.AnnoSpiderSubnetReclaimPolicy, constant.ReclaimPolicyImmediate))
+
+			// the later updates of the finished Job reclaim nothing again
+			err = handler(context.TODO(), finished, finished.DeepCopy())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(subnetMgr.reclaimed).To(HaveLen(1))
+		})
+
+		It("doesn't create or scale the auto-created IPPools of the finished Job", func() {
+			jobIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			Expect(jobIndexer.Add(finish(job, batchv1.JobFailed))).To(Succeed())
+			sac.jobLister = batchlisters.NewJobLister(jobIndexer)
+
+			// the fake SubnetManager panics on the calls to create or scale
+			// the IPPools
+			err := sac.syncHandler(appWorkQueueKey{MetaNamespaceKey: "default/job", AppKind: constant.KindJob}, logutils.Logger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("GetCronJobPodSelector", func() {
+		var cronJob *batchv1.CronJob
+		BeforeEach(func() {
+			cronJob = &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "cron"}}
+		})
+
+		It("selects the Pods by the labels of the Pod template", func() {
+			cronJob.Spec.JobTemplate.Spec.Template.Labels = map[string]string{"app": "cron"}
+			Expect(controllers.GetCronJobPodSelector(cronJob)).To(Equal(&metav1.LabelSelector{MatchLabels: map[string]string{"app": "cron"}}))
+		})
+
+		It("prefers the selector of the Job template", func() {
+			selector := &metav1.LabelSelector{MatchLabels: map[string]string{"job": "cron"}}
+			cronJob.Spec.JobTemplate.Spec.Selector = selector
+			cronJob.Spec.JobTemplate.Spec.Template.Labels = map[string]string{"app": "cron"}
+			Expect(controllers.GetCronJobPodSelector(cronJob)).To(Equal(selector))
+		})
+
+		It("selects nothing without the labels", func() {
+			Expect(controllers.GetCronJobPodSelector(cronJob)).To(BeNil())
+		})
+	})
+})
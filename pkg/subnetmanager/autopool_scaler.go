@@ -0,0 +1,237 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package subnetmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/election"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	clientset "github.com/spidernet-io/spiderpool/pkg/k8s/client/clientset/versioned"
+	"github.com/spidernet-io/spiderpool/pkg/k8s/client/informers/externalversions"
+	informers "github.com/spidernet-io/spiderpool/pkg/k8s/client/informers/externalversions/spiderpool.spidernet.io/v1"
+	listers "github.com/spidernet-io/spiderpool/pkg/k8s/client/listers/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+var scalerLogger *zap.Logger
+
+// AutoPoolScaler is a feedback controller marking the status
+// AutoElasticIPCount of auto-created IPPools from their actual usage, so
+// that a percentage of their IP addresses are kept free. The auto-created
+// IPPools grow before the replicas catch up with the Pods scaled rapidly,
+// such as by HPA, and shrink back once the usage drops.
+type AutoPoolScaler struct {
+	client.Client
+
+	IPPoolsLister listers.SpiderIPPoolLister
+	IPPoolsSynced cache.InformerSynced
+
+	Workqueue workqueue.RateLimitingInterface
+
+	// HeadroomPercent is the percentage of free IP addresses to keep in
+	// each auto-created IPPool, 0 means the IPPools only follow the replicas.
+	HeadroomPercent     int
+	LeaderRetryElectGap time.Duration
+	ResyncPeriod        time.Duration
+	Workers             int
+	MaxWorkqueueLength  int
+}
+
+func (as *AutoPoolScaler) SetupInformer(ctx context.Context, client clientset.Interface, leader election.SpiderLeaseElector) error {
+	if client == nil {
+		return fmt.Errorf("spiderpool clientset must be specified")
+	}
+	if leader == nil {
+		return fmt.Errorf("controller leader must be specified")
+	}
+	if as.HeadroomPercent < 0 || as.HeadroomPercent >= 100 {
+		return fmt.Errorf("the headroom percent of auto-created IPPools must be in [0, 100), but got %d", as.HeadroomPercent)
+	}
+
+	scalerLogger = logutils.Logger.Named("Auto-Created-IPPool-Scaler")
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			if !leader.IsElected() {
+				time.Sleep(as.LeaderRetryElectGap)
+				continue
+			}
+
+			innerCtx, innerCancel := context.WithCancel(ctx)
+			go func() {
+				for {
+					select {
+					case <-innerCtx.Done():
+						return
+					default:
+					}
+
+					if !leader.IsElected() {
+						scalerLogger.Warn("Leader lost, stop auto-created IPPool scaler")
+						innerCancel()
+						return
+					}
+					time.Sleep(as.LeaderRetryElectGap)
+				}
+			}()
+
+			scalerLogger.Info("Initialize auto-created IPPool scaler")
+			informerFactory := externalversions.NewSharedInformerFactory(client, as.ResyncPeriod)
+			as.addEventHandlers(informerFactory.Spiderpool().V1().SpiderIPPools())
+
+			informerFactory.Start(innerCtx.Done())
+			if err := as.run(logutils.IntoContext(innerCtx, scalerLogger), as.Workers); err != nil {
+				scalerLogger.Sugar().Errorf("failed to run auto-created IPPool scaler: %v", err)
+				innerCancel()
+			}
+			scalerLogger.Info("Auto-created IPPool scaler down")
+		}
+	}()
+
+	return nil
+}
+
+func (as *AutoPoolScaler) addEventHandlers(ipPoolInformer informers.SpiderIPPoolInformer) {
+	as.IPPoolsLister = ipPoolInformer.Lister()
+	as.IPPoolsSynced = ipPoolInformer.Informer().HasSynced
+	as.Workqueue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Auto-Created-IPPool-Scaler")
+
+	ipPoolInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: as.enqueueIPPool,
+		UpdateFunc: func(old, new interface{}) {
+			as.enqueueIPPool(new)
+		},
+		DeleteFunc: nil,
+	})
+}
+
+// enqueueIPPool enqueues the auto-created IPPool whose status AutoElasticIPCount
+// doesn't match its usage.
+func (as *AutoPoolScaler) enqueueIPPool(obj interface{}) {
+	pool := obj.(*spiderpoolv1.SpiderIPPool)
+	if !ippoolmanager.IsAutoCreatedIPPool(pool) || pool.DeletionTimestamp != nil || !as.needScale(pool) {
+		return
+	}
+
+	logger := scalerLogger.With(
+		zap.String("IPPoolName", pool.Name),
+		zap.String("Operation", "SYNC"),
+	)
+
+	if as.Workqueue.Len() >= as.MaxWorkqueueLength {
+		logger.Sugar().Errorf(MessageWorkqueueFull)
+		return
+	}
+
+	as.Workqueue.Add(pool.Name)
+	logger.Debug("Enqueue auto-created IPPool")
+}
+
+func (as *AutoPoolScaler) run(ctx context.Context, workers int) error {
+	defer utilruntime.HandleCrash()
+	defer as.Workqueue.ShutDown()
+
+	logger := logutils.FromContext(ctx)
+	logger.Info("Starting auto-created IPPool scaler")
+
+	logger.Info("Waiting for informer caches to sync")
+	if ok := cache.WaitForNamedCacheSync("Auto-Created-IPPool-Scaler", ctx.Done(), as.IPPoolsSynced); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	logger.Info("Starting workers")
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, as.runWorker, time.Second)
+	}
+
+	logger.Info("Started workers")
+	<-ctx.Done()
+	logger.Info("Shutting down workers")
+
+	return nil
+}
+
+func (as *AutoPoolScaler) runWorker(ctx context.Context) {
+	for as.processNextWorkItem(ctx) {
+	}
+}
+
+func (as *AutoPoolScaler) processNextWorkItem(ctx context.Context) bool {
+	obj, shutdown := as.Workqueue.Get()
+	if shutdown {
+		return false
+	}
+	defer as.Workqueue.Done(obj)
+
+	logger := logutils.FromContext(ctx).With(
+		zap.String("IPPoolName", obj.(string)),
+		zap.String("Operation", "PROCESS"),
+	)
+
+	if err := as.syncHandler(logutils.IntoContext(ctx, logger), obj.(string)); err != nil {
+		logger.Sugar().Warnf("Failed to handle, requeuing: %v", err)
+		as.Workqueue.AddRateLimited(obj)
+		return true
+	}
+	as.Workqueue.Forget(obj)
+
+	return true
+}
+
+func (as *AutoPoolScaler) syncHandler(ctx context.Context, poolName string) error {
+	pool, err := as.IPPoolsLister.Get(poolName)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if pool.DeletionTimestamp != nil || !as.needScale(pool) {
+		return nil
+	}
+
+	elasticIPCount := ElasticIPCount(len(pool.Status.AllocatedIPs), as.HeadroomPercent)
+	poolCopy := pool.DeepCopy()
+	poolCopy.Status.AutoElasticIPCount = &elasticIPCount
+	if err := as.Status().Update(ctx, poolCopy); err != nil {
+		return err
+	}
+	logutils.FromContext(ctx).Sugar().Infof("Mark the elastic IP number of auto-created IPPool to %d for %d allocated IP addresses", elasticIPCount, len(pool.Status.AllocatedIPs))
+
+	return nil
+}
+
+func (as *AutoPoolScaler) needScale(pool *spiderpoolv1.SpiderIPPool) bool {
+	elasticIPCount := ElasticIPCount(len(pool.Status.AllocatedIPs), as.HeadroomPercent)
+	if pool.Status.AutoElasticIPCount == nil {
+		return elasticIPCount != 0
+	}
+
+	return *pool.Status.AutoElasticIPCount != elasticIPCount
+}
+
+// ElasticIPCount returns the least IP number to keep headroomPercent of the
+// IP addresses free with the allocated IP addresses.
+func ElasticIPCount(allocatedIPCount, headroomPercent int) int64 {
+	if allocatedIPCount == 0 || headroomPercent <= 0 {
+		return int64(allocatedIPCount)
+	}
+
+	usedPercent := 100 - headroomPercent
+	return int64((allocatedIPCount*100 + usedPercent - 1) / usedPercent)
+}
@@ -0,0 +1,134 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package subnetmanager
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	listers "github.com/spidernet-io/spiderpool/pkg/k8s/client/listers/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+var _ = Describe("AutoPoolScaler", Label("autopool_scaler_test"), func() {
+	DescribeTable("ElasticIPCount",
+		func(allocatedIPCount, headroomPercent int, expected int64) {
+			Expect(ElasticIPCount(allocatedIPCount, headroomPercent)).To(Equal(expected))
+		},
+		Entry("without allocations", 0, 20, int64(0)),
+		Entry("without headroom", 10, 0, int64(10)),
+		Entry("with headroom", 8, 20, int64(10)),
+		Entry("rounding up", 9, 20, int64(12)),
+	)
+
+	Describe("AutoPoolTargetIPCount", func() {
+		It("waits for the desired IP number", func() {
+			_, ok := ippoolmanager.AutoPoolTargetIPCount(&spiderpoolv1.SpiderIPPool{})
+			Expect(ok).To(BeFalse())
+		})
+
+		It("scales to the larger one of the desired and elastic IP numbers", func() {
+			pool := &spiderpoolv1.SpiderIPPool{}
+			pool.Status.AutoDesiredIPCount = pointer.Int64(5)
+			n, ok := ippoolmanager.AutoPoolTargetIPCount(pool)
+			Expect(ok).To(BeTrue())
+			Expect(n).To(Equal(5))
+
+			pool.Status.AutoElasticIPCount = pointer.Int64(8)
+			n, _ = ippoolmanager.AutoPoolTargetIPCount(pool)
+			Expect(n).To(Equal(8))
+
+			pool.Status.AutoElasticIPCount = pointer.Int64(3)
+			n, _ = ippoolmanager.AutoPoolTargetIPCount(pool)
+			Expect(n).To(Equal(5))
+		})
+	})
+
+	Describe("scaling with the usage", func() {
+		var as *AutoPoolScaler
+		var fakeClient client.Client
+		var poolIndexer cache.Indexer
+		var pool *spiderpoolv1.SpiderIPPool
+		BeforeEach(func() {
+			scalerLogger = logutils.Logger.Named("Auto-Created-IPPool-Scaler")
+
+			pool = &spiderpoolv1.SpiderIPPool{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "auto-pool",
+					Labels: map[string]string{constant.LabelIPPoolOwnerApplication: "Deployment_default_app"},
+				},
+			}
+			pool.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{}
+			for _, ip := range []string{"172.18.40.1", "172.18.40.2", "172.18.40.3", "172.18.40.4"} {
+				pool.Status.AllocatedIPs[ip] = spiderpoolv1.PoolIPAllocation{ContainerID: ip}
+			}
+		})
+
+		JustBeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(spiderpoolv1.AddToScheme(scheme)).To(Succeed())
+			fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(pool).Build()
+			poolIndexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			Expect(poolIndexer.Add(pool)).To(Succeed())
+
+			as = &AutoPoolScaler{
+				Client:             fakeClient,
+				IPPoolsLister:      listers.NewSpiderIPPoolLister(poolIndexer),
+				Workqueue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Auto-Created-IPPool-Scaler"),
+				HeadroomPercent:    20,
+				MaxWorkqueueLength: 10,
+			}
+			DeferCleanup(as.Workqueue.ShutDown)
+		})
+
+		It("marks the elastic IP number of the auto-created IPPool", func() {
+			Expect(as.needScale(pool)).To(BeTrue())
+			Expect(as.syncHandler(context.TODO(), pool.Name)).To(Succeed())
+
+			var updated spiderpoolv1.SpiderIPPool
+			Expect(fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(pool), &updated)).To(Succeed())
+			Expect(updated.Status.AutoElasticIPCount).To(Equal(pointer.Int64(5)))
+			Expect(as.needScale(&updated)).To(BeFalse())
+		})
+
+		It("ignores the IPPool already gone", func() {
+			Expect(as.syncHandler(context.TODO(), "gone-pool")).To(Succeed())
+		})
+
+		It("enqueues the auto-created IPPools out of scale only", func() {
+			as.enqueueIPPool(pool)
+			Expect(as.Workqueue.Len()).To(Equal(1))
+
+			manual := pool.DeepCopy()
+			manual.Name = "manual-pool"
+			manual.Labels = nil
+			as.enqueueIPPool(manual)
+
+			scaled := pool.DeepCopy()
+			scaled.Name = "scaled-pool"
+			scaled.Status.AutoElasticIPCount = pointer.Int64(5)
+			as.enqueueIPPool(scaled)
+
+			deleting := pool.DeepCopy()
+			deleting.Name = "deleting-pool"
+			now := metav1.Now()
+			deleting.DeletionTimestamp = &now
+			as.enqueueIPPool(deleting)
+
+			Expect(as.Workqueue.Len()).To(Equal(1))
+		})
+	})
+})
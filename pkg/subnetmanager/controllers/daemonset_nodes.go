@@ -0,0 +1,125 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// daemonSetTolerations are the tolerations added to the Pods of DaemonSets by
+// the DaemonSet controller, so they're scheduled to the nodes with these taints.
+var daemonSetTolerations = []corev1.Toleration{
+	{Key: corev1.TaintNodeNotReady, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+	{Key: corev1.TaintNodeUnreachable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+	{Key: corev1.TaintNodeDiskPressure, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	{Key: corev1.TaintNodeMemoryPressure, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	{Key: corev1.TaintNodePIDPressure, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	{Key: corev1.TaintNodeUnschedulable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+}
+
+// CountDaemonSetNodes returns the number of the nodes which the DaemonSet runs
+// its Pods on, which match its node selector and required node affinity, and
+// whose NoSchedule and NoExecute taints are tolerated.
+func CountDaemonSetNodes(daemonSet *appsv1.DaemonSet, nodes []*corev1.Node) int {
+	count := 0
+	for _, node := range nodes {
+		if DaemonSetRunsOnNode(daemonSet, node) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// DaemonSetRunsOnNode reports whether the DaemonSet runs its Pod on the node.
+func DaemonSetRunsOnNode(daemonSet *appsv1.DaemonSet, node *corev1.Node) bool {
+	podSpec := daemonSet.Spec.Template.Spec
+	podSpec.Tolerations = append(append([]corev1.Toleration(nil), podSpec.Tolerations...), daemonSetTolerations...)
+
+	return PodFitsNode(&podSpec, node)
+}
+
+// PodFitsNode reports whether the Pod may be scheduled to the node, which
+// matches its node selector and required node affinity, and whose
+// NoSchedule and NoExecute taints are tolerated.
+func PodFitsNode(podSpec *corev1.PodSpec, node *corev1.Node) bool {
+	if !labels.SelectorFromSet(podSpec.NodeSelector).Matches(labels.Set(node.Labels)) {
+		return false
+	}
+
+	if podSpec.Affinity != nil && podSpec.Affinity.NodeAffinity != nil &&
+		podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		if !matchNodeSelectorTerms(podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms, node) {
+			return false
+		}
+	}
+
+	for i := range node.Spec.Taints {
+		taint := node.Spec.Taints[i]
+		if taint.Effect != corev1.TaintEffectNoSchedule && taint.Effect != corev1.TaintEffectNoExecute {
+			continue
+		}
+
+		tolerated := false
+		for j := range podSpec.Tolerations {
+			if podSpec.Tolerations[j].ToleratesTaint(&taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchNodeSelectorTerms reports whether the node matches any of the terms,
+// the terms are ORed while the requirements of each term are ANDed.
+func matchNodeSelectorTerms(terms []corev1.NodeSelectorTerm, node *corev1.Node) bool {
+	for _, term := range terms {
+		if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+			continue
+		}
+		if matchNodeSelectorRequirements(term.MatchExpressions, labels.Set(node.Labels)) &&
+			matchNodeSelectorRequirements(term.MatchFields, labels.Set{"metadata.name": node.Name}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchNodeSelectorRequirements(requirements []corev1.NodeSelectorRequirement, set labels.Set) bool {
+	for _, req := range requirements {
+		var op selection.Operator
+		switch req.Operator {
+		case corev1.NodeSelectorOpIn:
+			op = selection.In
+		case corev1.NodeSelectorOpNotIn:
+			op = selection.NotIn
+		case corev1.NodeSelectorOpExists:
+			op = selection.Exists
+		case corev1.NodeSelectorOpDoesNotExist:
+			op = selection.DoesNotExist
+		case corev1.NodeSelectorOpGt:
+			op = selection.GreaterThan
+		case corev1.NodeSelectorOpLt:
+			op = selection.LessThan
+		default:
+			return false
+		}
+
+		r, err := labels.NewRequirement(req.Key, op, req.Values)
+		if nil != err || !r.Matches(set) {
+			return false
+		}
+	}
+
+	return true
+}
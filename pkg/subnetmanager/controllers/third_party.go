@@ -0,0 +1,176 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/singletons"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+const defaultThirdPartyReplicasPath = "{.spec.replicas}"
+
+// ValidateThirdPartyControllers checks the configured third-party controllers,
+// their kinds must be distinct from each other and the kubernetes original
+// controllers, since the kind is a part of the auto-created IPPool labels.
+func ValidateThirdPartyControllers(controllers []types.ThirdPartyController) error {
+	builtinKinds := map[string]struct{}{
+		constant.KindPod:         {},
+		constant.KindDeployment:  {},
+		constant.KindReplicaSet:  {},
+		constant.KindStatefulSet: {},
+		constant.KindDaemonSet:   {},
+		constant.KindJob:         {},
+		constant.KindCronJob:     {},
+		constant.KindUnknown:     {},
+	}
+
+	kinds := map[string]struct{}{}
+	for _, c := range controllers {
+		if c.APIVersion == "" || c.Kind == "" || c.Resource == "" {
+			return fmt.Errorf("the apiVersion, kind and resource of third-party controller '%+v' must be specified", c)
+		}
+		gv, err := schema.ParseGroupVersion(c.APIVersion)
+		if nil != err {
+			return fmt.Errorf("invalid apiVersion of third-party controller %s: %v", c.Kind, err)
+		}
+		if gv.Group == appsv1.GroupName || gv.Group == batchv1.GroupName {
+			return fmt.Errorf("third-party controller %s must not belong to the kubernetes original group '%s'", c.Kind, gv.Group)
+		}
+		if _, ok := builtinKinds[c.Kind]; ok {
+			return fmt.Errorf("the kind of third-party controller %s conflicts with the kubernetes original controllers", c.Kind)
+		}
+		if _, ok := kinds[c.Kind]; ok {
+			return fmt.Errorf("duplicate third-party controller kind %s", c.Kind)
+		}
+		kinds[c.Kind] = struct{}{}
+
+		if err := jsonpath.New(c.Kind).Parse(thirdPartyReplicasPath(c)); nil != err {
+			return fmt.Errorf("invalid replicasPath of third-party controller %s: %v", c.Kind, err)
+		}
+	}
+
+	return nil
+}
+
+// GetThirdPartyController returns the configured third-party controller with
+// the given apiVersion and kind.
+func GetThirdPartyController(apiVersion, kind string) (types.ThirdPartyController, bool) {
+	for _, c := range singletons.ThirdPartyControllers {
+		if c.APIVersion == apiVersion && c.Kind == kind {
+			return c, true
+		}
+	}
+
+	return types.ThirdPartyController{}, false
+}
+
+// GetThirdPartyControllerByKind returns the configured third-party controller
+// with the given kind, which is unique among them.
+func GetThirdPartyControllerByKind(kind string) (types.ThirdPartyController, bool) {
+	for _, c := range singletons.ThirdPartyControllers {
+		if c.Kind == kind {
+			return c, true
+		}
+	}
+
+	return types.ThirdPartyController{}, false
+}
+
+// ThirdPartyControllerGVR returns the GroupVersionResource to list and watch
+// the third-party controller.
+func ThirdPartyControllerGVR(c types.ThirdPartyController) schema.GroupVersionResource {
+	// Ignore the error here, the apiVersion has been verified in
+	// ValidateThirdPartyControllers.
+	gv, _ := schema.ParseGroupVersion(c.APIVersion)
+
+	return gv.WithResource(c.Resource)
+}
+
+// GetThirdPartyAppReplicas returns the replicas of the third-party application
+// with its configured replicasPath, the missing replicas is considered as 0.
+func GetThirdPartyAppReplicas(app *unstructured.Unstructured) (int, error) {
+	c, ok := GetThirdPartyController(app.GetAPIVersion(), app.GetKind())
+	if !ok {
+		return 0, fmt.Errorf("%w: unknown third-party controller %s %s", constant.ErrWrongInput, app.GetAPIVersion(), app.GetKind())
+	}
+
+	jp := jsonpath.New(c.Kind)
+	jp.AllowMissingKeys(true)
+	if err := jp.Parse(thirdPartyReplicasPath(c)); nil != err {
+		return 0, err
+	}
+	results, err := jp.FindResults(app.UnstructuredContent())
+	if nil != err {
+		return 0, fmt.Errorf("failed to find the replicas of %s %s/%s: %v", c.Kind, app.GetNamespace(), app.GetName(), err)
+	}
+	if len(results) == 0 || len(results[0]) == 0 {
+		return 0, nil
+	}
+
+	switch replicas := results[0][0].Interface().(type) {
+	case int64:
+		return int(replicas), nil
+	case int32:
+		return int(replicas), nil
+	case int:
+		return replicas, nil
+	case float64:
+		return int(replicas), nil
+	default:
+		return 0, fmt.Errorf("invalid replicas '%v' of %s %s/%s", replicas, c.Kind, app.GetNamespace(), app.GetName())
+	}
+}
+
+// GetThirdPartyAppPodSelector returns the 'spec.selector' of the third-party application.
+func GetThirdPartyAppPodSelector(app *unstructured.Unstructured) (*metav1.LabelSelector, error) {
+	selectorMap, found, err := unstructured.NestedMap(app.UnstructuredContent(), "spec", "selector")
+	if nil != err {
+		return nil, fmt.Errorf("invalid selector of %s %s/%s: %v", app.GetKind(), app.GetNamespace(), app.GetName(), err)
+	}
+	if !found {
+		return nil, nil
+	}
+
+	var selector metav1.LabelSelector
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(selectorMap, &selector); nil != err {
+		return nil, fmt.Errorf("invalid selector of %s %s/%s: %v", app.GetKind(), app.GetNamespace(), app.GetName(), err)
+	}
+
+	return &selector, nil
+}
+
+// GetThirdPartyAppPodAnnotations returns the 'spec.template.metadata.annotations'
+// of the third-party application.
+func GetThirdPartyAppPodAnnotations(app *unstructured.Unstructured) (map[string]string, error) {
+	annotations, _, err := unstructured.NestedStringMap(app.UnstructuredContent(), "spec", "template", "metadata", "annotations")
+	if nil != err {
+		return nil, fmt.Errorf("invalid Pod template annotations of %s %s/%s: %v", app.GetKind(), app.GetNamespace(), app.GetName(), err)
+	}
+
+	return annotations, nil
+}
+
+func thirdPartyReplicasPath(c types.ThirdPartyController) string {
+	path := strings.TrimSpace(c.ReplicasPath)
+	if path == "" {
+		return defaultThirdPartyReplicasPath
+	}
+	if !strings.HasPrefix(path, "{") {
+		path = fmt.Sprintf("{%s}", path)
+	}
+
+	return path
+}
@@ -0,0 +1,43 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+func (c *Controller) AddThirdPartyHandler(kind string, informer cache.SharedIndexInformer) {
+	controllersLogger.Sugar().Infof("Setting up third-party controller %s handlers", kind)
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.onThirdPartyAdd,
+		UpdateFunc: c.onThirdPartyUpdate,
+		DeleteFunc: c.onThirdPartyDelete,
+	})
+}
+
+func (c *Controller) onThirdPartyAdd(obj interface{}) {
+	err := c.reconcileFunc(logutils.IntoContext(context.TODO(), controllersLogger), nil, obj)
+	if nil != err {
+		controllersLogger.Sugar().Errorf("onThirdPartyAdd: %v", err)
+	}
+}
+
+func (c *Controller) onThirdPartyUpdate(oldObj interface{}, newObj interface{}) {
+	err := c.reconcileFunc(logutils.IntoContext(context.TODO(), controllersLogger), oldObj, newObj)
+	if nil != err {
+		controllersLogger.Sugar().Errorf("onThirdPartyUpdate: %v", err)
+	}
+}
+
+func (c *Controller) onThirdPartyDelete(obj interface{}) {
+	err := c.cleanupFunc(logutils.IntoContext(context.TODO(), controllersLogger), obj)
+	if nil != err {
+		controllersLogger.Sugar().Errorf("onThirdPartyDelete: %v", err)
+	}
+}
@@ -10,8 +10,12 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	apitypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/pointer"
 
@@ -85,15 +89,51 @@ func GenSubnetFreeIPs(subnet *spiderpoolv1.SpiderSubnet) ([]net.IP, error) {
 	return freeIPs, nil
 }
 
+// ValidateFallbackSubnet checks whether the auto-created IPPools of the subnet
+// could borrow IPs from the fallback subnet, which must have the same IP version
+// and VLAN.
+func ValidateFallbackSubnet(subnet, fallback *spiderpoolv1.SpiderSubnet) error {
+	if fallback.DeletionTimestamp != nil {
+		return fmt.Errorf("%w: fallback SpiderSubnet '%s' is terminating", constant.ErrWrongInput, fallback.Name)
+	}
+	if pointer.Int64Deref(subnet.Spec.IPVersion, 0) != pointer.Int64Deref(fallback.Spec.IPVersion, 0) {
+		return fmt.Errorf("%w: fallback SpiderSubnet '%s' has a different IP version from SpiderSubnet '%s'", constant.ErrWrongInput, fallback.Name, subnet.Name)
+	}
+	if pointer.Int64Deref(subnet.Spec.Vlan, 0) != pointer.Int64Deref(fallback.Spec.Vlan, 0) {
+		return fmt.Errorf("%w: fallback SpiderSubnet '%s' has a different VLAN from SpiderSubnet '%s'", constant.ErrWrongInput, fallback.Name, subnet.Name)
+	}
+
+	return nil
+}
+
 // GetSubnetAnnoConfig generates SpiderSubnet configuration from pod annotation,
 // if the pod doesn't have the related subnet annotation but has IPPools/IPPool relative annotation it will return nil.
 // If the pod doesn't have any subnet/ippool annotations, it will use the cluster default subnet configuration.
 func GetSubnetAnnoConfig(podAnnotations map[string]string, log *zap.Logger) (*types.PodSubnetAnnoConfig, error) {
 	var subnetAnnoConfig types.PodSubnetAnnoConfig
 
-	// annotation: ipam.spidernet.io/subnets
-	subnets, ok := podAnnotations[constant.AnnoSpiderSubnets]
+	// annotation: ipam.spidernet.io/subnet-dual-stack
+	dualStack, ok := podAnnotations[constant.AnnoSpiderSubnetDualStack]
 	if ok {
+		log.Sugar().Debugf("found SpiderSubnet feature annotation '%s' value '%s'", constant.AnnoSpiderSubnetDualStack, dualStack)
+		for _, anno := range []string{constant.AnnoSpiderSubnets, constant.AnnoSpiderSubnet, constant.AnnoSpiderSubnetPoolIPNumber} {
+			if _, ok := podAnnotations[anno]; ok {
+				return nil, fmt.Errorf("annotation '%s' conflicts with annotation '%s'", constant.AnnoSpiderSubnetDualStack, anno)
+			}
+		}
+
+		subnetPair, err := ParseDualStackSubnetAnno(dualStack)
+		if nil != err {
+			return nil, err
+		}
+		subnetAnnoConfig.SingleSubnet = &types.AnnoSubnetItem{
+			Interface: subnetPair.Interface,
+			IPv4:      []string{subnetPair.IPv4},
+			IPv6:      []string{subnetPair.IPv6},
+		}
+		subnetAnnoConfig.DualStack = true
+	} else if subnets, ok := podAnnotations[constant.AnnoSpiderSubnets]; ok {
+		// annotation: ipam.spidernet.io/subnets
 		log.Sugar().Debugf("found SpiderSubnet feature annotation '%s' value '%s'", constant.AnnoSpiderSubnets, subnets)
 		err := json.Unmarshal([]byte(subnets), &subnetAnnoConfig.MultipleSubnets)
 		if nil != err {
@@ -120,7 +160,10 @@ func GetSubnetAnnoConfig(podAnnotations map[string]string, log *zap.Logger) (*ty
 	var err error
 
 	// annotation: ipam.spidernet.io/ippool-ip-number
-	poolIPNum, ok := podAnnotations[constant.AnnoSpiderSubnetPoolIPNumber]
+	poolIPNum, ok, err := GetPoolIPNumberAnno(podAnnotations)
+	if nil != err {
+		return nil, err
+	}
 	if ok {
 		log.Sugar().Debugf("use IPPool IP number '%s'", poolIPNum)
 		isFlexible, ipNum, err = GetPoolIPNumber(poolIPNum)
@@ -145,6 +188,20 @@ func GetSubnetAnnoConfig(podAnnotations map[string]string, log *zap.Logger) (*ty
 		subnetAnnoConfig.FlexibleIPNum = pointer.Int(singletons.ClusterDefaultPool.ClusterSubnetDefaultFlexibleIPNumber)
 	}
 
+	// annotation: ipam.spidernet.io/subnet-pool-ips, the IP number of the auto-created IPPools
+	// is fixed to the number of the pinned IPs of their IP version
+	if poolIPs, ok := podAnnotations[constant.AnnoSpiderSubnetPoolIPs]; ok {
+		if _, ok, _ := GetPoolIPNumberAnno(podAnnotations); ok {
+			return nil, fmt.Errorf("annotation '%s' conflicts with the IPPool IP number", constant.AnnoSpiderSubnetPoolIPs)
+		}
+		log.Sugar().Debugf("use pinned IPPool IPs '%s'", poolIPs)
+		subnetAnnoConfig.PinnedIPv4IPs, subnetAnnoConfig.PinnedIPv6IPs, err = ParsePinnedIPs(poolIPs)
+		if nil != err {
+			return nil, err
+		}
+		subnetAnnoConfig.FlexibleIPNum = nil
+	}
+
 	// annotation: "ipam.spidernet.io/reclaim-ippool", reclaim IPPool or not (default true)
 	reclaimPool, err := ShouldReclaimIPPool(podAnnotations)
 	if nil != err {
@@ -152,6 +209,11 @@ func GetSubnetAnnoConfig(podAnnotations map[string]string, log *zap.Logger) (*ty
 	}
 	subnetAnnoConfig.ReclaimIPPool = reclaimPool
 
+	// annotation: "ipam.spidernet.io/ippool-reclaim-policy", it's applied once the application is deleted
+	if _, err := GetReclaimPolicy(podAnnotations); nil != err {
+		return nil, err
+	}
+
 	err = mutateAndValidateSubnetAnno(&subnetAnnoConfig)
 	if nil != err {
 		return nil, err
@@ -160,6 +222,90 @@ func GetSubnetAnnoConfig(podAnnotations map[string]string, log *zap.Logger) (*ty
 	return &subnetAnnoConfig, nil
 }
 
+// ParseDualStackSubnetAnno parses the value of annotation "ipam.spidernet.io/subnet-dual-stack",
+// both the IPv4 and IPv6 SpiderSubnets must be specified.
+func ParseDualStackSubnetAnno(anno string) (*types.AnnoDualStackSubnetValue, error) {
+	var subnetPair types.AnnoDualStackSubnetValue
+	if err := json.Unmarshal([]byte(anno), &subnetPair); nil != err {
+		return nil, fmt.Errorf("failed to parse anntation '%s' value '%s', error: %v", constant.AnnoSpiderSubnetDualStack, anno, err)
+	}
+	if subnetPair.IPv4 == "" || subnetPair.IPv6 == "" {
+		return nil, fmt.Errorf("both IPv4 and IPv6 SpiderSubnets must be specified in annotation '%s'", constant.AnnoSpiderSubnetDualStack)
+	}
+
+	return &subnetPair, nil
+}
+
+// GetPoolIPNumberAnno returns the IP number of the auto-created IPPools specified by
+// annotation "ipam.spidernet.io/ippool-ip-number" or the dual-stack SpiderSubnet shorthand.
+func GetPoolIPNumberAnno(podAnnotations map[string]string) (string, bool, error) {
+	if dualStack, ok := podAnnotations[constant.AnnoSpiderSubnetDualStack]; ok {
+		subnetPair, err := ParseDualStackSubnetAnno(dualStack)
+		if nil != err {
+			return "", false, err
+		}
+
+		return subnetPair.IPPoolIPNumber, subnetPair.IPPoolIPNumber != "", nil
+	}
+
+	poolIPNum, ok := podAnnotations[constant.AnnoSpiderSubnetPoolIPNumber]
+	return poolIPNum, ok, nil
+}
+
+// ParsePinnedIPs parses the value of annotation "ipam.spidernet.io/subnet-pool-ips",
+// the IP ranges separated by commas, and groups them by IP version.
+func ParsePinnedIPs(poolIPs string) (v4IPRanges, v6IPRanges []string, err error) {
+	for _, ipRange := range strings.Split(poolIPs, ",") {
+		ipRange = strings.TrimSpace(ipRange)
+		switch {
+		case spiderpoolip.IsIPv4IPRange(ipRange):
+			v4IPRanges = append(v4IPRanges, ipRange)
+		case spiderpoolip.IsIPv6IPRange(ipRange):
+			v6IPRanges = append(v6IPRanges, ipRange)
+		default:
+			return nil, nil, fmt.Errorf("invalid IP range '%s' in annotation '%s'", ipRange, constant.AnnoSpiderSubnetPoolIPs)
+		}
+	}
+
+	if v4IPRanges, err = spiderpoolip.MergeIPRanges(constant.IPv4, v4IPRanges); nil != err {
+		return nil, nil, fmt.Errorf("invalid IPv4 ranges in annotation '%s': %v", constant.AnnoSpiderSubnetPoolIPs, err)
+	}
+	if v6IPRanges, err = spiderpoolip.MergeIPRanges(constant.IPv6, v6IPRanges); nil != err {
+		return nil, nil, fmt.Errorf("invalid IPv6 ranges in annotation '%s': %v", constant.AnnoSpiderSubnetPoolIPs, err)
+	}
+
+	return v4IPRanges, v6IPRanges, nil
+}
+
+// GetPinnedIPs returns the pinned IP ranges of the IP version in the SpiderSubnet configuration.
+func GetPinnedIPs(subnetConfig *types.PodSubnetAnnoConfig, ipVersion types.IPVersion) []string {
+	if ipVersion == constant.IPv4 {
+		return subnetConfig.PinnedIPv4IPs
+	}
+
+	return subnetConfig.PinnedIPv6IPs
+}
+
+// IsPinnedIPs reports whether the auto-created IPPools use the pinned IPs.
+func IsPinnedIPs(subnetConfig *types.PodSubnetAnnoConfig) bool {
+	return len(subnetConfig.PinnedIPv4IPs) != 0 || len(subnetConfig.PinnedIPv6IPs) != 0
+}
+
+// GetPinnedIPNumber returns the IP number of the auto-created IPPool which uses
+// the pinned IP ranges.
+func GetPinnedIPNumber(ipVersion types.IPVersion, pinnedIPs []string) (int, error) {
+	if len(pinnedIPs) == 0 {
+		return 0, fmt.Errorf("%w: no IPv%d IPs pinned in annotation '%s'", constant.ErrWrongInput, ipVersion, constant.AnnoSpiderSubnetPoolIPs)
+	}
+
+	ips, err := spiderpoolip.ParseIPRanges(ipVersion, pinnedIPs)
+	if nil != err {
+		return 0, err
+	}
+
+	return len(ips), nil
+}
+
 // mutateAndValidateSubnetAnno will filter multiple subnets you specified and only leaves you the first one to use.
 // And it also checks Interface name or subnets you specified whether are duplicate.
 func mutateAndValidateSubnetAnno(subnetConfig *types.PodSubnetAnnoConfig) error {
@@ -169,6 +315,10 @@ func mutateAndValidateSubnetAnno(subnetConfig *types.PodSubnetAnnoConfig) error
 		var ifNameArray []string
 
 		for index := range subnetConfig.MultipleSubnets {
+			// each interface gets its own auto-created IPPools, which are selected by the interface name
+			if subnetConfig.MultipleSubnets[index].Interface == "" {
+				return fmt.Errorf("it's invalid to set an empty interface name with multiple interfaces: %v", subnetConfig)
+			}
 			ifNameArray = append(ifNameArray, subnetConfig.MultipleSubnets[index].Interface)
 
 			if len(subnetConfig.MultipleSubnets[index].IPv4) != 0 {
@@ -289,6 +439,33 @@ func CalculateJobPodNum(jobSpecParallelism, jobSpecCompletions *int32) int {
 	return 1
 }
 
+// IsJobFinished reports whether the Job has completed or failed, whose
+// Pods no longer need IP addresses.
+func IsJobFinished(job *batchv1.Job) bool {
+	for _, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetCronJobPodSelector returns the selector of the Pods of all Jobs
+// created by the CronJob. The generated selector of each Job only matches
+// its own Pods, so the labels of the Pod template are used instead if the
+// selector of the Job template is not specified.
+func GetCronJobPodSelector(cronJob *batchv1.CronJob) *metav1.LabelSelector {
+	if cronJob.Spec.JobTemplate.Spec.Selector != nil {
+		return cronJob.Spec.JobTemplate.Spec.Selector
+	}
+	if len(cronJob.Spec.JobTemplate.Spec.Template.Labels) == 0 {
+		return nil
+	}
+
+	return &metav1.LabelSelector{MatchLabels: cronJob.Spec.JobTemplate.Spec.Template.Labels}
+}
+
 // IsDefaultIPPoolMode judges whether we use subnet feature or not with the given parameter types.PodSubnetAnnoConfig
 func IsDefaultIPPoolMode(subnetConfig *types.PodSubnetAnnoConfig) bool {
 	if subnetConfig == nil {
@@ -333,3 +510,45 @@ func ShouldReclaimIPPool(anno map[string]string) (bool, error) {
 	// no specified reclaim-IPPool, default to set it true
 	return true, nil
 }
+
+// GetReclaimPolicy parses the annotation "ipam.spidernet.io/ippool-reclaim-policy"
+// of the SpiderSubnet or the Pod template, nil is returned if it's not set.
+func GetReclaimPolicy(anno map[string]string) (*types.AutoPoolReclaimPolicy, error) {
+	value, ok := anno[constant.AnnoSpiderSubnetReclaimPolicy]
+	if !ok {
+		return nil, nil
+	}
+
+	policy, delay, found := strings.Cut(value, ":")
+	switch policy {
+	case constant.ReclaimPolicyImmediate, constant.ReclaimPolicyRetain:
+		if found {
+			return nil, fmt.Errorf("%w: reclaim policy '%s' of '%s' takes no delay", constant.ErrWrongInput, policy, constant.AnnoSpiderSubnetReclaimPolicy)
+		}
+		return &types.AutoPoolReclaimPolicy{Policy: policy}, nil
+	case constant.ReclaimPolicyDelayed:
+		minutes, err := strconv.Atoi(delay)
+		if nil != err || minutes <= 0 {
+			return nil, fmt.Errorf("%w: reclaim policy '%s' of '%s' must be in the form of 'Delayed:<minutes>' with positive minutes", constant.ErrWrongInput, value, constant.AnnoSpiderSubnetReclaimPolicy)
+		}
+		return &types.AutoPoolReclaimPolicy{Policy: policy, Delay: time.Duration(minutes) * time.Minute}, nil
+	default:
+		return nil, fmt.Errorf("%w: reclaim policy '%s' of '%s' must be '%s', '%s' or 'Delayed:<minutes>'", constant.ErrWrongInput, value,
+			constant.AnnoSpiderSubnetReclaimPolicy, constant.ReclaimPolicyImmediate, constant.ReclaimPolicyRetain)
+	}
+}
+
+// GetAutoPoolReclaimPolicy returns the reclaim policy of the auto-created
+// IPPools, the one of the Pod template takes precedence over the one of the
+// SpiderSubnet. nil is returned if neither is set.
+func GetAutoPoolReclaimPolicy(podAnno map[string]string, subnet *spiderpoolv1.SpiderSubnet) (*types.AutoPoolReclaimPolicy, error) {
+	policy, err := GetReclaimPolicy(podAnno)
+	if nil != err || policy != nil {
+		return policy, err
+	}
+	if subnet == nil {
+		return nil, nil
+	}
+
+	return GetReclaimPolicy(subnet.Annotations)
+}
@@ -0,0 +1,240 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package subnetmanager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// SubnetDefragPlan is the plan to compact the free IP addresses of a Subnet
+// by moving the auto-created IPPools without any allocated IP address.
+type SubnetDefragPlan struct {
+	Subnet      string `json:"subnet"`
+	FreeIPCount int    `json:"freeIPCount"`
+
+	// FreeRanges and LargestFreeBlock describe the free IP addresses
+	// before the plan is applied.
+	FreeRanges       []string `json:"freeRanges,omitempty"`
+	LargestFreeBlock int      `json:"largestFreeBlock"`
+
+	// Moves are applied in order, so that the IPPools never overlap.
+	Moves []IPPoolMove `json:"moves,omitempty"`
+
+	// FreeRangesAfter and LargestFreeBlockAfter describe the free IP
+	// addresses after the plan is applied.
+	FreeRangesAfter       []string `json:"freeRangesAfter,omitempty"`
+	LargestFreeBlockAfter int      `json:"largestFreeBlockAfter"`
+}
+
+// IPPoolMove replaces the IP ranges of the IPPool.
+type IPPoolMove struct {
+	IPPool    string   `json:"ippool"`
+	IPs       []string `json:"ips"`
+	TargetIPs []string `json:"targetIPs"`
+}
+
+// Worthwhile reports whether the plan grows the largest free block of IP
+// addresses of the Subnet.
+func (p *SubnetDefragPlan) Worthwhile() bool {
+	return len(p.Moves) != 0 && p.LargestFreeBlockAfter > p.LargestFreeBlock
+}
+
+// GenSubnetDefragPlan computes the plan to compact the free IP addresses of
+// the Subnet. The auto-created IPPools without any allocated IP address are
+// moved in order of their first IP address, each of them to the first block
+// of consecutive free IP addresses large enough for it. The ones with pinned
+// or excluded IP addresses are left alone.
+func GenSubnetDefragPlan(subnet *spiderpoolv1.SpiderSubnet, ipPools []*spiderpoolv1.SpiderIPPool) (*SubnetDefragPlan, error) {
+	ipVersion := *subnet.Spec.IPVersion
+	subnetIPs, err := spiderpoolip.AssembleTotalIPs(ipVersion, subnet.Spec.IPs, subnet.Spec.ExcludeIPs)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(subnetIPs, func(i, j int) bool {
+		return spiderpoolip.Cmp(subnetIPs[i], subnetIPs[j]) < 0
+	})
+
+	used := map[string]bool{}
+	type movable struct {
+		name    string
+		ips     []net.IP
+		ipRange []string
+	}
+	var movables []movable
+	for _, pool := range ipPools {
+		poolIPs, err := spiderpoolip.ParseIPRanges(ipVersion, pool.Spec.IPs)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range poolIPs {
+			used[ip.String()] = true
+		}
+
+		if isMovableIPPool(pool) && len(poolIPs) != 0 {
+			sort.Slice(poolIPs, func(i, j int) bool {
+				return spiderpoolip.Cmp(poolIPs[i], poolIPs[j]) < 0
+			})
+			movables = append(movables, movable{name: pool.Name, ips: poolIPs, ipRange: pool.Spec.IPs})
+		}
+	}
+	sort.Slice(movables, func(i, j int) bool {
+		return spiderpoolip.Cmp(movables[i].ips[0], movables[j].ips[0]) < 0
+	})
+
+	plan := &SubnetDefragPlan{Subnet: subnet.Name}
+	plan.FreeRanges, plan.FreeIPCount, plan.LargestFreeBlock, err = freeIPBlocks(ipVersion, subnetIPs, used)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range movables {
+		for _, ip := range m.ips {
+			delete(used, ip.String())
+		}
+
+		// the consecutive IPPool is only moved forward
+		targetIPs := firstFitIPBlock(subnetIPs, used, len(m.ips))
+		if targetIPs == nil || (spiderpoolip.Cmp(targetIPs[0], m.ips[0]) >= 0 && isConsecutiveIPs(m.ips)) {
+			targetIPs = m.ips
+		}
+		for _, ip := range targetIPs {
+			used[ip.String()] = true
+		}
+
+		ranges, err := spiderpoolip.ConvertIPsToIPRanges(ipVersion, m.ips)
+		if err != nil {
+			return nil, err
+		}
+		targetRanges, err := spiderpoolip.ConvertIPsToIPRanges(ipVersion, targetIPs)
+		if err != nil {
+			return nil, err
+		}
+		if reflect.DeepEqual(ranges, targetRanges) {
+			continue
+		}
+		plan.Moves = append(plan.Moves, IPPoolMove{
+			IPPool:    m.name,
+			IPs:       append([]string(nil), m.ipRange...),
+			TargetIPs: targetRanges,
+		})
+	}
+
+	plan.FreeRangesAfter, _, plan.LargestFreeBlockAfter, err = freeIPBlocks(ipVersion, subnetIPs, used)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// ApplySubnetDefragPlan moves the IPPools as planned, and returns how many of
+// them are moved. It stops at the first IPPool changed since the plan was
+// computed, whose following moves may overlap with it.
+func ApplySubnetDefragPlan(ctx context.Context, c client.Client, plan *SubnetDefragPlan) (int, error) {
+	for i, move := range plan.Moves {
+		var pool spiderpoolv1.SpiderIPPool
+		if err := c.Get(ctx, apitypes.NamespacedName{Name: move.IPPool}, &pool); err != nil {
+			return i, err
+		}
+		if !isMovableIPPool(&pool) || !reflect.DeepEqual(pool.Spec.IPs, move.IPs) {
+			return i, fmt.Errorf("IPPool '%s' has changed since the defragmentation plan was computed", pool.Name)
+		}
+
+		pool.Spec.IPs = move.TargetIPs
+		if err := c.Update(ctx, &pool); err != nil {
+			return i, fmt.Errorf("failed to move IPPool '%s' to %v: %w", pool.Name, move.TargetIPs, err)
+		}
+	}
+
+	return len(plan.Moves), nil
+}
+
+// isMovableIPPool reports whether the IP ranges of the IPPool could be
+// replaced without affecting any Pod.
+func isMovableIPPool(pool *spiderpoolv1.SpiderIPPool) bool {
+	if !ippoolmanager.IsAutoCreatedIPPool(pool) || pool.DeletionTimestamp != nil {
+		return false
+	}
+	if len(pool.Status.AllocatedIPs) != 0 || len(pool.Spec.ExcludeIPs) != 0 {
+		return false
+	}
+	if _, ok := pool.Annotations[constant.AnnoSpiderSubnetPoolIPs]; ok {
+		return false
+	}
+
+	return true
+}
+
+// firstFitIPBlock returns the first n consecutive IP addresses of the sorted
+// IP addresses which aren't used, nil if there's no such block.
+func firstFitIPBlock(sortedIPs []net.IP, used map[string]bool, n int) []net.IP {
+	var block []net.IP
+	for _, ip := range sortedIPs {
+		if used[ip.String()] {
+			block = nil
+			continue
+		}
+		if len(block) != 0 && !spiderpoolip.NextIP(block[len(block)-1]).Equal(ip) {
+			block = nil
+		}
+
+		block = append(block, ip)
+		if len(block) == n {
+			return block
+		}
+	}
+
+	return nil
+}
+
+// freeIPBlocks returns the IP ranges and the count of the sorted IP addresses
+// which aren't used, along with the size of the largest consecutive block.
+func freeIPBlocks(ipVersion types.IPVersion, sortedIPs []net.IP, used map[string]bool) ([]string, int, int, error) {
+	var freeIPs []net.IP
+	largest, current := 0, 0
+	for _, ip := range sortedIPs {
+		if used[ip.String()] {
+			current = 0
+			continue
+		}
+		if len(freeIPs) != 0 && !spiderpoolip.NextIP(freeIPs[len(freeIPs)-1]).Equal(ip) {
+			current = 0
+		}
+		freeIPs = append(freeIPs, ip)
+		current++
+		if current > largest {
+			largest = current
+		}
+	}
+
+	ranges, err := spiderpoolip.ConvertIPsToIPRanges(ipVersion, freeIPs)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	return ranges, len(freeIPs), largest, nil
+}
+
+func isConsecutiveIPs(sortedIPs []net.IP) bool {
+	for i := 1; i < len(sortedIPs); i++ {
+		if !spiderpoolip.NextIP(sortedIPs[i-1]).Equal(sortedIPs[i]) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package subnetmanager_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/subnetmanager"
+)
+
+var _ = Describe("Subnet defragmentation", Label("subnet_defrag_test"), func() {
+	Describe("GenSubnetDefragPlan", func() {
+		var subnetT *spiderpoolv1.SpiderSubnet
+		var busyPoolT, idlePoolT, manualPoolT *spiderpoolv1.SpiderIPPool
+
+		newAutoPool := func(name string, ips []string) *spiderpoolv1.SpiderIPPool {
+			return &spiderpoolv1.SpiderIPPool{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+					Labels: map[string]string{
+						constant.LabelIPPoolOwnerSpiderSubnet: "subnet",
+						constant.LabelIPPoolOwnerApplication:  "Deployment_default_" + name,
+					},
+				},
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion: pointer.Int64(constant.IPv4),
+					Subnet:    "172.18.40.0/24",
+					IPs:       ips,
+				},
+			}
+		}
+
+		BeforeEach(func() {
+			subnetT = &spiderpoolv1.SpiderSubnet{
+				ObjectMeta: metav1.ObjectMeta{Name: "subnet"},
+				Spec: spiderpoolv1.SubnetSpec{
+					IPVersion: pointer.Int64(constant.IPv4),
+					Subnet:    "172.18.40.0/24",
+					IPs:       []string{"172.18.40.1-172.18.40.10"},
+				},
+			}
+
+			busyPoolT = newAutoPool("busy", []string{"172.18.40.1-172.18.40.2"})
+			busyPoolT.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{
+				"172.18.40.1": spiderpoolv1.PoolIPAllocation{ContainerID: "container"},
+			}
+			idlePoolT = newAutoPool("idle", []string{"172.18.40.5-172.18.40.6"})
+			manualPoolT = &spiderpoolv1.SpiderIPPool{
+				ObjectMeta: metav1.ObjectMeta{Name: "manual"},
+				Spec: spiderpoolv1.IPPoolSpec{
+					IPVersion: pointer.Int64(constant.IPv4),
+					Subnet:    "172.18.40.0/24",
+					IPs:       []string{"172.18.40.9"},
+				},
+			}
+		})
+
+		It("moves the idle auto-created IPPools forward", func() {
+			plan, err := subnetmanager.GenSubnetDefragPlan(subnetT, []*spiderpoolv1.SpiderIPPool{busyPoolT, idlePoolT, manualPoolT})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(plan.FreeIPCount).To(Equal(5))
+			Expect(plan.FreeRanges).To(Equal([]string{"172.18.40.3-172.18.40.4", "172.18.40.7-172.18.40.8", "172.18.40.10"}))
+			Expect(plan.LargestFreeBlock).To(Equal(2))
+
+			Expect(plan.Moves).To(Equal([]subnetmanager.IPPoolMove{{
+				IPPool:    "idle",
+				IPs:       []string{"172.18.40.5-172.18.40.6"},
+				TargetIPs: []string{"172.18.40.3-172.18.40.4"},
+			}}))
+			Expect(plan.FreeRangesAfter).To(Equal([]string{"172.18.40.5-172.18.40.8", "172.18.40.10"}))
+			Expect(plan.LargestFreeBlockAfter).To(Equal(4))
+			Expect(plan.Worthwhile()).To(BeTrue())
+		})
+
+		It("leaves the IPPools with pinned IP addresses alone", func() {
+			idlePoolT.Annotations = map[string]string{constant.AnnoSpiderSubnetPoolIPs: "172.18.40.5-172.18.40.6"}
+
+			plan, err := subnetmanager.GenSubnetDefragPlan(subnetT, []*spiderpoolv1.SpiderIPPool{busyPoolT, idlePoolT, manualPoolT})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plan.Moves).To(BeEmpty())
+			Expect(plan.LargestFreeBlockAfter).To(Equal(plan.LargestFreeBlock))
+			Expect(plan.Worthwhile()).To(BeFalse())
+		})
+
+		It("doesn't move the compacted IPPools", func() {
+			idlePoolT.Spec.IPs = []string{"172.18.40.3-172.18.40.4"}
+
+			plan, err := subnetmanager.GenSubnetDefragPlan(subnetT, []*spiderpoolv1.SpiderIPPool{busyPoolT, idlePoolT, manualPoolT})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plan.Moves).To(BeEmpty())
+		})
+	})
+})
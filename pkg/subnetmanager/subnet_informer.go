@@ -11,6 +11,7 @@ import (
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -24,6 +25,7 @@ import (
 
 	"github.com/spidernet-io/spiderpool/pkg/constant"
 	"github.com/spidernet-io/spiderpool/pkg/election"
+	"github.com/spidernet-io/spiderpool/pkg/event"
 	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
 	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
 	clientset "github.com/spidernet-io/spiderpool/pkg/k8s/client/clientset/versioned"
@@ -57,6 +59,13 @@ type SubnetController struct {
 	ResyncPeriod            time.Duration
 	SubnetControllerWorkers int
 	MaxWorkqueueLength      int
+
+	// DefragCheckInterval is the interval to compute the defragmentation
+	// plans of all Subnets, it is disabled if not positive.
+	DefragCheckInterval time.Duration
+	// EnableDefragCompaction applies the defragmentation plans which grow
+	// the largest free block of IP addresses, rather than only reporting them.
+	EnableDefragCompaction bool
 }
 
 func (sc *SubnetController) SetupInformer(ctx context.Context, client clientset.Interface, leader election.SpiderLeaseElector) error {
@@ -138,7 +147,9 @@ func (sc *SubnetController) addEventHandlers(subnetInformer informers.SpiderSubn
 			oldIPPool := old.(*spiderpoolv1.SpiderIPPool)
 			newIPPool := new.(*spiderpoolv1.SpiderIPPool)
 			if reflect.DeepEqual(newIPPool.Spec.IPs, oldIPPool.Spec.IPs) &&
-				reflect.DeepEqual(newIPPool.Spec.ExcludeIPs, oldIPPool.Spec.ExcludeIPs) {
+				reflect.DeepEqual(newIPPool.Spec.ExcludeIPs, oldIPPool.Spec.ExcludeIPs) &&
+				!applicationIPPoolChanged(oldIPPool, newIPPool) &&
+				!vacatingIPPoolChanged(oldIPPool, newIPPool) {
 				return
 			}
 			sc.enqueueSubnetOnIPPoolChange(new)
@@ -199,6 +210,12 @@ func (sc *SubnetController) enqueueSubnetOnIPPoolChange(obj interface{}) {
 
 	sc.Workqueue.Add(ownerSubnet)
 	logger.Debug(MessageEnqueueSubnet)
+
+	// The SpiderSubnet on behalf of which the IPPool borrows IP addresses
+	// records the borrowed ones as well.
+	if borrowedBy, ok := ipPool.Labels[constant.LabelIPPoolBorrowedBy]; ok {
+		sc.Workqueue.Add(borrowedBy)
+	}
 }
 
 func (sc *SubnetController) run(ctx context.Context, workers int) error {
@@ -218,6 +235,10 @@ func (sc *SubnetController) run(ctx context.Context, workers int) error {
 		go wait.UntilWithContext(ctx, sc.runWorker, time.Second)
 	}
 
+	if sc.DefragCheckInterval > 0 {
+		go wait.UntilWithContext(ctx, sc.defragSubnets, sc.DefragCheckInterval)
+	}
+
 	logger.Info("Started workers")
 	<-ctx.Done()
 	logger.Info("Shutting down workers")
@@ -265,6 +286,16 @@ func (sc *SubnetController) syncHandler(ctx context.Context, subnetName string)
 		return fmt.Errorf("failed to sync reference for controller Subnet: %v", err)
 	}
 
+	if subnet.DeletionTimestamp == nil {
+		if err := sc.syncTemplatedIPPools(ctx, subnet); err != nil {
+			return fmt.Errorf("failed to sync the IPPools generated from the template of Subnet: %v", err)
+		}
+
+		if err := sc.vacateExcludedIPs(ctx, subnet); err != nil {
+			return fmt.Errorf("failed to vacate the IP addresses excluded by Subnet: %v", err)
+		}
+	}
+
 	subnetCopy := subnet.DeepCopy()
 	if err := sc.syncControlledIPPoolIPs(ctx, subnetCopy); err != nil {
 		return fmt.Errorf("failed to sync the IP ranges of controlled IPPools of Subnet: %v", err)
@@ -348,6 +379,26 @@ func (sc *SubnetController) syncControlledIPPoolIPs(ctx context.Context, subnet
 		controlledIPPools[pool.Name] = spiderpoolv1.PoolIPPreAllocation{IPs: ranges}
 	}
 	subnet.Status.ControlledIPPools = controlledIPPools
+	subnet.Status.ApplicationIPPools = genApplicationIPPools(ipPools)
+
+	// Record the IPPools borrowing IP addresses from the fallback subnets.
+	borrowedSelector := labels.Set{constant.LabelIPPoolBorrowedBy: subnet.Name}.AsSelector()
+	borrowingIPPools, err := sc.IPPoolsLister.List(borrowedSelector)
+	if err != nil {
+		return err
+	}
+
+	var borrowedIPPools spiderpoolv1.BorrowedIPPools
+	for _, pool := range borrowingIPPools {
+		if borrowedIPPools == nil {
+			borrowedIPPools = spiderpoolv1.BorrowedIPPools{}
+		}
+		borrowedIPPools[pool.Name] = spiderpoolv1.BorrowedIPPool{
+			Subnet: pool.Labels[constant.LabelIPPoolOwnerSpiderSubnet],
+			IPs:    pool.Spec.IPs,
+		}
+	}
+	subnet.Status.BorrowedIPPools = borrowedIPPools
 
 	// Update the count of total IP addresses.
 	totalIPCount := int64(len(subnetTotalIPs))
@@ -391,3 +442,58 @@ func (sc *SubnetController) removeFinalizer(ctx context.Context, subnet *spiderp
 
 	return nil
 }
+
+// defragSubnets computes the defragmentation plan of each Subnet, the plan
+// growing the largest free block of IP addresses is reported with an event,
+// and applied if the compaction is enabled.
+func (sc *SubnetController) defragSubnets(ctx context.Context) {
+	logger := logutils.FromContext(ctx)
+
+	subnets, err := sc.SubnetsLister.List(labels.Everything())
+	if err != nil {
+		logger.Sugar().Errorf("failed to list Subnets to defragment: %v", err)
+		return
+	}
+
+	for _, subnet := range subnets {
+		if subnet.DeletionTimestamp != nil {
+			continue
+		}
+
+		selector := labels.Set{constant.LabelIPPoolOwnerSpiderSubnet: subnet.Name}.AsSelector()
+		ipPools, err := sc.IPPoolsLister.List(selector)
+		if err != nil {
+			logger.Sugar().Errorf("failed to list IPPools of Subnet %s to defragment: %v", subnet.Name, err)
+			continue
+		}
+
+		plan, err := GenSubnetDefragPlan(subnet, ipPools)
+		if err != nil {
+			logger.Sugar().Errorf("failed to compute the defragmentation plan of Subnet %s: %v", subnet.Name, err)
+			continue
+		}
+		if !plan.Worthwhile() {
+			continue
+		}
+
+		if !sc.EnableDefragCompaction {
+			event.EventRecorder.Eventf(subnet, corev1.EventTypeNormal, constant.EventReasonDefragSubnet,
+				"Moving %d idle auto-created IPPools would grow the largest free IP block from %d to %d",
+				len(plan.Moves), plan.LargestFreeBlock, plan.LargestFreeBlockAfter)
+			continue
+		}
+
+		moved, err := ApplySubnetDefragPlan(ctx, sc.Client, plan)
+		if err != nil {
+			logger.Sugar().Warnf("failed to defragment Subnet %s after moving %d IPPools: %v", subnet.Name, moved, err)
+			event.EventRecorder.Eventf(subnet, corev1.EventTypeWarning, constant.EventReasonDefragSubnet,
+				"Defragmentation stopped after moving %d of %d idle auto-created IPPools: %v", moved, len(plan.Moves), err)
+			continue
+		}
+
+		logger.Sugar().Infof("defragment Subnet %s by moving %d IPPools", subnet.Name, moved)
+		event.EventRecorder.Eventf(subnet, corev1.EventTypeNormal, constant.EventReasonDefragSubnet,
+			"Moved %d idle auto-created IPPools, the largest free IP block grows from %d to %d",
+			moved, plan.LargestFreeBlock, plan.LargestFreeBlockAfter)
+	}
+}
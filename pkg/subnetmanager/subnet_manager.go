@@ -6,7 +6,9 @@ package subnetmanager
 import (
 	"context"
 	"fmt"
+	"strings"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	apitypes "k8s.io/apimachinery/pkg/types"
@@ -15,6 +17,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
 	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
 	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
 	"github.com/spidernet-io/spiderpool/pkg/logutils"
@@ -26,8 +29,10 @@ import (
 type SubnetManager interface {
 	GetSubnetByName(ctx context.Context, subnetName string) (*spiderpoolv1.SpiderSubnet, error)
 	ListSubnets(ctx context.Context, opts ...client.ListOption) (*spiderpoolv1.SpiderSubnetList, error)
-	AllocateEmptyIPPool(ctx context.Context, subnetMgrName string, podController types.PodTopController, podSelector *metav1.LabelSelector, ipNum int, ipVersion types.IPVersion, reclaimIPPool bool, ifName string) (*spiderpoolv1.SpiderIPPool, error)
+	AllocateEmptyIPPool(ctx context.Context, subnetMgrName string, podController types.PodTopController, podSelector *metav1.LabelSelector, ipNum int, pinnedIPs []string, ipVersion types.IPVersion, reclaimIPPool bool, ifName string) (*spiderpoolv1.SpiderIPPool, error)
 	CheckScaleIPPool(ctx context.Context, pool *spiderpoolv1.SpiderIPPool, subnetManagerName string, ipNum int) (bool, error)
+	ListAutoIPPools(ctx context.Context, matchLabels client.MatchingLabels) (*spiderpoolv1.SpiderIPPoolList, error)
+	ReclaimIPPool(ctx context.Context, pool *spiderpoolv1.SpiderIPPool, podAnno map[string]string) (bool, error)
 }
 
 type subnetManager struct {
@@ -74,10 +79,11 @@ func (sm *subnetManager) ListSubnets(ctx context.Context, opts ...client.ListOpt
 	return &subnetList, nil
 }
 
-// AllocateEmptyIPPool will create an empty IPPool and mark the status.AutoDesiredIPCount
+// AllocateEmptyIPPool will create an empty IPPool and mark the status.AutoDesiredIPCount,
+// the IPPool only takes the pinned IPs from the SpiderSubnet if they are specified.
 // notice: this function only serves for auto-created IPPool
 func (sm *subnetManager) AllocateEmptyIPPool(ctx context.Context, subnetName string, podController types.PodTopController,
-	podSelector *metav1.LabelSelector, ipNum int, ipVersion types.IPVersion, reclaimIPPool bool, ifName string) (*spiderpoolv1.SpiderIPPool, error) {
+	podSelector *metav1.LabelSelector, ipNum int, pinnedIPs []string, ipVersion types.IPVersion, reclaimIPPool bool, ifName string) (*spiderpoolv1.SpiderIPPool, error) {
 	if len(subnetName) == 0 {
 		return nil, fmt.Errorf("%w: spider subnet name must be specified", constant.ErrWrongInput)
 	}
@@ -96,16 +102,42 @@ func (sm *subnetManager) AllocateEmptyIPPool(ctx context.Context, subnetName str
 			constant.ErrWrongInput, subnet.Name)
 	}
 
+	// the IPPool borrows IPs from a fallback subnet if the subnet runs out of free IPs,
+	// except that it uses the pinned IPs which must belong to the subnet
+	borrowedBy := ""
+	if len(pinnedIPs) != 0 {
+		for _, ipRange := range pinnedIPs {
+			contains, err := spiderpoolip.ContainsIPRange(ipVersion, subnet.Spec.Subnet, ipRange)
+			if nil != err {
+				return nil, fmt.Errorf("%w: invalid pinned IPs '%s': %v", constant.ErrWrongInput, ipRange, err)
+			}
+			if !contains {
+				return nil, fmt.Errorf("%w: pinned IPs '%s' don't pertain to SpiderSubnet '%s' subnet '%s'",
+					constant.ErrWrongInput, ipRange, subnet.Name, subnet.Spec.Subnet)
+			}
+		}
+	} else {
+		lender, err := sm.findLenderSubnet(ctx, subnet, ipNum)
+		if nil != err {
+			return nil, err
+		}
+		if lender.Name != subnet.Name {
+			borrowedBy = subnet.Name
+			subnet = lender
+		}
+	}
+
 	sp := &spiderpoolv1.SpiderIPPool{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: controllers.SubnetPoolName(podController.Kind, podController.Namespace, podController.Name, ipVersion, ifName, podController.UID),
 		},
 		Spec: spiderpoolv1.IPPoolSpec{
-			Subnet:      subnet.Spec.Subnet,
-			Gateway:     subnet.Spec.Gateway,
-			Vlan:        subnet.Spec.Vlan,
-			Routes:      subnet.Spec.Routes,
-			PodAffinity: podSelector,
+			Subnet:            subnet.Spec.Subnet,
+			Gateway:           subnet.Spec.Gateway,
+			Vlan:              subnet.Spec.Vlan,
+			PodAffinity:       podSelector,
+			NamespaceAffinity: subnet.Spec.DefaultNamespaceAffinity.DeepCopy(),
+			NodeAffinity:      subnet.Spec.DefaultNodeAffinity.DeepCopy(),
 		},
 	}
 
@@ -127,11 +159,19 @@ func (sm *subnetManager) AllocateEmptyIPPool(ctx context.Context, subnetName str
 	if reclaimIPPool {
 		poolLabels[constant.LabelIPPoolReclaimIPPool] = constant.True
 	}
+	if borrowedBy != "" {
+		poolLabels[constant.LabelIPPoolBorrowedBy] = borrowedBy
+	}
 	sp.Labels = poolLabels
+	if len(pinnedIPs) != 0 {
+		sp.Annotations = map[string]string{
+			constant.AnnoSpiderSubnetPoolIPs: strings.Join(pinnedIPs, ","),
+		}
+	}
 
 	err = ctrl.SetControllerReference(subnet, sp, sm.Scheme)
 	if nil != err {
-		return nil, fmt.Errorf("failed to set SpiderIPPool %s owner reference with SpiderSubnet %s: %v", sp.Name, subnetName, err)
+		return nil, fmt.Errorf("failed to set SpiderIPPool %s owner reference with SpiderSubnet %s: %v", sp.Name, subnet.Name, err)
 	}
 
 	timeRecorder := metric.NewTimeRecorder()
@@ -147,6 +187,11 @@ func (sm *subnetManager) AllocateEmptyIPPool(ctx context.Context, subnetName str
 		return nil, err
 	}
 
+	// The routes of the Subnet are inherited rather than copied, so that
+	// they're synchronized once the Subnet is changed. They're set along
+	// with the desired IP number before any IP address is allocated.
+	sp.Status.InheritedRoutes = subnet.Spec.Routes
+
 	log.Sugar().Infof("try to update IPPool '%v' status DesiredIPNumber '%d'", sp, ipNum)
 	err = sm.ipPoolManager.UpdateDesiredIPNumber(ctx, sp, ipNum)
 	if nil != err {
@@ -157,6 +202,77 @@ func (sm *subnetManager) AllocateEmptyIPPool(ctx context.Context, subnetName str
 	return sp, nil
 }
 
+// findLenderSubnet returns the first fallback subnet of the given subnet with
+// enough free IPs for the auto-created IPPool. The subnet itself is returned if
+// it has enough free IPs, or none of its fallback subnets does.
+func (sm *subnetManager) findLenderSubnet(ctx context.Context, subnet *spiderpoolv1.SpiderSubnet, ipNum int) (*spiderpoolv1.SpiderSubnet, error) {
+	if len(subnet.Spec.FallbackSubnets) == 0 {
+		return subnet, nil
+	}
+
+	log := logutils.FromContext(ctx)
+	freeIPs, err := controllers.GenSubnetFreeIPs(subnet)
+	if nil != err {
+		return nil, fmt.Errorf("failed to generate SpiderSubnet '%s' free IPs, error: %v", subnet.Name, err)
+	}
+	if len(freeIPs) >= ipNum {
+		return subnet, nil
+	}
+
+	for _, name := range subnet.Spec.FallbackSubnets {
+		fallback, err := sm.GetSubnetByName(ctx, name)
+		if nil != err {
+			if apierrors.IsNotFound(err) {
+				log.Sugar().Warnf("fallback SpiderSubnet '%s' of SpiderSubnet '%s' doesn't exist", name, subnet.Name)
+				continue
+			}
+			return nil, err
+		}
+
+		if err := controllers.ValidateFallbackSubnet(subnet, fallback); nil != err {
+			log.Sugar().Warnf("skip fallback SpiderSubnet '%s': %v", name, err)
+			continue
+		}
+
+		fallbackFreeIPs, err := controllers.GenSubnetFreeIPs(fallback)
+		if nil != err {
+			return nil, fmt.Errorf("failed to generate SpiderSubnet '%s' free IPs, error: %v", fallback.Name, err)
+		}
+		if len(fallbackFreeIPs) >= ipNum {
+			log.Sugar().Infof("SpiderSubnet '%s' has only %d free IPs, borrow %d IPs from fallback SpiderSubnet '%s'",
+				subnet.Name, len(freeIPs), ipNum, fallback.Name)
+			return fallback, nil
+		}
+	}
+
+	log.Sugar().Warnf("neither SpiderSubnet '%s' nor its fallback SpiderSubnets %v have %d free IPs", subnet.Name, subnet.Spec.FallbackSubnets, ipNum)
+	return subnet, nil
+}
+
+// ListAutoIPPools lists the auto-created IPPools with the given labels, including
+// the ones borrowing IPs from the fallback subnets of the SpiderSubnet in labels.
+func (sm *subnetManager) ListAutoIPPools(ctx context.Context, matchLabels client.MatchingLabels) (*spiderpoolv1.SpiderIPPoolList, error) {
+	poolList, err := sm.ipPoolManager.ListIPPools(ctx, matchLabels)
+	if nil != err {
+		return nil, err
+	}
+
+	subnetName, ok := matchLabels[constant.LabelIPPoolOwnerSpiderSubnet]
+	if len(poolList.Items) != 0 || !ok {
+		return poolList, nil
+	}
+
+	borrowedLabels := client.MatchingLabels{}
+	for k, v := range matchLabels {
+		if k != constant.LabelIPPoolOwnerSpiderSubnet {
+			borrowedLabels[k] = v
+		}
+	}
+	borrowedLabels[constant.LabelIPPoolBorrowedBy] = subnetName
+
+	return sm.ipPoolManager.ListIPPools(ctx, borrowedLabels)
+}
+
 // CheckScaleIPPool will fetch some IPs from the specified subnet manager to expand the pool IPs
 func (sm *subnetManager) CheckScaleIPPool(ctx context.Context, pool *spiderpoolv1.SpiderIPPool, subnetName string, ipNum int) (bool, error) {
 	if pool == nil {
@@ -192,3 +308,23 @@ func (sm *subnetManager) CheckScaleIPPool(ctx context.Context, pool *spiderpoolv
 
 	return false, nil
 }
+
+// ReclaimIPPool reclaims the auto-created IPPool whose application is deleted
+// with the reclaim policy of the application Pod template or the SpiderSubnet.
+func (sm *subnetManager) ReclaimIPPool(ctx context.Context, pool *spiderpoolv1.SpiderIPPool, podAnno map[string]string) (bool, error) {
+	var subnet *spiderpoolv1.SpiderSubnet
+	if subnetName := ippoolmanager.GetAutoIPPoolSubnet(pool); subnetName != "" {
+		var err error
+		subnet, err = sm.GetSubnetByName(ctx, subnetName)
+		if client.IgnoreNotFound(err) != nil {
+			return false, err
+		}
+	}
+
+	policy, err := controllers.GetAutoPoolReclaimPolicy(podAnno, subnet)
+	if nil != err {
+		return false, err
+	}
+
+	return sm.ipPoolManager.ReclaimAutoIPPool(ctx, pool, policy)
+}
@@ -58,7 +58,7 @@ func (sw *SubnetWebhook) mutateSubnet(ctx context.Context, subnet *spiderpoolv1.
 		logger.Sugar().Infof("Set label %s: %s", constant.LabelSubnetCIDR, cidr)
 	}
 
-	if len(subnet.Spec.IPs) > 1 {
+	if len(subnet.Spec.IPs) > 1 || spiderpoolip.HasCompactIPRanges(*subnet.Spec.IPVersion, subnet.Spec.IPs) {
 		mergedIPs, err := spiderpoolip.MergeIPRanges(*subnet.Spec.IPVersion, subnet.Spec.IPs)
 		if err != nil {
 			return fmt.Errorf("failed to merge 'spec.ips': %v", err)
@@ -68,7 +68,7 @@ func (sw *SubnetWebhook) mutateSubnet(ctx context.Context, subnet *spiderpoolv1.
 		logger.Sugar().Debugf("Merge 'spec.ips':\n%v\n\nto:\n\n%v", subnet.Spec.IPs, mergedIPs)
 	}
 
-	if len(subnet.Spec.ExcludeIPs) > 1 {
+	if len(subnet.Spec.ExcludeIPs) > 1 || spiderpoolip.HasCompactIPRanges(*subnet.Spec.IPVersion, subnet.Spec.ExcludeIPs) {
 		mergedExcludeIPs, err := spiderpoolip.MergeIPRanges(*subnet.Spec.IPVersion, subnet.Spec.ExcludeIPs)
 		if err != nil {
 			return fmt.Errorf("failed to merge 'spec.excludeIPs': %v", err)
@@ -0,0 +1,98 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package subnetmanager
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/subnetmanager/controllers"
+)
+
+// ApplicationIPPoolFilter reports whether the auto-created IPPool of an
+// application is selected.
+type ApplicationIPPoolFilter func(appPool spiderpoolv1.ApplicationIPPool) bool
+
+// FilterApplicationKind selects the auto-created IPPools of the applications
+// of the kind.
+func FilterApplicationKind(kind string) ApplicationIPPoolFilter {
+	return func(appPool spiderpoolv1.ApplicationIPPool) bool {
+		return appPool.Kind == kind
+	}
+}
+
+// FilterApplicationNamespace selects the auto-created IPPools of the
+// applications in the namespace.
+func FilterApplicationNamespace(namespace string) ApplicationIPPoolFilter {
+	return func(appPool spiderpoolv1.ApplicationIPPool) bool {
+		return appPool.Namespace == namespace
+	}
+}
+
+// FilterApplication selects the auto-created IPPools of the application.
+func FilterApplication(kind, namespace, name string) ApplicationIPPoolFilter {
+	return func(appPool spiderpoolv1.ApplicationIPPool) bool {
+		return appPool.Kind == kind && appPool.Namespace == namespace && appPool.Name == name
+	}
+}
+
+// ListApplicationIPPools returns the auto-created IPPools in the status of the
+// Subnet selected by all the filters.
+func ListApplicationIPPools(subnet *spiderpoolv1.SpiderSubnet, filters ...ApplicationIPPoolFilter) []spiderpoolv1.ApplicationIPPool {
+	var appPools []spiderpoolv1.ApplicationIPPool
+OUTER:
+	for _, appPool := range subnet.Status.ApplicationIPPools {
+		for _, filter := range filters {
+			if !filter(appPool) {
+				continue OUTER
+			}
+		}
+		appPools = append(appPools, *appPool.DeepCopy())
+	}
+
+	return appPools
+}
+
+// genApplicationIPPools generates the usage of the auto-created IPPools by
+// application, sorted by pool name.
+func genApplicationIPPools(ipPools []*spiderpoolv1.SpiderIPPool) []spiderpoolv1.ApplicationIPPool {
+	var appPools []spiderpoolv1.ApplicationIPPool
+	for _, pool := range ipPools {
+		kind, namespace, name, found := controllers.ParseAppLabelValue(pool.Labels[constant.LabelIPPoolOwnerApplication])
+		if !found {
+			continue
+		}
+
+		appPool := spiderpoolv1.ApplicationIPPool{
+			Kind:             kind,
+			Namespace:        namespace,
+			Name:             name,
+			IPPool:           pool.Name,
+			DesiredIPCount:   pool.Status.AutoDesiredIPCount,
+			TotalIPCount:     pool.Status.TotalIPCount,
+			AllocatedIPCount: pool.Status.AllocatedIPCount,
+		}
+		appPools = append(appPools, *appPool.DeepCopy())
+	}
+
+	sort.Slice(appPools, func(i, j int) bool {
+		return appPools[i].IPPool < appPools[j].IPPool
+	})
+
+	return appPools
+}
+
+// applicationIPPoolChanged reports whether the usage of the auto-created
+// IPPool in the status of its Subnet changes.
+func applicationIPPoolChanged(oldIPPool, newIPPool *spiderpoolv1.SpiderIPPool) bool {
+	if _, ok := newIPPool.Labels[constant.LabelIPPoolOwnerApplication]; !ok {
+		return false
+	}
+
+	return !reflect.DeepEqual(oldIPPool.Status.AutoDesiredIPCount, newIPPool.Status.AutoDesiredIPCount) ||
+		!reflect.DeepEqual(oldIPPool.Status.TotalIPCount, newIPPool.Status.TotalIPCount) ||
+		!reflect.DeepEqual(oldIPPool.Status.AllocatedIPCount, newIPPool.Status.AllocatedIPCount)
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package subnetmanager_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/pointer"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/subnetmanager"
+)
+
+var _ = Describe("Subnet status", Label("subnet_status_test"), func() {
+	Describe("ListApplicationIPPools", func() {
+		var subnetT *spiderpoolv1.SpiderSubnet
+
+		BeforeEach(func() {
+			subnetT = &spiderpoolv1.SpiderSubnet{
+				Status: spiderpoolv1.SubnetStatus{
+					ApplicationIPPools: []spiderpoolv1.ApplicationIPPool{
+						{
+							Kind:             constant.KindDeployment,
+							Namespace:        "default",
+							Name:             "web",
+							IPPool:           "auto-deployment-default-web-v4-eth0",
+							DesiredIPCount:   pointer.Int64(3),
+							AllocatedIPCount: pointer.Int64(2),
+						},
+						{
+							Kind:      constant.KindStatefulSet,
+							Namespace: "default",
+							Name:      "db",
+							IPPool:    "auto-statefulset-default-db-v4-eth0",
+						},
+						{
+							Kind:      constant.KindDeployment,
+							Namespace: "kube-system",
+							Name:      "dns",
+							IPPool:    "auto-deployment-kube-system-dns-v4-eth0",
+						},
+					},
+				},
+			}
+		})
+
+		It("lists all auto-created IPPools without filters", func() {
+			appPools := subnetmanager.ListApplicationIPPools(subnetT)
+			Expect(appPools).To(HaveLen(3))
+		})
+
+		It("filters the auto-created IPPools by kind and namespace", func() {
+			appPools := subnetmanager.ListApplicationIPPools(subnetT,
+				subnetmanager.FilterApplicationKind(constant.KindDeployment),
+				subnetmanager.FilterApplicationNamespace("default"),
+			)
+			Expect(appPools).To(HaveLen(1))
+			Expect(appPools[0].Name).To(Equal("web"))
+			Expect(*appPools[0].DesiredIPCount).To(Equal(int64(3)))
+			Expect(*appPools[0].AllocatedIPCount).To(Equal(int64(2)))
+		})
+
+		It("filters the auto-created IPPools by application", func() {
+			appPools := subnetmanager.ListApplicationIPPools(subnetT,
+				subnetmanager.FilterApplication(constant.KindStatefulSet, "default", "db"),
+			)
+			Expect(appPools).To(HaveLen(1))
+			Expect(appPools[0].IPPool).To(Equal("auto-statefulset-default-db-v4-eth0"))
+
+			appPools = subnetmanager.ListApplicationIPPools(subnetT,
+				subnetmanager.FilterApplication(constant.KindStatefulSet, "default", "web"),
+			)
+			Expect(appPools).To(BeEmpty())
+		})
+	})
+})
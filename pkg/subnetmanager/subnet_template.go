@@ -0,0 +1,282 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package subnetmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// maxTemplateSliceHostBits limits the size of each CIDR slice of the
+// generated IPPools.
+const maxTemplateSliceHostBits = 16
+
+// GetSubnetIPPoolTemplate parses the IPPool template of the Subnet, nil is
+// returned if the Subnet has no template.
+func GetSubnetIPPoolTemplate(subnet *spiderpoolv1.SpiderSubnet) (*types.AnnoSubnetIPPoolTemplateValue, error) {
+	anno, ok := subnet.Annotations[constant.AnnoSpiderSubnetIPPoolTemplate]
+	if !ok {
+		return nil, nil
+	}
+
+	var tmpl types.AnnoSubnetIPPoolTemplateValue
+	if err := json.Unmarshal([]byte(anno), &tmpl); err != nil {
+		return nil, fmt.Errorf("%w, failed to parse the IPPool template: %v", constant.ErrWrongInput, err)
+	}
+
+	if tmpl.Scope != constant.IPPoolTemplateScopeNode && tmpl.Scope != constant.IPPoolTemplateScopeZone {
+		return nil, fmt.Errorf("%w, scope of the IPPool template must be '%s' or '%s'", constant.ErrWrongInput, constant.IPPoolTemplateScopeNode, constant.IPPoolTemplateScopeZone)
+	}
+	if tmpl.Scope == constant.IPPoolTemplateScopeZone && tmpl.ZoneLabel == "" {
+		tmpl.ZoneLabel = corev1.LabelTopologyZone
+	}
+
+	if subnet.Spec.IPVersion == nil {
+		return nil, fmt.Errorf("%w, IP version of the Subnet is not set", constant.ErrWrongInput)
+	}
+	ipNet, err := spiderpoolip.ParseCIDR(*subnet.Spec.IPVersion, subnet.Spec.Subnet)
+	if err != nil {
+		return nil, err
+	}
+	ones, bits := ipNet.Mask.Size()
+	if tmpl.PrefixLength <= ones || tmpl.PrefixLength > bits || bits-tmpl.PrefixLength > maxTemplateSliceHostBits {
+		return nil, fmt.Errorf("%w, prefix length of the IPPool template must be in (%d, %d] and leave at most %d host bits", constant.ErrWrongInput, ones, bits, maxTemplateSliceHostBits)
+	}
+
+	return &tmpl, nil
+}
+
+// syncTemplatedIPPools generates an IPPool for each selected node or zone
+// from the template of the Subnet, with a free slice of the Subnet's CIDR.
+// The generated IPPools whose nodes or zones are gone are deleted when they
+// have no IP allocations.
+func (sc *SubnetController) syncTemplatedIPPools(ctx context.Context, subnet *spiderpoolv1.SpiderSubnet) error {
+	logger := logutils.FromContext(ctx)
+
+	tmpl, err := GetSubnetIPPoolTemplate(subnet)
+	if err != nil || tmpl == nil {
+		return err
+	}
+
+	var nodeList corev1.NodeList
+	if err := sc.List(ctx, &nodeList, client.MatchingLabels(tmpl.NodeSelector)); err != nil {
+		return err
+	}
+
+	// The node affinities of the IPPools to generate, keyed by the nodes or
+	// zones.
+	affinities := map[string]map[string]string{}
+	for _, node := range nodeList.Items {
+		key := node.Name
+		hostname, ok := node.Labels[corev1.LabelHostname]
+		if !ok {
+			hostname = node.Name
+		}
+		affinity := map[string]string{corev1.LabelHostname: hostname}
+		if tmpl.Scope == constant.IPPoolTemplateScopeZone {
+			zone, ok := node.Labels[tmpl.ZoneLabel]
+			if !ok {
+				continue
+			}
+			key = zone
+			affinity = map[string]string{tmpl.ZoneLabel: zone}
+		}
+
+		if errs := validation.IsValidLabelValue(key); len(errs) != 0 {
+			logger.Sugar().Warnf("Skip generating IPPool for %s %s: %s", tmpl.Scope, key, strings.Join(errs, "; "))
+			continue
+		}
+		affinities[key] = affinity
+	}
+
+	selector := labels.Set{constant.LabelIPPoolOwnerSpiderSubnet: subnet.Name}.AsSelector()
+	ipPools, err := sc.IPPoolsLister.List(selector)
+	if err != nil {
+		return err
+	}
+
+	usedIPs := map[string]struct{}{}
+	for _, pool := range ipPools {
+		ips, err := spiderpoolip.ParseIPRanges(*subnet.Spec.IPVersion, pool.Spec.IPs)
+		if err != nil {
+			return err
+		}
+		for _, ip := range ips {
+			usedIPs[ip.String()] = struct{}{}
+		}
+
+		key, ok := pool.Labels[constant.LabelIPPoolTemplateKey]
+		if !ok {
+			continue
+		}
+		if _, ok := affinities[key]; ok {
+			delete(affinities, key)
+			continue
+		}
+
+		if pool.DeletionTimestamp == nil && len(pool.Status.AllocatedIPs) == 0 {
+			if err := sc.Delete(ctx, pool); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+			logger.Sugar().Infof("Delete the IPPool %s generated for the %s %s which is gone", pool.Name, tmpl.Scope, key)
+		}
+	}
+
+	if len(affinities) == 0 {
+		return nil
+	}
+
+	subnetTotalIPs, err := spiderpoolip.AssembleTotalIPs(*subnet.Spec.IPVersion, subnet.Spec.IPs, subnet.Spec.ExcludeIPs)
+	if err != nil {
+		return err
+	}
+	ipNet, err := spiderpoolip.ParseCIDR(*subnet.Spec.IPVersion, subnet.Spec.Subnet)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(affinities))
+	for key := range affinities {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	slices := newCIDRSlicer(ipNet, tmpl.PrefixLength)
+	for _, key := range keys {
+		var ips []net.IP
+		for {
+			first, last, ok := slices.next()
+			if !ok {
+				return fmt.Errorf("%w, no free slice with prefix length %d for the IPPool of %s %s", constant.ErrIPUsedOut, tmpl.PrefixLength, tmpl.Scope, key)
+			}
+			ips, err = spiderpoolip.ParseIPRange(*subnet.Spec.IPVersion, fmt.Sprintf("%s-%s", first, last))
+			if err != nil {
+				return err
+			}
+			ips = spiderpoolip.IPsIntersectionSet(ips, subnetTotalIPs, false)
+			if len(ips) != 0 && !containsAnyIP(usedIPs, ips) {
+				break
+			}
+		}
+
+		ranges, err := spiderpoolip.ConvertIPsToIPRanges(*subnet.Spec.IPVersion, ips)
+		if err != nil {
+			return err
+		}
+
+		pool := genTemplatedIPPool(subnet, key, ranges, affinities[key])
+		if err := ctrl.SetControllerReference(subnet, pool, sc.Scheme); err != nil {
+			return err
+		}
+		if err := sc.Create(ctx, pool); err != nil {
+			return fmt.Errorf("failed to create the IPPool %s for %s %s: %v", pool.Name, tmpl.Scope, key, err)
+		}
+		for _, ip := range ips {
+			usedIPs[ip.String()] = struct{}{}
+		}
+		logger.Sugar().Infof("Generate IPPool %s with IP ranges %v for %s %s", pool.Name, ranges, tmpl.Scope, key)
+	}
+
+	return nil
+}
+
+func genTemplatedIPPool(subnet *spiderpoolv1.SpiderSubnet, key string, ipRanges []string, nodeAffinity map[string]string) *spiderpoolv1.SpiderIPPool {
+	version := constant.LabelIPPoolVersionV4
+	if *subnet.Spec.IPVersion == constant.IPv6 {
+		version = constant.LabelIPPoolVersionV6
+	}
+
+	return &spiderpoolv1.SpiderIPPool{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("%s-%s", subnet.Name, strings.ReplaceAll(strings.ToLower(key), "_", "-")),
+			Labels: map[string]string{
+				constant.LabelIPPoolOwnerSpiderSubnet: subnet.Name,
+				constant.LabelIPPoolTemplateKey:       key,
+				constant.LabelIPPoolVersion:           version,
+			},
+		},
+		Spec: spiderpoolv1.IPPoolSpec{
+			IPVersion:    pointer.Int64(*subnet.Spec.IPVersion),
+			Subnet:       subnet.Spec.Subnet,
+			IPs:          ipRanges,
+			Gateway:      subnet.Spec.Gateway,
+			Vlan:         subnet.Spec.Vlan,
+			NodeAffinity: &metav1.LabelSelector{MatchLabels: nodeAffinity},
+		},
+	}
+}
+
+func containsAnyIP(set map[string]struct{}, ips []net.IP) bool {
+	for _, ip := range ips {
+		if _, ok := set[ip.String()]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cidrSlicer iterates the slices of a CIDR with a longer prefix length.
+type cidrSlicer struct {
+	base  *big.Int
+	size  *big.Int
+	index *big.Int
+	count *big.Int
+	bytes int
+}
+
+func newCIDRSlicer(ipNet *net.IPNet, prefixLength int) *cidrSlicer {
+	ones, bits := ipNet.Mask.Size()
+	ip := ipNet.IP.To4()
+	if ip == nil {
+		ip = ipNet.IP.To16()
+	}
+
+	return &cidrSlicer{
+		base:  new(big.Int).SetBytes(ip),
+		size:  new(big.Int).Lsh(big.NewInt(1), uint(bits-prefixLength)),
+		index: big.NewInt(0),
+		count: new(big.Int).Lsh(big.NewInt(1), uint(prefixLength-ones)),
+		bytes: len(ip),
+	}
+}
+
+// next returns the first and last IP addresses of the next slice.
+func (s *cidrSlicer) next() (net.IP, net.IP, bool) {
+	if s.index.Cmp(s.count) >= 0 {
+		return nil, nil, false
+	}
+
+	first := new(big.Int).Add(s.base, new(big.Int).Mul(s.index, s.size))
+	last := new(big.Int).Add(first, new(big.Int).Sub(s.size, big.NewInt(1)))
+	s.index.Add(s.index, big.NewInt(1))
+
+	return s.toIP(first), s.toIP(last), true
+}
+
+func (s *cidrSlicer) toIP(n *big.Int) net.IP {
+	ip := make(net.IP, s.bytes)
+	n.FillBytes(ip)
+
+	return ip
+}
@@ -0,0 +1,117 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package subnetmanager
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/event"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+// vacateExcludedIPs drains the IP addresses of the controlled IPPools which
+// are excluded by the Subnet. The ones still allocated are marked as being
+// vacated so that they're no longer allocated, and the released ones are
+// removed from the IPPools.
+func (sc *SubnetController) vacateExcludedIPs(ctx context.Context, subnet *spiderpoolv1.SpiderSubnet) error {
+	logger := logutils.FromContext(ctx)
+
+	ipVersion := *subnet.Spec.IPVersion
+	excludeIPs, err := spiderpoolip.ParseIPRanges(ipVersion, subnet.Spec.ExcludeIPs)
+	if err != nil {
+		return err
+	}
+
+	selector := labels.Set{constant.LabelIPPoolOwnerSpiderSubnet: subnet.Name}.AsSelector()
+	ipPools, err := sc.IPPoolsLister.List(selector)
+	if err != nil {
+		return err
+	}
+
+	for _, pool := range ipPools {
+		if pool.DeletionTimestamp != nil {
+			continue
+		}
+
+		poolIPs, err := spiderpoolip.ParseIPRanges(ipVersion, pool.Spec.IPs)
+		if err != nil {
+			return err
+		}
+
+		var vacating, released []net.IP
+		for _, ip := range spiderpoolip.IPsIntersectionSet(poolIPs, excludeIPs, false) {
+			if _, ok := pool.Status.AllocatedIPs[ip.String()]; ok {
+				vacating = append(vacating, ip)
+			} else {
+				released = append(released, ip)
+			}
+		}
+
+		vacatingRanges, err := spiderpoolip.ConvertIPsToIPRanges(ipVersion, vacating)
+		if err != nil {
+			return err
+		}
+		if len(released) == 0 && reflect.DeepEqual(vacatingRanges, ippoolmanager.GetVacatingIPs(pool)) {
+			continue
+		}
+
+		poolCopy := pool.DeepCopy()
+		if len(released) != 0 {
+			poolCopy.Spec.IPs, err = spiderpoolip.ConvertIPsToIPRanges(ipVersion, spiderpoolip.IPsDiffSet(poolIPs, released, false))
+			if err != nil {
+				return err
+			}
+		}
+		if len(vacatingRanges) != 0 {
+			if poolCopy.Annotations == nil {
+				poolCopy.Annotations = map[string]string{}
+			}
+			poolCopy.Annotations[constant.AnnoIPPoolVacatingIPs] = strings.Join(vacatingRanges, ",")
+		} else {
+			delete(poolCopy.Annotations, constant.AnnoIPPoolVacatingIPs)
+		}
+
+		if err := sc.Update(ctx, poolCopy); err != nil {
+			return err
+		}
+
+		releasedRanges, err := spiderpoolip.ConvertIPsToIPRanges(ipVersion, released)
+		if err != nil {
+			return err
+		}
+		logger.Sugar().Infof("Vacate the IP addresses %v of IPPool %s excluded by Subnet, and remove the released ones %v", vacatingRanges, pool.Name, releasedRanges)
+		if len(vacatingRanges) != 0 {
+			event.EventRecorder.Eventf(poolCopy, corev1.EventTypeWarning, constant.EventReasonVacateIPs,
+				"IP addresses %v excluded by Subnet %s are being vacated, they're no longer allocated", vacatingRanges, subnet.Name)
+		}
+		if len(releasedRanges) != 0 {
+			event.EventRecorder.Eventf(poolCopy, corev1.EventTypeNormal, constant.EventReasonVacateIPs,
+				"IP addresses %v excluded by Subnet %s are vacated and removed", releasedRanges, subnet.Name)
+			event.EventRecorder.Eventf(subnet, corev1.EventTypeNormal, constant.EventReasonVacateIPs,
+				"IP addresses %v excluded are vacated by IPPool %s", releasedRanges, pool.Name)
+		}
+	}
+
+	return nil
+}
+
+// vacatingIPPoolChanged reports whether the IP addresses being vacated by the
+// IPPool may be released.
+func vacatingIPPoolChanged(oldIPPool, newIPPool *spiderpoolv1.SpiderIPPool) bool {
+	if _, ok := newIPPool.Annotations[constant.AnnoIPPoolVacatingIPs]; !ok {
+		return false
+	}
+
+	return !reflect.DeepEqual(oldIPPool.Status.AllocatedIPs, newIPPool.Status.AllocatedIPs)
+}
@@ -8,12 +8,15 @@ import (
 	"fmt"
 	"strconv"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apitypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	"github.com/spidernet-io/spiderpool/pkg/constant"
 	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
 	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
 	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/subnetmanager/controllers"
 	"github.com/spidernet-io/spiderpool/pkg/types"
 )
 
@@ -24,7 +27,10 @@ var (
 	excludeIPsField        *field.Path = field.NewPath("spec").Child("excludeIPs")
 	gatewayField           *field.Path = field.NewPath("spec").Child("gateway")
 	routesField            *field.Path = field.NewPath("spec").Child("routes")
+	fallbackSubnetsField   *field.Path = field.NewPath("spec").Child("fallbackSubnets")
 	controlledIPPoolsField *field.Path = field.NewPath("status").Child("controlledIPPools")
+	ippoolTemplateField    *field.Path = field.NewPath("metadata").Child("annotations").Key(constant.AnnoSpiderSubnetIPPoolTemplate)
+	reclaimPolicyField     *field.Path = field.NewPath("metadata").Child("annotations").Key(constant.AnnoSpiderSubnetReclaimPolicy)
 )
 
 func (sw *SubnetWebhook) validateCreateSubnet(ctx context.Context, subnet *spiderpoolv1.SpiderSubnet) field.ErrorList {
@@ -103,11 +109,79 @@ func (sw *SubnetWebhook) validateSubnetSpec(ctx context.Context, subnet *spiderp
 		return err
 	}
 
-	return validateSubnetRoutes(*subnet.Spec.IPVersion, subnet.Spec.Subnet, subnet.Spec.Routes)
+	if err := validateSubnetRoutes(*subnet.Spec.IPVersion, subnet.Spec.Subnet, subnet.Spec.Routes); err != nil {
+		return err
+	}
+
+	if err := sw.validateSubnetFallbackSubnets(ctx, subnet); err != nil {
+		return err
+	}
+
+	if err := validateSubnetIPPoolTemplate(subnet); err != nil {
+		return err
+	}
+
+	return validateSubnetReclaimPolicy(subnet)
+}
+
+// validateSubnetFallbackSubnets checks the fallback subnets which exist, the
+// ones created later are checked when the IPs are borrowed.
+func (sw *SubnetWebhook) validateSubnetFallbackSubnets(ctx context.Context, subnet *spiderpoolv1.SpiderSubnet) *field.Error {
+	names := map[string]struct{}{}
+	for i, name := range subnet.Spec.FallbackSubnets {
+		if name == subnet.Name {
+			return field.Invalid(fallbackSubnetsField.Index(i), name, "could not fall back to the Subnet itself")
+		}
+		if _, ok := names[name]; ok {
+			return field.Duplicate(fallbackSubnetsField.Index(i), name)
+		}
+		names[name] = struct{}{}
+
+		var fallback spiderpoolv1.SpiderSubnet
+		if err := sw.Get(ctx, apitypes.NamespacedName{Name: name}, &fallback); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return field.InternalError(fallbackSubnetsField.Index(i), fmt.Errorf("failed to get fallback Subnet %s: %v", name, err))
+		}
+
+		if err := controllers.ValidateFallbackSubnet(subnet, &fallback); err != nil {
+			return field.Invalid(fallbackSubnetsField.Index(i), name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func validateSubnetIPPoolTemplate(subnet *spiderpoolv1.SpiderSubnet) *field.Error {
+	if _, err := GetSubnetIPPoolTemplate(subnet); err != nil {
+		return field.Invalid(
+			ippoolTemplateField,
+			subnet.Annotations[constant.AnnoSpiderSubnetIPPoolTemplate],
+			err.Error(),
+		)
+	}
+
+	return nil
+}
+
+func validateSubnetReclaimPolicy(subnet *spiderpoolv1.SpiderSubnet) *field.Error {
+	if _, err := controllers.GetReclaimPolicy(subnet.Annotations); err != nil {
+		return field.Invalid(
+			reclaimPolicyField,
+			subnet.Annotations[constant.AnnoSpiderSubnetReclaimPolicy],
+			err.Error(),
+		)
+	}
+
+	return nil
 }
 
+// validateSubnetIPInUse forbids removing the IP ranges used by the controlled
+// IPPools from 'spec.ips'. Excluding them with 'spec.excludeIPs' is allowed,
+// they're vacated by the controlled IPPools once released.
 func validateSubnetIPInUse(subnet *spiderpoolv1.SpiderSubnet) *field.Error {
-	totalIPs, err := spiderpoolip.AssembleTotalIPs(*subnet.Spec.IPVersion, subnet.Spec.IPs, subnet.Spec.ExcludeIPs)
+	totalIPs, err := spiderpoolip.AssembleTotalIPs(*subnet.Spec.IPVersion, subnet.Spec.IPs, nil)
 	if err != nil {
 		return field.InternalError(ipsField, fmt.Errorf("failed to assemble the total IP addresses of the Subnet %s: %v", subnet.Name, err))
 	}
@@ -122,7 +196,7 @@ func validateSubnetIPInUse(subnet *spiderpoolv1.SpiderSubnet) *field.Error {
 			ranges, _ := spiderpoolip.ConvertIPsToIPRanges(*subnet.Spec.IPVersion, invalidIPs)
 			return field.Forbidden(
 				ipsField,
-				fmt.Sprintf("remove some IP ranges %v that is being used by IPPool %s, total IP addresses of an Subnet are jointly determined by 'spec.ips' and 'spec.excludeIPs'", ranges, poolName),
+				fmt.Sprintf("remove some IP ranges %v that is being used by IPPool %s, exclude them with 'spec.excludeIPs' to vacate them instead", ranges, poolName),
 			)
 		}
 	}
@@ -19,6 +19,7 @@ import (
 	"github.com/spidernet-io/spiderpool/pkg/constant"
 	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
 	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/reportonly"
 )
 
 var WebhookLogger *zap.Logger
@@ -28,6 +29,10 @@ type SubnetWebhook struct {
 
 	EnableIPv4 bool
 	EnableIPv6 bool
+
+	// ReportOnly admits the requests which would be denied, the denials
+	// are logged and counted only.
+	ReportOnly bool
 }
 
 func (sw *SubnetWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
@@ -35,10 +40,15 @@ func (sw *SubnetWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
 		WebhookLogger = logutils.Logger.Named("Subnet-Webhook")
 	}
 
+	var validator webhook.CustomValidator = sw
+	if sw.ReportOnly {
+		validator = reportonly.NewValidator(constant.SpiderSubnetKind, sw)
+	}
+
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(&spiderpoolv1.SpiderSubnet{}).
 		WithDefaulter(sw).
-		WithValidator(sw).
+		WithValidator(validator).
 		Complete()
 }
 
@@ -52,7 +62,7 @@ func (sw *SubnetWebhook) Default(ctx context.Context, obj runtime.Object) error
 		zap.String("SubnetName", subnet.Name),
 		zap.String("Operation", "DEFAULT"),
 	)
-	logger.Sugar().Debugf("Request Subnet: %+v", *subnet)
+	logger.Sugar().Debugf("Request Subnet: %v", subnet)
 
 	if err := sw.mutateSubnet(logutils.IntoContext(ctx, logger), subnet); err != nil {
 		logger.Sugar().Errorf("Failed to mutate Subnet: %v", err)
@@ -71,7 +81,7 @@ func (sw *SubnetWebhook) ValidateCreate(ctx context.Context, obj runtime.Object)
 		zap.String("SubnetName", subnet.Name),
 		zap.String("Operation", "CREATE"),
 	)
-	logger.Sugar().Debugf("Request Subnet: %+v", *subnet)
+	logger.Sugar().Debugf("Request Subnet: %v", subnet)
 
 	if errs := sw.validateCreateSubnet(logutils.IntoContext(ctx, logger), subnet); len(errs) != 0 {
 		logger.Sugar().Errorf("Failed to create Subnet: %v", errs.ToAggregate().Error())
@@ -94,8 +104,8 @@ func (sw *SubnetWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runt
 		zap.String("SubnetName", newSubnet.Name),
 		zap.String("Operation", "UPDATE"),
 	)
-	logger.Sugar().Debugf("Request old Subnet: %+v", *oldSubnet)
-	logger.Sugar().Debugf("Request new Subnet: %+v", *newSubnet)
+	logger.Sugar().Debugf("Request old Subnet: %v", oldSubnet)
+	logger.Sugar().Debugf("Request new Subnet: %v", newSubnet)
 
 	if newSubnet.DeletionTimestamp != nil {
 		if !controllerutil.ContainsFinalizer(newSubnet, constant.SpiderFinalizer) {
@@ -675,6 +675,139 @@ var _ = Describe("SubnetWebhook", Label("subnet_webhook_test"), func() {
 				})
 			})
 
+			When("Validating the IPPool template", func() {
+				BeforeEach(func() {
+					subnetT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					subnetT.Spec.Subnet = "172.18.0.0/16"
+					subnetT.Spec.IPs = append(subnetT.Spec.IPs, "172.18.0.1-172.18.255.254")
+				})
+
+				It("inputs invalid scope", func() {
+					subnetT.SetAnnotations(map[string]string{
+						constant.AnnoSpiderSubnetIPPoolTemplate: `{"scope":"rack","prefixLength":24}`,
+					})
+
+					ctx := context.TODO()
+					err := subnetWebhook.ValidateCreate(ctx, subnetT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs the prefix length shorter than 'spec.subnet'", func() {
+					subnetT.SetAnnotations(map[string]string{
+						constant.AnnoSpiderSubnetIPPoolTemplate: `{"scope":"node","prefixLength":12}`,
+					})
+
+					ctx := context.TODO()
+					err := subnetWebhook.ValidateCreate(ctx, subnetT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs valid template", func() {
+					subnetT.SetAnnotations(map[string]string{
+						constant.AnnoSpiderSubnetIPPoolTemplate: `{"scope":"zone","prefixLength":24}`,
+					})
+
+					ctx := context.TODO()
+					err := subnetWebhook.ValidateCreate(ctx, subnetT)
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+
+			When("Validating 'spec.fallbackSubnets'", func() {
+				BeforeEach(func() {
+					subnetT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					subnetT.Spec.Subnet = "172.18.40.0/24"
+					subnetT.Spec.IPs = append(subnetT.Spec.IPs, "172.18.40.2-172.18.40.3")
+				})
+
+				It("falls back to the Subnet itself", func() {
+					subnetT.Spec.FallbackSubnets = []string{subnetName}
+
+					ctx := context.TODO()
+					err := subnetWebhook.ValidateCreate(ctx, subnetT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs duplicate fallback Subnets", func() {
+					subnetT.Spec.FallbackSubnets = []string{existSubnetName, existSubnetName}
+
+					ctx := context.TODO()
+					err := subnetWebhook.ValidateCreate(ctx, subnetT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("falls back to the Subnet with a different VLAN", func() {
+					existSubnetT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					existSubnetT.Spec.Subnet = "172.18.41.0/24"
+					existSubnetT.Spec.IPs = append(existSubnetT.Spec.IPs, "172.18.41.2-172.18.41.3")
+					existSubnetT.Spec.Vlan = pointer.Int64(100)
+
+					ctx := context.TODO()
+					err := fakeClient.Create(ctx, existSubnetT)
+					Expect(err).NotTo(HaveOccurred())
+
+					subnetT.Spec.FallbackSubnets = []string{existSubnetName}
+					err = subnetWebhook.ValidateCreate(ctx, subnetT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("falls back to the Subnet not created yet", func() {
+					subnetT.Spec.FallbackSubnets = []string{existSubnetName}
+
+					ctx := context.TODO()
+					err := subnetWebhook.ValidateCreate(ctx, subnetT)
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+
+			When("Validating the reclaim policy", func() {
+				BeforeEach(func() {
+					subnetT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					subnetT.Spec.Subnet = "172.18.40.0/24"
+					subnetT.Spec.IPs = append(subnetT.Spec.IPs, "172.18.40.2-172.18.40.3")
+				})
+
+				It("inputs unknown policy", func() {
+					subnetT.SetAnnotations(map[string]string{
+						constant.AnnoSpiderSubnetReclaimPolicy: "Never",
+					})
+
+					ctx := context.TODO()
+					err := subnetWebhook.ValidateCreate(ctx, subnetT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs Delayed policy without minutes", func() {
+					subnetT.SetAnnotations(map[string]string{
+						constant.AnnoSpiderSubnetReclaimPolicy: constant.ReclaimPolicyDelayed,
+					})
+
+					ctx := context.TODO()
+					err := subnetWebhook.ValidateCreate(ctx, subnetT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs Retain policy with minutes", func() {
+					subnetT.SetAnnotations(map[string]string{
+						constant.AnnoSpiderSubnetReclaimPolicy: constant.ReclaimPolicyRetain + ":10",
+					})
+
+					ctx := context.TODO()
+					err := subnetWebhook.ValidateCreate(ctx, subnetT)
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				})
+
+				It("inputs valid Delayed policy", func() {
+					subnetT.SetAnnotations(map[string]string{
+						constant.AnnoSpiderSubnetReclaimPolicy: constant.ReclaimPolicyDelayed + ":30",
+					})
+
+					ctx := context.TODO()
+					err := subnetWebhook.ValidateCreate(ctx, subnetT)
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+
 			It("creates IPv4 Subnet with all fields valid", func() {
 				subnetT.Spec.IPVersion = pointer.Int64(constant.IPv4)
 				subnetT.Spec.Subnet = "172.18.40.0/24"
@@ -1070,6 +1203,32 @@ var _ = Describe("SubnetWebhook", Label("subnet_webhook_test"), func() {
 					err := subnetWebhook.ValidateUpdate(ctx, subnetT, newSubnetT)
 					Expect(apierrors.IsInvalid(err)).To(BeTrue())
 				})
+
+				It("excludes IP range that is being used by IPPool", func() {
+					subnetT.Spec.IPVersion = pointer.Int64(constant.IPv4)
+					subnetT.Spec.Subnet = "172.18.40.0/24"
+					subnetT.Spec.IPs = append(subnetT.Spec.IPs,
+						[]string{
+							"172.18.40.1-172.18.40.2",
+							"172.18.40.10",
+						}...,
+					)
+
+					subnetT.Status.ControlledIPPools = spiderpoolv1.PoolIPPreAllocations{
+						"pool": spiderpoolv1.PoolIPPreAllocation{
+							IPs: []string{
+								"172.18.40.10",
+							},
+						},
+					}
+
+					newSubnetT := subnetT.DeepCopy()
+					newSubnetT.Spec.ExcludeIPs = append(newSubnetT.Spec.ExcludeIPs, "172.18.40.10")
+
+					ctx := context.TODO()
+					err := subnetWebhook.ValidateUpdate(ctx, subnetT, newSubnetT)
+					Expect(err).NotTo(HaveOccurred())
+				})
 			})
 
 			It("deletes Subnet", func() {
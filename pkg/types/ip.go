@@ -3,6 +3,8 @@
 
 package types
 
+import "github.com/spidernet-io/spiderpool/api/v1/agent/models"
+
 type IPVersion = int64
 
 type Vlan = int64
@@ -11,4 +13,15 @@ type IPAndCID struct {
 	IP          string
 	ContainerID string
 	Node        string
+	PodUID      string
+}
+
+// AllocationResult is the IP address allocated from an IPPool, with the
+// routes of the IPPool and the way the gateway of the IP address works.
+type AllocationResult struct {
+	IP           *models.IPConfig
+	PoolUID      string
+	Routes       []*models.Route
+	CleanGateway bool
+	Stripe       int
 }
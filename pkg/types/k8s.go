@@ -6,6 +6,7 @@ package types
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	apitypes "k8s.io/apimachinery/pkg/types"
@@ -26,6 +27,7 @@ type PodTopController struct {
 type AnnoPodIPPoolValue struct {
 	IPv4Pools []string `json:"ipv4,omitempty"`
 	IPv6Pools []string `json:"ipv6,omitempty"`
+	Striping  bool     `json:"striping,omitempty"`
 }
 
 type AnnoPodIPPoolsValue []AnnoIPPoolItem
@@ -35,6 +37,7 @@ type AnnoIPPoolItem struct {
 	IPv4Pools    []string `json:"ipv4,omitempty"`
 	IPv6Pools    []string `json:"ipv6,omitempty"`
 	CleanGateway bool     `json:"cleangateway"`
+	Striping     bool     `json:"striping,omitempty"`
 }
 
 type AnnoPodRoutesValue []AnnoRouteItem
@@ -44,6 +47,10 @@ type AnnoRouteItem struct {
 	Gw  string `json:"gw"`
 }
 
+// AnnoPodMACValue maps the NICs to their fixed MAC addresses, the MAC
+// address "auto" is derived from the IP address of the NIC.
+type AnnoPodMACValue map[string]string
+
 type AnnoNSDefautlV4PoolValue []string
 
 type AnnoNSDefautlV6PoolValue []string
@@ -56,12 +63,37 @@ type ClusterDefaultPoolConfig struct {
 	ClusterSubnetDefaultFlexibleIPNumber int
 }
 
+// ThirdPartyController is a third-party workload controller which the
+// auto-created IPPools are created and scaled for, like Deployments. The
+// workload must describe its Pods with 'spec.selector' and 'spec.template'.
+type ThirdPartyController struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	// Resource is the plural resource name of the workload, such as 'clonesets'.
+	Resource string `yaml:"resource"`
+	// ReplicasPath is the JSONPath to the replicas of the workload, it
+	// defaults to '{.spec.replicas}'.
+	ReplicasPath string `yaml:"replicasPath"`
+}
+
+// AutoPoolReclaimPolicy is the policy to reclaim the auto-created IPPools
+// once their application is deleted.
+type AutoPoolReclaimPolicy struct {
+	// Policy is one of 'Immediate', 'Retain' and 'Delayed'.
+	Policy string
+	// Delay is the grace period of the 'Delayed' policy.
+	Delay time.Duration
+}
+
 type PodSubnetAnnoConfig struct {
 	MultipleSubnets []AnnoSubnetItem
 	SingleSubnet    *AnnoSubnetItem
 	FlexibleIPNum   *int
 	AssignIPNum     int
 	ReclaimIPPool   bool
+	PinnedIPv4IPs   []string
+	PinnedIPv6IPs   []string
+	DualStack       bool
 }
 
 func (in *PodSubnetAnnoConfig) String() string {
@@ -74,12 +106,40 @@ func (in *PodSubnetAnnoConfig) String() string {
 		`SingleSubnet:` + strings.Replace(strings.Replace(in.SingleSubnet.String(), "AnnoSubnetItem", "", 1), `&`, ``, 1) + `,`,
 		`FlexibleIPNum:` + stringutil.ValueToStringGenerated(in.FlexibleIPNum) + `,`,
 		`AssignIPNumber:` + fmt.Sprintf("%v", in.AssignIPNum) + `,`,
-		`ReclaimIPPool:` + fmt.Sprintf("%v", in.ReclaimIPPool),
+		`ReclaimIPPool:` + fmt.Sprintf("%v", in.ReclaimIPPool) + `,`,
+		`PinnedIPv4IPs:` + fmt.Sprintf("%v", in.PinnedIPv4IPs) + `,`,
+		`PinnedIPv6IPs:` + fmt.Sprintf("%v", in.PinnedIPv6IPs) + `,`,
+		`DualStack:` + fmt.Sprintf("%v", in.DualStack),
 		`}`,
 	}, "")
 	return s
 }
 
+// AnnoSubnetIPPoolTemplateValue is the template on SpiderSubnet to generate
+// an IPPool for each node or zone.
+type AnnoSubnetIPPoolTemplateValue struct {
+	// Scope is "node" or "zone".
+	Scope string `json:"scope"`
+	// PrefixLength is the length of the CIDR slice of each IPPool.
+	PrefixLength int `json:"prefixLength"`
+	// NodeSelector selects the nodes to generate IPPools for, all nodes
+	// are selected if empty.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// ZoneLabel is the node label of zones, "topology.kubernetes.io/zone"
+	// by default.
+	ZoneLabel string `json:"zoneLabel,omitempty"`
+}
+
+// AnnoDualStackSubnetValue is the shorthand of a pair of IPv4 and IPv6
+// SpiderSubnet CR names, the NIC and the IP number of their auto-created
+// IPPools.
+type AnnoDualStackSubnetValue struct {
+	Interface      string `json:"interface,omitempty"`
+	IPv4           string `json:"ipv4"`
+	IPv6           string `json:"ipv6"`
+	IPPoolIPNumber string `json:"ippoolIPNumber,omitempty"`
+}
+
 // AnnoSubnetItem describes the SpiderSubnet CR names and NIC
 type AnnoSubnetItem struct {
 	Interface string   `json:"interface,omitempty"`
@@ -0,0 +1,270 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+// Package convert provides the conversions between the IP allocation formats
+// of Spiderpool, which are the IP allocation details recorded in Endpoints,
+// the IP configurations and routes in the IPAM responses of the agent, and
+// the routes in the spec of IPPools and annotations of Pods. They are stable
+// for the CNI plugins and tools out of this repository to reuse.
+package convert
+
+import (
+	"github.com/asaskevich/govalidator"
+
+	"github.com/spidernet-io/spiderpool/api/v1/agent/models"
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// IPDetailsToIPConfigsAndAllRoutes converts the IP allocation details
+// recorded in Endpoints to the IP configurations and routes, including the
+// default routes of the primary IP addresses.
+func IPDetailsToIPConfigsAndAllRoutes(details []spiderpoolv1.IPAllocationDetail) ([]*models.IPConfig, []*models.Route) {
+	var ips []*models.IPConfig
+	var routes []*models.Route
+	for _, d := range details {
+		nic := d.NIC
+		primary := d.Stripe == nil || *d.Stripe == 0
+		var mac string
+		if d.MAC != nil {
+			mac = *d.MAC
+		}
+
+		if d.IPv4 != nil {
+			version := constant.IPv4
+			var ipv4Gateway string
+			if d.IPv4Gateway != nil {
+				ipv4Gateway = *d.IPv4Gateway
+				if primary {
+					routes = append(routes, GenDefaultRoute(nic, ipv4Gateway))
+				}
+			}
+			ips = append(ips, &models.IPConfig{
+				Address: d.IPv4,
+				Gateway: ipv4Gateway,
+				IPPool:  *d.IPv4Pool,
+				Nic:     &nic,
+				Version: &version,
+				Vlan:    *d.Vlan,
+				Mac:     mac,
+			})
+		}
+
+		if d.IPv6 != nil {
+			version := constant.IPv6
+			var ipv6Gateway string
+			if d.IPv6Gateway != nil {
+				ipv6Gateway = *d.IPv6Gateway
+				if primary {
+					routes = append(routes, GenDefaultRoute(nic, ipv6Gateway))
+				}
+			}
+			ips = append(ips, &models.IPConfig{
+				Address: d.IPv6,
+				Gateway: ipv6Gateway,
+				IPPool:  *d.IPv6Pool,
+				Nic:     &nic,
+				Version: &version,
+				Vlan:    *d.Vlan,
+				Mac:     mac,
+			})
+		}
+
+		routes = append(routes, SpecRoutesToOAIRoutes(d.NIC, d.Routes)...)
+	}
+
+	return ips, routes
+}
+
+// ResultsToIPConfigsAndAllRoutes converts the IP allocation results to
+// the IP configurations and routes, including the default routes of the IP
+// addresses of stripe 0 whose gateways are not cleaned.
+func ResultsToIPConfigsAndAllRoutes(results []*types.AllocationResult) ([]*models.IPConfig, []*models.Route) {
+	var ips []*models.IPConfig
+	var routes []*models.Route
+	for _, r := range results {
+		ips = append(ips, r.IP)
+		routes = append(routes, r.Routes...)
+
+		// Only the IP address of stripe 0 provides the default route.
+		if r.CleanGateway || r.Stripe != 0 {
+			continue
+		}
+
+		if r.IP.Gateway != "" {
+			routes = append(routes, GenDefaultRoute(*r.IP.Nic, r.IP.Gateway))
+		}
+	}
+
+	return ips, routes
+}
+
+// GenDefaultRoute returns the default route of the NIC via the gateway, or
+// nil if the gateway is not a valid IP address.
+func GenDefaultRoute(nic, gateway string) *models.Route {
+	var route *models.Route
+	if govalidator.IsIPv4(gateway) {
+		dst := "0.0.0.0/0"
+		route = &models.Route{
+			IfName: &nic,
+			Dst:    &dst,
+			Gw:     &gateway,
+		}
+	}
+
+	if govalidator.IsIPv6(gateway) {
+		dst := "::/0"
+		route = &models.Route{
+			IfName: &nic,
+			Dst:    &dst,
+			Gw:     &gateway,
+		}
+	}
+
+	return route
+}
+
+// ResultsToIPDetails converts the IP allocation results to the IP
+// allocation details to be recorded in Endpoints.
+func ResultsToIPDetails(results []*types.AllocationResult) []spiderpoolv1.IPAllocationDetail {
+	// The IP addresses of different stripes of a NIC are recorded in
+	// separate details.
+	type nicStripe struct {
+		nic    string
+		stripe int
+	}
+
+	nicToDetail := map[nicStripe]*spiderpoolv1.IPAllocationDetail{}
+	var cleanGateway *bool
+	for _, r := range results {
+		var gateway *string
+		if r.IP.Gateway != "" {
+			gateway = new(string)
+			*gateway = r.IP.Gateway
+			if cleanGateway == nil {
+				cleanGateway = new(bool)
+				*cleanGateway = r.CleanGateway
+			}
+		}
+		var mac *string
+		if r.IP.Mac != "" {
+			mac = new(string)
+			*mac = r.IP.Mac
+		}
+		var poolUID *string
+		if r.PoolUID != "" {
+			poolUID = new(string)
+			*poolUID = r.PoolUID
+		}
+		routes := OAIRoutesToSpecRoutes(r.Routes)
+		key := nicStripe{nic: *r.IP.Nic, stripe: r.Stripe}
+		if d, ok := nicToDetail[key]; ok {
+			if *r.IP.Version == constant.IPv4 {
+				d.IPv4 = r.IP.Address
+				d.IPv4Pool = &r.IP.IPPool
+				d.IPv4PoolUID = poolUID
+				d.IPv4Gateway = gateway
+				d.CleanGateway = cleanGateway
+				d.Routes = append(d.Routes, routes...)
+			} else {
+				d.IPv6 = r.IP.Address
+				d.IPv6Pool = &r.IP.IPPool
+				d.IPv6PoolUID = poolUID
+				d.IPv6Gateway = gateway
+				d.CleanGateway = cleanGateway
+				d.Routes = append(d.Routes, routes...)
+			}
+			continue
+		}
+
+		var stripe *int
+		if r.Stripe != 0 {
+			stripe = new(int)
+			*stripe = r.Stripe
+		}
+
+		if *r.IP.Version == constant.IPv4 {
+			nicToDetail[key] = &spiderpoolv1.IPAllocationDetail{
+				NIC:          *r.IP.Nic,
+				IPv4:         r.IP.Address,
+				IPv4Pool:     &r.IP.IPPool,
+				IPv4PoolUID:  poolUID,
+				Vlan:         &r.IP.Vlan,
+				IPv4Gateway:  gateway,
+				CleanGateway: cleanGateway,
+				Routes:       routes,
+				Stripe:       stripe,
+				MAC:          mac,
+			}
+		} else {
+			nicToDetail[key] = &spiderpoolv1.IPAllocationDetail{
+				NIC:          *r.IP.Nic,
+				IPv6:         r.IP.Address,
+				IPv6Pool:     &r.IP.IPPool,
+				IPv6PoolUID:  poolUID,
+				Vlan:         &r.IP.Vlan,
+				IPv6Gateway:  gateway,
+				CleanGateway: cleanGateway,
+				Routes:       routes,
+				Stripe:       stripe,
+				MAC:          mac,
+			}
+		}
+	}
+
+	details := []spiderpoolv1.IPAllocationDetail{}
+	for _, d := range nicToDetail {
+		details = append(details, *d)
+	}
+
+	return details
+}
+
+// AnnoPodRoutesToOAIRoutes converts the routes in the annotation of
+// Pods to the routes without NIC.
+func AnnoPodRoutesToOAIRoutes(annoPodRoutes types.AnnoPodRoutesValue) []*models.Route {
+	var routes []*models.Route
+	for _, r := range annoPodRoutes {
+		dst := r.Dst
+		gw := r.Gw
+		routes = append(routes, &models.Route{
+			IfName: new(string),
+			Dst:    &dst,
+			Gw:     &gw,
+		})
+	}
+
+	return routes
+}
+
+// SpecRoutesToOAIRoutes converts the routes in the spec of IPPools to
+// the routes of the NIC.
+func SpecRoutesToOAIRoutes(nic string, specRoutes []spiderpoolv1.Route) []*models.Route {
+	var routes []*models.Route
+	for _, r := range specRoutes {
+		dst := r.Dst
+		gw := r.Gw
+		routes = append(routes, &models.Route{
+			IfName: &nic,
+			Dst:    &dst,
+			Gw:     &gw,
+		})
+	}
+
+	return routes
+}
+
+// OAIRoutesToSpecRoutes converts the routes to the ones recorded in
+// IP allocation details.
+func OAIRoutesToSpecRoutes(oaiRoutes []*models.Route) []spiderpoolv1.Route {
+	var routes []spiderpoolv1.Route
+	for _, r := range oaiRoutes {
+		routes = append(routes, spiderpoolv1.Route{
+			Dst: *r.Dst,
+			Gw:  *r.Gw,
+		})
+	}
+
+	return routes
+}
@@ -0,0 +1,171 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package convert_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/pointer"
+
+	"github.com/spidernet-io/spiderpool/api/v1/agent/models"
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+	"github.com/spidernet-io/spiderpool/pkg/utils/convert"
+)
+
+var _ = Describe("Convert", Label("convert_test"), func() {
+	var ipv4Result, ipv6Result *types.AllocationResult
+
+	BeforeEach(func() {
+		ipv4Result = &types.AllocationResult{
+			IP: &models.IPConfig{
+				Address: pointer.String("172.18.40.10/24"),
+				Gateway: "172.18.40.1",
+				IPPool:  "default-ipv4-ippool",
+				Nic:     pointer.String("eth0"),
+				Version: pointer.Int64(constant.IPv4),
+				Vlan:    0,
+			},
+			Routes: []*models.Route{
+				{
+					IfName: pointer.String("eth0"),
+					Dst:    pointer.String("192.168.40.0/24"),
+					Gw:     pointer.String("172.18.40.40"),
+				},
+			},
+		}
+		ipv6Result = &types.AllocationResult{
+			IP: &models.IPConfig{
+				Address: pointer.String("abcd:1234::a/120"),
+				Gateway: "abcd:1234::1",
+				IPPool:  "default-ipv6-ippool",
+				Nic:     pointer.String("eth0"),
+				Version: pointer.Int64(constant.IPv6),
+				Vlan:    0,
+			},
+		}
+	})
+
+	Describe("Test GenDefaultRoute", func() {
+		It("generates IPv4 default route", func() {
+			route := convert.GenDefaultRoute("eth0", "172.18.40.1")
+			Expect(route).To(Equal(&models.Route{
+				IfName: pointer.String("eth0"),
+				Dst:    pointer.String("0.0.0.0/0"),
+				Gw:     pointer.String("172.18.40.1"),
+			}))
+		})
+
+		It("generates IPv6 default route", func() {
+			route := convert.GenDefaultRoute("eth0", "abcd:1234::1")
+			Expect(*route.Dst).To(Equal("::/0"))
+		})
+
+		It("inputs invalid gateway", func() {
+			route := convert.GenDefaultRoute("eth0", constant.InvalidGateway)
+			Expect(route).To(BeNil())
+		})
+	})
+
+	Describe("Test ResultsToIPConfigsAndAllRoutes", func() {
+		It("appends default routes", func() {
+			ips, routes := convert.ResultsToIPConfigsAndAllRoutes([]*types.AllocationResult{ipv4Result, ipv6Result})
+			Expect(ips).To(Equal([]*models.IPConfig{ipv4Result.IP, ipv6Result.IP}))
+			Expect(routes).To(HaveLen(3))
+			Expect(*routes[1].Dst).To(Equal("0.0.0.0/0"))
+			Expect(*routes[2].Dst).To(Equal("::/0"))
+		})
+
+		It("does not append default routes if the gateway is cleaned", func() {
+			ipv4Result.CleanGateway = true
+			ipv6Result.Stripe = 1
+			_, routes := convert.ResultsToIPConfigsAndAllRoutes([]*types.AllocationResult{ipv4Result, ipv6Result})
+			Expect(routes).To(Equal(ipv4Result.Routes))
+		})
+	})
+
+	Describe("Test ResultsToIPDetails", func() {
+		It("merges the dual-stack IP addresses of the same NIC", func() {
+			details := convert.ResultsToIPDetails([]*types.AllocationResult{ipv4Result, ipv6Result})
+			Expect(details).To(HaveLen(1))
+			Expect(details[0].NIC).To(Equal("eth0"))
+			Expect(details[0].IPv4).To(Equal(ipv4Result.IP.Address))
+			Expect(*details[0].IPv4Pool).To(Equal("default-ipv4-ippool"))
+			Expect(*details[0].IPv4Gateway).To(Equal("172.18.40.1"))
+			Expect(details[0].IPv6).To(Equal(ipv6Result.IP.Address))
+			Expect(*details[0].IPv6Pool).To(Equal("default-ipv6-ippool"))
+			Expect(*details[0].CleanGateway).To(BeFalse())
+			Expect(details[0].Routes).To(Equal([]spiderpoolv1.Route{{Dst: "192.168.40.0/24", Gw: "172.18.40.40"}}))
+			Expect(details[0].Stripe).To(BeNil())
+		})
+
+		It("records the IP addresses of different stripes separately", func() {
+			ipv6Result.IP.Version = pointer.Int64(constant.IPv4)
+			ipv6Result.Stripe = 1
+			details := convert.ResultsToIPDetails([]*types.AllocationResult{ipv4Result, ipv6Result})
+			Expect(details).To(HaveLen(2))
+		})
+
+		It("records the UIDs of IPPools", func() {
+			ipv4Result.PoolUID = "ddc0bfb5-0b33-4c4a-b84c-2a1c3e07ee3a"
+			details := convert.ResultsToIPDetails([]*types.AllocationResult{ipv4Result, ipv6Result})
+			Expect(details).To(HaveLen(1))
+			Expect(*details[0].IPv4PoolUID).To(Equal("ddc0bfb5-0b33-4c4a-b84c-2a1c3e07ee3a"))
+			Expect(details[0].IPv6PoolUID).To(BeNil())
+		})
+	})
+
+	Describe("Test IPDetailsToIPConfigsAndAllRoutes", func() {
+		It("converts back the results", func() {
+			details := convert.ResultsToIPDetails([]*types.AllocationResult{ipv4Result, ipv6Result})
+			ips, routes := convert.IPDetailsToIPConfigsAndAllRoutes(details)
+			Expect(ips).To(ConsistOf(ipv4Result.IP, ipv6Result.IP))
+			Expect(routes).To(HaveLen(3))
+		})
+
+		It("converts back the fixed MAC address", func() {
+			ipv4Result.IP.Mac = "0a:58:ac:12:28:0a"
+			ipv6Result.IP.Mac = "0a:58:ac:12:28:0a"
+			details := convert.ResultsToIPDetails([]*types.AllocationResult{ipv4Result, ipv6Result})
+			Expect(details).To(HaveLen(1))
+			Expect(*details[0].MAC).To(Equal("0a:58:ac:12:28:0a"))
+
+			ips, _ := convert.IPDetailsToIPConfigsAndAllRoutes(details)
+			Expect(ips).To(ConsistOf(ipv4Result.IP, ipv6Result.IP))
+		})
+
+		It("does not generate default routes for the secondary stripes", func() {
+			details := []spiderpoolv1.IPAllocationDetail{
+				{
+					NIC:         "eth0",
+					IPv4:        pointer.String("172.18.40.10/24"),
+					IPv4Pool:    pointer.String("default-ipv4-ippool"),
+					IPv4Gateway: pointer.String("172.18.40.1"),
+					Vlan:        pointer.Int64(0),
+					Stripe:      pointer.Int(1),
+				},
+			}
+			ips, routes := convert.IPDetailsToIPConfigsAndAllRoutes(details)
+			Expect(ips).To(HaveLen(1))
+			Expect(routes).To(BeEmpty())
+		})
+	})
+
+	Describe("Test route conversions", func() {
+		It("converts the routes of Pod annotation", func() {
+			routes := convert.AnnoPodRoutesToOAIRoutes(types.AnnoPodRoutesValue{{Dst: "192.168.40.0/24", Gw: "172.18.40.40"}})
+			Expect(routes).To(HaveLen(1))
+			Expect(*routes[0].IfName).To(BeEmpty())
+			Expect(*routes[0].Dst).To(Equal("192.168.40.0/24"))
+		})
+
+		It("converts the routes of IPPool spec back and forth", func() {
+			specRoutes := []spiderpoolv1.Route{{Dst: "192.168.40.0/24", Gw: "172.18.40.40"}}
+			routes := convert.SpecRoutesToOAIRoutes("eth0", specRoutes)
+			Expect(*routes[0].IfName).To(Equal("eth0"))
+			Expect(convert.OAIRoutesToSpecRoutes(routes)).To(Equal(specRoutes))
+		})
+	})
+})
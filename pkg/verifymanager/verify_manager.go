@@ -0,0 +1,387 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package verifymanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/election"
+	"github.com/spidernet-io/spiderpool/pkg/event"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/metric"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+const (
+	canaryContainerName = "canary"
+	pollInterval        = 2 * time.Second
+)
+
+type VerifyManagerConfig struct {
+	// IPPoolName is the dedicated test IPPool which the canary Pods
+	// allocate IP addresses from.
+	IPPoolName string
+	// Namespace is where the canary Pods are created.
+	Namespace string
+	// Image is the image of canary Pods, it needs a shell and ping.
+	Image string
+
+	IntervalDuration time.Duration
+	TimeoutDuration  time.Duration
+}
+
+func setDefaultsForVerifyManagerConfig(config VerifyManagerConfig) VerifyManagerConfig {
+	if config.Namespace == "" {
+		config.Namespace = metav1.NamespaceDefault
+	}
+
+	if config.Image == "" {
+		config.Image = "busybox"
+	}
+
+	if config.IntervalDuration <= 0 {
+		config.IntervalDuration = 10 * time.Minute
+	}
+
+	if config.TimeoutDuration <= 0 {
+		config.TimeoutDuration = 2 * time.Minute
+	}
+
+	return config
+}
+
+// VerifyManager continuously verifies the whole IPAM path. Periodically, it
+// creates a canary Pod on each node with an IP address allocated from the
+// dedicated test IPPool, checks the allocation, the connectivity from the
+// Pod to the gateway and the release of the IP address after the Pod is
+// deleted. The results are reported as metrics and the condition Verified
+// of the test IPPool.
+type VerifyManager interface {
+	Start(ctx context.Context) error
+}
+
+type verifyManager struct {
+	config        VerifyManagerConfig
+	client        client.Client
+	ipPoolManager ippoolmanager.IPPoolManager
+	leader        election.SpiderLeaseElector
+}
+
+func NewVerifyManager(config VerifyManagerConfig, client client.Client, ipPoolManager ippoolmanager.IPPoolManager, leader election.SpiderLeaseElector) (VerifyManager, error) {
+	if config.IPPoolName == "" {
+		return nil, fmt.Errorf("test IPPool %w", constant.ErrMissingRequiredParam)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("k8s client %w", constant.ErrMissingRequiredParam)
+	}
+	if ipPoolManager == nil {
+		return nil, fmt.Errorf("ippool manager %w", constant.ErrMissingRequiredParam)
+	}
+	if leader == nil {
+		return nil, fmt.Errorf("leader elector %w", constant.ErrMissingRequiredParam)
+	}
+
+	return &verifyManager{
+		config:        setDefaultsForVerifyManagerConfig(config),
+		client:        client,
+		ipPoolManager: ipPoolManager,
+		leader:        leader,
+	}, nil
+}
+
+func (vm *verifyManager) Start(ctx context.Context) error {
+	logger := logutils.Logger.Named("Self-Verification")
+	ctx = logutils.IntoContext(ctx, logger)
+
+	go func() {
+		ticker := time.NewTicker(vm.config.IntervalDuration)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				logger.Info("Self verification is stopped")
+				return
+			case <-ticker.C:
+				if !vm.leader.IsElected() {
+					continue
+				}
+				vm.verify(ctx)
+			}
+		}
+	}()
+
+	logger.Sugar().Infof("Self verification with IPPool %s is started, interval: %s", vm.config.IPPoolName, vm.config.IntervalDuration)
+
+	return nil
+}
+
+// verify runs a round of verification on all ready nodes which match the
+// node affinity of the test IPPool.
+func (vm *verifyManager) verify(ctx context.Context) {
+	logger := logutils.FromContext(ctx)
+	start := time.Now()
+
+	ipPool, err := vm.ipPoolManager.GetIPPoolByName(ctx, vm.config.IPPoolName)
+	if err != nil {
+		logger.Sugar().Errorf("Failed to get test IPPool %s: %v", vm.config.IPPoolName, err)
+		return
+	}
+	if ipPool.Spec.IPVersion == nil {
+		logger.Sugar().Warnf("IP version of test IPPool %s is not set yet, skip this round", vm.config.IPPoolName)
+		return
+	}
+
+	// Clean up the canary Pods left over by an interrupted round.
+	if err := vm.client.DeleteAllOf(ctx, &corev1.Pod{},
+		client.InNamespace(vm.config.Namespace),
+		client.MatchingLabels{constant.LabelSelfVerification: constant.True},
+		client.GracePeriodSeconds(0),
+	); err != nil {
+		logger.Sugar().Warnf("Failed to clean up stale canary Pods: %v", err)
+	}
+
+	nodes, err := vm.getNodesToVerify(ctx, ipPool)
+	if err != nil {
+		logger.Sugar().Errorf("Failed to get nodes to verify: %v", err)
+		return
+	}
+	if len(nodes) == 0 {
+		logger.Warn("No node is available for self verification, skip this round")
+		return
+	}
+
+	var lock sync.Mutex
+	failures := map[string]error{}
+	var wg sync.WaitGroup
+	for _, node := range nodes {
+		wg.Add(1)
+		go func(nodeName string) {
+			defer wg.Done()
+			metric.SelfVerificationTotalCounts.Add(ctx, 1)
+			if err := vm.verifyNode(ctx, ipPool, nodeName); err != nil {
+				metric.SelfVerificationFailureCounts.Add(ctx, 1)
+				lock.Lock()
+				failures[nodeName] = err
+				lock.Unlock()
+			}
+		}(node)
+	}
+	wg.Wait()
+
+	duration := time.Since(start)
+	metric.SelfVerificationLatestDurationSeconds.Record(duration.Seconds())
+
+	condition := metav1.Condition{
+		Type:    constant.IPPoolConditionVerified,
+		Status:  metav1.ConditionTrue,
+		Reason:  constant.IPPoolReasonVerificationSucceeded,
+		Message: fmt.Sprintf("verified on %d nodes", len(nodes)),
+	}
+	if len(failures) != 0 {
+		var items []string
+		for node, err := range failures {
+			items = append(items, fmt.Sprintf("%s: %v", node, err))
+		}
+		sort.Strings(items)
+
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = constant.IPPoolReasonVerificationFailed
+		condition.Message = fmt.Sprintf("failed on %d/%d nodes: %s", len(failures), len(nodes), strings.Join(items, "; "))
+		logger.Sugar().Warnf("Self verification failed in %s, %s", duration, condition.Message)
+		event.EventRecorder.Event(ipPool, corev1.EventTypeWarning, constant.EventReasonSelfVerificationFailed, condition.Message)
+	} else {
+		logger.Sugar().Infof("Self verification succeeded in %s, %s", duration, condition.Message)
+	}
+
+	if err := vm.ipPoolManager.SetIPPoolCondition(ctx, ipPool.Name, condition); err != nil {
+		logger.Sugar().Errorf("Failed to set condition %s of test IPPool %s: %v", condition.Type, ipPool.Name, err)
+	}
+}
+
+// getNodesToVerify returns the names of ready and schedulable nodes which
+// match the node affinity of the IPPool.
+func (vm *verifyManager) getNodesToVerify(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool) ([]string, error) {
+	selector := labels.Everything()
+	if ipPool.Spec.NodeAffinity != nil {
+		var err error
+		selector, err = metav1.LabelSelectorAsSelector(ipPool.Spec.NodeAffinity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var nodeList corev1.NodeList
+	if err := vm.client.List(ctx, &nodeList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	var nodes []string
+	for _, node := range nodeList.Items {
+		if node.Spec.Unschedulable || node.DeletionTimestamp != nil {
+			continue
+		}
+		for _, c := range node.Status.Conditions {
+			if c.Type == corev1.NodeReady && c.Status == corev1.ConditionTrue {
+				nodes = append(nodes, node.Name)
+				break
+			}
+		}
+	}
+
+	return nodes, nil
+}
+
+// verifyNode creates a canary Pod on the node and checks the allocation,
+// the connectivity to the gateway and the release of the IP address.
+func (vm *verifyManager) verifyNode(ctx context.Context, ipPool *spiderpoolv1.SpiderIPPool, nodeName string) error {
+	logger := logutils.FromContext(ctx).With(zap.String("Node", nodeName))
+
+	pod, err := vm.genCanaryPod(ipPool, nodeName)
+	if err != nil {
+		return err
+	}
+	if err := vm.client.Create(ctx, pod); err != nil {
+		return fmt.Errorf("failed to create canary Pod: %v", err)
+	}
+	logger.Sugar().Debugf("Create canary Pod %s/%s", pod.Namespace, pod.Name)
+
+	key := apitypes.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
+	var ip string
+	verifyErr := wait.PollImmediateWithContext(ctx, pollInterval, vm.config.TimeoutDuration, func(ctx context.Context) (bool, error) {
+		if err := vm.client.Get(ctx, key, pod); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+
+		if ip == "" {
+			ip = getPodIPInIPPool(pod, ipPool)
+		}
+
+		switch pod.Status.Phase {
+		case corev1.PodSucceeded:
+			return true, nil
+		case corev1.PodFailed:
+			return false, fmt.Errorf("no connectivity to gateway %s", pointer.StringDeref(ipPool.Spec.Gateway, ""))
+		}
+
+		return false, nil
+	})
+
+	if err := vm.client.Delete(ctx, pod, client.GracePeriodSeconds(0)); client.IgnoreNotFound(err) != nil {
+		logger.Sugar().Warnf("Failed to delete canary Pod %s/%s: %v", pod.Namespace, pod.Name, err)
+	}
+
+	if ip == "" {
+		if verifyErr != nil && verifyErr != wait.ErrWaitTimeout {
+			return fmt.Errorf("allocation: %v", verifyErr)
+		}
+		return fmt.Errorf("allocation: no IP address is allocated from IPPool %s within %s", ipPool.Name, vm.config.TimeoutDuration)
+	}
+	if verifyErr != nil {
+		if verifyErr == wait.ErrWaitTimeout {
+			return fmt.Errorf("connectivity: canary Pod is not completed within %s", vm.config.TimeoutDuration)
+		}
+		return fmt.Errorf("connectivity: %v", verifyErr)
+	}
+
+	releaseErr := wait.PollImmediateWithContext(ctx, pollInterval, vm.config.TimeoutDuration, func(ctx context.Context) (bool, error) {
+		current, err := vm.ipPoolManager.GetIPPoolByName(ctx, ipPool.Name)
+		if err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		allocation, ok := current.Status.AllocatedIPs[ip]
+		if !ok || allocation.Pod != pod.Name {
+			return true, nil
+		}
+
+		return false, nil
+	})
+	if releaseErr != nil {
+		if releaseErr == wait.ErrWaitTimeout {
+			return fmt.Errorf("release: IP address %s is not released within %s", ip, vm.config.TimeoutDuration)
+		}
+		return fmt.Errorf("release: %v", releaseErr)
+	}
+
+	logger.Sugar().Debugf("Canary Pod %s/%s with IP address %s is verified", pod.Namespace, pod.Name, ip)
+
+	return nil
+}
+
+func (vm *verifyManager) genCanaryPod(ipPool *spiderpoolv1.SpiderIPPool, nodeName string) (*corev1.Pod, error) {
+	var poolAnno types.AnnoPodIPPoolValue
+	if *ipPool.Spec.IPVersion == constant.IPv4 {
+		poolAnno.IPv4Pools = []string{ipPool.Name}
+	} else {
+		poolAnno.IPv6Pools = []string{ipPool.Name}
+	}
+	anno, err := json.Marshal(poolAnno)
+	if err != nil {
+		return nil, err
+	}
+
+	command := "true"
+	if ipPool.Spec.Gateway != nil {
+		command = fmt.Sprintf("ping -c 3 -W 2 %s", *ipPool.Spec.Gateway)
+	}
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: constant.Spiderpool + "-verify-",
+			Namespace:    vm.config.Namespace,
+			Labels: map[string]string{
+				constant.LabelSelfVerification: constant.True,
+			},
+			Annotations: map[string]string{
+				constant.AnnoPodIPPool: string(anno),
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:                      nodeName,
+			RestartPolicy:                 corev1.RestartPolicyNever,
+			TerminationGracePeriodSeconds: pointer.Int64(0),
+			AutomountServiceAccountToken:  pointer.Bool(false),
+			Tolerations: []corev1.Toleration{{
+				Operator: corev1.TolerationOpExists,
+			}},
+			Containers: []corev1.Container{{
+				Name:    canaryContainerName,
+				Image:   vm.config.Image,
+				Command: []string{"sh", "-c", command},
+			}},
+		},
+	}, nil
+}
+
+// getPodIPInIPPool returns the IP address of the Pod which belongs to the
+// subnet of the IPPool.
+func getPodIPInIPPool(pod *corev1.Pod, ipPool *spiderpoolv1.SpiderIPPool) string {
+	for _, podIP := range pod.Status.PodIPs {
+		contains, err := spiderpoolip.ContainsIP(*ipPool.Spec.IPVersion, ipPool.Spec.Subnet, podIP.IP)
+		if err == nil && contains {
+			return podIP.IP
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,359 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package verifymanager
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	"github.com/spidernet-io/spiderpool/pkg/event"
+	"github.com/spidernet-io/spiderpool/pkg/ippoolmanager"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/reservedipmanager"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// fakeLeader is the elector whose leadership is fixed.
+type fakeLeader struct {
+	elected bool
+}
+
+func (f *fakeLeader) Run(ctx context.Context, clientSet kubernetes.Interface) error { return nil }
+
+func (f *fakeLeader) IsElected() bool { return f.elected }
+
+// canaryClient plays the kubelet and the CNI, it sets the status of the
+// canary Pods when they are read. If stuck, the IP address of the canary
+// Pod stays allocated in the IPPool, as if it is never released.
+type canaryClient struct {
+	client.Client
+	status corev1.PodStatus
+	stuck  bool
+
+	mu     sync.Mutex
+	canary string
+}
+
+func (c *canaryClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if err := c.Client.Get(ctx, key, obj, opts...); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		if o.Labels[constant.LabelSelfVerification] == constant.True {
+			o.Status = c.status
+			c.canary = o.Name
+		}
+	case *spiderpoolv1.SpiderIPPool:
+		if c.stuck && c.canary != "" {
+			o.Status.AllocatedIPs = spiderpoolv1.PoolIPAllocations{
+				getPodIPInIPPool(&corev1.Pod{Status: c.status}, o): {Pod: c.canary},
+			}
+		}
+	}
+
+	return nil
+}
+
+var _ = Describe("VerifyManager", Label("verify_manager_test"), func() {
+	newIPPool := func() *spiderpoolv1.SpiderIPPool {
+		pool := &spiderpoolv1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: "verify-pool"}}
+		pool.Spec.IPVersion = pointer.Int64(constant.IPv4)
+		pool.Spec.Subnet = "172.18.40.0/24"
+		pool.Spec.Gateway = pointer.String("172.18.40.1")
+		return pool
+	}
+
+	newNode := func(name string, ready bool, mutate func(node *corev1.Node)) *corev1.Node {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"zone": "a"}},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: status},
+			}},
+		}
+		if mutate != nil {
+			mutate(node)
+		}
+		return node
+	}
+
+	var fakeClient client.Client
+	var ipPoolManager ippoolmanager.IPPoolManager
+	var vm *verifyManager
+	var canaryStatus corev1.PodStatus
+	setup := func(objs ...client.Object) {
+		fakeClient = &canaryClient{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+			status: canaryStatus,
+		}
+		rIPManager, err := reservedipmanager.NewReservedIPManager(fakeClient)
+		Expect(err).NotTo(HaveOccurred())
+		ipPoolManager, err = ippoolmanager.NewIPPoolManager(ippoolmanager.IPPoolManagerConfig{}, fakeClient, rIPManager)
+		Expect(err).NotTo(HaveOccurred())
+
+		manager, err := NewVerifyManager(VerifyManagerConfig{
+			IPPoolName:      "verify-pool",
+			TimeoutDuration: 10 * time.Millisecond,
+		}, fakeClient, ipPoolManager, &fakeLeader{elected: true})
+		Expect(err).NotTo(HaveOccurred())
+		vm = manager.(*verifyManager)
+	}
+
+	BeforeEach(func() {
+		canaryStatus = corev1.PodStatus{
+			Phase:  corev1.PodSucceeded,
+			PodIPs: []corev1.PodIP{{IP: "fd00::10"}, {IP: "172.18.40.10"}},
+		}
+	})
+
+	listCanaryPods := func() []corev1.Pod {
+		var podList corev1.PodList
+		err := fakeClient.List(context.TODO(), &podList, client.MatchingLabels{constant.LabelSelfVerification: constant.True})
+		Expect(err).NotTo(HaveOccurred())
+		return podList.Items
+	}
+
+	Describe("NewVerifyManager", func() {
+		It("requires the test IPPool and the dependencies", func() {
+			setup()
+			leader := &fakeLeader{}
+
+			_, err := NewVerifyManager(VerifyManagerConfig{}, fakeClient, ipPoolManager, leader)
+			Expect(err).To(MatchError(constant.ErrMissingRequiredParam))
+			_, err = NewVerifyManager(VerifyManagerConfig{IPPoolName: "verify-pool"}, nil, ipPoolManager, leader)
+			Expect(err).To(MatchError(constant.ErrMissingRequiredParam))
+			_, err = NewVerifyManager(VerifyManagerConfig{IPPoolName: "verify-pool"}, fakeClient, nil, leader)
+			Expect(err).To(MatchError(constant.ErrMissingRequiredParam))
+			_, err = NewVerifyManager(VerifyManagerConfig{IPPoolName: "verify-pool"}, fakeClient, ipPoolManager, nil)
+			Expect(err).To(MatchError(constant.ErrMissingRequiredParam))
+		})
+
+		It("sets the defaults", func() {
+			Expect(setDefaultsForVerifyManagerConfig(VerifyManagerConfig{IPPoolName: "verify-pool"})).To(Equal(VerifyManagerConfig{
+				IPPoolName:       "verify-pool",
+				Namespace:        metav1.NamespaceDefault,
+				Image:            "busybox",
+				IntervalDuration: 10 * time.Minute,
+				TimeoutDuration:  2 * time.Minute,
+			}))
+		})
+	})
+
+	Describe("getNodesToVerify", func() {
+		It("returns the ready and schedulable Nodes matching the node affinity of the IPPool", func() {
+			setup(
+				newNode("ready", true, nil),
+				newNode("not-ready", false, nil),
+				newNode("unschedulable", true, func(node *corev1.Node) { node.Spec.Unschedulable = true }),
+				newNode("other-zone", true, func(node *corev1.Node) { node.Labels["zone"] = "b" }),
+			)
+			pool := newIPPool()
+			pool.Spec.NodeAffinity = &metav1.LabelSelector{MatchLabels: map[string]string{"zone": "a"}}
+
+			nodes, err := vm.getNodesToVerify(context.TODO(), pool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nodes).To(Equal([]string{"ready"}))
+		})
+
+		It("returns all ready Nodes without node affinity", func() {
+			setup(
+				newNode("node-a", true, nil),
+				newNode("node-b", true, func(node *corev1.Node) { node.Labels["zone"] = "b" }),
+			)
+
+			nodes, err := vm.getNodesToVerify(context.TODO(), newIPPool())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nodes).To(ConsistOf("node-a", "node-b"))
+		})
+
+		It("fails with the invalid node affinity", func() {
+			setup()
+			pool := newIPPool()
+			pool.Spec.NodeAffinity = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "zone", Operator: "Unknown"},
+			}}
+
+			_, err := vm.getNodesToVerify(context.TODO(), pool)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("genCanaryPod", func() {
+		It("pings the gateway from the IPPool of its IP version on the Node", func() {
+			setup()
+			pod, err := vm.genCanaryPod(newIPPool(), "node")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(pod.Namespace).To(Equal(metav1.NamespaceDefault))
+			Expect(pod.Labels).To(HaveKeyWithValue(constant.LabelSelfVerification, constant.True))
+			Expect(pod.Spec.NodeName).To(Equal("node"))
+			Expect(pod.Spec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+			Expect(pod.Spec.Containers).To(HaveLen(1))
+			Expect(pod.Spec.Containers[0].Command).To(Equal([]string{"sh", "-c", "ping -c 3 -W 2 172.18.40.1"}))
+
+			var anno types.AnnoPodIPPoolValue
+			err = json.Unmarshal([]byte(pod.Annotations[constant.AnnoPodIPPool]), &anno)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(anno.IPv4Pools).To(Equal([]string{"verify-pool"}))
+			Expect(anno.IPv6Pools).To(BeEmpty())
+		})
+
+		It("only runs without the gateway", func() {
+			setup()
+			pool := newIPPool()
+			pool.Spec.IPVersion = pointer.Int64(constant.IPv6)
+			pool.Spec.Gateway = nil
+
+			pod, err := vm.genCanaryPod(pool, "node")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Command).To(Equal([]string{"sh", "-c", "true"}))
+
+			var anno types.AnnoPodIPPoolValue
+			err = json.Unmarshal([]byte(pod.Annotations[constant.AnnoPodIPPool]), &anno)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(anno.IPv6Pools).To(Equal([]string{"verify-pool"}))
+			Expect(anno.IPv4Pools).To(BeEmpty())
+		})
+	})
+
+	Describe("getPodIPInIPPool", func() {
+		It("returns the IP address of the Pod in the subnet of the IPPool", func() {
+			pod := &corev1.Pod{Status: canaryStatus}
+			Expect(getPodIPInIPPool(pod, newIPPool())).To(Equal("172.18.40.10"))
+
+			pod.Status.PodIPs = []corev1.PodIP{{IP: "172.18.41.10"}}
+			Expect(getPodIPInIPPool(pod, newIPPool())).To(BeEmpty())
+		})
+	})
+
+	Describe("verifyNode", func() {
+		It("verifies the allocation, the connectivity and the release", func() {
+			setup(newIPPool())
+
+			err := vm.verifyNode(context.TODO(), newIPPool(), "node")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(listCanaryPods()).To(BeEmpty())
+		})
+
+		It("fails if no IP address is allocated from the IPPool", func() {
+			canaryStatus = corev1.PodStatus{Phase: corev1.PodPending}
+			setup(newIPPool())
+
+			err := vm.verifyNode(context.TODO(), newIPPool(), "node")
+			Expect(err).To(MatchError(ContainSubstring("allocation:")))
+			Expect(listCanaryPods()).To(BeEmpty())
+		})
+
+		It("fails if the canary Pod can't reach the gateway", func() {
+			canaryStatus.Phase = corev1.PodFailed
+			setup(newIPPool())
+
+			err := vm.verifyNode(context.TODO(), newIPPool(), "node")
+			Expect(err).To(MatchError(ContainSubstring("connectivity: no connectivity to gateway 172.18.40.1")))
+		})
+
+		It("fails if the canary Pod is not completed in time", func() {
+			canaryStatus.Phase = corev1.PodRunning
+			setup(newIPPool())
+
+			err := vm.verifyNode(context.TODO(), newIPPool(), "node")
+			Expect(err).To(MatchError(ContainSubstring("connectivity: canary Pod is not completed")))
+		})
+
+		It("fails if the IP address is not released after the canary Pod is deleted", func() {
+			setup(newIPPool())
+			fakeClient.(*canaryClient).stuck = true
+
+			err := vm.verifyNode(context.TODO(), newIPPool(), "node")
+			Expect(err).To(MatchError(ContainSubstring("release: IP address 172.18.40.10 is not released")))
+		})
+	})
+
+	Describe("verify", func() {
+		var recorder *record.FakeRecorder
+		BeforeEach(func() {
+			recorder = record.NewFakeRecorder(10)
+			defaultRecorder := event.EventRecorder
+			event.EventRecorder = recorder
+			DeferCleanup(func() {
+				event.EventRecorder = defaultRecorder
+			})
+		})
+
+		getVerified := func() *metav1.Condition {
+			pool, err := ipPoolManager.GetIPPoolByName(context.TODO(), "verify-pool")
+			Expect(err).NotTo(HaveOccurred())
+			return apimeta.FindStatusCondition(pool.Status.Conditions, constant.IPPoolConditionVerified)
+		}
+
+		It("sets the IPPool verified after verifying all Nodes", func() {
+			stale := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Namespace: metav1.NamespaceDefault,
+				Name:      "stale",
+				Labels:    map[string]string{constant.LabelSelfVerification: constant.True},
+			}}
+			setup(newIPPool(), newNode("node-a", true, nil), newNode("node-b", true, nil), stale)
+
+			vm.verify(context.TODO())
+			condition := getVerified()
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal(constant.IPPoolReasonVerificationSucceeded))
+			Expect(condition.Message).To(Equal("verified on 2 nodes"))
+			Expect(listCanaryPods()).To(BeEmpty())
+			Expect(recorder.Events).To(BeEmpty())
+		})
+
+		It("sets the IPPool not verified and emits an Event on failures", func() {
+			canaryStatus.Phase = corev1.PodFailed
+			setup(newIPPool(), newNode("node-a", true, nil))
+
+			vm.verify(context.TODO())
+			condition := getVerified()
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(constant.IPPoolReasonVerificationFailed))
+			Expect(condition.Message).To(HavePrefix("failed on 1/1 nodes: node-a: connectivity:"))
+			Expect(recorder.Events).To(Receive(ContainSubstring(constant.EventReasonSelfVerificationFailed)))
+		})
+
+		It("skips the round without the Nodes to verify", func() {
+			setup(newIPPool(), newNode("node-a", false, nil))
+
+			vm.verify(context.TODO())
+			Expect(getVerified()).To(BeNil())
+		})
+
+		It("skips the round until the IP version of the IPPool is set", func() {
+			pool := newIPPool()
+			pool.Spec.IPVersion = nil
+			setup(pool, newNode("node-a", true, nil))
+
+			vm.verify(context.TODO())
+			Expect(getVerified()).To(BeNil())
+			Expect(listCanaryPods()).To(BeEmpty())
+		})
+	})
+})
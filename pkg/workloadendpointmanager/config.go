@@ -9,6 +9,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
@@ -20,6 +21,17 @@ type EndpointManagerConfig struct {
 	ConflictRetryUnitTime time.Duration
 	scheme                *runtime.Scheme
 	MaxHistoryRecords     *int
+	// MaxHistoryAge prunes the historical IP allocation records older than
+	// it, 0 means never.
+	MaxHistoryAge time.Duration
+	// IndexedReader is the cache with the Endpoint fields indexed by
+	// RegisterEndpointIndexers. Without it, the Endpoints listed by the
+	// indexed fields are filtered from all of them.
+	IndexedReader client.Reader
+	// CachedReader is the informer-backed cache of Endpoints read on the hot
+	// path of IP allocation by GetCachedEndpointByName, and by the listing of
+	// the Endpoints of a node, which reads from the API server if nil.
+	CachedReader client.Reader
 }
 
 func setDefaultsForEndpointManagerConfig(config EndpointManagerConfig) EndpointManagerConfig {
@@ -4,7 +4,12 @@
 package workloadendpointmanager
 
 import (
+	"sort"
 	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
 	"github.com/spidernet-io/spiderpool/pkg/logutils"
@@ -71,3 +76,200 @@ func ListAllHistoricalIPs(endpoint *spiderpoolv1.SpiderEndpoint) map[string][]ty
 
 	return wepHistoryIPs
 }
+
+// CompactEndpoint rewrites the historical IP allocations of the Endpoint
+// into the compact form, and reports whether the Endpoint is changed. The
+// consecutive records of the same container, which are left by marking and
+// patching the IP allocation separately, are merged into the one with IP
+// addresses, and at most maxHistoryRecords latest records are kept.
+func CompactEndpoint(endpoint *spiderpoolv1.SpiderEndpoint, maxHistoryRecords int) bool {
+	history := endpoint.Status.History
+	if len(history) == 0 {
+		return false
+	}
+
+	compacted := make([]spiderpoolv1.PodIPAllocation, 0, len(history))
+	for _, record := range history {
+		last := len(compacted) - 1
+		if last < 0 || compacted[last].ContainerID != record.ContainerID {
+			compacted = append(compacted, record)
+			continue
+		}
+		// The CNI calls of the merged records are kept, the older first.
+		calls := appendCNICalls(record.CNICalls, compacted[last].CNICalls...)
+		if len(compacted[last].IPs) == 0 && len(record.IPs) != 0 {
+			compacted[last] = record
+		}
+		if len(calls) != 0 {
+			compacted[last].CNICalls = calls
+		}
+	}
+
+	if maxHistoryRecords > 0 && len(compacted) > maxHistoryRecords {
+		compacted = compacted[:maxHistoryRecords]
+	}
+
+	if len(compacted) == len(history) {
+		return false
+	}
+	endpoint.Status.History = compacted
+
+	return true
+}
+
+// CollapseEndpointHistory collapses the latest historical IP allocation
+// record of the Endpoint into the previous one if they're identical, which
+// are left by the restarts of the Pod sandbox, and reports whether the
+// Endpoint is changed. The collapsed record keeps the container ID of the
+// latest container, whose IP addresses are the ones recorded in the IPPools,
+// the creation times of both the first and the latest containers, and the
+// CNI calls of both.
+func CollapseEndpointHistory(endpoint *spiderpoolv1.SpiderEndpoint) bool {
+	history := endpoint.Status.History
+	if len(history) < 2 {
+		return false
+	}
+
+	latest, previous := history[0], history[1]
+	if latest.ContainerID == previous.ContainerID || !sameIPAllocation(&latest, &previous) {
+		return false
+	}
+
+	collapsed := latest.DeepCopy()
+	collapsed.CreationTime = previous.CreationTime
+	collapsed.LastCreationTime = lastCreationTime(&latest)
+	collapsed.CNICalls = appendCNICalls(previous.CNICalls, latest.CNICalls...)
+	if len(collapsed.CNICalls) == 0 {
+		collapsed.CNICalls = nil
+	}
+	endpoint.Status.History = append([]spiderpoolv1.PodIPAllocation{*collapsed}, history[2:]...)
+
+	return true
+}
+
+// sameIPAllocation reports whether the IP allocations assign the same IP
+// addresses on the same node, regardless of their containers.
+func sameIPAllocation(a, b *spiderpoolv1.PodIPAllocation) bool {
+	if !equality.Semantic.DeepEqual(a.Node, b.Node) {
+		return false
+	}
+	if len(a.IPs) == 0 || len(b.IPs) == 0 {
+		return len(a.IPs) == len(b.IPs)
+	}
+
+	return equality.Semantic.DeepEqual(a.IPs, b.IPs)
+}
+
+// lastCreationTime returns the creation time of the latest container of the
+// historical IP allocation record.
+func lastCreationTime(record *spiderpoolv1.PodIPAllocation) *metav1.Time {
+	if record.LastCreationTime != nil {
+		return record.LastCreationTime
+	}
+
+	return record.CreationTime
+}
+
+// HistoryIndexBefore returns the index of the first historical IP allocation
+// record of the Endpoint created before the time. The records are prepended
+// once the IP addresses are allocated, so they're sorted by their creation
+// time in descending order, and the ones from the index on are all created
+// before the time. The collapsed records are judged by the creation time of
+// their latest containers. The records without creation time are never
+// counted.
+func HistoryIndexBefore(endpoint *spiderpoolv1.SpiderEndpoint, t time.Time) int {
+	history := endpoint.Status.History
+	return sort.Search(len(history), func(i int) bool {
+		last := lastCreationTime(&history[i])
+		return last != nil && last.Time.Before(t)
+	})
+}
+
+// HistorySince returns the historical IP allocation records of the Endpoint
+// created since the time.
+func HistorySince(endpoint *spiderpoolv1.SpiderEndpoint, t time.Time) []spiderpoolv1.PodIPAllocation {
+	return endpoint.Status.History[:HistoryIndexBefore(endpoint, t)]
+}
+
+// PruneEndpointHistory drops the historical IP allocation records of the
+// Endpoint older than maxHistoryAge, and reports whether the Endpoint is
+// changed. The record of the current container is always kept.
+func PruneEndpointHistory(endpoint *spiderpoolv1.SpiderEndpoint, maxHistoryAge time.Duration, now time.Time) bool {
+	if maxHistoryAge <= 0 {
+		return false
+	}
+
+	history := endpoint.Status.History
+	i := HistoryIndexBefore(endpoint, now.Add(-maxHistoryAge))
+	if i == 0 && len(history) != 0 {
+		i = 1
+	}
+	if i >= len(history) {
+		return false
+	}
+	endpoint.Status.History = history[:i]
+
+	return true
+}
+
+// LastActiveTime returns the latest time recorded by the Endpoint, among its
+// creation and deletion, its IP allocations and their CNI calls.
+func LastActiveTime(endpoint *spiderpoolv1.SpiderEndpoint) time.Time {
+	last := endpoint.CreationTimestamp.Time
+	observe := func(t *metav1.Time) {
+		if t != nil && t.Time.After(last) {
+			last = t.Time
+		}
+	}
+
+	observe(endpoint.DeletionTimestamp)
+	records := endpoint.Status.History
+	if endpoint.Status.Current != nil {
+		records = append([]spiderpoolv1.PodIPAllocation{*endpoint.Status.Current}, records...)
+	}
+	for i := range records {
+		observe(records[i].CreationTime)
+		observe(records[i].LastCreationTime)
+		for j := range records[i].CNICalls {
+			observe(records[i].CNICalls[j].Time)
+		}
+	}
+
+	return last
+}
+
+// IsStaleEndpoint reports whether the Endpoint has no current IP allocation
+// and has been inactive for maxAge, it's an orphan once its Pod is gone.
+func IsStaleEndpoint(endpoint *spiderpoolv1.SpiderEndpoint, maxAge time.Duration, now time.Time) bool {
+	if maxAge <= 0 {
+		return false
+	}
+	if endpoint.Status.Current != nil && len(endpoint.Status.Current.IPs) != 0 {
+		return false
+	}
+
+	return now.Sub(LastActiveTime(endpoint)) >= maxAge
+}
+
+// HasCurrentIP reports whether the IP address of the IPPool is among the
+// current IP allocation of the Endpoint.
+func HasCurrentIP(endpoint *spiderpoolv1.SpiderEndpoint, poolName, ip string) bool {
+	if endpoint.Status.Current == nil {
+		return false
+	}
+
+	match := func(pool, ipAndCIDR *string) bool {
+		if pool == nil || ipAndCIDR == nil || *pool != poolName {
+			return false
+		}
+		currentIP, _, _ := strings.Cut(*ipAndCIDR, "/")
+		return currentIP == ip
+	}
+	for _, d := range endpoint.Status.Current.IPs {
+		if match(d.IPv4Pool, d.IPv4) || match(d.IPv6Pool, d.IPv6) {
+			return true
+		}
+	}
+
+	return false
+}
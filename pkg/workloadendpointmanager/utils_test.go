@@ -5,6 +5,7 @@ package workloadendpointmanager_test
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/moby/moby/pkg/stringid"
 	. "github.com/onsi/ginkgo/v2"
@@ -89,4 +90,306 @@ var _ = Describe("WorkloadEndpointManager utils", Label("workloadendpoint_manage
 	})
 
 	PDescribe("Test ListAllHistoricalIPs", func() {})
+
+	Describe("Test CompactEndpoint", func() {
+		var containerID1, containerID2 string
+		var ips []spiderpoolv1.IPAllocationDetail
+
+		BeforeEach(func() {
+			containerID1 = stringid.GenerateRandomID()
+			containerID2 = stringid.GenerateRandomID()
+			ips = []spiderpoolv1.IPAllocationDetail{
+				{
+					NIC:      "eth0",
+					Vlan:     pointer.Int64(0),
+					IPv4:     pointer.String("172.18.40.10/24"),
+					IPv4Pool: pointer.String("ipv4-ippool-1"),
+				},
+			}
+		})
+
+		It("compacts the Endpoint without history", func() {
+			Expect(workloadendpointmanager.CompactEndpoint(endpointT, 10)).To(BeFalse())
+		})
+
+		It("compacts the compact Endpoint", func() {
+			endpointT.Status.History = []spiderpoolv1.PodIPAllocation{
+				{ContainerID: containerID2, IPs: ips},
+				{ContainerID: containerID1, IPs: ips},
+			}
+
+			Expect(workloadendpointmanager.CompactEndpoint(endpointT, 10)).To(BeFalse())
+			Expect(endpointT.Status.History).To(HaveLen(2))
+		})
+
+		It("merges the records of the same container", func() {
+			endpointT.Status.History = []spiderpoolv1.PodIPAllocation{
+				{ContainerID: containerID2},
+				{ContainerID: containerID1, IPs: ips},
+				{ContainerID: containerID1},
+			}
+
+			Expect(workloadendpointmanager.CompactEndpoint(endpointT, 10)).To(BeTrue())
+			Expect(endpointT.Status.History).To(Equal([]spiderpoolv1.PodIPAllocation{
+				{ContainerID: containerID2},
+				{ContainerID: containerID1, IPs: ips},
+			}))
+		})
+
+		It("drops the records beyond the limit", func() {
+			endpointT.Status.History = []spiderpoolv1.PodIPAllocation{
+				{ContainerID: containerID2, IPs: ips},
+				{ContainerID: containerID1, IPs: ips},
+			}
+
+			Expect(workloadendpointmanager.CompactEndpoint(endpointT, 1)).To(BeTrue())
+			Expect(endpointT.Status.History).To(HaveLen(1))
+			Expect(endpointT.Status.History[0].ContainerID).To(Equal(containerID2))
+		})
+
+		It("keeps the CNI calls of the merged records", func() {
+			add := workloadendpointmanager.NewCNICall(constant.CNIOperationAdd, "node", constant.CNIResultFailure)
+			del := workloadendpointmanager.NewCNICall(constant.CNIOperationDel, "node", constant.CNIResultRollback)
+			endpointT.Status.History = []spiderpoolv1.PodIPAllocation{
+				{ContainerID: containerID1, CNICalls: []spiderpoolv1.CNICall{del}},
+				{ContainerID: containerID1, IPs: ips, CNICalls: []spiderpoolv1.CNICall{add}},
+			}
+
+			Expect(workloadendpointmanager.CompactEndpoint(endpointT, 10)).To(BeTrue())
+			Expect(endpointT.Status.History).To(HaveLen(1))
+			Expect(endpointT.Status.History[0].CNICalls).To(Equal([]spiderpoolv1.CNICall{add, del}))
+		})
+	})
+
+	Describe("Test CollapseEndpointHistory", func() {
+		var containerID1, containerID2 string
+		var ips []spiderpoolv1.IPAllocationDetail
+		var first, last time.Time
+
+		BeforeEach(func() {
+			containerID1 = stringid.GenerateRandomID()
+			containerID2 = stringid.GenerateRandomID()
+			ips = []spiderpoolv1.IPAllocationDetail{
+				{
+					NIC:      "eth0",
+					Vlan:     pointer.Int64(0),
+					IPv4:     pointer.String("172.18.40.10/24"),
+					IPv4Pool: pointer.String("ipv4-ippool-1"),
+				},
+			}
+			last = time.Now()
+			first = last.Add(-time.Hour)
+		})
+
+		It("collapses the Endpoint with single record", func() {
+			endpointT.Status.History = []spiderpoolv1.PodIPAllocation{
+				{ContainerID: containerID1, IPs: ips},
+			}
+
+			Expect(workloadendpointmanager.CollapseEndpointHistory(endpointT)).To(BeFalse())
+		})
+
+		It("keeps the records with different IP addresses", func() {
+			other := []spiderpoolv1.IPAllocationDetail{*ips[0].DeepCopy()}
+			other[0].IPv4 = pointer.String("172.18.40.11/24")
+			endpointT.Status.History = []spiderpoolv1.PodIPAllocation{
+				{ContainerID: containerID2, Node: pointer.String("node"), IPs: other},
+				{ContainerID: containerID1, Node: pointer.String("node"), IPs: ips},
+			}
+
+			Expect(workloadendpointmanager.CollapseEndpointHistory(endpointT)).To(BeFalse())
+			Expect(endpointT.Status.History).To(HaveLen(2))
+		})
+
+		It("keeps the records on different nodes", func() {
+			endpointT.Status.History = []spiderpoolv1.PodIPAllocation{
+				{ContainerID: containerID2, Node: pointer.String("node2"), IPs: ips},
+				{ContainerID: containerID1, Node: pointer.String("node1"), IPs: ips},
+			}
+
+			Expect(workloadendpointmanager.CollapseEndpointHistory(endpointT)).To(BeFalse())
+			Expect(endpointT.Status.History).To(HaveLen(2))
+		})
+
+		It("collapses the identical records with the first and last creation times", func() {
+			add := workloadendpointmanager.NewCNICall(constant.CNIOperationAdd, "node", constant.CNIResultSuccess)
+			del := workloadendpointmanager.NewCNICall(constant.CNIOperationDel, "node", constant.CNIResultSuccess)
+			endpointT.Status.History = []spiderpoolv1.PodIPAllocation{
+				{ContainerID: containerID2, Node: pointer.String("node"), IPs: ips, CreationTime: &metav1.Time{Time: last}, CNICalls: []spiderpoolv1.CNICall{add}},
+				{ContainerID: containerID1, Node: pointer.String("node"), IPs: ips, CreationTime: &metav1.Time{Time: first}, CNICalls: []spiderpoolv1.CNICall{add, del}},
+			}
+
+			Expect(workloadendpointmanager.CollapseEndpointHistory(endpointT)).To(BeTrue())
+			Expect(endpointT.Status.History).To(HaveLen(1))
+			collapsed := endpointT.Status.History[0]
+			Expect(collapsed.ContainerID).To(Equal(containerID2))
+			Expect(collapsed.CreationTime.Time).To(Equal(first))
+			Expect(collapsed.LastCreationTime.Time).To(Equal(last))
+			Expect(collapsed.CNICalls).To(Equal([]spiderpoolv1.CNICall{add, del, add}))
+		})
+
+		It("prunes the collapsed records by their last creation time", func() {
+			endpointT.Status.History = []spiderpoolv1.PodIPAllocation{
+				{ContainerID: containerID2, CreationTime: &metav1.Time{Time: last}},
+				{ContainerID: containerID1, CreationTime: &metav1.Time{Time: first.Add(-200 * time.Hour)}, LastCreationTime: &metav1.Time{Time: first}},
+			}
+
+			Expect(workloadendpointmanager.PruneEndpointHistory(endpointT, 168*time.Hour, last)).To(BeFalse())
+			Expect(endpointT.Status.History).To(HaveLen(2))
+		})
+	})
+
+	Describe("Test CNI call accessors", func() {
+		It("accesses the CNI calls of the container", func() {
+			containerID := stringid.GenerateRandomID()
+			add := workloadendpointmanager.NewCNICall(constant.CNIOperationAdd, "node", constant.CNIResultFailure)
+			del := workloadendpointmanager.NewCNICall(constant.CNIOperationDel, "node", constant.CNIResultRollback)
+			endpointT.Status.History = []spiderpoolv1.PodIPAllocation{
+				{ContainerID: containerID, CNICalls: []spiderpoolv1.CNICall{add, del}},
+				{ContainerID: stringid.GenerateRandomID()},
+			}
+
+			Expect(workloadendpointmanager.GetCNICalls(endpointT, containerID)).To(Equal([]spiderpoolv1.CNICall{add, del}))
+			Expect(workloadendpointmanager.GetCNICalls(endpointT, stringid.GenerateRandomID())).To(BeEmpty())
+			Expect(workloadendpointmanager.LastCNICall(&endpointT.Status.History[0])).To(Equal(&del))
+			Expect(workloadendpointmanager.LastCNICall(&endpointT.Status.History[1])).To(BeNil())
+			Expect(workloadendpointmanager.IsRolledBack(&endpointT.Status.History[0])).To(BeTrue())
+			Expect(workloadendpointmanager.IsRolledBack(&endpointT.Status.History[1])).To(BeFalse())
+		})
+	})
+
+	Describe("Test PruneEndpointHistory", func() {
+		var now time.Time
+
+		BeforeEach(func() {
+			now = time.Now()
+			endpointT.Status.History = []spiderpoolv1.PodIPAllocation{
+				{ContainerID: stringid.GenerateRandomID(), CreationTime: &metav1.Time{Time: now.Add(-time.Hour)}},
+				{ContainerID: stringid.GenerateRandomID(), CreationTime: &metav1.Time{Time: now.Add(-48 * time.Hour)}},
+				{ContainerID: stringid.GenerateRandomID(), CreationTime: &metav1.Time{Time: now.Add(-200 * time.Hour)}},
+			}
+		})
+
+		It("indexes the records by creation time", func() {
+			Expect(workloadendpointmanager.HistoryIndexBefore(endpointT, now)).To(Equal(0))
+			Expect(workloadendpointmanager.HistoryIndexBefore(endpointT, now.Add(-24*time.Hour))).To(Equal(1))
+			Expect(workloadendpointmanager.HistorySince(endpointT, now.Add(-168*time.Hour))).To(HaveLen(2))
+			Expect(workloadendpointmanager.HistorySince(endpointT, now.Add(-300*time.Hour))).To(HaveLen(3))
+		})
+
+		It("never prunes without max age", func() {
+			Expect(workloadendpointmanager.PruneEndpointHistory(endpointT, 0, now)).To(BeFalse())
+			Expect(endpointT.Status.History).To(HaveLen(3))
+		})
+
+		It("prunes the records older than max age", func() {
+			Expect(workloadendpointmanager.PruneEndpointHistory(endpointT, 168*time.Hour, now)).To(BeTrue())
+			Expect(endpointT.Status.History).To(HaveLen(2))
+			Expect(workloadendpointmanager.PruneEndpointHistory(endpointT, 168*time.Hour, now)).To(BeFalse())
+		})
+
+		It("keeps the record of the current container", func() {
+			Expect(workloadendpointmanager.PruneEndpointHistory(endpointT, time.Minute, now)).To(BeTrue())
+			Expect(endpointT.Status.History).To(HaveLen(1))
+		})
+	})
+
+	Describe("Test BuildEndpointSummary", func() {
+		It("summarizes the Endpoint without current IP allocation", func() {
+			endpointT.Status.Current = nil
+			endpointT.Status.History = []spiderpoolv1.PodIPAllocation{{ContainerID: stringid.GenerateRandomID()}}
+
+			summary := workloadendpointmanager.BuildEndpointSummary(endpointT)
+			Expect(*summary).To(Equal(spiderpoolv1.EndpointSummary{HistoryCount: 1}))
+		})
+
+		It("summarizes the current IP allocation of all interfaces", func() {
+			endpointT.Status.Current = &spiderpoolv1.PodIPAllocation{
+				ContainerID: stringid.GenerateRandomID(),
+				Node:        pointer.String("node"),
+				IPs: []spiderpoolv1.IPAllocationDetail{
+					{
+						NIC:      "eth0",
+						IPv4:     pointer.String("172.18.40.10/24"),
+						IPv4Pool: pointer.String("pool-v4"),
+						IPv6:     pointer.String("abcd:1234::a/120"),
+						IPv6Pool: pointer.String("pool-v6"),
+					},
+					{
+						NIC:      "net1",
+						IPv4:     pointer.String("172.18.40.11/24"),
+						IPv4Pool: pointer.String("pool-v4"),
+					},
+				},
+			}
+			endpointT.Status.History = []spiderpoolv1.PodIPAllocation{*endpointT.Status.Current}
+
+			summary := workloadendpointmanager.BuildEndpointSummary(endpointT)
+			Expect(summary.IPs).To(Equal("172.18.40.10,abcd:1234::a,172.18.40.11"))
+			Expect(summary.Pools).To(Equal("pool-v4,pool-v6"))
+			Expect(summary.Node).To(Equal("node"))
+			Expect(summary.HistoryCount).To(Equal(1))
+		})
+	})
+
+	Describe("Test IsStaleEndpoint", func() {
+		var now time.Time
+
+		BeforeEach(func() {
+			now = time.Now()
+			endpointT.CreationTimestamp = metav1.Time{Time: now.Add(-48 * time.Hour)}
+			endpointT.Status.Current = nil
+			endpointT.Status.History = []spiderpoolv1.PodIPAllocation{
+				{
+					ContainerID:  stringid.GenerateRandomID(),
+					CreationTime: &metav1.Time{Time: now.Add(-30 * time.Hour)},
+					CNICalls: []spiderpoolv1.CNICall{
+						{Operation: constant.CNIOperationDel, Result: constant.CNIResultSuccess, Time: &metav1.Time{Time: now.Add(-25 * time.Hour)}},
+					},
+				},
+			}
+		})
+
+		It("takes the latest time recorded by the Endpoint", func() {
+			Expect(workloadendpointmanager.LastActiveTime(endpointT)).To(BeTemporally("==", now.Add(-25*time.Hour)))
+		})
+
+		It("never judges without max age", func() {
+			Expect(workloadendpointmanager.IsStaleEndpoint(endpointT, 0, now)).To(BeFalse())
+		})
+
+		It("judges the inactive Endpoint without current IP allocation as stale", func() {
+			Expect(workloadendpointmanager.IsStaleEndpoint(endpointT, 24*time.Hour, now)).To(BeTrue())
+			Expect(workloadendpointmanager.IsStaleEndpoint(endpointT, 26*time.Hour, now)).To(BeFalse())
+		})
+
+		It("keeps the Endpoint with current IP allocation", func() {
+			endpointT.Status.Current = &endpointT.Status.History[0]
+			endpointT.Status.Current.IPs = []spiderpoolv1.IPAllocationDetail{{NIC: "eth0", IPv4: pointer.String("172.18.40.10/24")}}
+			Expect(workloadendpointmanager.IsStaleEndpoint(endpointT, 24*time.Hour, now)).To(BeFalse())
+		})
+	})
+
+	Describe("Test HasCurrentIP", func() {
+		It("matches the IP address of the IPPool in the current IP allocation", func() {
+			Expect(workloadendpointmanager.HasCurrentIP(endpointT, "pool-v4", "172.18.40.10")).To(BeFalse())
+
+			endpointT.Status.Current = &spiderpoolv1.PodIPAllocation{
+				ContainerID: stringid.GenerateRandomID(),
+				IPs: []spiderpoolv1.IPAllocationDetail{
+					{
+						NIC:      "eth0",
+						IPv4:     pointer.String("172.18.40.10/24"),
+						IPv4Pool: pointer.String("pool-v4"),
+						IPv6:     pointer.String("abcd:1234::a/120"),
+						IPv6Pool: pointer.String("pool-v6"),
+					},
+				},
+			}
+			Expect(workloadendpointmanager.HasCurrentIP(endpointT, "pool-v4", "172.18.40.10")).To(BeTrue())
+			Expect(workloadendpointmanager.HasCurrentIP(endpointT, "pool-v6", "abcd:1234::a")).To(BeTrue())
+			Expect(workloadendpointmanager.HasCurrentIP(endpointT, "pool-v6", "172.18.40.10")).To(BeFalse())
+			Expect(workloadendpointmanager.HasCurrentIP(endpointT, "pool-v4", "172.18.40.11")).To(BeFalse())
+		})
+	})
 })
@@ -0,0 +1,112 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package workloadendpointmanager
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apitypes "k8s.io/apimachinery/pkg/types"
+
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+)
+
+// endpointWriteTTL is how long the updates of the manager are tracked, the
+// cache is supposed to have observed them by then.
+const endpointWriteTTL = time.Minute
+
+// endpointWrites tracks the resourceVersions of the Endpoints updated by the
+// manager, so that the cached Endpoints which haven't observed the updates
+// are never used.
+type endpointWrites struct {
+	mutex     sync.Mutex
+	versions  map[apitypes.NamespacedName]endpointWrite
+	lastSweep time.Time
+}
+
+type endpointWrite struct {
+	resourceVersion string
+	time            time.Time
+}
+
+func newEndpointWrites() *endpointWrites {
+	return &endpointWrites{versions: map[apitypes.NamespacedName]endpointWrite{}}
+}
+
+// record tracks the resourceVersion of the Endpoint just updated, and drops
+// the ones tracked for longer than the TTL.
+func (w *endpointWrites) record(endpoint *spiderpoolv1.SpiderEndpoint) {
+	now := time.Now()
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	key := apitypes.NamespacedName{Namespace: endpoint.Namespace, Name: endpoint.Name}
+	w.versions[key] = endpointWrite{resourceVersion: endpoint.ResourceVersion, time: now}
+
+	if now.Sub(w.lastSweep) < endpointWriteTTL {
+		return
+	}
+	for k, v := range w.versions {
+		if now.Sub(v.time) >= endpointWriteTTL {
+			delete(w.versions, k)
+		}
+	}
+	w.lastSweep = now
+}
+
+// fresh reports whether the cached Endpoint has observed the latest update of
+// the manager. The tracked update is dropped once it's observed.
+func (w *endpointWrites) fresh(key apitypes.NamespacedName, endpoint *spiderpoolv1.SpiderEndpoint) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	v, ok := w.versions[key]
+	if !ok || time.Since(v.time) >= endpointWriteTTL {
+		return true
+	}
+	if endpoint == nil || endpoint.ResourceVersion != v.resourceVersion {
+		return false
+	}
+	delete(w.versions, key)
+
+	return true
+}
+
+// GetCachedEndpointByName reads the Endpoint from CachedReader, which falls
+// back to the API server if the cache fails, or the cached one hasn't
+// observed the latest update of the manager. The cached Endpoint may still
+// miss the recent updates of the others, which are rejected by the API server
+// as conflicts when the Endpoint is updated, or guarded by the JSON patches.
+func (em *workloadEndpointManager) GetCachedEndpointByName(ctx context.Context, namespace, podName string) (*spiderpoolv1.SpiderEndpoint, error) {
+	if em.config.CachedReader == nil {
+		return em.GetEndpointByName(ctx, namespace, podName)
+	}
+
+	key := apitypes.NamespacedName{Namespace: namespace, Name: podName}
+	var endpoint spiderpoolv1.SpiderEndpoint
+	err := em.config.CachedReader.Get(ctx, key, &endpoint)
+	if err != nil && !apierrors.IsNotFound(err) {
+		// e.g. the cache isn't started yet
+		return em.GetEndpointByName(ctx, namespace, podName)
+	}
+
+	if err == nil && em.writes.fresh(key, &endpoint) {
+		return &endpoint, nil
+	}
+	if apierrors.IsNotFound(err) && em.writes.fresh(key, nil) {
+		return nil, err
+	}
+
+	return em.GetEndpointByName(ctx, namespace, podName)
+}
+
+// recordWrite tracks the update of the Endpoint if it's read from the cache.
+func (em *workloadEndpointManager) recordWrite(endpoint *spiderpoolv1.SpiderEndpoint) {
+	if em.config.CachedReader != nil {
+		em.writes.record(endpoint)
+	}
+}
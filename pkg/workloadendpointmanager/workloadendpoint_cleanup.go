@@ -0,0 +1,140 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package workloadendpointmanager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/limiter"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+const defaultCleanupParallelism = 10
+
+// EndpointCleanupOptions controls which Endpoints are cleaned up, and how.
+type EndpointCleanupOptions struct {
+	// Namespace limits the cleanup to the Endpoints in it, all Namespaces
+	// if empty.
+	Namespace string
+	// Filter selects the Endpoints to clean up among the listed ones, all
+	// of them if nil.
+	Filter func(endpoint *spiderpoolv1.SpiderEndpoint) bool
+	// Parallelism is the maximum number of the Endpoints cleaned up
+	// concurrently, 10 if not positive.
+	Parallelism int
+	// Pacer paces the cleanup between the batches of Endpoints, no pause
+	// if nil.
+	Pacer limiter.Pacer
+	// DryRun only reports the Endpoints which would be cleaned up.
+	DryRun bool
+}
+
+// EndpointCleanupResult reports the Endpoints cleaned up, by namespace/name.
+type EndpointCleanupResult struct {
+	Cleaned []string `json:"cleaned,omitempty"`
+	Failed  []string `json:"failed,omitempty"`
+}
+
+// CleanupEndpoints deletes the Endpoints matching the label selector and the
+// options, and removes their finalizers. The caller must make sure that the
+// IP addresses recorded by them are released, or no longer needed.
+func (em *workloadEndpointManager) CleanupEndpoints(ctx context.Context, selector labels.Selector, opts EndpointCleanupOptions) (*EndpointCleanupResult, error) {
+	logger := logutils.FromContext(ctx)
+
+	listOpts := []client.ListOption{client.InNamespace(opts.Namespace)}
+	if selector != nil {
+		listOpts = append(listOpts, client.MatchingLabelsSelector{Selector: selector})
+	}
+	endpointList, err := em.ListEndpoints(ctx, listOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	parallelism := opts.Parallelism
+	if parallelism <= 0 {
+		parallelism = defaultCleanupParallelism
+	}
+
+	result := &EndpointCleanupResult{}
+	var errs []error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	tokens := make(chan struct{}, parallelism)
+	dispatched := 0
+
+LOOP:
+	for i := range endpointList.Items {
+		endpoint := &endpointList.Items[i]
+		if opts.Filter != nil && !opts.Filter(endpoint) {
+			continue
+		}
+
+		key := endpoint.Namespace + "/" + endpoint.Name
+		if opts.DryRun {
+			result.Cleaned = append(result.Cleaned, key)
+			continue
+		}
+
+		if err := limiter.Pause(ctx, opts.Pacer, dispatched); err != nil {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+			break LOOP
+		}
+		dispatched++
+
+		select {
+		case tokens <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			errs = append(errs, ctx.Err())
+			mu.Unlock()
+			break LOOP
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-tokens }()
+
+			err := em.cleanupEndpoint(ctx, endpoint)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				logger.Sugar().Warnf("Failed to clean up Endpoint %s: %v", key, err)
+				result.Failed = append(result.Failed, key)
+				errs = append(errs, fmt.Errorf("failed to clean up Endpoint %s: %w", key, err))
+				return
+			}
+			result.Cleaned = append(result.Cleaned, key)
+		}()
+	}
+	wg.Wait()
+
+	sort.Strings(result.Cleaned)
+	sort.Strings(result.Failed)
+
+	return result, utilerrors.NewAggregate(errs)
+}
+
+// cleanupEndpoint deletes the Endpoint first, so that it's no longer reused
+// by the Pod with the same name, and then removes its finalizer.
+func (em *workloadEndpointManager) cleanupEndpoint(ctx context.Context, endpoint *spiderpoolv1.SpiderEndpoint) error {
+	if endpoint.DeletionTimestamp == nil {
+		if err := em.DeleteEndpoint(ctx, endpoint); err != nil {
+			return err
+		}
+	}
+
+	return em.RemoveFinalizer(ctx, endpoint.Namespace, endpoint.Name)
+}
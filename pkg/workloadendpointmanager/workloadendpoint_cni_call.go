@@ -0,0 +1,113 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package workloadendpointmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+)
+
+// maxCNICallRecords is the maximum number of the CNI calls recorded in each
+// historical IP allocation, the older ones are dropped.
+const maxCNICallRecords = 10
+
+// NewCNICall returns the record of the CNI call handled now.
+func NewCNICall(operation, node, result string) spiderpoolv1.CNICall {
+	return spiderpoolv1.CNICall{
+		Operation: operation,
+		Node:      node,
+		Result:    result,
+		Time:      &metav1.Time{Time: time.Now()},
+	}
+}
+
+// GetCNICalls returns the CNI calls of the container recorded in the
+// historical IP allocations of the Endpoint.
+func GetCNICalls(endpoint *spiderpoolv1.SpiderEndpoint, containerID string) []spiderpoolv1.CNICall {
+	if i := historyIndexOf(endpoint, containerID); i >= 0 {
+		return endpoint.Status.History[i].CNICalls
+	}
+
+	return nil
+}
+
+// LastCNICall returns the latest CNI call of the historical IP allocation,
+// nil if none is recorded.
+func LastCNICall(allocation *spiderpoolv1.PodIPAllocation) *spiderpoolv1.CNICall {
+	if allocation == nil || len(allocation.CNICalls) == 0 {
+		return nil
+	}
+
+	return &allocation.CNICalls[len(allocation.CNICalls)-1]
+}
+
+// IsRolledBack reports whether the IP addresses of the historical IP
+// allocation were rolled back after a failed CNI ADD.
+func IsRolledBack(allocation *spiderpoolv1.PodIPAllocation) bool {
+	if allocation == nil {
+		return false
+	}
+
+	for _, call := range allocation.CNICalls {
+		if call.Result == constant.CNIResultRollback {
+			return true
+		}
+	}
+
+	return false
+}
+
+// appendCNICalls appends the CNI calls, and drops the oldest ones over the
+// limit.
+func appendCNICalls(calls []spiderpoolv1.CNICall, newCalls ...spiderpoolv1.CNICall) []spiderpoolv1.CNICall {
+	calls = append(append([]spiderpoolv1.CNICall{}, calls...), newCalls...)
+	if len(calls) > maxCNICallRecords {
+		calls = calls[len(calls)-maxCNICallRecords:]
+	}
+
+	return calls
+}
+
+func historyIndexOf(endpoint *spiderpoolv1.SpiderEndpoint, containerID string) int {
+	if endpoint == nil {
+		return -1
+	}
+
+	for i := range endpoint.Status.History {
+		if endpoint.Status.History[i].ContainerID == containerID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (em *workloadEndpointManager) RecordCNICall(ctx context.Context, containerID string, call spiderpoolv1.CNICall, endpoint *spiderpoolv1.SpiderEndpoint) error {
+	i := historyIndexOf(endpoint, containerID)
+	if i < 0 {
+		return nil
+	}
+
+	patch, err := newJSONPatch(
+		jsonPatchOperation{Op: "test", Path: fmt.Sprintf("/status/history/%d/containerID", i), Value: containerID},
+		jsonPatchOperation{Op: "add", Path: fmt.Sprintf("/status/history/%d/cniCalls", i), Value: appendCNICalls(endpoint.Status.History[i].CNICalls, call)},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := em.client.Status().Patch(ctx, endpoint, patch); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	em.recordWrite(endpoint)
+
+	return nil
+}
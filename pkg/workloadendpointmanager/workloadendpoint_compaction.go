@@ -0,0 +1,101 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package workloadendpointmanager
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/limiter"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+// CompactEndpoints rewrites all Endpoints into the compact form, such as the
+// ones created by the older versions with huge histories, prunes their
+// historical records beyond the max age, and returns the number of the
+// rewritten Endpoints. The rewrites are paced by the pacer if not nil.
+func (em *workloadEndpointManager) CompactEndpoints(ctx context.Context, pacer limiter.Pacer) (int, error) {
+	logger := logutils.FromContext(ctx)
+
+	endpointList, err := em.ListEndpoints(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var compacted, written int
+	var errs []error
+	for _, endpoint := range endpointList.Items {
+		if !em.compactHistory(endpoint.DeepCopy()) {
+			continue
+		}
+
+		if err := limiter.Pause(ctx, pacer, written); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		written++
+
+		ok, err := em.compactEndpoint(ctx, endpoint.Namespace, endpoint.Name)
+		if err != nil {
+			logger.Sugar().Warnf("Failed to compact Endpoint %s/%s: %v", endpoint.Namespace, endpoint.Name, err)
+			errs = append(errs, err)
+			continue
+		}
+		if ok {
+			logger.Sugar().Debugf("Compact Endpoint %s/%s", endpoint.Namespace, endpoint.Name)
+			compacted++
+		}
+	}
+
+	return compacted, utilerrors.NewAggregate(errs)
+}
+
+func (em *workloadEndpointManager) compactEndpoint(ctx context.Context, namespace, podName string) (bool, error) {
+	for i := 0; i <= em.config.MaxConflictRetries; i++ {
+		endpoint, err := em.GetEndpointByName(ctx, namespace, podName)
+		if err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+
+		if !em.compactHistory(endpoint) {
+			return false, nil
+		}
+
+		if err := em.client.Status().Update(ctx, endpoint); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			if !apierrors.IsConflict(err) {
+				return false, err
+			}
+			if i == em.config.MaxConflictRetries {
+				return false, fmt.Errorf("%w (%d times), failed to compact Endpoint %s/%s", constant.ErrRetriesExhausted, em.config.MaxConflictRetries, namespace, podName)
+			}
+			time.Sleep(time.Duration(rand.Intn(1<<(i+1))) * em.config.ConflictRetryUnitTime)
+			continue
+		}
+		break
+	}
+
+	return true, nil
+}
+
+func (em *workloadEndpointManager) compactHistory(endpoint *spiderpoolv1.SpiderEndpoint) bool {
+	compacted := CompactEndpoint(endpoint, *em.config.MaxHistoryRecords)
+	pruned := PruneEndpointHistory(endpoint, em.config.MaxHistoryAge, time.Now())
+	if !compacted && !pruned {
+		return false
+	}
+	setEndpointSummary(endpoint)
+
+	return true
+}
@@ -0,0 +1,227 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package workloadendpointmanager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolip "github.com/spidernet-io/spiderpool/pkg/ip"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+	"github.com/spidernet-io/spiderpool/pkg/types"
+)
+
+// The fields of the current IP allocation which the Endpoints are indexed by.
+const (
+	EndpointFieldContainerID = "status.current.containerID"
+	EndpointFieldNode        = "status.current.node"
+	EndpointFieldIP          = "status.current.ips"
+)
+
+// EndpointFieldOwnerController is the field of the top controller of the
+// Pod which the Endpoints are indexed by, in the form of "<kind>/<name>".
+const EndpointFieldOwnerController = "status.ownerController"
+
+// EndpointIndexers are the index functions of the Endpoint fields.
+var EndpointIndexers = map[string]client.IndexerFunc{
+	EndpointFieldContainerID:     IndexEndpointContainerID,
+	EndpointFieldNode:            IndexEndpointNode,
+	EndpointFieldIP:              IndexEndpointIPs,
+	EndpointFieldOwnerController: IndexEndpointOwnerController,
+}
+
+// RegisterEndpointIndexers registers the index functions of the Endpoint
+// fields to the cache, whose reader is set as IndexedReader of the config of
+// the Endpoint manager.
+func RegisterEndpointIndexers(ctx context.Context, indexer client.FieldIndexer) error {
+	for field, indexerFunc := range EndpointIndexers {
+		if err := indexer.IndexField(ctx, &spiderpoolv1.SpiderEndpoint{}, field, indexerFunc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IndexEndpointContainerID indexes the Endpoint by the container ID of its
+// current IP allocation.
+func IndexEndpointContainerID(obj client.Object) []string {
+	current := obj.(*spiderpoolv1.SpiderEndpoint).Status.Current
+	if current == nil || current.ContainerID == "" {
+		return nil
+	}
+
+	return []string{current.ContainerID}
+}
+
+// IndexEndpointNode indexes the Endpoint by the node of its current IP
+// allocation.
+func IndexEndpointNode(obj client.Object) []string {
+	current := obj.(*spiderpoolv1.SpiderEndpoint).Status.Current
+	if current == nil || current.Node == nil || *current.Node == "" {
+		return nil
+	}
+
+	return []string{*current.Node}
+}
+
+// IndexEndpointIPs indexes the Endpoint by the IP addresses of its current
+// IP allocation, without the prefix length.
+func IndexEndpointIPs(obj client.Object) []string {
+	current := obj.(*spiderpoolv1.SpiderEndpoint).Status.Current
+	if current == nil {
+		return nil
+	}
+
+	var ips []string
+	for _, d := range current.IPs {
+		if d.IPv4 != nil {
+			ip, _, _ := strings.Cut(*d.IPv4, "/")
+			ips = append(ips, ip)
+		}
+		if d.IPv6 != nil {
+			ip, _, _ := strings.Cut(*d.IPv6, "/")
+			ips = append(ips, ip)
+		}
+	}
+
+	return ips
+}
+
+// IndexEndpointOwnerController indexes the Endpoint by the top controller of
+// its Pod.
+func IndexEndpointOwnerController(obj client.Object) []string {
+	status := obj.(*spiderpoolv1.SpiderEndpoint).Status
+	if status.OwnerControllerType == "" || status.OwnerControllerName == "" {
+		return nil
+	}
+
+	return []string{status.OwnerControllerType + "/" + status.OwnerControllerName}
+}
+
+func (em *workloadEndpointManager) ListEndpointsByContainerID(ctx context.Context, containerID string) ([]spiderpoolv1.SpiderEndpoint, error) {
+	return em.listIndexedEndpoints(ctx, EndpointFieldContainerID, containerID)
+}
+
+// ListEndpointsByNode lists the Endpoints whose current IP allocations are
+// on the node. Without IndexedReader, they are listed from CachedReader if
+// its Endpoints are indexed by RegisterEndpointIndexers, which may miss the
+// recent updates but spares listing all the Endpoints of the cluster for
+// every node.
+func (em *workloadEndpointManager) ListEndpointsByNode(ctx context.Context, nodeName string) ([]spiderpoolv1.SpiderEndpoint, error) {
+	if em.config.IndexedReader == nil && em.config.CachedReader != nil {
+		var endpointList spiderpoolv1.SpiderEndpointList
+		err := em.config.CachedReader.List(ctx, &endpointList, client.MatchingFields{EndpointFieldNode: nodeName})
+		if err == nil {
+			return endpointList.Items, nil
+		}
+		logutils.FromContext(ctx).Sugar().Warnf("Failed to list the Endpoints of node %s from cache, list all of them: %v", nodeName, err)
+	}
+
+	return em.listIndexedEndpoints(ctx, EndpointFieldNode, nodeName)
+}
+
+func (em *workloadEndpointManager) ListEndpointsByIP(ctx context.Context, ip string) ([]spiderpoolv1.SpiderEndpoint, error) {
+	return em.listIndexedEndpoints(ctx, EndpointFieldIP, ip)
+}
+
+// ListCachedEndpointsByOwnerController lists the Endpoints of the Pods of the
+// top controller in the namespace from CachedReader, whose Endpoints are
+// indexed by RegisterEndpointIndexers. The cached Endpoints may miss the
+// recent updates. Without the cache, or if it fails, all the Endpoints in
+// the namespace are listed from the API server and filtered.
+func (em *workloadEndpointManager) ListCachedEndpointsByOwnerController(ctx context.Context, namespace, kind, name string) ([]spiderpoolv1.SpiderEndpoint, error) {
+	value := kind + "/" + name
+	if em.config.CachedReader != nil {
+		var endpointList spiderpoolv1.SpiderEndpointList
+		err := em.config.CachedReader.List(ctx, &endpointList, client.InNamespace(namespace), client.MatchingFields{EndpointFieldOwnerController: value})
+		if err == nil {
+			return endpointList.Items, nil
+		}
+	}
+
+	endpointList, err := em.ListEndpoints(ctx, client.InNamespace(namespace))
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []spiderpoolv1.SpiderEndpoint
+	for i := range endpointList.Items {
+		for _, v := range IndexEndpointOwnerController(&endpointList.Items[i]) {
+			if v == value {
+				endpoints = append(endpoints, endpointList.Items[i])
+			}
+		}
+	}
+
+	return endpoints, nil
+}
+
+// GetEndpointByIP returns the Endpoint whose current IP allocation holds the
+// IP address. It returns a NotFound error if the IP address isn't allocated,
+// and ErrIPConflict if it's allocated to more than one Pod.
+func (em *workloadEndpointManager) GetEndpointByIP(ctx context.Context, ipVersion types.IPVersion, ip string) (*spiderpoolv1.SpiderEndpoint, error) {
+	if err := spiderpoolip.IsIP(ipVersion, ip); err != nil {
+		return nil, fmt.Errorf("%w: %v", constant.ErrWrongInput, err)
+	}
+
+	// IPv6 addresses are indexed in the canonical form.
+	ip = net.ParseIP(ip).String()
+	endpoints, err := em.ListEndpointsByIP(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(endpoints) {
+	case 0:
+		return nil, apierrors.NewNotFound(spiderpoolv1.Resource("spiderendpoint"), ip)
+	case 1:
+		return &endpoints[0], nil
+	default:
+		var owners []string
+		for _, endpoint := range endpoints {
+			owners = append(owners, endpoint.Namespace+"/"+endpoint.Name)
+		}
+		return nil, fmt.Errorf("%w: IP address %s is allocated to %v", constant.ErrIPConflict, ip, owners)
+	}
+}
+
+// listIndexedEndpoints lists the Endpoints with the value of the indexed
+// field from the cache. Without the indexed cache, all Endpoints are listed
+// and filtered by the same index function.
+func (em *workloadEndpointManager) listIndexedEndpoints(ctx context.Context, field, value string) ([]spiderpoolv1.SpiderEndpoint, error) {
+	if em.config.IndexedReader != nil {
+		var endpointList spiderpoolv1.SpiderEndpointList
+		if err := em.config.IndexedReader.List(ctx, &endpointList, client.MatchingFields{field: value}); err != nil {
+			return nil, err
+		}
+
+		return endpointList.Items, nil
+	}
+
+	endpointList, err := em.ListEndpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	indexerFunc := EndpointIndexers[field]
+	var endpoints []spiderpoolv1.SpiderEndpoint
+	for i := range endpointList.Items {
+		for _, v := range indexerFunc(&endpointList.Items[i]) {
+			if v == value {
+				endpoints = append(endpoints, endpointList.Items[i])
+				break
+			}
+		}
+	}
+
+	return endpoints, nil
+}
@@ -13,31 +13,44 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	apitypes "k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/spidernet-io/spiderpool/pkg/constant"
 	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/limiter"
 	"github.com/spidernet-io/spiderpool/pkg/logutils"
 	"github.com/spidernet-io/spiderpool/pkg/types"
 )
 
 type WorkloadEndpointManager interface {
 	GetEndpointByName(ctx context.Context, namespace, podName string) (*spiderpoolv1.SpiderEndpoint, error)
+	GetCachedEndpointByName(ctx context.Context, namespace, podName string) (*spiderpoolv1.SpiderEndpoint, error)
 	ListEndpoints(ctx context.Context, opts ...client.ListOption) (*spiderpoolv1.SpiderEndpointList, error)
+	ListEndpointsByContainerID(ctx context.Context, containerID string) ([]spiderpoolv1.SpiderEndpoint, error)
+	ListEndpointsByNode(ctx context.Context, nodeName string) ([]spiderpoolv1.SpiderEndpoint, error)
+	ListEndpointsByIP(ctx context.Context, ip string) ([]spiderpoolv1.SpiderEndpoint, error)
+	ListCachedEndpointsByOwnerController(ctx context.Context, namespace, kind, name string) ([]spiderpoolv1.SpiderEndpoint, error)
+	GetEndpointByIP(ctx context.Context, ipVersion types.IPVersion, ip string) (*spiderpoolv1.SpiderEndpoint, error)
 	DeleteEndpoint(ctx context.Context, endpoint *spiderpoolv1.SpiderEndpoint) error
 	RemoveFinalizer(ctx context.Context, namespace, podName string) error
+	CleanupEndpoints(ctx context.Context, selector labels.Selector, opts EndpointCleanupOptions) (*EndpointCleanupResult, error)
 	MarkIPAllocation(ctx context.Context, containerID string, pod *corev1.Pod, podController types.PodTopController) (*spiderpoolv1.SpiderEndpoint, error)
 	ReMarkIPAllocation(ctx context.Context, containerID string, endpoint *spiderpoolv1.SpiderEndpoint, pod *corev1.Pod) error
 	PatchIPAllocation(ctx context.Context, allocation *spiderpoolv1.PodIPAllocation, endpoint *spiderpoolv1.SpiderEndpoint) error
 	ClearCurrentIPAllocation(ctx context.Context, containerID string, endpoint *spiderpoolv1.SpiderEndpoint) error
+	RecordCNICall(ctx context.Context, containerID string, call spiderpoolv1.CNICall, endpoint *spiderpoolv1.SpiderEndpoint) error
 	ReallocateCurrentIPAllocation(ctx context.Context, containerID, nodeName string, endpoint *spiderpoolv1.SpiderEndpoint) error
+	CompactEndpoints(ctx context.Context, pacer limiter.Pacer) (int, error)
+	MigrateEndpoints(ctx context.Context, report func(EndpointMigrationProgress), pacer limiter.Pacer) (EndpointMigrationProgress, error)
 }
 
 type workloadEndpointManager struct {
 	config EndpointManagerConfig
 	client client.Client
+	writes *endpointWrites
 }
 
 func NewWorkloadEndpointManager(config EndpointManagerConfig, client client.Client) (WorkloadEndpointManager, error) {
@@ -48,6 +61,7 @@ func NewWorkloadEndpointManager(config EndpointManagerConfig, client client.Clie
 	return &workloadEndpointManager{
 		config: setDefaultsForEndpointManagerConfig(config),
 		client: client,
+		writes: newEndpointWrites(),
 	}, nil
 }
 
@@ -129,6 +143,7 @@ func (em *workloadEndpointManager) MarkIPAllocation(ctx context.Context, contain
 		}
 	}
 	controllerutil.AddFinalizer(endpoint, constant.SpiderFinalizer)
+	setEndpointSchemaVersion(endpoint, LatestEndpointSchemaVersion())
 
 	logger.Sugar().Debugf("Create a new Endpoint %s/%s", endpoint.Namespace, endpoint.Name)
 	if err := em.client.Create(ctx, endpoint); err != nil {
@@ -145,11 +160,13 @@ func (em *workloadEndpointManager) MarkIPAllocation(ctx context.Context, contain
 	endpoint.Status.History = []spiderpoolv1.PodIPAllocation{*allocation}
 	endpoint.Status.OwnerControllerType = podController.Kind
 	endpoint.Status.OwnerControllerName = podController.Name
+	setEndpointSummary(endpoint)
 
 	logger.Sugar().Debugf("Update the current container ID of the new Endpoint %s/%s", endpoint.Namespace, endpoint.Name)
 	if err := em.client.Status().Update(ctx, endpoint); err != nil {
 		return nil, err
 	}
+	em.recordWrite(endpoint)
 
 	return endpoint, nil
 }
@@ -188,18 +205,36 @@ func (em *workloadEndpointManager) ReMarkIPAllocation(ctx context.Context, conta
 		CreationTime: &metav1.Time{Time: time.Now()},
 	}
 
+	// The Pod sandbox restarting with the same IP addresses leaves the
+	// identical records, which are collapsed to keep the history short.
+	if CollapseEndpointHistory(endpoint) {
+		logger.Sugar().Debugf("Collapse the identical historical IP allocations of the Endpoint %s/%s", endpoint.Namespace, endpoint.Name)
+	}
+
 	endpoint.Status.Current = allocation
 	endpoint.Status.History = append([]spiderpoolv1.PodIPAllocation{*allocation}, endpoint.Status.History...)
 	if len(endpoint.Status.History) > *em.config.MaxHistoryRecords {
 		logger.Sugar().Warnf("threshold of historical IP allocation records(<=%d) exceeded", em.config.MaxHistoryRecords)
 		endpoint.Status.History = endpoint.Status.History[:*em.config.MaxHistoryRecords]
 	}
+	PruneEndpointHistory(endpoint, em.config.MaxHistoryAge, time.Now())
+	setEndpointSummary(endpoint)
 
 	logger.Sugar().Debugf("Change the current container ID of the Endpoint %s/%s", endpoint.Namespace, endpoint.Name)
+	if err := em.client.Status().Update(ctx, endpoint); err != nil {
+		return err
+	}
+	em.recordWrite(endpoint)
 
-	return em.client.Status().Update(ctx, endpoint)
+	return nil
 }
 
+// PatchIPAllocation sets the IP allocation details of the NICs in the
+// allocation to the current IP allocation of the Endpoint, the details of the
+// other NICs are kept. The patch is guarded by the IP allocation details it's
+// merged with, so the NICs of a Pod patched in parallel never overwrite each
+// other, the one which finds the details changed merges with the latest
+// Endpoint and patches again.
 func (em *workloadEndpointManager) PatchIPAllocation(ctx context.Context, allocation *spiderpoolv1.PodIPAllocation, endpoint *spiderpoolv1.SpiderEndpoint) error {
 	if endpoint == nil {
 		return fmt.Errorf("endpoint %w", constant.ErrMissingRequiredParam)
@@ -209,23 +244,93 @@ func (em *workloadEndpointManager) PatchIPAllocation(ctx context.Context, alloca
 		return fmt.Errorf("allocation %w", constant.ErrMissingRequiredParam)
 	}
 
-	if endpoint.Status.Current == nil {
-		return errors.New("patch a unmarked Endpoint")
+	logger := logutils.FromContext(ctx)
+	for i := 0; i <= em.config.MaxConflictRetries; i++ {
+		if endpoint.Status.Current == nil {
+			return errors.New("patch a unmarked Endpoint")
+		}
+
+		if len(endpoint.Status.History) == 0 ||
+			endpoint.Status.History[0].ContainerID != endpoint.Status.Current.ContainerID {
+			return errors.New("data of the Endpoint is corrupt")
+		}
+
+		if endpoint.Status.Current.ContainerID != allocation.ContainerID {
+			return errors.New("patch a mismarked Endpoint")
+		}
+
+		patch, err := newIPAllocationPatch(allocation, endpoint)
+		if err != nil {
+			return err
+		}
+
+		if err := em.client.Status().Patch(ctx, endpoint, patch); err != nil {
+			if !isJSONPatchTestFailed(err) {
+				return err
+			}
+			if i == em.config.MaxConflictRetries {
+				return fmt.Errorf("%w (%d times), failed to patch IP allocation to Endpoint %s/%s", constant.ErrRetriesExhausted, em.config.MaxConflictRetries, endpoint.Namespace, endpoint.Name)
+			}
+
+			logger.Sugar().Debugf("Endpoint %s/%s is patched in the meantime, merge with the latest one", endpoint.Namespace, endpoint.Name)
+			time.Sleep(time.Duration(rand.Intn(1<<(i+1))) * em.config.ConflictRetryUnitTime)
+			if err := em.client.Get(ctx, apitypes.NamespacedName{Namespace: endpoint.Namespace, Name: endpoint.Name}, endpoint); err != nil {
+				return err
+			}
+			continue
+		}
+		em.recordWrite(endpoint)
+		break
 	}
 
-	if len(endpoint.Status.History) == 0 ||
-		endpoint.Status.History[0].ContainerID != endpoint.Status.Current.ContainerID {
-		return errors.New("data of the Endpoint is corrupt")
+	return nil
+}
+
+// newIPAllocationPatch builds the patch merging the IP allocation details of
+// the allocation into the current and the latest historical IP allocations
+// of the Endpoint.
+func newIPAllocationPatch(allocation *spiderpoolv1.PodIPAllocation, endpoint *spiderpoolv1.SpiderEndpoint) (client.Patch, error) {
+	current := endpoint.Status.Current.DeepCopy()
+	current.IPs = mergeIPAllocationDetails(endpoint.Status.Current.IPs, allocation.IPs)
+	// The CNI calls of the allocation are recorded in the historical one.
+	history := current.DeepCopy()
+	history.CNICalls = endpoint.Status.History[0].CNICalls
+	if len(allocation.CNICalls) != 0 {
+		history.CNICalls = appendCNICalls(history.CNICalls, allocation.CNICalls...)
 	}
+	patched := endpoint.DeepCopy()
+	patched.Status.Current = current
+
+	return newJSONPatch(
+		jsonPatchOperation{Op: "test", Path: "/status/current/containerID", Value: allocation.ContainerID},
+		jsonPatchOperation{Op: "test", Path: "/status/history/0/containerID", Value: allocation.ContainerID},
+		jsonPatchOperation{Op: "test", Path: "/status/current/ips", Value: endpoint.Status.Current.IPs},
+		jsonPatchOperation{Op: "test", Path: "/status/history/0/cniCalls", Value: endpoint.Status.History[0].CNICalls},
+		jsonPatchOperation{Op: "add", Path: "/status/current/ips", Value: current.IPs},
+		jsonPatchOperation{Op: "replace", Path: "/status/history/0", Value: history},
+		jsonPatchOperation{Op: "add", Path: "/status/summary", Value: BuildEndpointSummary(patched)},
+	)
+}
 
-	if endpoint.Status.Current.ContainerID != allocation.ContainerID {
-		return errors.New("patch a mismarked Endpoint")
+// mergeIPAllocationDetails replaces the IP allocation details of the NICs in
+// patches, and appends the ones of the new NICs.
+func mergeIPAllocationDetails(details, patches []spiderpoolv1.IPAllocationDetail) []spiderpoolv1.IPAllocationDetail {
+	nics := map[string]struct{}{}
+	for _, d := range patches {
+		nics[d.NIC] = struct{}{}
 	}
 
-	endpoint.Status.Current.IPs = allocation.IPs
-	endpoint.Status.History = append([]spiderpoolv1.PodIPAllocation{*endpoint.Status.Current}, endpoint.Status.History...)
+	merged := []spiderpoolv1.IPAllocationDetail{}
+	for _, d := range details {
+		if _, ok := nics[d.NIC]; !ok {
+			merged = append(merged, *d.DeepCopy())
+		}
+	}
+	for _, d := range patches {
+		merged = append(merged, *d.DeepCopy())
+	}
 
-	return em.client.Status().Update(ctx, endpoint)
+	return merged
 }
 
 func (em *workloadEndpointManager) ClearCurrentIPAllocation(ctx context.Context, containerID string, endpoint *spiderpoolv1.SpiderEndpoint) error {
@@ -237,10 +342,21 @@ func (em *workloadEndpointManager) ClearCurrentIPAllocation(ctx context.Context,
 		return nil
 	}
 
-	endpoint.Status.Current = nil
-	if err := em.client.Status().Update(ctx, endpoint); err != nil {
+	cleared := endpoint.DeepCopy()
+	cleared.Status.Current = nil
+	patch, err := newJSONPatch(
+		jsonPatchOperation{Op: "test", Path: "/status/current/containerID", Value: containerID},
+		jsonPatchOperation{Op: "remove", Path: "/status/current"},
+		jsonPatchOperation{Op: "add", Path: "/status/summary", Value: BuildEndpointSummary(cleared)},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := em.client.Status().Patch(ctx, endpoint, patch); err != nil {
 		return client.IgnoreNotFound(err)
 	}
+	em.recordWrite(endpoint)
 
 	return nil
 }
@@ -261,6 +377,11 @@ func (em *workloadEndpointManager) ReallocateCurrentIPAllocation(ctx context.Con
 	endpoint.Status.Current.ContainerID = containerID
 	*endpoint.Status.Current.Node = nodeName
 	endpoint.Status.History = append([]spiderpoolv1.PodIPAllocation{*endpoint.Status.Current}, endpoint.Status.History...)
+	setEndpointSummary(endpoint)
+	if err := em.client.Status().Update(ctx, endpoint); err != nil {
+		return err
+	}
+	em.recordWrite(endpoint)
 
-	return em.client.Status().Update(ctx, endpoint)
+	return nil
 }
@@ -6,6 +6,7 @@ package workloadendpointmanager_test
 import (
 	"context"
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -17,11 +18,14 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfields "k8s.io/apimachinery/pkg/fields"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/spidernet-io/spiderpool/pkg/constant"
@@ -30,6 +34,54 @@ import (
 	"github.com/spidernet-io/spiderpool/pkg/workloadendpointmanager"
 )
 
+// fieldIndexedReader serves the Lists by the indexed field of the Endpoints
+// like the informer cache with the indexers registered.
+type fieldIndexedReader struct {
+	client.Reader
+	lists int
+}
+
+func (r *fieldIndexedReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	r.lists++
+	listOptions := (&client.ListOptions{}).ApplyOptions(opts)
+	if err := r.Reader.List(ctx, list, client.InNamespace(listOptions.Namespace)); err != nil {
+		return err
+	}
+	if listOptions.FieldSelector == nil {
+		return nil
+	}
+
+	endpointList := list.(*spiderpoolv1.SpiderEndpointList)
+	var items []spiderpoolv1.SpiderEndpoint
+	for _, endpoint := range endpointList.Items {
+		for field, indexerFunc := range workloadendpointmanager.EndpointIndexers {
+			for _, v := range indexerFunc(&endpoint) {
+				if listOptions.FieldSelector.Matches(k8sfields.Set{field: v}) {
+					items = append(items, endpoint)
+				}
+			}
+		}
+	}
+	endpointList.Items = items
+
+	return nil
+}
+
+// fakePacer records the number of writes done before each write, and fails
+// with err if set.
+type fakePacer struct {
+	mu      sync.Mutex
+	written []int
+	err     error
+}
+
+func (p *fakePacer) Pause(ctx context.Context, written int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.written = append(p.written, written)
+	return p.err
+}
+
 var _ = Describe("WorkloadEndpointManager", Label("workloadendpoint_manager_test"), func() {
 	Describe("New WorkloadEndpointManager", func() {
 		It("sets default config", func() {
@@ -114,6 +166,89 @@ var _ = Describe("WorkloadEndpointManager", Label("workloadendpoint_manager_test
 			})
 		})
 
+		Describe("GetCachedEndpointByName", func() {
+			var cachedClient client.Client
+			var cachedManager workloadendpointmanager.WorkloadEndpointManager
+
+			BeforeEach(func() {
+				cachedClient = fake.NewClientBuilder().
+					WithScheme(scheme).
+					Build()
+
+				var err error
+				cachedManager, err = workloadendpointmanager.NewWorkloadEndpointManager(
+					workloadendpointmanager.EndpointManagerConfig{CachedReader: cachedClient},
+					fakeClient,
+				)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("gets the Endpoint from the API server without cache", func() {
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				endpoint, err := endpointManager.GetCachedEndpointByName(ctx, namespace, endpointName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoint).To(Equal(endpointT))
+			})
+
+			It("gets non-existent Endpoint from the cache", func() {
+				ctx := context.TODO()
+				endpoint, err := cachedManager.GetCachedEndpointByName(ctx, namespace, endpointName)
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+				Expect(endpoint).To(BeNil())
+			})
+
+			It("gets the Endpoint from the cache", func() {
+				ctx := context.TODO()
+				err := cachedClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				endpoint, err := cachedManager.GetCachedEndpointByName(ctx, namespace, endpointName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoint).To(Equal(endpointT))
+			})
+
+			It("falls back to the API server if the cache fails", func() {
+				// Endpoint isn't registered in the scheme of the cache.
+				brokenManager, err := workloadendpointmanager.NewWorkloadEndpointManager(
+					workloadendpointmanager.EndpointManagerConfig{CachedReader: fake.NewClientBuilder().Build()},
+					fakeClient,
+				)
+				Expect(err).NotTo(HaveOccurred())
+
+				ctx := context.TODO()
+				err = fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				endpoint, err := brokenManager.GetCachedEndpointByName(ctx, namespace, endpointName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoint).To(Equal(endpointT))
+			})
+
+			It("falls back to the API server if the cached Endpoint is stale", func() {
+				containerID := stringid.GenerateRandomID()
+				endpointT.Status.Current = &spiderpoolv1.PodIPAllocation{ContainerID: containerID}
+				endpointT.Status.History = []spiderpoolv1.PodIPAllocation{*endpointT.Status.Current}
+
+				ctx := context.TODO()
+				err := cachedClient.Create(ctx, endpointT.DeepCopy())
+				Expect(err).NotTo(HaveOccurred())
+				err = fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				call := workloadendpointmanager.NewCNICall(constant.CNIOperationAdd, "node", constant.CNIResultSuccess)
+				err = cachedManager.RecordCNICall(ctx, containerID, call, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				endpoint, err := cachedManager.GetCachedEndpointByName(ctx, namespace, endpointName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoint.ResourceVersion).To(Equal(endpointT.ResourceVersion))
+				Expect(workloadendpointmanager.GetCNICalls(endpoint, containerID)).To(HaveLen(1))
+			})
+		})
+
 		Describe("ListEndpoints", func() {
 			It("failed to list Endpoints due to some unknown errors", func() {
 				patches := gomonkey.ApplyMethodReturn(fakeClient, "List", constant.ErrUnknown)
@@ -285,6 +420,115 @@ var _ = Describe("WorkloadEndpointManager", Label("workloadendpoint_manager_test
 			})
 		})
 
+		Describe("CleanupEndpoints", func() {
+			var another *spiderpoolv1.SpiderEndpoint
+
+			BeforeEach(func() {
+				controllerutil.AddFinalizer(endpointT, constant.SpiderFinalizer)
+				another = endpointT.DeepCopy()
+				another.Name = endpointName + "-another"
+			})
+
+			AfterEach(func() {
+				ctx := context.TODO()
+				err := fakeClient.Delete(ctx, another, deleteOption)
+				Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+			})
+
+			It("failed to list Endpoints due to some unknown errors", func() {
+				patches := gomonkey.ApplyMethodReturn(fakeClient, "List", constant.ErrUnknown)
+				defer patches.Reset()
+
+				ctx := context.TODO()
+				_, err := endpointManager.CleanupEndpoints(ctx, nil, workloadendpointmanager.EndpointCleanupOptions{})
+				Expect(err).To(MatchError(constant.ErrUnknown))
+			})
+
+			It("only reports the Endpoints to clean up in dry-run mode", func() {
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				result, err := endpointManager.CleanupEndpoints(ctx, k8slabels.SelectorFromSet(labels), workloadendpointmanager.EndpointCleanupOptions{
+					Namespace: namespace,
+					DryRun:    true,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Cleaned).To(Equal([]string{namespace + "/" + endpointName}))
+
+				err = fakeClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: endpointName}, &spiderpoolv1.SpiderEndpoint{})
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("failed to remove the finalizer of Endpoint due to some unknown errors", func() {
+				patches := gomonkey.ApplyMethodReturn(fakeClient, "Update", constant.ErrUnknown)
+				defer patches.Reset()
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				result, err := endpointManager.CleanupEndpoints(ctx, k8slabels.SelectorFromSet(labels), workloadendpointmanager.EndpointCleanupOptions{})
+				Expect(err).To(MatchError(constant.ErrUnknown))
+				Expect(result.Failed).To(Equal([]string{namespace + "/" + endpointName}))
+			})
+
+			It("paces the cleanup with the pacer", func() {
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+				err = fakeClient.Create(ctx, another)
+				Expect(err).NotTo(HaveOccurred())
+
+				pacer := &fakePacer{}
+				result, err := endpointManager.CleanupEndpoints(ctx, k8slabels.SelectorFromSet(labels), workloadendpointmanager.EndpointCleanupOptions{
+					Parallelism: 1,
+					Pacer:       pacer,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Cleaned).To(HaveLen(2))
+				Expect(pacer.written).To(Equal([]int{0, 1}))
+			})
+
+			It("stops the cleanup once the pacer fails", func() {
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				pacer := &fakePacer{err: context.Canceled}
+				result, err := endpointManager.CleanupEndpoints(ctx, k8slabels.SelectorFromSet(labels), workloadendpointmanager.EndpointCleanupOptions{
+					Pacer: pacer,
+				})
+				Expect(err).To(MatchError(context.Canceled))
+				Expect(result.Cleaned).To(BeEmpty())
+
+				err = fakeClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: endpointName}, &spiderpoolv1.SpiderEndpoint{})
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("cleans up the selected Endpoints", func() {
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+				err = fakeClient.Create(ctx, another)
+				Expect(err).NotTo(HaveOccurred())
+
+				result, err := endpointManager.CleanupEndpoints(ctx, k8slabels.SelectorFromSet(labels), workloadendpointmanager.EndpointCleanupOptions{
+					Filter: func(endpoint *spiderpoolv1.SpiderEndpoint) bool {
+						return endpoint.Name == another.Name
+					},
+					Parallelism: 1,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Cleaned).To(Equal([]string{namespace + "/" + another.Name}))
+
+				err = fakeClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: another.Name}, &spiderpoolv1.SpiderEndpoint{})
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+				err = fakeClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: endpointName}, &spiderpoolv1.SpiderEndpoint{})
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
 		Describe("MarkIPAllocation", func() {
 			var podT *corev1.Pod
 
@@ -483,6 +727,41 @@ var _ = Describe("WorkloadEndpointManager", Label("workloadendpoint_manager_test
 				Expect(endpoint.Status.History).To(HaveLen(1))
 				Expect(*endpoint.Status.Current).To(Equal(endpoint.Status.History[0]))
 			})
+
+			It("collapses the identical IP allocations of the restarted sandboxes", func() {
+				first := time.Now().Add(-time.Hour)
+				endpointT.Status.History[0].CreationTime = &metav1.Time{Time: first}
+				endpointT.Status.History = append([]spiderpoolv1.PodIPAllocation{{
+					ContainerID:  stringid.GenerateRandomID(),
+					Node:         &podT.Spec.NodeName,
+					CreationTime: &metav1.Time{Time: time.Now()},
+				}}, endpointT.Status.History...)
+				restarted := endpointT.Status.History[0]
+				endpointT.Status.Current = restarted.DeepCopy()
+
+				manager, err := workloadendpointmanager.NewWorkloadEndpointManager(
+					workloadendpointmanager.EndpointManagerConfig{MaxHistoryRecords: pointer.Int(10)},
+					fakeClient,
+				)
+				Expect(err).NotTo(HaveOccurred())
+
+				ctx := context.TODO()
+				err = fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				newContainerID := stringid.GenerateRandomID()
+				err = manager.ReMarkIPAllocation(ctx, newContainerID, endpointT, podT)
+				Expect(err).NotTo(HaveOccurred())
+
+				var endpoint spiderpoolv1.SpiderEndpoint
+				err = fakeClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: endpointName}, &endpoint)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoint.Status.History).To(HaveLen(2))
+				Expect(endpoint.Status.History[0].ContainerID).To(Equal(newContainerID))
+				Expect(endpoint.Status.History[1].ContainerID).To(Equal(restarted.ContainerID))
+				Expect(endpoint.Status.History[1].CreationTime.Unix()).To(Equal(first.Unix()))
+				Expect(endpoint.Status.History[1].LastCreationTime.Unix()).To(Equal(restarted.CreationTime.Unix()))
+			})
 		})
 
 		Describe("PatchIPAllocation", func() {
@@ -562,8 +841,8 @@ var _ = Describe("WorkloadEndpointManager", Label("workloadendpoint_manager_test
 				Expect(err).To(HaveOccurred())
 			})
 
-			It("failed to update the status of Endpoint due to some unknown errors", func() {
-				patches := gomonkey.ApplyMethodReturn(fakeClient.Status(), "Update", constant.ErrUnknown)
+			It("failed to patch the status of Endpoint due to some unknown errors", func() {
+				patches := gomonkey.ApplyMethodReturn(fakeClient.Status(), "Patch", constant.ErrUnknown)
 				defer patches.Reset()
 
 				endpointT.Status.Current = marked
@@ -590,6 +869,126 @@ var _ = Describe("WorkloadEndpointManager", Label("workloadendpoint_manager_test
 				Expect(err).NotTo(HaveOccurred())
 				Expect(endpoint.Status.Current.IPs).To(Equal(patch.IPs))
 				Expect(*endpoint.Status.Current).To(Equal(endpoint.Status.History[0]))
+				Expect(endpoint.Status.Summary).NotTo(BeNil())
+				Expect(endpoint.Status.Summary.IPs).To(Equal("172.18.40.10,abcd:1234::a"))
+				Expect(endpoint.Status.Summary.Pools).To(Equal("default-ipv4-ippool,default-ipv6-ippool"))
+				Expect(endpoint.Status.Summary.Node).To(Equal("node"))
+			})
+
+			It("patches the IP allocation of the stale Endpoint without conflict", func() {
+				endpointT.Status.Current = marked
+				endpointT.Status.History = append(endpointT.Status.History, *marked)
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				// Another NIC updates the Endpoint in the meantime.
+				stale := endpointT.DeepCopy()
+				endpointT.Labels["updated"] = "true"
+				err = fakeClient.Update(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(stale.ResourceVersion).NotTo(Equal(endpointT.ResourceVersion))
+
+				err = endpointManager.PatchIPAllocation(ctx, patch, stale)
+				Expect(err).NotTo(HaveOccurred())
+
+				var endpoint spiderpoolv1.SpiderEndpoint
+				err = fakeClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: endpointName}, &endpoint)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoint.Labels).To(HaveKeyWithValue("updated", "true"))
+				Expect(endpoint.Status.Current.IPs).To(Equal(patch.IPs))
+				Expect(endpoint.Status.History).To(HaveLen(1))
+			})
+
+			It("patches the IP allocations of the NICs in parallel without overwriting each other", func() {
+				endpointT.Status.Current = marked
+				endpointT.Status.History = append(endpointT.Status.History, *marked)
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				manager, err := workloadendpointmanager.NewWorkloadEndpointManager(
+					workloadendpointmanager.EndpointManagerConfig{MaxConflictRetries: 20},
+					fakeClient,
+				)
+				Expect(err).NotTo(HaveOccurred())
+
+				nics := []string{"eth0", "net1", "net2", "net3", "net4"}
+				var wg sync.WaitGroup
+				errCh := make(chan error, len(nics))
+				for n, nic := range nics {
+					wg.Add(1)
+					go func(n int, nic string) {
+						defer GinkgoRecover()
+						defer wg.Done()
+
+						// Every NIC patches its own stale copy of the Endpoint.
+						stale := endpointT.DeepCopy()
+						errCh <- manager.PatchIPAllocation(ctx, &spiderpoolv1.PodIPAllocation{
+							ContainerID: marked.ContainerID,
+							IPs: []spiderpoolv1.IPAllocationDetail{{
+								NIC:      nic,
+								IPv4:     pointer.String(fmt.Sprintf("172.18.4%d.10/24", n)),
+								IPv4Pool: pointer.String(fmt.Sprintf("ipv4-ippool-%s", nic)),
+							}},
+							CNICalls: []spiderpoolv1.CNICall{workloadendpointmanager.NewCNICall(constant.CNIOperationAdd, "node", constant.CNIResultSuccess)},
+						}, stale)
+					}(n, nic)
+				}
+				wg.Wait()
+				close(errCh)
+				for err := range errCh {
+					Expect(err).NotTo(HaveOccurred())
+				}
+
+				var endpoint spiderpoolv1.SpiderEndpoint
+				err = fakeClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: endpointName}, &endpoint)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoint.Status.History).To(HaveLen(1))
+				Expect(endpoint.Status.History[0].CNICalls).To(HaveLen(len(nics)))
+				Expect(endpoint.Status.Current.IPs).To(HaveLen(len(nics)))
+				for _, nic := range nics {
+					Expect(endpoint.Status.Current.IPs).To(ContainElement(HaveField("NIC", nic)))
+				}
+				Expect(endpoint.Status.History[0].IPs).To(ConsistOf(endpoint.Status.Current.IPs))
+			})
+
+			It("keeps the IP allocations of the other NICs", func() {
+				marked.IPs = []spiderpoolv1.IPAllocationDetail{{NIC: "net1", IPv4: pointer.String("172.18.41.10/24")}}
+				endpointT.Status.Current = marked
+				endpointT.Status.History = append(endpointT.Status.History, *marked)
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = endpointManager.PatchIPAllocation(ctx, patch, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				var endpoint spiderpoolv1.SpiderEndpoint
+				err = fakeClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: endpointName}, &endpoint)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoint.Status.Current.IPs).To(Equal(append(marked.IPs, patch.IPs...)))
+			})
+
+			It("patches the IP allocation after the Endpoint is marked by another container", func() {
+				endpointT.Status.Current = marked
+				endpointT.Status.History = append(endpointT.Status.History, *marked)
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				stale := endpointT.DeepCopy()
+				endpointT.Status.Current = &spiderpoolv1.PodIPAllocation{ContainerID: stringid.GenerateRandomID()}
+				endpointT.Status.History = append([]spiderpoolv1.PodIPAllocation{*endpointT.Status.Current}, endpointT.Status.History...)
+				err = fakeClient.Status().Update(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = endpointManager.PatchIPAllocation(ctx, patch, stale)
+				Expect(err).To(HaveOccurred())
 			})
 		})
 
@@ -614,8 +1013,8 @@ var _ = Describe("WorkloadEndpointManager", Label("workloadendpoint_manager_test
 				Expect(err).NotTo(HaveOccurred())
 			})
 
-			It("failed to update the status of Endpoint due to some unknown errors", func() {
-				patches := gomonkey.ApplyMethodReturn(fakeClient.Status(), "Update", constant.ErrUnknown)
+			It("failed to patch the status of Endpoint due to some unknown errors", func() {
+				patches := gomonkey.ApplyMethodReturn(fakeClient.Status(), "Patch", constant.ErrUnknown)
 				defer patches.Reset()
 
 				containerId := stringid.GenerateRandomID()
@@ -650,6 +1049,122 @@ var _ = Describe("WorkloadEndpointManager", Label("workloadendpoint_manager_test
 				err = fakeClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: endpointName}, &endpoint)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(endpoint.Status.Current).To(BeNil())
+				Expect(endpoint.Status.Summary).NotTo(BeNil())
+				Expect(endpoint.Status.Summary.IPs).To(BeEmpty())
+			})
+
+			It("clears up the current IP allocation of the stale Endpoint without conflict", func() {
+				containerId := stringid.GenerateRandomID()
+				endpointT.Status.Current.ContainerID = containerId
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				stale := endpointT.DeepCopy()
+				endpointT.Labels["updated"] = "true"
+				err = fakeClient.Update(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = endpointManager.ClearCurrentIPAllocation(ctx, containerId, stale)
+				Expect(err).NotTo(HaveOccurred())
+
+				var endpoint spiderpoolv1.SpiderEndpoint
+				err = fakeClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: endpointName}, &endpoint)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoint.Status.Current).To(BeNil())
+			})
+
+			It("never clears up the current IP allocation of another container", func() {
+				containerId := stringid.GenerateRandomID()
+				endpointT.Status.Current.ContainerID = containerId
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				stale := endpointT.DeepCopy()
+				newContainerID := stringid.GenerateRandomID()
+				endpointT.Status.Current.ContainerID = newContainerID
+				err = fakeClient.Status().Update(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = endpointManager.ClearCurrentIPAllocation(ctx, containerId, stale)
+				Expect(err).To(HaveOccurred())
+
+				var endpoint spiderpoolv1.SpiderEndpoint
+				err = fakeClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: endpointName}, &endpoint)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoint.Status.Current.ContainerID).To(Equal(newContainerID))
+			})
+		})
+
+		Describe("RecordCNICall", func() {
+			var containerID string
+
+			BeforeEach(func() {
+				containerID = stringid.GenerateRandomID()
+				endpointT.Status.Current = &spiderpoolv1.PodIPAllocation{ContainerID: containerID}
+				endpointT.Status.History = []spiderpoolv1.PodIPAllocation{*endpointT.Status.Current}
+			})
+
+			It("records nothing for the container without historical IP allocation", func() {
+				ctx := context.TODO()
+				call := workloadendpointmanager.NewCNICall(constant.CNIOperationDel, "node", constant.CNIResultSuccess)
+				err := endpointManager.RecordCNICall(ctx, stringid.GenerateRandomID(), call, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("failed to patch the status of Endpoint due to some unknown errors", func() {
+				patches := gomonkey.ApplyMethodReturn(fakeClient.Status(), "Patch", constant.ErrUnknown)
+				defer patches.Reset()
+
+				ctx := context.TODO()
+				call := workloadendpointmanager.NewCNICall(constant.CNIOperationDel, "node", constant.CNIResultSuccess)
+				err := endpointManager.RecordCNICall(ctx, containerID, call, endpointT)
+				Expect(err).To(MatchError(constant.ErrUnknown))
+			})
+
+			It("records the CNI calls in the historical IP allocation", func() {
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				add := workloadendpointmanager.NewCNICall(constant.CNIOperationAdd, "node", constant.CNIResultSuccess)
+				err = endpointManager.PatchIPAllocation(ctx, &spiderpoolv1.PodIPAllocation{
+					ContainerID: containerID,
+					CNICalls:    []spiderpoolv1.CNICall{add},
+				}, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				del := workloadendpointmanager.NewCNICall(constant.CNIOperationDel, "node", constant.CNIResultSuccess)
+				err = endpointManager.RecordCNICall(ctx, containerID, del, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				var endpoint spiderpoolv1.SpiderEndpoint
+				err = fakeClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: endpointName}, &endpoint)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoint.Status.Current.CNICalls).To(BeEmpty())
+				calls := workloadendpointmanager.GetCNICalls(&endpoint, containerID)
+				Expect(calls).To(HaveLen(2))
+				Expect(calls[0].Operation).To(Equal(constant.CNIOperationAdd))
+				Expect(calls[1].Operation).To(Equal(constant.CNIOperationDel))
+			})
+
+			It("keeps the latest CNI calls", func() {
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				for i := 0; i < 12; i++ {
+					call := workloadendpointmanager.NewCNICall(constant.CNIOperationAdd, fmt.Sprintf("node-%d", i), constant.CNIResultFailure)
+					err = endpointManager.RecordCNICall(ctx, containerID, call, endpointT)
+					Expect(err).NotTo(HaveOccurred())
+				}
+
+				calls := workloadendpointmanager.GetCNICalls(endpointT, containerID)
+				Expect(calls).To(HaveLen(10))
+				Expect(calls[9].Node).To(Equal("node-11"))
 			})
 		})
 
@@ -706,5 +1221,271 @@ var _ = Describe("WorkloadEndpointManager", Label("workloadendpoint_manager_test
 				Expect(*endpointT.Status.Current.Node).To(Equal(nodeName))
 			})
 		})
+
+		Describe("CompactEndpoints", func() {
+			It("failed to list Endpoints due to some unknown errors", func() {
+				patches := gomonkey.ApplyMethodReturn(fakeClient, "List", constant.ErrUnknown)
+				defer patches.Reset()
+
+				ctx := context.TODO()
+				_, err := endpointManager.CompactEndpoints(ctx, nil)
+				Expect(err).To(MatchError(constant.ErrUnknown))
+			})
+
+			It("compacts the Endpoint with duplicated historical records", func() {
+				containerID := stringid.GenerateRandomID()
+				endpointT.Status.Current.ContainerID = containerID
+				endpointT.Status.History = []spiderpoolv1.PodIPAllocation{
+					{ContainerID: containerID, IPs: []spiderpoolv1.IPAllocationDetail{{NIC: "eth0"}}},
+					{ContainerID: containerID},
+				}
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				compacted, err := endpointManager.CompactEndpoints(ctx, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(compacted).To(BeNumerically(">=", 1))
+
+				var endpoint spiderpoolv1.SpiderEndpoint
+				err = fakeClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: endpointName}, &endpoint)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoint.Status.History).To(HaveLen(1))
+				Expect(endpoint.Status.History[0].IPs).To(HaveLen(1))
+			})
+		})
+
+		Describe("ListEndpointsBy indexed fields", func() {
+			var containerID, nodeName string
+
+			BeforeEach(func() {
+				containerID = stringid.GenerateRandomID()
+				nodeName = fmt.Sprintf("node-%v", count)
+				endpointT.Status.Current = &spiderpoolv1.PodIPAllocation{
+					ContainerID: containerID,
+					Node:        pointer.String(nodeName),
+					IPs: []spiderpoolv1.IPAllocationDetail{
+						{
+							NIC:  "eth0",
+							IPv4: pointer.String(fmt.Sprintf("172.18.41.%v/24", count%250+1)),
+							IPv6: pointer.String(fmt.Sprintf("abcd:1234::%x/120", count)),
+						},
+					},
+				}
+			})
+
+			It("indexes the fields of the current IP allocation", func() {
+				Expect(workloadendpointmanager.IndexEndpointContainerID(endpointT)).To(Equal([]string{containerID}))
+				Expect(workloadendpointmanager.IndexEndpointNode(endpointT)).To(Equal([]string{nodeName}))
+				Expect(workloadendpointmanager.IndexEndpointIPs(endpointT)).To(Equal([]string{
+					fmt.Sprintf("172.18.41.%v", count%250+1),
+					fmt.Sprintf("abcd:1234::%x", count),
+				}))
+
+				endpointT.Status.Current = nil
+				Expect(workloadendpointmanager.IndexEndpointContainerID(endpointT)).To(BeEmpty())
+				Expect(workloadendpointmanager.IndexEndpointNode(endpointT)).To(BeEmpty())
+				Expect(workloadendpointmanager.IndexEndpointIPs(endpointT)).To(BeEmpty())
+			})
+
+			It("lists the Endpoints by the indexed fields without the indexed cache", func() {
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				endpoints, err := endpointManager.ListEndpointsByContainerID(ctx, containerID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoints).To(HaveLen(1))
+				Expect(endpoints[0].Name).To(Equal(endpointName))
+
+				endpoints, err = endpointManager.ListEndpointsByNode(ctx, nodeName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoints).To(HaveLen(1))
+
+				endpoints, err = endpointManager.ListEndpointsByIP(ctx, fmt.Sprintf("abcd:1234::%x", count))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoints).To(HaveLen(1))
+
+				endpoints, err = endpointManager.ListEndpointsByNode(ctx, "unknown-node")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoints).To(BeEmpty())
+			})
+
+			It("lists the Endpoints of the node from the cache", func() {
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				cache := &fieldIndexedReader{Reader: fakeClient}
+				cachedManager, err := workloadendpointmanager.NewWorkloadEndpointManager(
+					workloadendpointmanager.EndpointManagerConfig{CachedReader: cache},
+					fakeClient,
+				)
+				Expect(err).NotTo(HaveOccurred())
+
+				endpoints, err := cachedManager.ListEndpointsByNode(ctx, nodeName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoints).To(HaveLen(1))
+				Expect(endpoints[0].Name).To(Equal(endpointName))
+				Expect(cache.lists).To(Equal(1))
+
+				endpoints, err = cachedManager.ListEndpointsByNode(ctx, "unknown-node")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoints).To(BeEmpty())
+				Expect(cache.lists).To(Equal(2))
+			})
+
+			It("gets the Endpoint by the IP address", func() {
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				endpoint, err := endpointManager.GetEndpointByIP(ctx, constant.IPv4, fmt.Sprintf("172.18.41.%v", count%250+1))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoint.Name).To(Equal(endpointName))
+
+				endpoint, err = endpointManager.GetEndpointByIP(ctx, constant.IPv6, fmt.Sprintf("abcd:1234:0::%x", count))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoint.Name).To(Equal(endpointName))
+
+				_, err = endpointManager.GetEndpointByIP(ctx, constant.IPv4, "abcd:1234::1")
+				Expect(err).To(MatchError(constant.ErrWrongInput))
+
+				_, err = endpointManager.GetEndpointByIP(ctx, constant.IPv4, "172.18.42.1")
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			})
+
+			It("indexes the top controller of the Pod", func() {
+				endpointT.Status.OwnerControllerType = constant.KindDeployment
+				endpointT.Status.OwnerControllerName = "deploy"
+				Expect(workloadendpointmanager.IndexEndpointOwnerController(endpointT)).To(Equal([]string{constant.KindDeployment + "/deploy"}))
+
+				endpointT.Status.OwnerControllerName = ""
+				Expect(workloadendpointmanager.IndexEndpointOwnerController(endpointT)).To(BeEmpty())
+			})
+
+			It("lists the Endpoints of the top controller in the namespace", func() {
+				endpointT.Status.OwnerControllerType = constant.KindDeployment
+				endpointT.Status.OwnerControllerName = fmt.Sprintf("deploy-%v", count)
+
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				others := []*spiderpoolv1.SpiderEndpoint{endpointT.DeepCopy(), endpointT.DeepCopy()}
+				others[0].Name = endpointName + "-other-owner"
+				others[0].Status.OwnerControllerName = fmt.Sprintf("deploy-other-%v", count)
+				others[1].Name = endpointName + "-other-namespace"
+				others[1].Namespace = "kube-system"
+				for _, other := range others {
+					other.ResourceVersion = ""
+					err := fakeClient.Create(ctx, other)
+					Expect(err).NotTo(HaveOccurred())
+					defer func(other *spiderpoolv1.SpiderEndpoint) {
+						err := fakeClient.Delete(ctx, other)
+						Expect(err).NotTo(HaveOccurred())
+					}(other)
+				}
+
+				endpoints, err := endpointManager.ListCachedEndpointsByOwnerController(ctx, namespace, constant.KindDeployment, endpointT.Status.OwnerControllerName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoints).To(HaveLen(1))
+				Expect(endpoints[0].Name).To(Equal(endpointName))
+
+				cache := &fieldIndexedReader{Reader: fakeClient}
+				cachedManager, err := workloadendpointmanager.NewWorkloadEndpointManager(
+					workloadendpointmanager.EndpointManagerConfig{CachedReader: cache},
+					fakeClient,
+				)
+				Expect(err).NotTo(HaveOccurred())
+
+				endpoints, err = cachedManager.ListCachedEndpointsByOwnerController(ctx, namespace, constant.KindDeployment, endpointT.Status.OwnerControllerName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoints).To(HaveLen(1))
+				Expect(endpoints[0].Name).To(Equal(endpointName))
+				Expect(cache.lists).To(Equal(1))
+			})
+
+			It("failed to get the Endpoint by the IP address allocated to multiple Pods", func() {
+				ctx := context.TODO()
+				err := fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				conflict := endpointT.DeepCopy()
+				conflict.Name = endpointName + "-conflict"
+				conflict.ResourceVersion = ""
+				err = fakeClient.Create(ctx, conflict)
+				Expect(err).NotTo(HaveOccurred())
+				defer func() {
+					err := fakeClient.Delete(ctx, conflict)
+					Expect(err).NotTo(HaveOccurred())
+				}()
+
+				_, err = endpointManager.GetEndpointByIP(ctx, constant.IPv4, fmt.Sprintf("172.18.41.%v", count%250+1))
+				Expect(err).To(MatchError(constant.ErrIPConflict))
+			})
+		})
+
+		Describe("MigrateEndpoints", func() {
+			It("failed to list Endpoints due to some unknown errors", func() {
+				patches := gomonkey.ApplyMethodReturn(fakeClient, "List", constant.ErrUnknown)
+				defer patches.Reset()
+
+				ctx := context.TODO()
+				_, err := endpointManager.MigrateEndpoints(ctx, nil, nil)
+				Expect(err).To(MatchError(constant.ErrUnknown))
+			})
+
+			It("migrates the Endpoint stored with the older schema", func() {
+				ctx := context.TODO()
+				containerID := stringid.GenerateRandomID()
+				poolName := fmt.Sprintf("migration-pool-%v", count)
+				ipPool := &spiderpoolv1.SpiderIPPool{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: poolName,
+						UID:  uuid.NewUUID(),
+					},
+					Status: spiderpoolv1.IPPoolStatus{
+						AllocatedIPs: spiderpoolv1.PoolIPAllocations{
+							"172.18.42.10": {ContainerID: containerID, NIC: "eth0", Namespace: namespace, Pod: endpointName},
+						},
+					},
+				}
+				err := fakeClient.Create(ctx, ipPool)
+				Expect(err).NotTo(HaveOccurred())
+
+				endpointT.Status.Current = &spiderpoolv1.PodIPAllocation{
+					ContainerID: containerID,
+					IPs: []spiderpoolv1.IPAllocationDetail{
+						{NIC: "eth0", IPv4: pointer.String("172.18.42.10/24"), IPv4Pool: pointer.String(poolName)},
+					},
+				}
+				endpointT.Status.History = []spiderpoolv1.PodIPAllocation{*endpointT.Status.Current, *endpointT.Status.Current}
+				err = fakeClient.Create(ctx, endpointT)
+				Expect(err).NotTo(HaveOccurred())
+
+				var reports int
+				progress, err := endpointManager.MigrateEndpoints(ctx, func(workloadendpointmanager.EndpointMigrationProgress) {
+					reports++
+				}, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(progress.Migrated).To(BeNumerically(">=", 1))
+				Expect(progress.Failed).To(Equal(0))
+				Expect(reports).To(Equal(progress.Total))
+
+				var endpoint spiderpoolv1.SpiderEndpoint
+				err = fakeClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: endpointName}, &endpoint)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(workloadendpointmanager.GetEndpointSchemaVersion(&endpoint)).To(Equal(workloadendpointmanager.LatestEndpointSchemaVersion()))
+				Expect(endpoint.Status.History).To(HaveLen(1))
+				Expect(endpoint.Status.Current.IPs[0].IPv4PoolUID).To(Equal(pointer.String(string(ipPool.UID))))
+
+				progress, err = endpointManager.MigrateEndpoints(ctx, nil, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(progress.Migrated).To(Equal(0))
+				Expect(progress.UpToDate).To(Equal(progress.Total))
+			})
+		})
 	})
 })
@@ -0,0 +1,247 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package workloadendpointmanager
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spidernet-io/spiderpool/pkg/constant"
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+	"github.com/spidernet-io/spiderpool/pkg/limiter"
+	"github.com/spidernet-io/spiderpool/pkg/logutils"
+)
+
+// EndpointMigration upgrades the Endpoints stored with the schema before its
+// version. Migrate changes the Endpoint in place, and reports whether it's
+// changed. It must be idempotent, since the Endpoints are upgraded again if
+// the controller restarts before the schema version is recorded.
+type EndpointMigration struct {
+	Version     int
+	Description string
+	Migrate     func(ctx context.Context, c client.Reader, endpoint *spiderpoolv1.SpiderEndpoint) (bool, error)
+}
+
+// endpointMigrations are applied in order of their versions, a new one is
+// appended with the next version once the status schema evolves.
+var endpointMigrations = []EndpointMigration{
+	{
+		Version:     1,
+		Description: "merge the consecutive historical records of the same container",
+		Migrate: func(_ context.Context, _ client.Reader, endpoint *spiderpoolv1.SpiderEndpoint) (bool, error) {
+			return CompactEndpoint(endpoint, 0), nil
+		},
+	},
+	{
+		Version:     2,
+		Description: "fill in the UIDs of the IPPools of the current IP allocation",
+		Migrate:     fillIPPoolUIDs,
+	},
+	{
+		Version:     3,
+		Description: "fill in the summary of the status",
+		Migrate: func(_ context.Context, _ client.Reader, endpoint *spiderpoolv1.SpiderEndpoint) (bool, error) {
+			return setEndpointSummary(endpoint), nil
+		},
+	},
+}
+
+// LatestEndpointSchemaVersion returns the schema version of the Endpoints
+// created by the current version.
+func LatestEndpointSchemaVersion() int {
+	return endpointMigrations[len(endpointMigrations)-1].Version
+}
+
+// GetEndpointSchemaVersion returns the schema version of the Endpoint, which
+// is 0 for the ones created before the versioned schema.
+func GetEndpointSchemaVersion(endpoint *spiderpoolv1.SpiderEndpoint) int {
+	version, err := strconv.Atoi(endpoint.Annotations[constant.AnnoEndpointSchemaVersion])
+	if err != nil {
+		return 0
+	}
+
+	return version
+}
+
+func setEndpointSchemaVersion(endpoint *spiderpoolv1.SpiderEndpoint, version int) {
+	if endpoint.Annotations == nil {
+		endpoint.Annotations = map[string]string{}
+	}
+	endpoint.Annotations[constant.AnnoEndpointSchemaVersion] = strconv.Itoa(version)
+}
+
+// MigrateEndpoint applies the migrations newer than the schema version of the
+// Endpoint in place, and reports whether its status is changed.
+func MigrateEndpoint(ctx context.Context, c client.Reader, endpoint *spiderpoolv1.SpiderEndpoint) (bool, error) {
+	version := GetEndpointSchemaVersion(endpoint)
+
+	changed := false
+	for _, m := range endpointMigrations {
+		if m.Version <= version {
+			continue
+		}
+
+		ok, err := m.Migrate(ctx, c, endpoint)
+		if err != nil {
+			return false, fmt.Errorf("failed to migrate Endpoint %s/%s to schema version %d (%s): %w", endpoint.Namespace, endpoint.Name, m.Version, m.Description, err)
+		}
+		changed = changed || ok
+	}
+
+	return changed, nil
+}
+
+// EndpointMigrationProgress reports the progress of upgrading the Endpoints.
+type EndpointMigrationProgress struct {
+	Total    int
+	Migrated int
+	UpToDate int
+	Failed   int
+}
+
+func (p EndpointMigrationProgress) String() string {
+	return fmt.Sprintf("%d/%d Endpoints processed, %d migrated, %d up to date, %d failed",
+		p.Migrated+p.UpToDate+p.Failed, p.Total, p.Migrated, p.UpToDate, p.Failed)
+}
+
+// MigrateEndpoints upgrades all Endpoints stored with the older schema to the
+// latest one, and records the schema version on them. The progress is
+// reported after each Endpoint is processed, and the upgrades are paced by
+// the pacer if not nil.
+func (em *workloadEndpointManager) MigrateEndpoints(ctx context.Context, report func(EndpointMigrationProgress), pacer limiter.Pacer) (EndpointMigrationProgress, error) {
+	logger := logutils.FromContext(ctx)
+
+	var progress EndpointMigrationProgress
+	endpointList, err := em.ListEndpoints(ctx)
+	if err != nil {
+		return progress, err
+	}
+
+	latest := LatestEndpointSchemaVersion()
+	progress.Total = len(endpointList.Items)
+	var errs []error
+	for _, endpoint := range endpointList.Items {
+		if GetEndpointSchemaVersion(&endpoint) < latest {
+			if err := limiter.Pause(ctx, pacer, progress.Migrated+progress.Failed); err != nil {
+				errs = append(errs, err)
+				break
+			}
+		}
+
+		if GetEndpointSchemaVersion(&endpoint) >= latest {
+			progress.UpToDate++
+		} else if err := em.migrateEndpoint(ctx, endpoint.Namespace, endpoint.Name); err != nil {
+			logger.Sugar().Warnf("Failed to migrate Endpoint %s/%s: %v", endpoint.Namespace, endpoint.Name, err)
+			errs = append(errs, err)
+			progress.Failed++
+		} else {
+			progress.Migrated++
+		}
+
+		if report != nil {
+			report(progress)
+		}
+	}
+
+	return progress, utilerrors.NewAggregate(errs)
+}
+
+func (em *workloadEndpointManager) migrateEndpoint(ctx context.Context, namespace, podName string) error {
+	latest := LatestEndpointSchemaVersion()
+	for i := 0; i <= em.config.MaxConflictRetries; i++ {
+		endpoint, err := em.GetEndpointByName(ctx, namespace, podName)
+		if err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if GetEndpointSchemaVersion(endpoint) >= latest {
+			return nil
+		}
+
+		changed, err := MigrateEndpoint(ctx, em.client, endpoint)
+		if err != nil {
+			return err
+		}
+
+		// The status is upgraded before the schema version is recorded, so
+		// that the Endpoint is upgraded again if the latter fails.
+		if changed {
+			err = em.client.Status().Update(ctx, endpoint)
+		}
+		if err == nil {
+			setEndpointSchemaVersion(endpoint, latest)
+			err = em.client.Update(ctx, endpoint)
+		}
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			if !apierrors.IsConflict(err) {
+				return err
+			}
+			if i == em.config.MaxConflictRetries {
+				return fmt.Errorf("%w (%d times), failed to migrate Endpoint %s/%s", constant.ErrRetriesExhausted, em.config.MaxConflictRetries, namespace, podName)
+			}
+			time.Sleep(time.Duration(rand.Intn(1<<(i+1))) * em.config.ConflictRetryUnitTime)
+			continue
+		}
+		break
+	}
+
+	return nil
+}
+
+// fillIPPoolUIDs fills in the UIDs of the IPPools of the current IP
+// allocation, which are missing in the Endpoints created before they were
+// recorded. The UID is filled in only if the IPPool still records the IP
+// address allocated to the same container.
+func fillIPPoolUIDs(ctx context.Context, c client.Reader, endpoint *spiderpoolv1.SpiderEndpoint) (bool, error) {
+	current := endpoint.Status.Current
+	if current == nil {
+		return false, nil
+	}
+
+	old := current.DeepCopy()
+	for i := range current.IPs {
+		d := &current.IPs[i]
+		if err := fillIPPoolUID(ctx, c, current.ContainerID, d.IPv4, d.IPv4Pool, &d.IPv4PoolUID); err != nil {
+			return false, err
+		}
+		if err := fillIPPoolUID(ctx, c, current.ContainerID, d.IPv6, d.IPv6Pool, &d.IPv6PoolUID); err != nil {
+			return false, err
+		}
+	}
+
+	return !equality.Semantic.DeepEqual(old, current), nil
+}
+
+func fillIPPoolUID(ctx context.Context, c client.Reader, containerID string, ipAndCIDR, poolName *string, poolUID **string) error {
+	if ipAndCIDR == nil || poolName == nil || *poolUID != nil {
+		return nil
+	}
+
+	var pool spiderpoolv1.SpiderIPPool
+	if err := c.Get(ctx, apitypes.NamespacedName{Name: *poolName}, &pool); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	ip, _, _ := strings.Cut(*ipAndCIDR, "/")
+	if allocation, ok := pool.Status.AllocatedIPs[ip]; !ok || allocation.ContainerID != containerID {
+		return nil
+	}
+
+	uid := string(pool.UID)
+	*poolUID = &uid
+
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+
+package workloadendpointmanager
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	spiderpoolv1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v1"
+)
+
+// BuildEndpointSummary summarizes the current and historical IP allocations
+// of the Endpoint.
+func BuildEndpointSummary(endpoint *spiderpoolv1.SpiderEndpoint) *spiderpoolv1.EndpointSummary {
+	summary := &spiderpoolv1.EndpointSummary{
+		HistoryCount: len(endpoint.Status.History),
+	}
+
+	current := endpoint.Status.Current
+	if current == nil {
+		return summary
+	}
+	if current.Node != nil {
+		summary.Node = *current.Node
+	}
+
+	var ips, pools []string
+	seen := map[string]struct{}{}
+	record := func(ip, pool *string) {
+		if ip != nil {
+			addr, _, _ := strings.Cut(*ip, "/")
+			ips = append(ips, addr)
+		}
+		if pool == nil {
+			return
+		}
+		if _, ok := seen[*pool]; !ok {
+			seen[*pool] = struct{}{}
+			pools = append(pools, *pool)
+		}
+	}
+	for _, d := range current.IPs {
+		record(d.IPv4, d.IPv4Pool)
+		record(d.IPv6, d.IPv6Pool)
+	}
+	summary.IPs = strings.Join(ips, ",")
+	summary.Pools = strings.Join(pools, ",")
+
+	return summary
+}
+
+// setEndpointSummary refreshes the summary of the Endpoint in place, and
+// reports whether it's changed.
+func setEndpointSummary(endpoint *spiderpoolv1.SpiderEndpoint) bool {
+	summary := BuildEndpointSummary(endpoint)
+	if equality.Semantic.DeepEqual(summary, endpoint.Status.Summary) {
+		return false
+	}
+	endpoint.Status.Summary = summary
+
+	return true
+}